@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/d-sense/event-processor/internal/archive"
+	"github.com/d-sense/event-processor/internal/config"
+	"github.com/d-sense/event-processor/internal/persistence"
+	"github.com/d-sense/event-processor/pkg/aws"
+	"github.com/d-sense/event-processor/pkg/logger"
+)
+
+func main() {
+	from := flag.String("from", "", "first removal date to restore (YYYY-MM-DD)")
+	to := flag.String("to", "", "last removal date to restore (YYYY-MM-DD)")
+	clientID := flag.String("client", "", "only restore events of this client")
+	eventType := flag.String("type", "", "only restore events of this type")
+	ttlDays := flag.Int("ttl-days", 0, "days restored events are kept for; 0 keeps them until deleted")
+	dryRun := flag.Bool("dry-run", false, "list the events that would be restored without writing them")
+	flag.Parse()
+
+	// Load configuration
+	cfg := config.Load()
+	log := logger.New(cfg.LogLevel)
+
+	filter := archive.Filter{ClientID: *clientID, EventType: *eventType}
+	var err error
+	if filter.From, err = parseDate(*from); err != nil {
+		log.Fatalf("Invalid -from: %v", err)
+	}
+	if filter.To, err = parseDate(*to); err != nil {
+		log.Fatalf("Invalid -to: %v", err)
+	}
+
+	awsCfg, err := aws.NewSession(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create AWS config: %v", err)
+	}
+
+	store, err := archive.NewStore(cfg, aws.NewS3Client(awsCfg, cfg))
+	if err != nil {
+		log.Fatalf("Invalid archive configuration: %v", err)
+	}
+
+	ctx := context.Background()
+	records, err := archive.Restore(ctx, store, filter)
+	if err != nil {
+		log.Fatalf("Failed to read archive: %v", err)
+	}
+
+	if *dryRun {
+		for _, record := range records {
+			fmt.Printf("%s\t%s\t%s\t%s\n", record.RemovedAt.Format(time.RFC3339), record.Event.ClientID, record.Event.EventType, record.Event.EventID)
+		}
+		log.WithField("events", len(records)).Info("Dry run complete")
+		return
+	}
+
+	repo, err := persistence.NewRepository(ctx, awsCfg, cfg)
+	if err != nil {
+		log.Fatalf("Failed to create repository: %v", err)
+	}
+
+	// Restored events would be removed again immediately if they kept their original expiry
+	var ttl int64
+	if *ttlDays > 0 {
+		ttl = time.Now().Add(time.Duration(*ttlDays) * 24 * time.Hour).Unix()
+	}
+
+	restored := 0
+	for _, record := range records {
+		event := record.Event
+		event.TTL = ttl
+		err := repo.SaveEvent(ctx, event)
+		if errors.Is(err, persistence.ErrEventExists) {
+			// Restored before, or never removed; the stored event is the more recent one
+			log.WithField("event_id", event.EventID).Info("Event already stored, skipping")
+			continue
+		}
+		if err != nil {
+			log.Fatalf("Failed to restore event %s: %v", event.EventID, err)
+		}
+		restored++
+	}
+
+	if closer, ok := repo.(persistence.Closer); ok {
+		if err := closer.Close(ctx); err != nil {
+			log.Fatalf("Failed to flush restored events: %v", err)
+		}
+	}
+	log.WithField("events", restored).WithField("skipped", len(records)-restored).Info("Restore complete")
+}
+
+// parseDate parses an optional YYYY-MM-DD date
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
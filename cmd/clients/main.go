@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/d-sense/event-processor/internal/api"
+	"github.com/d-sense/event-processor/internal/clients"
+	"github.com/d-sense/event-processor/internal/config"
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+const usage = `Usage: clients [-url URL] [-actor NAME] <command> [arguments]
+
+Manages client configurations through the admin API of a running server, authenticating with
+ADMIN_TOKEN. Every change is recorded in the server's audit log under the actor's name.
+
+Commands:
+  list                                   list every client
+  get <id>                               show a client
+  create <id> -types a,b [-config k=v,...] [-policies file] [-limits json] [-disabled]
+                                         create a client
+  update <id> [-types a,b] [-config k=v,...] [-policies file] [-limits json]
+                                         replace the allowed types, settings, policies or limits of a client
+  enable <id>                            accept events from a client again
+  disable <id>                           reject every event of a client
+  delete <id>                            remove a client
+  history <id>                           show who changed a client and how
+
+Flags:
+`
+
+// adminClient calls the admin API of the server
+type adminClient struct {
+	baseURL string
+	token   string
+	actor   string
+	http    *http.Client
+}
+
+func main() {
+	cfg := config.Load()
+
+	baseURL := flag.String("url", "http://localhost:"+cfg.ServicePort, "base URL of the event processor")
+	actor := flag.String("actor", os.Getenv("USER"), "name recorded in the audit log")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if cfg.AdminToken == "" {
+		fail(errors.New("ADMIN_TOKEN is not set"))
+	}
+
+	client := &adminClient{
+		baseURL: strings.TrimSuffix(*baseURL, "/"),
+		token:   cfg.AdminToken,
+		actor:   *actor,
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+
+	command, args := flag.Arg(0), flag.Args()[1:]
+	if command == "list" {
+		fail(client.list())
+		return
+	}
+
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	clientID, args := args[0], args[1:]
+
+	switch command {
+	case "get":
+		var config models.ClientConfig
+		fail(client.do(http.MethodGet, "/"+clientID, nil, &config))
+		printJSON(config)
+	case "create":
+		fail(client.create(clientID, args))
+	case "update":
+		fail(client.update(clientID, args))
+	case "enable", "disable":
+		var config models.ClientConfig
+		fail(client.do(http.MethodPost, "/"+clientID+"/"+command, nil, &config))
+		printJSON(config)
+	case "delete":
+		fail(client.do(http.MethodDelete, "/"+clientID, nil, nil))
+		fmt.Printf("deleted %s\n", clientID)
+	case "history":
+		fail(client.history(clientID))
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// list prints one line per client
+func (c *adminClient) list() error {
+	var response struct {
+		Clients []models.ClientConfig `json:"clients"`
+	}
+	if err := c.do(http.MethodGet, "", nil, &response); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CLIENT\tACTIVE\tALLOWED TYPES\tCONFIG")
+	for _, config := range response.Clients {
+		fmt.Fprintf(w, "%s\t%t\t%s\t%s\n", config.ClientID, config.Active, formatTypes(config.AllowedTypes), formatSettings(config.Config))
+	}
+	return w.Flush()
+}
+
+// create parses the create flags and creates a client
+func (c *adminClient) create(clientID string, args []string) error {
+	flags := flag.NewFlagSet("create", flag.ExitOnError)
+	allowedTypes := flags.String("types", "", "comma separated event types the client may send")
+	settings := flags.String("config", "", "comma separated key=value settings")
+	policies := flags.String("policies", "", "JSON file of policies keyed by event type")
+	limits := flags.String("limits", "", `JSON rate limits and quotas, e.g. {"ratePerSecond":10,"dailyQuota":100000}`)
+	disabled := flags.Bool("disabled", false, "create the client disabled")
+	flags.Parse(args)
+
+	config := models.ClientConfig{ClientID: clientID, Active: !*disabled}
+	config.AllowedTypes = parseTypes(*allowedTypes)
+	var err error
+	if config.Config, err = parseSettings(*settings); err != nil {
+		return err
+	}
+	if *policies != "" {
+		if config.Policies, err = readPolicies(*policies); err != nil {
+			return err
+		}
+	}
+	if config.Limits, err = parseLimits(*limits); err != nil {
+		return err
+	}
+
+	var created models.ClientConfig
+	if err := c.do(http.MethodPost, "", config, &created); err != nil {
+		return err
+	}
+	printJSON(created)
+	return nil
+}
+
+// update replaces the allowed types, settings, policies or limits given as flags, keeping everything else
+func (c *adminClient) update(clientID string, args []string) error {
+	flags := flag.NewFlagSet("update", flag.ExitOnError)
+	allowedTypes := flags.String("types", "", "comma separated event types the client may send")
+	settings := flags.String("config", "", "comma separated key=value settings")
+	policies := flags.String("policies", "", "JSON file of policies keyed by event type; empty removes them")
+	limits := flags.String("limits", "", "JSON rate limits and quotas; empty removes them")
+	flags.Parse(args)
+
+	given := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	if len(given) == 0 {
+		return errors.New("nothing to update: set -types, -config, -policies or -limits")
+	}
+
+	var config models.ClientConfig
+	if err := c.do(http.MethodGet, "/"+clientID, nil, &config); err != nil {
+		return err
+	}
+	if given["types"] {
+		config.AllowedTypes = parseTypes(*allowedTypes)
+	}
+	if given["config"] {
+		var err error
+		if config.Config, err = parseSettings(*settings); err != nil {
+			return err
+		}
+	}
+	if given["policies"] {
+		config.Policies = nil
+		if *policies != "" {
+			var err error
+			if config.Policies, err = readPolicies(*policies); err != nil {
+				return err
+			}
+		}
+	}
+	if given["limits"] {
+		var err error
+		if config.Limits, err = parseLimits(*limits); err != nil {
+			return err
+		}
+	}
+
+	var updated models.ClientConfig
+	if err := c.do(http.MethodPut, "/"+clientID, config, &updated); err != nil {
+		return err
+	}
+	printJSON(updated)
+	return nil
+}
+
+// history prints the audit entries of a client
+func (c *adminClient) history(clientID string) error {
+	var response struct {
+		Entries []clients.AuditEntry `json:"entries"`
+	}
+	if err := c.do(http.MethodGet, "/"+clientID+"/audit", nil, &response); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tACTOR\tACTION\tALLOWED TYPES\tACTIVE")
+	for _, entry := range response.Entries {
+		allowedTypes, active := "-", "-"
+		if entry.After != nil {
+			allowedTypes, active = formatTypes(entry.After.AllowedTypes), fmt.Sprint(entry.After.Active)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", entry.Time.Format(time.RFC3339), entry.Actor, entry.Action, allowedTypes, active)
+	}
+	return w.Flush()
+}
+
+// do sends a request to /v1/admin/clients+path and decodes the response into out
+func (c *adminClient) do(method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+"/v1/admin/clients"+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set(api.ActorHeader, c.actor)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var failure struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&failure)
+		return fmt.Errorf("%s: %s", resp.Status, failure.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// parseTypes splits a comma separated list of event types
+func parseTypes(value string) []models.EventType {
+	var allowedTypes []models.EventType
+	for _, eventType := range strings.Split(value, ",") {
+		if eventType = strings.TrimSpace(eventType); eventType != "" {
+			allowedTypes = append(allowedTypes, models.EventType(eventType))
+		}
+	}
+	return allowedTypes
+}
+
+// parseSettings splits comma separated key=value pairs
+func parseSettings(value string) (map[string]string, error) {
+	settings := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		key, settingValue, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid setting %q: expected key=value", pair)
+		}
+		settings[key] = settingValue
+	}
+	return settings, nil
+}
+
+// readPolicies reads a JSON file of policies keyed by event type
+func readPolicies(path string) (map[models.EventType]models.EventPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var policies map[models.EventType]models.EventPolicy
+	if err := json.Unmarshal(data, &policies); err != nil {
+		return nil, fmt.Errorf("invalid policies in %s: %w", path, err)
+	}
+	return policies, nil
+}
+
+// parseLimits parses JSON rate limits and quotas; empty means no limits
+func parseLimits(value string) (*models.ClientLimits, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var limits models.ClientLimits
+	if err := json.Unmarshal([]byte(value), &limits); err != nil {
+		return nil, fmt.Errorf("invalid limits: %w", err)
+	}
+	return &limits, nil
+}
+
+func formatTypes(allowedTypes []models.EventType) string {
+	names := make([]string, len(allowedTypes))
+	for i, eventType := range allowedTypes {
+		names[i] = string(eventType)
+	}
+	return strings.Join(names, ",")
+}
+
+func formatSettings(settings map[string]string) string {
+	pairs := make([]string, 0, len(settings))
+	for key, value := range settings {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func printJSON(value interface{}) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(value)
+}
+
+// fail exits with the error, if any
+func fail(err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "clients: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+
+	"github.com/d-sense/event-processor/internal/config"
+	"github.com/d-sense/event-processor/internal/dlq"
+	"github.com/d-sense/event-processor/pkg/aws"
+	"github.com/d-sense/event-processor/pkg/logger"
+)
+
+const usage = `Usage: dlq <command> [-client ID] [-type TYPE] [-reason TEXT] [-category NAME] [-ids a,b] [-limit N] [flags]
+
+Works on the dead letter queue at SQS_DLQ_URL. Messages are selected by client, event type, text in
+their failure reason, error category of their last attempt or message ID (DLQ or original); peek
+and group leave them in the queue.
+
+Commands:
+  peek                                   show the selected messages
+  group                                  count the selected messages by failure reason and client
+  redrive [-rate N] [-patch JSON|@file]  send the selected messages back to SQS_QUEUE_URL, optionally
+                                         after applying a JSON merge patch to their bodies
+  purge [-all]                           delete the selected messages; -all is required without a filter
+`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	command := flag.Arg(0)
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	clientID := flags.String("client", "", "only select messages of this client")
+	eventType := flags.String("type", "", "only select messages of this event type")
+	reason := flags.String("reason", "", "only select messages whose failure reason contains this text")
+	category := flags.String("category", "", "only select messages whose last attempt failed with this error category, e.g. storage")
+	ids := flags.String("ids", "", "comma separated DLQ or original message IDs to select")
+	limit := flags.Int("limit", 0, "maximum number of messages to select; 0 selects every match (peek defaults to 10)")
+	rate := flags.Float64("rate", 10, "redrive: messages sent per second; 0 sends as fast as possible")
+	patch := flags.String("patch", "", "redrive: JSON merge patch applied to every body, or @file to read it from a file")
+	all := flags.Bool("all", false, "purge: allow purging without a filter")
+	flags.Parse(flag.Args()[1:])
+
+	filter := dlq.Filter{ClientID: *clientID, EventType: *eventType, Reason: *reason, Category: *category, Limit: *limit}
+	for _, id := range strings.Split(*ids, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			filter.MessageIDs = append(filter.MessageIDs, id)
+		}
+	}
+
+	// Load configuration
+	cfg := config.Load()
+	log := logger.New(cfg.LogLevel)
+
+	awsCfg, err := aws.NewSession(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create AWS config: %v", err)
+	}
+	manager := dlq.NewManager(sqs.NewFromConfig(awsCfg, func(o *sqs.Options) {
+		o.BaseEndpoint = awssdk.String(cfg.AWSEndpointURL)
+	}), cfg.SQSDLQUrl, cfg.SQSQueueURL, dlq.Options{WaitTime: time.Second}, log)
+
+	ctx := context.Background()
+	switch command {
+	case "peek":
+		if filter.Limit == 0 {
+			filter.Limit = 10
+		}
+		messages, err := manager.Peek(ctx, filter)
+		fail(err)
+		printJSON(messages)
+	case "group":
+		groups, err := manager.Group(ctx, filter)
+		fail(err)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "COUNT\tCLIENT\tFAILURE REASON")
+		for _, group := range groups {
+			fmt.Fprintf(w, "%d\t%s\t%s\n", group.Count, group.ClientID, group.FailureReason)
+		}
+		fail(w.Flush())
+	case "redrive":
+		options := dlq.RedriveOptions{Filter: filter, RatePerSecond: *rate}
+		options.Patch, err = readPatch(*patch)
+		fail(err)
+		result, err := manager.Redrive(ctx, options)
+		fail(err)
+		printJSON(result)
+	case "purge":
+		if filter.IsEmpty() && !*all {
+			fail(errors.New("refusing to purge every message: set a filter or -all"))
+		}
+		result, err := manager.Purge(ctx, filter)
+		fail(err)
+		printJSON(result)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// readPatch reads a merge patch given inline or as @file
+func readPatch(value string) (json.RawMessage, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	patch := []byte(value)
+	if path, ok := strings.CutPrefix(value, "@"); ok {
+		var err error
+		if patch, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := dlq.ValidatePatch(patch); err != nil {
+		return nil, err
+	}
+	return patch, nil
+}
+
+func printJSON(value interface{}) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(value)
+}
+
+// fail exits with the error, if any
+func fail(err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dlq: %v\n", err)
+		os.Exit(1)
+	}
+}
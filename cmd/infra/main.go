@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+
+	"github.com/d-sense/event-processor/internal/config"
+	"github.com/d-sense/event-processor/internal/infra"
+	"github.com/d-sense/event-processor/pkg/aws"
+	"github.com/d-sense/event-processor/pkg/logger"
+)
+
+const usage = `Usage: infra [-file spec.yaml] <command>
+
+Commands:
+  plan   show how the DynamoDB tables and SQS queues differ from the spec
+  apply  change them to match the spec
+
+Flags:
+`
+
+func main() {
+	specPath := flag.String("file", "", "infrastructure spec file (defaults to INFRA_SPEC_PATH)")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 || (flag.Arg(0) != "plan" && flag.Arg(0) != "apply") {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Load configuration
+	cfg := config.Load()
+	log := logger.New(cfg.LogLevel)
+	if *specPath == "" {
+		*specPath = cfg.InfraSpecPath
+	}
+
+	spec, err := infra.LoadSpec(*specPath)
+	if err != nil {
+		log.Fatalf("Failed to load infrastructure spec: %v", err)
+	}
+
+	awsCfg, err := aws.NewSession(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create AWS config: %v", err)
+	}
+
+	planner := infra.NewPlanner(dynamodb.NewFromConfig(awsCfg), sqs.NewFromConfig(awsCfg, func(o *sqs.Options) {
+		o.BaseEndpoint = awssdk.String(cfg.AWSEndpointURL)
+	}), log)
+
+	ctx := context.Background()
+	plan, err := planner.Plan(ctx, spec)
+	if err != nil {
+		log.Fatalf("Failed to plan infrastructure changes: %v", err)
+	}
+	if err := plan.Write(os.Stdout); err != nil {
+		log.Fatalf("Failed to print plan: %v", err)
+	}
+
+	if flag.Arg(0) == "apply" && !plan.Empty() {
+		if err := planner.Apply(ctx, plan); err != nil {
+			log.Fatalf("Failed to apply infrastructure changes: %v", err)
+		}
+		fmt.Println("Apply complete.")
+	}
+}
@@ -1,12 +1,15 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/google/uuid"
+	"io"
 	"log"
 	"math/rand"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -26,29 +29,43 @@ func main() {
 	endpoint := getEnv("AWS_ENDPOINT_URL", "http://localhost:4566")
 	region := getEnv("AWS_REGION", "us-east-1")
 	queueURL := getEnv("SQS_QUEUE_URL", "http://localhost:4566/000000000000/event-queue")
+	publishURL := getEnv("PUBLISH_URL", "")
+
+	// Publish over HTTP to a server running in dev mode, or to SQS otherwise
+	var publish func(event *models.Event) error
+	if publishURL != "" {
+		log.Printf("Publishing events over HTTP to: %s", publishURL)
+		httpClient := &http.Client{Timeout: 10 * time.Second}
+		publish = func(event *models.Event) error {
+			return publishEvent(httpClient, publishURL, event)
+		}
+	} else {
+		log.Printf("Using AWS endpoint: %s", endpoint)
+		log.Printf("Using queue URL: %s", queueURL)
+
+		// Create AWS config
+		awsCfg, err := config.LoadDefaultConfig(context.TODO(),
+			config.WithRegion(region),
+			config.WithCredentialsProvider(credentials.StaticCredentialsProvider{
+				Value: aws.Credentials{
+					AccessKeyID:     "test",
+					SecretAccessKey: "test",
+				},
+			}),
+		)
+		if err != nil {
+			log.Fatalf("Failed to create AWS config: %v", err)
+		}
 
-	log.Printf("Using AWS endpoint: %s", endpoint)
-	log.Printf("Using queue URL: %s", queueURL)
-
-	// Create AWS config
-	awsCfg, err := config.LoadDefaultConfig(context.TODO(),
-		config.WithRegion(region),
-		config.WithCredentialsProvider(credentials.StaticCredentialsProvider{
-			Value: aws.Credentials{
-				AccessKeyID:     "test",
-				SecretAccessKey: "test",
-			},
-		}),
-	)
-	if err != nil {
-		log.Fatalf("Failed to create AWS config: %v", err)
+		// Create SQS client
+		sqsClient := sqs.NewFromConfig(awsCfg, func(o *sqs.Options) {
+			o.BaseEndpoint = aws.String(endpoint)
+		})
+		publish = func(event *models.Event) error {
+			return sendEvent(sqsClient, queueURL, event)
+		}
 	}
 
-	// Create SQS client
-	sqsClient := sqs.NewFromConfig(awsCfg, func(o *sqs.Options) {
-		o.BaseEndpoint = aws.String(endpoint)
-	})
-
 	// Setup graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -72,7 +89,7 @@ func main() {
 			log.Printf("Generated event #%d: %s (Type: %s, Client: %s)",
 				eventCounter, event.EventID, event.EventType, event.ClientID)
 
-			if err := sendEvent(sqsClient, queueURL, event); err != nil {
+			if err := publish(event); err != nil {
 				log.Printf("Failed to send event: %v", err)
 				continue
 			}
@@ -188,3 +205,23 @@ func sendEvent(sqsClient *sqs.Client, queueURL string, event *models.Event) erro
 
 	return err
 }
+
+// publishEvent posts an event to the HTTP publish endpoint of a server running in dev mode
+func publishEvent(httpClient *http.Client, publishURL string, event *models.Event) error {
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	resp, err := httpClient.Post(publishURL, "application/json", bytes.NewReader(eventJSON))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("publish failed with status %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
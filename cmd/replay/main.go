@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/d-sense/event-processor/internal/authz"
+	"github.com/d-sense/event-processor/internal/config"
+	"github.com/d-sense/event-processor/internal/persistence"
+	"github.com/d-sense/event-processor/internal/processor"
+	"github.com/d-sense/event-processor/internal/replay"
+	"github.com/d-sense/event-processor/internal/retention"
+	"github.com/d-sense/event-processor/internal/validator"
+	"github.com/d-sense/event-processor/pkg/aws"
+	"github.com/d-sense/event-processor/pkg/logger"
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+const usage = `Usage: replay [-client ID] [-status STATUS] [-type TYPE] [-from TIME] [-to TIME] [-limit N] [-rate N] [-dry-run]
+
+Runs stored events through validation and triage again, e.g. the failed events of a client after a
+handler fix. Processed events that pass become reprocessed, and failed events are retried into
+processed or failed; events whose outcome is not an allowed status transition are left alone and
+listed as failures. Every replayed event records the run. Client limits are not enforced and nothing is quarantined. With -dry-run, the
+changes reprocessing would make are printed without storing anything.
+
+Flags:
+`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	clientID := flag.String("client", "", "only reprocess events of this client")
+	status := flag.String("status", "", "only reprocess events with this status, e.g. failed")
+	eventType := flag.String("type", "", "only reprocess events of this type")
+	from := flag.String("from", "", "only reprocess events with a timestamp at or after this time (RFC 3339 or YYYY-MM-DD)")
+	to := flag.String("to", "", "only reprocess events with a timestamp at or before this time (RFC 3339 or YYYY-MM-DD)")
+	limit := flag.Int("limit", 0, "maximum number of events to reprocess; 0 reprocesses every match")
+	rate := flag.Float64("rate", 10, "events reprocessed per second; 0 reprocesses as fast as possible")
+	dryRun := flag.Bool("dry-run", false, "print what would change without storing anything")
+	flag.Parse()
+
+	options := replay.Options{
+		Filter: replay.Filter{
+			ClientID:  *clientID,
+			Status:    models.EventStatus(*status),
+			EventType: models.EventType(*eventType),
+			Limit:     *limit,
+		},
+		DryRun:        *dryRun,
+		RatePerSecond: *rate,
+	}
+	var err error
+	if options.Filter.From, err = parseTime(*from); err != nil {
+		fail(fmt.Errorf("invalid -from: %w", err))
+	}
+	if options.Filter.To, err = parseTime(*to); err != nil {
+		fail(fmt.Errorf("invalid -to: %w", err))
+	}
+	fail(options.Filter.Validate())
+
+	// Load configuration
+	cfg := config.Load()
+	log := logger.New(cfg.LogLevel)
+	for _, warning := range cfg.Warnings {
+		log.Warn(warning)
+	}
+	for _, problem := range cfg.Errors {
+		log.Fatal(problem)
+	}
+
+	ttlPolicy, err := retention.NewPolicy(cfg)
+	if err != nil {
+		log.Fatalf("Invalid retention configuration: %v", err)
+	}
+	authzMode, err := authz.ParseMode(cfg.AuthzMode)
+	if err != nil {
+		log.Fatalf("Invalid authorization configuration: %v", err)
+	}
+
+	awsCfg, err := aws.NewSession(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create AWS config: %v", err)
+	}
+
+	// An interrupted replay stops after the current event and still prints what it did
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	repo, err := persistence.NewRepository(ctx, awsCfg, cfg)
+	if err != nil {
+		log.Fatalf("Failed to create repository: %v", err)
+	}
+	store, ok := repo.(replay.Store)
+	if !ok {
+		log.Fatalf("The %s storage backend cannot replace events", cfg.StorageBackend)
+	}
+
+	eventValidator, err := validator.New(cfg.SchemaDir)
+	if err != nil {
+		log.Fatalf("Failed to load schemas: %v", err)
+	}
+	eventProcessor := processor.New(repo, eventValidator, authz.New(repo, authzMode, log), nil, nil, ttlPolicy, log)
+	result, err := replay.NewReplayer(store, eventProcessor, log).Run(ctx, options)
+	if result != nil {
+		printJSON(result)
+	}
+	if errors.Is(err, context.Canceled) {
+		err = errors.New("interrupted")
+	}
+	fail(err)
+}
+
+// parseTime parses an optional RFC 3339 time or YYYY-MM-DD date
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func printJSON(value interface{}) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(value)
+}
+
+// fail exits with the error, if any
+func fail(err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "replay: %v\n", err)
+		os.Exit(1)
+	}
+}
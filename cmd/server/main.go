@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
@@ -10,48 +11,183 @@ import (
 	"syscall"
 	"time"
 
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/sirupsen/logrus"
+
+	"github.com/d-sense/event-processor/internal/api"
+	"github.com/d-sense/event-processor/internal/authz"
+	"github.com/d-sense/event-processor/internal/clients"
 	"github.com/d-sense/event-processor/internal/config"
 	"github.com/d-sense/event-processor/internal/consumer"
+	"github.com/d-sense/event-processor/internal/dlq"
 	"github.com/d-sense/event-processor/internal/health"
+	"github.com/d-sense/event-processor/internal/infra"
+	"github.com/d-sense/event-processor/internal/metrics"
 	"github.com/d-sense/event-processor/internal/persistence"
 	"github.com/d-sense/event-processor/internal/processor"
+	"github.com/d-sense/event-processor/internal/queue"
+	"github.com/d-sense/event-processor/internal/ratelimit"
+	"github.com/d-sense/event-processor/internal/replay"
+	"github.com/d-sense/event-processor/internal/retention"
+	"github.com/d-sense/event-processor/internal/stream"
 	"github.com/d-sense/event-processor/internal/validator"
 	"github.com/d-sense/event-processor/pkg/aws"
 	"github.com/d-sense/event-processor/pkg/logger"
 )
 
 func main() {
+	devMode := flag.Bool("dev", false, "run without external services using an in-memory repository and queue")
+	flag.Parse()
+
 	// Load configuration
 	cfg := config.Load()
 
 	// Setup logging using centralized logger package
 	log := logger.New(cfg.LogLevel)
+	for _, warning := range cfg.Warnings {
+		log.Warn(warning)
+	}
+	for _, problem := range cfg.Errors {
+		log.Fatal(problem)
+	}
+
+	var (
+		repo           persistence.Repository
+		clientCache    *clients.CachedRepository
+		quarantine     persistence.QuarantineStore
+		auditLog       clients.AuditLog
+		sender         *queue.Sender
+		deadLetters    *dlq.Manager
+		replayStore    replay.Store
+		eventConsumer  *consumer.SQSConsumer
+		eventProcessor *processor.EventProcessor
+		publisher      *api.PublishHandler
+	)
+	eventValidator, err := validator.New(cfg.SchemaDir)
+	if err != nil {
+		log.Fatalf("Failed to load schemas: %v", err)
+	}
+	ttlPolicy, err := retention.NewPolicy(cfg)
+	if err != nil {
+		log.Fatalf("Invalid retention configuration: %v", err)
+	}
 
-	// Create AWS config
-	awsCfg, err := aws.NewSession(cfg)
+	authzMode, err := authz.ParseMode(cfg.AuthzMode)
 	if err != nil {
-		log.Fatalf("Failed to create AWS config: %v", err)
+		log.Fatalf("Invalid authorization configuration: %v", err)
+	}
+
+	// Client configurations are looked up for every event, so they are cached in front of the repository
+	cacheOptions := clients.CacheOptions{
+		TTL:         time.Duration(cfg.ClientCacheTTLSeconds) * time.Second,
+		NegativeTTL: time.Duration(cfg.ClientCacheNegativeTTLSeconds) * time.Second,
+	}
+
+	maxDelay := time.Duration(cfg.LimitMaxDelayMs) * time.Millisecond
+
+	if *devMode {
+		// Run fully in-process: in-memory storage seeded with the sample clients and an in-memory queue
+		log.Warn("Running in dev mode: events are kept in memory and lost on shutdown")
+		memoryQueue := queue.NewMemoryQueue(cfg.SQSQueueURL)
+		storage := persistence.NewMemoryRepository(persistence.SampleClientConfigs()...)
+		clientCache = clients.NewCachedRepository(storage, cacheOptions)
+		repo = clientCache
+		quarantine = storage
+		auditLog = storage
+		replayStore = storage
+		limiter := ratelimit.New(ratelimit.NewMemoryStore(), maxDelay, log)
+		eventProcessor = processor.New(repo, eventValidator, authz.New(repo, authzMode, log), limiter, quarantine, ttlPolicy, log)
+		eventConsumer = consumer.NewConsumer(memoryQueue, cfg, eventProcessor, log)
+		publisher = api.NewPublishHandler(memoryQueue, eventValidator, log)
+		sender = queue.NewSender(memoryQueue, cfg.SQSQueueURL)
+		deadLetters = dlq.NewManager(memoryQueue, cfg.SQSDLQUrl, cfg.SQSQueueURL, dlq.Options{}, log)
+	} else {
+		// Create AWS config
+		awsCfg, err := aws.NewSession(cfg)
+		if err != nil {
+			log.Fatalf("Failed to create AWS config: %v", err)
+		}
+
+		// Tables and queues are managed with cmd/infra; local setups opt in to converging them on start
+		if cfg.InfraApplyOnStart {
+			applyInfrastructure(awsCfg, cfg, log)
+		}
+
+		storage, err := persistence.NewRepository(context.Background(), awsCfg, cfg)
+		if err != nil {
+			log.Fatalf("Failed to create repository: %v", err)
+		}
+		clientCache = clients.NewCachedRepository(storage, cacheOptions)
+		repo = clientCache
+
+		// Only DynamoDB streams the changes of client configurations to the caches of every replica
+		if cfg.StorageBackend == persistence.BackendDynamoDB || cfg.StorageBackend == "" {
+			invalidationCtx, stopInvalidation := context.WithCancel(context.Background())
+			defer stopInvalidation()
+			invalidateClientChanges(invalidationCtx, awsCfg, cfg, clientCache, log)
+		} else {
+			log.Warn("Client configurations changed on other replicas are only picked up once cached ones expire")
+		}
+
+		// Every storage backend keeps rejected events and client history, but the cache in front of it does not expose them
+		quarantine, _ = storage.(persistence.QuarantineStore)
+		auditLog, _ = storage.(clients.AuditLog)
+		replayStore, _ = storage.(replay.Store)
+
+		// Rate limits and quotas only hold across replicas when their counters are kept in DynamoDB
+		var limitStore ratelimit.Store = ratelimit.NewDynamoDBStore(awsCfg, cfg.DynamoDBLimitsTableName)
+		if cfg.StorageBackend != persistence.BackendDynamoDB && cfg.StorageBackend != "" {
+			log.Warn("Client limits are counted in memory and only hold per replica")
+			limitStore = ratelimit.NewMemoryStore()
+		}
+		limiter := ratelimit.New(limitStore, maxDelay, log)
+		eventProcessor = processor.New(repo, eventValidator, authz.New(repo, authzMode, log), limiter, quarantine, ttlPolicy, log)
+		eventConsumer = consumer.NewSQSConsumer(awsCfg, cfg, eventProcessor, log)
+
+		sqsClient := sqs.NewFromConfig(awsCfg, func(o *sqs.Options) {
+			o.BaseEndpoint = awssdk.String(cfg.AWSEndpointURL)
+		})
+		sender = queue.NewSender(sqsClient, cfg.SQSQueueURL)
+		deadLetters = dlq.NewManager(sqsClient, cfg.SQSDLQUrl, cfg.SQSQueueURL, dlq.Options{WaitTime: time.Second}, log)
+
+		log.WithField("backend", cfg.StorageBackend).Info("Using storage backend")
 	}
 
-	// Initialize all the components
-	repo := persistence.NewDynamoDBRepository(awsCfg, cfg)
-	eventValidator := validator.New(cfg.SchemaPath)
-	eventProcessor := processor.New(repo, eventValidator, log)
-	eventConsumer := consumer.NewSQSConsumer(awsCfg, cfg, eventProcessor, log)
 	healthChecker := health.New(repo, log)
+	apiHandler := api.New(repo, log)
 
-	// Initialize infrastructure (tables and queues) if they don't exist
-	// TODO: this task should be handled by IoC.
-	infraManager := persistence.NewInfrastructureManager(awsCfg, log)
-	if err := infraManager.SetupInfrastructure(context.Background()); err != nil {
-		log.WithError(err).Warn("Failed to setup infrastructure, continuing anyway")
+	// Client configurations, quarantined events, the DLQ, replays and schemas are managed over the admin API, which needs a token to be enabled
+	var (
+		adminHandler      *api.AdminHandler
+		quarantineHandler *api.QuarantineHandler
+		dlqHandler        *api.DLQHandler
+		replayHandler     *api.ReplayHandler
+		schemaHandler     *api.SchemaHandler
+	)
+	if cfg.AdminToken != "" {
+		if auditLog == nil {
+			log.Fatalf("The %s storage backend does not keep the client audit log", cfg.StorageBackend)
+		}
+		adminHandler = api.NewAdminHandler(clients.NewManager(repo, auditLog, log), clientCache, cfg.AdminToken, log)
+		if quarantine != nil {
+			quarantineHandler = api.NewQuarantineHandler(quarantine, sender, cfg.AdminToken, log)
+		}
+		dlqHandler = api.NewDLQHandler(deadLetters, cfg.AdminToken, log)
+		if replayStore != nil {
+			replayHandler = api.NewReplayHandler(replay.NewReplayer(replayStore, eventProcessor, log), cfg.AdminToken, log)
+		}
+		schemaHandler = api.NewSchemaHandler(eventValidator, cfg.AdminToken, log)
 	} else {
-		log.Info("Infrastructure setup completed successfully")
+		log.Info("Admin API disabled: ADMIN_TOKEN is not set")
 	}
 
 	// Start HTTP server
 	go func() {
-		http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 			status := healthChecker.Check(r.Context())
 			w.Header().Set("Content-Type", "application/json")
 			if status.Healthy {
@@ -69,12 +205,40 @@ func main() {
 			}
 			w.Write(jsonData)
 		})
+		mux.Handle("/metrics", metrics.Handler())
+		apiHandler.Register(mux)
+		if adminHandler != nil {
+			adminHandler.Register(mux)
+		}
+		if quarantineHandler != nil {
+			quarantineHandler.Register(mux)
+		}
+		if dlqHandler != nil {
+			dlqHandler.Register(mux)
+		}
+		if replayHandler != nil {
+			replayHandler.Register(mux)
+		}
+		if schemaHandler != nil {
+			schemaHandler.Register(mux)
+		}
+		if publisher != nil {
+			publisher.Register(mux)
+		}
+
 		log.WithField("port", cfg.ServicePort).Info("Starting HTTP server")
-		if err := http.ListenAndServe(fmt.Sprintf(":%s", cfg.ServicePort), nil); err != nil {
+		if err := http.ListenAndServe(fmt.Sprintf(":%s", cfg.ServicePort), mux); err != nil {
 			log.WithError(err).Fatal("HTTP server failed")
 		}
 	}()
 
+	// Schemas can be changed without a restart; changes that do not compile are not loaded
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	if cfg.SchemaReloadIntervalSeconds > 0 {
+		go eventValidator.Watch(watchCtx, time.Duration(cfg.SchemaReloadIntervalSeconds)*time.Second, log)
+	}
+
 	// Start event consumer
 	go func() {
 		log.Info("Starting event consumer")
@@ -96,5 +260,64 @@ func main() {
 		log.WithError(err).Error("Failed to stop event consumer gracefully")
 	}
 
+	// Flush buffered writes so that in-flight events are not lost
+	if closer, ok := repo.(persistence.Closer); ok {
+		if err := closer.Close(ctx); err != nil {
+			log.WithError(err).Error("Failed to flush buffered writes")
+		}
+	}
+
 	log.Info("Shutdown complete")
 }
+
+// applyInfrastructure converges the tables and queues to the infrastructure spec and seeds the
+// sample client configurations; the server does not start on infrastructure it cannot converge
+func applyInfrastructure(awsCfg awssdk.Config, cfg *config.Config, log *logrus.Logger) {
+	ctx := context.Background()
+
+	spec, err := infra.LoadSpec(cfg.InfraSpecPath)
+	if err != nil {
+		log.Fatalf("Failed to load infrastructure spec: %v", err)
+	}
+
+	planner := infra.NewPlanner(dynamodb.NewFromConfig(awsCfg), sqs.NewFromConfig(awsCfg, func(o *sqs.Options) {
+		o.BaseEndpoint = awssdk.String(cfg.AWSEndpointURL)
+	}), log)
+	plan, err := planner.Plan(ctx, spec)
+	if err != nil {
+		log.Fatalf("Failed to plan infrastructure changes: %v", err)
+	}
+	if err := planner.Apply(ctx, plan); err != nil {
+		log.Fatalf("Failed to apply infrastructure changes: %v", err)
+	}
+	log.WithField("changes", len(plan.Changes)).Info("Infrastructure matches the spec")
+
+	tableNames := persistence.DefaultTableNames()
+	tableNames.Events = cfg.DynamoDBTableName
+	tableNames.EventsClients = cfg.DynamoDBClientsTableName
+	tableManager := persistence.NewTableManager(awsCfg, tableNames, log)
+	if err := tableManager.InsertSampleClientConfigs(ctx); err != nil {
+		log.WithError(err).Warn("Failed to insert sample client configs, continuing...")
+	}
+}
+
+// invalidateClientChanges reads the stream of the clients table until ctx is done, dropping the
+// cached configuration of every changed client. Without a stream, configurations changed on other
+// replicas are picked up once cached ones expire.
+func invalidateClientChanges(ctx context.Context, awsCfg awssdk.Config, cfg *config.Config, cache *clients.CachedRepository, log *logrus.Logger) {
+	streamARN, err := stream.LatestStreamARN(ctx, dynamodb.NewFromConfig(awsCfg), cfg.DynamoDBClientsTableName)
+	if err != nil {
+		log.WithError(err).Warn("Client configurations changed on other replicas are only picked up once cached ones expire")
+		return
+	}
+
+	options := stream.DefaultReaderOptions()
+	if cfg.StreamPollIntervalMs > 0 {
+		options.PollInterval = time.Duration(cfg.StreamPollIntervalMs) * time.Millisecond
+	}
+	sinks := []stream.Sink{stream.NewInvalidationSink(cache)}
+	reader := stream.NewReader(dynamodbstreams.NewFromConfig(awsCfg), streamARN, sinks, stream.NewMemoryCheckpointStore(), options, log)
+
+	log.WithField("stream_arn", streamARN).Info("Invalidating cached client configurations changed on any replica")
+	go reader.Run(ctx)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+
+	"github.com/d-sense/event-processor/internal/archive"
+	"github.com/d-sense/event-processor/internal/config"
+	"github.com/d-sense/event-processor/internal/metrics"
+	"github.com/d-sense/event-processor/internal/stream"
+	"github.com/d-sense/event-processor/pkg/aws"
+	"github.com/d-sense/event-processor/pkg/logger"
+)
+
+func main() {
+	// Load configuration
+	cfg := config.Load()
+	log := logger.New(cfg.LogLevel)
+
+	awsCfg, err := aws.NewSession(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create AWS config: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	streamARN := cfg.StreamARN
+	if streamARN == "" {
+		streamARN, err = stream.LatestStreamARN(ctx, dynamodb.NewFromConfig(awsCfg), cfg.DynamoDBTableName)
+		if err != nil {
+			log.Fatalf("Failed to find the stream of the events table: %v", err)
+		}
+	}
+
+	archiveStore, err := archive.NewStore(cfg, aws.NewS3Client(awsCfg, cfg))
+	if err != nil {
+		log.Fatalf("Invalid archive configuration: %v", err)
+	}
+
+	sinks, err := stream.NewSinks(cfg, sqs.NewFromConfig(awsCfg, func(o *sqs.Options) {
+		o.BaseEndpoint = awssdk.String(cfg.AWSEndpointURL)
+	}), archiveStore, log)
+	if err != nil {
+		log.Fatalf("Invalid stream sink configuration: %v", err)
+	}
+
+	checkpoints, err := stream.NewFileCheckpointStore(cfg.StreamCheckpointPath)
+	if err != nil {
+		log.Fatalf("Failed to load checkpoints: %v", err)
+	}
+
+	deadLetters, err := stream.NewFileDeadLetterStore(cfg.StreamDeadLetterPath)
+	if err != nil {
+		log.Fatalf("Failed to open dead letters: %v", err)
+	}
+	defer deadLetters.Close()
+
+	options := stream.DefaultReaderOptions()
+	if cfg.StreamPollIntervalMs > 0 {
+		options.PollInterval = time.Duration(cfg.StreamPollIntervalMs) * time.Millisecond
+	}
+	if cfg.StreamMaxDeliveryAttempts > 0 {
+		options.MaxDeliveryAttempts = cfg.StreamMaxDeliveryAttempts
+	}
+	options.DeadLetters = deadLetters
+	reader := stream.NewReader(dynamodbstreams.NewFromConfig(awsCfg), streamARN, sinks, checkpoints, options, log)
+
+	// Expose metrics on the service port
+	go func() {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		if err := http.ListenAndServe(fmt.Sprintf(":%s", cfg.ServicePort), mux); err != nil {
+			log.WithError(err).Error("Metrics server failed")
+		}
+	}()
+
+	log.WithField("stream_arn", streamARN).WithField("sinks", cfg.StreamSinks).Info("Starting stream reader")
+	if err := reader.Run(ctx); err != nil {
+		log.WithError(err).Fatal("Stream reader failed")
+	}
+
+	for _, sink := range sinks {
+		if closer, ok := sink.(interface{ Close() error }); ok {
+			if err := closer.Close(); err != nil {
+				log.WithError(err).WithField("sink", sink.Name()).Error("Failed to close sink")
+			}
+		}
+	}
+	log.Info("Shutdown complete")
+}
@@ -0,0 +1,249 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/d-sense/event-processor/internal/clients"
+	"github.com/d-sense/event-processor/internal/persistence"
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// ActorHeader names who makes an admin change, recorded in the client audit log
+const ActorHeader = "X-Actor"
+
+// maxAdminBodyBytes bounds the size of a client configuration request
+const maxAdminBodyBytes = 64 * 1024
+
+// CacheInvalidator drops cached client configurations, e.g. clients.CachedRepository
+type CacheInvalidator interface {
+	Invalidate(clientID string)
+	InvalidateAll()
+}
+
+// AdminHandler serves the client configuration management API
+type AdminHandler struct {
+	manager *clients.Manager
+	cache   CacheInvalidator
+	token   string
+	logger  *logrus.Logger
+}
+
+// clientRequest is the JSON body creating or replacing a client configuration
+type clientRequest struct {
+	ClientID     string             `json:"clientId"`
+	AllowedTypes []models.EventType `json:"allowedTypes"`
+	Config       map[string]string  `json:"config"`
+
+	// Policies restricts the events of allowed types, keyed by event type
+	Policies map[models.EventType]models.EventPolicy `json:"policies"`
+
+	// Limits caps the client's event rate and daily and monthly event counts
+	Limits *models.ClientLimits `json:"limits"`
+
+	// Active defaults to true
+	Active *bool `json:"active"`
+}
+
+// NewAdminHandler creates an admin handler; requests must carry token as a bearer token. The cache
+// routes are only served when cache is not nil.
+func NewAdminHandler(manager *clients.Manager, cache CacheInvalidator, token string, logger *logrus.Logger) *AdminHandler {
+	return &AdminHandler{
+		manager: manager,
+		cache:   cache,
+		token:   token,
+		logger:  logger,
+	}
+}
+
+// Register registers the admin routes on the given mux
+func (h *AdminHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /v1/admin/clients", h.authorize(h.listClients))
+	mux.HandleFunc("POST /v1/admin/clients", h.authorize(h.createClient))
+	mux.HandleFunc("GET /v1/admin/clients/{id}", h.authorize(h.getClient))
+	mux.HandleFunc("PUT /v1/admin/clients/{id}", h.authorize(h.updateClient))
+	mux.HandleFunc("DELETE /v1/admin/clients/{id}", h.authorize(h.deleteClient))
+	mux.HandleFunc("POST /v1/admin/clients/{id}/enable", h.authorize(h.setActive(true)))
+	mux.HandleFunc("POST /v1/admin/clients/{id}/disable", h.authorize(h.setActive(false)))
+	mux.HandleFunc("GET /v1/admin/clients/{id}/audit", h.authorize(h.clientHistory))
+
+	if h.cache != nil {
+		mux.HandleFunc("DELETE /v1/admin/cache/clients", h.authorize(h.invalidateClients))
+		mux.HandleFunc("DELETE /v1/admin/cache/clients/{id}", h.authorize(h.invalidateClient))
+	}
+}
+
+// authorize rejects requests without the admin bearer token
+func (h *AdminHandler) authorize(next http.HandlerFunc) http.HandlerFunc {
+	return requireAdminToken(h.token, next)
+}
+
+// requireAdminToken rejects requests that do not carry adminToken as a bearer token
+func requireAdminToken(adminToken string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("invalid admin token"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// listClients handles GET /v1/admin/clients
+func (h *AdminHandler) listClients(w http.ResponseWriter, r *http.Request) {
+	configs, err := h.manager.List(r.Context())
+	if err != nil {
+		h.writeManagerError(w, err)
+		return
+	}
+	if configs == nil {
+		configs = []*models.ClientConfig{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"clients": configs})
+}
+
+// getClient handles GET /v1/admin/clients/{id}
+func (h *AdminHandler) getClient(w http.ResponseWriter, r *http.Request) {
+	config, err := h.manager.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		h.writeManagerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, config)
+}
+
+// createClient handles POST /v1/admin/clients
+func (h *AdminHandler) createClient(w http.ResponseWriter, r *http.Request) {
+	config, err := decodeClientRequest(w, r, "")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	created, err := h.manager.Create(r.Context(), r.Header.Get(ActorHeader), config)
+	if err != nil {
+		h.writeManagerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
+
+// updateClient handles PUT /v1/admin/clients/{id}, replacing the whole configuration
+func (h *AdminHandler) updateClient(w http.ResponseWriter, r *http.Request) {
+	config, err := decodeClientRequest(w, r, r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	updated, err := h.manager.Update(r.Context(), r.Header.Get(ActorHeader), config)
+	if err != nil {
+		h.writeManagerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// setActive handles POST /v1/admin/clients/{id}/enable and /disable
+func (h *AdminHandler) setActive(active bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		config, err := h.manager.SetActive(r.Context(), r.Header.Get(ActorHeader), r.PathValue("id"), active)
+		if err != nil {
+			h.writeManagerError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, config)
+	}
+}
+
+// deleteClient handles DELETE /v1/admin/clients/{id}
+func (h *AdminHandler) deleteClient(w http.ResponseWriter, r *http.Request) {
+	if err := h.manager.Delete(r.Context(), r.Header.Get(ActorHeader), r.PathValue("id")); err != nil {
+		h.writeManagerError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// clientHistory handles GET /v1/admin/clients/{id}/audit
+func (h *AdminHandler) clientHistory(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.manager.History(r.Context(), r.PathValue("id"))
+	if err != nil {
+		h.writeManagerError(w, err)
+		return
+	}
+	if entries == nil {
+		entries = []clients.AuditEntry{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"entries": entries})
+}
+
+// invalidateClients handles DELETE /v1/admin/cache/clients, dropping every cached configuration
+func (h *AdminHandler) invalidateClients(w http.ResponseWriter, r *http.Request) {
+	h.cache.InvalidateAll()
+	h.logger.WithField("actor", r.Header.Get(ActorHeader)).Info("Client config cache invalidated")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// invalidateClient handles DELETE /v1/admin/cache/clients/{id}, so the next event of the client
+// reads its configuration from the repository
+func (h *AdminHandler) invalidateClient(w http.ResponseWriter, r *http.Request) {
+	h.cache.Invalidate(r.PathValue("id"))
+	h.logger.WithFields(logrus.Fields{
+		"actor":     r.Header.Get(ActorHeader),
+		"client_id": r.PathValue("id"),
+	}).Info("Client config cache entry invalidated")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeClientRequest reads a client configuration; pathID, when set, must match the body's clientId
+func decodeClientRequest(w http.ResponseWriter, r *http.Request, pathID string) (*models.ClientConfig, error) {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAdminBodyBytes))
+	decoder.DisallowUnknownFields()
+
+	var request clientRequest
+	if err := decoder.Decode(&request); err != nil {
+		return nil, fmt.Errorf("invalid client config: %w", err)
+	}
+
+	if pathID != "" {
+		if request.ClientID != "" && request.ClientID != pathID {
+			return nil, fmt.Errorf("clientId %q does not match the path", request.ClientID)
+		}
+		request.ClientID = pathID
+	}
+
+	config := &models.ClientConfig{
+		ClientID:     request.ClientID,
+		AllowedTypes: request.AllowedTypes,
+		Config:       request.Config,
+		Policies:     request.Policies,
+		Limits:       request.Limits,
+		Active:       request.Active == nil || *request.Active,
+	}
+	return config, nil
+}
+
+// writeManagerError maps client management errors to status codes
+func (h *AdminHandler) writeManagerError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, clients.ErrActorRequired):
+		writeError(w, http.StatusBadRequest, fmt.Errorf("%w: set the %s header", err, ActorHeader))
+	case errors.Is(err, clients.ErrInvalidConfig):
+		writeError(w, http.StatusBadRequest, err)
+	case errors.Is(err, persistence.ErrClientConfigNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, clients.ErrClientExists):
+		writeError(w, http.StatusConflict, err)
+	default:
+		h.logger.WithError(err).Error("Failed to manage client config")
+		writeError(w, http.StatusInternalServerError, errors.New("failed to manage client config"))
+	}
+}
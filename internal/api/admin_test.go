@@ -0,0 +1,345 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d-sense/event-processor/internal/clients"
+	"github.com/d-sense/event-processor/internal/persistence"
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+const testAdminToken = "test-admin-token"
+
+type adminTestCase struct {
+	name           string
+	method         string
+	path           string
+	body           string
+	token          string
+	actor          string
+	expectedStatus int
+	assertBody     func(*testing.T, map[string]interface{})
+	assertStored   func(*testing.T, *persistence.MemoryRepository)
+	description    string
+}
+
+// TestAdminHandler tests the client management routes
+func TestAdminHandler(t *testing.T) {
+	tests := []adminTestCase{
+		{
+			name:           "List Clients",
+			method:         http.MethodGet,
+			path:           "/v1/admin/clients",
+			expectedStatus: http.StatusOK,
+			assertBody: func(t *testing.T, body map[string]interface{}) {
+				require.Len(t, body["clients"], 1)
+				assert.Equal(t, "client-001", body["clients"].([]interface{})[0].(map[string]interface{})["clientId"])
+			},
+			description: "Should list every client",
+		},
+		{
+			name:           "Missing Token",
+			method:         http.MethodGet,
+			path:           "/v1/admin/clients",
+			token:          "-",
+			expectedStatus: http.StatusUnauthorized,
+			description:    "Should reject requests without the admin token",
+		},
+		{
+			name:           "Wrong Token",
+			method:         http.MethodGet,
+			path:           "/v1/admin/clients",
+			token:          "guess",
+			expectedStatus: http.StatusUnauthorized,
+			description:    "Should reject requests with another token",
+		},
+		{
+			name:           "Get Missing Client",
+			method:         http.MethodGet,
+			path:           "/v1/admin/clients/client-404",
+			expectedStatus: http.StatusNotFound,
+			description:    "Should return 404 for unknown clients",
+		},
+		{
+			name:           "Create Client",
+			method:         http.MethodPost,
+			path:           "/v1/admin/clients",
+			body:           `{"clientId":"client-002","allowedTypes":["transaction"],"config":{"retention_days":"365"}}`,
+			expectedStatus: http.StatusCreated,
+			assertBody: func(t *testing.T, body map[string]interface{}) {
+				assert.Equal(t, "client-002", body["clientId"])
+				assert.Equal(t, true, body["active"], "clients should be active unless stated otherwise")
+			},
+			assertStored: func(t *testing.T, repo *persistence.MemoryRepository) {
+				stored, err := repo.GetClientConfig(context.Background(), "client-002")
+				require.NoError(t, err)
+				assert.Equal(t, []models.EventType{models.EventTypeTransaction}, stored.AllowedTypes)
+			},
+			description: "Should create a client",
+		},
+		{
+			name:           "Create Client With Policy",
+			method:         http.MethodPost,
+			path:           "/v1/admin/clients",
+			body:           `{"clientId":"client-002","allowedTypes":["transaction"],"policies":{"transaction":{"versions":["1.0"],"payload":{"currency":{"required":true,"oneOf":["EUR"]}}}}}`,
+			expectedStatus: http.StatusCreated,
+			assertStored: func(t *testing.T, repo *persistence.MemoryRepository) {
+				stored, err := repo.GetClientConfig(context.Background(), "client-002")
+				require.NoError(t, err)
+				assert.Equal(t, map[models.EventType]models.EventPolicy{
+					models.EventTypeTransaction: {
+						Versions: []string{"1.0"},
+						Payload:  map[string]models.PayloadConstraint{"currency": {Required: true, OneOf: []string{"EUR"}}},
+					},
+				}, stored.Policies)
+			},
+			description: "Should store the policies of a client",
+		},
+		{
+			name:           "Create Client With Invalid Policy",
+			method:         http.MethodPost,
+			path:           "/v1/admin/clients",
+			body:           `{"clientId":"client-002","allowedTypes":["transaction"],"policies":{"transaction":{"payload":{"amount":{"min":10,"max":1}}}}}`,
+			expectedStatus: http.StatusBadRequest,
+			assertBody: func(t *testing.T, body map[string]interface{}) {
+				assert.Contains(t, body["error"], `payload field "amount" has a min above its max`)
+			},
+			description: "Should validate policies",
+		},
+		{
+			name:           "Create Client With Limits",
+			method:         http.MethodPost,
+			path:           "/v1/admin/clients",
+			body:           `{"clientId":"client-002","allowedTypes":["monitoring"],"limits":{"ratePerSecond":5,"dailyQuota":1000,"onExcess":"delay"}}`,
+			expectedStatus: http.StatusCreated,
+			assertStored: func(t *testing.T, repo *persistence.MemoryRepository) {
+				stored, err := repo.GetClientConfig(context.Background(), "client-002")
+				require.NoError(t, err)
+				assert.Equal(t, &models.ClientLimits{RatePerSecond: 5, DailyQuota: 1000, OnExcess: models.ExcessDelay}, stored.Limits)
+			},
+			description: "Should store the limits of a client",
+		},
+		{
+			name:           "Create Client With Invalid Limits",
+			method:         http.MethodPost,
+			path:           "/v1/admin/clients",
+			body:           `{"clientId":"client-002","allowedTypes":["monitoring"],"limits":{"dailyQuota":1000,"onExcess":"queue"}}`,
+			expectedStatus: http.StatusBadRequest,
+			assertBody: func(t *testing.T, body map[string]interface{}) {
+				assert.Contains(t, body["error"], `limits: unknown onExcess action "queue"`)
+			},
+			description: "Should validate limits",
+		},
+		{
+			name:           "Create Existing Client",
+			method:         http.MethodPost,
+			path:           "/v1/admin/clients",
+			body:           `{"clientId":"client-001","allowedTypes":["monitoring"]}`,
+			expectedStatus: http.StatusConflict,
+			description:    "Should not overwrite existing clients",
+		},
+		{
+			name:           "Create Invalid Client",
+			method:         http.MethodPost,
+			path:           "/v1/admin/clients",
+			body:           `{"clientId":"client-002","allowedTypes":["billing"]}`,
+			expectedStatus: http.StatusBadRequest,
+			assertBody: func(t *testing.T, body map[string]interface{}) {
+				assert.Contains(t, body["error"], `unknown event type "billing"`)
+			},
+			description: "Should validate the configuration",
+		},
+		{
+			name:           "Create Without Actor",
+			method:         http.MethodPost,
+			path:           "/v1/admin/clients",
+			body:           `{"clientId":"client-002","allowedTypes":["monitoring"]}`,
+			actor:          "-",
+			expectedStatus: http.StatusBadRequest,
+			assertBody: func(t *testing.T, body map[string]interface{}) {
+				assert.Equal(t, "actor is required: set the X-Actor header", body["error"])
+			},
+			description: "Should require the actor header for changes",
+		},
+		{
+			name:           "Create With Unknown Field",
+			method:         http.MethodPost,
+			path:           "/v1/admin/clients",
+			body:           `{"clientId":"client-002","allowed_types":["monitoring"]}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject misspelled fields instead of ignoring them",
+		},
+		{
+			name:           "Update Client",
+			method:         http.MethodPut,
+			path:           "/v1/admin/clients/client-001",
+			body:           `{"allowedTypes":["monitoring","user_action"],"active":false}`,
+			expectedStatus: http.StatusOK,
+			assertStored: func(t *testing.T, repo *persistence.MemoryRepository) {
+				stored, err := repo.GetClientConfig(context.Background(), "client-001")
+				require.NoError(t, err)
+				assert.Equal(t, []models.EventType{models.EventTypeMonitoring, models.EventTypeUserAction}, stored.AllowedTypes)
+				assert.False(t, stored.Active)
+			},
+			description: "Should replace the configuration",
+		},
+		{
+			name:           "Update With Mismatched ID",
+			method:         http.MethodPut,
+			path:           "/v1/admin/clients/client-001",
+			body:           `{"clientId":"client-002","allowedTypes":["monitoring"]}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject bodies for another client",
+		},
+		{
+			name:           "Disable Client",
+			method:         http.MethodPost,
+			path:           "/v1/admin/clients/client-001/disable",
+			expectedStatus: http.StatusOK,
+			assertBody: func(t *testing.T, body map[string]interface{}) {
+				assert.Equal(t, false, body["active"])
+			},
+			description: "Should disable the client",
+		},
+		{
+			name:           "Delete Client",
+			method:         http.MethodDelete,
+			path:           "/v1/admin/clients/client-001",
+			expectedStatus: http.StatusNoContent,
+			assertStored: func(t *testing.T, repo *persistence.MemoryRepository) {
+				_, err := repo.GetClientConfig(context.Background(), "client-001")
+				assert.ErrorIs(t, err, persistence.ErrClientConfigNotFound)
+			},
+			description: "Should delete the client",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := persistence.NewMemoryRepository(&models.ClientConfig{
+				ClientID:     "client-001",
+				AllowedTypes: []models.EventType{models.EventTypeMonitoring},
+				Active:       true,
+			})
+			mux := http.NewServeMux()
+			NewAdminHandler(clients.NewManager(repo, repo, logrus.New()), nil, testAdminToken, logrus.New()).Register(mux)
+
+			recorder := httptest.NewRecorder()
+			mux.ServeHTTP(recorder, adminRequest(tt))
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code, tt.description)
+			if tt.expectedStatus != http.StatusNoContent {
+				var body map[string]interface{}
+				assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+				if tt.assertBody != nil {
+					tt.assertBody(t, body)
+				}
+			}
+			if tt.assertStored != nil {
+				tt.assertStored(t, repo)
+			}
+		})
+	}
+}
+
+// TestAdminHandlerAudit tests that changes made over the API are recorded with their actor
+func TestAdminHandlerAudit(t *testing.T) {
+	repo := persistence.NewMemoryRepository()
+	mux := http.NewServeMux()
+	manager := clients.NewManager(repo, repo, logrus.New())
+	NewAdminHandler(manager, nil, testAdminToken, logrus.New()).Register(mux)
+
+	for _, tt := range []adminTestCase{
+		{method: http.MethodPost, path: "/v1/admin/clients", body: `{"clientId":"client-002","allowedTypes":["monitoring"]}`, actor: "alice"},
+		{method: http.MethodPost, path: "/v1/admin/clients/client-002/disable", actor: "bob"},
+	} {
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, adminRequest(tt))
+		require.Less(t, recorder.Code, http.StatusBadRequest, recorder.Body.String())
+	}
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, adminRequest(adminTestCase{method: http.MethodGet, path: "/v1/admin/clients/client-002/audit"}))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var body struct {
+		Entries []clients.AuditEntry `json:"entries"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	require.Len(t, body.Entries, 2)
+	assert.Equal(t, "alice", body.Entries[0].Actor)
+	assert.Equal(t, clients.ActionCreate, body.Entries[0].Action)
+	assert.Equal(t, "bob", body.Entries[1].Actor)
+	assert.Equal(t, clients.ActionDisable, body.Entries[1].Action)
+	assert.True(t, body.Entries[1].Before.Active)
+	assert.False(t, body.Entries[1].After.Active)
+}
+
+// TestAdminHandlerCache tests that the cache routes make changes made elsewhere visible
+func TestAdminHandlerCache(t *testing.T) {
+	ctx := context.Background()
+	storage := persistence.NewMemoryRepository(&models.ClientConfig{ClientID: "client-001", Active: true})
+	cache := clients.NewCachedRepository(storage, clients.CacheOptions{TTL: time.Hour})
+	mux := http.NewServeMux()
+	NewAdminHandler(clients.NewManager(cache, storage, logrus.New()), cache, testAdminToken, logrus.New()).Register(mux)
+
+	for _, path := range []string{"/v1/admin/cache/clients/client-001", "/v1/admin/cache/clients"} {
+		t.Run(path, func(t *testing.T) {
+			_, err := cache.GetClientConfig(ctx, "client-001")
+			require.NoError(t, err)
+
+			// Changed by another instance
+			require.NoError(t, storage.PutClientConfig(ctx, &models.ClientConfig{ClientID: "client-001", Active: false}))
+			cached, err := cache.GetClientConfig(ctx, "client-001")
+			require.NoError(t, err)
+			require.True(t, cached.Active)
+
+			recorder := httptest.NewRecorder()
+			mux.ServeHTTP(recorder, adminRequest(adminTestCase{method: http.MethodDelete, path: path}))
+			require.Equal(t, http.StatusNoContent, recorder.Code)
+
+			fresh, err := cache.GetClientConfig(ctx, "client-001")
+			require.NoError(t, err)
+			assert.False(t, fresh.Active)
+
+			require.NoError(t, storage.PutClientConfig(ctx, &models.ClientConfig{ClientID: "client-001", Active: true}))
+			cache.InvalidateAll()
+		})
+	}
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, adminRequest(adminTestCase{method: http.MethodDelete, path: "/v1/admin/cache/clients", token: "guess"}))
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+// adminRequest builds the request of a test case; token and actor default to valid values and "-" omits them
+func adminRequest(tt adminTestCase) *http.Request {
+	req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+
+	token := tt.token
+	if token == "" {
+		token = testAdminToken
+	}
+	if token != "-" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	actor := tt.actor
+	if actor == "" {
+		actor = "test-admin"
+	}
+	if actor != "-" {
+		req.Header.Set(ActorHeader, actor)
+	}
+	return req
+}
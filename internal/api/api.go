@@ -0,0 +1,218 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/d-sense/event-processor/internal/persistence"
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// Handler serves the REST API over stored events
+type Handler struct {
+	repository persistence.Repository
+	logger     *logrus.Logger
+}
+
+// New creates a new API handler
+func New(repo persistence.Repository, logger *logrus.Logger) *Handler {
+	return &Handler{
+		repository: repo,
+		logger:     logger,
+	}
+}
+
+// Register registers the API routes on the given mux
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /v1/events/{id}", h.getEvent)
+	mux.HandleFunc("GET /v1/events", h.listEventsByStatus)
+	mux.HandleFunc("GET /v1/clients/{id}/events", h.listEventsByClient)
+}
+
+// errorResponse is the JSON body returned for failed requests
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// getEvent handles GET /v1/events/{id}
+func (h *Handler) getEvent(w http.ResponseWriter, r *http.Request) {
+	fields := parseFields(r)
+
+	event, err := h.repository.GetEvent(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, persistence.ErrEventNotFound) {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		h.logger.WithError(err).Error("Failed to get event")
+		writeError(w, http.StatusInternalServerError, errors.New("failed to get event"))
+		return
+	}
+
+	body, err := selectFields(event, fields)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+// listEventsByStatus handles GET /v1/events?status=...
+func (h *Handler) listEventsByStatus(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		writeError(w, http.StatusBadRequest, errors.New("status query parameter is required"))
+		return
+	}
+	if !models.IsValidEventStatus(status) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid status: %s", status))
+		return
+	}
+
+	opts, err := parseListOptions(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	page, err := h.repository.ListEventsByStatus(r.Context(), models.EventStatus(status), opts)
+	h.writePage(w, r, page, err)
+}
+
+// listEventsByClient handles GET /v1/clients/{id}/events
+func (h *Handler) listEventsByClient(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseListOptions(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	page, err := h.repository.ListEventsByClient(r.Context(), r.PathValue("id"), opts)
+	h.writePage(w, r, page, err)
+}
+
+// writePage writes a page of events, applying field selection to each event
+func (h *Handler) writePage(w http.ResponseWriter, r *http.Request, page *persistence.EventPage, err error) {
+	if err != nil {
+		if errors.Is(err, persistence.ErrInvalidCursor) {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		h.logger.WithError(err).Error("Failed to list events")
+		writeError(w, http.StatusInternalServerError, errors.New("failed to list events"))
+		return
+	}
+
+	fields := parseFields(r)
+	events := make([]interface{}, 0, len(page.Events))
+	for _, event := range page.Events {
+		body, err := selectFields(event, fields)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		events = append(events, body)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"events":     events,
+		"nextCursor": page.NextCursor,
+	})
+}
+
+// parseListOptions reads limit, cursor, from and to query parameters
+func parseListOptions(r *http.Request) (persistence.ListOptions, error) {
+	query := r.URL.Query()
+	opts := persistence.ListOptions{
+		Cursor: query.Get("cursor"),
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value <= 0 {
+			return opts, fmt.Errorf("invalid limit: %s", limit)
+		}
+		opts.Limit = value
+	}
+
+	var err error
+	if opts.From, err = parseTime(query.Get("from")); err != nil {
+		return opts, fmt.Errorf("invalid from: %w", err)
+	}
+	if opts.To, err = parseTime(query.Get("to")); err != nil {
+		return opts, fmt.Errorf("invalid to: %w", err)
+	}
+	if !opts.From.IsZero() && !opts.To.IsZero() && opts.To.Before(opts.From) {
+		return opts, errors.New("to must not be before from")
+	}
+
+	return opts, nil
+}
+
+// parseTime parses an optional RFC 3339 timestamp
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// parseFields reads the comma separated fields query parameter
+func parseFields(r *http.Request) []string {
+	value := r.URL.Query().Get("fields")
+	if value == "" {
+		return nil
+	}
+
+	var fields []string
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// selectFields returns the event restricted to the requested top-level JSON fields
+func selectFields(event *models.ProcessedEvent, fields []string) (interface{}, error) {
+	if len(fields) == 0 {
+		return event, nil
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	var all map[string]interface{}
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal event: %w", err)
+	}
+
+	selected := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		if value, ok := all[field]; ok {
+			selected[field] = value
+		}
+	}
+	return selected, nil
+}
+
+// writeJSON writes body as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
@@ -0,0 +1,276 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/d-sense/event-processor/internal/persistence"
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// MockRepository is a mock implementation of the Repository interface
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) SaveEvent(ctx context.Context, event *models.ProcessedEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetEvent(ctx context.Context, eventID string) (*models.ProcessedEvent, error) {
+	args := m.Called(ctx, eventID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ProcessedEvent), args.Error(1)
+}
+
+func (m *MockRepository) ListEventsByClient(ctx context.Context, clientID string, opts persistence.ListOptions) (*persistence.EventPage, error) {
+	args := m.Called(ctx, clientID, opts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*persistence.EventPage), args.Error(1)
+}
+
+func (m *MockRepository) ListEventsByStatus(ctx context.Context, status models.EventStatus, opts persistence.ListOptions) (*persistence.EventPage, error) {
+	args := m.Called(ctx, status, opts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*persistence.EventPage), args.Error(1)
+}
+
+func (m *MockRepository) UpdateEventStatus(ctx context.Context, eventID string, update persistence.StatusUpdate) (*models.ProcessedEvent, error) {
+	args := m.Called(ctx, eventID, update)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ProcessedEvent), args.Error(1)
+}
+
+func (m *MockRepository) GetClientConfig(ctx context.Context, clientID string) (*models.ClientConfig, error) {
+	args := m.Called(ctx, clientID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ClientConfig), args.Error(1)
+}
+
+func (m *MockRepository) PutClientConfig(ctx context.Context, config *models.ClientConfig) error {
+	args := m.Called(ctx, config)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CreateClientConfig(ctx context.Context, config *models.ClientConfig) error {
+	args := m.Called(ctx, config)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListClientConfigs(ctx context.Context) ([]*models.ClientConfig, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.ClientConfig), args.Error(1)
+}
+
+func (m *MockRepository) DeleteClientConfig(ctx context.Context, clientID string) error {
+	args := m.Called(ctx, clientID)
+	return args.Error(0)
+}
+
+func (m *MockRepository) HealthCheck(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+// Test data structures
+type apiTestCase struct {
+	name           string
+	path           string
+	mockRepository func(*MockRepository)
+	expectedStatus int
+	assertBody     func(*testing.T, map[string]interface{})
+	description    string
+}
+
+// TestHandler tests the API routes
+func TestHandler(t *testing.T) {
+	event := createStoredEvent()
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []apiTestCase{
+		{
+			name: "Get Event",
+			path: "/v1/events/" + event.EventID,
+			mockRepository: func(mr *MockRepository) {
+				mr.On("GetEvent", mock.Anything, event.EventID).Return(event, nil)
+			},
+			expectedStatus: http.StatusOK,
+			assertBody: func(t *testing.T, body map[string]interface{}) {
+				assert.Equal(t, event.EventID, body["eventId"])
+				assert.Equal(t, "processed", body["status"])
+				assert.Contains(t, body, "payload")
+			},
+			description: "Should return the full stored event",
+		},
+		{
+			name: "Get Event With Field Selection",
+			path: "/v1/events/" + event.EventID + "?fields=eventId,status",
+			mockRepository: func(mr *MockRepository) {
+				mr.On("GetEvent", mock.Anything, event.EventID).Return(event, nil)
+			},
+			expectedStatus: http.StatusOK,
+			assertBody: func(t *testing.T, body map[string]interface{}) {
+				assert.Len(t, body, 2)
+				assert.Equal(t, event.EventID, body["eventId"])
+				assert.Equal(t, "processed", body["status"])
+			},
+			description: "Should only return the selected fields",
+		},
+		{
+			name: "Get Missing Event",
+			path: "/v1/events/missing",
+			mockRepository: func(mr *MockRepository) {
+				mr.On("GetEvent", mock.Anything, "missing").Return(nil, fmt.Errorf("%w: missing", persistence.ErrEventNotFound))
+			},
+			expectedStatus: http.StatusNotFound,
+			description:    "Should return 404 when the event does not exist",
+		},
+		{
+			name: "Get Event Repository Failure",
+			path: "/v1/events/" + event.EventID,
+			mockRepository: func(mr *MockRepository) {
+				mr.On("GetEvent", mock.Anything, event.EventID).Return(nil, errors.New("dynamodb error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			assertBody: func(t *testing.T, body map[string]interface{}) {
+				assert.Equal(t, "failed to get event", body["error"])
+			},
+			description: "Should hide repository errors behind a generic message",
+		},
+		{
+			name: "List Events By Client",
+			path: "/v1/clients/client-001/events?limit=1&from=2025-01-01T00:00:00Z&fields=eventId",
+			mockRepository: func(mr *MockRepository) {
+				mr.On("ListEventsByClient", mock.Anything, "client-001", persistence.ListOptions{Limit: 1, From: from}).
+					Return(&persistence.EventPage{Events: []*models.ProcessedEvent{event}, NextCursor: "next"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			assertBody: func(t *testing.T, body map[string]interface{}) {
+				assert.Equal(t, "next", body["nextCursor"])
+				events := body["events"].([]interface{})
+				assert.Len(t, events, 1)
+				assert.Equal(t, map[string]interface{}{"eventId": event.EventID}, events[0])
+			},
+			description: "Should pass pagination and time range options to the repository",
+		},
+		{
+			name: "List Events By Client Invalid Cursor",
+			path: "/v1/clients/client-001/events?cursor=bad",
+			mockRepository: func(mr *MockRepository) {
+				mr.On("ListEventsByClient", mock.Anything, "client-001", persistence.ListOptions{Cursor: "bad"}).
+					Return(nil, persistence.ErrInvalidCursor)
+			},
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should return 400 for an invalid cursor",
+		},
+		{
+			name:           "List Events By Client Invalid Limit",
+			path:           "/v1/clients/client-001/events?limit=abc",
+			mockRepository: func(mr *MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject non-numeric limits",
+		},
+		{
+			name:           "List Events By Client Inverted Range",
+			path:           "/v1/clients/client-001/events?from=2025-02-01T00:00:00Z&to=2025-01-01T00:00:00Z",
+			mockRepository: func(mr *MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject a time range that ends before it starts",
+		},
+		{
+			name: "List Events By Status",
+			path: "/v1/events?status=failed",
+			mockRepository: func(mr *MockRepository) {
+				mr.On("ListEventsByStatus", mock.Anything, models.EventStatusFailed, persistence.ListOptions{}).
+					Return(&persistence.EventPage{Events: []*models.ProcessedEvent{}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			assertBody: func(t *testing.T, body map[string]interface{}) {
+				assert.Empty(t, body["events"])
+				assert.Equal(t, "", body["nextCursor"])
+			},
+			description: "Should list events with the requested status",
+		},
+		{
+			name:           "List Events Missing Status",
+			path:           "/v1/events",
+			mockRepository: func(mr *MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should require the status query parameter",
+		},
+		{
+			name:           "List Events Invalid Status",
+			path:           "/v1/events?status=unknown",
+			mockRepository: func(mr *MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject unknown statuses",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepository := &MockRepository{}
+			tt.mockRepository(mockRepository)
+
+			mux := http.NewServeMux()
+			New(mockRepository, logrus.New()).Register(mux)
+
+			recorder := httptest.NewRecorder()
+			mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+			var body map[string]interface{}
+			assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+			if tt.assertBody != nil {
+				tt.assertBody(t, body)
+			}
+
+			mockRepository.AssertExpectations(t)
+		})
+	}
+}
+
+// Helper functions to create test data
+
+func createStoredEvent() *models.ProcessedEvent {
+	return &models.ProcessedEvent{
+		Event: models.Event{
+			EventID:   "123e4567-e89b-12d3-a456-426614174000",
+			EventType: models.EventTypeMonitoring,
+			ClientID:  "client-001",
+			Timestamp: time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC),
+			Payload: map[string]interface{}{
+				"severity": "high",
+			},
+			Version: "1.0",
+		},
+		ProcessedAt: time.Date(2025, 1, 15, 10, 0, 1, 0, time.UTC),
+		Status:      models.EventStatusProcessed,
+	}
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/d-sense/event-processor/internal/dlq"
+	"github.com/d-sense/event-processor/internal/persistence"
+)
+
+// DLQHandler serves the API over the dead letter queue: peeking at and grouping dead lettered
+// messages, redriving them to the event queue and purging them
+type DLQHandler struct {
+	manager *dlq.Manager
+	token   string
+	logger  *logrus.Logger
+}
+
+// purgeRequest is the JSON body of a purge
+type purgeRequest struct {
+	Filter dlq.Filter `json:"filter"`
+
+	// All must be set to purge with an empty filter
+	All bool `json:"all"`
+}
+
+// NewDLQHandler creates a DLQ handler; requests must carry token as a bearer token
+func NewDLQHandler(manager *dlq.Manager, token string, logger *logrus.Logger) *DLQHandler {
+	return &DLQHandler{
+		manager: manager,
+		token:   token,
+		logger:  logger,
+	}
+}
+
+// Register registers the DLQ routes on the given mux
+func (h *DLQHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /v1/admin/dlq/messages", requireAdminToken(h.token, h.peek))
+	mux.HandleFunc("GET /v1/admin/dlq/groups", requireAdminToken(h.token, h.group))
+	mux.HandleFunc("POST /v1/admin/dlq/redrive", requireAdminToken(h.token, h.redrive))
+	mux.HandleFunc("POST /v1/admin/dlq/purge", requireAdminToken(h.token, h.purge))
+}
+
+// peek handles GET /v1/admin/dlq/messages?clientId=...&eventType=...&reason=...&category=...&messageId=...&limit=...
+func (h *DLQHandler) peek(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseDLQFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if filter.Limit == 0 {
+		filter.Limit = persistence.DefaultPageSize
+	}
+
+	messages, err := h.manager.Peek(r.Context(), filter)
+	if err != nil {
+		h.writeQueueError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"messages": messages})
+}
+
+// group handles GET /v1/admin/dlq/groups?clientId=...&eventType=...&reason=...&category=...
+func (h *DLQHandler) group(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseDLQFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	groups, err := h.manager.Group(r.Context(), filter)
+	if err != nil {
+		h.writeQueueError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"groups": groups})
+}
+
+// redrive handles POST /v1/admin/dlq/redrive; the response is sent once every selected message has
+// been redriven, so large redrives should be limited or paced from the CLI
+func (h *DLQHandler) redrive(w http.ResponseWriter, r *http.Request) {
+	var options dlq.RedriveOptions
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAdminBodyBytes)).Decode(&options); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid redrive: %w", err))
+		return
+	}
+	if options.RatePerSecond < 0 {
+		writeError(w, http.StatusBadRequest, errors.New("invalid redrive: ratePerSecond must not be negative"))
+		return
+	}
+	if len(options.Patch) > 0 {
+		if err := dlq.ValidatePatch(options.Patch); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid redrive: %w", err))
+			return
+		}
+	}
+
+	result, err := h.manager.Redrive(r.Context(), options)
+	if err != nil {
+		h.writeQueueError(w, err)
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"actor":     r.Header.Get(ActorHeader),
+		"matched":   result.Matched,
+		"redriven":  result.Succeeded,
+		"failed":    len(result.Failures),
+		"patched":   len(options.Patch) > 0,
+		"client_id": options.Filter.ClientID,
+	}).Info("Dead lettered messages redriven")
+	writeJSON(w, http.StatusOK, result)
+}
+
+// purge handles POST /v1/admin/dlq/purge
+func (h *DLQHandler) purge(w http.ResponseWriter, r *http.Request) {
+	var request purgeRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAdminBodyBytes)).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid purge: %w", err))
+		return
+	}
+	if request.Filter.IsEmpty() && !request.All {
+		writeError(w, http.StatusBadRequest, errors.New("invalid purge: set a filter, or all to purge every message"))
+		return
+	}
+
+	result, err := h.manager.Purge(r.Context(), request.Filter)
+	if err != nil {
+		h.writeQueueError(w, err)
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"actor":     r.Header.Get(ActorHeader),
+		"purged":    result.Succeeded,
+		"failed":    len(result.Failures),
+		"client_id": request.Filter.ClientID,
+	}).Info("Dead lettered messages purged")
+	writeJSON(w, http.StatusOK, result)
+}
+
+// writeQueueError reports a failure to read the dead letter queue
+func (h *DLQHandler) writeQueueError(w http.ResponseWriter, err error) {
+	h.logger.WithError(err).Error("Failed to access the dead letter queue")
+	writeError(w, http.StatusInternalServerError, errors.New("failed to access the dead letter queue"))
+}
+
+// parseDLQFilter reads a filter from query parameters; messageId may be repeated
+func parseDLQFilter(query url.Values) (dlq.Filter, error) {
+	filter := dlq.Filter{
+		ClientID:   query.Get("clientId"),
+		EventType:  query.Get("eventType"),
+		Reason:     query.Get("reason"),
+		Category:   query.Get("category"),
+		MessageIDs: query["messageId"],
+	}
+	if limit := query.Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value <= 0 {
+			return dlq.Filter{}, fmt.Errorf("invalid limit: %s", limit)
+		}
+		filter.Limit = value
+	}
+	return filter, nil
+}
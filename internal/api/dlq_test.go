@@ -0,0 +1,211 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d-sense/event-processor/internal/dlq"
+	"github.com/d-sense/event-processor/internal/queue"
+)
+
+const (
+	testEventQueueURL = "memory://event-queue"
+	testDLQURL        = "memory://event-dlq"
+)
+
+type dlqTestCase struct {
+	name           string
+	method         string
+	path           string
+	body           string
+	token          string
+	expectedStatus int
+	assertBody     func(*testing.T, map[string]interface{})
+	assertQueues   func(*testing.T, *queue.MemoryQueue)
+	description    string
+}
+
+// TestDLQHandler tests the dead letter queue routes
+func TestDLQHandler(t *testing.T) {
+	tests := []dlqTestCase{
+		{
+			name:           "Peek Messages",
+			method:         http.MethodGet,
+			path:           "/v1/admin/dlq/messages?clientId=client-001",
+			expectedStatus: http.StatusOK,
+			assertBody: func(t *testing.T, body map[string]interface{}) {
+				messages := body["messages"].([]interface{})
+				require.Len(t, messages, 2)
+				message := messages[0].(map[string]interface{})
+				assert.Equal(t, "orig-1", message["originalMessageId"])
+				assert.Equal(t, "Max retries exceeded", message["failureReason"])
+				assert.Equal(t, `{"eventId":"evt-1","clientId":"client-001"}`, message["body"])
+			},
+			assertQueues: func(t *testing.T, q *queue.MemoryQueue) {
+				assert.Equal(t, 3, q.Len(testDLQURL))
+			},
+			description: "Should return the matching messages and leave them in the DLQ",
+		},
+		{
+			name:           "Peek By Message ID",
+			method:         http.MethodGet,
+			path:           "/v1/admin/dlq/messages?messageId=orig-2&messageId=orig-3",
+			expectedStatus: http.StatusOK,
+			assertBody: func(t *testing.T, body map[string]interface{}) {
+				assert.Len(t, body["messages"], 2)
+			},
+			description: "Should accept several message IDs",
+		},
+		{
+			name:           "Peek With Invalid Limit",
+			method:         http.MethodGet,
+			path:           "/v1/admin/dlq/messages?limit=-1",
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject limits below one",
+		},
+		{
+			name:           "Wrong Token",
+			method:         http.MethodGet,
+			path:           "/v1/admin/dlq/messages",
+			token:          "guess",
+			expectedStatus: http.StatusUnauthorized,
+			description:    "Should reject requests without the admin token",
+		},
+		{
+			name:           "Group Messages",
+			method:         http.MethodGet,
+			path:           "/v1/admin/dlq/groups",
+			expectedStatus: http.StatusOK,
+			assertBody: func(t *testing.T, body map[string]interface{}) {
+				groups := body["groups"].([]interface{})
+				require.Len(t, groups, 2)
+				assert.Equal(t, map[string]interface{}{"failureReason": "Max retries exceeded", "clientId": "client-001", "count": 2.0}, groups[0])
+			},
+			description: "Should count messages by failure reason and client",
+		},
+		{
+			name:           "Redrive With Patch",
+			method:         http.MethodPost,
+			path:           "/v1/admin/dlq/redrive",
+			body:           `{"filter":{"clientId":"client-001","limit":1},"patch":{"version":"1.0"},"ratePerSecond":5}`,
+			expectedStatus: http.StatusOK,
+			assertBody: func(t *testing.T, body map[string]interface{}) {
+				assert.Equal(t, 1.0, body["matched"])
+				assert.Equal(t, 1.0, body["succeeded"])
+			},
+			assertQueues: func(t *testing.T, q *queue.MemoryQueue) {
+				assert.Equal(t, 2, q.Len(testDLQURL))
+				output, err := q.ReceiveMessage(context.Background(), &sqs.ReceiveMessageInput{QueueUrl: aws.String(testEventQueueURL)})
+				require.NoError(t, err)
+				require.Len(t, output.Messages, 1)
+				assert.JSONEq(t, `{"eventId":"evt-1","clientId":"client-001","version":"1.0"}`, aws.ToString(output.Messages[0].Body))
+			},
+			description: "Should send the patched messages back to the event queue",
+		},
+		{
+			name:           "Redrive Invalid Patch",
+			method:         http.MethodPost,
+			path:           "/v1/admin/dlq/redrive",
+			body:           `{"patch":"{oops"}`,
+			expectedStatus: http.StatusBadRequest,
+			assertQueues: func(t *testing.T, q *queue.MemoryQueue) {
+				assert.Equal(t, 3, q.Len(testDLQURL))
+			},
+			description: "Should reject patches that are not objects before redriving anything",
+		},
+		{
+			name:           "Redrive Negative Rate",
+			method:         http.MethodPost,
+			path:           "/v1/admin/dlq/redrive",
+			body:           `{"ratePerSecond":-1}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject negative rates",
+		},
+		{
+			name:           "Purge By Filter",
+			method:         http.MethodPost,
+			path:           "/v1/admin/dlq/purge",
+			body:           `{"filter":{"clientId":"client-002"}}`,
+			expectedStatus: http.StatusOK,
+			assertBody: func(t *testing.T, body map[string]interface{}) {
+				assert.Equal(t, 1.0, body["succeeded"])
+			},
+			assertQueues: func(t *testing.T, q *queue.MemoryQueue) {
+				assert.Equal(t, 2, q.Len(testDLQURL))
+				assert.Equal(t, 0, q.Len(testEventQueueURL))
+			},
+			description: "Should delete the matching messages",
+		},
+		{
+			name:           "Purge Without Filter",
+			method:         http.MethodPost,
+			path:           "/v1/admin/dlq/purge",
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+			assertQueues: func(t *testing.T, q *queue.MemoryQueue) {
+				assert.Equal(t, 3, q.Len(testDLQURL))
+			},
+			description: "Should refuse to purge everything by accident",
+		},
+		{
+			name:           "Purge All",
+			method:         http.MethodPost,
+			path:           "/v1/admin/dlq/purge",
+			body:           `{"all":true}`,
+			expectedStatus: http.StatusOK,
+			assertQueues: func(t *testing.T, q *queue.MemoryQueue) {
+				assert.Equal(t, 0, q.Len(testDLQURL))
+			},
+			description: "Should purge every message when asked to",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := queue.NewMemoryQueue(testEventQueueURL)
+			for _, message := range []struct{ originalID, clientID, body string }{
+				{"orig-1", "client-001", `{"eventId":"evt-1","clientId":"client-001"}`},
+				{"orig-2", "client-001", `{"eventId":"evt-2","clientId":"client-001"}`},
+				{"orig-3", "client-002", `{"eventId":"evt-3","clientId":"client-002"}`},
+			} {
+				_, err := q.SendMessage(context.Background(), &sqs.SendMessageInput{
+					QueueUrl:    aws.String(testDLQURL),
+					MessageBody: aws.String(message.body),
+					MessageAttributes: map[string]types.MessageAttributeValue{
+						dlq.AttributeOriginalMessageID: {DataType: aws.String("String"), StringValue: aws.String(message.originalID)},
+						dlq.AttributeFailureReason:     {DataType: aws.String("String"), StringValue: aws.String("Max retries exceeded")},
+						dlq.AttributeClientID:          {DataType: aws.String("String"), StringValue: aws.String(message.clientID)},
+					},
+				})
+				require.NoError(t, err)
+			}
+
+			mux := http.NewServeMux()
+			manager := dlq.NewManager(q, testDLQURL, testEventQueueURL, dlq.Options{}, logrus.New())
+			NewDLQHandler(manager, testAdminToken, logrus.New()).Register(mux)
+
+			recorder := httptest.NewRecorder()
+			mux.ServeHTTP(recorder, adminRequest(adminTestCase{method: tt.method, path: tt.path, body: tt.body, token: tt.token}))
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code, tt.description)
+			var body map[string]interface{}
+			assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+			if tt.assertBody != nil {
+				tt.assertBody(t, body)
+			}
+			if tt.assertQueues != nil {
+				tt.assertQueues(t, q)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/d-sense/event-processor/internal/validator"
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// maxPublishBodyBytes bounds the size of a published event, matching the SQS message size limit
+const maxPublishBodyBytes = 256 * 1024
+
+// Publisher enqueues events for processing
+type Publisher interface {
+	Publish(ctx context.Context, event *models.Event) (string, error)
+}
+
+// Validator validates events before they are enqueued, e.g. validator.Validator
+type Validator interface {
+	ValidateAndParseEvent(eventData interface{}) (*models.Event, error)
+}
+
+// PublishHandler accepts events over HTTP and enqueues them, standing in for SQS in development
+type PublishHandler struct {
+	publisher Publisher
+	validator Validator
+	logger    *logrus.Logger
+}
+
+// publishResponse is the JSON body returned for an accepted event
+type publishResponse struct {
+	MessageID string `json:"messageId"`
+}
+
+// invalidEventResponse is the JSON body returned for an event that fails validation; Validation
+// lists the schema rules it breaks, if it was rejected by a schema
+type invalidEventResponse struct {
+	Error      string                    `json:"error"`
+	Validation *models.ValidationFailure `json:"validation,omitempty"`
+}
+
+// NewPublishHandler creates a new publish handler; events are rejected unless they pass the
+// validator, and a nil validator leaves validation to the consumer
+func NewPublishHandler(publisher Publisher, validator Validator, logger *logrus.Logger) *PublishHandler {
+	return &PublishHandler{
+		publisher: publisher,
+		validator: validator,
+		logger:    logger,
+	}
+}
+
+// Register registers the publish route on the given mux
+func (h *PublishHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("POST /v1/events", h.publish)
+}
+
+// publish handles POST /v1/events
+func (h *PublishHandler) publish(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPublishBodyBytes))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid event: %w", err))
+		return
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	// Keep numbers exact; the validated event is only used to reject the body
+	decoder.UseNumber()
+
+	var event models.Event
+	if err := decoder.Decode(&event); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid event: %w", err))
+		return
+	}
+
+	if h.validator != nil {
+		if _, err := h.validator.ValidateAndParseEvent(body); err != nil {
+			writeJSON(w, http.StatusBadRequest, invalidEventResponse{
+				Error:      fmt.Sprintf("invalid event: %v", err),
+				Validation: validator.Failure(err),
+			})
+			return
+		}
+	}
+
+	messageID, err := h.publisher.Publish(r.Context(), &event)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to publish event")
+		writeError(w, http.StatusInternalServerError, errors.New("failed to publish event"))
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, publishResponse{MessageID: messageID})
+}
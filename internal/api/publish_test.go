@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d-sense/event-processor/internal/validator"
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// MockPublisher is a mock implementation of the Publisher interface
+type MockPublisher struct {
+	mock.Mock
+}
+
+func (m *MockPublisher) Publish(ctx context.Context, event *models.Event) (string, error) {
+	args := m.Called(ctx, event)
+	return args.String(0), args.Error(1)
+}
+
+// TestPublishHandler tests the POST /v1/events route
+func TestPublishHandler(t *testing.T) {
+	schemas, err := validator.New("../../schemas")
+	require.NoError(t, err)
+	fingerprint := schemas.Status().Fingerprint
+
+	tests := []struct {
+		name           string
+		body           string
+		validate       bool
+		mockPublisher  func(*MockPublisher)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "Event Accepted",
+			body: `{"eventId":"evt-1","eventType":"monitoring","clientId":"client-001","version":"1.0","payload":{"id":12345678901234567890}}`,
+			mockPublisher: func(mp *MockPublisher) {
+				mp.On("Publish", mock.Anything, mock.MatchedBy(func(event *models.Event) bool {
+					return event.EventID == "evt-1" && event.Payload["id"] == json.Number("12345678901234567890")
+				})).Return("msg-1", nil)
+			},
+			expectedStatus: http.StatusAccepted,
+			expectedBody:   `{"messageId":"msg-1"}`,
+		},
+		{
+			name:           "Malformed JSON",
+			body:           `{"eventId":`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Publisher Error",
+			body: `{"eventId":"evt-1"}`,
+			mockPublisher: func(mp *MockPublisher) {
+				mp.On("Publish", mock.Anything, mock.Anything).Return("", errors.New("queue full"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"failed to publish event"}`,
+		},
+		{
+			name:     "Valid Event",
+			body:     `{"eventId":"123e4567-e89b-12d3-a456-426614174000","eventType":"transaction","clientId":"client-001","timestamp":"2025-01-21T10:00:00Z","version":"1.1","payload":{"transactionId":"txn-1","amount":10.5,"currency":"EUR"}}`,
+			validate: true,
+			mockPublisher: func(mp *MockPublisher) {
+				mp.On("Publish", mock.Anything, mock.Anything).Return("msg-1", nil)
+			},
+			expectedStatus: http.StatusAccepted,
+			expectedBody:   `{"messageId":"msg-1"}`,
+		},
+		{
+			name:           "Payload Rejected by Schema",
+			body:           `{"eventId":"123e4567-e89b-12d3-a456-426614174000","eventType":"transaction","clientId":"client-001","timestamp":"2025-01-21T10:00:00Z","version":"1.1","payload":{"transactionId":"txn-1","amount":"ten","currency":"eur"}}`,
+			validate:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{
+				"error": "invalid event: payload validation failed for version 1.1 of transaction events: [amount: Invalid type. Expected: number, given: string currency: Does not match pattern '^[A-Z]{3}$']",
+				"validation": {
+					"schema": "payload",
+					"eventType": "transaction",
+					"schemaVersion": "1.1",
+					"schemaFingerprint": "` + fingerprint + `",
+					"violations": [
+						{"field": "payload.amount", "rule": "invalid_type", "expected": "number", "actual": "string", "message": "amount: Invalid type. Expected: number, given: string"},
+						{"field": "payload.currency", "rule": "pattern", "expected": "^[A-Z]{3}$", "actual": "\"eur\"", "message": "currency: Does not match pattern '^[A-Z]{3}$'"}
+					]
+				}
+			}`,
+		},
+		{
+			name:           "Event Rejected by Business Rules",
+			body:           `{"eventId":"123e4567-e89b-12d3-a456-426614174000","eventType":"transaction","clientId":"client-001","timestamp":"0001-01-01T00:00:00Z","version":"1.1","payload":{"transactionId":"txn-1","amount":10.5,"currency":"EUR"}}`,
+			validate:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid event: timestamp cannot be zero"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockPublisher := &MockPublisher{}
+			if tt.mockPublisher != nil {
+				tt.mockPublisher(mockPublisher)
+			}
+			var eventValidator Validator
+			if tt.validate {
+				eventValidator = schemas
+			}
+
+			mux := http.NewServeMux()
+			NewPublishHandler(mockPublisher, eventValidator, logrus.New()).Register(mux)
+
+			recorder := httptest.NewRecorder()
+			mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/events", strings.NewReader(tt.body)))
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, recorder.Body.String())
+			}
+			mockPublisher.AssertExpectations(t)
+		})
+	}
+}
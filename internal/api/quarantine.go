@@ -0,0 +1,169 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/d-sense/event-processor/internal/persistence"
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// Sender enqueues raw event bodies for processing, e.g. queue.Sender
+type Sender interface {
+	Send(ctx context.Context, body string) (string, error)
+}
+
+// QuarantineHandler serves the API over quarantined events, which are rejected events kept for an
+// operator to fix and resubmit or discard
+type QuarantineHandler struct {
+	store  persistence.QuarantineStore
+	sender Sender
+	token  string
+	logger *logrus.Logger
+}
+
+// resubmitRequest is the optional JSON body of a resubmission
+type resubmitRequest struct {
+	// Event replaces the quarantined body when set
+	Event json.RawMessage `json:"event"`
+}
+
+// resubmitResponse is the JSON body returned for a resubmitted event
+type resubmitResponse struct {
+	QuarantineID string `json:"quarantineId"`
+	MessageID    string `json:"messageId"`
+}
+
+// NewQuarantineHandler creates a quarantine handler; requests must carry token as a bearer token and
+// resubmitted events are sent with sender
+func NewQuarantineHandler(store persistence.QuarantineStore, sender Sender, token string, logger *logrus.Logger) *QuarantineHandler {
+	return &QuarantineHandler{
+		store:  store,
+		sender: sender,
+		token:  token,
+		logger: logger,
+	}
+}
+
+// Register registers the quarantine routes on the given mux
+func (h *QuarantineHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /v1/admin/quarantine", requireAdminToken(h.token, h.listQuarantined))
+	mux.HandleFunc("GET /v1/admin/quarantine/{id}", requireAdminToken(h.token, h.getQuarantined))
+	mux.HandleFunc("POST /v1/admin/quarantine/{id}/resubmit", requireAdminToken(h.token, h.resubmit))
+	mux.HandleFunc("DELETE /v1/admin/quarantine/{id}", requireAdminToken(h.token, h.discard))
+}
+
+// listQuarantined handles GET /v1/admin/quarantine?clientId=...&category=...&limit=...
+func (h *QuarantineHandler) listQuarantined(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := persistence.QuarantineFilter{ClientID: query.Get("clientId")}
+
+	if category := query.Get("category"); category != "" {
+		if !models.IsValidQuarantineCategory(category) {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid category: %s", category))
+			return
+		}
+		filter.Category = models.QuarantineCategory(category)
+	}
+	if limit := query.Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit: %s", limit))
+			return
+		}
+		filter.Limit = value
+	}
+
+	events, err := h.store.ListQuarantinedEvents(r.Context(), filter)
+	if err != nil {
+		h.writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"events": events})
+}
+
+// getQuarantined handles GET /v1/admin/quarantine/{id}
+func (h *QuarantineHandler) getQuarantined(w http.ResponseWriter, r *http.Request) {
+	event, err := h.store.GetQuarantinedEvent(r.Context(), r.PathValue("id"))
+	if err != nil {
+		h.writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, event)
+}
+
+// resubmit handles POST /v1/admin/quarantine/{id}/resubmit. The event is sent back to the queue,
+// corrected when the request has an event, and removed from quarantine; if it is rejected again it
+// is quarantined under a new ID.
+func (h *QuarantineHandler) resubmit(w http.ResponseWriter, r *http.Request) {
+	var request resubmitRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPublishBodyBytes)).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid resubmission: %w", err))
+		return
+	}
+
+	event, err := h.store.GetQuarantinedEvent(r.Context(), r.PathValue("id"))
+	if err != nil {
+		h.writeStoreError(w, err)
+		return
+	}
+
+	body := event.Body
+	if len(request.Event) > 0 {
+		body = string(request.Event)
+	}
+
+	logger := h.logger.WithFields(logrus.Fields{
+		"actor":         r.Header.Get(ActorHeader),
+		"quarantine_id": event.QuarantineID,
+		"corrected":     len(request.Event) > 0,
+	})
+
+	messageID, err := h.sender.Send(r.Context(), body)
+	if err != nil {
+		logger.WithError(err).Error("Failed to resubmit quarantined event")
+		writeError(w, http.StatusInternalServerError, errors.New("failed to resubmit quarantined event"))
+		return
+	}
+
+	if err := h.store.DeleteQuarantinedEvent(r.Context(), event.QuarantineID); err != nil && !errors.Is(err, persistence.ErrQuarantinedEventNotFound) {
+		// The event is on its way, so only the stale quarantine record is left to clean up
+		logger.WithError(err).WithField("message_id", messageID).Error("Resubmitted event is still quarantined")
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("event was resubmitted as message %s but is still quarantined", messageID))
+		return
+	}
+
+	logger.WithField("message_id", messageID).Info("Quarantined event resubmitted")
+	writeJSON(w, http.StatusAccepted, resubmitResponse{QuarantineID: event.QuarantineID, MessageID: messageID})
+}
+
+// discard handles DELETE /v1/admin/quarantine/{id}
+func (h *QuarantineHandler) discard(w http.ResponseWriter, r *http.Request) {
+	if err := h.store.DeleteQuarantinedEvent(r.Context(), r.PathValue("id")); err != nil {
+		h.writeStoreError(w, err)
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"actor":         r.Header.Get(ActorHeader),
+		"quarantine_id": r.PathValue("id"),
+	}).Info("Quarantined event discarded")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeStoreError maps quarantine store errors to status codes
+func (h *QuarantineHandler) writeStoreError(w http.ResponseWriter, err error) {
+	if errors.Is(err, persistence.ErrQuarantinedEventNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	h.logger.WithError(err).Error("Failed to access quarantined events")
+	writeError(w, http.StatusInternalServerError, errors.New("failed to access quarantined events"))
+}
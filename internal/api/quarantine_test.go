@@ -0,0 +1,244 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d-sense/event-processor/internal/persistence"
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// MockSender is a mock implementation of the Sender interface
+type MockSender struct {
+	mock.Mock
+}
+
+func (m *MockSender) Send(ctx context.Context, body string) (string, error) {
+	args := m.Called(ctx, body)
+	return args.String(0), args.Error(1)
+}
+
+type quarantineTestCase struct {
+	name           string
+	method         string
+	path           string
+	body           string
+	token          string
+	mockSender     func(*MockSender)
+	expectedStatus int
+	assertBody     func(*testing.T, map[string]interface{})
+	assertStored   func(*testing.T, *persistence.MemoryRepository)
+	description    string
+}
+
+const quarantinedBody = `{"eventId":"evt-1","eventType":"transaction","clientId":"client-001","payload":{"transactionId":"txn-1"}}`
+
+// TestQuarantineHandler tests the quarantine routes
+func TestQuarantineHandler(t *testing.T) {
+	tests := []quarantineTestCase{
+		{
+			name:           "List Quarantined Events",
+			method:         http.MethodGet,
+			path:           "/v1/admin/quarantine",
+			expectedStatus: http.StatusOK,
+			assertBody: func(t *testing.T, body map[string]interface{}) {
+				events := body["events"].([]interface{})
+				require.Len(t, events, 2)
+				assert.Equal(t, "q-2", events[0].(map[string]interface{})["quarantineId"])
+			},
+			description: "Should list every quarantined event, most recent first",
+		},
+		{
+			name:           "List By Client And Category",
+			method:         http.MethodGet,
+			path:           "/v1/admin/quarantine?clientId=client-001&category=payload",
+			expectedStatus: http.StatusOK,
+			assertBody: func(t *testing.T, body map[string]interface{}) {
+				events := body["events"].([]interface{})
+				require.Len(t, events, 1)
+				assert.Equal(t, "q-1", events[0].(map[string]interface{})["quarantineId"])
+			},
+			description: "Should filter by client and category",
+		},
+		{
+			name:           "List With Invalid Category",
+			method:         http.MethodGet,
+			path:           "/v1/admin/quarantine?category=lost",
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject unknown categories",
+		},
+		{
+			name:           "List With Invalid Limit",
+			method:         http.MethodGet,
+			path:           "/v1/admin/quarantine?limit=0",
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject limits below one",
+		},
+		{
+			name:           "Wrong Token",
+			method:         http.MethodGet,
+			path:           "/v1/admin/quarantine",
+			token:          "guess",
+			expectedStatus: http.StatusUnauthorized,
+			description:    "Should reject requests without the admin token",
+		},
+		{
+			name:           "Inspect Quarantined Event",
+			method:         http.MethodGet,
+			path:           "/v1/admin/quarantine/q-1",
+			expectedStatus: http.StatusOK,
+			assertBody: func(t *testing.T, body map[string]interface{}) {
+				assert.Equal(t, "payload", body["category"])
+				assert.Equal(t, quarantinedBody, body["body"])
+				assert.Equal(t, []interface{}{"invalid payload: amount \"15k\" is not a number"}, body["errors"])
+			},
+			description: "Should return the raw body and the reasons for the rejection",
+		},
+		{
+			name:           "Inspect Missing Event",
+			method:         http.MethodGet,
+			path:           "/v1/admin/quarantine/q-404",
+			expectedStatus: http.StatusNotFound,
+			description:    "Should return 404 for unknown quarantine IDs",
+		},
+		{
+			name:   "Resubmit Unchanged",
+			method: http.MethodPost,
+			path:   "/v1/admin/quarantine/q-1/resubmit",
+			mockSender: func(ms *MockSender) {
+				ms.On("Send", mock.Anything, quarantinedBody).Return("msg-1", nil)
+			},
+			expectedStatus: http.StatusAccepted,
+			assertBody: func(t *testing.T, body map[string]interface{}) {
+				assert.Equal(t, "msg-1", body["messageId"])
+				assert.Equal(t, "q-1", body["quarantineId"])
+			},
+			assertStored: func(t *testing.T, repo *persistence.MemoryRepository) {
+				_, err := repo.GetQuarantinedEvent(context.Background(), "q-1")
+				assert.ErrorIs(t, err, persistence.ErrQuarantinedEventNotFound)
+			},
+			description: "Should send the original body back to the queue and release the event",
+		},
+		{
+			name:   "Resubmit Corrected Event",
+			method: http.MethodPost,
+			path:   "/v1/admin/quarantine/q-1/resubmit",
+			body:   `{"event":{"eventId":"evt-1","payload":{"transactionId":"txn-1","amount":10}}}`,
+			mockSender: func(ms *MockSender) {
+				ms.On("Send", mock.Anything, `{"eventId":"evt-1","payload":{"transactionId":"txn-1","amount":10}}`).Return("msg-2", nil)
+			},
+			expectedStatus: http.StatusAccepted,
+			description:    "Should send the corrected event instead of the quarantined body",
+		},
+		{
+			name:           "Resubmit Invalid Request",
+			method:         http.MethodPost,
+			path:           "/v1/admin/quarantine/q-1/resubmit",
+			body:           `{"event":`,
+			expectedStatus: http.StatusBadRequest,
+			assertStored: func(t *testing.T, repo *persistence.MemoryRepository) {
+				_, err := repo.GetQuarantinedEvent(context.Background(), "q-1")
+				assert.NoError(t, err)
+			},
+			description: "Should reject corrections that are not JSON and keep the event",
+		},
+		{
+			name:   "Resubmit Send Failure",
+			method: http.MethodPost,
+			path:   "/v1/admin/quarantine/q-1/resubmit",
+			mockSender: func(ms *MockSender) {
+				ms.On("Send", mock.Anything, quarantinedBody).Return("", errors.New("sqs error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			assertStored: func(t *testing.T, repo *persistence.MemoryRepository) {
+				_, err := repo.GetQuarantinedEvent(context.Background(), "q-1")
+				assert.NoError(t, err)
+			},
+			description: "Should keep events that could not be resubmitted",
+		},
+		{
+			name:           "Resubmit Missing Event",
+			method:         http.MethodPost,
+			path:           "/v1/admin/quarantine/q-404/resubmit",
+			expectedStatus: http.StatusNotFound,
+			description:    "Should return 404 for unknown quarantine IDs",
+		},
+		{
+			name:           "Discard Event",
+			method:         http.MethodDelete,
+			path:           "/v1/admin/quarantine/q-1",
+			expectedStatus: http.StatusNoContent,
+			assertStored: func(t *testing.T, repo *persistence.MemoryRepository) {
+				_, err := repo.GetQuarantinedEvent(context.Background(), "q-1")
+				assert.ErrorIs(t, err, persistence.ErrQuarantinedEventNotFound)
+			},
+			description: "Should delete the quarantined event",
+		},
+		{
+			name:           "Discard Missing Event",
+			method:         http.MethodDelete,
+			path:           "/v1/admin/quarantine/q-404",
+			expectedStatus: http.StatusNotFound,
+			description:    "Should return 404 for unknown quarantine IDs",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := persistence.NewMemoryRepository()
+			quarantinedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+			require.NoError(t, repo.QuarantineEvent(ctx, &models.QuarantinedEvent{
+				QuarantineID:  "q-1",
+				Category:      models.QuarantinePayload,
+				ClientID:      "client-001",
+				EventID:       "evt-1",
+				EventType:     models.EventTypeTransaction,
+				Errors:        []string{"invalid payload: amount \"15k\" is not a number"},
+				Body:          quarantinedBody,
+				QuarantinedAt: quarantinedAt,
+			}))
+			require.NoError(t, repo.QuarantineEvent(ctx, &models.QuarantinedEvent{
+				QuarantineID:  "q-2",
+				Category:      models.QuarantineValidation,
+				Errors:        []string{"validation failed: invalid JSON"},
+				Body:          "{not json",
+				QuarantinedAt: quarantinedAt.Add(time.Minute),
+			}))
+
+			mockSender := &MockSender{}
+			if tt.mockSender != nil {
+				tt.mockSender(mockSender)
+			}
+
+			mux := http.NewServeMux()
+			NewQuarantineHandler(repo, mockSender, testAdminToken, logrus.New()).Register(mux)
+
+			recorder := httptest.NewRecorder()
+			mux.ServeHTTP(recorder, adminRequest(adminTestCase{method: tt.method, path: tt.path, body: tt.body, token: tt.token}))
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code, tt.description)
+			if tt.expectedStatus != http.StatusNoContent {
+				var body map[string]interface{}
+				assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+				if tt.assertBody != nil {
+					tt.assertBody(t, body)
+				}
+			}
+			if tt.assertStored != nil {
+				tt.assertStored(t, repo)
+			}
+			mockSender.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/d-sense/event-processor/internal/replay"
+)
+
+// ReplayHandler serves the API for reprocessing stored events
+type ReplayHandler struct {
+	replayer *replay.Replayer
+	token    string
+	logger   *logrus.Logger
+}
+
+// NewReplayHandler creates a replay handler; requests must carry token as a bearer token
+func NewReplayHandler(replayer *replay.Replayer, token string, logger *logrus.Logger) *ReplayHandler {
+	return &ReplayHandler{
+		replayer: replayer,
+		token:    token,
+		logger:   logger,
+	}
+}
+
+// Register registers the replay routes on the given mux
+func (h *ReplayHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("POST /v1/admin/replay", requireAdminToken(h.token, h.replay))
+}
+
+// replay handles POST /v1/admin/replay; the response is sent once every selected event has been
+// reprocessed, so large replays should be limited or run from the CLI
+func (h *ReplayHandler) replay(w http.ResponseWriter, r *http.Request) {
+	var options replay.Options
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAdminBodyBytes)).Decode(&options); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid replay: %w", err))
+		return
+	}
+
+	result, err := h.replayer.Run(r.Context(), options)
+	if errors.Is(err, replay.ErrInvalidOptions) {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to replay events")
+		writeError(w, http.StatusInternalServerError, errors.New("failed to replay events"))
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"actor":     r.Header.Get(ActorHeader),
+		"run_id":    result.RunID,
+		"dry_run":   result.DryRun,
+		"matched":   result.Matched,
+		"changed":   result.Changed,
+		"failed":    len(result.Failures),
+		"client_id": options.Filter.ClientID,
+		"status":    string(options.Filter.Status),
+	}).Info("Stored events replayed")
+	writeJSON(w, http.StatusOK, result)
+}
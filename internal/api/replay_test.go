@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d-sense/event-processor/internal/persistence"
+	"github.com/d-sense/event-processor/internal/replay"
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// approvingReprocessor reprocesses every event successfully, marking it as fixed
+type approvingReprocessor struct{}
+
+func (approvingReprocessor) Reprocess(ctx context.Context, stored *models.ProcessedEvent) (*models.ProcessedEvent, error) {
+	outcome := *stored
+	outcome.Status = models.EventStatusProcessed
+	outcome.ErrorMsg = ""
+	outcome.Payload = map[string]interface{}{"fixed": true}
+	return &outcome, nil
+}
+
+// TestReplayHandler tests the replay route
+func TestReplayHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		token          string
+		expectedStatus int
+		assertBody     func(*testing.T, map[string]interface{})
+		assertStorage  func(*testing.T, *persistence.MemoryRepository)
+		description    string
+	}{
+		{
+			name:           "Dry Run",
+			body:           `{"filter":{"clientId":"client-001","status":"failed"},"dryRun":true}`,
+			expectedStatus: http.StatusOK,
+			assertBody: func(t *testing.T, body map[string]interface{}) {
+				assert.Equal(t, 1.0, body["matched"])
+				assert.Equal(t, true, body["dryRun"])
+				changes := body["changes"].([]interface{})
+				require.Len(t, changes, 1)
+				fields := changes[0].(map[string]interface{})["fields"].([]interface{})
+				assert.Contains(t, fields, map[string]interface{}{"field": "payload.fixed", "after": true})
+			},
+			assertStorage: func(t *testing.T, repo *persistence.MemoryRepository) {
+				event, err := repo.GetEvent(context.Background(), "evt-1")
+				require.NoError(t, err)
+				assert.Equal(t, models.EventStatusFailed, event.Status)
+			},
+			description: "Should return the changes without storing them",
+		},
+		{
+			name:           "Replay",
+			body:           `{"filter":{"status":"failed","from":"2024-03-01T00:00:00Z"},"ratePerSecond":50}`,
+			expectedStatus: http.StatusOK,
+			assertBody: func(t *testing.T, body map[string]interface{}) {
+				assert.Equal(t, 2.0, body["matched"])
+				assert.Equal(t, 2.0, body["changed"])
+			},
+			assertStorage: func(t *testing.T, repo *persistence.MemoryRepository) {
+				event, err := repo.GetEvent(context.Background(), "evt-2")
+				require.NoError(t, err)
+				assert.Equal(t, models.EventStatusProcessed, event.Status)
+				assert.Len(t, event.Replays, 1)
+			},
+			description: "Should reprocess the selected events",
+		},
+		{
+			name:           "Invalid Filter",
+			body:           `{"filter":{"eventType":"unknown"}}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject unknown event types",
+		},
+		{
+			name:           "Invalid Body",
+			body:           `{"filter":`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject malformed bodies",
+		},
+		{
+			name:           "Wrong Token",
+			body:           `{}`,
+			token:          "guess",
+			expectedStatus: http.StatusUnauthorized,
+			description:    "Should reject requests without the admin token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := persistence.NewMemoryRepository()
+			for i, stored := range []struct{ eventID, clientID string }{{"evt-1", "client-001"}, {"evt-2", "client-002"}} {
+				require.NoError(t, repo.SaveEvent(context.Background(), &models.ProcessedEvent{
+					Event: models.Event{
+						EventID:   stored.eventID,
+						EventType: models.EventTypeTransaction,
+						ClientID:  stored.clientID,
+						Timestamp: time.Date(2024, 3, 1, 12, i, 0, 0, time.UTC),
+						Payload:   map[string]interface{}{"amount": 10.0},
+					},
+					Status:   models.EventStatusFailed,
+					ErrorMsg: "handler bug",
+					Revision: 1,
+				}))
+			}
+
+			mux := http.NewServeMux()
+			NewReplayHandler(replay.NewReplayer(repo, approvingReprocessor{}, logrus.New()), testAdminToken, logrus.New()).Register(mux)
+
+			recorder := httptest.NewRecorder()
+			mux.ServeHTTP(recorder, adminRequest(adminTestCase{method: http.MethodPost, path: "/v1/admin/replay", body: tt.body, token: tt.token}))
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code, tt.description)
+			var body map[string]interface{}
+			assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+			if tt.assertBody != nil {
+				tt.assertBody(t, body)
+			}
+			if tt.assertStorage != nil {
+				tt.assertStorage(t, repo)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/d-sense/event-processor/internal/validator"
+)
+
+// SchemaHandler serves the API over the schemas events are validated against
+type SchemaHandler struct {
+	validator *validator.Validator
+	token     string
+	logger    *logrus.Logger
+}
+
+// NewSchemaHandler creates a schema handler; requests must carry token as a bearer token
+func NewSchemaHandler(validator *validator.Validator, token string, logger *logrus.Logger) *SchemaHandler {
+	return &SchemaHandler{
+		validator: validator,
+		token:     token,
+		logger:    logger,
+	}
+}
+
+// Register registers the schema routes on the given mux
+func (h *SchemaHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /v1/admin/schemas", requireAdminToken(h.token, h.status))
+	mux.HandleFunc("POST /v1/admin/schemas/reload", requireAdminToken(h.token, h.reload))
+}
+
+// status handles GET /v1/admin/schemas, listing the loaded schema versions
+func (h *SchemaHandler) status(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.validator.Status())
+}
+
+// reload handles POST /v1/admin/schemas/reload, loading the schema directory now instead of at the
+// next check. Schemas that do not compile are reported and the loaded ones are kept.
+func (h *SchemaHandler) reload(w http.ResponseWriter, r *http.Request) {
+	reloaded, err := h.validator.Reload()
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	status := h.validator.Status()
+	h.logger.WithFields(logrus.Fields{
+		"actor":       r.Header.Get(ActorHeader),
+		"reloaded":    reloaded,
+		"fingerprint": status.Fingerprint,
+	}).Info("Schemas reload requested")
+	writeJSON(w, http.StatusOK, map[string]interface{}{"reloaded": reloaded, "schemas": status})
+}
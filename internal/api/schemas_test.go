@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d-sense/event-processor/internal/validator"
+)
+
+// TestSchemaHandler tests the schema routes
+func TestSchemaHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		token          string
+		change         string
+		expectedStatus int
+		assertBody     func(*testing.T, map[string]interface{})
+		description    string
+	}{
+		{
+			name:           "Loaded Versions",
+			method:         http.MethodGet,
+			path:           "/v1/admin/schemas",
+			expectedStatus: http.StatusOK,
+			assertBody: func(t *testing.T, body map[string]interface{}) {
+				assert.NotEmpty(t, body["fingerprint"])
+				eventTypes := body["eventTypes"].([]interface{})
+				require.Len(t, eventTypes, 1)
+				assert.Equal(t, "transaction", eventTypes[0].(map[string]interface{})["eventType"])
+				assert.Equal(t, "1.1", eventTypes[0].(map[string]interface{})["currentVersion"])
+			},
+			description: "Should list the loaded schema versions",
+		},
+		{
+			name:           "Reload",
+			method:         http.MethodPost,
+			path:           "/v1/admin/schemas/reload",
+			change:         `{"required": ["userId"]}`,
+			expectedStatus: http.StatusOK,
+			assertBody: func(t *testing.T, body map[string]interface{}) {
+				assert.Equal(t, true, body["reloaded"])
+				eventTypes := body["schemas"].(map[string]interface{})["eventTypes"].([]interface{})
+				assert.Len(t, eventTypes, 2)
+			},
+			description: "Should load the changed schema directory",
+		},
+		{
+			name:           "Reload Fails",
+			method:         http.MethodPost,
+			path:           "/v1/admin/schemas/reload",
+			change:         `{"type": 12}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			assertBody: func(t *testing.T, body map[string]interface{}) {
+				assert.Contains(t, body["error"], "failed to compile payload schema 1.0 of user_action events")
+			},
+			description: "Should report schemas that do not compile",
+		},
+		{
+			name:           "Wrong Token",
+			method:         http.MethodGet,
+			path:           "/v1/admin/schemas",
+			token:          "guess",
+			expectedStatus: http.StatusUnauthorized,
+			description:    "Should reject requests without the admin token",
+		},
+	}
+
+	schema, err := os.ReadFile("../../schemas/event-schema.json")
+	require.NoError(t, err)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, validator.EventSchemaFile), schema, 0o644))
+			eventValidator, err := validator.New(dir)
+			require.NoError(t, err)
+			if tt.change != "" {
+				require.NoError(t, os.MkdirAll(filepath.Join(dir, validator.PayloadSchemaDir, "user_action"), 0o755))
+				require.NoError(t, os.WriteFile(filepath.Join(dir, validator.PayloadSchemaDir, "user_action", "1.0.json"), []byte(tt.change), 0o644))
+			}
+
+			mux := http.NewServeMux()
+			NewSchemaHandler(eventValidator, testAdminToken, logrus.New()).Register(mux)
+
+			recorder := httptest.NewRecorder()
+			mux.ServeHTTP(recorder, adminRequest(adminTestCase{method: tt.method, path: tt.path, token: tt.token}))
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code, tt.description)
+			var body map[string]interface{}
+			assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+			if tt.assertBody != nil {
+				tt.assertBody(t, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,461 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ManifestKey is the key of the single manifest written by earlier versions, which rewrote it
+// for every batch. It is still read, together with the manifest fragments.
+const ManifestKey = "manifest.json"
+
+// FragmentPrefix is the key prefix of the manifest fragments, one per archive file
+const FragmentPrefix = "manifest/"
+
+// fragmentKey returns the key of the manifest fragment of an archive file
+func fragmentKey(key string) string {
+	return FragmentPrefix + key + ".json"
+}
+
+// dateLayout is the layout of the date partition
+const dateLayout = "2006-01-02"
+
+// ManifestEntry describes one archive file
+type ManifestEntry struct {
+	Key           string    `json:"key"`
+	Format        string    `json:"format"`
+	Date          string    `json:"date"`
+	ClientID      string    `json:"clientId"`
+	EventType     string    `json:"eventType"`
+	Records       int       `json:"records"`
+	FirstSequence string    `json:"firstSequence"`
+	LastSequence  string    `json:"lastSequence"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// Manifest lists the archive files of a store, ordered by key
+type Manifest struct {
+	Files []ManifestEntry `json:"files"`
+}
+
+// put adds entry, replacing an earlier entry for the same file
+func (m *Manifest) put(entry ManifestEntry) {
+	i := sort.Search(len(m.Files), func(i int) bool { return m.Files[i].Key >= entry.Key })
+	if i < len(m.Files) && m.Files[i].Key == entry.Key {
+		m.Files[i] = entry
+		return
+	}
+	m.Files = append(m.Files, ManifestEntry{})
+	copy(m.Files[i+1:], m.Files[i:])
+	m.Files[i] = entry
+}
+
+// ReadManifest loads the manifest of store from its fragments and the manifest of earlier
+// versions; a store without either has an empty manifest
+func ReadManifest(ctx context.Context, store Store) (*Manifest, error) {
+	manifest := &Manifest{}
+	body, err := store.Get(ctx, ManifestKey)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(body, manifest); err != nil {
+			return nil, fmt.Errorf("invalid archive manifest: %w", err)
+		}
+	case !errors.Is(err, ErrNotFound):
+		return nil, err
+	}
+
+	keys, err := store.List(ctx, FragmentPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list manifest fragments: %w", err)
+	}
+	for _, key := range keys {
+		body, err := store.Get(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifest fragment %s: %w", key, err)
+		}
+		var entry ManifestEntry
+		if err := json.Unmarshal(body, &entry); err != nil {
+			return nil, fmt.Errorf("invalid manifest fragment %s: %w", key, err)
+		}
+		manifest.put(entry)
+	}
+	return manifest, nil
+}
+
+// Archiver writes removed events to date, client and event type partitioned files.
+//
+// Records are first appended to a spool file of their partition in a local directory, which keeps
+// them across restarts once Archive has returned. A spool file is rolled into an archive file once
+// it reaches the size limit or age, and on Close. File keys are derived from the sequence numbers
+// they hold, so rolling a spool file again after a failure rewrites the same archive file.
+type Archiver struct {
+	store   Store
+	format  string
+	options ArchiverOptions
+	logger  *logrus.Logger
+	now     func() time.Time
+
+	mu    sync.Mutex
+	spool map[string]*spoolFile
+
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+// ArchiverOptions configures an Archiver
+type ArchiverOptions struct {
+	// SpoolDir holds the records of archive files that are still being filled
+	SpoolDir string
+
+	// MaxFileBytes rolls a spool file into an archive file once it holds this many bytes
+	MaxFileBytes int64
+
+	// MaxFileAge rolls a spool file into an archive file this long after its first record
+	MaxFileAge time.Duration
+}
+
+// DefaultArchiverOptions returns the default archiver options
+func DefaultArchiverOptions() ArchiverOptions {
+	return ArchiverOptions{
+		SpoolDir:     "archive-spool",
+		MaxFileBytes: 64 << 20,
+		MaxFileAge:   5 * time.Minute,
+	}
+}
+
+// spoolFile is the spool file of one partition
+type spoolFile struct {
+	path     string
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+// NewArchiver creates an archiver writing files of the given format to store. Spool files left by
+// an earlier run are picked up and rolled like new ones.
+func NewArchiver(store Store, format string, options ArchiverOptions, logger *logrus.Logger) (*Archiver, error) {
+	if !validFormat(format) {
+		return nil, fmt.Errorf("unknown archive format %q", format)
+	}
+	defaults := DefaultArchiverOptions()
+	if options.SpoolDir == "" {
+		options.SpoolDir = defaults.SpoolDir
+	}
+	if options.MaxFileBytes <= 0 {
+		options.MaxFileBytes = defaults.MaxFileBytes
+	}
+	if options.MaxFileAge <= 0 {
+		options.MaxFileAge = defaults.MaxFileAge
+	}
+
+	a := &Archiver{
+		store:   store,
+		format:  format,
+		options: options,
+		logger:  logger,
+		now:     time.Now,
+		spool:   make(map[string]*spoolFile),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	if err := a.recover(); err != nil {
+		return nil, err
+	}
+	go a.run()
+	return a, nil
+}
+
+// spoolExtension is the extension of spool files, which hold NDJSON whatever the archive format
+const spoolExtension = ".ndjson"
+
+// spoolName returns the name of the spool file of a record's partition. Client IDs and event types
+// are hashed, as they may hold characters that file names cannot.
+func spoolName(record Record) string {
+	partition := sha256.Sum256([]byte(record.Event.ClientID + "\x00" + string(record.Event.EventType)))
+	return fmt.Sprintf("%s-%x%s", record.RemovedAt.UTC().Format(dateLayout), partition[:8], spoolExtension)
+}
+
+// archiveKey returns the key of the archive file holding records of one partition
+func archiveKey(format string, records []Record) string {
+	first, last := records[0], records[len(records)-1]
+	return fmt.Sprintf("date=%s/client_id=%s/event_type=%s/part-%s-%s.%s",
+		first.RemovedAt.UTC().Format(dateLayout), url.PathEscape(first.Event.ClientID), url.PathEscape(string(first.Event.EventType)),
+		first.SequenceNumber, last.SequenceNumber, format)
+}
+
+// Archive appends records to the spool files of their partitions. Once it returns, the records
+// are kept across restarts; spool files that reached the size limit are rolled right away.
+func (a *Archiver) Archive(ctx context.Context, records []Record) error {
+	var (
+		names      []string
+		partitions = make(map[string][]Record)
+	)
+	for _, record := range records {
+		if record.Event == nil {
+			return fmt.Errorf("archive record %s has no event", record.SequenceNumber)
+		}
+		name := spoolName(record)
+		if _, ok := partitions[name]; !ok {
+			names = append(names, name)
+		}
+		partitions[name] = append(partitions[name], record)
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, name := range names {
+		body, err := encodeNDJSON(partitions[name])
+		if err != nil {
+			return err
+		}
+		spool, err := a.spoolFile(name)
+		if err != nil {
+			return err
+		}
+		if _, err := spool.file.Write(body); err != nil {
+			return fmt.Errorf("failed to write spool file %s: %w", spool.path, err)
+		}
+		if err := spool.file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync spool file %s: %w", spool.path, err)
+		}
+		spool.size += int64(len(body))
+	}
+
+	// The records are kept in the spool, so a failed roll is only retried later
+	for _, name := range names {
+		if spool := a.spool[name]; spool.size >= a.options.MaxFileBytes {
+			if err := a.roll(ctx, name); err != nil {
+				a.logger.WithError(err).WithField("spool_file", spool.path).Error("Failed to roll archive file")
+			}
+		}
+	}
+	return nil
+}
+
+// spoolFile returns the open spool file of a partition, creating it if needed
+func (a *Archiver) spoolFile(name string) (*spoolFile, error) {
+	if spool, ok := a.spool[name]; ok {
+		return spool, nil
+	}
+	if err := os.MkdirAll(a.options.SpoolDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+	path := filepath.Join(a.options.SpoolDir, name)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open spool file: %w", err)
+	}
+	spool := &spoolFile{path: path, file: file, openedAt: a.now()}
+	a.spool[name] = spool
+	return spool, nil
+}
+
+// recover reopens the spool files of an earlier run. A record cut off by a crash was never
+// acknowledged, so it is dropped and delivered again by the stream.
+func (a *Archiver) recover() error {
+	entries, err := os.ReadDir(a.options.SpoolDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read spool directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), spoolExtension) {
+			continue
+		}
+		path := filepath.Join(a.options.SpoolDir, entry.Name())
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read spool file: %w", err)
+		}
+		complete := int64(bytes.LastIndexByte(body, '\n') + 1)
+		if err := os.Truncate(path, complete); err != nil {
+			return fmt.Errorf("failed to truncate spool file: %w", err)
+		}
+
+		spool, err := a.spoolFile(entry.Name())
+		if err != nil {
+			return err
+		}
+		spool.size = complete
+		if info, err := entry.Info(); err == nil {
+			spool.openedAt = info.ModTime()
+		}
+	}
+	return nil
+}
+
+// roll writes the records of a spool file to an archive file and its manifest fragment, then
+// removes the spool file
+func (a *Archiver) roll(ctx context.Context, name string) error {
+	spool := a.spool[name]
+	body, err := os.ReadFile(spool.path)
+	if err != nil {
+		return fmt.Errorf("failed to read spool file: %w", err)
+	}
+	records, err := decodeNDJSON(body)
+	if err != nil {
+		return fmt.Errorf("invalid spool file %s: %w", spool.path, err)
+	}
+
+	if len(records) > 0 {
+		encoded, err := encode(a.format, records)
+		if err != nil {
+			return err
+		}
+		key := archiveKey(a.format, records)
+		if err := a.store.Put(ctx, key, encoded); err != nil {
+			return err
+		}
+
+		first, last := records[0], records[len(records)-1]
+		fragment, err := json.Marshal(ManifestEntry{
+			Key:           key,
+			Format:        a.format,
+			Date:          first.RemovedAt.UTC().Format(dateLayout),
+			ClientID:      first.Event.ClientID,
+			EventType:     string(first.Event.EventType),
+			Records:       len(records),
+			FirstSequence: first.SequenceNumber,
+			LastSequence:  last.SequenceNumber,
+			CreatedAt:     a.now().UTC(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to marshal manifest fragment: %w", err)
+		}
+		if err := a.store.Put(ctx, fragmentKey(key), fragment); err != nil {
+			return err
+		}
+	}
+
+	spool.file.Close()
+	delete(a.spool, name)
+	if err := os.Remove(spool.path); err != nil {
+		return fmt.Errorf("failed to remove spool file: %w", err)
+	}
+	return nil
+}
+
+// rollDue rolls the spool files older than the maximum age, or all of them
+func (a *Archiver) rollDue(ctx context.Context, all bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var problems []error
+	for name, spool := range a.spool {
+		if !all && a.now().Sub(spool.openedAt) < a.options.MaxFileAge {
+			continue
+		}
+		if err := a.roll(ctx, name); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	return errors.Join(problems...)
+}
+
+// run rolls spool files as they reach the maximum age until the archiver is closed
+func (a *Archiver) run() {
+	defer close(a.stopped)
+
+	ticker := time.NewTicker(max(a.options.MaxFileAge/4, time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.done:
+			return
+		case <-ticker.C:
+			if err := a.rollDue(context.Background(), false); err != nil {
+				a.logger.WithError(err).Error("Failed to roll archive files")
+			}
+		}
+	}
+}
+
+// Close rolls every spool file into an archive file. Spool files that cannot be rolled are kept
+// and rolled by the next archiver.
+func (a *Archiver) Close(ctx context.Context) error {
+	a.closeOnce.Do(func() { close(a.done) })
+	<-a.stopped
+	return a.rollDue(ctx, true)
+}
+
+// Filter selects archived records; zero fields match everything
+type Filter struct {
+	// From and To bound the removal date, inclusive
+	From      time.Time
+	To        time.Time
+	ClientID  string
+	EventType string
+}
+
+// matches reports whether the file described by entry may hold records selected by the filter
+func (f Filter) matches(entry ManifestEntry) bool {
+	if f.ClientID != "" && entry.ClientID != f.ClientID {
+		return false
+	}
+	if f.EventType != "" && entry.EventType != f.EventType {
+		return false
+	}
+	if !f.From.IsZero() && entry.Date < f.From.UTC().Format(dateLayout) {
+		return false
+	}
+	if !f.To.IsZero() && entry.Date > f.To.UTC().Format(dateLayout) {
+		return false
+	}
+	return true
+}
+
+// Restore reads back the archived records selected by filter, in manifest order. Records
+// archived more than once are returned once.
+func Restore(ctx context.Context, store Store, filter Filter) ([]Record, error) {
+	manifest, err := ReadManifest(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	seen := make(map[string]bool)
+	for _, entry := range manifest.Files {
+		if !filter.matches(entry) {
+			continue
+		}
+
+		body, err := store.Get(ctx, entry.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive file %s: %w", entry.Key, err)
+		}
+		fileRecords, err := decode(entry.Format, body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid archive file %s: %w", entry.Key, err)
+		}
+
+		for _, record := range fileRecords {
+			if seen[record.SequenceNumber] {
+				continue
+			}
+			seen[record.SequenceNumber] = true
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
@@ -0,0 +1,278 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// failingStore fails every write after the first n
+type failingStore struct {
+	Store
+	remaining int
+}
+
+func (s *failingStore) Put(ctx context.Context, key string, body []byte) error {
+	if s.remaining == 0 {
+		return errors.New("store unavailable")
+	}
+	s.remaining--
+	return s.Store.Put(ctx, key, body)
+}
+
+// testRecord builds an archive record of an event removed at removedAt
+func testRecord(sequenceNumber, clientID string, eventType models.EventType, removedAt time.Time) Record {
+	return Record{
+		Event: &models.ProcessedEvent{
+			Event: models.Event{
+				EventID:   "evt-" + sequenceNumber,
+				EventType: eventType,
+				ClientID:  clientID,
+				Timestamp: time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC),
+				Payload:   map[string]interface{}{"amount": 12.5, "tags": []interface{}{"a"}},
+				Version:   "1.0",
+			},
+			ProcessedAt: time.Date(2025, 1, 1, 10, 0, 1, 0, time.UTC),
+			Status:      models.EventStatusProcessed,
+			TTL:         removedAt.Unix(),
+			Revision:    1,
+		},
+		RemovedAt:      removedAt,
+		Expired:        true,
+		SequenceNumber: sequenceNumber,
+	}
+}
+
+// TestEncodeDecode tests that records survive a round trip through each format
+func TestEncodeDecode(t *testing.T) {
+	records := []Record{
+		testRecord("100", "client-001", models.EventTypeTransaction, time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)),
+		testRecord("101", "client-002", models.EventTypeMonitoring, time.Date(2025, 3, 1, 12, 0, 1, 0, time.UTC)),
+	}
+	records[1].Event.Status = models.EventStatusFailed
+	records[1].Event.ErrorMsg = "validation failed"
+	records[1].Expired = false
+
+	for _, format := range []string{FormatNDJSON, FormatParquet} {
+		t.Run(format, func(t *testing.T) {
+			body, err := encode(format, records)
+			require.NoError(t, err)
+
+			decoded, err := decode(format, body)
+			require.NoError(t, err)
+			assert.Equal(t, records, decoded)
+		})
+	}
+
+	_, err := encode("csv", records)
+	assert.ErrorContains(t, err, `unknown archive format "csv"`)
+}
+
+// newTestArchiver creates an archiver spooling to a temporary directory at a fixed time
+func newTestArchiver(t *testing.T, store Store, format string, options ArchiverOptions, now *time.Time) *Archiver {
+	archiver, err := NewArchiver(store, format, options, testLogger())
+	require.NoError(t, err)
+	archiver.now = func() time.Time { return *now }
+	return archiver
+}
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+// manifestKeys returns the keys of the files in the manifest of store
+func manifestKeys(t *testing.T, store Store) []string {
+	manifest, err := ReadManifest(context.Background(), store)
+	require.NoError(t, err)
+
+	var keys []string
+	for _, entry := range manifest.Files {
+		keys = append(keys, entry.Key)
+	}
+	return keys
+}
+
+// TestArchive tests partitioning, rolling spool files on close and after a failure
+func TestArchive(t *testing.T) {
+	ctx := context.Background()
+	day1 := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+	now := day2
+	spoolDir := t.TempDir()
+
+	store := &failingStore{Store: NewFileStore(t.TempDir()), remaining: 1}
+	archiver := newTestArchiver(t, store, FormatParquet, ArchiverOptions{SpoolDir: spoolDir, MaxFileAge: time.Hour}, &now)
+	require.NoError(t, archiver.Archive(ctx, []Record{
+		testRecord("100", "client-001", models.EventTypeTransaction, day1),
+		testRecord("101", "client-002", models.EventTypeTransaction, day1),
+		testRecord("102", "client-001", models.EventTypeTransaction, day1),
+		testRecord("103", "client/3", models.EventTypeMonitoring, day2),
+	}))
+	require.NoError(t, archiver.Archive(ctx, []Record{testRecord("104", "client-001", models.EventTypeTransaction, day1)}))
+	assert.Empty(t, manifestKeys(t, store), "Should keep records in the spool until their file is rolled")
+
+	// Rolling fails part way, so the next archiver rolls the spool files again
+	assert.ErrorContains(t, archiver.Close(ctx), "store unavailable")
+	store.remaining = -1
+	next := newTestArchiver(t, store, FormatParquet, ArchiverOptions{SpoolDir: spoolDir, MaxFileAge: time.Hour}, &now)
+	require.NoError(t, next.Close(ctx))
+
+	manifest, err := ReadManifest(ctx, store)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"date=2025-03-01/client_id=client-001/event_type=transaction/part-100-104.parquet",
+		"date=2025-03-01/client_id=client-002/event_type=transaction/part-101-101.parquet",
+		"date=2025-03-02/client_id=client%2F3/event_type=monitoring/part-103-103.parquet",
+	}, manifestKeys(t, store))
+	assert.Equal(t, 3, manifest.Files[0].Records)
+	assert.Equal(t, "client/3", manifest.Files[2].ClientID)
+
+	spooled, err := os.ReadDir(spoolDir)
+	require.NoError(t, err)
+	assert.Empty(t, spooled, "Should remove rolled spool files")
+}
+
+// TestArchiveRolls tests rolling spool files by size and by age
+func TestArchiveRolls(t *testing.T) {
+	ctx := context.Background()
+	day1 := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	now := day1
+
+	t.Run("By Size", func(t *testing.T) {
+		store := NewFileStore(t.TempDir())
+		archiver := newTestArchiver(t, store, FormatNDJSON, ArchiverOptions{SpoolDir: t.TempDir(), MaxFileBytes: 1, MaxFileAge: time.Hour}, &now)
+		defer archiver.Close(ctx)
+
+		require.NoError(t, archiver.Archive(ctx, []Record{testRecord("100", "client-001", models.EventTypeTransaction, day1)}))
+		assert.Equal(t, []string{"date=2025-03-01/client_id=client-001/event_type=transaction/part-100-100.ndjson"}, manifestKeys(t, store))
+	})
+
+	t.Run("By Age", func(t *testing.T) {
+		store := NewFileStore(t.TempDir())
+		archiver := newTestArchiver(t, store, FormatNDJSON, ArchiverOptions{SpoolDir: t.TempDir(), MaxFileAge: time.Hour}, &now)
+		defer archiver.Close(ctx)
+
+		require.NoError(t, archiver.Archive(ctx, []Record{testRecord("100", "client-001", models.EventTypeTransaction, day1)}))
+		now = day1.Add(30 * time.Minute)
+		require.NoError(t, archiver.rollDue(ctx, false))
+		assert.Empty(t, manifestKeys(t, store), "Should keep filling files younger than the maximum age")
+
+		now = day1.Add(time.Hour)
+		require.NoError(t, archiver.rollDue(ctx, false))
+		assert.Equal(t, []string{"date=2025-03-01/client_id=client-001/event_type=transaction/part-100-100.ndjson"}, manifestKeys(t, store))
+	})
+}
+
+// TestArchiveRecoversCutOffRecords tests that a record cut off by a crash is dropped from the spool
+func TestArchiveRecoversCutOffRecords(t *testing.T) {
+	ctx := context.Background()
+	day1 := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	now := day1
+	spoolDir := t.TempDir()
+
+	complete, err := encodeNDJSON([]Record{testRecord("100", "client-001", models.EventTypeTransaction, day1)})
+	require.NoError(t, err)
+	cutOff, err := encodeNDJSON([]Record{testRecord("101", "client-001", models.EventTypeTransaction, day1)})
+	require.NoError(t, err)
+	name := spoolName(testRecord("100", "client-001", models.EventTypeTransaction, day1))
+	require.NoError(t, os.WriteFile(filepath.Join(spoolDir, name), append(complete, cutOff[:20]...), 0o644))
+
+	store := NewFileStore(t.TempDir())
+	archiver := newTestArchiver(t, store, FormatNDJSON, ArchiverOptions{SpoolDir: spoolDir, MaxFileAge: time.Hour}, &now)
+	require.NoError(t, archiver.Archive(ctx, []Record{testRecord("102", "client-001", models.EventTypeTransaction, day1)}))
+	require.NoError(t, archiver.Close(ctx))
+
+	records, err := Restore(ctx, store, Filter{})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "100", records[0].SequenceNumber)
+	assert.Equal(t, "102", records[1].SequenceNumber)
+}
+
+// TestRestore tests reading back archived records selected by a filter
+func TestRestore(t *testing.T) {
+	ctx := context.Background()
+	day1 := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+
+	store := NewFileStore(t.TempDir())
+
+	// A file listed in the single manifest of earlier versions
+	legacy, err := encode(FormatNDJSON, []Record{testRecord("099", "client-001", models.EventTypeTransaction, day1)})
+	require.NoError(t, err)
+	legacyKey := "date=2025-03-01/client_id=client-001/event_type=transaction/part-099-099.ndjson"
+	require.NoError(t, store.Put(ctx, legacyKey, legacy))
+	manifest, err := json.Marshal(Manifest{Files: []ManifestEntry{{Key: legacyKey, Format: FormatNDJSON, Date: "2025-03-01", ClientID: "client-001", EventType: "transaction", Records: 1}}})
+	require.NoError(t, err)
+	require.NoError(t, store.Put(ctx, ManifestKey, manifest))
+
+	now := day2
+	archiver := newTestArchiver(t, store, FormatNDJSON, ArchiverOptions{SpoolDir: t.TempDir(), MaxFileBytes: 1, MaxFileAge: time.Hour}, &now)
+	defer archiver.Close(ctx)
+	require.NoError(t, archiver.Archive(ctx, []Record{
+		testRecord("100", "client-001", models.EventTypeTransaction, day1),
+		testRecord("101", "client-002", models.EventTypeMonitoring, day1),
+	}))
+	// Redelivered record in a differently shaped batch
+	require.NoError(t, archiver.Archive(ctx, []Record{
+		testRecord("101", "client-002", models.EventTypeMonitoring, day1),
+		testRecord("102", "client-001", models.EventTypeTransaction, day2),
+	}))
+
+	tests := []struct {
+		name        string
+		filter      Filter
+		expected    []string
+		description string
+	}{
+		{
+			name:        "Everything",
+			filter:      Filter{},
+			expected:    []string{"099", "100", "101", "102"},
+			description: "Should return each archived record once, including files of the earlier manifest",
+		},
+		{
+			name:        "By Client",
+			filter:      Filter{ClientID: "client-002"},
+			expected:    []string{"101"},
+			description: "Should only read files of the client",
+		},
+		{
+			name:        "By Event Type And Date",
+			filter:      Filter{EventType: "transaction", From: day2, To: day2},
+			expected:    []string{"102"},
+			description: "Should only read files in the date range",
+		},
+		{
+			name:        "No Match",
+			filter:      Filter{To: day1.Add(-24 * time.Hour)},
+			description: "Should return nothing when no file matches",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := Restore(ctx, store, tt.filter)
+			require.NoError(t, err)
+
+			var sequences []string
+			for _, record := range records {
+				sequences = append(sequences, record.SequenceNumber)
+			}
+			assert.Equal(t, tt.expected, sequences)
+		})
+	}
+}
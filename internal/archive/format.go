@@ -0,0 +1,180 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// Archive file formats accepted in ARCHIVE_FORMAT
+const (
+	FormatNDJSON  = "ndjson"
+	FormatParquet = "parquet"
+)
+
+// Record is an event removed from the events table, as kept in the archive
+type Record struct {
+	Event          *models.ProcessedEvent `json:"event"`
+	RemovedAt      time.Time              `json:"removedAt"`
+	Expired        bool                   `json:"expired"`
+	SequenceNumber string                 `json:"sequenceNumber"`
+}
+
+// parquetRow is the flattened layout of a record in Parquet files; the payload is kept as JSON
+// because its shape differs between event types
+type parquetRow struct {
+	EventID        string    `parquet:"event_id"`
+	EventType      string    `parquet:"event_type"`
+	ClientID       string    `parquet:"client_id"`
+	Timestamp      time.Time `parquet:"timestamp"`
+	Payload        string    `parquet:"payload"`
+	Version        string    `parquet:"version"`
+	ProcessedAt    time.Time `parquet:"processed_at"`
+	Status         string    `parquet:"status"`
+	ErrorMsg       string    `parquet:"error_msg"`
+	RetryCount     int64     `parquet:"retry_count"`
+	TTL            int64     `parquet:"ttl"`
+	Revision       int64     `parquet:"revision"`
+	RemovedAt      time.Time `parquet:"removed_at"`
+	Expired        bool      `parquet:"expired"`
+	SequenceNumber string    `parquet:"sequence_number"`
+}
+
+// validFormat reports whether format is a supported archive format
+func validFormat(format string) bool {
+	return format == FormatNDJSON || format == FormatParquet
+}
+
+// encode writes records in the given format
+func encode(format string, records []Record) ([]byte, error) {
+	switch format {
+	case FormatNDJSON:
+		return encodeNDJSON(records)
+	case FormatParquet:
+		return encodeParquet(records)
+	default:
+		return nil, fmt.Errorf("unknown archive format %q", format)
+	}
+}
+
+// decode reads records written by encode
+func decode(format string, body []byte) ([]Record, error) {
+	switch format {
+	case FormatNDJSON:
+		return decodeNDJSON(body)
+	case FormatParquet:
+		return decodeParquet(body)
+	default:
+		return nil, fmt.Errorf("unknown archive format %q", format)
+	}
+}
+
+func encodeNDJSON(records []Record) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return nil, fmt.Errorf("failed to marshal record: %w", err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeNDJSON(body []byte) ([]Record, error) {
+	var records []Record
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("invalid archive line %d: %w", len(records)+1, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read archive file: %w", err)
+	}
+	return records, nil
+}
+
+func encodeParquet(records []Record) ([]byte, error) {
+	rows := make([]parquetRow, 0, len(records))
+	for _, record := range records {
+		event := record.Event
+		payload, err := json.Marshal(event.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal payload of event %s: %w", event.EventID, err)
+		}
+
+		rows = append(rows, parquetRow{
+			EventID:        event.EventID,
+			EventType:      string(event.EventType),
+			ClientID:       event.ClientID,
+			Timestamp:      event.Timestamp,
+			Payload:        string(payload),
+			Version:        event.Version,
+			ProcessedAt:    event.ProcessedAt,
+			Status:         string(event.Status),
+			ErrorMsg:       event.ErrorMsg,
+			RetryCount:     int64(event.RetryCount),
+			TTL:            event.TTL,
+			Revision:       event.Revision,
+			RemovedAt:      record.RemovedAt,
+			Expired:        record.Expired,
+			SequenceNumber: record.SequenceNumber,
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := parquet.Write(&buf, rows); err != nil {
+		return nil, fmt.Errorf("failed to write parquet file: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeParquet(body []byte) ([]Record, error) {
+	rows, err := parquet.Read[parquetRow](bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parquet file: %w", err)
+	}
+
+	records := make([]Record, 0, len(rows))
+	for _, row := range rows {
+		var payload map[string]interface{}
+		if err := json.Unmarshal([]byte(row.Payload), &payload); err != nil {
+			return nil, fmt.Errorf("invalid payload of event %s: %w", row.EventID, err)
+		}
+
+		records = append(records, Record{
+			Event: &models.ProcessedEvent{
+				Event: models.Event{
+					EventID:   row.EventID,
+					EventType: models.EventType(row.EventType),
+					ClientID:  row.ClientID,
+					Timestamp: row.Timestamp.UTC(),
+					Payload:   payload,
+					Version:   row.Version,
+				},
+				ProcessedAt: row.ProcessedAt.UTC(),
+				Status:      models.EventStatus(row.Status),
+				ErrorMsg:    row.ErrorMsg,
+				RetryCount:  int(row.RetryCount),
+				TTL:         row.TTL,
+				Revision:    row.Revision,
+			},
+			RemovedAt:      row.RemovedAt.UTC(),
+			Expired:        row.Expired,
+			SequenceNumber: row.SequenceNumber,
+		})
+	}
+	return records, nil
+}
@@ -0,0 +1,230 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/d-sense/event-processor/internal/config"
+)
+
+// ErrNotFound is returned by stores when an object does not exist
+var ErrNotFound = errors.New("archive object not found")
+
+// Store holds archive files under slash separated keys
+type Store interface {
+	Put(ctx context.Context, key string, body []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// List returns the keys starting with prefix, in order
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// FileStore keeps archive files in a directory of the local filesystem
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a store rooted at dir; directories are created on first write
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+// Put writes body to key, replacing it atomically
+func (s *FileStore) Put(ctx context.Context, key string, body []byte) error {
+	target := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".archive-*")
+	if err != nil {
+		return fmt.Errorf("failed to create archive file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write archive file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync archive file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close archive file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("failed to replace archive file: %w", err)
+	}
+	return nil
+}
+
+// Get reads key
+func (s *FileStore) Get(ctx context.Context, key string) ([]byte, error) {
+	body, err := os.ReadFile(filepath.Join(s.dir, filepath.FromSlash(key)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive file: %w", err)
+	}
+	return body, nil
+}
+
+// List returns the keys starting with prefix, in order
+func (s *FileStore) List(ctx context.Context, prefix string) ([]string, error) {
+	// Only the directory holding the prefix and its subdirectories can hold matching keys
+	root := filepath.Join(s.dir, filepath.FromSlash(path.Dir(prefix+"x")))
+	var keys []string
+	err := filepath.WalkDir(root, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".archive-") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.dir, name)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list archive files: %w", err)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// S3API is the subset of the S3 API used by the S3 store
+type S3API interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+}
+
+// S3Store keeps archive files in a bucket of S3 or an S3 compatible service
+type S3Store struct {
+	client S3API
+	bucket string
+	prefix string
+}
+
+// NewS3Store creates a store writing below prefix in bucket
+func NewS3Store(client S3API, bucket, prefix string) *S3Store {
+	return &S3Store{client: client, bucket: bucket, prefix: strings.Trim(prefix, "/")}
+}
+
+// objectKey returns the S3 key of an archive key
+func (s *S3Store) objectKey(key string) string {
+	if s.prefix == "" {
+		return key
+	}
+	return path.Join(s.prefix, key)
+}
+
+// Put uploads body to key
+func (s *S3Store) Put(ctx context.Context, key string, body []byte) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(key)),
+		Body:   bytes.NewReader(body),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	return nil
+}
+
+// Get downloads key
+func (s *S3Store) Get(ctx context.Context, key string) ([]byte, error) {
+	output, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(key)),
+	})
+	var noSuchKey *s3types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", key, err)
+	}
+	defer output.Body.Close()
+
+	body, err := io.ReadAll(output.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", key, err)
+	}
+	return body, nil
+}
+
+// List returns the keys starting with prefix, in order
+func (s *S3Store) List(ctx context.Context, prefix string) ([]string, error) {
+	objectPrefix := s.objectKey(prefix)
+	if s.prefix != "" && strings.HasSuffix(prefix, "/") {
+		objectPrefix += "/"
+	}
+
+	var (
+		keys  []string
+		token *string
+	)
+	for {
+		output, err := s.client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+			Bucket:            aws.String(s.bucket),
+			Prefix:            aws.String(objectPrefix),
+			ContinuationToken: token,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", prefix, err)
+		}
+		for _, object := range output.Contents {
+			key := aws.ToString(object.Key)
+			if s.prefix != "" {
+				key = strings.TrimPrefix(key, s.prefix+"/")
+			}
+			keys = append(keys, key)
+		}
+		if !aws.ToBool(output.IsTruncated) {
+			return keys, nil
+		}
+		token = output.NextContinuationToken
+	}
+}
+
+// NewStore creates the store cfg.ArchiveDestination points at: "s3://bucket/prefix" for S3,
+// anything else is a local directory
+func NewStore(cfg *config.Config, s3Client S3API) (Store, error) {
+	location, ok := strings.CutPrefix(cfg.ArchiveDestination, "s3://")
+	if !ok {
+		if cfg.ArchiveDestination == "" {
+			return nil, fmt.Errorf("ARCHIVE_DESTINATION is not set")
+		}
+		return NewFileStore(cfg.ArchiveDestination), nil
+	}
+
+	bucket, prefix, _ := strings.Cut(location, "/")
+	if bucket == "" {
+		return nil, fmt.Errorf("invalid archive destination %q: missing bucket", cfg.ArchiveDestination)
+	}
+	return NewS3Store(s3Client, bucket, prefix), nil
+}
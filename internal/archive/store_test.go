@@ -0,0 +1,177 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d-sense/event-processor/internal/config"
+)
+
+// MockS3API is a mock implementation of the S3API interface
+type MockS3API struct {
+	mock.Mock
+}
+
+func (m *MockS3API) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*s3.PutObjectOutput), args.Error(1)
+}
+
+func (m *MockS3API) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*s3.GetObjectOutput), args.Error(1)
+}
+
+func (m *MockS3API) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*s3.ListObjectsV2Output), args.Error(1)
+}
+
+// TestFileStore tests writing and reading nested keys on the local filesystem
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewFileStore(t.TempDir())
+
+	_, err := store.Get(ctx, "date=2025-01-01/part-1-2.ndjson")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, store.Put(ctx, "date=2025-01-01/part-1-2.ndjson", []byte("first")))
+	require.NoError(t, store.Put(ctx, "date=2025-01-01/part-1-2.ndjson", []byte("second")))
+
+	body, err := store.Get(ctx, "date=2025-01-01/part-1-2.ndjson")
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(body))
+
+	keys, err := store.List(ctx, "manifest/")
+	require.NoError(t, err)
+	assert.Empty(t, keys, "Should list nothing under a missing prefix")
+
+	require.NoError(t, store.Put(ctx, "manifest/date=2025-01-02/part-3-4.ndjson.json", []byte("{}")))
+	require.NoError(t, store.Put(ctx, "manifest/date=2025-01-01/part-1-2.ndjson.json", []byte("{}")))
+	keys, err = store.List(ctx, "manifest/")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"manifest/date=2025-01-01/part-1-2.ndjson.json", "manifest/date=2025-01-02/part-3-4.ndjson.json"}, keys)
+}
+
+// TestS3Store tests that keys are written and listed below the prefix and missing keys are reported
+func TestS3Store(t *testing.T) {
+	ctx := context.Background()
+	client := &MockS3API{}
+	client.On("PutObject", mock.Anything, mock.MatchedBy(func(input *s3.PutObjectInput) bool {
+		body, _ := io.ReadAll(input.Body)
+		return aws.ToString(input.Bucket) == "event-archive" &&
+			aws.ToString(input.Key) == "expired/manifest.json" &&
+			string(body) == "{}"
+	})).Return(&s3.PutObjectOutput{}, nil).Once()
+	client.On("GetObject", mock.Anything, mock.MatchedBy(func(input *s3.GetObjectInput) bool {
+		return aws.ToString(input.Key) == "expired/manifest.json"
+	})).Return(&s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader("{}"))}, nil).Once()
+	client.On("GetObject", mock.Anything, mock.MatchedBy(func(input *s3.GetObjectInput) bool {
+		return aws.ToString(input.Key) == "expired/missing.json"
+	})).Return(nil, &s3types.NoSuchKey{}).Once()
+	client.On("GetObject", mock.Anything, mock.Anything).Return(nil, errors.New("access denied")).Once()
+	client.On("ListObjectsV2", mock.Anything, mock.MatchedBy(func(input *s3.ListObjectsV2Input) bool {
+		return aws.ToString(input.Prefix) == "expired/manifest/" && input.ContinuationToken == nil
+	})).Return(&s3.ListObjectsV2Output{
+		Contents:              []s3types.Object{{Key: aws.String("expired/manifest/a.json")}},
+		IsTruncated:           aws.Bool(true),
+		NextContinuationToken: aws.String("page-2"),
+	}, nil).Once()
+	client.On("ListObjectsV2", mock.Anything, mock.MatchedBy(func(input *s3.ListObjectsV2Input) bool {
+		return aws.ToString(input.ContinuationToken) == "page-2"
+	})).Return(&s3.ListObjectsV2Output{
+		Contents: []s3types.Object{{Key: aws.String("expired/manifest/b.json")}},
+	}, nil).Once()
+
+	store := NewS3Store(client, "event-archive", "/expired/")
+	require.NoError(t, store.Put(ctx, ManifestKey, []byte("{}")))
+
+	body, err := store.Get(ctx, ManifestKey)
+	require.NoError(t, err)
+	assert.Equal(t, "{}", string(body))
+
+	_, err = store.Get(ctx, "missing.json")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = store.Get(ctx, "other.json")
+	assert.ErrorContains(t, err, "access denied")
+
+	keys, err := store.List(ctx, FragmentPrefix)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"manifest/a.json", "manifest/b.json"}, keys)
+	client.AssertExpectations(t)
+}
+
+// TestNewStore tests choosing a store from the archive destination
+func TestNewStore(t *testing.T) {
+	tests := []struct {
+		name        string
+		destination string
+		expected    Store
+		errorMsg    string
+		description string
+	}{
+		{
+			name:        "Local Directory",
+			destination: "/var/archive",
+			expected:    NewFileStore("/var/archive"),
+			description: "Should use the filesystem for plain paths",
+		},
+		{
+			name:        "S3 Bucket With Prefix",
+			destination: "s3://event-archive/expired/events",
+			expected:    &S3Store{bucket: "event-archive", prefix: "expired/events"},
+			description: "Should split the bucket and prefix",
+		},
+		{
+			name:        "S3 Bucket",
+			destination: "s3://event-archive",
+			expected:    &S3Store{bucket: "event-archive"},
+			description: "Should write to the bucket root without a prefix",
+		},
+		{
+			name:        "S3 Without Bucket",
+			destination: "s3:///expired",
+			errorMsg:    "missing bucket",
+			description: "Should reject destinations without a bucket",
+		},
+		{
+			name:        "Empty",
+			destination: "",
+			errorMsg:    "ARCHIVE_DESTINATION is not set",
+			description: "Should require a destination",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewStore(&config.Config{ArchiveDestination: tt.destination}, nil)
+
+			if tt.errorMsg != "" {
+				assert.ErrorContains(t, err, tt.errorMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, store)
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/d-sense/event-processor/internal/persistence"
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// Mode decides what happens to events of clients without a usable configuration
+type Mode string
+
+const (
+	// ModeAllowUnknown accepts events of clients without a configuration and events whose client
+	// configuration cannot be read
+	ModeAllowUnknown Mode = "allow-unknown"
+
+	// ModeDenyUnknown rejects events of clients without a configuration but accepts events whose
+	// client configuration cannot be read
+	ModeDenyUnknown Mode = "deny-unknown"
+
+	// ModeFailClosed rejects events of clients without a configuration and fails events whose client
+	// configuration cannot be read, so they are retried
+	ModeFailClosed Mode = "fail-closed"
+)
+
+var (
+	// ErrDenied is returned when a client may not send an event
+	ErrDenied = errors.New("event denied")
+
+	// ErrLookupFailed is returned in fail-closed mode when the client configuration cannot be read
+	ErrLookupFailed = errors.New("client config lookup failed")
+)
+
+// ParseMode parses an authorization mode
+func ParseMode(value string) (Mode, error) {
+	switch mode := Mode(value); mode {
+	case ModeAllowUnknown, ModeDenyUnknown, ModeFailClosed:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown authorization mode %q: expected %s, %s or %s", value, ModeAllowUnknown, ModeDenyUnknown, ModeFailClosed)
+	}
+}
+
+// ConfigSource provides client configurations; every persistence.Repository is one
+type ConfigSource interface {
+	GetClientConfig(ctx context.Context, clientID string) (*models.ClientConfig, error)
+}
+
+// Authorizer decides whether a client may send an event from the client's configuration: whether
+// the client is active, the event type is allowed, and the event satisfies the type's policy
+type Authorizer struct {
+	source ConfigSource
+	mode   Mode
+	logger *logrus.Logger
+
+	// patterns and locations cache compiled payload patterns and loaded time zones by name
+	patterns  sync.Map
+	locations sync.Map
+}
+
+// New creates an authorizer reading client configurations from source
+func New(source ConfigSource, mode Mode, logger *logrus.Logger) *Authorizer {
+	return &Authorizer{
+		source: source,
+		mode:   mode,
+		logger: logger,
+	}
+}
+
+// Authorize returns the configuration of the event's client if the client may send the event, which
+// was received at receivedAt. It returns nil without an error when the client has no usable
+// configuration and the mode allows the event anyway. Rejections wrap ErrDenied.
+func (a *Authorizer) Authorize(ctx context.Context, event *models.Event, receivedAt time.Time) (*models.ClientConfig, error) {
+	logger := a.logger.WithFields(logrus.Fields{
+		"client_id": event.ClientID,
+		"mode":      a.mode,
+	})
+
+	config, err := a.source.GetClientConfig(ctx, event.ClientID)
+	switch {
+	case errors.Is(err, persistence.ErrClientConfigNotFound):
+		if a.mode == ModeAllowUnknown {
+			logger.Debug("Client config not found, allowing by default")
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%w: client %s is not configured", ErrDenied, event.ClientID)
+	case err != nil:
+		if a.mode == ModeFailClosed {
+			return nil, fmt.Errorf("%w for client %s: %w", ErrLookupFailed, event.ClientID, err)
+		}
+		logger.WithError(err).Warn("Client config lookup failed, allowing the event")
+		return nil, nil
+	}
+
+	if !config.Active {
+		return nil, fmt.Errorf("%w: client %s is not active", ErrDenied, event.ClientID)
+	}
+
+	allowed := false
+	for _, allowedType := range config.AllowedTypes {
+		if allowedType == event.EventType {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, fmt.Errorf("%w: client %s is not allowed to send events of type %s", ErrDenied, event.ClientID, event.EventType)
+	}
+
+	if policy, ok := config.Policies[event.EventType]; ok {
+		if err := a.checkPolicy(policy, event, receivedAt); err != nil {
+			return nil, fmt.Errorf("%w: client %s: %w", ErrDenied, event.ClientID, err)
+		}
+	}
+
+	return config, nil
+}
+
+// pattern returns the compiled regular expression, compiling each pattern once
+func (a *Authorizer) pattern(expr string) (*regexp.Regexp, error) {
+	if cached, ok := a.patterns.Load(expr); ok {
+		return cached.(*regexp.Regexp), nil
+	}
+
+	compiled, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	a.patterns.Store(expr, compiled)
+	return compiled, nil
+}
+
+// location returns the named time zone, loading each zone once
+func (a *Authorizer) location(name string) (*time.Location, error) {
+	if cached, ok := a.locations.Load(name); ok {
+		return cached.(*time.Location), nil
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	a.locations.Store(name, location)
+	return location, nil
+}
@@ -0,0 +1,244 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d-sense/event-processor/internal/persistence"
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// MockConfigSource is a mock implementation of the ConfigSource interface
+type MockConfigSource struct {
+	mock.Mock
+}
+
+func (m *MockConfigSource) GetClientConfig(ctx context.Context, clientID string) (*models.ClientConfig, error) {
+	args := m.Called(ctx, clientID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ClientConfig), args.Error(1)
+}
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func testEvent(clientID string, eventType models.EventType) *models.Event {
+	return &models.Event{
+		EventID:   "123e4567-e89b-12d3-a456-426614174000",
+		EventType: eventType,
+		ClientID:  clientID,
+		Timestamp: time.Now().UTC(),
+		Payload:   map[string]interface{}{"severity": "medium"},
+		Version:   "1.0",
+	}
+}
+
+func activeClient(clientID string, allowedTypes ...models.EventType) *models.ClientConfig {
+	return &models.ClientConfig{ClientID: clientID, AllowedTypes: allowedTypes, Active: true}
+}
+
+// TestParseMode tests parsing of authorization modes
+func TestParseMode(t *testing.T) {
+	for _, mode := range []Mode{ModeAllowUnknown, ModeDenyUnknown, ModeFailClosed} {
+		parsed, err := ParseMode(string(mode))
+		assert.NoError(t, err)
+		assert.Equal(t, mode, parsed)
+	}
+
+	_, err := ParseMode("deny-all")
+	assert.EqualError(t, err, `unknown authorization mode "deny-all": expected allow-unknown, deny-unknown or fail-closed`)
+}
+
+// TestAuthorize tests the client checks and how each mode treats missing and unreadable configurations
+func TestAuthorize(t *testing.T) {
+	notFound := fmt.Errorf("%w: client-004", persistence.ErrClientConfigNotFound)
+	outage := errors.New("dynamodb unavailable")
+
+	tests := []struct {
+		name           string
+		mode           Mode
+		event          *models.Event
+		mockSource     func(*MockConfigSource)
+		expectError    error
+		errorMsg       string
+		expectedConfig *models.ClientConfig
+		description    string
+	}{
+		{
+			name:  "Valid Client with Permission",
+			mode:  ModeAllowUnknown,
+			event: testEvent("client-001", models.EventTypeMonitoring),
+			mockSource: func(ms *MockConfigSource) {
+				ms.On("GetClientConfig", mock.Anything, "client-001").Return(activeClient("client-001", models.EventTypeMonitoring), nil)
+			},
+			expectedConfig: activeClient("client-001", models.EventTypeMonitoring),
+			description:    "Should allow event when client has permission",
+		},
+		{
+			name:  "Client Without Permission",
+			mode:  ModeAllowUnknown,
+			event: testEvent("client-002", models.EventTypeMonitoring),
+			mockSource: func(ms *MockConfigSource) {
+				ms.On("GetClientConfig", mock.Anything, "client-002").Return(activeClient("client-002", models.EventTypeUserAction), nil)
+			},
+			expectError: ErrDenied,
+			errorMsg:    "client client-002 is not allowed to send events of type monitoring",
+			description: "Should deny event when client lacks permission",
+		},
+		{
+			name:  "Inactive Client",
+			mode:  ModeAllowUnknown,
+			event: testEvent("client-003", models.EventTypeUserAction),
+			mockSource: func(ms *MockConfigSource) {
+				ms.On("GetClientConfig", mock.Anything, "client-003").Return(&models.ClientConfig{ClientID: "client-003", AllowedTypes: []models.EventType{models.EventTypeUserAction}}, nil)
+			},
+			expectError: ErrDenied,
+			errorMsg:    "client client-003 is not active",
+			description: "Should deny event when client is inactive",
+		},
+		{
+			name:  "Unknown Client - Allow Unknown",
+			mode:  ModeAllowUnknown,
+			event: testEvent("client-004", models.EventTypeTransaction),
+			mockSource: func(ms *MockConfigSource) {
+				ms.On("GetClientConfig", mock.Anything, "client-004").Return(nil, notFound)
+			},
+			description: "Should allow events of unknown clients",
+		},
+		{
+			name:  "Lookup Failure - Allow Unknown",
+			mode:  ModeAllowUnknown,
+			event: testEvent("client-004", models.EventTypeTransaction),
+			mockSource: func(ms *MockConfigSource) {
+				ms.On("GetClientConfig", mock.Anything, "client-004").Return(nil, outage)
+			},
+			description: "Should allow events when the configuration cannot be read",
+		},
+		{
+			name:  "Unknown Client - Deny Unknown",
+			mode:  ModeDenyUnknown,
+			event: testEvent("client-004", models.EventTypeTransaction),
+			mockSource: func(ms *MockConfigSource) {
+				ms.On("GetClientConfig", mock.Anything, "client-004").Return(nil, notFound)
+			},
+			expectError: ErrDenied,
+			errorMsg:    "client client-004 is not configured",
+			description: "Should deny events of unknown clients",
+		},
+		{
+			name:  "Lookup Failure - Deny Unknown",
+			mode:  ModeDenyUnknown,
+			event: testEvent("client-004", models.EventTypeTransaction),
+			mockSource: func(ms *MockConfigSource) {
+				ms.On("GetClientConfig", mock.Anything, "client-004").Return(nil, outage)
+			},
+			description: "Should still allow events when the configuration cannot be read",
+		},
+		{
+			name:  "Unknown Client - Fail Closed",
+			mode:  ModeFailClosed,
+			event: testEvent("client-004", models.EventTypeTransaction),
+			mockSource: func(ms *MockConfigSource) {
+				ms.On("GetClientConfig", mock.Anything, "client-004").Return(nil, notFound)
+			},
+			expectError: ErrDenied,
+			errorMsg:    "client client-004 is not configured",
+			description: "Should deny events of unknown clients",
+		},
+		{
+			name:  "Lookup Failure - Fail Closed",
+			mode:  ModeFailClosed,
+			event: testEvent("client-004", models.EventTypeTransaction),
+			mockSource: func(ms *MockConfigSource) {
+				ms.On("GetClientConfig", mock.Anything, "client-004").Return(nil, outage)
+			},
+			expectError: ErrLookupFailed,
+			errorMsg:    "client config lookup failed for client client-004: dynamodb unavailable",
+			description: "Should fail events when the configuration cannot be read",
+		},
+		{
+			name:  "Policy Violation",
+			mode:  ModeAllowUnknown,
+			event: testEvent("client-001", models.EventTypeMonitoring),
+			mockSource: func(ms *MockConfigSource) {
+				config := activeClient("client-001", models.EventTypeMonitoring)
+				config.Policies = map[models.EventType]models.EventPolicy{
+					models.EventTypeMonitoring: {
+						Versions: []string{"2.0"},
+						Payload:  map[string]models.PayloadConstraint{"severity": {OneOf: []string{"high", "critical"}}},
+					},
+				}
+				ms.On("GetClientConfig", mock.Anything, "client-001").Return(config, nil)
+			},
+			expectError: ErrDenied,
+			errorMsg:    "event denied: client client-001: version \"1.0\" of monitoring events is not allowed\npayload field \"severity\" must be one of high, critical",
+			description: "Should report every policy violation",
+		},
+		{
+			name:  "Policy Of Another Type",
+			mode:  ModeAllowUnknown,
+			event: testEvent("client-001", models.EventTypeMonitoring),
+			mockSource: func(ms *MockConfigSource) {
+				config := activeClient("client-001", models.EventTypeMonitoring, models.EventTypeTransaction)
+				config.Policies = map[models.EventType]models.EventPolicy{
+					models.EventTypeTransaction: {Versions: []string{"2.0"}},
+				}
+				ms.On("GetClientConfig", mock.Anything, "client-001").Return(config, nil)
+			},
+			expectedConfig: &models.ClientConfig{
+				ClientID:     "client-001",
+				AllowedTypes: []models.EventType{models.EventTypeMonitoring, models.EventTypeTransaction},
+				Active:       true,
+				Policies: map[models.EventType]models.EventPolicy{
+					models.EventTypeTransaction: {Versions: []string{"2.0"}},
+				},
+			},
+			description: "Should only apply the policy of the event's type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSource := &MockConfigSource{}
+			tt.mockSource(mockSource)
+
+			config, err := New(mockSource, tt.mode, testLogger()).Authorize(context.Background(), tt.event, time.Now())
+
+			if tt.expectError != nil {
+				assert.ErrorIs(t, err, tt.expectError, tt.description)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				assert.Nil(t, config)
+			} else {
+				assert.NoError(t, err, tt.description)
+				assert.Equal(t, tt.expectedConfig, config)
+			}
+			mockSource.AssertExpectations(t)
+		})
+	}
+}
+
+// TestAuthorizeWithRepository tests authorization against a repository's missing client error
+func TestAuthorizeWithRepository(t *testing.T) {
+	repo := persistence.NewMemoryRepository()
+
+	_, err := New(repo, ModeDenyUnknown, testLogger()).Authorize(context.Background(), testEvent("client-404", models.EventTypeMonitoring), time.Now())
+	require.ErrorIs(t, err, ErrDenied)
+
+	config, err := New(repo, ModeAllowUnknown, testLogger()).Authorize(context.Background(), testEvent("client-404", models.EventTypeMonitoring), time.Now())
+	require.NoError(t, err)
+	assert.Nil(t, config)
+}
@@ -0,0 +1,245 @@
+package authz
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// weekdays maps the day names of time windows to weekdays
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// window is a parsed time window; start and end are minutes after midnight
+type window struct {
+	start, end int
+	days       map[time.Weekday]bool
+	timezone   string
+}
+
+// ValidatePolicy checks that a policy can be evaluated, returning every problem found
+func ValidatePolicy(policy models.EventPolicy) error {
+	var problems []error
+	for _, version := range policy.Versions {
+		if strings.TrimSpace(version) == "" {
+			problems = append(problems, errors.New("versions must not be empty"))
+		}
+	}
+
+	for _, field := range sortedFields(policy.Payload) {
+		constraint := policy.Payload[field]
+		if field == "" {
+			problems = append(problems, errors.New("payload field names must not be empty"))
+		}
+		if constraint.Pattern != "" {
+			if _, err := regexp.Compile(constraint.Pattern); err != nil {
+				problems = append(problems, fmt.Errorf("payload field %q has an invalid pattern: %w", field, err))
+			}
+		}
+		if constraint.Min != nil && constraint.Max != nil && *constraint.Min > *constraint.Max {
+			problems = append(problems, fmt.Errorf("payload field %q has a min above its max", field))
+		}
+		if (constraint.Min != nil || constraint.Max != nil) && (len(constraint.OneOf) > 0 || constraint.Pattern != "") {
+			problems = append(problems, fmt.Errorf("payload field %q cannot be both a number and a string", field))
+		}
+	}
+
+	for i, timeWindow := range policy.Windows {
+		if _, err := parseWindow(timeWindow); err != nil {
+			problems = append(problems, fmt.Errorf("window %d: %w", i+1, err))
+		}
+		if _, err := time.LoadLocation(timeWindow.Timezone); err != nil {
+			problems = append(problems, fmt.Errorf("window %d: unknown timezone %q", i+1, timeWindow.Timezone))
+		}
+	}
+
+	return errors.Join(problems...)
+}
+
+// checkPolicy returns every way the event, received at receivedAt, violates the policy of its type
+func (a *Authorizer) checkPolicy(policy models.EventPolicy, event *models.Event, receivedAt time.Time) error {
+	var problems []error
+	if len(policy.Versions) > 0 && !slices.Contains(policy.Versions, event.Version) {
+		problems = append(problems, fmt.Errorf("version %q of %s events is not allowed", event.Version, event.EventType))
+	}
+
+	for _, field := range sortedFields(policy.Payload) {
+		if err := a.checkField(field, policy.Payload[field], event.Payload); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	if len(policy.Windows) > 0 {
+		// The event timestamp is set by the client, so it is only trusted when the policy says so
+		at := receivedAt
+		if policy.WindowsOnEventTime {
+			at = event.Timestamp
+		}
+		open, err := a.inWindows(policy.Windows, at)
+		if err != nil {
+			problems = append(problems, err)
+		} else if !open {
+			problems = append(problems, fmt.Errorf("%s events are not accepted at %s", event.EventType, at.UTC().Format(time.RFC3339)))
+		}
+	}
+
+	return errors.Join(problems...)
+}
+
+// checkField checks a payload field against its constraint
+func (a *Authorizer) checkField(field string, constraint models.PayloadConstraint, payload map[string]interface{}) error {
+	value, ok := payload[field]
+	if !ok {
+		if constraint.Required {
+			return fmt.Errorf("payload field %q is required", field)
+		}
+		return nil
+	}
+
+	if len(constraint.OneOf) > 0 || constraint.Pattern != "" {
+		text, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("payload field %q must be a string", field)
+		}
+		if len(constraint.OneOf) > 0 && !slices.Contains(constraint.OneOf, text) {
+			return fmt.Errorf("payload field %q must be one of %s", field, strings.Join(constraint.OneOf, ", "))
+		}
+		if constraint.Pattern != "" {
+			pattern, err := a.pattern(constraint.Pattern)
+			if err != nil {
+				return fmt.Errorf("payload field %q has an invalid pattern: %w", field, err)
+			}
+			if !pattern.MatchString(text) {
+				return fmt.Errorf("payload field %q must match %s", field, constraint.Pattern)
+			}
+		}
+	}
+
+	if constraint.Min != nil || constraint.Max != nil {
+		number, ok := toFloat(value)
+		if !ok {
+			return fmt.Errorf("payload field %q must be a number", field)
+		}
+		if constraint.Min != nil && number < *constraint.Min {
+			return fmt.Errorf("payload field %q must be at least %v", field, *constraint.Min)
+		}
+		if constraint.Max != nil && number > *constraint.Max {
+			return fmt.Errorf("payload field %q must be at most %v", field, *constraint.Max)
+		}
+	}
+
+	return nil
+}
+
+// inWindows reports whether at falls in any of the windows
+func (a *Authorizer) inWindows(timeWindows []models.TimeWindow, at time.Time) (bool, error) {
+	for i, timeWindow := range timeWindows {
+		parsed, err := parseWindow(timeWindow)
+		if err != nil {
+			return false, fmt.Errorf("window %d: %w", i+1, err)
+		}
+		location, err := a.location(parsed.timezone)
+		if err != nil {
+			return false, fmt.Errorf("window %d: unknown timezone %q", i+1, parsed.timezone)
+		}
+		if parsed.contains(at.In(location)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// contains reports whether local, in the window's timezone, falls in the window. A window spanning
+// midnight belongs to the day it starts on.
+func (w window) contains(local time.Time) bool {
+	minute := local.Hour()*60 + local.Minute()
+	today := local.Weekday()
+	yesterday := (today + 6) % 7
+
+	if w.start < w.end {
+		return w.start <= minute && minute < w.end && w.onDay(today)
+	}
+	return (minute >= w.start && w.onDay(today)) || (minute < w.end && w.onDay(yesterday))
+}
+
+// onDay reports whether the window applies on the day
+func (w window) onDay(day time.Weekday) bool {
+	return len(w.days) == 0 || w.days[day]
+}
+
+// parseWindow parses the times and days of a time window
+func parseWindow(timeWindow models.TimeWindow) (window, error) {
+	start, err := parseTimeOfDay(timeWindow.Start)
+	if err != nil {
+		return window{}, fmt.Errorf("invalid start: %w", err)
+	}
+	end, err := parseTimeOfDay(timeWindow.End)
+	if err != nil {
+		return window{}, fmt.Errorf("invalid end: %w", err)
+	}
+
+	parsed := window{start: start, end: end, timezone: timeWindow.Timezone}
+	for _, name := range timeWindow.Days {
+		day, ok := weekdays[strings.ToLower(name)]
+		if !ok {
+			return window{}, fmt.Errorf("unknown day %q: expected mon, tue, wed, thu, fri, sat or sun", name)
+		}
+		if parsed.days == nil {
+			parsed.days = make(map[time.Weekday]bool)
+		}
+		parsed.days[day] = true
+	}
+	return parsed, nil
+}
+
+// parseTimeOfDay parses an HH:MM time into minutes after midnight
+func parseTimeOfDay(value string) (int, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not an HH:MM time", value)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// toFloat converts a JSON number to a float64
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		number, err := v.Float64()
+		return number, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// sortedFields returns the constrained payload fields in a stable order
+func sortedFields(constraints map[string]models.PayloadConstraint) []string {
+	fields := make([]string, 0, len(constraints))
+	for field := range constraints {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
@@ -0,0 +1,308 @@
+package authz
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+func float(value float64) *float64 {
+	return &value
+}
+
+// TestCheckPolicy tests evaluation of versions, payload constraints and time windows
+func TestCheckPolicy(t *testing.T) {
+	// Events are received on Wednesday 2025-03-05 10:30 UTC, 11:30 in Berlin, and claim to have
+	// happened on the Sunday before
+	receivedAt := time.Date(2025, 3, 5, 10, 30, 0, 0, time.UTC)
+	timestamp := receivedAt.AddDate(0, 0, -3)
+
+	tests := []struct {
+		name        string
+		policy      models.EventPolicy
+		version     string
+		payload     map[string]interface{}
+		expectError bool
+		errorMsg    string
+		description string
+	}{
+		{
+			name:        "Empty Policy",
+			policy:      models.EventPolicy{},
+			version:     "1.0",
+			expectError: false,
+			description: "Should accept every event",
+		},
+		{
+			name:        "Allowed Version",
+			policy:      models.EventPolicy{Versions: []string{"1.0", "1.1"}},
+			version:     "1.1",
+			expectError: false,
+			description: "Should accept listed versions",
+		},
+		{
+			name:        "Disallowed Version",
+			policy:      models.EventPolicy{Versions: []string{"2.0"}},
+			version:     "1.0",
+			expectError: true,
+			errorMsg:    `version "1.0" of transaction events is not allowed`,
+			description: "Should reject versions that are not listed",
+		},
+		{
+			name: "Required Field Missing",
+			policy: models.EventPolicy{Payload: map[string]models.PayloadConstraint{
+				"currency": {Required: true},
+			}},
+			payload:     map[string]interface{}{},
+			expectError: true,
+			errorMsg:    `payload field "currency" is required`,
+			description: "Should reject events without required fields",
+		},
+		{
+			name: "Optional Field Missing",
+			policy: models.EventPolicy{Payload: map[string]models.PayloadConstraint{
+				"currency": {OneOf: []string{"EUR"}},
+			}},
+			payload:     map[string]interface{}{},
+			expectError: false,
+			description: "Should only check the value of present fields",
+		},
+		{
+			name: "Value In Set",
+			policy: models.EventPolicy{Payload: map[string]models.PayloadConstraint{
+				"currency": {OneOf: []string{"EUR", "USD"}},
+			}},
+			payload:     map[string]interface{}{"currency": "USD"},
+			expectError: false,
+			description: "Should accept listed values",
+		},
+		{
+			name: "Value Not In Set",
+			policy: models.EventPolicy{Payload: map[string]models.PayloadConstraint{
+				"currency": {OneOf: []string{"EUR", "USD"}},
+			}},
+			payload:     map[string]interface{}{"currency": "GBP"},
+			expectError: true,
+			errorMsg:    `payload field "currency" must be one of EUR, USD`,
+			description: "Should reject values that are not listed",
+		},
+		{
+			name: "Pattern Mismatch",
+			policy: models.EventPolicy{Payload: map[string]models.PayloadConstraint{
+				"transactionId": {Pattern: `^txn-[0-9]+$`},
+			}},
+			payload:     map[string]interface{}{"transactionId": "abc"},
+			expectError: true,
+			errorMsg:    `payload field "transactionId" must match ^txn-[0-9]+$`,
+			description: "Should reject strings that do not match the pattern",
+		},
+		{
+			name: "String Constraint On Number",
+			policy: models.EventPolicy{Payload: map[string]models.PayloadConstraint{
+				"currency": {OneOf: []string{"EUR"}},
+			}},
+			payload:     map[string]interface{}{"currency": 978.0},
+			expectError: true,
+			errorMsg:    `payload field "currency" must be a string`,
+			description: "Should reject values of the wrong type",
+		},
+		{
+			name: "Number In Range",
+			policy: models.EventPolicy{Payload: map[string]models.PayloadConstraint{
+				"amount": {Min: float(0), Max: float(10000)},
+			}},
+			payload:     map[string]interface{}{"amount": 10000.0},
+			expectError: false,
+			description: "Should accept numbers within inclusive bounds",
+		},
+		{
+			name: "Number Above Max",
+			policy: models.EventPolicy{Payload: map[string]models.PayloadConstraint{
+				"amount": {Max: float(10000)},
+			}},
+			payload:     map[string]interface{}{"amount": 10000.5},
+			expectError: true,
+			errorMsg:    `payload field "amount" must be at most 10000`,
+			description: "Should reject numbers above the max",
+		},
+		{
+			name: "Number Below Min",
+			policy: models.EventPolicy{Payload: map[string]models.PayloadConstraint{
+				"amount": {Min: float(1)},
+			}},
+			payload:     map[string]interface{}{"amount": 0},
+			expectError: true,
+			errorMsg:    `payload field "amount" must be at least 1`,
+			description: "Should reject numbers below the min",
+		},
+		{
+			name: "Inside Window",
+			policy: models.EventPolicy{Windows: []models.TimeWindow{
+				{Start: "09:00", End: "17:00", Days: []string{"mon", "tue", "wed", "thu", "fri"}},
+			}},
+			expectError: false,
+			description: "Should accept events inside a window",
+		},
+		{
+			name: "Outside Window",
+			policy: models.EventPolicy{Windows: []models.TimeWindow{
+				{Start: "11:00", End: "17:00"},
+			}},
+			expectError: true,
+			errorMsg:    "transaction events are not accepted at 2025-03-05T10:30:00Z",
+			description: "Should reject events outside every window",
+		},
+		{
+			name: "Window In Timezone",
+			policy: models.EventPolicy{Windows: []models.TimeWindow{
+				{Start: "11:00", End: "12:00", Timezone: "Europe/Berlin"},
+			}},
+			expectError: false,
+			description: "Should compare times in the window's timezone",
+		},
+		{
+			name: "Window On Another Day",
+			policy: models.EventPolicy{Windows: []models.TimeWindow{
+				{Start: "09:00", End: "17:00", Days: []string{"sat", "sun"}},
+			}},
+			expectError: true,
+			errorMsg:    "not accepted",
+			description: "Should reject events on days outside the window",
+		},
+		{
+			name: "Window Ignores Event Timestamp",
+			policy: models.EventPolicy{Windows: []models.TimeWindow{
+				{Start: "09:00", End: "17:00", Days: []string{"sun"}},
+			}},
+			expectError: true,
+			errorMsg:    "transaction events are not accepted at 2025-03-05T10:30:00Z",
+			description: "Should check windows against when the event was received, not the timestamp the client set",
+		},
+		{
+			name: "Window On Event Time",
+			policy: models.EventPolicy{
+				Windows:            []models.TimeWindow{{Start: "09:00", End: "17:00", Days: []string{"sun"}}},
+				WindowsOnEventTime: true,
+			},
+			expectError: false,
+			description: "Should check windows against the event timestamp when the policy says so",
+		},
+		{
+			name: "Any Of Several Windows",
+			policy: models.EventPolicy{Windows: []models.TimeWindow{
+				{Start: "00:00", End: "06:00"},
+				{Start: "10:00", End: "11:00"},
+			}},
+			expectError: false,
+			description: "Should accept events inside any window",
+		},
+		{
+			name: "Invalid Stored Window",
+			policy: models.EventPolicy{Windows: []models.TimeWindow{
+				{Start: "9am", End: "17:00"},
+			}},
+			expectError: true,
+			errorMsg:    `window 1: invalid start: "9am" is not an HH:MM time`,
+			description: "Should reject events when a window cannot be evaluated",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authorizer := New(nil, ModeAllowUnknown, testLogger())
+			event := &models.Event{EventType: models.EventTypeTransaction, Version: tt.version, Payload: tt.payload, Timestamp: timestamp}
+
+			err := authorizer.checkPolicy(tt.policy, event, receivedAt)
+
+			if tt.expectError {
+				assert.Error(t, err, tt.description)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+			} else {
+				assert.NoError(t, err, tt.description)
+			}
+		})
+	}
+}
+
+// TestWindowContains tests windows spanning midnight, which belong to the day they start on
+func TestWindowContains(t *testing.T) {
+	overnight, err := parseWindow(models.TimeWindow{Start: "22:00", End: "02:00", Days: []string{"fri"}})
+	assert.NoError(t, err)
+
+	// 2025-03-07 is a Friday
+	assert.True(t, overnight.contains(time.Date(2025, 3, 7, 23, 0, 0, 0, time.UTC)), "Friday night")
+	assert.True(t, overnight.contains(time.Date(2025, 3, 8, 1, 59, 0, 0, time.UTC)), "early Saturday belongs to Friday's window")
+	assert.False(t, overnight.contains(time.Date(2025, 3, 8, 2, 0, 0, 0, time.UTC)), "the end is exclusive")
+	assert.False(t, overnight.contains(time.Date(2025, 3, 8, 23, 0, 0, 0, time.UTC)), "Saturday night")
+	assert.False(t, overnight.contains(time.Date(2025, 3, 7, 1, 0, 0, 0, time.UTC)), "early Friday belongs to Thursday's window")
+}
+
+// TestValidatePolicy tests that policies which cannot be evaluated are rejected
+func TestValidatePolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      models.EventPolicy
+		expectError bool
+		errorMsg    string
+		description string
+	}{
+		{
+			name: "Valid Policy",
+			policy: models.EventPolicy{
+				Versions: []string{"1.0"},
+				Payload: map[string]models.PayloadConstraint{
+					"currency": {Required: true, OneOf: []string{"EUR"}},
+					"amount":   {Min: float(0), Max: float(100)},
+				},
+				Windows: []models.TimeWindow{{Start: "09:00", End: "17:00", Days: []string{"Mon"}, Timezone: "America/New_York"}},
+			},
+			expectError: false,
+			description: "Should accept a well formed policy",
+		},
+		{
+			name:        "Empty Version",
+			policy:      models.EventPolicy{Versions: []string{" "}},
+			expectError: true,
+			errorMsg:    "versions must not be empty",
+			description: "Should reject empty versions",
+		},
+		{
+			name: "Invalid Constraints",
+			policy: models.EventPolicy{Payload: map[string]models.PayloadConstraint{
+				"amount":        {Min: float(10), Max: float(1)},
+				"transactionId": {Pattern: "("},
+				"currency":      {OneOf: []string{"EUR"}, Max: float(1)},
+			}},
+			expectError: true,
+			errorMsg:    "payload field \"amount\" has a min above its max\npayload field \"currency\" cannot be both a number and a string\npayload field \"transactionId\" has an invalid pattern",
+			description: "Should report every invalid constraint",
+		},
+		{
+			name: "Invalid Windows",
+			policy: models.EventPolicy{Windows: []models.TimeWindow{
+				{Start: "09:00", End: "25:00"},
+				{Start: "09:00", End: "17:00", Days: []string{"weekday"}},
+				{Start: "09:00", End: "17:00", Timezone: "Mars/Olympus"},
+			}},
+			expectError: true,
+			errorMsg:    "window 1: invalid end: \"25:00\" is not an HH:MM time\nwindow 2: unknown day \"weekday\"",
+			description: "Should report invalid times, days and timezones",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePolicy(tt.policy)
+
+			if tt.expectError {
+				assert.Error(t, err, tt.description)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+			} else {
+				assert.NoError(t, err, tt.description)
+			}
+		})
+	}
+}
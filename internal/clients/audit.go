@@ -0,0 +1,32 @@
+package clients
+
+import (
+	"context"
+
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// Action is a change made to a client configuration
+type Action = models.ClientAction
+
+const (
+	ActionCreate  = models.ClientActionCreate
+	ActionUpdate  = models.ClientActionUpdate
+	ActionEnable  = models.ClientActionEnable
+	ActionDisable = models.ClientActionDisable
+	ActionDelete  = models.ClientActionDelete
+)
+
+// AuditEntry records who changed a client configuration and how
+type AuditEntry = models.ClientChange
+
+// AuditLog stores the history of client configuration changes. It is kept next to the configurations
+// so that every replica records into and reads the same history; every persistence.ClientAuditStore
+// is one.
+type AuditLog interface {
+	RecordClientChange(ctx context.Context, change models.ClientChange) error
+
+	// ListClientChanges returns the changes to a client in the order they were made, or of every
+	// client when clientID is empty
+	ListClientChanges(ctx context.Context, clientID string) ([]models.ClientChange, error)
+}
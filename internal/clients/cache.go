@@ -0,0 +1,169 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/d-sense/event-processor/internal/metrics"
+	"github.com/d-sense/event-processor/internal/persistence"
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// Results of a cached client configuration lookup, used for the result metric label
+const (
+	cacheHit         = "hit"
+	cacheNegativeHit = "negative_hit"
+	cacheMiss        = "miss"
+)
+
+// CacheOptions configures a CachedRepository
+type CacheOptions struct {
+	// TTL is how long a client configuration is served from the cache
+	TTL time.Duration
+
+	// NegativeTTL is how long a missing client configuration is remembered; 0 disables negative caching
+	NegativeTTL time.Duration
+}
+
+// cacheEntry is a cached lookup result; config is nil for clients without a configuration
+type cacheEntry struct {
+	config  *models.ClientConfig
+	expires time.Time
+}
+
+// CachedRepository serves client configurations from memory in front of a repository. Concurrent
+// lookups of the same client share one repository call, and configurations changed through the
+// repository are invalidated immediately. Changes made elsewhere are picked up after Invalidate is
+// called, which the server does for every change streamed from the DynamoDB clients table, or
+// otherwise once the TTL expires.
+type CachedRepository struct {
+	persistence.Repository
+
+	options CacheOptions
+	group   singleflight.Group
+	now     func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+
+	// generation is incremented by every invalidation so lookups started before it are not cached
+	generation uint64
+}
+
+// NewCachedRepository creates a cache in front of repo
+func NewCachedRepository(repo persistence.Repository, options CacheOptions) *CachedRepository {
+	return &CachedRepository{
+		Repository: repo,
+		options:    options,
+		now:        time.Now,
+		entries:    make(map[string]cacheEntry),
+	}
+}
+
+// GetClientConfig returns the cached configuration of a client, loading it on a miss
+func (c *CachedRepository) GetClientConfig(ctx context.Context, clientID string) (*models.ClientConfig, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[clientID]
+	generation := c.generation
+	c.mu.Unlock()
+
+	if ok && c.now().Before(entry.expires) {
+		if entry.config == nil {
+			metrics.ClientConfigCacheLookups.WithLabelValues(cacheNegativeHit).Inc()
+			return nil, fmt.Errorf("%w: %s", persistence.ErrClientConfigNotFound, clientID)
+		}
+		metrics.ClientConfigCacheLookups.WithLabelValues(cacheHit).Inc()
+		return entry.config.Clone(), nil
+	}
+	metrics.ClientConfigCacheLookups.WithLabelValues(cacheMiss).Inc()
+
+	result, err, _ := c.group.Do(clientID, func() (interface{}, error) {
+		return c.load(ctx, clientID, generation)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Callers may modify the configuration, so they never get the cached one
+	return result.(*models.ClientConfig).Clone(), nil
+}
+
+// PutClientConfig stores a client configuration and drops the cached one
+func (c *CachedRepository) PutClientConfig(ctx context.Context, config *models.ClientConfig) error {
+	defer c.Invalidate(config.ClientID)
+	return c.Repository.PutClientConfig(ctx, config)
+}
+
+// CreateClientConfig stores the configuration of a new client and drops the cached one, which may
+// remember that the client had none
+func (c *CachedRepository) CreateClientConfig(ctx context.Context, config *models.ClientConfig) error {
+	defer c.Invalidate(config.ClientID)
+	return c.Repository.CreateClientConfig(ctx, config)
+}
+
+// DeleteClientConfig removes a client configuration and drops the cached one
+func (c *CachedRepository) DeleteClientConfig(ctx context.Context, clientID string) error {
+	defer c.Invalidate(clientID)
+	return c.Repository.DeleteClientConfig(ctx, clientID)
+}
+
+// Invalidate drops the cached configuration of a client
+func (c *CachedRepository) Invalidate(clientID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, clientID)
+	c.generation++
+}
+
+// InvalidateAll drops every cached configuration
+func (c *CachedRepository) InvalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]cacheEntry)
+	c.generation++
+}
+
+// Close closes the repository when it buffers writes
+func (c *CachedRepository) Close(ctx context.Context) error {
+	if closer, ok := c.Repository.(persistence.Closer); ok {
+		return closer.Close(ctx)
+	}
+	return nil
+}
+
+// ReplaceEvent replaces a stored event, as putting a client under legal hold does; every repository
+// of the persistence package can
+func (c *CachedRepository) ReplaceEvent(ctx context.Context, event *models.ProcessedEvent, expectedRevision int64) error {
+	replacer, ok := c.Repository.(persistence.EventReplacer)
+	if !ok {
+		return fmt.Errorf("repository %T cannot replace events", c.Repository)
+	}
+	return replacer.ReplaceEvent(ctx, event, expectedRevision)
+}
+
+// load reads a configuration from the repository and caches the result unless the cache was
+// invalidated since generation; errors other than a missing configuration are never cached
+func (c *CachedRepository) load(ctx context.Context, clientID string, generation uint64) (*models.ClientConfig, error) {
+	config, err := c.Repository.GetClientConfig(ctx, clientID)
+
+	ttl := c.options.TTL
+	if errors.Is(err, persistence.ErrClientConfigNotFound) {
+		ttl = c.options.NegativeTTL
+	} else if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if ttl > 0 && c.generation == generation {
+		c.entries[clientID] = cacheEntry{config: config, expires: c.now().Add(ttl)}
+	}
+	c.mu.Unlock()
+
+	return config, err
+}
@@ -0,0 +1,277 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d-sense/event-processor/internal/persistence"
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// countingRepository counts client configuration lookups; lookups wait for release when it is set
+// and fail with err when it is set
+type countingRepository struct {
+	*persistence.MemoryRepository
+
+	lookups atomic.Int32
+	release chan struct{}
+	err     error
+}
+
+func (r *countingRepository) GetClientConfig(ctx context.Context, clientID string) (*models.ClientConfig, error) {
+	r.lookups.Add(1)
+	if r.release != nil {
+		<-r.release
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.MemoryRepository.GetClientConfig(ctx, clientID)
+}
+
+// newTestCache creates a cache with a controllable clock over a counting repository
+func newTestCache(options CacheOptions, configs ...*models.ClientConfig) (*CachedRepository, *countingRepository, *time.Time) {
+	repo := &countingRepository{MemoryRepository: persistence.NewMemoryRepository(configs...)}
+	cache := NewCachedRepository(repo, options)
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+	return cache, repo, &now
+}
+
+// TestCachedRepositoryLookups tests which lookups are answered from the cache
+func TestCachedRepositoryLookups(t *testing.T) {
+	ctx := context.Background()
+	options := CacheOptions{TTL: time.Minute, NegativeTTL: 10 * time.Second}
+
+	tests := []struct {
+		name            string
+		options         CacheOptions
+		lookups         func(t *testing.T, cache *CachedRepository, repo *countingRepository, now *time.Time)
+		expectedLookups int32
+		description     string
+	}{
+		{
+			name:    "Repeated Lookups",
+			options: options,
+			lookups: func(t *testing.T, cache *CachedRepository, repo *countingRepository, now *time.Time) {
+				for i := 0; i < 3; i++ {
+					config, err := cache.GetClientConfig(ctx, "client-001")
+					require.NoError(t, err)
+					assert.Equal(t, testClient("client-001"), config)
+				}
+			},
+			expectedLookups: 1,
+			description:     "Should read a configuration once within the TTL",
+		},
+		{
+			name:    "Expired Entry",
+			options: options,
+			lookups: func(t *testing.T, cache *CachedRepository, repo *countingRepository, now *time.Time) {
+				_, err := cache.GetClientConfig(ctx, "client-001")
+				require.NoError(t, err)
+				*now = now.Add(time.Minute)
+				_, err = cache.GetClientConfig(ctx, "client-001")
+				require.NoError(t, err)
+			},
+			expectedLookups: 2,
+			description:     "Should read the configuration again once the TTL has passed",
+		},
+		{
+			name:    "Missing Client",
+			options: options,
+			lookups: func(t *testing.T, cache *CachedRepository, repo *countingRepository, now *time.Time) {
+				for i := 0; i < 2; i++ {
+					_, err := cache.GetClientConfig(ctx, "client-404")
+					assert.ErrorIs(t, err, persistence.ErrClientConfigNotFound)
+					assert.ErrorContains(t, err, "client-404")
+				}
+				*now = now.Add(10 * time.Second)
+				_, err := cache.GetClientConfig(ctx, "client-404")
+				assert.ErrorIs(t, err, persistence.ErrClientConfigNotFound)
+			},
+			expectedLookups: 2,
+			description:     "Should remember missing clients for the negative TTL",
+		},
+		{
+			name:    "Negative Caching Disabled",
+			options: CacheOptions{TTL: time.Minute},
+			lookups: func(t *testing.T, cache *CachedRepository, repo *countingRepository, now *time.Time) {
+				for i := 0; i < 2; i++ {
+					_, err := cache.GetClientConfig(ctx, "client-404")
+					assert.ErrorIs(t, err, persistence.ErrClientConfigNotFound)
+				}
+			},
+			expectedLookups: 2,
+			description:     "Should look up missing clients every time without a negative TTL",
+		},
+		{
+			name:    "Caching Disabled",
+			options: CacheOptions{},
+			lookups: func(t *testing.T, cache *CachedRepository, repo *countingRepository, now *time.Time) {
+				for i := 0; i < 2; i++ {
+					_, err := cache.GetClientConfig(ctx, "client-001")
+					require.NoError(t, err)
+				}
+			},
+			expectedLookups: 2,
+			description:     "Should look up the configuration every time without a TTL",
+		},
+		{
+			name:    "Repository Failure",
+			options: options,
+			lookups: func(t *testing.T, cache *CachedRepository, repo *countingRepository, now *time.Time) {
+				repo.err = errors.New("throttled")
+				_, err := cache.GetClientConfig(ctx, "client-001")
+				assert.EqualError(t, err, "throttled")
+				repo.err = nil
+				_, err = cache.GetClientConfig(ctx, "client-001")
+				assert.NoError(t, err)
+			},
+			expectedLookups: 2,
+			description:     "Should not cache failed lookups",
+		},
+		{
+			name:    "Caller Modifies Config",
+			options: options,
+			lookups: func(t *testing.T, cache *CachedRepository, repo *countingRepository, now *time.Time) {
+				config, err := cache.GetClientConfig(ctx, "client-001")
+				require.NoError(t, err)
+				config.AllowedTypes[0] = models.EventTypeTransaction
+				config.Config["tier"] = "bronze"
+
+				config, err = cache.GetClientConfig(ctx, "client-001")
+				require.NoError(t, err)
+				assert.Equal(t, testClient("client-001"), config)
+			},
+			expectedLookups: 1,
+			description:     "Should hand out copies of the cached configuration",
+		},
+		{
+			name:    "Changed Through Cache",
+			options: options,
+			lookups: func(t *testing.T, cache *CachedRepository, repo *countingRepository, now *time.Time) {
+				_, err := cache.GetClientConfig(ctx, "client-001")
+				require.NoError(t, err)
+
+				disabled := testClient("client-001")
+				disabled.Active = false
+				require.NoError(t, cache.PutClientConfig(ctx, disabled))
+				config, err := cache.GetClientConfig(ctx, "client-001")
+				require.NoError(t, err)
+				assert.False(t, config.Active)
+
+				require.NoError(t, cache.DeleteClientConfig(ctx, "client-001"))
+				_, err = cache.GetClientConfig(ctx, "client-001")
+				assert.ErrorIs(t, err, persistence.ErrClientConfigNotFound)
+			},
+			expectedLookups: 3,
+			description:     "Should drop cached configurations that are changed through the cache",
+		},
+		{
+			name:    "Created Through Cache",
+			options: options,
+			lookups: func(t *testing.T, cache *CachedRepository, repo *countingRepository, now *time.Time) {
+				_, err := cache.GetClientConfig(ctx, "client-002")
+				assert.ErrorIs(t, err, persistence.ErrClientConfigNotFound)
+
+				require.NoError(t, cache.CreateClientConfig(ctx, testClient("client-002")))
+				config, err := cache.GetClientConfig(ctx, "client-002")
+				require.NoError(t, err)
+				assert.Equal(t, testClient("client-002"), config)
+			},
+			expectedLookups: 2,
+			description:     "Should forget that a client created through the cache had no configuration",
+		},
+		{
+			name:    "Invalidated",
+			options: options,
+			lookups: func(t *testing.T, cache *CachedRepository, repo *countingRepository, now *time.Time) {
+				_, err := cache.GetClientConfig(ctx, "client-001")
+				require.NoError(t, err)
+
+				// Changed by another instance
+				require.NoError(t, repo.MemoryRepository.DeleteClientConfig(ctx, "client-001"))
+				_, err = cache.GetClientConfig(ctx, "client-001")
+				require.NoError(t, err, "the stale configuration is served until invalidated")
+
+				cache.Invalidate("client-001")
+				_, err = cache.GetClientConfig(ctx, "client-001")
+				assert.ErrorIs(t, err, persistence.ErrClientConfigNotFound)
+
+				cache.InvalidateAll()
+				_, err = cache.GetClientConfig(ctx, "client-001")
+				assert.ErrorIs(t, err, persistence.ErrClientConfigNotFound)
+			},
+			expectedLookups: 3,
+			description:     "Should read configurations again after they are invalidated",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache, repo, now := newTestCache(tt.options, testClient("client-001"))
+
+			tt.lookups(t, cache, repo, now)
+
+			assert.Equal(t, tt.expectedLookups, repo.lookups.Load(), tt.description)
+		})
+	}
+}
+
+// TestCachedRepositorySharesConcurrentLookups tests that concurrent misses for a client read it once
+func TestCachedRepositorySharesConcurrentLookups(t *testing.T) {
+	cache, repo, _ := newTestCache(CacheOptions{TTL: time.Minute}, testClient("client-001"))
+	repo.release = make(chan struct{})
+
+	const callers = 10
+	var started, done sync.WaitGroup
+	started.Add(callers)
+	done.Add(callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			defer done.Done()
+			started.Done()
+			config, err := cache.GetClientConfig(context.Background(), "client-001")
+			assert.NoError(t, err)
+			assert.Equal(t, testClient("client-001"), config)
+		}()
+	}
+
+	started.Wait()
+	require.Eventually(t, func() bool { return repo.lookups.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(repo.release)
+	done.Wait()
+
+	assert.Equal(t, int32(1), repo.lookups.Load())
+}
+
+// TestCachedRepositoryInvalidatedDuringLookup tests that a lookup racing an invalidation is not cached
+func TestCachedRepositoryInvalidatedDuringLookup(t *testing.T) {
+	cache, repo, _ := newTestCache(CacheOptions{TTL: time.Minute}, testClient("client-001"))
+	repo.release = make(chan struct{})
+
+	lookupDone := make(chan struct{})
+	go func() {
+		defer close(lookupDone)
+		_, err := cache.GetClientConfig(context.Background(), "client-001")
+		assert.NoError(t, err)
+	}()
+
+	require.Eventually(t, func() bool { return repo.lookups.Load() == 1 }, time.Second, time.Millisecond)
+	cache.Invalidate("client-001")
+	close(repo.release)
+	<-lookupDone
+
+	repo.release = nil
+	_, err := cache.GetClientConfig(context.Background(), "client-001")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), repo.lookups.Load(), "the result read before the invalidation should not be cached")
+}
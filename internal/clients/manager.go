@@ -0,0 +1,256 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/d-sense/event-processor/internal/authz"
+	"github.com/d-sense/event-processor/internal/persistence"
+	"github.com/d-sense/event-processor/internal/ratelimit"
+	"github.com/d-sense/event-processor/internal/retention"
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+var (
+	// ErrInvalidConfig is returned when a client configuration fails validation
+	ErrInvalidConfig = errors.New("invalid client config")
+
+	// ErrClientExists is returned when creating a client that already has a configuration
+	ErrClientExists = errors.New("client already exists")
+
+	// ErrActorRequired is returned when a change does not say who made it
+	ErrActorRequired = errors.New("actor is required")
+)
+
+// clientIDPattern restricts client IDs to characters that are safe in URLs, log fields and archive paths
+var clientIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+
+// Store persists client configurations; every persistence.Repository is one
+type Store interface {
+	GetClientConfig(ctx context.Context, clientID string) (*models.ClientConfig, error)
+	PutClientConfig(ctx context.Context, config *models.ClientConfig) error
+	CreateClientConfig(ctx context.Context, config *models.ClientConfig) error
+	ListClientConfigs(ctx context.Context) ([]*models.ClientConfig, error)
+	DeleteClientConfig(ctx context.Context, clientID string) error
+}
+
+// Manager validates client configuration changes and records each one in the audit log
+type Manager struct {
+	store  Store
+	audit  AuditLog
+	logger *logrus.Logger
+	now    func() time.Time
+}
+
+// NewManager creates a manager storing configurations in store
+func NewManager(store Store, audit AuditLog, logger *logrus.Logger) *Manager {
+	return &Manager{
+		store:  store,
+		audit:  audit,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Validate checks a client configuration
+func Validate(config *models.ClientConfig) error {
+	var problems []error
+	if !clientIDPattern.MatchString(config.ClientID) {
+		problems = append(problems, fmt.Errorf("clientId %q must be 1 to 128 letters, digits, '.', '_' or '-'", config.ClientID))
+	}
+
+	seen := make(map[models.EventType]bool)
+	for _, eventType := range config.AllowedTypes {
+		if !models.IsValidEventType(string(eventType)) {
+			problems = append(problems, fmt.Errorf("unknown event type %q", eventType))
+		} else if seen[eventType] {
+			problems = append(problems, fmt.Errorf("event type %q is listed twice", eventType))
+		}
+		seen[eventType] = true
+	}
+
+	for key := range config.Config {
+		if strings.TrimSpace(key) == "" || strings.ContainsAny(key, " \t\n") {
+			problems = append(problems, fmt.Errorf("config key %q must not be empty or contain whitespace", key))
+		}
+	}
+
+	policyTypes := make([]models.EventType, 0, len(config.Policies))
+	for eventType := range config.Policies {
+		policyTypes = append(policyTypes, eventType)
+	}
+	sort.Slice(policyTypes, func(i, j int) bool { return policyTypes[i] < policyTypes[j] })
+	for _, eventType := range policyTypes {
+		if !seen[eventType] {
+			problems = append(problems, fmt.Errorf("policy for event type %q, which the client may not send", eventType))
+			continue
+		}
+		if err := authz.ValidatePolicy(config.Policies[eventType]); err != nil {
+			problems = append(problems, fmt.Errorf("policy for %s events: %w", eventType, err))
+		}
+	}
+
+	if config.Limits != nil {
+		if err := ratelimit.ValidateLimits(*config.Limits); err != nil {
+			problems = append(problems, fmt.Errorf("limits: %w", err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %w", ErrInvalidConfig, errors.Join(problems...))
+	}
+	return nil
+}
+
+// Get returns the configuration of a client
+func (m *Manager) Get(ctx context.Context, clientID string) (*models.ClientConfig, error) {
+	return m.store.GetClientConfig(ctx, clientID)
+}
+
+// List returns every client configuration
+func (m *Manager) List(ctx context.Context) ([]*models.ClientConfig, error) {
+	return m.store.ListClientConfigs(ctx)
+}
+
+// History returns the recorded changes to a client
+func (m *Manager) History(ctx context.Context, clientID string) ([]AuditEntry, error) {
+	return m.audit.ListClientChanges(ctx, clientID)
+}
+
+// Create stores the configuration of a new client
+func (m *Manager) Create(ctx context.Context, actor string, config *models.ClientConfig) (*models.ClientConfig, error) {
+	if err := m.check(actor, config); err != nil {
+		return nil, err
+	}
+
+	// Creating conditionally keeps concurrent creates of the same client from replacing each other
+	err := m.store.CreateClientConfig(ctx, config)
+	if errors.Is(err, persistence.ErrClientConfigExists) {
+		return nil, fmt.Errorf("%w: %s", ErrClientExists, config.ClientID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := m.record(ctx, actor, ActionCreate, config.ClientID, nil, config); err != nil {
+		return config, err
+	}
+	return config, m.hold(ctx, config)
+}
+
+// Update replaces the configuration of an existing client
+func (m *Manager) Update(ctx context.Context, actor string, config *models.ClientConfig) (*models.ClientConfig, error) {
+	if err := m.check(actor, config); err != nil {
+		return nil, err
+	}
+
+	before, err := m.store.GetClientConfig(ctx, config.ClientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.store.PutClientConfig(ctx, config); err != nil {
+		return nil, err
+	}
+	if err := m.record(ctx, actor, ActionUpdate, config.ClientID, before, config); err != nil {
+		return config, err
+	}
+	return config, m.hold(ctx, config)
+}
+
+// SetActive enables or disables a client; disabled clients have all of their events rejected
+func (m *Manager) SetActive(ctx context.Context, actor, clientID string, active bool) (*models.ClientConfig, error) {
+	if actor == "" {
+		return nil, ErrActorRequired
+	}
+
+	before, err := m.store.GetClientConfig(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	after := *before
+	after.Active = active
+	if err := m.store.PutClientConfig(ctx, &after); err != nil {
+		return nil, err
+	}
+
+	action := ActionDisable
+	if active {
+		action = ActionEnable
+	}
+	return &after, m.record(ctx, actor, action, clientID, before, &after)
+}
+
+// Delete removes the configuration of a client
+func (m *Manager) Delete(ctx context.Context, actor, clientID string) error {
+	if actor == "" {
+		return ErrActorRequired
+	}
+
+	before, err := m.store.GetClientConfig(ctx, clientID)
+	if err != nil {
+		return err
+	}
+
+	if err := m.store.DeleteClientConfig(ctx, clientID); err != nil {
+		return err
+	}
+	return m.record(ctx, actor, ActionDelete, clientID, before, nil)
+}
+
+// check validates the actor and configuration of a change
+func (m *Manager) check(actor string, config *models.ClientConfig) error {
+	if actor == "" {
+		return ErrActorRequired
+	}
+	return Validate(config)
+}
+
+// record writes an audit entry for a change that has been made
+func (m *Manager) record(ctx context.Context, actor string, action Action, clientID string, before, after *models.ClientConfig) error {
+	m.logger.WithFields(logrus.Fields{
+		"actor":     actor,
+		"action":    action,
+		"client_id": clientID,
+	}).Info("Client configuration changed")
+
+	entry := AuditEntry{
+		Time:     m.now().UTC(),
+		Actor:    actor,
+		Action:   action,
+		ClientID: clientID,
+		Before:   before,
+		After:    after,
+	}
+	if err := m.audit.RecordClientChange(ctx, entry); err != nil {
+		return fmt.Errorf("client %s was changed but the audit entry could not be written: %w", clientID, err)
+	}
+	return nil
+}
+
+// hold removes the expiry of the stored events of a client under legal hold, as the hold only keeps
+// events written from now on from expiring. It runs on every change to such a client, so a hold
+// that failed part way is completed by saving the client again.
+func (m *Manager) hold(ctx context.Context, config *models.ClientConfig) error {
+	events, ok := m.store.(retention.EventStore)
+	if !ok || !retention.LegalHold(config) {
+		return nil
+	}
+
+	held, err := retention.Hold(ctx, events, config.ClientID)
+	if err != nil {
+		return fmt.Errorf("client %s was changed but its stored events could not all be put on legal hold: %w", config.ClientID, err)
+	}
+	m.logger.WithFields(logrus.Fields{
+		"client_id": config.ClientID,
+		"held":      held,
+	}).Info("Stored events put on legal hold")
+	return nil
+}
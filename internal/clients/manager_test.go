@@ -0,0 +1,300 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d-sense/event-processor/internal/persistence"
+	"github.com/d-sense/event-processor/internal/retention"
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// failingAuditLog fails every write
+type failingAuditLog struct{}
+
+func (failingAuditLog) RecordClientChange(ctx context.Context, change models.ClientChange) error {
+	return errors.New("dynamodb error")
+}
+
+func (failingAuditLog) ListClientChanges(ctx context.Context, clientID string) ([]models.ClientChange, error) {
+	return nil, nil
+}
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+// newTestManager creates a manager over an in-memory repository keeping the audit log too
+func newTestManager(t *testing.T, configs ...*models.ClientConfig) (*Manager, *persistence.MemoryRepository) {
+	repo := persistence.NewMemoryRepository(configs...)
+	manager := NewManager(repo, repo, testLogger())
+	manager.now = func() time.Time { return time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC) }
+	return manager, repo
+}
+
+func testClient(clientID string) *models.ClientConfig {
+	return &models.ClientConfig{
+		ClientID:     clientID,
+		AllowedTypes: []models.EventType{models.EventTypeMonitoring},
+		Config:       map[string]string{"tier": "gold"},
+		Active:       true,
+	}
+}
+
+// TestValidate tests client configuration validation
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      *models.ClientConfig
+		expectError bool
+		errorMsg    string
+		description string
+	}{
+		{
+			name:        "Valid Config",
+			config:      testClient("client-001"),
+			expectError: false,
+			description: "Should accept a well formed configuration",
+		},
+		{
+			name:        "Empty Client ID",
+			config:      &models.ClientConfig{},
+			expectError: true,
+			errorMsg:    `clientId "" must be 1 to 128 letters`,
+			description: "Should require a client ID",
+		},
+		{
+			name:        "Client ID With Slash",
+			config:      &models.ClientConfig{ClientID: "client/001"},
+			expectError: true,
+			errorMsg:    `clientId "client/001"`,
+			description: "Should reject client IDs that are unsafe in paths",
+		},
+		{
+			name: "Unknown And Duplicate Types",
+			config: &models.ClientConfig{
+				ClientID:     "client-001",
+				AllowedTypes: []models.EventType{"billing", models.EventTypeMonitoring, models.EventTypeMonitoring},
+			},
+			expectError: true,
+			errorMsg:    "unknown event type \"billing\"\nevent type \"monitoring\" is listed twice",
+			description: "Should report every invalid allowed type",
+		},
+		{
+			name: "Policy For Type Not Allowed",
+			config: &models.ClientConfig{
+				ClientID:     "client-001",
+				AllowedTypes: []models.EventType{models.EventTypeMonitoring},
+				Policies:     map[models.EventType]models.EventPolicy{models.EventTypeTransaction: {}},
+			},
+			expectError: true,
+			errorMsg:    `policy for event type "transaction", which the client may not send`,
+			description: "Should reject policies for event types the client may not send",
+		},
+		{
+			name: "Invalid Policy",
+			config: &models.ClientConfig{
+				ClientID:     "client-001",
+				AllowedTypes: []models.EventType{models.EventTypeMonitoring},
+				Policies: map[models.EventType]models.EventPolicy{
+					models.EventTypeMonitoring: {Windows: []models.TimeWindow{{Start: "9", End: "17:00"}}},
+				},
+			},
+			expectError: true,
+			errorMsg:    `policy for monitoring events: window 1: invalid start`,
+			description: "Should reject policies that cannot be evaluated",
+		},
+		{
+			name: "Invalid Limits",
+			config: &models.ClientConfig{
+				ClientID:     "client-001",
+				AllowedTypes: []models.EventType{models.EventTypeMonitoring},
+				Limits:       &models.ClientLimits{RatePerSecond: 10, OnExcess: models.ExcessSample, SampleRate: 1.5},
+			},
+			expectError: true,
+			errorMsg:    "limits: sampleRate must be between 0 and 1",
+			description: "Should reject limits that cannot be enforced",
+		},
+		{
+			name:        "Blank Config Key",
+			config:      &models.ClientConfig{ClientID: "client-001", Config: map[string]string{" ": "x"}},
+			expectError: true,
+			errorMsg:    "must not be empty or contain whitespace",
+			description: "Should reject blank config keys",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.config)
+
+			if tt.expectError {
+				assert.ErrorIs(t, err, ErrInvalidConfig, tt.description)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+			} else {
+				assert.NoError(t, err, tt.description)
+			}
+		})
+	}
+}
+
+// TestManagerLifecycle tests creating, updating, disabling and deleting a client with its audit trail
+func TestManagerLifecycle(t *testing.T) {
+	ctx := context.Background()
+	manager, repo := newTestManager(t)
+
+	created, err := manager.Create(ctx, "alice", testClient("client-001"))
+	require.NoError(t, err)
+	assert.Equal(t, testClient("client-001"), created)
+
+	_, err = manager.Create(ctx, "alice", testClient("client-001"))
+	assert.ErrorIs(t, err, ErrClientExists)
+
+	updated := testClient("client-001")
+	updated.AllowedTypes = append(updated.AllowedTypes, models.EventTypeTransaction)
+	_, err = manager.Update(ctx, "bob", updated)
+	require.NoError(t, err)
+
+	disabled, err := manager.SetActive(ctx, "bob", "client-001", false)
+	require.NoError(t, err)
+	assert.False(t, disabled.Active)
+	stored, err := repo.GetClientConfig(ctx, "client-001")
+	require.NoError(t, err)
+	assert.Equal(t, disabled, stored)
+
+	require.NoError(t, manager.Delete(ctx, "carol", "client-001"))
+	_, err = repo.GetClientConfig(ctx, "client-001")
+	assert.ErrorIs(t, err, persistence.ErrClientConfigNotFound)
+
+	history, err := manager.History(ctx, "client-001")
+	require.NoError(t, err)
+	require.Len(t, history, 4)
+
+	var actions []Action
+	var actors []string
+	for _, entry := range history {
+		actions = append(actions, entry.Action)
+		actors = append(actors, entry.Actor)
+	}
+	assert.Equal(t, []Action{ActionCreate, ActionUpdate, ActionDisable, ActionDelete}, actions)
+	assert.Equal(t, []string{"alice", "bob", "bob", "carol"}, actors)
+
+	assert.Nil(t, history[0].Before)
+	assert.Equal(t, testClient("client-001"), history[1].Before)
+	assert.Equal(t, updated, history[1].After)
+	assert.Nil(t, history[3].After)
+	assert.Equal(t, time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC), history[0].Time)
+}
+
+// TestManagerLegalHold tests that putting a client under legal hold keeps its stored events too
+func TestManagerLegalHold(t *testing.T) {
+	ctx := context.Background()
+	manager, repo := newTestManager(t, testClient("client-001"))
+	// The server manages clients through the cache in front of its repository
+	manager.store = NewCachedRepository(repo, CacheOptions{TTL: time.Hour})
+	event := (&models.Event{EventID: "evt-1", EventType: models.EventTypeMonitoring, ClientID: "client-001", Timestamp: time.Now()}).ToProcessedEvent()
+	require.NoError(t, repo.SaveEvent(ctx, event))
+
+	_, err := manager.Update(ctx, "bob", testClient("client-001"))
+	require.NoError(t, err)
+	stored, err := repo.GetEvent(ctx, "evt-1")
+	require.NoError(t, err)
+	assert.NotZero(t, stored.TTL, "Should leave events of clients without a hold alone")
+
+	held := testClient("client-001")
+	held.Config[retention.ConfigKeyLegalHold] = "true"
+	_, err = manager.Update(ctx, "bob", held)
+	require.NoError(t, err)
+	stored, err = repo.GetEvent(ctx, "evt-1")
+	require.NoError(t, err)
+	assert.Zero(t, stored.TTL, "Should remove the expiry of events stored before the hold")
+}
+
+// TestManagerRejectsInvalidChanges tests that invalid changes are not stored or audited
+func TestManagerRejectsInvalidChanges(t *testing.T) {
+	ctx := context.Background()
+	manager, repo := newTestManager(t, testClient("client-001"))
+
+	tests := []struct {
+		name        string
+		change      func() error
+		expectError error
+		description string
+	}{
+		{
+			name: "Missing Actor",
+			change: func() error {
+				_, err := manager.Create(ctx, "", testClient("client-002"))
+				return err
+			},
+			expectError: ErrActorRequired,
+			description: "Should require the actor of a change",
+		},
+		{
+			name: "Invalid Config",
+			change: func() error {
+				_, err := manager.Update(ctx, "alice", &models.ClientConfig{ClientID: "client-001", AllowedTypes: []models.EventType{"billing"}})
+				return err
+			},
+			expectError: ErrInvalidConfig,
+			description: "Should validate updates",
+		},
+		{
+			name: "Update Missing Client",
+			change: func() error {
+				_, err := manager.Update(ctx, "alice", testClient("client-404"))
+				return err
+			},
+			expectError: persistence.ErrClientConfigNotFound,
+			description: "Should not create clients through updates",
+		},
+		{
+			name: "Disable Missing Client",
+			change: func() error {
+				_, err := manager.SetActive(ctx, "alice", "client-404", false)
+				return err
+			},
+			expectError: persistence.ErrClientConfigNotFound,
+			description: "Should report missing clients",
+		},
+		{
+			name: "Delete Without Actor",
+			change: func() error {
+				return manager.Delete(ctx, "", "client-001")
+			},
+			expectError: ErrActorRequired,
+			description: "Should require the actor of a deletion",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, tt.change(), tt.expectError, tt.description)
+		})
+	}
+
+	stored, err := repo.GetClientConfig(ctx, "client-001")
+	require.NoError(t, err)
+	assert.Equal(t, testClient("client-001"), stored)
+
+	history, err := manager.History(ctx, "")
+	require.NoError(t, err)
+	assert.Empty(t, history)
+}
+
+// TestManagerAuditFailure tests that a change whose audit entry cannot be written is reported
+func TestManagerAuditFailure(t *testing.T) {
+	manager := NewManager(persistence.NewMemoryRepository(), failingAuditLog{}, testLogger())
+
+	_, err := manager.Create(context.Background(), "alice", testClient("client-001"))
+	assert.EqualError(t, err, "client client-001 was changed but the audit entry could not be written: dynamodb error")
+}
@@ -2,9 +2,13 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"strconv"
 )
 
+// eventSchemaFile is the name of the event schema in the schema directory, see validator.EventSchemaFile
+const eventSchemaFile = "event-schema.json"
+
 type Config struct {
 	// AWS Configuration
 	AWSRegion          string
@@ -19,18 +23,86 @@ type Config struct {
 	SQSWaitTimeSeconds int64
 
 	// DynamoDB Configuration
-	DynamoDBTableName string
-	DynamoDBEndpoint  string
+	DynamoDBTableName            string
+	DynamoDBClientsTableName     string
+	DynamoDBLimitsTableName      string
+	DynamoDBQuarantineTableName  string
+	DynamoDBClientAuditTableName string
+	DynamoDBEndpoint             string
+
+	// DynamoDBBatchSize groups event writes into TransactWriteItems calls, by default one per worker so a
+	// batch is written as soon as every worker waits on it; 1 or less writes each event with PutItem
+	DynamoDBBatchSize       int
+	DynamoDBFlushIntervalMs int
+
+	// Storage Configuration
+	StorageBackend string
+	DatabaseURL    string
+
+	// Retention Configuration; TTLEventTypeDays overrides the default per event type, e.g. "transaction=365,monitoring=7"
+	TTLDefaultDays   int
+	TTLFailedDays    int
+	TTLEventTypeDays string
+
+	// Stream Configuration; StreamARN defaults to the latest stream of DynamoDBTableName
+	StreamARN            string
+	StreamSinks          string
+	StreamSQSQueueURL    string
+	StreamWebhookURL     string
+	StreamFilePath       string
+	StreamCheckpointPath string
+	StreamPollIntervalMs int
+
+	// StreamMaxDeliveryAttempts bounds how often a batch is sent to a sink before it is appended to
+	// StreamDeadLetterPath instead
+	StreamMaxDeliveryAttempts int
+	StreamDeadLetterPath      string
+
+	// Archive Configuration; ArchiveDestination is a local directory or s3://bucket/prefix
+	ArchiveDestination string
+	ArchiveFormat      string
+
+	// Archive files are filled in ArchiveSpoolDir and rolled at ArchiveRollSizeMB or ArchiveRollIntervalSeconds
+	ArchiveSpoolDir            string
+	ArchiveRollSizeMB          int
+	ArchiveRollIntervalSeconds int
+
+	// Infrastructure Configuration; the server only converges the spec when InfraApplyOnStart is set
+	InfraSpecPath     string
+	InfraApplyOnStart bool
+
+	// Admin Configuration; the client management API is disabled unless AdminToken is set
+	AdminToken string
+
+	// Client Cache Configuration; a TTL of 0 looks up the client configuration for every event
+	ClientCacheTTLSeconds         int
+	ClientCacheNegativeTTLSeconds int
+
+	// Authorization Configuration; AuthzMode is allow-unknown, deny-unknown or fail-closed
+	AuthzMode string
+
+	// Client Limits Configuration; the delay action holds an event for at most LimitMaxDelayMs
+	LimitMaxDelayMs int
 
 	// Service Configuration
 	ServicePort    string
 	WorkerPoolSize int
 	LogLevel       string
-	SchemaPath     string
+
+	// Schema Configuration; SchemaDir holds the event schema and the payload schemas, checked for
+	// changes every SchemaReloadIntervalSeconds (0 loads them once)
+	SchemaDir                   string
+	SchemaReloadIntervalSeconds int
+
+	// Warnings lists deprecated settings in use, for commands to log once they have a logger
+	Warnings []string
+
+	// Errors lists settings that cannot be honoured, for commands to fail on once they have a logger
+	Errors []string
 }
 
 func Load() *Config {
-	return &Config{
+	cfg := &Config{
 		// AWS Configuration
 		AWSRegion:          getEnv("AWS_REGION", "us-east-1"),
 		AWSAccessKeyID:     getEnv("AWS_ACCESS_KEY_ID", "test"),
@@ -44,15 +116,92 @@ func Load() *Config {
 		SQSWaitTimeSeconds: getEnvAsInt64("SQS_WAIT_TIME_SECONDS", 20),
 
 		// DynamoDB Configuration
-		DynamoDBTableName: getEnv("DYNAMODB_TABLE_NAME", "events"),
-		DynamoDBEndpoint:  getEnv("AWS_ENDPOINT_URL", "http://localhost:4566"), // Use AWS_ENDPOINT_URL for consistency
+		DynamoDBTableName:            getEnv("DYNAMODB_TABLE_NAME", "events"),
+		DynamoDBClientsTableName:     getEnv("DYNAMODB_CLIENTS_TABLE_NAME", "events-clients"),
+		DynamoDBLimitsTableName:      getEnv("DYNAMODB_LIMITS_TABLE_NAME", "events-limits"),
+		DynamoDBQuarantineTableName:  getEnv("DYNAMODB_QUARANTINE_TABLE_NAME", "events-quarantine"),
+		DynamoDBClientAuditTableName: getEnv("DYNAMODB_CLIENT_AUDIT_TABLE_NAME", "events-client-audit"),
+		DynamoDBEndpoint:             getEnv("AWS_ENDPOINT_URL", "http://localhost:4566"), // Use AWS_ENDPOINT_URL for consistency
+
+		DynamoDBFlushIntervalMs: getEnvAsInt("DYNAMODB_FLUSH_INTERVAL_MS", 50),
+
+		// Storage Configuration
+		StorageBackend: getEnv("STORAGE_BACKEND", "dynamodb"),
+		DatabaseURL:    getEnv("DATABASE_URL", ""),
+
+		// Retention Configuration
+		TTLDefaultDays:   getEnvAsInt("TTL_DEFAULT_DAYS", 30),
+		TTLFailedDays:    getEnvAsInt("TTL_FAILED_DAYS", 90),
+		TTLEventTypeDays: getEnv("TTL_EVENT_TYPE_DAYS", ""),
+
+		// Stream Configuration
+		StreamARN:            getEnv("STREAM_ARN", ""),
+		StreamSinks:          getEnv("STREAM_SINKS", "file"),
+		StreamSQSQueueURL:    getEnv("STREAM_SQS_QUEUE_URL", ""),
+		StreamWebhookURL:     getEnv("STREAM_WEBHOOK_URL", ""),
+		StreamFilePath:       getEnv("STREAM_FILE_PATH", "changes.ndjson"),
+		StreamCheckpointPath: getEnv("STREAM_CHECKPOINT_PATH", "stream-checkpoints.json"),
+		StreamPollIntervalMs: getEnvAsInt("STREAM_POLL_INTERVAL_MS", 1000),
+
+		StreamMaxDeliveryAttempts: getEnvAsInt("STREAM_MAX_DELIVERY_ATTEMPTS", 10),
+		StreamDeadLetterPath:      getEnv("STREAM_DEAD_LETTER_PATH", "stream-dead-letters.ndjson"),
+
+		// Archive Configuration
+		ArchiveDestination: getEnv("ARCHIVE_DESTINATION", "archive"),
+		ArchiveFormat:      getEnv("ARCHIVE_FORMAT", "ndjson"),
+
+		ArchiveSpoolDir:            getEnv("ARCHIVE_SPOOL_DIR", "archive-spool"),
+		ArchiveRollSizeMB:          getEnvAsInt("ARCHIVE_ROLL_SIZE_MB", 64),
+		ArchiveRollIntervalSeconds: getEnvAsInt("ARCHIVE_ROLL_INTERVAL_SECONDS", 300),
+
+		// Infrastructure Configuration
+		InfraSpecPath:     getEnv("INFRA_SPEC_PATH", "deployments/infrastructure.yaml"),
+		InfraApplyOnStart: getEnvAsBool("INFRA_APPLY_ON_START", false),
+
+		// Admin Configuration
+		AdminToken: getEnv("ADMIN_TOKEN", ""),
+
+		// Client Cache Configuration
+		ClientCacheTTLSeconds:         getEnvAsInt("CLIENT_CACHE_TTL_SECONDS", 60),
+		ClientCacheNegativeTTLSeconds: getEnvAsInt("CLIENT_CACHE_NEGATIVE_TTL_SECONDS", 10),
+
+		// Authorization Configuration
+		AuthzMode: getEnv("AUTHZ_MODE", "allow-unknown"),
+
+		// Client Limits Configuration
+		LimitMaxDelayMs: getEnvAsInt("LIMIT_MAX_DELAY_MS", 5000),
 
 		// Service Configuration
 		ServicePort:    getEnv("SERVICE_PORT", "8080"),
 		WorkerPoolSize: getEnvAsInt("WORKER_POOL_SIZE", 10),
 		LogLevel:       getEnv("LOG_LEVEL", "info"),
-		SchemaPath:     getEnv("SCHEMA_PATH", "../../schemas/event-schema.json"),
+
+		// Schema Configuration
+		SchemaDir:                   getEnv("SCHEMA_DIR", "../../schemas"),
+		SchemaReloadIntervalSeconds: getEnvAsInt("SCHEMA_RELOAD_INTERVAL_SECONDS", 10),
+	}
+
+	// Each worker waits on at most one write, so a batch of one item per worker is full once all
+	// of them are waiting
+	cfg.DynamoDBBatchSize = getEnvAsInt("DYNAMODB_BATCH_SIZE", cfg.WorkerPoolSize)
+
+	// SCHEMA_PATH named the event schema file before SCHEMA_DIR replaced it; deployments still
+	// setting it get the directory of that file, which only works if the file has the name the
+	// schema directory gives it
+	if schemaPath := os.Getenv("SCHEMA_PATH"); schemaPath != "" {
+		switch {
+		case os.Getenv("SCHEMA_DIR") != "":
+			cfg.Warnings = append(cfg.Warnings, "SCHEMA_PATH is deprecated and ignored as SCHEMA_DIR is set")
+		case filepath.Base(schemaPath) != eventSchemaFile:
+			cfg.Errors = append(cfg.Errors, "SCHEMA_PATH is deprecated and its file must be named "+eventSchemaFile+
+				", rename "+schemaPath+" and set SCHEMA_DIR to its directory instead")
+		default:
+			cfg.SchemaDir = filepath.Dir(schemaPath)
+			cfg.Warnings = append(cfg.Warnings, "SCHEMA_PATH is deprecated, set SCHEMA_DIR to "+cfg.SchemaDir+" instead")
+		}
 	}
+
+	return cfg
 }
 
 func getEnv(key, defaultValue string) string {
@@ -79,3 +228,12 @@ func getEnvAsInt64(key string, defaultValue int64) int64 {
 	}
 	return defaultValue
 }
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
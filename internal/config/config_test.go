@@ -33,6 +33,15 @@ type getEnvAsIntTestCase struct {
 	description    string
 }
 
+type getEnvAsBoolTestCase struct {
+	name           string
+	key            string
+	defaultValue   bool
+	envValue       string
+	expectedResult bool
+	description    string
+}
+
 type getEnvAsInt64TestCase struct {
 	name           string
 	key            string
@@ -49,20 +58,46 @@ func TestLoad(t *testing.T) {
 			name:    "Default Configuration - No Environment Variables",
 			envVars: map[string]string{},
 			expectedConfig: &Config{
-				AWSRegion:          "us-east-1",
-				AWSAccessKeyID:     "test",
-				AWSSecretAccessKey: "test",
-				AWSEndpointURL:     "http://localhost:4566",
-				SQSQueueURL:        "http://localhost:4566/000000000000/event-queue",
-				SQSDLQUrl:          "http://localhost:4566/000000000000/event-dlq",
-				SQSMaxMessages:     10,
-				SQSWaitTimeSeconds: 20,
-				DynamoDBTableName:  "events",
-				DynamoDBEndpoint:   "http://localhost:4566",
-				ServicePort:        "8080",
-				WorkerPoolSize:     10,
-				LogLevel:           "info",
-				SchemaPath:         "../../schemas/event-schema.json",
+				AWSRegion:                     "us-east-1",
+				AWSAccessKeyID:                "test",
+				AWSSecretAccessKey:            "test",
+				AWSEndpointURL:                "http://localhost:4566",
+				SQSQueueURL:                   "http://localhost:4566/000000000000/event-queue",
+				SQSDLQUrl:                     "http://localhost:4566/000000000000/event-dlq",
+				SQSMaxMessages:                10,
+				SQSWaitTimeSeconds:            20,
+				DynamoDBTableName:             "events",
+				DynamoDBClientsTableName:      "events-clients",
+				DynamoDBLimitsTableName:       "events-limits",
+				DynamoDBQuarantineTableName:   "events-quarantine",
+				DynamoDBClientAuditTableName:  "events-client-audit",
+				DynamoDBEndpoint:              "http://localhost:4566",
+				DynamoDBBatchSize:             10,
+				DynamoDBFlushIntervalMs:       50,
+				StorageBackend:                "dynamodb",
+				TTLDefaultDays:                30,
+				TTLFailedDays:                 90,
+				StreamSinks:                   "file",
+				StreamFilePath:                "changes.ndjson",
+				StreamCheckpointPath:          "stream-checkpoints.json",
+				StreamPollIntervalMs:          1000,
+				StreamMaxDeliveryAttempts:     10,
+				StreamDeadLetterPath:          "stream-dead-letters.ndjson",
+				ArchiveDestination:            "archive",
+				ArchiveFormat:                 "ndjson",
+				ArchiveSpoolDir:               "archive-spool",
+				ArchiveRollSizeMB:             64,
+				ArchiveRollIntervalSeconds:    300,
+				InfraSpecPath:                 "deployments/infrastructure.yaml",
+				ClientCacheTTLSeconds:         60,
+				ClientCacheNegativeTTLSeconds: 10,
+				AuthzMode:                     "allow-unknown",
+				LimitMaxDelayMs:               5000,
+				ServicePort:                   "8080",
+				WorkerPoolSize:                10,
+				LogLevel:                      "info",
+				SchemaDir:                     "../../schemas",
+				SchemaReloadIntervalSeconds:   10,
 			},
 			description: "Should load default configuration when no environment variables are set",
 		},
@@ -75,20 +110,46 @@ func TestLoad(t *testing.T) {
 				"AWS_ENDPOINT_URL":      "https://custom-endpoint.com",
 			},
 			expectedConfig: &Config{
-				AWSRegion:          "eu-west-1",
-				AWSAccessKeyID:     "custom-key",
-				AWSSecretAccessKey: "custom-secret",
-				AWSEndpointURL:     "https://custom-endpoint.com",
-				SQSQueueURL:        "http://localhost:4566/000000000000/event-queue",
-				SQSDLQUrl:          "http://localhost:4566/000000000000/event-dlq",
-				SQSMaxMessages:     10,
-				SQSWaitTimeSeconds: 20,
-				DynamoDBTableName:  "events",
-				DynamoDBEndpoint:   "https://custom-endpoint.com", // Should use AWS_ENDPOINT_URL
-				ServicePort:        "8080",
-				WorkerPoolSize:     10,
-				LogLevel:           "info",
-				SchemaPath:         "../../schemas/event-schema.json",
+				AWSRegion:                     "eu-west-1",
+				AWSAccessKeyID:                "custom-key",
+				AWSSecretAccessKey:            "custom-secret",
+				AWSEndpointURL:                "https://custom-endpoint.com",
+				SQSQueueURL:                   "http://localhost:4566/000000000000/event-queue",
+				SQSDLQUrl:                     "http://localhost:4566/000000000000/event-dlq",
+				SQSMaxMessages:                10,
+				SQSWaitTimeSeconds:            20,
+				DynamoDBTableName:             "events",
+				DynamoDBClientsTableName:      "events-clients",
+				DynamoDBLimitsTableName:       "events-limits",
+				DynamoDBQuarantineTableName:   "events-quarantine",
+				DynamoDBClientAuditTableName:  "events-client-audit",
+				DynamoDBEndpoint:              "https://custom-endpoint.com", // Should use AWS_ENDPOINT_URL
+				DynamoDBBatchSize:             10,
+				DynamoDBFlushIntervalMs:       50,
+				StorageBackend:                "dynamodb",
+				TTLDefaultDays:                30,
+				TTLFailedDays:                 90,
+				StreamSinks:                   "file",
+				StreamFilePath:                "changes.ndjson",
+				StreamCheckpointPath:          "stream-checkpoints.json",
+				StreamPollIntervalMs:          1000,
+				StreamMaxDeliveryAttempts:     10,
+				StreamDeadLetterPath:          "stream-dead-letters.ndjson",
+				ArchiveDestination:            "archive",
+				ArchiveFormat:                 "ndjson",
+				ArchiveSpoolDir:               "archive-spool",
+				ArchiveRollSizeMB:             64,
+				ArchiveRollIntervalSeconds:    300,
+				InfraSpecPath:                 "deployments/infrastructure.yaml",
+				ClientCacheTTLSeconds:         60,
+				ClientCacheNegativeTTLSeconds: 10,
+				AuthzMode:                     "allow-unknown",
+				LimitMaxDelayMs:               5000,
+				ServicePort:                   "8080",
+				WorkerPoolSize:                10,
+				LogLevel:                      "info",
+				SchemaDir:                     "../../schemas",
+				SchemaReloadIntervalSeconds:   10,
 			},
 			description: "Should override AWS configuration with environment variables",
 		},
@@ -101,69 +162,151 @@ func TestLoad(t *testing.T) {
 				"SQS_WAIT_TIME_SECONDS": "30",
 			},
 			expectedConfig: &Config{
-				AWSRegion:          "us-east-1",
-				AWSAccessKeyID:     "test",
-				AWSSecretAccessKey: "test",
-				AWSEndpointURL:     "http://localhost:4566",
-				SQSQueueURL:        "https://sqs.custom.com/queue",
-				SQSDLQUrl:          "https://sqs.custom.com/dlq",
-				SQSMaxMessages:     25,
-				SQSWaitTimeSeconds: 30,
-				DynamoDBTableName:  "events",
-				DynamoDBEndpoint:   "http://localhost:4566",
-				ServicePort:        "8080",
-				WorkerPoolSize:     10,
-				LogLevel:           "info",
-				SchemaPath:         "../../schemas/event-schema.json",
+				AWSRegion:                     "us-east-1",
+				AWSAccessKeyID:                "test",
+				AWSSecretAccessKey:            "test",
+				AWSEndpointURL:                "http://localhost:4566",
+				SQSQueueURL:                   "https://sqs.custom.com/queue",
+				SQSDLQUrl:                     "https://sqs.custom.com/dlq",
+				SQSMaxMessages:                25,
+				SQSWaitTimeSeconds:            30,
+				DynamoDBTableName:             "events",
+				DynamoDBClientsTableName:      "events-clients",
+				DynamoDBLimitsTableName:       "events-limits",
+				DynamoDBQuarantineTableName:   "events-quarantine",
+				DynamoDBClientAuditTableName:  "events-client-audit",
+				DynamoDBEndpoint:              "http://localhost:4566",
+				DynamoDBBatchSize:             10,
+				DynamoDBFlushIntervalMs:       50,
+				StorageBackend:                "dynamodb",
+				TTLDefaultDays:                30,
+				TTLFailedDays:                 90,
+				StreamSinks:                   "file",
+				StreamFilePath:                "changes.ndjson",
+				StreamCheckpointPath:          "stream-checkpoints.json",
+				StreamPollIntervalMs:          1000,
+				StreamMaxDeliveryAttempts:     10,
+				StreamDeadLetterPath:          "stream-dead-letters.ndjson",
+				ArchiveDestination:            "archive",
+				ArchiveFormat:                 "ndjson",
+				ArchiveSpoolDir:               "archive-spool",
+				ArchiveRollSizeMB:             64,
+				ArchiveRollIntervalSeconds:    300,
+				InfraSpecPath:                 "deployments/infrastructure.yaml",
+				ClientCacheTTLSeconds:         60,
+				ClientCacheNegativeTTLSeconds: 10,
+				AuthzMode:                     "allow-unknown",
+				LimitMaxDelayMs:               5000,
+				ServicePort:                   "8080",
+				WorkerPoolSize:                10,
+				LogLevel:                      "info",
+				SchemaDir:                     "../../schemas",
+				SchemaReloadIntervalSeconds:   10,
 			},
 			description: "Should override SQS configuration with environment variables",
 		},
 		{
 			name: "Custom DynamoDB Configuration",
 			envVars: map[string]string{
-				"DYNAMODB_TABLE_NAME": "custom-events-table",
+				"DYNAMODB_TABLE_NAME":              "custom-events-table",
+				"DYNAMODB_BATCH_SIZE":              "10",
+				"DYNAMODB_FLUSH_INTERVAL_MS":       "200",
+				"DYNAMODB_CLIENT_AUDIT_TABLE_NAME": "custom-client-audit",
 			},
 			expectedConfig: &Config{
-				AWSRegion:          "us-east-1",
-				AWSAccessKeyID:     "test",
-				AWSSecretAccessKey: "test",
-				AWSEndpointURL:     "http://localhost:4566",
-				SQSQueueURL:        "http://localhost:4566/000000000000/event-queue",
-				SQSDLQUrl:          "http://localhost:4566/000000000000/event-dlq",
-				SQSMaxMessages:     10,
-				SQSWaitTimeSeconds: 20,
-				DynamoDBTableName:  "custom-events-table",
-				DynamoDBEndpoint:   "http://localhost:4566",
-				ServicePort:        "8080",
-				WorkerPoolSize:     10,
-				LogLevel:           "info",
-				SchemaPath:         "../../schemas/event-schema.json",
-			},
-			description: "Should override DynamoDB table name with environment variable",
+				AWSRegion:                     "us-east-1",
+				AWSAccessKeyID:                "test",
+				AWSSecretAccessKey:            "test",
+				AWSEndpointURL:                "http://localhost:4566",
+				SQSQueueURL:                   "http://localhost:4566/000000000000/event-queue",
+				SQSDLQUrl:                     "http://localhost:4566/000000000000/event-dlq",
+				SQSMaxMessages:                10,
+				SQSWaitTimeSeconds:            20,
+				DynamoDBTableName:             "custom-events-table",
+				DynamoDBClientsTableName:      "events-clients",
+				DynamoDBLimitsTableName:       "events-limits",
+				DynamoDBQuarantineTableName:   "events-quarantine",
+				DynamoDBClientAuditTableName:  "custom-client-audit",
+				DynamoDBEndpoint:              "http://localhost:4566",
+				DynamoDBBatchSize:             10,
+				DynamoDBFlushIntervalMs:       200,
+				StorageBackend:                "dynamodb",
+				TTLDefaultDays:                30,
+				TTLFailedDays:                 90,
+				StreamSinks:                   "file",
+				StreamFilePath:                "changes.ndjson",
+				StreamCheckpointPath:          "stream-checkpoints.json",
+				StreamPollIntervalMs:          1000,
+				StreamMaxDeliveryAttempts:     10,
+				StreamDeadLetterPath:          "stream-dead-letters.ndjson",
+				ArchiveDestination:            "archive",
+				ArchiveFormat:                 "ndjson",
+				ArchiveSpoolDir:               "archive-spool",
+				ArchiveRollSizeMB:             64,
+				ArchiveRollIntervalSeconds:    300,
+				InfraSpecPath:                 "deployments/infrastructure.yaml",
+				ClientCacheTTLSeconds:         60,
+				ClientCacheNegativeTTLSeconds: 10,
+				AuthzMode:                     "allow-unknown",
+				LimitMaxDelayMs:               5000,
+				ServicePort:                   "8080",
+				WorkerPoolSize:                10,
+				LogLevel:                      "info",
+				SchemaDir:                     "../../schemas",
+				SchemaReloadIntervalSeconds:   10,
+			},
+			description: "Should override DynamoDB table name and batching with environment variables",
 		},
 		{
 			name: "Custom Service Configuration",
 			envVars: map[string]string{
-				"SERVICE_PORT":     "9090",
-				"WORKER_POOL_SIZE": "20",
-				"LOG_LEVEL":        "debug",
-				"SCHEMA_PATH":      "/custom/schema/path.json",
+				"SERVICE_PORT":                   "9090",
+				"WORKER_POOL_SIZE":               "20",
+				"LOG_LEVEL":                      "debug",
+				"SCHEMA_DIR":                     "/custom/schemas",
+				"SCHEMA_RELOAD_INTERVAL_SECONDS": "30",
 			},
 			expectedConfig: &Config{
-				AWSRegion:          "us-east-1",
-				AWSAccessKeyID:     "test",
-				AWSSecretAccessKey: "test",
-				AWSEndpointURL:     "http://localhost:4566",
-				SQSQueueURL:        "http://localhost:4566/000000000000/event-queue",
-				SQSDLQUrl:          "http://localhost:4566/000000000000/event-dlq",
-				SQSMaxMessages:     10,
-				SQSWaitTimeSeconds: 20,
-				DynamoDBTableName:  "events",
-				DynamoDBEndpoint:   "http://localhost:4566",
-				ServicePort:        "9090",
-				WorkerPoolSize:     20,
-				LogLevel:           "debug",
-				SchemaPath:         "/custom/schema/path.json",
+				AWSRegion:                     "us-east-1",
+				AWSAccessKeyID:                "test",
+				AWSSecretAccessKey:            "test",
+				AWSEndpointURL:                "http://localhost:4566",
+				SQSQueueURL:                   "http://localhost:4566/000000000000/event-queue",
+				SQSDLQUrl:                     "http://localhost:4566/000000000000/event-dlq",
+				SQSMaxMessages:                10,
+				SQSWaitTimeSeconds:            20,
+				DynamoDBTableName:             "events",
+				DynamoDBClientsTableName:      "events-clients",
+				DynamoDBLimitsTableName:       "events-limits",
+				DynamoDBQuarantineTableName:   "events-quarantine",
+				DynamoDBClientAuditTableName:  "events-client-audit",
+				DynamoDBEndpoint:              "http://localhost:4566",
+				DynamoDBBatchSize:             20,
+				DynamoDBFlushIntervalMs:       50,
+				StorageBackend:                "dynamodb",
+				TTLDefaultDays:                30,
+				TTLFailedDays:                 90,
+				StreamSinks:                   "file",
+				StreamFilePath:                "changes.ndjson",
+				StreamCheckpointPath:          "stream-checkpoints.json",
+				StreamPollIntervalMs:          1000,
+				StreamMaxDeliveryAttempts:     10,
+				StreamDeadLetterPath:          "stream-dead-letters.ndjson",
+				ArchiveDestination:            "archive",
+				ArchiveFormat:                 "ndjson",
+				ArchiveSpoolDir:               "archive-spool",
+				ArchiveRollSizeMB:             64,
+				ArchiveRollIntervalSeconds:    300,
+				InfraSpecPath:                 "deployments/infrastructure.yaml",
+				ClientCacheTTLSeconds:         60,
+				ClientCacheNegativeTTLSeconds: 10,
+				AuthzMode:                     "allow-unknown",
+				LimitMaxDelayMs:               5000,
+				ServicePort:                   "9090",
+				WorkerPoolSize:                20,
+				LogLevel:                      "debug",
+				SchemaDir:                     "/custom/schemas",
+				SchemaReloadIntervalSeconds:   30,
 			},
 			description: "Should override service configuration with environment variables",
 		},
@@ -182,23 +325,49 @@ func TestLoad(t *testing.T) {
 				"SERVICE_PORT":          "8443",
 				"WORKER_POOL_SIZE":      "50",
 				"LOG_LEVEL":             "warn",
-				"SCHEMA_PATH":           "/etc/event-processor/schemas/event-schema.json",
+				"SCHEMA_DIR":            "/etc/event-processor/schemas",
 			},
 			expectedConfig: &Config{
-				AWSRegion:          "ap-southeast-1",
-				AWSAccessKeyID:     "prod-key",
-				AWSSecretAccessKey: "prod-secret",
-				AWSEndpointURL:     "https://prod-endpoint.aws.com",
-				SQSQueueURL:        "https://prod-sqs.aws.com/events",
-				SQSDLQUrl:          "https://prod-sqs.aws.com/events-dlq",
-				SQSMaxMessages:     50,
-				SQSWaitTimeSeconds: 60,
-				DynamoDBTableName:  "prod-events",
-				DynamoDBEndpoint:   "https://prod-endpoint.aws.com",
-				ServicePort:        "8443",
-				WorkerPoolSize:     50,
-				LogLevel:           "warn",
-				SchemaPath:         "/etc/event-processor/schemas/event-schema.json",
+				AWSRegion:                     "ap-southeast-1",
+				AWSAccessKeyID:                "prod-key",
+				AWSSecretAccessKey:            "prod-secret",
+				AWSEndpointURL:                "https://prod-endpoint.aws.com",
+				SQSQueueURL:                   "https://prod-sqs.aws.com/events",
+				SQSDLQUrl:                     "https://prod-sqs.aws.com/events-dlq",
+				SQSMaxMessages:                50,
+				SQSWaitTimeSeconds:            60,
+				DynamoDBTableName:             "prod-events",
+				DynamoDBClientsTableName:      "events-clients",
+				DynamoDBLimitsTableName:       "events-limits",
+				DynamoDBQuarantineTableName:   "events-quarantine",
+				DynamoDBClientAuditTableName:  "events-client-audit",
+				DynamoDBEndpoint:              "https://prod-endpoint.aws.com",
+				DynamoDBBatchSize:             50,
+				DynamoDBFlushIntervalMs:       50,
+				StorageBackend:                "dynamodb",
+				TTLDefaultDays:                30,
+				TTLFailedDays:                 90,
+				StreamSinks:                   "file",
+				StreamFilePath:                "changes.ndjson",
+				StreamCheckpointPath:          "stream-checkpoints.json",
+				StreamPollIntervalMs:          1000,
+				StreamMaxDeliveryAttempts:     10,
+				StreamDeadLetterPath:          "stream-dead-letters.ndjson",
+				ArchiveDestination:            "archive",
+				ArchiveFormat:                 "ndjson",
+				ArchiveSpoolDir:               "archive-spool",
+				ArchiveRollSizeMB:             64,
+				ArchiveRollIntervalSeconds:    300,
+				InfraSpecPath:                 "deployments/infrastructure.yaml",
+				ClientCacheTTLSeconds:         60,
+				ClientCacheNegativeTTLSeconds: 10,
+				AuthzMode:                     "allow-unknown",
+				LimitMaxDelayMs:               5000,
+				ServicePort:                   "8443",
+				WorkerPoolSize:                50,
+				LogLevel:                      "warn",
+				SchemaDir:                     "/etc/event-processor/schemas",
+				SchemaReloadIntervalSeconds:   10,
 			},
 			description: "Should override all configuration with environment variables",
 		},
@@ -211,23 +380,561 @@ func TestLoad(t *testing.T) {
 				"LOG_LEVEL":        "error",
 			},
 			expectedConfig: &Config{
-				AWSRegion:          "ca-central-1",
-				AWSAccessKeyID:     "test",
-				AWSSecretAccessKey: "test",
-				AWSEndpointURL:     "http://localhost:4566",
-				SQSQueueURL:        "https://mixed-sqs.com/queue",
-				SQSDLQUrl:          "http://localhost:4566/000000000000/event-dlq",
-				SQSMaxMessages:     10,
-				SQSWaitTimeSeconds: 20,
-				DynamoDBTableName:  "events",
-				DynamoDBEndpoint:   "http://localhost:4566",
-				ServicePort:        "8080",
-				WorkerPoolSize:     15,
-				LogLevel:           "error",
-				SchemaPath:         "../../schemas/event-schema.json",
+				AWSRegion:                     "ca-central-1",
+				AWSAccessKeyID:                "test",
+				AWSSecretAccessKey:            "test",
+				AWSEndpointURL:                "http://localhost:4566",
+				SQSQueueURL:                   "https://mixed-sqs.com/queue",
+				SQSDLQUrl:                     "http://localhost:4566/000000000000/event-dlq",
+				SQSMaxMessages:                10,
+				SQSWaitTimeSeconds:            20,
+				DynamoDBTableName:             "events",
+				DynamoDBClientsTableName:      "events-clients",
+				DynamoDBLimitsTableName:       "events-limits",
+				DynamoDBQuarantineTableName:   "events-quarantine",
+				DynamoDBClientAuditTableName:  "events-client-audit",
+				DynamoDBEndpoint:              "http://localhost:4566",
+				DynamoDBBatchSize:             15,
+				DynamoDBFlushIntervalMs:       50,
+				StorageBackend:                "dynamodb",
+				TTLDefaultDays:                30,
+				TTLFailedDays:                 90,
+				StreamSinks:                   "file",
+				StreamFilePath:                "changes.ndjson",
+				StreamCheckpointPath:          "stream-checkpoints.json",
+				StreamPollIntervalMs:          1000,
+				StreamMaxDeliveryAttempts:     10,
+				StreamDeadLetterPath:          "stream-dead-letters.ndjson",
+				ArchiveDestination:            "archive",
+				ArchiveFormat:                 "ndjson",
+				ArchiveSpoolDir:               "archive-spool",
+				ArchiveRollSizeMB:             64,
+				ArchiveRollIntervalSeconds:    300,
+				InfraSpecPath:                 "deployments/infrastructure.yaml",
+				ClientCacheTTLSeconds:         60,
+				ClientCacheNegativeTTLSeconds: 10,
+				AuthzMode:                     "allow-unknown",
+				LimitMaxDelayMs:               5000,
+				ServicePort:                   "8080",
+				WorkerPoolSize:                15,
+				LogLevel:                      "error",
+				SchemaDir:                     "../../schemas",
+				SchemaReloadIntervalSeconds:   10,
 			},
 			description: "Should mix custom and default configuration values",
 		},
+		{
+			name: "Custom Storage Configuration",
+			envVars: map[string]string{
+				"STORAGE_BACKEND": "postgres",
+				"DATABASE_URL":    "postgres://events:secret@db:5432/events",
+			},
+			expectedConfig: &Config{
+				AWSRegion:                     "us-east-1",
+				AWSAccessKeyID:                "test",
+				AWSSecretAccessKey:            "test",
+				AWSEndpointURL:                "http://localhost:4566",
+				SQSQueueURL:                   "http://localhost:4566/000000000000/event-queue",
+				SQSDLQUrl:                     "http://localhost:4566/000000000000/event-dlq",
+				SQSMaxMessages:                10,
+				SQSWaitTimeSeconds:            20,
+				DynamoDBTableName:             "events",
+				DynamoDBClientsTableName:      "events-clients",
+				DynamoDBLimitsTableName:       "events-limits",
+				DynamoDBQuarantineTableName:   "events-quarantine",
+				DynamoDBClientAuditTableName:  "events-client-audit",
+				DynamoDBEndpoint:              "http://localhost:4566",
+				DynamoDBBatchSize:             10,
+				DynamoDBFlushIntervalMs:       50,
+				StorageBackend:                "postgres",
+				DatabaseURL:                   "postgres://events:secret@db:5432/events",
+				TTLDefaultDays:                30,
+				TTLFailedDays:                 90,
+				StreamSinks:                   "file",
+				StreamFilePath:                "changes.ndjson",
+				StreamCheckpointPath:          "stream-checkpoints.json",
+				StreamPollIntervalMs:          1000,
+				StreamMaxDeliveryAttempts:     10,
+				StreamDeadLetterPath:          "stream-dead-letters.ndjson",
+				ArchiveDestination:            "archive",
+				ArchiveFormat:                 "ndjson",
+				ArchiveSpoolDir:               "archive-spool",
+				ArchiveRollSizeMB:             64,
+				ArchiveRollIntervalSeconds:    300,
+				InfraSpecPath:                 "deployments/infrastructure.yaml",
+				ClientCacheTTLSeconds:         60,
+				ClientCacheNegativeTTLSeconds: 10,
+				AuthzMode:                     "allow-unknown",
+				LimitMaxDelayMs:               5000,
+				ServicePort:                   "8080",
+				WorkerPoolSize:                10,
+				LogLevel:                      "info",
+				SchemaDir:                     "../../schemas",
+				SchemaReloadIntervalSeconds:   10,
+			},
+			description: "Should select the storage backend and database URL from environment variables",
+		},
+		{
+			name: "Custom Retention Configuration",
+			envVars: map[string]string{
+				"TTL_DEFAULT_DAYS":    "14",
+				"TTL_FAILED_DAYS":     "180",
+				"TTL_EVENT_TYPE_DAYS": "transaction=365,monitoring=7",
+			},
+			expectedConfig: &Config{
+				AWSRegion:                     "us-east-1",
+				AWSAccessKeyID:                "test",
+				AWSSecretAccessKey:            "test",
+				AWSEndpointURL:                "http://localhost:4566",
+				SQSQueueURL:                   "http://localhost:4566/000000000000/event-queue",
+				SQSDLQUrl:                     "http://localhost:4566/000000000000/event-dlq",
+				SQSMaxMessages:                10,
+				SQSWaitTimeSeconds:            20,
+				DynamoDBTableName:             "events",
+				DynamoDBClientsTableName:      "events-clients",
+				DynamoDBLimitsTableName:       "events-limits",
+				DynamoDBQuarantineTableName:   "events-quarantine",
+				DynamoDBClientAuditTableName:  "events-client-audit",
+				DynamoDBEndpoint:              "http://localhost:4566",
+				DynamoDBBatchSize:             10,
+				DynamoDBFlushIntervalMs:       50,
+				StorageBackend:                "dynamodb",
+				TTLDefaultDays:                14,
+				TTLFailedDays:                 180,
+				TTLEventTypeDays:              "transaction=365,monitoring=7",
+				StreamSinks:                   "file",
+				StreamFilePath:                "changes.ndjson",
+				StreamCheckpointPath:          "stream-checkpoints.json",
+				StreamPollIntervalMs:          1000,
+				StreamMaxDeliveryAttempts:     10,
+				StreamDeadLetterPath:          "stream-dead-letters.ndjson",
+				ArchiveDestination:            "archive",
+				ArchiveFormat:                 "ndjson",
+				ArchiveSpoolDir:               "archive-spool",
+				ArchiveRollSizeMB:             64,
+				ArchiveRollIntervalSeconds:    300,
+				InfraSpecPath:                 "deployments/infrastructure.yaml",
+				ClientCacheTTLSeconds:         60,
+				ClientCacheNegativeTTLSeconds: 10,
+				AuthzMode:                     "allow-unknown",
+				LimitMaxDelayMs:               5000,
+				ServicePort:                   "8080",
+				WorkerPoolSize:                10,
+				LogLevel:                      "info",
+				SchemaDir:                     "../../schemas",
+				SchemaReloadIntervalSeconds:   10,
+			},
+			description: "Should load the retention policy from environment variables",
+		},
+		{
+			name: "Custom Stream Configuration",
+			envVars: map[string]string{
+				"STREAM_ARN":                   "arn:aws:dynamodb:us-east-1:000000000000:table/events/stream/2025-01-01T00:00:00.000",
+				"STREAM_SINKS":                 "sqs,webhook",
+				"STREAM_SQS_QUEUE_URL":         "http://localhost:4566/000000000000/event-changes",
+				"STREAM_WEBHOOK_URL":           "http://analytics:9000/changes",
+				"STREAM_CHECKPOINT_PATH":       "/data/checkpoints.json",
+				"STREAM_POLL_INTERVAL_MS":      "250",
+				"STREAM_MAX_DELIVERY_ATTEMPTS": "3",
+				"STREAM_DEAD_LETTER_PATH":      "/data/dead-letters.ndjson",
+			},
+			expectedConfig: &Config{
+				AWSRegion:                     "us-east-1",
+				AWSAccessKeyID:                "test",
+				AWSSecretAccessKey:            "test",
+				AWSEndpointURL:                "http://localhost:4566",
+				SQSQueueURL:                   "http://localhost:4566/000000000000/event-queue",
+				SQSDLQUrl:                     "http://localhost:4566/000000000000/event-dlq",
+				SQSMaxMessages:                10,
+				SQSWaitTimeSeconds:            20,
+				DynamoDBTableName:             "events",
+				DynamoDBClientsTableName:      "events-clients",
+				DynamoDBLimitsTableName:       "events-limits",
+				DynamoDBQuarantineTableName:   "events-quarantine",
+				DynamoDBClientAuditTableName:  "events-client-audit",
+				DynamoDBEndpoint:              "http://localhost:4566",
+				DynamoDBBatchSize:             10,
+				DynamoDBFlushIntervalMs:       50,
+				StorageBackend:                "dynamodb",
+				TTLDefaultDays:                30,
+				TTLFailedDays:                 90,
+				StreamARN:                     "arn:aws:dynamodb:us-east-1:000000000000:table/events/stream/2025-01-01T00:00:00.000",
+				StreamSinks:                   "sqs,webhook",
+				StreamSQSQueueURL:             "http://localhost:4566/000000000000/event-changes",
+				StreamWebhookURL:              "http://analytics:9000/changes",
+				StreamFilePath:                "changes.ndjson",
+				StreamCheckpointPath:          "/data/checkpoints.json",
+				StreamPollIntervalMs:          250,
+				StreamMaxDeliveryAttempts:     3,
+				StreamDeadLetterPath:          "/data/dead-letters.ndjson",
+				ArchiveDestination:            "archive",
+				ArchiveFormat:                 "ndjson",
+				ArchiveSpoolDir:               "archive-spool",
+				ArchiveRollSizeMB:             64,
+				ArchiveRollIntervalSeconds:    300,
+				InfraSpecPath:                 "deployments/infrastructure.yaml",
+				ClientCacheTTLSeconds:         60,
+				ClientCacheNegativeTTLSeconds: 10,
+				AuthzMode:                     "allow-unknown",
+				LimitMaxDelayMs:               5000,
+				ServicePort:                   "8080",
+				WorkerPoolSize:                10,
+				LogLevel:                      "info",
+				SchemaDir:                     "../../schemas",
+				SchemaReloadIntervalSeconds:   10,
+			},
+			description: "Should load the stream reader configuration from environment variables",
+		},
+		{
+			name: "Custom Archive Configuration",
+			envVars: map[string]string{
+				"ARCHIVE_DESTINATION": "s3://event-archive/expired",
+				"ARCHIVE_FORMAT":      "parquet",
+			},
+			expectedConfig: &Config{
+				AWSRegion:                     "us-east-1",
+				AWSAccessKeyID:                "test",
+				AWSSecretAccessKey:            "test",
+				AWSEndpointURL:                "http://localhost:4566",
+				SQSQueueURL:                   "http://localhost:4566/000000000000/event-queue",
+				SQSDLQUrl:                     "http://localhost:4566/000000000000/event-dlq",
+				SQSMaxMessages:                10,
+				SQSWaitTimeSeconds:            20,
+				DynamoDBTableName:             "events",
+				DynamoDBClientsTableName:      "events-clients",
+				DynamoDBLimitsTableName:       "events-limits",
+				DynamoDBQuarantineTableName:   "events-quarantine",
+				DynamoDBClientAuditTableName:  "events-client-audit",
+				DynamoDBEndpoint:              "http://localhost:4566",
+				DynamoDBBatchSize:             10,
+				DynamoDBFlushIntervalMs:       50,
+				StorageBackend:                "dynamodb",
+				TTLDefaultDays:                30,
+				TTLFailedDays:                 90,
+				StreamSinks:                   "file",
+				StreamFilePath:                "changes.ndjson",
+				StreamCheckpointPath:          "stream-checkpoints.json",
+				StreamPollIntervalMs:          1000,
+				StreamMaxDeliveryAttempts:     10,
+				StreamDeadLetterPath:          "stream-dead-letters.ndjson",
+				ArchiveDestination:            "s3://event-archive/expired",
+				ArchiveFormat:                 "parquet",
+				ArchiveSpoolDir:               "archive-spool",
+				ArchiveRollSizeMB:             64,
+				ArchiveRollIntervalSeconds:    300,
+				InfraSpecPath:                 "deployments/infrastructure.yaml",
+				ClientCacheTTLSeconds:         60,
+				ClientCacheNegativeTTLSeconds: 10,
+				AuthzMode:                     "allow-unknown",
+				LimitMaxDelayMs:               5000,
+				ServicePort:                   "8080",
+				WorkerPoolSize:                10,
+				LogLevel:                      "info",
+				SchemaDir:                     "../../schemas",
+				SchemaReloadIntervalSeconds:   10,
+			},
+			description: "Should load the archive destination and format from environment variables",
+		},
+		{
+			name: "Custom Infrastructure Configuration",
+			envVars: map[string]string{
+				"INFRA_SPEC_PATH":      "/etc/event-processor/infrastructure.yaml",
+				"INFRA_APPLY_ON_START": "true",
+			},
+			expectedConfig: &Config{
+				AWSRegion:                     "us-east-1",
+				AWSAccessKeyID:                "test",
+				AWSSecretAccessKey:            "test",
+				AWSEndpointURL:                "http://localhost:4566",
+				SQSQueueURL:                   "http://localhost:4566/000000000000/event-queue",
+				SQSDLQUrl:                     "http://localhost:4566/000000000000/event-dlq",
+				SQSMaxMessages:                10,
+				SQSWaitTimeSeconds:            20,
+				DynamoDBTableName:             "events",
+				DynamoDBClientsTableName:      "events-clients",
+				DynamoDBLimitsTableName:       "events-limits",
+				DynamoDBQuarantineTableName:   "events-quarantine",
+				DynamoDBClientAuditTableName:  "events-client-audit",
+				DynamoDBEndpoint:              "http://localhost:4566",
+				DynamoDBBatchSize:             10,
+				DynamoDBFlushIntervalMs:       50,
+				StorageBackend:                "dynamodb",
+				TTLDefaultDays:                30,
+				TTLFailedDays:                 90,
+				StreamSinks:                   "file",
+				StreamFilePath:                "changes.ndjson",
+				StreamCheckpointPath:          "stream-checkpoints.json",
+				StreamPollIntervalMs:          1000,
+				StreamMaxDeliveryAttempts:     10,
+				StreamDeadLetterPath:          "stream-dead-letters.ndjson",
+				ArchiveDestination:            "archive",
+				ArchiveFormat:                 "ndjson",
+				ArchiveSpoolDir:               "archive-spool",
+				ArchiveRollSizeMB:             64,
+				ArchiveRollIntervalSeconds:    300,
+				InfraSpecPath:                 "/etc/event-processor/infrastructure.yaml",
+				InfraApplyOnStart:             true,
+				ClientCacheTTLSeconds:         60,
+				ClientCacheNegativeTTLSeconds: 10,
+				AuthzMode:                     "allow-unknown",
+				LimitMaxDelayMs:               5000,
+				ServicePort:                   "8080",
+				WorkerPoolSize:                10,
+				LogLevel:                      "info",
+				SchemaDir:                     "../../schemas",
+				SchemaReloadIntervalSeconds:   10,
+			},
+			description: "Should load the infrastructure spec path and opt in to applying it on start",
+		},
+		{
+			name: "Custom Admin Configuration",
+			envVars: map[string]string{
+				"ADMIN_TOKEN": "secret-token",
+			},
+			expectedConfig: &Config{
+				AWSRegion:                     "us-east-1",
+				AWSAccessKeyID:                "test",
+				AWSSecretAccessKey:            "test",
+				AWSEndpointURL:                "http://localhost:4566",
+				SQSQueueURL:                   "http://localhost:4566/000000000000/event-queue",
+				SQSDLQUrl:                     "http://localhost:4566/000000000000/event-dlq",
+				SQSMaxMessages:                10,
+				SQSWaitTimeSeconds:            20,
+				DynamoDBTableName:             "events",
+				DynamoDBClientsTableName:      "events-clients",
+				DynamoDBLimitsTableName:       "events-limits",
+				DynamoDBQuarantineTableName:   "events-quarantine",
+				DynamoDBClientAuditTableName:  "events-client-audit",
+				DynamoDBEndpoint:              "http://localhost:4566",
+				DynamoDBBatchSize:             10,
+				DynamoDBFlushIntervalMs:       50,
+				StorageBackend:                "dynamodb",
+				TTLDefaultDays:                30,
+				TTLFailedDays:                 90,
+				StreamSinks:                   "file",
+				StreamFilePath:                "changes.ndjson",
+				StreamCheckpointPath:          "stream-checkpoints.json",
+				StreamPollIntervalMs:          1000,
+				StreamMaxDeliveryAttempts:     10,
+				StreamDeadLetterPath:          "stream-dead-letters.ndjson",
+				ArchiveDestination:            "archive",
+				ArchiveFormat:                 "ndjson",
+				ArchiveSpoolDir:               "archive-spool",
+				ArchiveRollSizeMB:             64,
+				ArchiveRollIntervalSeconds:    300,
+				InfraSpecPath:                 "deployments/infrastructure.yaml",
+				AdminToken:                    "secret-token",
+				ClientCacheTTLSeconds:         60,
+				ClientCacheNegativeTTLSeconds: 10,
+				AuthzMode:                     "allow-unknown",
+				LimitMaxDelayMs:               5000,
+				ServicePort:                   "8080",
+				WorkerPoolSize:                10,
+				LogLevel:                      "info",
+				SchemaDir:                     "../../schemas",
+				SchemaReloadIntervalSeconds:   10,
+			},
+			description: "Should load the admin token and client audit log path from environment variables",
+		},
+		{
+			name: "Custom Client Cache Configuration",
+			envVars: map[string]string{
+				"DYNAMODB_CLIENTS_TABLE_NAME":       "prod-clients",
+				"CLIENT_CACHE_TTL_SECONDS":          "300",
+				"CLIENT_CACHE_NEGATIVE_TTL_SECONDS": "0",
+			},
+			expectedConfig: &Config{
+				AWSRegion:                     "us-east-1",
+				AWSAccessKeyID:                "test",
+				AWSSecretAccessKey:            "test",
+				AWSEndpointURL:                "http://localhost:4566",
+				SQSQueueURL:                   "http://localhost:4566/000000000000/event-queue",
+				SQSDLQUrl:                     "http://localhost:4566/000000000000/event-dlq",
+				SQSMaxMessages:                10,
+				SQSWaitTimeSeconds:            20,
+				DynamoDBTableName:             "events",
+				DynamoDBClientsTableName:      "prod-clients",
+				DynamoDBLimitsTableName:       "events-limits",
+				DynamoDBQuarantineTableName:   "events-quarantine",
+				DynamoDBClientAuditTableName:  "events-client-audit",
+				DynamoDBEndpoint:              "http://localhost:4566",
+				DynamoDBBatchSize:             10,
+				DynamoDBFlushIntervalMs:       50,
+				StorageBackend:                "dynamodb",
+				TTLDefaultDays:                30,
+				TTLFailedDays:                 90,
+				StreamSinks:                   "file",
+				StreamFilePath:                "changes.ndjson",
+				StreamCheckpointPath:          "stream-checkpoints.json",
+				StreamPollIntervalMs:          1000,
+				StreamMaxDeliveryAttempts:     10,
+				StreamDeadLetterPath:          "stream-dead-letters.ndjson",
+				ArchiveDestination:            "archive",
+				ArchiveFormat:                 "ndjson",
+				ArchiveSpoolDir:               "archive-spool",
+				ArchiveRollSizeMB:             64,
+				ArchiveRollIntervalSeconds:    300,
+				InfraSpecPath:                 "deployments/infrastructure.yaml",
+				ClientCacheTTLSeconds:         300,
+				ClientCacheNegativeTTLSeconds: 0,
+				AuthzMode:                     "allow-unknown",
+				LimitMaxDelayMs:               5000,
+				ServicePort:                   "8080",
+				WorkerPoolSize:                10,
+				LogLevel:                      "info",
+				SchemaDir:                     "../../schemas",
+				SchemaReloadIntervalSeconds:   10,
+			},
+			description: "Should load the clients table name and client cache TTLs from environment variables",
+		},
+		{
+			name: "Custom Authorization Configuration",
+			envVars: map[string]string{
+				"AUTHZ_MODE": "fail-closed",
+			},
+			expectedConfig: &Config{
+				AWSRegion:                     "us-east-1",
+				AWSAccessKeyID:                "test",
+				AWSSecretAccessKey:            "test",
+				AWSEndpointURL:                "http://localhost:4566",
+				SQSQueueURL:                   "http://localhost:4566/000000000000/event-queue",
+				SQSDLQUrl:                     "http://localhost:4566/000000000000/event-dlq",
+				SQSMaxMessages:                10,
+				SQSWaitTimeSeconds:            20,
+				DynamoDBTableName:             "events",
+				DynamoDBClientsTableName:      "events-clients",
+				DynamoDBLimitsTableName:       "events-limits",
+				DynamoDBQuarantineTableName:   "events-quarantine",
+				DynamoDBClientAuditTableName:  "events-client-audit",
+				DynamoDBEndpoint:              "http://localhost:4566",
+				DynamoDBBatchSize:             10,
+				DynamoDBFlushIntervalMs:       50,
+				StorageBackend:                "dynamodb",
+				TTLDefaultDays:                30,
+				TTLFailedDays:                 90,
+				StreamSinks:                   "file",
+				StreamFilePath:                "changes.ndjson",
+				StreamCheckpointPath:          "stream-checkpoints.json",
+				StreamPollIntervalMs:          1000,
+				StreamMaxDeliveryAttempts:     10,
+				StreamDeadLetterPath:          "stream-dead-letters.ndjson",
+				ArchiveDestination:            "archive",
+				ArchiveFormat:                 "ndjson",
+				ArchiveSpoolDir:               "archive-spool",
+				ArchiveRollSizeMB:             64,
+				ArchiveRollIntervalSeconds:    300,
+				InfraSpecPath:                 "deployments/infrastructure.yaml",
+				ClientCacheTTLSeconds:         60,
+				ClientCacheNegativeTTLSeconds: 10,
+				AuthzMode:                     "fail-closed",
+				LimitMaxDelayMs:               5000,
+				ServicePort:                   "8080",
+				WorkerPoolSize:                10,
+				LogLevel:                      "info",
+				SchemaDir:                     "../../schemas",
+				SchemaReloadIntervalSeconds:   10,
+			},
+			description: "Should load the authorization mode from environment variables",
+		},
+		{
+			name: "Custom Client Limits Configuration",
+			envVars: map[string]string{
+				"DYNAMODB_LIMITS_TABLE_NAME": "prod-limits",
+				"LIMIT_MAX_DELAY_MS":         "250",
+			},
+			expectedConfig: &Config{
+				AWSRegion:                     "us-east-1",
+				AWSAccessKeyID:                "test",
+				AWSSecretAccessKey:            "test",
+				AWSEndpointURL:                "http://localhost:4566",
+				SQSQueueURL:                   "http://localhost:4566/000000000000/event-queue",
+				SQSDLQUrl:                     "http://localhost:4566/000000000000/event-dlq",
+				SQSMaxMessages:                10,
+				SQSWaitTimeSeconds:            20,
+				DynamoDBTableName:             "events",
+				DynamoDBClientsTableName:      "events-clients",
+				DynamoDBLimitsTableName:       "prod-limits",
+				DynamoDBQuarantineTableName:   "events-quarantine",
+				DynamoDBClientAuditTableName:  "events-client-audit",
+				DynamoDBEndpoint:              "http://localhost:4566",
+				DynamoDBBatchSize:             10,
+				DynamoDBFlushIntervalMs:       50,
+				StorageBackend:                "dynamodb",
+				TTLDefaultDays:                30,
+				TTLFailedDays:                 90,
+				StreamSinks:                   "file",
+				StreamFilePath:                "changes.ndjson",
+				StreamCheckpointPath:          "stream-checkpoints.json",
+				StreamPollIntervalMs:          1000,
+				StreamMaxDeliveryAttempts:     10,
+				StreamDeadLetterPath:          "stream-dead-letters.ndjson",
+				ArchiveDestination:            "archive",
+				ArchiveFormat:                 "ndjson",
+				ArchiveSpoolDir:               "archive-spool",
+				ArchiveRollSizeMB:             64,
+				ArchiveRollIntervalSeconds:    300,
+				InfraSpecPath:                 "deployments/infrastructure.yaml",
+				ClientCacheTTLSeconds:         60,
+				ClientCacheNegativeTTLSeconds: 10,
+				AuthzMode:                     "allow-unknown",
+				LimitMaxDelayMs:               250,
+				ServicePort:                   "8080",
+				WorkerPoolSize:                10,
+				LogLevel:                      "info",
+				SchemaDir:                     "../../schemas",
+				SchemaReloadIntervalSeconds:   10,
+			},
+			description: "Should load the limits table name and max delay from environment variables",
+		},
+		{
+			name: "Custom Quarantine Configuration",
+			envVars: map[string]string{
+				"DYNAMODB_QUARANTINE_TABLE_NAME": "prod-quarantine",
+			},
+			expectedConfig: &Config{
+				AWSRegion:                     "us-east-1",
+				AWSAccessKeyID:                "test",
+				AWSSecretAccessKey:            "test",
+				AWSEndpointURL:                "http://localhost:4566",
+				SQSQueueURL:                   "http://localhost:4566/000000000000/event-queue",
+				SQSDLQUrl:                     "http://localhost:4566/000000000000/event-dlq",
+				SQSMaxMessages:                10,
+				SQSWaitTimeSeconds:            20,
+				DynamoDBTableName:             "events",
+				DynamoDBClientsTableName:      "events-clients",
+				DynamoDBLimitsTableName:       "events-limits",
+				DynamoDBQuarantineTableName:   "prod-quarantine",
+				DynamoDBClientAuditTableName:  "events-client-audit",
+				DynamoDBEndpoint:              "http://localhost:4566",
+				DynamoDBBatchSize:             10,
+				DynamoDBFlushIntervalMs:       50,
+				StorageBackend:                "dynamodb",
+				TTLDefaultDays:                30,
+				TTLFailedDays:                 90,
+				StreamSinks:                   "file",
+				StreamFilePath:                "changes.ndjson",
+				StreamCheckpointPath:          "stream-checkpoints.json",
+				StreamPollIntervalMs:          1000,
+				StreamMaxDeliveryAttempts:     10,
+				StreamDeadLetterPath:          "stream-dead-letters.ndjson",
+				ArchiveDestination:            "archive",
+				ArchiveFormat:                 "ndjson",
+				ArchiveSpoolDir:               "archive-spool",
+				ArchiveRollSizeMB:             64,
+				ArchiveRollIntervalSeconds:    300,
+				InfraSpecPath:                 "deployments/infrastructure.yaml",
+				ClientCacheTTLSeconds:         60,
+				ClientCacheNegativeTTLSeconds: 10,
+				AuthzMode:                     "allow-unknown",
+				LimitMaxDelayMs:               5000,
+				ServicePort:                   "8080",
+				WorkerPoolSize:                10,
+				LogLevel:                      "info",
+				SchemaDir:                     "../../schemas",
+				SchemaReloadIntervalSeconds:   10,
+			},
+			description: "Should load the quarantine table name from environment variables",
+		},
 	}
 
 	for _, tt := range tests {
@@ -250,11 +957,95 @@ func TestLoad(t *testing.T) {
 			assert.Equal(t, tt.expectedConfig.SQSMaxMessages, result.SQSMaxMessages)
 			assert.Equal(t, tt.expectedConfig.SQSWaitTimeSeconds, result.SQSWaitTimeSeconds)
 			assert.Equal(t, tt.expectedConfig.DynamoDBTableName, result.DynamoDBTableName)
+			assert.Equal(t, tt.expectedConfig.DynamoDBClientsTableName, result.DynamoDBClientsTableName)
+			assert.Equal(t, tt.expectedConfig.DynamoDBLimitsTableName, result.DynamoDBLimitsTableName)
+			assert.Equal(t, tt.expectedConfig.DynamoDBQuarantineTableName, result.DynamoDBQuarantineTableName)
+			assert.Equal(t, tt.expectedConfig.DynamoDBClientAuditTableName, result.DynamoDBClientAuditTableName)
 			assert.Equal(t, tt.expectedConfig.DynamoDBEndpoint, result.DynamoDBEndpoint)
+			assert.Equal(t, tt.expectedConfig.DynamoDBBatchSize, result.DynamoDBBatchSize)
+			assert.Equal(t, tt.expectedConfig.DynamoDBFlushIntervalMs, result.DynamoDBFlushIntervalMs)
+			assert.Equal(t, tt.expectedConfig.StorageBackend, result.StorageBackend)
+			assert.Equal(t, tt.expectedConfig.DatabaseURL, result.DatabaseURL)
+			assert.Equal(t, tt.expectedConfig.TTLDefaultDays, result.TTLDefaultDays)
+			assert.Equal(t, tt.expectedConfig.TTLFailedDays, result.TTLFailedDays)
+			assert.Equal(t, tt.expectedConfig.TTLEventTypeDays, result.TTLEventTypeDays)
+			assert.Equal(t, tt.expectedConfig.StreamARN, result.StreamARN)
+			assert.Equal(t, tt.expectedConfig.StreamSinks, result.StreamSinks)
+			assert.Equal(t, tt.expectedConfig.StreamSQSQueueURL, result.StreamSQSQueueURL)
+			assert.Equal(t, tt.expectedConfig.StreamWebhookURL, result.StreamWebhookURL)
+			assert.Equal(t, tt.expectedConfig.StreamFilePath, result.StreamFilePath)
+			assert.Equal(t, tt.expectedConfig.StreamCheckpointPath, result.StreamCheckpointPath)
+			assert.Equal(t, tt.expectedConfig.StreamPollIntervalMs, result.StreamPollIntervalMs)
+			assert.Equal(t, tt.expectedConfig.StreamMaxDeliveryAttempts, result.StreamMaxDeliveryAttempts)
+			assert.Equal(t, tt.expectedConfig.StreamDeadLetterPath, result.StreamDeadLetterPath)
+			assert.Equal(t, tt.expectedConfig.ArchiveDestination, result.ArchiveDestination)
+			assert.Equal(t, tt.expectedConfig.ArchiveFormat, result.ArchiveFormat)
+			assert.Equal(t, tt.expectedConfig.InfraSpecPath, result.InfraSpecPath)
+			assert.Equal(t, tt.expectedConfig.InfraApplyOnStart, result.InfraApplyOnStart)
+			assert.Equal(t, tt.expectedConfig.AdminToken, result.AdminToken)
+			assert.Equal(t, tt.expectedConfig.ClientCacheTTLSeconds, result.ClientCacheTTLSeconds)
+			assert.Equal(t, tt.expectedConfig.ClientCacheNegativeTTLSeconds, result.ClientCacheNegativeTTLSeconds)
+			assert.Equal(t, tt.expectedConfig.AuthzMode, result.AuthzMode)
+			assert.Equal(t, tt.expectedConfig.LimitMaxDelayMs, result.LimitMaxDelayMs)
 			assert.Equal(t, tt.expectedConfig.ServicePort, result.ServicePort)
 			assert.Equal(t, tt.expectedConfig.WorkerPoolSize, result.WorkerPoolSize)
 			assert.Equal(t, tt.expectedConfig.LogLevel, result.LogLevel)
-			assert.Equal(t, tt.expectedConfig.SchemaPath, result.SchemaPath)
+			assert.Equal(t, tt.expectedConfig.SchemaDir, result.SchemaDir)
+			assert.Equal(t, tt.expectedConfig.SchemaReloadIntervalSeconds, result.SchemaReloadIntervalSeconds)
+		})
+	}
+}
+
+// TestLoadDeprecatedSchemaPath tests that SCHEMA_PATH still selects the schemas, with a warning, if
+// its file has the name of the event schema
+func TestLoadDeprecatedSchemaPath(t *testing.T) {
+	tests := []struct {
+		name             string
+		envVars          map[string]string
+		expectedDir      string
+		expectedWarnings []string
+		expectedErrors   []string
+		description      string
+	}{
+		{
+			name:             "Schema Path Only",
+			envVars:          map[string]string{"SCHEMA_PATH": "/etc/event-processor/schemas/event-schema.json"},
+			expectedDir:      "/etc/event-processor/schemas",
+			expectedWarnings: []string{"SCHEMA_PATH is deprecated, set SCHEMA_DIR to /etc/event-processor/schemas instead"},
+			description:      "Should load the schemas from the directory of the event schema",
+		},
+		{
+			name:           "Schema Path With Other Name",
+			envVars:        map[string]string{"SCHEMA_PATH": "/etc/event-processor/schemas/events.json"},
+			expectedDir:    "../../schemas",
+			expectedErrors: []string{"SCHEMA_PATH is deprecated and its file must be named event-schema.json, rename /etc/event-processor/schemas/events.json and set SCHEMA_DIR to its directory instead"},
+			description:    "Should refuse event schemas the schema directory would not find",
+		},
+		{
+			name:             "Schema Path And Dir",
+			envVars:          map[string]string{"SCHEMA_PATH": "/old/event-schema.json", "SCHEMA_DIR": "/new"},
+			expectedDir:      "/new",
+			expectedWarnings: []string{"SCHEMA_PATH is deprecated and ignored as SCHEMA_DIR is set"},
+			description:      "Should prefer SCHEMA_DIR",
+		},
+		{
+			name:        "Schema Dir Only",
+			envVars:     map[string]string{"SCHEMA_DIR": "/new"},
+			expectedDir: "/new",
+			description: "Should not warn without SCHEMA_PATH",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestEnvironment(tt.envVars)
+			defer cleanupTestEnvironment(tt.envVars)
+
+			result := Load()
+
+			assert.Equal(t, tt.expectedDir, result.SchemaDir, tt.description)
+			assert.Equal(t, tt.expectedWarnings, result.Warnings, tt.description)
+			assert.Equal(t, tt.expectedErrors, result.Errors, tt.description)
 		})
 	}
 }
@@ -525,6 +1316,68 @@ func TestGetEnvAsInt64(t *testing.T) {
 	}
 }
 
+// TestGetEnvAsBool tests the getEnvAsBool function
+func TestGetEnvAsBool(t *testing.T) {
+	tests := []getEnvAsBoolTestCase{
+		{
+			name:           "True Bool Environment Variable",
+			key:            "BOOL_KEY",
+			defaultValue:   false,
+			envValue:       "true",
+			expectedResult: true,
+			description:    "Should return true when environment variable contains 'true'",
+		},
+		{
+			name:           "Numeric Bool Environment Variable",
+			key:            "NUMERIC_BOOL_KEY",
+			defaultValue:   true,
+			envValue:       "0",
+			expectedResult: false,
+			description:    "Should return false when environment variable contains '0'",
+		},
+		{
+			name:           "Environment Variable Not Set",
+			key:            "MISSING_BOOL_KEY",
+			defaultValue:   true,
+			envValue:       "",
+			expectedResult: true,
+			description:    "Should return default value when environment variable is not set",
+		},
+		{
+			name:           "Invalid Bool Environment Variable",
+			key:            "INVALID_BOOL_KEY",
+			defaultValue:   false,
+			envValue:       "yes please",
+			expectedResult: false,
+			description:    "Should return default value when environment variable contains invalid bool",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup environment variable for this test
+			if tt.envValue != "" {
+				err := os.Setenv(tt.key, tt.envValue)
+				if err != nil {
+					return
+				}
+				defer func(key string) {
+					err := os.Unsetenv(key)
+					if err != nil {
+						return
+					}
+				}(tt.key)
+			}
+
+			// Execute test
+			result := getEnvAsBool(tt.key, tt.defaultValue)
+
+			// Assertions
+			assert.Equal(t, tt.expectedResult, result)
+		})
+	}
+}
+
 // TestConfigStruct tests the Config struct fields
 func TestConfigStruct(t *testing.T) {
 	t.Run("Config Struct Fields", func(t *testing.T) {
@@ -543,7 +1396,7 @@ func TestConfigStruct(t *testing.T) {
 			ServicePort:        "9090",
 			WorkerPoolSize:     15,
 			LogLevel:           "debug",
-			SchemaPath:         "/test/schema.json",
+			SchemaDir:          "/test/schemas",
 		}
 
 		// Assertions
@@ -560,7 +1413,7 @@ func TestConfigStruct(t *testing.T) {
 		assert.Equal(t, "9090", config.ServicePort)
 		assert.Equal(t, 15, config.WorkerPoolSize)
 		assert.Equal(t, "debug", config.LogLevel)
-		assert.Equal(t, "/test/schema.json", config.SchemaPath)
+		assert.Equal(t, "/test/schemas", config.SchemaDir)
 	})
 }
 
@@ -3,6 +3,7 @@ package consumer
 import (
 	"context"
 	"fmt"
+	"os"
 	"strconv"
 	"time"
 
@@ -12,8 +13,12 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/d-sense/event-processor/internal/config"
+	"github.com/d-sense/event-processor/internal/dlq"
+	"github.com/d-sense/event-processor/internal/metrics"
 	"github.com/d-sense/event-processor/internal/processor"
+	"github.com/d-sense/event-processor/internal/validator"
 	"github.com/d-sense/event-processor/pkg/logger"
+	"github.com/d-sense/event-processor/pkg/models"
 )
 
 // SQSClient defines the interface for SQS operations
@@ -35,6 +40,10 @@ type SQSConsumer struct {
 	maxRetries int
 	waitTime   int64
 	batchSize  int64
+	workers    chan struct{}
+
+	// host identifies this consumer in the failure history of messages
+	host string
 }
 
 // NewSQSConsumer creates a new SQS consumer
@@ -44,6 +53,23 @@ func NewSQSConsumer(awsCfg aws.Config, cfg *config.Config, processor processor.P
 		o.BaseEndpoint = aws.String(cfg.AWSEndpointURL)
 	})
 
+	return NewConsumer(sqsClient, cfg, processor, logger)
+}
+
+// NewConsumer creates a consumer reading from any SQS-compatible client, such as an in-memory queue
+func NewConsumer(sqsClient SQSClient, cfg *config.Config, processor processor.Processor, logger *logrus.Logger) *SQSConsumer {
+	// Bound concurrent message processing by the configured worker pool size
+	workerPoolSize := cfg.WorkerPoolSize
+	if workerPoolSize <= 0 {
+		workerPoolSize = 10
+	}
+	metrics.WorkerPoolSize.Set(float64(workerPoolSize))
+
+	host, err := os.Hostname()
+	if err != nil {
+		host = metrics.Unknown
+	}
+
 	return &SQSConsumer{
 		sqsClient:  sqsClient,
 		queueURL:   cfg.SQSQueueURL,
@@ -54,6 +80,8 @@ func NewSQSConsumer(awsCfg aws.Config, cfg *config.Config, processor processor.P
 		maxRetries: 3,
 		waitTime:   20,
 		batchSize:  10,
+		workers:    make(chan struct{}, workerPoolSize),
+		host:       host,
 	}
 }
 
@@ -112,9 +140,16 @@ func (c *SQSConsumer) pollMessages(ctx context.Context) {
 		MessageAttributeNames: []string{
 			"All",
 		},
+		MessageSystemAttributeNames: []types.MessageSystemAttributeName{
+			types.MessageSystemAttributeNameAWSTraceHeader,
+			// Client policies check time windows against when the message was received
+			types.MessageSystemAttributeNameSentTimestamp,
+		},
 	}
 
+	start := time.Now()
 	result, err := c.sqsClient.ReceiveMessage(ctx, input)
+	metrics.QueueReceiveLatency.WithLabelValues(metrics.Outcome(err)).Observe(time.Since(start).Seconds())
 	if err != nil {
 		c.logger.WithError(err).Error("Failed to receive messages from SQS")
 		return
@@ -126,11 +161,43 @@ func (c *SQSConsumer) pollMessages(ctx context.Context) {
 
 	c.logger.WithField("message_count", len(result.Messages)).Debug("Received messages from SQS")
 
-	for _, message := range result.Messages {
-		go c.processMessage(ctx, &message)
+	for i, message := range result.Messages {
+		metrics.MessagesReceived.WithLabelValues(messageEventType(&message)).Inc()
+
+		if !c.dispatch(ctx, &message) {
+			// Messages left unprocessed become visible again once their visibility timeout expires
+			c.logger.WithField("message_count", len(result.Messages)-i).Info("Consumer stopping, leaving messages in the queue")
+			return
+		}
 	}
 }
 
+// dispatch hands a message to the worker pool, blocking while all workers are busy. It reports
+// false without processing the message if the consumer stops while waiting for a worker.
+func (c *SQSConsumer) dispatch(ctx context.Context, message *types.Message) bool {
+	if c.workers == nil {
+		go c.processMessage(ctx, message)
+		return true
+	}
+
+	select {
+	case c.workers <- struct{}{}:
+	case <-ctx.Done():
+		return false
+	case <-c.stopChan:
+		return false
+	}
+	metrics.WorkerPoolBusy.Inc()
+	go func() {
+		defer func() {
+			metrics.WorkerPoolBusy.Dec()
+			<-c.workers
+		}()
+		c.processMessage(ctx, message)
+	}()
+	return true
+}
+
 // processMessage processes a single SQS message
 func (c *SQSConsumer) processMessage(ctx context.Context, message *types.Message) {
 	messageID := aws.ToString(message.MessageId)
@@ -143,10 +210,13 @@ func (c *SQSConsumer) processMessage(ctx context.Context, message *types.Message
 	})
 	logger.Debug("Processing message")
 
+	eventType := messageEventType(message)
+
 	// Check retry count
 	retryCount := c.getRetryCount(message)
 	if retryCount >= c.maxRetries {
 		logger.WithField("retry_count", retryCount).Warn("Message exceeded max retries, sending to DLQ")
+		metrics.MessagesDeadLettered.WithLabelValues(metrics.ReasonMaxRetries, eventType).Inc()
 		c.sendToDLQ(ctx, message, "Max retries exceeded")
 		c.deleteMessage(ctx, message)
 		return
@@ -156,10 +226,23 @@ func (c *SQSConsumer) processMessage(ctx context.Context, message *types.Message
 	if err := c.processor.ProcessEvent(context.Background(), message); err != nil {
 		logger.WithError(err).Error("Failed to process event")
 
+		// The failure travels with the message, so that it is known when the message is dead lettered
+		stage, category := processor.Classify(err)
+		message.MessageAttributes = dlq.WithAttempt(message.MessageAttributes, dlq.Attempt{
+			At:         time.Now(),
+			Host:       c.host,
+			Stage:      stage,
+			Category:   category,
+			Error:      err.Error(),
+			Validation: validator.Failure(err),
+		})
+
 		// Increment retry count and requeue if under max retries
 		if retryCount < c.maxRetries {
+			metrics.MessagesRetried.WithLabelValues(eventType).Inc()
 			c.requeueMessage(ctx, message, retryCount+1)
 		} else {
+			metrics.MessagesDeadLettered.WithLabelValues(metrics.ReasonMaxRetries, eventType).Inc()
 			c.sendToDLQ(ctx, message, fmt.Sprintf("Processing failed: %v", err))
 		}
 		return
@@ -170,21 +253,16 @@ func (c *SQSConsumer) processMessage(ctx context.Context, message *types.Message
 	c.deleteMessage(ctx, message)
 }
 
-// sendToDLQ sends a message to the Dead Letter Queue
+// sendToDLQ sends a message to the Dead Letter Queue with all of its attributes and a description of
+// its failed attempts
 func (c *SQSConsumer) sendToDLQ(ctx context.Context, message *types.Message, reason string) {
+	metadata := dlq.NewFailureMetadata(dlq.Attempts(message.MessageAttributes), c.host, time.Now())
+
 	input := &sqs.SendMessageInput{
-		QueueUrl:    aws.String(c.dlqURL),
-		MessageBody: message.Body,
-		MessageAttributes: map[string]types.MessageAttributeValue{
-			"OriginalMessageId": {
-				DataType:    aws.String("String"),
-				StringValue: message.MessageId,
-			},
-			"FailureReason": {
-				DataType:    aws.String("String"),
-				StringValue: aws.String(reason),
-			},
-		},
+		QueueUrl:                aws.String(c.dlqURL),
+		MessageBody:             message.Body,
+		MessageAttributes:       dlq.DeadLetterAttributes(message.MessageAttributes, aws.ToString(message.MessageId), reason, metadata),
+		MessageSystemAttributes: dlq.TraceHeader(message),
 	}
 
 	_, err := c.sqsClient.SendMessage(ctx, input)
@@ -200,17 +278,18 @@ func (c *SQSConsumer) requeueMessage(ctx context.Context, message *types.Message
 		message.MessageAttributes = make(map[string]types.MessageAttributeValue)
 	}
 
-	message.MessageAttributes["RetryCount"] = types.MessageAttributeValue{
+	message.MessageAttributes[dlq.AttributeRetryCount] = types.MessageAttributeValue{
 		DataType:    aws.String("Number"),
 		StringValue: aws.String(strconv.Itoa(newRetryCount)),
 	}
 
-	// Send back to queue
+	// Send back to queue, keeping attributes beyond the SQS limit in the failure metadata
 	input := &sqs.SendMessageInput{
-		QueueUrl:          aws.String(c.queueURL),
-		MessageBody:       message.Body,
-		MessageAttributes: message.MessageAttributes,
-		DelaySeconds:      int32(newRetryCount * 5), // Exponential backoff
+		QueueUrl:                aws.String(c.queueURL),
+		MessageBody:             message.Body,
+		MessageAttributes:       dlq.RequeueAttributes(message.MessageAttributes),
+		MessageSystemAttributes: dlq.TraceHeader(message),
+		DelaySeconds:            int32(newRetryCount * 5), // Exponential backoff
 	}
 
 	_, err := c.sqsClient.SendMessage(ctx, input)
@@ -245,7 +324,7 @@ func (c *SQSConsumer) getRetryCount(message *types.Message) int {
 		return 0
 	}
 
-	retryAttr, exists := message.MessageAttributes["RetryCount"]
+	retryAttr, exists := message.MessageAttributes[dlq.AttributeRetryCount]
 	if !exists {
 		return 0
 	}
@@ -258,3 +337,12 @@ func (c *SQSConsumer) getRetryCount(message *types.Message) int {
 
 	return 0
 }
+
+// messageEventType extracts the event type metric label from message attributes. Attributes are set
+// by producers, so only known event types are used, keeping the number of label values bounded.
+func messageEventType(message *types.Message) string {
+	if attr, ok := message.MessageAttributes[dlq.AttributeEventType]; ok && models.IsValidEventType(aws.ToString(attr.StringValue)) {
+		return aws.ToString(attr.StringValue)
+	}
+	return metrics.Unknown
+}
@@ -3,6 +3,7 @@ package consumer
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strconv"
 	"testing"
 	"time"
@@ -13,9 +14,14 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
+	"github.com/d-sense/event-processor/internal/authz"
 	"github.com/d-sense/event-processor/internal/config"
+	"github.com/d-sense/event-processor/internal/dlq"
 	"github.com/d-sense/event-processor/internal/processor"
+	"github.com/d-sense/event-processor/internal/validator"
+	"github.com/d-sense/event-processor/pkg/models"
 )
 
 // MockSQSClient is a mock implementation of the SQS client
@@ -413,6 +419,113 @@ func TestGetRetryCount(t *testing.T) {
 	}
 }
 
+// TestMessageEventType tests the messageEventType function
+func TestMessageEventType(t *testing.T) {
+	tests := []struct {
+		name              string
+		message           *types.Message
+		expectedEventType string
+	}{
+		{
+			name:              "No Message Attributes",
+			message:           createTestMessage("msg-001", "test body", 0),
+			expectedEventType: "unknown",
+		},
+		{
+			name: "Producer Attributes Present",
+			message: createTestMessageWithAttributes("msg-002", "test body", map[string]string{
+				"EventType": "transaction",
+				"ClientID":  "client-001",
+			}),
+			expectedEventType: "transaction",
+		},
+		{
+			name: "Unknown Event Type",
+			message: createTestMessageWithAttributes("msg-003", "test body", map[string]string{
+				"EventType": "made_up",
+			}),
+			expectedEventType: "unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedEventType, messageEventType(tt.message))
+		})
+	}
+}
+
+// TestDispatchStopsWhileWorkersBusy tests that a consumer stopping while every worker is busy
+// leaves the message unprocessed instead of waiting for a worker
+func TestDispatchStopsWhileWorkersBusy(t *testing.T) {
+	mockProcessor := &MockProcessor{}
+	consumer := &SQSConsumer{
+		processor: mockProcessor,
+		logger:    logrus.New(),
+		stopChan:  make(chan struct{}),
+		workers:   make(chan struct{}, 1),
+	}
+	consumer.workers <- struct{}{}
+
+	dispatched := make(chan bool)
+	go func() {
+		dispatched <- consumer.dispatch(context.Background(), createTestMessage("msg-001", "test body", 0))
+	}()
+	close(consumer.stopChan)
+
+	select {
+	case ok := <-dispatched:
+		assert.False(t, ok, "The message should not be dispatched")
+	case <-time.After(time.Second):
+		t.Fatal("dispatch should return when the consumer stops")
+	}
+	mockProcessor.AssertNotCalled(t, "ProcessEvent", mock.Anything, mock.Anything)
+}
+
+// TestDispatchBoundsConcurrency tests that dispatch never runs more messages than there are workers
+func TestDispatchBoundsConcurrency(t *testing.T) {
+	mockSQS := &MockSQSClient{}
+	mockSQS.On("DeleteMessage", mock.Anything, mock.AnythingOfType("*sqs.DeleteMessageInput")).Return(&sqs.DeleteMessageOutput{}, nil)
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 3)
+	mockProcessor := &MockProcessor{}
+	mockProcessor.On("ProcessEvent", mock.Anything, mock.AnythingOfType("*types.Message")).Run(func(args mock.Arguments) {
+		started <- struct{}{}
+		<-release
+	}).Return(nil)
+
+	consumer := &SQSConsumer{
+		sqsClient:  mockSQS,
+		processor:  mockProcessor,
+		logger:     logrus.New(),
+		queueURL:   "https://sqs.test.com/queue",
+		maxRetries: 3,
+		workers:    make(chan struct{}, 2),
+	}
+
+	dispatched := make(chan struct{})
+	go func() {
+		for i := 0; i < 3; i++ {
+			consumer.dispatch(context.Background(), createTestMessage("msg-"+strconv.Itoa(i), "test body", 0))
+		}
+		close(dispatched)
+	}()
+
+	// Two workers start, the third dispatch blocks until a worker is released
+	<-started
+	<-started
+	select {
+	case <-dispatched:
+		t.Fatal("dispatch should block while all workers are busy")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	<-dispatched
+	<-started
+}
+
 // TestSendToDLQ tests the sendToDLQ method
 func TestSendToDLQ(t *testing.T) {
 	tests := []sendToDLQTestCase{
@@ -476,6 +589,171 @@ func TestSendToDLQ(t *testing.T) {
 	}
 }
 
+// TestDeadLetterFailureContext tests that failed attempts are recorded on requeued messages and
+// dead lettered messages keep every attribute of the original along with the failure metadata
+func TestDeadLetterFailureContext(t *testing.T) {
+	var sent []*sqs.SendMessageInput
+	mockSQS := &MockSQSClient{}
+	mockSQS.On("SendMessage", mock.Anything, mock.AnythingOfType("*sqs.SendMessageInput")).
+		Run(func(args mock.Arguments) { sent = append(sent, args.Get(1).(*sqs.SendMessageInput)) }).
+		Return(&sqs.SendMessageOutput{}, nil)
+	mockSQS.On("DeleteMessage", mock.Anything, mock.AnythingOfType("*sqs.DeleteMessageInput")).Return(&sqs.DeleteMessageOutput{}, nil)
+
+	mockProcessor := &MockProcessor{}
+	mockProcessor.On("ProcessEvent", mock.Anything, mock.AnythingOfType("*types.Message")).
+		Return(&processor.StageError{Stage: processor.StageTriage, Err: fmt.Errorf("triage failed: %w", authz.ErrLookupFailed)})
+
+	consumer := &SQSConsumer{
+		sqsClient:  mockSQS,
+		processor:  mockProcessor,
+		logger:     logrus.New(),
+		queueURL:   "https://sqs.test.com/queue",
+		dlqURL:     "https://sqs.test.com/dlq",
+		maxRetries: 3,
+		host:       "worker-1",
+	}
+
+	// The last attempt fails and is requeued with the failure recorded
+	message := createTestMessageWithAttributes("msg-001", "test body", map[string]string{
+		"EventType":   "transaction",
+		"ClientID":    "client-001",
+		"traceparent": "00-trace-span-01",
+	})
+	message.MessageAttributes["RetryCount"] = types.MessageAttributeValue{DataType: aws.String("Number"), StringValue: aws.String("2")}
+	message.Attributes = map[string]string{"AWSTraceHeader": "Root=1-abc"}
+	consumer.processMessage(context.Background(), message)
+
+	if !assert.Len(t, sent, 1) {
+		return
+	}
+	requeued := sent[0]
+	assert.Equal(t, "https://sqs.test.com/queue", aws.ToString(requeued.QueueUrl))
+	assert.Equal(t, "Root=1-abc", aws.ToString(requeued.MessageSystemAttributes["AWSTraceHeader"].StringValue))
+	attempts := dlq.Attempts(requeued.MessageAttributes)
+	if assert.Len(t, attempts, 1) {
+		assert.Equal(t, "worker-1", attempts[0].Host)
+		assert.Equal(t, processor.StageTriage, attempts[0].Stage)
+		assert.Equal(t, processor.CategoryClientLookup, attempts[0].Category)
+	}
+
+	// The requeued message has run out of retries and is dead lettered with everything it carried
+	consumer.processMessage(context.Background(), &types.Message{
+		MessageId:         aws.String("msg-002"),
+		Body:              requeued.MessageBody,
+		ReceiptHandle:     aws.String("receipt-msg-002"),
+		MessageAttributes: requeued.MessageAttributes,
+		Attributes:        map[string]string{"AWSTraceHeader": "Root=1-abc"},
+	})
+
+	if !assert.Len(t, sent, 2) {
+		return
+	}
+	deadLettered := sent[1]
+	assert.Equal(t, "https://sqs.test.com/dlq", aws.ToString(deadLettered.QueueUrl))
+	assert.Equal(t, "Root=1-abc", aws.ToString(deadLettered.MessageSystemAttributes["AWSTraceHeader"].StringValue))
+
+	attributes := make(map[string]string)
+	for name, value := range deadLettered.MessageAttributes {
+		attributes[name] = aws.ToString(value.StringValue)
+	}
+	assert.Equal(t, "transaction", attributes["EventType"])
+	assert.Equal(t, "client-001", attributes["ClientID"])
+	assert.Equal(t, "00-trace-span-01", attributes["traceparent"])
+	assert.Equal(t, "3", attributes["RetryCount"])
+	assert.Equal(t, "msg-002", attributes["OriginalMessageId"])
+	assert.Equal(t, "Max retries exceeded", attributes["FailureReason"])
+
+	metadata := dlq.ReadFailureMetadata(deadLettered.MessageAttributes)
+	if assert.NotNil(t, metadata) {
+		assert.Equal(t, processor.CategoryClientLookup, metadata.Category)
+		assert.Equal(t, processor.StageTriage, metadata.Stage)
+		assert.Equal(t, "worker-1", metadata.ConsumerHost)
+		assert.Len(t, metadata.Attempts, 1)
+		assert.False(t, metadata.DeadLetteredAt.IsZero())
+	}
+}
+
+// TestRequeueAttributeLimit tests that a message sent with as many attributes as SQS accepts is
+// requeued within the limit, with the attributes that do not fit kept in the failure metadata
+func TestRequeueAttributeLimit(t *testing.T) {
+	var sent []*sqs.SendMessageInput
+	mockSQS := &MockSQSClient{}
+	mockSQS.On("SendMessage", mock.Anything, mock.AnythingOfType("*sqs.SendMessageInput")).
+		Run(func(args mock.Arguments) { sent = append(sent, args.Get(1).(*sqs.SendMessageInput)) }).
+		Return(&sqs.SendMessageOutput{}, nil)
+
+	mockProcessor := &MockProcessor{}
+	mockProcessor.On("ProcessEvent", mock.Anything, mock.AnythingOfType("*types.Message")).
+		Return(&processor.StageError{Stage: processor.StagePersistence, Err: errors.New("dynamodb error")})
+
+	consumer := &SQSConsumer{
+		sqsClient:  mockSQS,
+		processor:  mockProcessor,
+		logger:     logrus.New(),
+		queueURL:   "https://sqs.test.com/queue",
+		maxRetries: 3,
+	}
+
+	attributes := make(map[string]string, dlq.MaxMessageAttributes)
+	for i := 0; i < dlq.MaxMessageAttributes; i++ {
+		attributes[fmt.Sprintf("Custom%d", i)] = fmt.Sprint(i)
+	}
+	consumer.processMessage(context.Background(), createTestMessageWithAttributes("msg-001", "test body", attributes))
+
+	require.Len(t, sent, 1)
+	requeued := sent[0].MessageAttributes
+	assert.LessOrEqual(t, len(requeued), dlq.MaxMessageAttributes)
+	assert.Equal(t, "1", aws.ToString(requeued[dlq.AttributeRetryCount].StringValue))
+	assert.Len(t, dlq.Attempts(requeued), 1)
+
+	metadata := dlq.ReadFailureMetadata(requeued)
+	require.NotNil(t, metadata)
+	for name, value := range attributes {
+		if kept, ok := requeued[name]; ok {
+			assert.Equal(t, value, aws.ToString(kept.StringValue))
+		} else {
+			assert.Equal(t, value, metadata.OverflowAttributes[name].StringValue, name)
+		}
+	}
+}
+
+// TestFailedValidationContext tests that the schema rules a message breaks are recorded with its attempt
+func TestFailedValidationContext(t *testing.T) {
+	var sent []*sqs.SendMessageInput
+	mockSQS := &MockSQSClient{}
+	mockSQS.On("SendMessage", mock.Anything, mock.AnythingOfType("*sqs.SendMessageInput")).
+		Run(func(args mock.Arguments) { sent = append(sent, args.Get(1).(*sqs.SendMessageInput)) }).
+		Return(&sqs.SendMessageOutput{}, nil)
+	mockSQS.On("DeleteMessage", mock.Anything, mock.AnythingOfType("*sqs.DeleteMessageInput")).Return(&sqs.DeleteMessageOutput{}, nil)
+
+	failure := models.ValidationFailure{
+		Schema:     models.ValidationSchemaEvent,
+		Violations: []models.Violation{{Field: "eventType", Rule: "required", Message: "(root): eventType is required"}},
+	}
+	mockProcessor := &MockProcessor{}
+	mockProcessor.On("ProcessEvent", mock.Anything, mock.AnythingOfType("*types.Message")).
+		Return(&processor.StageError{Stage: processor.StageValidation, Err: fmt.Errorf("validation failed: %w", &validator.ValidationError{ValidationFailure: failure})})
+
+	consumer := &SQSConsumer{
+		sqsClient:  mockSQS,
+		processor:  mockProcessor,
+		logger:     logrus.New(),
+		queueURL:   "https://sqs.test.com/queue",
+		dlqURL:     "https://sqs.test.com/dlq",
+		maxRetries: 3,
+	}
+	consumer.processMessage(context.Background(), createTestMessage("msg-001", "test body", 0))
+
+	if !assert.Len(t, sent, 1) {
+		return
+	}
+	attempts := dlq.Attempts(sent[0].MessageAttributes)
+	if assert.Len(t, attempts, 1) {
+		assert.Equal(t, processor.CategoryInvalidEvent, attempts[0].Category)
+		assert.Equal(t, &failure, attempts[0].Validation)
+	}
+}
+
 // TestRequeueMessage tests the requeueMessage method
 func TestRequeueMessage(t *testing.T) {
 	tests := []requeueMessageTestCase{
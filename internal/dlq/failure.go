@@ -0,0 +1,285 @@
+package dlq
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// MaxMessageAttributes is the number of message attributes SQS accepts on a message
+const MaxMessageAttributes = 10
+
+// maxErrorLength bounds the error kept per attempt, as attributes count towards the message size
+const maxErrorLength = 512
+
+// maxViolations bounds the schema violations kept per attempt, for the same reason
+const maxViolations = 10
+
+// Attempt records a failed attempt to process a message
+type Attempt struct {
+	At       time.Time `json:"at"`
+	Host     string    `json:"host,omitempty"`
+	Stage    string    `json:"stage"`
+	Category string    `json:"category"`
+	Error    string    `json:"error"`
+
+	// Validation lists the schema rules the message breaks, for attempts failing validation
+	Validation *models.ValidationFailure `json:"validation,omitempty"`
+}
+
+// FailureMetadata describes why a message was dead lettered; it is kept as JSON in the
+// FailureMetadata attribute
+type FailureMetadata struct {
+	// Category, Stage and Error describe the last failed attempt
+	Category string `json:"category"`
+	Stage    string `json:"stage"`
+	Error    string `json:"error,omitempty"`
+
+	// Validation lists the schema rules the message broke on the last failed attempt
+	Validation *models.ValidationFailure `json:"validation,omitempty"`
+
+	// Attempts lists every failed attempt, oldest first
+	Attempts []Attempt `json:"attempts"`
+
+	ConsumerHost   string    `json:"consumerHost,omitempty"`
+	DeadLetteredAt time.Time `json:"deadLetteredAt"`
+
+	// OverflowAttributes keeps the original attributes that did not fit on the dead lettered message
+	OverflowAttributes map[string]Attribute `json:"overflowAttributes,omitempty"`
+}
+
+// Attribute is a message attribute kept in the failure metadata, with its data type so that binary
+// and custom typed attributes are restored as they were sent
+type Attribute struct {
+	DataType    string `json:"dataType"`
+	StringValue string `json:"stringValue,omitempty"`
+	BinaryValue []byte `json:"binaryValue,omitempty"`
+}
+
+// newAttribute keeps a message attribute
+func newAttribute(value types.MessageAttributeValue) Attribute {
+	return Attribute{
+		DataType:    aws.ToString(value.DataType),
+		StringValue: aws.ToString(value.StringValue),
+		BinaryValue: value.BinaryValue,
+	}
+}
+
+// messageAttribute returns the message attribute kept
+func (a Attribute) messageAttribute() types.MessageAttributeValue {
+	value := types.MessageAttributeValue{DataType: aws.String(a.DataType)}
+	if a.BinaryValue != nil {
+		value.BinaryValue = a.BinaryValue
+	} else {
+		value.StringValue = aws.String(a.StringValue)
+	}
+	return value
+}
+
+// NewFailureMetadata describes a message failing after the given attempts
+func NewFailureMetadata(attempts []Attempt, host string, deadLetteredAt time.Time) FailureMetadata {
+	metadata := FailureMetadata{
+		Category:       "unknown",
+		Stage:          "unknown",
+		Attempts:       attempts,
+		ConsumerHost:   host,
+		DeadLetteredAt: deadLetteredAt.UTC(),
+	}
+	if metadata.Attempts == nil {
+		metadata.Attempts = []Attempt{}
+	}
+	if len(attempts) > 0 {
+		last := attempts[len(attempts)-1]
+		metadata.Category, metadata.Stage, metadata.Error = last.Category, last.Stage, last.Error
+		metadata.Validation = last.Validation
+	}
+	return metadata
+}
+
+// Attempts returns the failed attempts recorded on a message, oldest first
+func Attempts(attributes map[string]types.MessageAttributeValue) []Attempt {
+	attr, ok := attributes[AttributeFailureHistory]
+	if !ok {
+		return nil
+	}
+
+	var attempts []Attempt
+	if err := json.Unmarshal([]byte(aws.ToString(attr.StringValue)), &attempts); err != nil {
+		return nil
+	}
+	return attempts
+}
+
+// WithAttempt returns a copy of the attributes with the attempt added to the failure history
+func WithAttempt(attributes map[string]types.MessageAttributeValue, attempt Attempt) map[string]types.MessageAttributeValue {
+	if len(attempt.Error) > maxErrorLength {
+		attempt.Error = attempt.Error[:maxErrorLength]
+	}
+	if attempt.Validation != nil && len(attempt.Validation.Violations) > maxViolations {
+		validation := *attempt.Validation
+		validation.Violations = validation.Violations[:maxViolations]
+		attempt.Validation = &validation
+	}
+	attempt.At = attempt.At.UTC()
+
+	history, _ := json.Marshal(append(Attempts(attributes), attempt))
+
+	updated := make(map[string]types.MessageAttributeValue, len(attributes)+1)
+	for name, value := range attributes {
+		updated[name] = value
+	}
+	updated[AttributeFailureHistory] = stringAttribute(string(history))
+	return updated
+}
+
+// RequeueAttributes returns the attributes of a message sent back to the queue for another attempt.
+// The retry count and failure history are always kept; other attributes that do not fit within
+// MaxMessageAttributes are kept in the failure metadata instead, as on dead lettered messages, so
+// that they are restored when the message is dead lettered and redriven.
+func RequeueAttributes(original map[string]types.MessageAttributeValue) map[string]types.MessageAttributeValue {
+	if len(original) <= MaxMessageAttributes {
+		return original
+	}
+
+	attributes := make(map[string]types.MessageAttributeValue, MaxMessageAttributes)
+	names := make([]string, 0, len(original))
+	for name, value := range original {
+		if consumerAttribute(name) {
+			attributes[name] = value
+		} else if name != AttributeFailureMetadata {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	// One attribute is left for the metadata
+	metadata := FailureMetadata{OverflowAttributes: overflowAttributes(original)}
+	for _, name := range names {
+		if len(attributes) < MaxMessageAttributes-1 {
+			attributes[name] = original[name]
+			continue
+		}
+		if metadata.OverflowAttributes == nil {
+			metadata.OverflowAttributes = make(map[string]Attribute)
+		}
+		metadata.OverflowAttributes[name] = newAttribute(original[name])
+	}
+
+	encoded, _ := json.Marshal(metadata)
+	attributes[AttributeFailureMetadata] = stringAttribute(string(encoded))
+	return attributes
+}
+
+// DeadLetterAttributes returns the attributes of a dead lettered message: every original attribute
+// plus the original message ID, the failure reason and the failure metadata. Original attributes
+// that do not fit within MaxMessageAttributes are kept in the metadata instead, those set by the
+// consumer going first; the failure history is then dropped, as the metadata lists the attempts.
+// Attributes already kept in the metadata of a requeued message stay there.
+func DeadLetterAttributes(original map[string]types.MessageAttributeValue, originalMessageID, reason string, metadata FailureMetadata) map[string]types.MessageAttributeValue {
+	if overflow := overflowAttributes(original); overflow != nil {
+		for name, value := range metadata.OverflowAttributes {
+			overflow[name] = value
+		}
+		metadata.OverflowAttributes = overflow
+	}
+
+	attributes := make(map[string]types.MessageAttributeValue, MaxMessageAttributes)
+	if originalMessageID != "" {
+		attributes[AttributeOriginalMessageID] = stringAttribute(originalMessageID)
+	}
+	attributes[AttributeFailureReason] = stringAttribute(reason)
+
+	// Attributes are kept in a stable order, the producer's before the consumer's
+	names := make([]string, 0, len(original))
+	for name := range original {
+		if name != AttributeOriginalMessageID && name != AttributeFailureReason && name != AttributeFailureMetadata {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if consumerAttribute(names[i]) != consumerAttribute(names[j]) {
+			return !consumerAttribute(names[i])
+		}
+		return names[i] < names[j]
+	})
+
+	// One attribute is left for the metadata
+	for _, name := range names {
+		if len(attributes) < MaxMessageAttributes-1 {
+			attributes[name] = original[name]
+			continue
+		}
+		if name == AttributeFailureHistory {
+			continue
+		}
+		if metadata.OverflowAttributes == nil {
+			metadata.OverflowAttributes = make(map[string]Attribute)
+		}
+		metadata.OverflowAttributes[name] = newAttribute(original[name])
+	}
+
+	encoded, _ := json.Marshal(metadata)
+	attributes[AttributeFailureMetadata] = stringAttribute(string(encoded))
+	return attributes
+}
+
+// ReadFailureMetadata returns the failure metadata of a dead lettered message, or nil if it has none
+func ReadFailureMetadata(attributes map[string]types.MessageAttributeValue) *FailureMetadata {
+	attr, ok := attributes[AttributeFailureMetadata]
+	if !ok {
+		return nil
+	}
+
+	var metadata FailureMetadata
+	if err := json.Unmarshal([]byte(aws.ToString(attr.StringValue)), &metadata); err != nil {
+		return nil
+	}
+	return &metadata
+}
+
+// overflowAttributes returns a copy of the attributes kept in the failure metadata of a message, or
+// nil if it has none
+func overflowAttributes(attributes map[string]types.MessageAttributeValue) map[string]Attribute {
+	metadata := ReadFailureMetadata(attributes)
+	if metadata == nil || len(metadata.OverflowAttributes) == 0 {
+		return nil
+	}
+
+	overflow := make(map[string]Attribute, len(metadata.OverflowAttributes))
+	for name, value := range metadata.OverflowAttributes {
+		overflow[name] = value
+	}
+	return overflow
+}
+
+// TraceHeader returns the system attributes to send with a copy of a message so that it stays in
+// the same X-Ray trace; it is nil for messages without a trace header
+func TraceHeader(message *types.Message) map[string]types.MessageSystemAttributeValue {
+	header, ok := message.Attributes[string(types.MessageSystemAttributeNameAWSTraceHeader)]
+	if !ok || header == "" {
+		return nil
+	}
+	return map[string]types.MessageSystemAttributeValue{
+		string(types.MessageSystemAttributeNameForSendsAWSTraceHeader): {
+			DataType:    aws.String("String"),
+			StringValue: aws.String(header),
+		},
+	}
+}
+
+// consumerAttribute reports whether an attribute is set by the consumer rather than the producer
+func consumerAttribute(name string) bool {
+	return name == AttributeRetryCount || name == AttributeFailureHistory
+}
+
+func stringAttribute(value string) types.MessageAttributeValue {
+	return types.MessageAttributeValue{
+		DataType:    aws.String("String"),
+		StringValue: aws.String(value),
+	}
+}
@@ -0,0 +1,282 @@
+package dlq
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+func attributeValues(attributes map[string]types.MessageAttributeValue) map[string]string {
+	values := make(map[string]string, len(attributes))
+	for name, value := range attributes {
+		values[name] = aws.ToString(value.StringValue)
+	}
+	return values
+}
+
+// TestWithAttempt tests that attempts are added to the failure history of a message
+func TestWithAttempt(t *testing.T) {
+	first := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	original := map[string]types.MessageAttributeValue{AttributeEventType: stringAttribute("transaction")}
+
+	attributes := WithAttempt(original, Attempt{At: first, Host: "worker-1", Stage: "triage", Category: "client_lookup", Error: "timeout"})
+	attributes = WithAttempt(attributes, Attempt{At: first.Add(5 * time.Second), Host: "worker-2", Stage: "persistence", Category: "storage", Error: strings.Repeat("x", 1000)})
+
+	attempts := Attempts(attributes)
+	require.Len(t, attempts, 2)
+	assert.Equal(t, Attempt{At: first, Host: "worker-1", Stage: "triage", Category: "client_lookup", Error: "timeout"}, attempts[0])
+	assert.Equal(t, "worker-2", attempts[1].Host)
+	assert.Len(t, attempts[1].Error, maxErrorLength, "Long errors should be truncated")
+
+	assert.Equal(t, "transaction", aws.ToString(attributes[AttributeEventType].StringValue))
+	assert.NotContains(t, original, AttributeFailureHistory, "The original attributes should not be modified")
+}
+
+// TestWithAttemptValidation tests that the schema violations of an attempt are kept, up to a limit
+func TestWithAttemptValidation(t *testing.T) {
+	violations := make([]models.Violation, maxViolations+5)
+	for i := range violations {
+		violations[i] = models.Violation{Field: fmt.Sprintf("payload.field%d", i), Rule: "required"}
+	}
+	validation := &models.ValidationFailure{Schema: models.ValidationSchemaPayload, EventType: models.EventTypeTransaction, SchemaVersion: "1.1", Violations: violations}
+
+	attributes := WithAttempt(nil, Attempt{Stage: "validation", Category: "invalid_event", Error: "payload validation failed", Validation: validation})
+
+	attempts := Attempts(attributes)
+	require.Len(t, attempts, 1)
+	require.NotNil(t, attempts[0].Validation)
+	assert.Equal(t, "1.1", attempts[0].Validation.SchemaVersion)
+	assert.Equal(t, violations[:maxViolations], attempts[0].Validation.Violations, "Violations past the limit should be dropped")
+	assert.Len(t, validation.Violations, maxViolations+5, "The attempt's violations should not be modified")
+
+	metadata := NewFailureMetadata(attempts, "worker-1", time.Now())
+	assert.Equal(t, attempts[0].Validation, metadata.Validation, "The metadata should describe the violations of the last attempt")
+}
+
+// TestNewFailureMetadata tests that the metadata describes the last attempt
+func TestNewFailureMetadata(t *testing.T) {
+	deadLetteredAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	metadata := NewFailureMetadata([]Attempt{
+		{Stage: "triage", Category: "client_lookup", Error: "timeout"},
+		{Stage: "persistence", Category: "storage", Error: "throttled"},
+	}, "worker-1", deadLetteredAt)
+	assert.Equal(t, "storage", metadata.Category)
+	assert.Equal(t, "persistence", metadata.Stage)
+	assert.Equal(t, "throttled", metadata.Error)
+	assert.Equal(t, "worker-1", metadata.ConsumerHost)
+	assert.Equal(t, deadLetteredAt, metadata.DeadLetteredAt)
+
+	metadata = NewFailureMetadata(nil, "worker-1", deadLetteredAt)
+	assert.Equal(t, "unknown", metadata.Category)
+	assert.Equal(t, "unknown", metadata.Stage)
+	assert.Empty(t, metadata.Attempts)
+}
+
+// TestDeadLetterAttributes tests that dead lettered messages keep every original attribute
+func TestDeadLetterAttributes(t *testing.T) {
+	tests := []struct {
+		name               string
+		original           map[string]types.MessageAttributeValue
+		expectedAttributes []string
+		expectedOverflow   map[string]Attribute
+		description        string
+	}{
+		{
+			name: "All Attributes Fit",
+			original: WithAttempt(map[string]types.MessageAttributeValue{
+				AttributeEventType:  stringAttribute("transaction"),
+				AttributeClientID:   stringAttribute("client-001"),
+				AttributeRetryCount: {DataType: aws.String("Number"), StringValue: aws.String("3")},
+				"traceparent":       stringAttribute("00-trace-span-01"),
+			}, Attempt{Stage: "triage", Category: "client_lookup"}),
+			expectedAttributes: []string{
+				AttributeClientID, AttributeEventType, AttributeFailureHistory, AttributeFailureMetadata,
+				AttributeFailureReason, AttributeOriginalMessageID, AttributeRetryCount, "traceparent",
+			},
+			description: "Should keep the original attributes next to the failure attributes",
+		},
+		{
+			name: "Too Many Attributes",
+			original: func() map[string]types.MessageAttributeValue {
+				attributes := map[string]types.MessageAttributeValue{
+					AttributeRetryCount: {DataType: aws.String("Number"), StringValue: aws.String("3")},
+				}
+				for i := 1; i <= 7; i++ {
+					attributes[fmt.Sprintf("Custom%d", i)] = stringAttribute(fmt.Sprint(i))
+				}
+				return WithAttempt(attributes, Attempt{Stage: "triage", Category: "client_lookup"})
+			}(),
+			expectedAttributes: []string{
+				"Custom1", "Custom2", "Custom3", "Custom4", "Custom5", "Custom6", "Custom7",
+				AttributeFailureMetadata, AttributeFailureReason, AttributeOriginalMessageID,
+			},
+			expectedOverflow: map[string]Attribute{AttributeRetryCount: {DataType: "Number", StringValue: "3"}},
+			description:      "Should move the consumer's attributes into the metadata when SQS has no room for them",
+		},
+		{
+			name: "Binary Attribute Overflow",
+			original: func() map[string]types.MessageAttributeValue {
+				attributes := map[string]types.MessageAttributeValue{
+					"Signature": {DataType: aws.String("Binary.sha256"), BinaryValue: []byte{0x00, 0xff, 0x10}},
+				}
+				for i := 1; i <= 7; i++ {
+					attributes[fmt.Sprintf("Custom%d", i)] = stringAttribute(fmt.Sprint(i))
+				}
+				return WithAttempt(attributes, Attempt{Stage: "triage", Category: "client_lookup"})
+			}(),
+			expectedAttributes: []string{
+				"Custom1", "Custom2", "Custom3", "Custom4", "Custom5", "Custom6", "Custom7",
+				AttributeFailureMetadata, AttributeFailureReason, AttributeOriginalMessageID,
+			},
+			expectedOverflow: map[string]Attribute{"Signature": {DataType: "Binary.sha256", BinaryValue: []byte{0x00, 0xff, 0x10}}},
+			description:      "Should keep the data type and binary value of attributes moved into the metadata",
+		},
+		{
+			name:     "Requeued Message",
+			original: RequeueAttributes(customAttributes(10)),
+			expectedAttributes: []string{
+				"Custom0", "Custom1", "Custom2", "Custom3", "Custom4", "Custom5", "Custom6",
+				AttributeFailureMetadata, AttributeFailureReason, AttributeOriginalMessageID,
+			},
+			expectedOverflow: map[string]Attribute{
+				"Custom7":           {DataType: "String", StringValue: "7"},
+				"Custom8":           {DataType: "String", StringValue: "8"},
+				"Custom9":           {DataType: "String", StringValue: "9"},
+				AttributeRetryCount: {DataType: "Number", StringValue: "2"},
+			},
+			description: "Should keep the attributes a requeue moved into the metadata",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata := NewFailureMetadata(Attempts(tt.original), "worker-1", time.Now())
+
+			attributes := DeadLetterAttributes(tt.original, "msg-001", "Max retries exceeded", metadata)
+
+			names := make([]string, 0, len(attributes))
+			for name := range attributes {
+				names = append(names, name)
+			}
+			assert.ElementsMatch(t, tt.expectedAttributes, names, tt.description)
+			assert.LessOrEqual(t, len(attributes), MaxMessageAttributes)
+
+			values := attributeValues(attributes)
+			assert.Equal(t, "msg-001", values[AttributeOriginalMessageID])
+			assert.Equal(t, "Max retries exceeded", values[AttributeFailureReason])
+
+			stored := ReadFailureMetadata(attributes)
+			require.NotNil(t, stored)
+			assert.Equal(t, "client_lookup", stored.Category)
+			assert.Equal(t, "triage", stored.Stage)
+			assert.Len(t, stored.Attempts, 1)
+			assert.Equal(t, tt.expectedOverflow, stored.OverflowAttributes, tt.description)
+		})
+	}
+}
+
+// customAttributes returns a message with n attributes set by the producer, a retry count and a
+// failure history
+func customAttributes(n int) map[string]types.MessageAttributeValue {
+	attributes := map[string]types.MessageAttributeValue{
+		AttributeRetryCount: {DataType: aws.String("Number"), StringValue: aws.String("2")},
+	}
+	for i := 0; i < n; i++ {
+		attributes[fmt.Sprintf("Custom%d", i)] = stringAttribute(fmt.Sprint(i))
+	}
+	return WithAttempt(attributes, Attempt{Stage: "triage", Category: "client_lookup"})
+}
+
+// TestRequeueAttributes tests that requeued messages stay within the SQS attribute limit
+func TestRequeueAttributes(t *testing.T) {
+	tests := []struct {
+		name               string
+		original           map[string]types.MessageAttributeValue
+		expectedAttributes []string
+		expectedOverflow   map[string]Attribute
+		description        string
+	}{
+		{
+			name:     "All Attributes Fit",
+			original: customAttributes(8),
+			expectedAttributes: []string{
+				"Custom0", "Custom1", "Custom2", "Custom3", "Custom4", "Custom5", "Custom6", "Custom7",
+				AttributeFailureHistory, AttributeRetryCount,
+			},
+			description: "Should send every attribute when SQS has room for them",
+		},
+		{
+			name:     "Too Many Attributes",
+			original: customAttributes(10),
+			expectedAttributes: []string{
+				"Custom0", "Custom1", "Custom2", "Custom3", "Custom4", "Custom5", "Custom6",
+				AttributeFailureHistory, AttributeFailureMetadata, AttributeRetryCount,
+			},
+			expectedOverflow: map[string]Attribute{
+				"Custom7": {DataType: "String", StringValue: "7"},
+				"Custom8": {DataType: "String", StringValue: "8"},
+				"Custom9": {DataType: "String", StringValue: "9"},
+			},
+			description: "Should keep the retry count and failure history and move the producer's last attributes into the metadata",
+		},
+		{
+			name: "Requeued Again",
+			original: func() map[string]types.MessageAttributeValue {
+				attributes := RequeueAttributes(customAttributes(10))
+				attributes["Custom7"] = stringAttribute("7")
+				return WithAttempt(attributes, Attempt{Stage: "persistence", Category: "storage"})
+			}(),
+			expectedAttributes: []string{
+				"Custom0", "Custom1", "Custom2", "Custom3", "Custom4", "Custom5", "Custom6",
+				AttributeFailureHistory, AttributeFailureMetadata, AttributeRetryCount,
+			},
+			expectedOverflow: map[string]Attribute{
+				"Custom7": {DataType: "String", StringValue: "7"},
+				"Custom8": {DataType: "String", StringValue: "8"},
+				"Custom9": {DataType: "String", StringValue: "9"},
+			},
+			description: "Should keep the attributes moved into the metadata on an earlier attempt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attributes := RequeueAttributes(tt.original)
+
+			names := make([]string, 0, len(attributes))
+			for name := range attributes {
+				names = append(names, name)
+			}
+			assert.ElementsMatch(t, tt.expectedAttributes, names, tt.description)
+			assert.LessOrEqual(t, len(attributes), MaxMessageAttributes)
+			assert.Equal(t, tt.original[AttributeRetryCount], attributes[AttributeRetryCount])
+			assert.Equal(t, Attempts(tt.original), Attempts(attributes))
+
+			if tt.expectedOverflow == nil {
+				assert.Nil(t, ReadFailureMetadata(attributes))
+				return
+			}
+			stored := ReadFailureMetadata(attributes)
+			require.NotNil(t, stored)
+			assert.Equal(t, tt.expectedOverflow, stored.OverflowAttributes, tt.description)
+		})
+	}
+}
+
+// TestTraceHeader tests that the X-Ray trace header is carried over to copies of a message
+func TestTraceHeader(t *testing.T) {
+	assert.Nil(t, TraceHeader(&types.Message{}))
+
+	header := TraceHeader(&types.Message{Attributes: map[string]string{"AWSTraceHeader": "Root=1-abc"}})
+	require.Contains(t, header, "AWSTraceHeader")
+	assert.Equal(t, "Root=1-abc", aws.ToString(header["AWSTraceHeader"].StringValue))
+}
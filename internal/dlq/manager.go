@@ -0,0 +1,481 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/sirupsen/logrus"
+
+	"github.com/d-sense/event-processor/internal/metrics"
+)
+
+// Message attributes set by the producer and the consumer
+const (
+	AttributeEventType         = "EventType"
+	AttributeClientID          = "ClientID"
+	AttributeRetryCount        = "RetryCount"
+	AttributeFailureHistory    = "FailureHistory"
+	AttributeOriginalMessageID = "OriginalMessageId"
+	AttributeFailureReason     = "FailureReason"
+	AttributeFailureMetadata   = "FailureMetadata"
+)
+
+// deadLetterAttributes are added by the consumer when a message fails and removed when it is
+// redriven, so that a redriven message starts over with a fresh retry count
+var deadLetterAttributes = []string{
+	AttributeRetryCount,
+	AttributeFailureHistory,
+	AttributeOriginalMessageID,
+	AttributeFailureReason,
+	AttributeFailureMetadata,
+}
+
+// SQSAPI is the part of an SQS client needed to manage a dead letter queue; *sqs.Client and
+// queue.MemoryQueue are one
+type SQSAPI interface {
+	ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error)
+	SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
+	DeleteMessage(ctx context.Context, params *sqs.DeleteMessageInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error)
+	ChangeMessageVisibility(ctx context.Context, params *sqs.ChangeMessageVisibilityInput, optFns ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error)
+}
+
+// Message is a dead lettered message
+type Message struct {
+	MessageID         string `json:"messageId"`
+	OriginalMessageID string `json:"originalMessageId,omitempty"`
+	FailureReason     string `json:"failureReason,omitempty"`
+	EventType         string `json:"eventType,omitempty"`
+	ClientID          string `json:"clientId,omitempty"`
+
+	// Failure is read from the FailureMetadata attribute; messages dead lettered before it was
+	// added have none
+	Failure *FailureMetadata `json:"failure,omitempty"`
+
+	// Attributes holds every string and number attribute of the message
+	Attributes map[string]string `json:"attributes"`
+
+	Body string `json:"body"`
+
+	receiptHandle string
+	attributes    map[string]types.MessageAttributeValue
+	traceHeader   map[string]types.MessageSystemAttributeValue
+}
+
+// Filter selects dead lettered messages; empty fields match every message
+type Filter struct {
+	ClientID  string `json:"clientId,omitempty"`
+	EventType string `json:"eventType,omitempty"`
+
+	// Reason matches messages whose failure reason contains it
+	Reason string `json:"reason,omitempty"`
+
+	// Category matches the error category of the last failed attempt
+	Category string `json:"category,omitempty"`
+
+	// MessageIDs matches messages by their DLQ or original message ID
+	MessageIDs []string `json:"messageIds,omitempty"`
+
+	// Limit is the maximum number of messages to select; zero selects every match
+	Limit int `json:"limit,omitempty"`
+}
+
+// IsEmpty reports whether the filter selects every message
+func (f Filter) IsEmpty() bool {
+	return f.ClientID == "" && f.EventType == "" && f.Reason == "" && f.Category == "" && len(f.MessageIDs) == 0
+}
+
+// Matches reports whether a message passes the filter
+func (f Filter) Matches(message *Message) bool {
+	if f.ClientID != "" && message.ClientID != f.ClientID {
+		return false
+	}
+	if f.EventType != "" && message.EventType != f.EventType {
+		return false
+	}
+	if f.Reason != "" && !strings.Contains(message.FailureReason, f.Reason) {
+		return false
+	}
+	if f.Category != "" && (message.Failure == nil || message.Failure.Category != f.Category) {
+		return false
+	}
+	if len(f.MessageIDs) == 0 {
+		return true
+	}
+	for _, id := range f.MessageIDs {
+		if id == message.MessageID || id == message.OriginalMessageID {
+			return true
+		}
+	}
+	return false
+}
+
+// Group counts the dead lettered messages of a client failing for the same reason
+type Group struct {
+	FailureReason string `json:"failureReason"`
+	ClientID      string `json:"clientId"`
+	Count         int    `json:"count"`
+}
+
+// RedriveOptions controls which messages are redriven and how
+type RedriveOptions struct {
+	Filter Filter `json:"filter"`
+
+	// Patch is a JSON merge patch (RFC 7396) applied to every body before it is sent
+	Patch json.RawMessage `json:"patch,omitempty"`
+
+	// RatePerSecond caps the number of messages sent per second; zero sends as fast as possible
+	RatePerSecond float64 `json:"ratePerSecond,omitempty"`
+}
+
+// Failure is a message an operation could not complete for
+type Failure struct {
+	MessageID string `json:"messageId"`
+	Error     string `json:"error"`
+}
+
+// Result reports what a redrive or purge did
+type Result struct {
+	// Matched is the number of messages selected by the filter
+	Matched int `json:"matched"`
+
+	// Succeeded is the number of messages redriven or purged
+	Succeeded int `json:"succeeded"`
+
+	// Failures lists the selected messages that were left in the dead letter queue
+	Failures []Failure `json:"failures,omitempty"`
+}
+
+// DefaultVisibilityTimeout is how long a scan hides the messages it received when no timeout is given
+const DefaultVisibilityTimeout = 5 * time.Minute
+
+// Options configures a Manager
+type Options struct {
+	// WaitTime is how long a receive waits for messages before the queue is taken to have been read
+	// completely; SQS needs at least a second, as short polls may miss messages that are available
+	WaitTime time.Duration
+
+	// VisibilityTimeout hides received messages while a scan is running, so that each is seen once
+	// (DefaultVisibilityTimeout when zero)
+	VisibilityTimeout time.Duration
+}
+
+// Manager inspects, redrives and purges the messages of a dead letter queue
+type Manager struct {
+	client   SQSAPI
+	dlqURL   string
+	queueURL string
+	options  Options
+	logger   *logrus.Logger
+}
+
+// NewManager creates a manager for the dead letter queue at dlqURL; messages are redriven to queueURL
+func NewManager(client SQSAPI, dlqURL, queueURL string, options Options, logger *logrus.Logger) *Manager {
+	if options.VisibilityTimeout <= 0 {
+		options.VisibilityTimeout = DefaultVisibilityTimeout
+	}
+	return &Manager{
+		client:   client,
+		dlqURL:   dlqURL,
+		queueURL: queueURL,
+		options:  options,
+		logger:   logger,
+	}
+}
+
+// Peek returns the messages matching the filter, leaving them in the queue
+func (m *Manager) Peek(ctx context.Context, filter Filter) ([]*Message, error) {
+	messages := []*Message{}
+	_, err := m.scan(ctx, filter, func(message *Message) (bool, error) {
+		messages = append(messages, message)
+		return false, nil
+	})
+	return messages, err
+}
+
+// Group counts the messages matching the filter by failure reason and client, largest group first
+func (m *Manager) Group(ctx context.Context, filter Filter) ([]Group, error) {
+	counts := make(map[Group]int)
+	_, err := m.scan(ctx, filter, func(message *Message) (bool, error) {
+		counts[Group{FailureReason: message.FailureReason, ClientID: message.ClientID}]++
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]Group, 0, len(counts))
+	for group, count := range counts {
+		group.Count = count
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		if groups[i].FailureReason != groups[j].FailureReason {
+			return groups[i].FailureReason < groups[j].FailureReason
+		}
+		return groups[i].ClientID < groups[j].ClientID
+	})
+	return groups, nil
+}
+
+// Redrive sends the messages matching the filter back to the event queue, patched when the options
+// have a patch, and removes them from the dead letter queue. Messages that cannot be patched or sent
+// stay in the dead letter queue and are reported as failures.
+func (m *Manager) Redrive(ctx context.Context, options RedriveOptions) (*Result, error) {
+	if len(options.Patch) > 0 {
+		if err := ValidatePatch(options.Patch); err != nil {
+			return nil, err
+		}
+	}
+
+	pace := newPacer(options.RatePerSecond)
+	return m.scan(ctx, options.Filter, func(message *Message) (bool, error) {
+		body := message.Body
+		if len(options.Patch) > 0 {
+			patched, err := MergePatch([]byte(body), options.Patch)
+			if err != nil {
+				return false, fmt.Errorf("failed to patch body: %w", err)
+			}
+			body = string(patched)
+		}
+
+		if err := pace.wait(ctx); err != nil {
+			return false, err
+		}
+		output, err := m.client.SendMessage(ctx, &sqs.SendMessageInput{
+			QueueUrl:                aws.String(m.queueURL),
+			MessageBody:             aws.String(body),
+			MessageAttributes:       redriveAttributes(message, body),
+			MessageSystemAttributes: message.traceHeader,
+		})
+		if err != nil {
+			return false, fmt.Errorf("failed to send message: %w", err)
+		}
+
+		metrics.MessagesRedriven.WithLabelValues(metrics.LabelOrUnknown(message.EventType), metrics.LabelOrUnknown(message.ClientID)).Inc()
+		m.logger.WithFields(logrus.Fields{
+			"message_id":     message.MessageID,
+			"new_message_id": aws.ToString(output.MessageId),
+			"patched":        len(options.Patch) > 0,
+		}).Info("Dead lettered message redriven")
+		return true, nil
+	})
+}
+
+// Purge deletes the messages matching the filter from the dead letter queue
+func (m *Manager) Purge(ctx context.Context, filter Filter) (*Result, error) {
+	return m.scan(ctx, filter, func(message *Message) (bool, error) {
+		metrics.MessagesPurged.WithLabelValues(metrics.LabelOrUnknown(message.EventType), metrics.LabelOrUnknown(message.ClientID)).Inc()
+		m.logger.WithField("message_id", message.MessageID).Info("Dead lettered message purged")
+		return true, nil
+	})
+}
+
+// scan receives every message of the dead letter queue and calls fn for those matching the filter,
+// up to its limit. Messages fn consumes are deleted; all others are made visible again once the
+// scan ends. An error from fn is recorded as a failure of that message; the scan only fails when
+// the queue cannot be read or the context is done.
+func (m *Manager) scan(ctx context.Context, filter Filter, fn func(*Message) (bool, error)) (*Result, error) {
+	result := &Result{}
+	seen := make(map[string]bool)
+	var held []*Message
+	defer func() {
+		m.release(held)
+	}()
+
+	for filter.Limit <= 0 || result.Matched < filter.Limit {
+		output, err := m.client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:              aws.String(m.dlqURL),
+			MaxNumberOfMessages:   10,
+			WaitTimeSeconds:       int32(m.options.WaitTime / time.Second),
+			VisibilityTimeout:     int32(m.options.VisibilityTimeout / time.Second),
+			MessageAttributeNames: []string{"All"},
+			MessageSystemAttributeNames: []types.MessageSystemAttributeName{
+				types.MessageSystemAttributeNameAWSTraceHeader,
+			},
+		})
+		if err != nil {
+			return result, fmt.Errorf("failed to receive dead lettered messages: %w", err)
+		}
+		if len(output.Messages) == 0 {
+			return result, nil
+		}
+
+		// A message seen before became visible again, so the whole queue has been read
+		wrapped := false
+		for i := range output.Messages {
+			message := newMessage(&output.Messages[i])
+			if seen[message.MessageID] {
+				wrapped = true
+				held = append(held, message)
+				continue
+			}
+			seen[message.MessageID] = true
+
+			if !filter.Matches(message) || (filter.Limit > 0 && result.Matched >= filter.Limit) {
+				held = append(held, message)
+				continue
+			}
+			result.Matched++
+
+			consumed, err := fn(message)
+			if err != nil {
+				if ctx.Err() != nil {
+					held = append(held, message)
+					return result, ctx.Err()
+				}
+				result.Failures = append(result.Failures, Failure{MessageID: message.MessageID, Error: err.Error()})
+			}
+			if !consumed {
+				held = append(held, message)
+				continue
+			}
+
+			if err := m.delete(ctx, message); err != nil {
+				result.Failures = append(result.Failures, Failure{MessageID: message.MessageID, Error: err.Error()})
+				continue
+			}
+			result.Succeeded++
+		}
+		if wrapped {
+			return result, nil
+		}
+	}
+	return result, nil
+}
+
+// delete removes a message from the dead letter queue
+func (m *Manager) delete(ctx context.Context, message *Message) error {
+	_, err := m.client.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(m.dlqURL),
+		ReceiptHandle: aws.String(message.receiptHandle),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete message: %w", err)
+	}
+	return nil
+}
+
+// release makes messages received by a scan visible again straight away
+func (m *Manager) release(messages []*Message) {
+	// Releasing must not be skipped because the scan's context is done
+	ctx := context.Background()
+	for _, message := range messages {
+		_, err := m.client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+			QueueUrl:          aws.String(m.dlqURL),
+			ReceiptHandle:     aws.String(message.receiptHandle),
+			VisibilityTimeout: 0,
+		})
+		if err != nil {
+			m.logger.WithError(err).WithField("message_id", message.MessageID).Warn("Failed to release dead lettered message; it is visible again once its visibility timeout ends")
+		}
+	}
+}
+
+// newMessage reads the attributes of a received message
+func newMessage(received *types.Message) *Message {
+	message := &Message{
+		MessageID:     aws.ToString(received.MessageId),
+		Body:          aws.ToString(received.Body),
+		Attributes:    make(map[string]string, len(received.MessageAttributes)),
+		Failure:       ReadFailureMetadata(received.MessageAttributes),
+		receiptHandle: aws.ToString(received.ReceiptHandle),
+		attributes:    received.MessageAttributes,
+		traceHeader:   TraceHeader(received),
+	}
+	for name, value := range received.MessageAttributes {
+		if value.StringValue != nil {
+			message.Attributes[name] = aws.ToString(value.StringValue)
+		}
+	}
+	message.OriginalMessageID = message.Attributes[AttributeOriginalMessageID]
+	message.FailureReason = message.Attributes[AttributeFailureReason]
+	message.EventType = message.Attributes[AttributeEventType]
+	message.ClientID = message.Attributes[AttributeClientID]
+	return message
+}
+
+// redriveAttributes returns the attributes of a redriven message: those the producer set, including
+// any kept in the failure metadata for lack of room, with the event type and client taken from the
+// body as a patch may have changed them
+func redriveAttributes(message *Message, body string) map[string]types.MessageAttributeValue {
+	attributes := make(map[string]types.MessageAttributeValue, len(message.attributes))
+	for name, value := range message.attributes {
+		attributes[name] = value
+	}
+	for _, name := range deadLetterAttributes {
+		delete(attributes, name)
+	}
+	if message.Failure != nil {
+		for name, value := range message.Failure.OverflowAttributes {
+			if _, ok := attributes[name]; !ok && !isDeadLetterAttribute(name) && len(attributes) < MaxMessageAttributes {
+				attributes[name] = value.messageAttribute()
+			}
+		}
+	}
+
+	var fields struct {
+		EventType string `json:"eventType"`
+		ClientID  string `json:"clientId"`
+	}
+	if json.Unmarshal([]byte(body), &fields) == nil {
+		if fields.EventType != "" {
+			attributes[AttributeEventType] = stringAttribute(fields.EventType)
+		}
+		if fields.ClientID != "" {
+			attributes[AttributeClientID] = stringAttribute(fields.ClientID)
+		}
+	}
+	return attributes
+}
+
+// isDeadLetterAttribute reports whether an attribute is removed from redriven messages
+func isDeadLetterAttribute(name string) bool {
+	for _, deadLetterAttribute := range deadLetterAttributes {
+		if name == deadLetterAttribute {
+			return true
+		}
+	}
+	return false
+}
+
+// pacer spaces out sends to stay under a rate
+type pacer struct {
+	interval time.Duration
+	next     time.Time
+}
+
+func newPacer(ratePerSecond float64) *pacer {
+	if ratePerSecond <= 0 {
+		return &pacer{}
+	}
+	return &pacer{interval: time.Duration(float64(time.Second) / ratePerSecond)}
+}
+
+// wait blocks until the next send is allowed
+func (p *pacer) wait(ctx context.Context) error {
+	if p.interval == 0 {
+		return nil
+	}
+
+	if delay := time.Until(p.next); delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	p.next = time.Now().Add(p.interval)
+	return nil
+}
@@ -0,0 +1,437 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d-sense/event-processor/internal/queue"
+)
+
+const (
+	testQueueURL = "memory://event-queue"
+	testDLQURL   = "memory://event-dlq"
+)
+
+// failingSendQueue is a memory queue whose sends fail
+type failingSendQueue struct {
+	*queue.MemoryQueue
+}
+
+func (q failingSendQueue) SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	return nil, errors.New("sqs error")
+}
+
+// deadLetter sends a message to the test DLQ with the attributes the consumer sets
+func deadLetter(t *testing.T, q *queue.MemoryQueue, originalID, clientID, reason, body string) {
+	attributes := map[string]types.MessageAttributeValue{
+		AttributeOriginalMessageID: {DataType: aws.String("String"), StringValue: aws.String(originalID)},
+		AttributeFailureReason:     {DataType: aws.String("String"), StringValue: aws.String(reason)},
+		AttributeEventType:         {DataType: aws.String("String"), StringValue: aws.String("transaction")},
+		AttributeRetryCount:        {DataType: aws.String("Number"), StringValue: aws.String("3")},
+		"TraceParent":              {DataType: aws.String("String"), StringValue: aws.String("00-trace-span-01")},
+	}
+	if clientID != "" {
+		attributes[AttributeClientID] = types.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(clientID)}
+	}
+	_, err := q.SendMessage(context.Background(), &sqs.SendMessageInput{
+		QueueUrl:          aws.String(testDLQURL),
+		MessageBody:       aws.String(body),
+		MessageAttributes: attributes,
+	})
+	require.NoError(t, err)
+}
+
+// newTestQueue returns a memory queue with four dead lettered messages
+func newTestQueue(t *testing.T) *queue.MemoryQueue {
+	q := queue.NewMemoryQueue(testQueueURL)
+	deadLetter(t, q, "orig-1", "client-001", "Max retries exceeded", `{"eventId":"evt-1","clientId":"client-001","payload":{"amount":"10"}}`)
+	deadLetter(t, q, "orig-2", "client-001", "Max retries exceeded", `{"eventId":"evt-2","clientId":"client-001","payload":{"amount":"20"}}`)
+	deadLetter(t, q, "orig-3", "client-002", "Max retries exceeded", `{"eventId":"evt-3","clientId":"client-002"}`)
+	deadLetter(t, q, "orig-4", "client-001", "Processing failed: timeout", `not json`)
+	return q
+}
+
+func newTestManager(client SQSAPI) *Manager {
+	return NewManager(client, testDLQURL, testQueueURL, Options{}, logrus.New())
+}
+
+func originalIDs(messages []*Message) []string {
+	ids := make([]string, len(messages))
+	for i, message := range messages {
+		ids[i] = message.OriginalMessageID
+	}
+	return ids
+}
+
+// TestPeek tests that peeked messages are filtered and stay in the queue
+func TestPeek(t *testing.T) {
+	tests := []struct {
+		name        string
+		filter      Filter
+		expectedIDs []string
+		description string
+	}{
+		{
+			name:        "Every Message",
+			expectedIDs: []string{"orig-1", "orig-2", "orig-3", "orig-4"},
+			description: "Should return every message in the order it was dead lettered",
+		},
+		{
+			name:        "By Client",
+			filter:      Filter{ClientID: "client-001"},
+			expectedIDs: []string{"orig-1", "orig-2", "orig-4"},
+			description: "Should return the messages of one client",
+		},
+		{
+			name:        "By Reason",
+			filter:      Filter{Reason: "timeout"},
+			expectedIDs: []string{"orig-4"},
+			description: "Should match failure reasons containing the filter",
+		},
+		{
+			name:        "By Message ID",
+			filter:      Filter{MessageIDs: []string{"orig-3"}},
+			expectedIDs: []string{"orig-3"},
+			description: "Should match original message IDs",
+		},
+		{
+			name:        "With Limit",
+			filter:      Filter{Limit: 2},
+			expectedIDs: []string{"orig-1", "orig-2"},
+			description: "Should stop at the limit",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newTestQueue(t)
+			manager := newTestManager(q)
+
+			messages, err := manager.Peek(context.Background(), tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedIDs, originalIDs(messages), tt.description)
+			assert.Equal(t, 4, q.Len(testDLQURL), "Peeking should leave every message in the queue")
+
+			// Released messages can be peeked again
+			again, err := manager.Peek(context.Background(), tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedIDs, originalIDs(again))
+		})
+	}
+}
+
+// TestPeekMessage tests the fields read from a dead lettered message
+func TestPeekMessage(t *testing.T) {
+	manager := newTestManager(newTestQueue(t))
+
+	messages, err := manager.Peek(context.Background(), Filter{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+
+	message := messages[0]
+	assert.NotEmpty(t, message.MessageID)
+	assert.Equal(t, "Max retries exceeded", message.FailureReason)
+	assert.Equal(t, "client-001", message.ClientID)
+	assert.Equal(t, "transaction", message.EventType)
+	assert.Equal(t, "00-trace-span-01", message.Attributes["TraceParent"])
+	assert.JSONEq(t, `{"eventId":"evt-1","clientId":"client-001","payload":{"amount":"10"}}`, message.Body)
+}
+
+// TestGroup tests that messages are counted by failure reason and client
+func TestGroup(t *testing.T) {
+	q := newTestQueue(t)
+	manager := newTestManager(q)
+
+	groups, err := manager.Group(context.Background(), Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, []Group{
+		{FailureReason: "Max retries exceeded", ClientID: "client-001", Count: 2},
+		{FailureReason: "Max retries exceeded", ClientID: "client-002", Count: 1},
+		{FailureReason: "Processing failed: timeout", ClientID: "client-001", Count: 1},
+	}, groups)
+	assert.Equal(t, 4, q.Len(testDLQURL))
+}
+
+// TestRedrive tests that redriven messages are sent back to the event queue
+func TestRedrive(t *testing.T) {
+	tests := []struct {
+		name              string
+		options           RedriveOptions
+		failSends         bool
+		expectedResult    Result
+		expectedBodies    []string
+		expectedRemaining int
+		expectError       bool
+		description       string
+	}{
+		{
+			name:              "Redrive Client",
+			options:           RedriveOptions{Filter: Filter{ClientID: "client-002"}},
+			expectedResult:    Result{Matched: 1, Succeeded: 1},
+			expectedBodies:    []string{`{"eventId":"evt-3","clientId":"client-002"}`},
+			expectedRemaining: 3,
+			description:       "Should send the selected messages unchanged and remove them from the DLQ",
+		},
+		{
+			name: "Redrive With Patch",
+			options: RedriveOptions{
+				Filter: Filter{Reason: "Max retries", ClientID: "client-001"},
+				Patch:  json.RawMessage(`{"payload":{"amount":10,"currency":"EUR"}}`),
+			},
+			expectedResult: Result{Matched: 2, Succeeded: 2},
+			expectedBodies: []string{
+				`{"eventId":"evt-1","clientId":"client-001","payload":{"amount":10,"currency":"EUR"}}`,
+				`{"eventId":"evt-2","clientId":"client-001","payload":{"amount":10,"currency":"EUR"}}`,
+			},
+			expectedRemaining: 2,
+			description:       "Should patch the bodies before sending them",
+		},
+		{
+			name: "Patch Of Invalid Body",
+			options: RedriveOptions{
+				Filter: Filter{Reason: "timeout"},
+				Patch:  json.RawMessage(`{"clientId":"client-001"}`),
+			},
+			expectedResult: Result{
+				Matched:  1,
+				Failures: []Failure{{Error: "failed to patch body: body is not a JSON object"}},
+			},
+			expectedRemaining: 4,
+			description:       "Should keep messages whose body cannot be patched",
+		},
+		{
+			name:        "Invalid Patch",
+			options:     RedriveOptions{Patch: json.RawMessage(`{"payload":`)},
+			expectError: true,
+			description: "Should reject patches that are not JSON before reading the queue",
+		},
+		{
+			name:      "Send Failure",
+			options:   RedriveOptions{Filter: Filter{MessageIDs: []string{"orig-3"}}},
+			failSends: true,
+			expectedResult: Result{
+				Matched:  1,
+				Failures: []Failure{{Error: "failed to send message: sqs error"}},
+			},
+			expectedRemaining: 4,
+			description:       "Should keep messages that could not be sent",
+		},
+		{
+			name:              "Paced Redrive",
+			options:           RedriveOptions{Filter: Filter{ClientID: "client-001", Limit: 2}, RatePerSecond: 50},
+			expectedResult:    Result{Matched: 2, Succeeded: 2},
+			expectedRemaining: 2,
+			description:       "Should redrive at the given rate up to the limit",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newTestQueue(t)
+			var client SQSAPI = q
+			if tt.failSends {
+				client = failingSendQueue{q}
+			}
+
+			result, err := newTestManager(client).Redrive(context.Background(), tt.options)
+			if tt.expectError {
+				assert.Error(t, err, tt.description)
+				assert.Equal(t, 4, q.Len(testDLQURL))
+				return
+			}
+			require.NoError(t, err)
+
+			// Failures are reported with the DLQ message ID, which is random
+			for i := range result.Failures {
+				assert.NotEmpty(t, result.Failures[i].MessageID)
+				result.Failures[i].MessageID = ""
+			}
+			assert.Equal(t, tt.expectedResult, *result, tt.description)
+			assert.Equal(t, tt.expectedRemaining, q.Len(testDLQURL), tt.description)
+			assert.Equal(t, tt.expectedResult.Succeeded, q.Len(testQueueURL))
+
+			if tt.expectedBodies != nil {
+				output, err := q.ReceiveMessage(context.Background(), &sqs.ReceiveMessageInput{
+					QueueUrl:            aws.String(testQueueURL),
+					MaxNumberOfMessages: 10,
+				})
+				require.NoError(t, err)
+				require.Len(t, output.Messages, len(tt.expectedBodies))
+				for i, message := range output.Messages {
+					assert.JSONEq(t, tt.expectedBodies[i], aws.ToString(message.Body), tt.description)
+				}
+			}
+		})
+	}
+}
+
+// TestRedriveAttributes tests that redriven messages keep the producer's attributes but not the DLQ's
+func TestRedriveAttributes(t *testing.T) {
+	q := newTestQueue(t)
+
+	_, err := newTestManager(q).Redrive(context.Background(), RedriveOptions{
+		Filter: Filter{MessageIDs: []string{"orig-1"}},
+		Patch:  json.RawMessage(`{"clientId":"client-009"}`),
+	})
+	require.NoError(t, err)
+
+	output, err := q.ReceiveMessage(context.Background(), &sqs.ReceiveMessageInput{QueueUrl: aws.String(testQueueURL)})
+	require.NoError(t, err)
+	require.Len(t, output.Messages, 1)
+
+	attributes := make(map[string]string)
+	for name, value := range output.Messages[0].MessageAttributes {
+		attributes[name] = aws.ToString(value.StringValue)
+	}
+	assert.Equal(t, map[string]string{
+		AttributeEventType: "transaction",
+		AttributeClientID:  "client-009",
+		"TraceParent":      "00-trace-span-01",
+	}, attributes)
+}
+
+// TestRedriveDeadLetteredByConsumer tests that messages dead lettered with failure metadata are
+// redriven with the attributes and trace header they had before they failed
+func TestRedriveDeadLetteredByConsumer(t *testing.T) {
+	q := queue.NewMemoryQueue(testQueueURL)
+
+	original := map[string]types.MessageAttributeValue{
+		AttributeEventType:  stringAttribute("transaction"),
+		AttributeClientID:   stringAttribute("client-001"),
+		AttributeRetryCount: {DataType: aws.String("Number"), StringValue: aws.String("3")},
+	}
+	for i := 1; i <= 6; i++ {
+		original[fmt.Sprintf("Custom%d", i)] = stringAttribute(fmt.Sprint(i))
+	}
+	original["Signature"] = types.MessageAttributeValue{DataType: aws.String("Binary"), BinaryValue: []byte{0x00, 0xff}}
+	original = WithAttempt(original, Attempt{At: time.Now(), Stage: "triage", Category: "client_lookup", Error: "timeout"})
+	metadata := NewFailureMetadata(Attempts(original), "worker-1", time.Now())
+
+	_, err := q.SendMessage(context.Background(), &sqs.SendMessageInput{
+		QueueUrl:          aws.String(testDLQURL),
+		MessageBody:       aws.String(`{"eventId":"evt-1","clientId":"client-001"}`),
+		MessageAttributes: DeadLetterAttributes(original, "orig-1", "Max retries exceeded", metadata),
+		MessageSystemAttributes: map[string]types.MessageSystemAttributeValue{
+			"AWSTraceHeader": {DataType: aws.String("String"), StringValue: aws.String("Root=1-abc")},
+		},
+	})
+	require.NoError(t, err)
+
+	manager := newTestManager(q)
+	messages, err := manager.Peek(context.Background(), Filter{Category: "client_lookup"})
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	require.NotNil(t, messages[0].Failure)
+	assert.Equal(t, "triage", messages[0].Failure.Stage)
+	assert.Equal(t, "worker-1", messages[0].Failure.ConsumerHost)
+
+	result, err := manager.Redrive(context.Background(), RedriveOptions{Filter: Filter{Category: "client_lookup"}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Succeeded)
+
+	output, err := q.ReceiveMessage(context.Background(), &sqs.ReceiveMessageInput{QueueUrl: aws.String(testQueueURL)})
+	require.NoError(t, err)
+	require.Len(t, output.Messages, 1)
+
+	expected := map[string]string{AttributeEventType: "transaction", AttributeClientID: "client-001"}
+	for i := 1; i <= 6; i++ {
+		expected[fmt.Sprintf("Custom%d", i)] = fmt.Sprint(i)
+	}
+	// Binary attributes have no string value
+	expected["Signature"] = ""
+	assert.Equal(t, expected, attributeValues(output.Messages[0].MessageAttributes), "Should restore the producer's attributes and drop the consumer's")
+	signature := output.Messages[0].MessageAttributes["Signature"]
+	assert.Equal(t, "Binary", aws.ToString(signature.DataType))
+	assert.Equal(t, []byte{0x00, 0xff}, signature.BinaryValue, "Should restore binary attributes kept in the metadata")
+	assert.Equal(t, "Root=1-abc", output.Messages[0].Attributes["AWSTraceHeader"])
+}
+
+// TestPurge tests that purged messages are deleted from the DLQ
+func TestPurge(t *testing.T) {
+	q := newTestQueue(t)
+	manager := newTestManager(q)
+
+	result, err := manager.Purge(context.Background(), Filter{ClientID: "client-001", Reason: "Max retries"})
+	require.NoError(t, err)
+	assert.Equal(t, Result{Matched: 2, Succeeded: 2}, *result)
+
+	messages, err := manager.Peek(context.Background(), Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"orig-3", "orig-4"}, originalIDs(messages))
+	assert.Equal(t, 0, q.Len(testQueueURL), "Purged messages should not be redriven")
+}
+
+// TestMergePatch tests JSON merge patches
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		document    string
+		patch       string
+		expected    string
+		expectError bool
+		errorMsg    string
+		description string
+	}{
+		{
+			name:        "Replace And Add",
+			document:    `{"eventType":"transaction","payload":{"amount":"10"}}`,
+			patch:       `{"version":"1.0","payload":{"amount":10}}`,
+			expected:    `{"eventType":"transaction","version":"1.0","payload":{"amount":10}}`,
+			description: "Should replace existing members and add new ones",
+		},
+		{
+			name:        "Remove With Null",
+			document:    `{"eventType":"transaction","payload":{"amount":10,"debug":true}}`,
+			patch:       `{"payload":{"debug":null}}`,
+			expected:    `{"eventType":"transaction","payload":{"amount":10}}`,
+			description: "Should remove members patched to null",
+		},
+		{
+			name:        "Replace Non Object",
+			document:    `{"payload":"oops"}`,
+			patch:       `{"payload":{"amount":10}}`,
+			expected:    `{"payload":{"amount":10}}`,
+			description: "Should replace members that are not objects with the patch object",
+		},
+		{
+			name:        "Body Not An Object",
+			document:    `[1,2]`,
+			patch:       `{"a":1}`,
+			expectError: true,
+			errorMsg:    "body is not a JSON object",
+			description: "Should only patch JSON objects",
+		},
+		{
+			name:        "Patch Not An Object",
+			document:    `{"a":1}`,
+			patch:       `[1]`,
+			expectError: true,
+			errorMsg:    "invalid patch: not a JSON object",
+			description: "Should only accept object patches",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patched, err := MergePatch([]byte(tt.document), []byte(tt.patch))
+			if tt.expectError {
+				assert.Error(t, err, tt.description)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(patched), tt.description)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package dlq
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// MergePatch applies a JSON merge patch (RFC 7396) to a JSON object: patch members replace those of
+// the document, null removes them and nested objects are merged recursively
+func MergePatch(document, patch []byte) ([]byte, error) {
+	var target map[string]interface{}
+	if err := json.Unmarshal(document, &target); err != nil || target == nil {
+		return nil, errors.New("body is not a JSON object")
+	}
+
+	patchObject, err := parsePatch(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(mergeObjects(target, patchObject))
+}
+
+// ValidatePatch checks that a patch is a JSON object, as MergePatch requires
+func ValidatePatch(patch []byte) error {
+	_, err := parsePatch(patch)
+	return err
+}
+
+// parsePatch decodes a merge patch
+func parsePatch(patch []byte) (map[string]interface{}, error) {
+	var changes interface{}
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return nil, fmt.Errorf("invalid patch: %w", err)
+	}
+	patchObject, ok := changes.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("invalid patch: not a JSON object")
+	}
+	return patchObject, nil
+}
+
+// mergeObjects merges patch into target and returns it
+func mergeObjects(target, patch map[string]interface{}) map[string]interface{} {
+	for key, value := range patch {
+		if value == nil {
+			delete(target, key)
+			continue
+		}
+
+		patchObject, ok := value.(map[string]interface{})
+		if !ok {
+			target[key] = value
+			continue
+		}
+		targetObject, ok := target[key].(map[string]interface{})
+		if !ok {
+			targetObject = make(map[string]interface{})
+		}
+		target[key] = mergeObjects(targetObject, patchObject)
+	}
+	return target
+}
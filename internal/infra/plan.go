@@ -0,0 +1,184 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Action is what a change does to a resource
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// symbols prefix changes in printed plans
+var symbols = map[Action]string{
+	ActionCreate: "+",
+	ActionUpdate: "~",
+	ActionDelete: "-",
+}
+
+// Change is a single step converging a resource towards the spec
+type Change struct {
+	Action   Action
+	Resource string
+	Name     string
+
+	// Details describe the differences, e.g. "VisibilityTimeout: 30 -> 60"
+	Details []string
+
+	apply func(ctx context.Context) error
+}
+
+// String returns the change as printed in plans
+func (c Change) String() string {
+	line := fmt.Sprintf("%s %s %s %s", symbols[c.Action], c.Action, c.Resource, c.Name)
+	for _, detail := range c.Details {
+		line += "\n    " + detail
+	}
+	return line
+}
+
+// Plan is the ordered list of changes that converge the actual infrastructure to a spec
+type Plan struct {
+	Changes []Change
+}
+
+// Empty reports whether the infrastructure already matches the spec
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// Write prints the plan
+func (p *Plan) Write(w io.Writer) error {
+	if p.Empty() {
+		_, err := fmt.Fprintln(w, "No changes. Infrastructure matches the spec.")
+		return err
+	}
+
+	counts := make(map[Action]int)
+	for _, change := range p.Changes {
+		counts[change.Action]++
+		if _, err := fmt.Fprintln(w, change); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "\nPlan: %d to create, %d to update, %d to delete.\n",
+		counts[ActionCreate], counts[ActionUpdate], counts[ActionDelete])
+	return err
+}
+
+// Planner compares a spec with the actual tables and queues and converges them
+type Planner struct {
+	dynamodb DynamoDBAPI
+	sqs      SQSAPI
+	logger   *logrus.Logger
+}
+
+// NewPlanner creates a planner using the given clients
+func NewPlanner(dynamodbClient DynamoDBAPI, sqsClient SQSAPI, logger *logrus.Logger) *Planner {
+	return &Planner{dynamodb: dynamodbClient, sqs: sqsClient, logger: logger}
+}
+
+// Plan returns the changes needed to converge the infrastructure to spec. Differences that cannot
+// be converged, such as a changed table key, are returned as an error.
+func (p *Planner) Plan(ctx context.Context, spec *Spec) (*Plan, error) {
+	plan := &Plan{}
+	var problems []error
+
+	for _, table := range spec.Tables {
+		changes, err := p.planTable(ctx, table)
+		if err != nil {
+			problems = append(problems, err)
+			continue
+		}
+		plan.Changes = append(plan.Changes, changes...)
+	}
+
+	for _, queue := range orderQueues(spec.Queues) {
+		change, err := p.planQueue(ctx, queue)
+		if err != nil {
+			problems = append(problems, err)
+			continue
+		}
+		if change != nil {
+			plan.Changes = append(plan.Changes, *change)
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, errors.Join(problems...)
+	}
+	return plan, nil
+}
+
+// Apply makes the changes of plan in order, stopping at the first failure
+func (p *Planner) Apply(ctx context.Context, plan *Plan) error {
+	for _, change := range plan.Changes {
+		p.logger.WithField("action", change.Action).
+			WithField("resource", change.Resource).
+			WithField("name", change.Name).
+			Info("Applying infrastructure change")
+
+		if err := change.apply(ctx); err != nil {
+			return fmt.Errorf("failed to %s %s %s: %w", change.Action, change.Resource, change.Name, err)
+		}
+	}
+	return nil
+}
+
+// orderQueues returns the queues with dead letter queues first, so they exist before the
+// queues redriving to them
+func orderQueues(queues []QueueSpec) []QueueSpec {
+	deadLetterQueues := make(map[string]bool)
+	for _, queue := range queues {
+		if queue.Redrive != nil {
+			deadLetterQueues[queue.Redrive.DeadLetterQueue] = true
+		}
+	}
+
+	ordered := append([]QueueSpec(nil), queues...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return deadLetterQueues[ordered[i].Name] && !deadLetterQueues[ordered[j].Name]
+	})
+	return ordered
+}
+
+// diffDetail describes a changed setting
+func diffDetail(setting, actual, desired string) string {
+	if actual == "" {
+		actual = "(none)"
+	}
+	if desired == "" {
+		desired = "(none)"
+	}
+	return fmt.Sprintf("%s: %s -> %s", setting, actual, desired)
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// keyDescription describes a hash and optional range key
+func keyDescription(hashKey KeySpec, rangeKey *KeySpec) string {
+	parts := []string{hashKey.Name + " (" + hashKey.Type + ")"}
+	if rangeKey != nil {
+		parts = append(parts, rangeKey.Name+" ("+rangeKey.Type+")")
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,523 @@
+package infra
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockDynamoDBAPI is a mock implementation of the DynamoDBAPI interface
+type MockDynamoDBAPI struct {
+	mock.Mock
+}
+
+func (m *MockDynamoDBAPI) DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dynamodb.DescribeTableOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBAPI) CreateTable(ctx context.Context, params *dynamodb.CreateTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dynamodb.CreateTableOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBAPI) UpdateTable(ctx context.Context, params *dynamodb.UpdateTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTableOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dynamodb.UpdateTableOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBAPI) DescribeTimeToLive(ctx context.Context, params *dynamodb.DescribeTimeToLiveInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTimeToLiveOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dynamodb.DescribeTimeToLiveOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBAPI) UpdateTimeToLive(ctx context.Context, params *dynamodb.UpdateTimeToLiveInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dynamodb.UpdateTimeToLiveOutput), args.Error(1)
+}
+
+// MockSQSAPI is a mock implementation of the SQSAPI interface
+type MockSQSAPI struct {
+	mock.Mock
+}
+
+func (m *MockSQSAPI) GetQueueUrl(ctx context.Context, params *sqs.GetQueueUrlInput, optFns ...func(*sqs.Options)) (*sqs.GetQueueUrlOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*sqs.GetQueueUrlOutput), args.Error(1)
+}
+
+func (m *MockSQSAPI) CreateQueue(ctx context.Context, params *sqs.CreateQueueInput, optFns ...func(*sqs.Options)) (*sqs.CreateQueueOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*sqs.CreateQueueOutput), args.Error(1)
+}
+
+func (m *MockSQSAPI) GetQueueAttributes(ctx context.Context, params *sqs.GetQueueAttributesInput, optFns ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*sqs.GetQueueAttributesOutput), args.Error(1)
+}
+
+func (m *MockSQSAPI) SetQueueAttributes(ctx context.Context, params *sqs.SetQueueAttributesInput, optFns ...func(*sqs.Options)) (*sqs.SetQueueAttributesOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*sqs.SetQueueAttributesOutput), args.Error(1)
+}
+
+// queueNamed matches GetQueueUrl calls for a queue
+func queueNamed(name string) interface{} {
+	return mock.MatchedBy(func(input *sqs.GetQueueUrlInput) bool { return aws.ToString(input.QueueName) == name })
+}
+
+// queueURL matches GetQueueAttributes and SetQueueAttributes calls for a queue
+func queueURL(name string) interface{} {
+	return mock.MatchedBy(func(input interface{}) bool {
+		switch input := input.(type) {
+		case *sqs.GetQueueAttributesInput:
+			return aws.ToString(input.QueueUrl) == testQueueURL(name)
+		case *sqs.SetQueueAttributesInput:
+			return aws.ToString(input.QueueUrl) == testQueueURL(name)
+		}
+		return false
+	})
+}
+
+func testQueueURL(name string) string {
+	return "http://localhost:4566/000000000000/" + name
+}
+
+func testQueueARN(name string) string {
+	return "arn:aws:sqs:us-east-1:000000000000:" + name
+}
+
+// existingQueue expects a queue to exist with the given attributes
+func existingQueue(mockClient *MockSQSAPI, name string, attributes map[string]string) {
+	withArn := map[string]string{string(sqstypes.QueueAttributeNameQueueArn): testQueueARN(name)}
+	for key, value := range attributes {
+		withArn[key] = value
+	}
+	mockClient.On("GetQueueUrl", mock.Anything, queueNamed(name)).
+		Return(&sqs.GetQueueUrlOutput{QueueUrl: aws.String(testQueueURL(name))}, nil)
+	mockClient.On("GetQueueAttributes", mock.Anything, queueURL(name)).
+		Return(&sqs.GetQueueAttributesOutput{Attributes: withArn}, nil)
+}
+
+// missingQueue expects a queue not to exist once
+func missingQueue(mockClient *MockSQSAPI, name string) {
+	mockClient.On("GetQueueUrl", mock.Anything, queueNamed(name)).
+		Return(nil, &sqstypes.QueueDoesNotExist{Message: aws.String("queue does not exist")}).Once()
+}
+
+// testSpec returns a spec with one indexed table, a queue and its dead letter queue
+func testSpec() *Spec {
+	spec := &Spec{
+		Tables: []TableSpec{
+			{
+				Name:         "events",
+				HashKey:      KeySpec{Name: "event_id", Type: "S"},
+				Stream:       "NEW_AND_OLD_IMAGES",
+				TTLAttribute: "ttl",
+				Indexes:      []IndexSpec{{Name: "client_id_index", HashKey: KeySpec{Name: "client_id", Type: "S"}}},
+			},
+		},
+		Queues: []QueueSpec{
+			{
+				Name:       "event-queue",
+				Attributes: map[string]string{"VisibilityTimeout": "30"},
+				Redrive:    &RedriveSpec{DeadLetterQueue: "event-dlq", MaxReceiveCount: 5},
+			},
+			{Name: "event-dlq", Attributes: map[string]string{"VisibilityTimeout": "30"}},
+		},
+	}
+	if err := spec.Validate(); err != nil {
+		panic(err)
+	}
+	return spec
+}
+
+// eventsTable returns the description of the events table of testSpec
+func eventsTable() *types.TableDescription {
+	return &types.TableDescription{
+		TableName:   aws.String("events"),
+		TableStatus: types.TableStatusActive,
+		KeySchema:   []types.KeySchemaElement{{AttributeName: aws.String("event_id"), KeyType: types.KeyTypeHash}},
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("event_id"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("client_id"), AttributeType: types.ScalarAttributeTypeS},
+		},
+		BillingModeSummary: &types.BillingModeSummary{BillingMode: types.BillingModePayPerRequest},
+		StreamSpecification: &types.StreamSpecification{
+			StreamEnabled:  aws.Bool(true),
+			StreamViewType: types.StreamViewTypeNewAndOldImages,
+		},
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndexDescription{
+			{
+				IndexName:   aws.String("client_id_index"),
+				IndexStatus: types.IndexStatusActive,
+				KeySchema:   []types.KeySchemaElement{{AttributeName: aws.String("client_id"), KeyType: types.KeyTypeHash}},
+			},
+		},
+	}
+}
+
+// timeToLive returns a TTL description enabled on attribute, disabled when empty
+func timeToLive(attribute string) *dynamodb.DescribeTimeToLiveOutput {
+	if attribute == "" {
+		return &dynamodb.DescribeTimeToLiveOutput{
+			TimeToLiveDescription: &types.TimeToLiveDescription{TimeToLiveStatus: types.TimeToLiveStatusDisabled},
+		}
+	}
+	return &dynamodb.DescribeTimeToLiveOutput{
+		TimeToLiveDescription: &types.TimeToLiveDescription{
+			AttributeName:    aws.String(attribute),
+			TimeToLiveStatus: types.TimeToLiveStatusEnabled,
+		},
+	}
+}
+
+// redriveTo returns the RedrivePolicy attribute SQS reports for a dead letter queue
+func redriveTo(name string, maxReceiveCount int) string {
+	return fmt.Sprintf(`{"deadLetterTargetArn":%q,"maxReceiveCount":%d}`, testQueueARN(name), maxReceiveCount)
+}
+
+// headlines returns the first line of every change
+func headlines(plan *Plan) []string {
+	var lines []string
+	for _, change := range plan.Changes {
+		lines = append(lines, fmt.Sprintf("%s %s %s", change.Action, change.Resource, change.Name))
+	}
+	return lines
+}
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+// TestPlan tests diffing the spec against the actual tables and queues
+func TestPlan(t *testing.T) {
+	tests := []struct {
+		name            string
+		mockDynamoDB    func(*MockDynamoDBAPI)
+		mockSQS         func(*MockSQSAPI)
+		expectedChanges []string
+		expectedDetails []string
+		expectError     bool
+		errorMsg        string
+		description     string
+	}{
+		{
+			name: "Nothing Exists",
+			mockDynamoDB: func(mockClient *MockDynamoDBAPI) {
+				mockClient.On("DescribeTable", mock.Anything, mock.Anything).
+					Return(nil, &types.ResourceNotFoundException{Message: aws.String("not found")})
+			},
+			mockSQS: func(mockClient *MockSQSAPI) {
+				missingQueue(mockClient, "event-queue")
+				missingQueue(mockClient, "event-dlq")
+			},
+			expectedChanges: []string{
+				"create table events",
+				"update ttl events",
+				"create queue event-dlq",
+				"create queue event-queue",
+			},
+			expectedDetails: []string{
+				"key: event_id (S)",
+				"index client_id_index: client_id (S)",
+				"stream: NEW_AND_OLD_IMAGES",
+				"redrive: event-dlq after 5 receives",
+			},
+			expectError: false,
+			description: "Should create everything, dead letter queues before the queues redriving to them",
+		},
+		{
+			name: "Up To Date",
+			mockDynamoDB: func(mockClient *MockDynamoDBAPI) {
+				mockClient.On("DescribeTable", mock.Anything, mock.Anything).
+					Return(&dynamodb.DescribeTableOutput{Table: eventsTable()}, nil)
+				mockClient.On("DescribeTimeToLive", mock.Anything, mock.Anything).Return(timeToLive("ttl"), nil)
+			},
+			mockSQS: func(mockClient *MockSQSAPI) {
+				existingQueue(mockClient, "event-dlq", map[string]string{"VisibilityTimeout": "30"})
+				existingQueue(mockClient, "event-queue", map[string]string{
+					"VisibilityTimeout": "30",
+					"RedrivePolicy":     redriveTo("event-dlq", 5),
+				})
+			},
+			expectedChanges: nil,
+			expectError:     false,
+			description:     "Should plan no changes when the infrastructure matches the spec",
+		},
+		{
+			name: "Drifted",
+			mockDynamoDB: func(mockClient *MockDynamoDBAPI) {
+				table := eventsTable()
+				table.StreamSpecification = nil
+				table.GlobalSecondaryIndexes = []types.GlobalSecondaryIndexDescription{
+					{
+						IndexName: aws.String("old_index"),
+						KeySchema: []types.KeySchemaElement{{AttributeName: aws.String("status"), KeyType: types.KeyTypeHash}},
+					},
+				}
+				mockClient.On("DescribeTable", mock.Anything, mock.Anything).
+					Return(&dynamodb.DescribeTableOutput{Table: table}, nil)
+				mockClient.On("DescribeTimeToLive", mock.Anything, mock.Anything).Return(timeToLive(""), nil)
+			},
+			mockSQS: func(mockClient *MockSQSAPI) {
+				existingQueue(mockClient, "event-dlq", map[string]string{"VisibilityTimeout": "30"})
+				existingQueue(mockClient, "event-queue", map[string]string{"VisibilityTimeout": "60"})
+			},
+			expectedChanges: []string{
+				"update stream events",
+				"create index events/client_id_index",
+				"delete index events/old_index",
+				"update ttl events",
+				"update queue event-queue",
+			},
+			expectedDetails: []string{
+				"view type: (none) -> NEW_AND_OLD_IMAGES",
+				"attribute: (none) -> ttl",
+				"VisibilityTimeout: 60 -> 30",
+				"redrive: (none) -> event-dlq after 5 receives",
+			},
+			expectError: false,
+			description: "Should plan an update for every setting that differs from the spec",
+		},
+		{
+			name: "Table Key Changed",
+			mockDynamoDB: func(mockClient *MockDynamoDBAPI) {
+				table := eventsTable()
+				table.KeySchema = []types.KeySchemaElement{{AttributeName: aws.String("client_id"), KeyType: types.KeyTypeHash}}
+				mockClient.On("DescribeTable", mock.Anything, mock.Anything).
+					Return(&dynamodb.DescribeTableOutput{Table: table}, nil)
+			},
+			mockSQS: func(mockClient *MockSQSAPI) {
+				existingQueue(mockClient, "event-dlq", map[string]string{"VisibilityTimeout": "30"})
+				existingQueue(mockClient, "event-queue", map[string]string{
+					"VisibilityTimeout": "30",
+					"RedrivePolicy":     redriveTo("event-dlq", 5),
+				})
+			},
+			expectError: true,
+			errorMsg:    "table events is keyed by client_id (S) but the spec wants event_id (S); tables cannot be re-keyed",
+			description: "Should refuse to plan changes that would require replacing a table",
+		},
+		{
+			name: "Describe Error",
+			mockDynamoDB: func(mockClient *MockDynamoDBAPI) {
+				mockClient.On("DescribeTable", mock.Anything, mock.Anything).Return(nil, errors.New("access denied"))
+			},
+			mockSQS: func(mockClient *MockSQSAPI) {
+				mockClient.On("GetQueueUrl", mock.Anything, mock.Anything).Return(nil, errors.New("access denied"))
+			},
+			expectError: true,
+			errorMsg:    "unable to describe table events: access denied\nunable to get URL of queue event-dlq: access denied",
+			description: "Should report every resource that could not be read",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dynamodbClient := &MockDynamoDBAPI{}
+			sqsClient := &MockSQSAPI{}
+			tt.mockDynamoDB(dynamodbClient)
+			tt.mockSQS(sqsClient)
+
+			plan, err := NewPlanner(dynamodbClient, sqsClient, testLogger()).Plan(context.Background(), testSpec())
+
+			if tt.expectError {
+				assert.Error(t, err, tt.description)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				return
+			}
+			require.NoError(t, err, tt.description)
+			assert.Equal(t, tt.expectedChanges, headlines(plan), tt.description)
+
+			var output bytes.Buffer
+			require.NoError(t, plan.Write(&output))
+			for _, detail := range tt.expectedDetails {
+				assert.Contains(t, output.String(), detail)
+			}
+			if plan.Empty() {
+				assert.Equal(t, "No changes. Infrastructure matches the spec.\n", output.String())
+			}
+		})
+	}
+}
+
+// TestPlanWrite tests the printed plan
+func TestPlanWrite(t *testing.T) {
+	plan := &Plan{Changes: []Change{
+		{Action: ActionCreate, Resource: "queue", Name: "event-dlq", Details: []string{"VisibilityTimeout: 30"}},
+		{Action: ActionDelete, Resource: "index", Name: "events/old_index"},
+	}}
+
+	var output bytes.Buffer
+	require.NoError(t, plan.Write(&output))
+	assert.Equal(t, "+ create queue event-dlq\n    VisibilityTimeout: 30\n- delete index events/old_index\n\n"+
+		"Plan: 1 to create, 0 to update, 1 to delete.\n", output.String())
+}
+
+// TestApply tests converging missing resources
+func TestApply(t *testing.T) {
+	dynamodbClient := &MockDynamoDBAPI{}
+	sqsClient := &MockSQSAPI{}
+
+	// The table is missing when planning and active once created
+	dynamodbClient.On("DescribeTable", mock.Anything, mock.Anything).
+		Return(nil, &types.ResourceNotFoundException{Message: aws.String("not found")}).Once()
+	dynamodbClient.On("CreateTable", mock.Anything, mock.MatchedBy(func(input *dynamodb.CreateTableInput) bool {
+		return aws.ToString(input.TableName) == "events" &&
+			input.BillingMode == types.BillingModePayPerRequest &&
+			len(input.AttributeDefinitions) == 2 &&
+			len(input.GlobalSecondaryIndexes) == 1 &&
+			input.StreamSpecification.StreamViewType == types.StreamViewTypeNewAndOldImages
+	})).Return(&dynamodb.CreateTableOutput{}, nil).Once()
+	dynamodbClient.On("DescribeTable", mock.Anything, mock.Anything).
+		Return(&dynamodb.DescribeTableOutput{Table: eventsTable()}, nil)
+	dynamodbClient.On("UpdateTimeToLive", mock.Anything, mock.MatchedBy(func(input *dynamodb.UpdateTimeToLiveInput) bool {
+		return aws.ToString(input.TimeToLiveSpecification.AttributeName) == "ttl" && aws.ToBool(input.TimeToLiveSpecification.Enabled)
+	})).Return(&dynamodb.UpdateTimeToLiveOutput{}, nil).Once()
+
+	// The dead letter queue is created first, so the redrive policy can resolve its ARN
+	missingQueue(sqsClient, "event-queue")
+	missingQueue(sqsClient, "event-dlq")
+	sqsClient.On("CreateQueue", mock.Anything, mock.MatchedBy(func(input *sqs.CreateQueueInput) bool {
+		return aws.ToString(input.QueueName) == "event-dlq" && input.Attributes["RedrivePolicy"] == ""
+	})).Return(&sqs.CreateQueueOutput{QueueUrl: aws.String(testQueueURL("event-dlq"))}, nil).Once()
+	existingQueue(sqsClient, "event-dlq", map[string]string{"VisibilityTimeout": "30"})
+	sqsClient.On("CreateQueue", mock.Anything, mock.MatchedBy(func(input *sqs.CreateQueueInput) bool {
+		return aws.ToString(input.QueueName) == "event-queue" &&
+			input.Attributes["VisibilityTimeout"] == "30" &&
+			input.Attributes["RedrivePolicy"] == `{"deadLetterTargetArn":"`+testQueueARN("event-dlq")+`","maxReceiveCount":"5"}`
+	})).Return(&sqs.CreateQueueOutput{QueueUrl: aws.String(testQueueURL("event-queue"))}, nil).Once()
+
+	planner := NewPlanner(dynamodbClient, sqsClient, testLogger())
+	plan, err := planner.Plan(context.Background(), testSpec())
+	require.NoError(t, err)
+	require.NoError(t, planner.Apply(context.Background(), plan))
+
+	dynamodbClient.AssertExpectations(t)
+	sqsClient.AssertExpectations(t)
+}
+
+// TestApplyStopsAtFirstError tests that a failed change stops the apply
+func TestApplyStopsAtFirstError(t *testing.T) {
+	sqsClient := &MockSQSAPI{}
+	missingQueue(sqsClient, "event-dlq")
+	missingQueue(sqsClient, "other-queue")
+	sqsClient.On("CreateQueue", mock.Anything, mock.Anything).Return(nil, errors.New("quota exceeded")).Once()
+
+	planner := NewPlanner(&MockDynamoDBAPI{}, sqsClient, testLogger())
+	plan, err := planner.Plan(context.Background(), &Spec{Queues: []QueueSpec{{Name: "event-dlq"}, {Name: "other-queue"}}})
+	require.NoError(t, err)
+	require.Len(t, plan.Changes, 2)
+
+	err = planner.Apply(context.Background(), plan)
+	assert.EqualError(t, err, "failed to create queue event-dlq: quota exceeded")
+	sqsClient.AssertExpectations(t)
+}
+
+// TestTableActiveRetryable tests when table waiters keep polling
+func TestTableActiveRetryable(t *testing.T) {
+	index := func(status types.IndexStatus) *dynamodb.DescribeTableOutput {
+		return &dynamodb.DescribeTableOutput{Table: &types.TableDescription{
+			TableStatus: types.TableStatusActive,
+			GlobalSecondaryIndexes: []types.GlobalSecondaryIndexDescription{
+				{IndexName: aws.String("client_id_index"), IndexStatus: status},
+			},
+		}}
+	}
+
+	tests := []struct {
+		name        string
+		output      *dynamodb.DescribeTableOutput
+		err         error
+		retry       bool
+		expectError bool
+		description string
+	}{
+		{
+			name:        "Active",
+			output:      index(types.IndexStatusActive),
+			retry:       false,
+			description: "Should stop once the table and its indexes are active",
+		},
+		{
+			name:        "Table Creating",
+			output:      &dynamodb.DescribeTableOutput{Table: &types.TableDescription{TableStatus: types.TableStatusCreating}},
+			retry:       true,
+			description: "Should wait for the table",
+		},
+		{
+			name:        "Index Creating",
+			output:      index(types.IndexStatusCreating),
+			retry:       true,
+			description: "Should wait for indexes being backfilled",
+		},
+		{
+			name:        "Table Not Found",
+			err:         &types.ResourceNotFoundException{},
+			retry:       true,
+			description: "Should wait for a table that is not visible yet",
+		},
+		{
+			name:        "Describe Failure",
+			err:         errors.New("access denied"),
+			expectError: true,
+			description: "Should stop on other errors",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retry, err := tableActiveRetryable(context.Background(), &dynamodb.DescribeTableInput{}, tt.output, tt.err)
+
+			if tt.expectError {
+				assert.Error(t, err, tt.description)
+				return
+			}
+			assert.NoError(t, err, tt.description)
+			assert.Equal(t, tt.retry, retry, tt.description)
+		})
+	}
+}
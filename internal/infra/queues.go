@@ -0,0 +1,197 @@
+package infra
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/smithy-go"
+)
+
+// SQSAPI is the subset of the SQS API used to plan and apply queues
+type SQSAPI interface {
+	GetQueueUrl(ctx context.Context, params *sqs.GetQueueUrlInput, optFns ...func(*sqs.Options)) (*sqs.GetQueueUrlOutput, error)
+	CreateQueue(ctx context.Context, params *sqs.CreateQueueInput, optFns ...func(*sqs.Options)) (*sqs.CreateQueueOutput, error)
+	GetQueueAttributes(ctx context.Context, params *sqs.GetQueueAttributesInput, optFns ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error)
+	SetQueueAttributes(ctx context.Context, params *sqs.SetQueueAttributesInput, optFns ...func(*sqs.Options)) (*sqs.SetQueueAttributesOutput, error)
+}
+
+// redrivePolicyAttribute is the queue attribute holding the redrive policy
+const redrivePolicyAttribute = string(sqstypes.QueueAttributeNameRedrivePolicy)
+
+// redrivePolicy is the JSON value of the RedrivePolicy attribute
+type redrivePolicy struct {
+	DeadLetterTargetArn string `json:"deadLetterTargetArn"`
+	MaxReceiveCount     any    `json:"maxReceiveCount"`
+}
+
+// isQueueNotFound reports whether err means a queue does not exist
+func isQueueNotFound(err error) bool {
+	var notFound *sqstypes.QueueDoesNotExist
+	if errors.As(err, &notFound) {
+		return true
+	}
+	// Older SQS endpoints such as LocalStack report the query protocol error code
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && strings.Contains(apiErr.ErrorCode(), "NonExistentQueue")
+}
+
+// planQueue returns the change converging one queue, nil when it matches the spec
+func (p *Planner) planQueue(ctx context.Context, queue QueueSpec) (*Change, error) {
+	urlOutput, err := p.sqs.GetQueueUrl(ctx, &sqs.GetQueueUrlInput{QueueName: aws.String(queue.Name)})
+	if isQueueNotFound(err) {
+		return p.createQueue(queue), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to get URL of queue %s: %w", queue.Name, err)
+	}
+	queueURL := aws.ToString(urlOutput.QueueUrl)
+
+	attributesOutput, err := p.sqs.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(queueURL),
+		AttributeNames: []sqstypes.QueueAttributeName{sqstypes.QueueAttributeNameAll},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get attributes of queue %s: %w", queue.Name, err)
+	}
+	actual := attributesOutput.Attributes
+
+	changed := make(map[string]string)
+	var details []string
+	for _, name := range sortedKeys(queue.Attributes) {
+		if desired := queue.Attributes[name]; actual[name] != desired {
+			changed[name] = desired
+			details = append(details, diffDetail(name, actual[name], desired))
+		}
+	}
+
+	actualRedrive, err := describeRedrivePolicy(actual[redrivePolicyAttribute])
+	if err != nil {
+		return nil, fmt.Errorf("queue %s: %w", queue.Name, err)
+	}
+	if desiredRedrive := describeRedrive(queue.Redrive); actualRedrive != desiredRedrive {
+		// The policy is resolved when applying, as the dead letter queue may not exist yet
+		changed[redrivePolicyAttribute] = ""
+		details = append(details, diffDetail("redrive", actualRedrive, desiredRedrive))
+	}
+
+	if len(changed) == 0 {
+		return nil, nil
+	}
+
+	return &Change{
+		Action:   ActionUpdate,
+		Resource: "queue",
+		Name:     queue.Name,
+		Details:  details,
+		apply: func(ctx context.Context) error {
+			if _, ok := changed[redrivePolicyAttribute]; ok {
+				policy, err := p.redrivePolicy(ctx, queue.Redrive)
+				if err != nil {
+					return err
+				}
+				changed[redrivePolicyAttribute] = policy
+			}
+			_, err := p.sqs.SetQueueAttributes(ctx, &sqs.SetQueueAttributesInput{
+				QueueUrl:   aws.String(queueURL),
+				Attributes: changed,
+			})
+			return err
+		},
+	}, nil
+}
+
+// createQueue returns the change creating a queue with its attributes and redrive policy
+func (p *Planner) createQueue(queue QueueSpec) *Change {
+	var details []string
+	for _, name := range sortedKeys(queue.Attributes) {
+		details = append(details, fmt.Sprintf("%s: %s", name, queue.Attributes[name]))
+	}
+	if queue.Redrive != nil {
+		details = append(details, "redrive: "+describeRedrive(queue.Redrive))
+	}
+
+	return &Change{
+		Action:   ActionCreate,
+		Resource: "queue",
+		Name:     queue.Name,
+		Details:  details,
+		apply: func(ctx context.Context) error {
+			attributes := make(map[string]string, len(queue.Attributes)+1)
+			for name, value := range queue.Attributes {
+				attributes[name] = value
+			}
+			if queue.Redrive != nil {
+				policy, err := p.redrivePolicy(ctx, queue.Redrive)
+				if err != nil {
+					return err
+				}
+				attributes[redrivePolicyAttribute] = policy
+			}
+
+			_, err := p.sqs.CreateQueue(ctx, &sqs.CreateQueueInput{
+				QueueName:  aws.String(queue.Name),
+				Attributes: attributes,
+			})
+			return err
+		},
+	}
+}
+
+// redrivePolicy returns the RedrivePolicy attribute value for redrive; empty removes the policy
+func (p *Planner) redrivePolicy(ctx context.Context, redrive *RedriveSpec) (string, error) {
+	if redrive == nil {
+		return "", nil
+	}
+
+	urlOutput, err := p.sqs.GetQueueUrl(ctx, &sqs.GetQueueUrlInput{QueueName: aws.String(redrive.DeadLetterQueue)})
+	if err != nil {
+		return "", fmt.Errorf("unable to get URL of dead letter queue %s: %w", redrive.DeadLetterQueue, err)
+	}
+	attributesOutput, err := p.sqs.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       urlOutput.QueueUrl,
+		AttributeNames: []sqstypes.QueueAttributeName{sqstypes.QueueAttributeNameQueueArn},
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to get ARN of dead letter queue %s: %w", redrive.DeadLetterQueue, err)
+	}
+
+	policy, err := json.Marshal(redrivePolicy{
+		DeadLetterTargetArn: attributesOutput.Attributes[string(sqstypes.QueueAttributeNameQueueArn)],
+		MaxReceiveCount:     fmt.Sprint(redrive.MaxReceiveCount),
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(policy), nil
+}
+
+// describeRedrive describes a desired redrive policy
+func describeRedrive(redrive *RedriveSpec) string {
+	if redrive == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s after %d receives", redrive.DeadLetterQueue, redrive.MaxReceiveCount)
+}
+
+// describeRedrivePolicy describes an actual RedrivePolicy attribute in the same way as describeRedrive
+func describeRedrivePolicy(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	var policy redrivePolicy
+	if err := json.Unmarshal([]byte(value), &policy); err != nil {
+		return "", fmt.Errorf("invalid redrive policy %q: %w", value, err)
+	}
+
+	// The dead letter queue name is the last part of its ARN
+	arn := policy.DeadLetterTargetArn
+	name := arn[strings.LastIndex(arn, ":")+1:]
+	return fmt.Sprintf("%s after %v receives", name, policy.MaxReceiveCount), nil
+}
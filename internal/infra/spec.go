@@ -0,0 +1,204 @@
+package infra
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec describes the DynamoDB tables and SQS queues the service needs
+type Spec struct {
+	Tables []TableSpec `yaml:"tables"`
+	Queues []QueueSpec `yaml:"queues"`
+}
+
+// KeySpec is a key attribute of a table or index; Type is S, N or B
+type KeySpec struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"`
+}
+
+// TableSpec describes a DynamoDB table
+type TableSpec struct {
+	Name     string   `yaml:"name"`
+	HashKey  KeySpec  `yaml:"hashKey"`
+	RangeKey *KeySpec `yaml:"rangeKey,omitempty"`
+
+	// BillingMode is PAY_PER_REQUEST (the default) or PROVISIONED with the given capacity,
+	// which also applies to the indexes
+	BillingMode   string `yaml:"billingMode,omitempty"`
+	ReadCapacity  int64  `yaml:"readCapacity,omitempty"`
+	WriteCapacity int64  `yaml:"writeCapacity,omitempty"`
+
+	// Stream is the stream view type, e.g. NEW_AND_OLD_IMAGES; empty disables the stream
+	Stream string `yaml:"stream,omitempty"`
+
+	// TTLAttribute is the attribute items expire by; empty leaves TTL disabled
+	TTLAttribute string `yaml:"ttlAttribute,omitempty"`
+
+	Indexes []IndexSpec `yaml:"indexes,omitempty"`
+}
+
+// IndexSpec describes a global secondary index
+type IndexSpec struct {
+	Name     string   `yaml:"name"`
+	HashKey  KeySpec  `yaml:"hashKey"`
+	RangeKey *KeySpec `yaml:"rangeKey,omitempty"`
+
+	// Projection is ALL (the default) or KEYS_ONLY
+	Projection string `yaml:"projection,omitempty"`
+}
+
+// QueueSpec describes an SQS queue
+type QueueSpec struct {
+	Name string `yaml:"name"`
+
+	// Attributes are SQS queue attributes such as VisibilityTimeout; RedrivePolicy is set from Redrive
+	Attributes map[string]string `yaml:"attributes,omitempty"`
+
+	Redrive *RedriveSpec `yaml:"redrive,omitempty"`
+}
+
+// RedriveSpec moves messages received more than MaxReceiveCount times to DeadLetterQueue,
+// which must be another queue of the spec
+type RedriveSpec struct {
+	DeadLetterQueue string `yaml:"deadLetterQueue"`
+	MaxReceiveCount int    `yaml:"maxReceiveCount"`
+}
+
+// Supported values of the spec
+const (
+	billingPayPerRequest = "PAY_PER_REQUEST"
+	billingProvisioned   = "PROVISIONED"
+	projectionAll        = "ALL"
+	projectionKeysOnly   = "KEYS_ONLY"
+)
+
+var streamViewTypes = map[string]bool{
+	"NEW_IMAGE":          true,
+	"OLD_IMAGE":          true,
+	"NEW_AND_OLD_IMAGES": true,
+	"KEYS_ONLY":          true,
+}
+
+// LoadSpec reads and validates a spec file
+func LoadSpec(path string) (*Spec, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read infrastructure spec: %w", err)
+	}
+
+	var spec Spec
+	if err := yaml.Unmarshal(content, &spec); err != nil {
+		return nil, fmt.Errorf("invalid infrastructure spec %s: %w", path, err)
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid infrastructure spec %s: %w", path, err)
+	}
+	return &spec, nil
+}
+
+// Validate checks the spec and fills in defaults
+func (s *Spec) Validate() error {
+	tables := make(map[string]bool)
+	for i := range s.Tables {
+		table := &s.Tables[i]
+		if table.Name == "" {
+			return fmt.Errorf("table %d has no name", i+1)
+		}
+		if tables[table.Name] {
+			return fmt.Errorf("table %s is declared twice", table.Name)
+		}
+		tables[table.Name] = true
+
+		if err := validateKeys(table.HashKey, table.RangeKey); err != nil {
+			return fmt.Errorf("table %s: %w", table.Name, err)
+		}
+
+		switch table.BillingMode {
+		case "":
+			table.BillingMode = billingPayPerRequest
+		case billingPayPerRequest:
+		case billingProvisioned:
+			if table.ReadCapacity <= 0 || table.WriteCapacity <= 0 {
+				return fmt.Errorf("table %s: provisioned billing requires readCapacity and writeCapacity", table.Name)
+			}
+		default:
+			return fmt.Errorf("table %s: unknown billing mode %q", table.Name, table.BillingMode)
+		}
+
+		if table.Stream != "" && !streamViewTypes[table.Stream] {
+			return fmt.Errorf("table %s: unknown stream view type %q", table.Name, table.Stream)
+		}
+
+		indexes := make(map[string]bool)
+		for j := range table.Indexes {
+			index := &table.Indexes[j]
+			if index.Name == "" {
+				return fmt.Errorf("table %s: index %d has no name", table.Name, j+1)
+			}
+			if indexes[index.Name] {
+				return fmt.Errorf("table %s: index %s is declared twice", table.Name, index.Name)
+			}
+			indexes[index.Name] = true
+
+			if err := validateKeys(index.HashKey, index.RangeKey); err != nil {
+				return fmt.Errorf("table %s: index %s: %w", table.Name, index.Name, err)
+			}
+			switch index.Projection {
+			case "":
+				index.Projection = projectionAll
+			case projectionAll, projectionKeysOnly:
+			default:
+				return fmt.Errorf("table %s: index %s: unknown projection %q", table.Name, index.Name, index.Projection)
+			}
+		}
+	}
+
+	queues := make(map[string]bool)
+	for i, queue := range s.Queues {
+		if queue.Name == "" {
+			return fmt.Errorf("queue %d has no name", i+1)
+		}
+		if queues[queue.Name] {
+			return fmt.Errorf("queue %s is declared twice", queue.Name)
+		}
+		queues[queue.Name] = true
+
+		if _, ok := queue.Attributes["RedrivePolicy"]; ok {
+			return fmt.Errorf("queue %s: use redrive instead of the RedrivePolicy attribute", queue.Name)
+		}
+	}
+	for _, queue := range s.Queues {
+		if queue.Redrive == nil {
+			continue
+		}
+		if !queues[queue.Redrive.DeadLetterQueue] || queue.Redrive.DeadLetterQueue == queue.Name {
+			return fmt.Errorf("queue %s: dead letter queue %q is not another queue of the spec", queue.Name, queue.Redrive.DeadLetterQueue)
+		}
+		if queue.Redrive.MaxReceiveCount < 1 {
+			return fmt.Errorf("queue %s: maxReceiveCount must be at least 1", queue.Name)
+		}
+	}
+	return nil
+}
+
+// validateKeys checks the key attributes of a table or index
+func validateKeys(hashKey KeySpec, rangeKey *KeySpec) error {
+	keys := []KeySpec{hashKey}
+	if rangeKey != nil {
+		keys = append(keys, *rangeKey)
+	}
+	for _, key := range keys {
+		if key.Name == "" {
+			return fmt.Errorf("key without a name")
+		}
+		switch key.Type {
+		case "S", "N", "B":
+		default:
+			return fmt.Errorf("key %s has unknown type %q", key.Name, key.Type)
+		}
+	}
+	return nil
+}
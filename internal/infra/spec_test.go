@@ -0,0 +1,143 @@
+package infra
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLoadSpec tests loading the spec shipped with the service
+func TestLoadSpec(t *testing.T) {
+	spec, err := LoadSpec(filepath.Join("..", "..", "deployments", "infrastructure.yaml"))
+	require.NoError(t, err)
+
+	require.Len(t, spec.Tables, 5)
+	events := spec.Tables[0]
+	assert.Equal(t, "events", events.Name)
+	assert.Equal(t, KeySpec{Name: "event_id", Type: "S"}, events.HashKey)
+	assert.Equal(t, "NEW_AND_OLD_IMAGES", events.Stream)
+	assert.Equal(t, "ttl", events.TTLAttribute)
+	require.Len(t, events.Indexes, 2)
+	assert.Equal(t, projectionAll, events.Indexes[0].Projection, "projection should default to ALL")
+
+	limits := spec.Tables[2]
+	assert.Equal(t, "events-limits", limits.Name)
+	assert.Equal(t, "ttl", limits.TTLAttribute)
+
+	audit := spec.Tables[4]
+	assert.Equal(t, "events-client-audit", audit.Name)
+	assert.Equal(t, &KeySpec{Name: "change_id", Type: "S"}, audit.RangeKey)
+
+	require.Len(t, spec.Queues, 2)
+	assert.Equal(t, &RedriveSpec{DeadLetterQueue: "event-dlq", MaxReceiveCount: 5}, spec.Queues[1].Redrive)
+
+	_, err = LoadSpec(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorContains(t, err, "failed to read infrastructure spec")
+
+	invalid := filepath.Join(t.TempDir(), "invalid.yaml")
+	require.NoError(t, os.WriteFile(invalid, []byte("tables: [{name: events}]"), 0o644))
+	_, err = LoadSpec(invalid)
+	assert.ErrorContains(t, err, "table events: key without a name")
+}
+
+// TestSpecValidate tests spec validation and defaults
+func TestSpecValidate(t *testing.T) {
+	key := KeySpec{Name: "id", Type: "S"}
+
+	tests := []struct {
+		name        string
+		spec        Spec
+		expectError bool
+		errorMsg    string
+		description string
+	}{
+		{
+			name: "Valid Spec",
+			spec: Spec{
+				Tables: []TableSpec{{Name: "events", HashKey: key, Indexes: []IndexSpec{{Name: "by_id", HashKey: key}}}},
+				Queues: []QueueSpec{
+					{Name: "queue", Redrive: &RedriveSpec{DeadLetterQueue: "dlq", MaxReceiveCount: 3}},
+					{Name: "dlq"},
+				},
+			},
+			expectError: false,
+			description: "Should accept a spec whose dead letter queue is declared after the queue",
+		},
+		{
+			name:        "Duplicate Table",
+			spec:        Spec{Tables: []TableSpec{{Name: "events", HashKey: key}, {Name: "events", HashKey: key}}},
+			expectError: true,
+			errorMsg:    "table events is declared twice",
+			description: "Should reject tables declared twice",
+		},
+		{
+			name:        "Unknown Key Type",
+			spec:        Spec{Tables: []TableSpec{{Name: "events", HashKey: KeySpec{Name: "id", Type: "BOOL"}}}},
+			expectError: true,
+			errorMsg:    `key id has unknown type "BOOL"`,
+			description: "Should reject key types DynamoDB cannot index",
+		},
+		{
+			name:        "Provisioned Without Capacity",
+			spec:        Spec{Tables: []TableSpec{{Name: "events", HashKey: key, BillingMode: "PROVISIONED"}}},
+			expectError: true,
+			errorMsg:    "provisioned billing requires readCapacity and writeCapacity",
+			description: "Should require capacity for provisioned tables",
+		},
+		{
+			name:        "Unknown Stream View Type",
+			spec:        Spec{Tables: []TableSpec{{Name: "events", HashKey: key, Stream: "EVERYTHING"}}},
+			expectError: true,
+			errorMsg:    `unknown stream view type "EVERYTHING"`,
+			description: "Should reject unknown stream view types",
+		},
+		{
+			name:        "Unknown Projection",
+			spec:        Spec{Tables: []TableSpec{{Name: "events", HashKey: key, Indexes: []IndexSpec{{Name: "by_id", HashKey: key, Projection: "SOME"}}}}},
+			expectError: true,
+			errorMsg:    `index by_id: unknown projection "SOME"`,
+			description: "Should reject unknown index projections",
+		},
+		{
+			name:        "Raw Redrive Policy",
+			spec:        Spec{Queues: []QueueSpec{{Name: "queue", Attributes: map[string]string{"RedrivePolicy": "{}"}}}},
+			expectError: true,
+			errorMsg:    "use redrive instead of the RedrivePolicy attribute",
+			description: "Should reject redrive policies given as a raw attribute",
+		},
+		{
+			name:        "Unknown Dead Letter Queue",
+			spec:        Spec{Queues: []QueueSpec{{Name: "queue", Redrive: &RedriveSpec{DeadLetterQueue: "dlq", MaxReceiveCount: 3}}}},
+			expectError: true,
+			errorMsg:    `dead letter queue "dlq" is not another queue of the spec`,
+			description: "Should reject redrives to queues outside the spec",
+		},
+		{
+			name: "Zero Max Receive Count",
+			spec: Spec{Queues: []QueueSpec{
+				{Name: "queue", Redrive: &RedriveSpec{DeadLetterQueue: "dlq"}},
+				{Name: "dlq"},
+			}},
+			expectError: true,
+			errorMsg:    "maxReceiveCount must be at least 1",
+			description: "Should reject redrives without a receive count",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.spec.Validate()
+
+			if tt.expectError {
+				assert.Error(t, err, tt.description)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+			} else {
+				assert.NoError(t, err, tt.description)
+				assert.Equal(t, billingPayPerRequest, tt.spec.Tables[0].BillingMode, "billing mode should default to on demand")
+			}
+		})
+	}
+}
@@ -0,0 +1,425 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoDBAPI is the subset of the DynamoDB API used to plan and apply tables
+type DynamoDBAPI interface {
+	DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error)
+	CreateTable(ctx context.Context, params *dynamodb.CreateTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error)
+	UpdateTable(ctx context.Context, params *dynamodb.UpdateTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTableOutput, error)
+	DescribeTimeToLive(ctx context.Context, params *dynamodb.DescribeTimeToLiveInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTimeToLiveOutput, error)
+	UpdateTimeToLive(ctx context.Context, params *dynamodb.UpdateTimeToLiveInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTimeToLiveOutput, error)
+}
+
+// Settings of the waiters used while tables and indexes are created or updated
+const (
+	tableWaitMinDelay = time.Second
+	tableWaitMaxDelay = 20 * time.Second
+	tableWaitTimeout  = 10 * time.Minute
+)
+
+// planTable returns the changes converging one table
+func (p *Planner) planTable(ctx context.Context, table TableSpec) ([]Change, error) {
+	output, err := p.dynamodb.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(table.Name)})
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		changes := []Change{p.createTable(table)}
+		if table.TTLAttribute != "" {
+			changes = append(changes, p.updateTimeToLive(table.Name, "", table.TTLAttribute))
+		}
+		return changes, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to describe table %s: %w", table.Name, err)
+	}
+	actual := output.Table
+
+	if actualKeys, desiredKeys := describeKeySchema(actual.KeySchema, actual.AttributeDefinitions), keyDescription(table.HashKey, table.RangeKey); actualKeys != desiredKeys {
+		return nil, fmt.Errorf("table %s is keyed by %s but the spec wants %s; tables cannot be re-keyed", table.Name, actualKeys, desiredKeys)
+	}
+
+	var changes []Change
+	if change := p.planBilling(table, actual); change != nil {
+		changes = append(changes, *change)
+	}
+	if change := p.planStream(table, actual); change != nil {
+		changes = append(changes, *change)
+	}
+
+	indexChanges, err := p.planIndexes(table, actual)
+	if err != nil {
+		return nil, err
+	}
+	changes = append(changes, indexChanges...)
+
+	ttl, err := p.dynamodb.DescribeTimeToLive(ctx, &dynamodb.DescribeTimeToLiveInput{TableName: aws.String(table.Name)})
+	if err != nil {
+		return nil, fmt.Errorf("unable to describe TTL of table %s: %w", table.Name, err)
+	}
+	actualTTL := ""
+	if description := ttl.TimeToLiveDescription; description != nil {
+		switch description.TimeToLiveStatus {
+		case types.TimeToLiveStatusEnabled, types.TimeToLiveStatusEnabling:
+			actualTTL = aws.ToString(description.AttributeName)
+		}
+	}
+	if actualTTL != table.TTLAttribute {
+		if actualTTL != "" && table.TTLAttribute != "" {
+			return nil, fmt.Errorf("table %s expires items by %s but the spec wants %s; disable TTL first",
+				table.Name, actualTTL, table.TTLAttribute)
+		}
+		changes = append(changes, p.updateTimeToLive(table.Name, actualTTL, table.TTLAttribute))
+	}
+
+	return changes, nil
+}
+
+// createTable returns the change creating a table with its indexes and stream
+func (p *Planner) createTable(table TableSpec) Change {
+	input := &dynamodb.CreateTableInput{
+		TableName:             aws.String(table.Name),
+		AttributeDefinitions:  attributeDefinitions(table),
+		KeySchema:             keySchema(table.HashKey, table.RangeKey),
+		BillingMode:           types.BillingMode(table.BillingMode),
+		ProvisionedThroughput: provisionedThroughput(table),
+	}
+	for _, index := range table.Indexes {
+		input.GlobalSecondaryIndexes = append(input.GlobalSecondaryIndexes, types.GlobalSecondaryIndex{
+			IndexName:             aws.String(index.Name),
+			KeySchema:             keySchema(index.HashKey, index.RangeKey),
+			Projection:            &types.Projection{ProjectionType: types.ProjectionType(index.Projection)},
+			ProvisionedThroughput: provisionedThroughput(table),
+		})
+	}
+	if table.Stream != "" {
+		input.StreamSpecification = &types.StreamSpecification{
+			StreamEnabled:  aws.Bool(true),
+			StreamViewType: types.StreamViewType(table.Stream),
+		}
+	}
+
+	details := []string{"key: " + keyDescription(table.HashKey, table.RangeKey), "billing: " + table.BillingMode}
+	for _, index := range table.Indexes {
+		details = append(details, fmt.Sprintf("index %s: %s", index.Name, keyDescription(index.HashKey, index.RangeKey)))
+	}
+	if table.Stream != "" {
+		details = append(details, "stream: "+table.Stream)
+	}
+
+	return Change{
+		Action:   ActionCreate,
+		Resource: "table",
+		Name:     table.Name,
+		Details:  details,
+		apply: func(ctx context.Context) error {
+			if _, err := p.dynamodb.CreateTable(ctx, input); err != nil {
+				return err
+			}
+			return waitForActiveTable(ctx, p.dynamodb, table.Name)
+		},
+	}
+}
+
+// planBilling returns the change of billing mode or capacity, if any
+func (p *Planner) planBilling(table TableSpec, actual *types.TableDescription) *Change {
+	actualMode := billingProvisioned
+	if actual.BillingModeSummary != nil && actual.BillingModeSummary.BillingMode != "" {
+		actualMode = string(actual.BillingModeSummary.BillingMode)
+	}
+
+	var details []string
+	if actualMode != table.BillingMode {
+		details = append(details, diffDetail("billing", actualMode, table.BillingMode))
+	}
+	if table.BillingMode == billingProvisioned && actual.ProvisionedThroughput != nil {
+		read, write := aws.ToInt64(actual.ProvisionedThroughput.ReadCapacityUnits), aws.ToInt64(actual.ProvisionedThroughput.WriteCapacityUnits)
+		if actualMode == billingProvisioned && (read != table.ReadCapacity || write != table.WriteCapacity) {
+			details = append(details, diffDetail("capacity", fmt.Sprintf("%d/%d", read, write), fmt.Sprintf("%d/%d", table.ReadCapacity, table.WriteCapacity)))
+		}
+	}
+	if len(details) == 0 {
+		return nil
+	}
+
+	input := &dynamodb.UpdateTableInput{
+		TableName:             aws.String(table.Name),
+		BillingMode:           types.BillingMode(table.BillingMode),
+		ProvisionedThroughput: provisionedThroughput(table),
+	}
+	// Provisioned tables need the capacity of every index as well
+	if table.BillingMode == billingProvisioned {
+		for _, index := range actual.GlobalSecondaryIndexes {
+			input.GlobalSecondaryIndexUpdates = append(input.GlobalSecondaryIndexUpdates, types.GlobalSecondaryIndexUpdate{
+				Update: &types.UpdateGlobalSecondaryIndexAction{
+					IndexName:             index.IndexName,
+					ProvisionedThroughput: provisionedThroughput(table),
+				},
+			})
+		}
+	}
+
+	return &Change{
+		Action:   ActionUpdate,
+		Resource: "table",
+		Name:     table.Name,
+		Details:  details,
+		apply: func(ctx context.Context) error {
+			return p.updateTable(ctx, input)
+		},
+	}
+}
+
+// planStream returns the change of the table stream, if any
+func (p *Planner) planStream(table TableSpec, actual *types.TableDescription) *Change {
+	actualStream := ""
+	if stream := actual.StreamSpecification; stream != nil && aws.ToBool(stream.StreamEnabled) {
+		actualStream = string(stream.StreamViewType)
+	}
+	if actualStream == table.Stream {
+		return nil
+	}
+
+	return &Change{
+		Action:   ActionUpdate,
+		Resource: "stream",
+		Name:     table.Name,
+		Details:  []string{diffDetail("view type", actualStream, table.Stream)},
+		apply: func(ctx context.Context) error {
+			// The view type of an enabled stream cannot be changed, so it is replaced
+			if actualStream != "" {
+				err := p.updateTable(ctx, &dynamodb.UpdateTableInput{
+					TableName:           aws.String(table.Name),
+					StreamSpecification: &types.StreamSpecification{StreamEnabled: aws.Bool(false)},
+				})
+				if err != nil {
+					return err
+				}
+			}
+			if table.Stream == "" {
+				return nil
+			}
+			return p.updateTable(ctx, &dynamodb.UpdateTableInput{
+				TableName: aws.String(table.Name),
+				StreamSpecification: &types.StreamSpecification{
+					StreamEnabled:  aws.Bool(true),
+					StreamViewType: types.StreamViewType(table.Stream),
+				},
+			})
+		},
+	}
+}
+
+// planIndexes returns the changes creating missing indexes and deleting the ones not in the spec
+func (p *Planner) planIndexes(table TableSpec, actual *types.TableDescription) ([]Change, error) {
+	existing := make(map[string]types.GlobalSecondaryIndexDescription)
+	for _, index := range actual.GlobalSecondaryIndexes {
+		existing[aws.ToString(index.IndexName)] = index
+	}
+
+	var changes []Change
+	desired := make(map[string]bool)
+	for _, index := range table.Indexes {
+		desired[index.Name] = true
+
+		if current, ok := existing[index.Name]; ok {
+			actualKeys := describeKeySchema(current.KeySchema, actual.AttributeDefinitions)
+			if desiredKeys := keyDescription(index.HashKey, index.RangeKey); actualKeys != desiredKeys {
+				return nil, fmt.Errorf("index %s of table %s is keyed by %s but the spec wants %s; indexes cannot be re-keyed, add one with a new name",
+					index.Name, table.Name, actualKeys, desiredKeys)
+			}
+			continue
+		}
+
+		input := &dynamodb.UpdateTableInput{
+			TableName:            aws.String(table.Name),
+			AttributeDefinitions: indexAttributeDefinitions(index),
+			GlobalSecondaryIndexUpdates: []types.GlobalSecondaryIndexUpdate{
+				{
+					Create: &types.CreateGlobalSecondaryIndexAction{
+						IndexName:             aws.String(index.Name),
+						KeySchema:             keySchema(index.HashKey, index.RangeKey),
+						Projection:            &types.Projection{ProjectionType: types.ProjectionType(index.Projection)},
+						ProvisionedThroughput: provisionedThroughput(table),
+					},
+				},
+			},
+		}
+		changes = append(changes, Change{
+			Action:   ActionCreate,
+			Resource: "index",
+			Name:     table.Name + "/" + index.Name,
+			Details:  []string{"key: " + keyDescription(index.HashKey, index.RangeKey)},
+			apply: func(ctx context.Context) error {
+				return p.updateTable(ctx, input)
+			},
+		})
+	}
+
+	for _, index := range actual.GlobalSecondaryIndexes {
+		name := aws.ToString(index.IndexName)
+		if desired[name] {
+			continue
+		}
+		input := &dynamodb.UpdateTableInput{
+			TableName: aws.String(table.Name),
+			GlobalSecondaryIndexUpdates: []types.GlobalSecondaryIndexUpdate{
+				{Delete: &types.DeleteGlobalSecondaryIndexAction{IndexName: aws.String(name)}},
+			},
+		}
+		changes = append(changes, Change{
+			Action:   ActionDelete,
+			Resource: "index",
+			Name:     table.Name + "/" + name,
+			apply: func(ctx context.Context) error {
+				return p.updateTable(ctx, input)
+			},
+		})
+	}
+	return changes, nil
+}
+
+// updateTimeToLive returns the change enabling or disabling TTL
+func (p *Planner) updateTimeToLive(tableName, actual, desired string) Change {
+	specification := &types.TimeToLiveSpecification{AttributeName: aws.String(desired), Enabled: aws.Bool(true)}
+	if desired == "" {
+		specification = &types.TimeToLiveSpecification{AttributeName: aws.String(actual), Enabled: aws.Bool(false)}
+	}
+
+	return Change{
+		Action:   ActionUpdate,
+		Resource: "ttl",
+		Name:     tableName,
+		Details:  []string{diffDetail("attribute", actual, desired)},
+		apply: func(ctx context.Context) error {
+			_, err := p.dynamodb.UpdateTimeToLive(ctx, &dynamodb.UpdateTimeToLiveInput{
+				TableName:               aws.String(tableName),
+				TimeToLiveSpecification: specification,
+			})
+			return err
+		},
+	}
+}
+
+// updateTable updates a table and waits until it and its indexes are active again, as DynamoDB
+// allows one update at a time
+func (p *Planner) updateTable(ctx context.Context, input *dynamodb.UpdateTableInput) error {
+	if _, err := p.dynamodb.UpdateTable(ctx, input); err != nil {
+		return err
+	}
+	return waitForActiveTable(ctx, p.dynamodb, aws.ToString(input.TableName))
+}
+
+// keySchema returns the key schema of a hash and optional range key
+func keySchema(hashKey KeySpec, rangeKey *KeySpec) []types.KeySchemaElement {
+	schema := []types.KeySchemaElement{{AttributeName: aws.String(hashKey.Name), KeyType: types.KeyTypeHash}}
+	if rangeKey != nil {
+		schema = append(schema, types.KeySchemaElement{AttributeName: aws.String(rangeKey.Name), KeyType: types.KeyTypeRange})
+	}
+	return schema
+}
+
+// attributeDefinitions returns the definitions of every key attribute of a table and its indexes
+func attributeDefinitions(table TableSpec) []types.AttributeDefinition {
+	var definitions []types.AttributeDefinition
+	seen := make(map[string]bool)
+	add := func(key *KeySpec) {
+		if key == nil || seen[key.Name] {
+			return
+		}
+		seen[key.Name] = true
+		definitions = append(definitions, types.AttributeDefinition{
+			AttributeName: aws.String(key.Name),
+			AttributeType: types.ScalarAttributeType(key.Type),
+		})
+	}
+
+	add(&table.HashKey)
+	add(table.RangeKey)
+	for i := range table.Indexes {
+		add(&table.Indexes[i].HashKey)
+		add(table.Indexes[i].RangeKey)
+	}
+	return definitions
+}
+
+// indexAttributeDefinitions returns the definitions of the key attributes of an index
+func indexAttributeDefinitions(index IndexSpec) []types.AttributeDefinition {
+	return attributeDefinitions(TableSpec{HashKey: index.HashKey, RangeKey: index.RangeKey})
+}
+
+// provisionedThroughput returns the capacity of a provisioned table, nil for on demand tables
+func provisionedThroughput(table TableSpec) *types.ProvisionedThroughput {
+	if table.BillingMode != billingProvisioned {
+		return nil
+	}
+	return &types.ProvisionedThroughput{
+		ReadCapacityUnits:  aws.Int64(table.ReadCapacity),
+		WriteCapacityUnits: aws.Int64(table.WriteCapacity),
+	}
+}
+
+// describeKeySchema describes an actual key schema in the same way as keyDescription
+func describeKeySchema(schema []types.KeySchemaElement, definitions []types.AttributeDefinition) string {
+	attributeTypes := make(map[string]string)
+	for _, definition := range definitions {
+		attributeTypes[aws.ToString(definition.AttributeName)] = string(definition.AttributeType)
+	}
+
+	var hashKey KeySpec
+	var rangeKey *KeySpec
+	for _, element := range schema {
+		key := KeySpec{Name: aws.ToString(element.AttributeName), Type: attributeTypes[aws.ToString(element.AttributeName)]}
+		if element.KeyType == types.KeyTypeRange {
+			rangeKey = &key
+		} else {
+			hashKey = key
+		}
+	}
+	return keyDescription(hashKey, rangeKey)
+}
+
+// waitForActiveTable waits until a table and all of its global secondary indexes are active
+func waitForActiveTable(ctx context.Context, client dynamodb.DescribeTableAPIClient, tableName string) error {
+	waiter := dynamodb.NewTableExistsWaiter(client, func(o *dynamodb.TableExistsWaiterOptions) {
+		o.MinDelay = tableWaitMinDelay
+		o.MaxDelay = tableWaitMaxDelay
+		o.Retryable = tableActiveRetryable
+	})
+
+	err := waiter.Wait(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(tableName)}, tableWaitTimeout)
+	if err != nil {
+		return fmt.Errorf("'%s' DynamoDB table did not become active: %w", tableName, err)
+	}
+	return nil
+}
+
+// tableActiveRetryable keeps a table waiter polling until the table and its indexes are active
+func tableActiveRetryable(ctx context.Context, input *dynamodb.DescribeTableInput, output *dynamodb.DescribeTableOutput, err error) (bool, error) {
+	if err != nil {
+		var notFound *types.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	if output.Table == nil || output.Table.TableStatus != types.TableStatusActive {
+		return true, nil
+	}
+	for _, index := range output.Table.GlobalSecondaryIndexes {
+		switch index.IndexStatus {
+		case types.IndexStatusCreating, types.IndexStatusUpdating, types.IndexStatusDeleting:
+			return true, nil
+		}
+	}
+	return false, nil
+}
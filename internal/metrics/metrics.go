@@ -0,0 +1,197 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "event_processor"
+
+// Unknown is used as a label value when the event type or client is not known
+const Unknown = "unknown"
+
+// Failure reasons used for the reason label
+const (
+	ReasonValidation  = "validation"
+	ReasonTriage      = "triage"
+	ReasonPersistence = "persistence"
+	ReasonMaxRetries  = "max_retries"
+)
+
+// OutcomeDeadLetter is the outcome of stream changes a sink kept rejecting until they were given up on
+const OutcomeDeadLetter = "dead_letter"
+
+var (
+	// MessagesReceived counts messages received from the event queue
+	MessagesReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_received_total",
+		Help:      "Total number of messages received from the event queue.",
+	}, []string{"event_type"})
+
+	// EventsProcessed counts events that were processed and persisted
+	EventsProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_processed_total",
+		Help:      "Total number of events processed and persisted, by final status.",
+	}, []string{"event_type", "client_id", "status"})
+
+	// EventsFailed counts events that failed processing
+	EventsFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_failed_total",
+		Help:      "Total number of events that failed processing, by reason.",
+	}, []string{"reason", "event_type", "client_id"})
+
+	// MessagesRetried counts messages requeued for another attempt
+	MessagesRetried = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_retried_total",
+		Help:      "Total number of messages requeued for retry.",
+	}, []string{"event_type"})
+
+	// MessagesDeadLettered counts messages sent to the dead letter queue
+	MessagesDeadLettered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_dead_lettered_total",
+		Help:      "Total number of messages sent to the dead letter queue, by reason.",
+	}, []string{"reason", "event_type"})
+
+	// MessagesRedriven counts dead lettered messages sent back to the event queue
+	MessagesRedriven = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_redriven_total",
+		Help:      "Total number of dead lettered messages sent back to the event queue.",
+	}, []string{"event_type", "client_id"})
+
+	// MessagesPurged counts dead lettered messages deleted without being redriven
+	MessagesPurged = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_purged_total",
+		Help:      "Total number of dead lettered messages purged.",
+	}, []string{"event_type", "client_id"})
+
+	// EventsReplayed counts stored events reprocessed by a replay
+	EventsReplayed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_replayed_total",
+		Help:      "Total number of stored events reprocessed, by result (changed, unchanged or failed).",
+	}, []string{"event_type", "client_id", "result"})
+
+	// EventsUpcast counts events transformed from an older version of their event type
+	EventsUpcast = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_upcast_total",
+		Help:      "Total number of events upcast to the current version of their event type, by version sent and version stored.",
+	}, []string{"event_type", "from_version", "to_version"})
+
+	// ValidationViolations counts the schema rules broken by events that failed validation
+	ValidationViolations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "validation_violations_total",
+		Help:      "Total number of schema rules broken by events failing validation, by schema (event or payload), field and rule.",
+	}, []string{"schema", "event_type", "field", "rule"})
+
+	// SchemaReloads counts attempts to load a changed schema directory
+	SchemaReloads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "schema_reloads_total",
+		Help:      "Total number of schema directory reloads, by outcome; failed reloads keep the schemas in use.",
+	}, []string{"outcome"})
+
+	// ProcessingDuration observes end-to-end event processing time
+	ProcessingDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "event_processing_duration_seconds",
+		Help:      "Time spent processing a single event.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"event_type"})
+
+	// DynamoDBLatency observes the latency of DynamoDB operations
+	DynamoDBLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "dynamodb_request_duration_seconds",
+		Help:      "Latency of DynamoDB requests, by operation and outcome.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "outcome"})
+
+	// QueueReceiveLatency observes the latency of queue receive calls
+	QueueReceiveLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "queue_receive_duration_seconds",
+		Help:      "Latency of queue receive calls, including long polling.",
+		Buckets:   []float64{.01, .05, .1, .5, 1, 2.5, 5, 10, 20, 30},
+	}, []string{"outcome"})
+
+	// StreamChanges counts table changes delivered from the stream to sinks
+	StreamChanges = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "stream_changes_total",
+		Help:      "Total number of stream changes sent to sinks or dead-lettered, by change type, sink and outcome.",
+	}, []string{"change_type", "sink", "outcome"})
+
+	// ClientConfigCacheLookups counts client configuration lookups by whether the cache answered them
+	ClientConfigCacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "client_config_cache_lookups_total",
+		Help:      "Total number of client configuration lookups, by result (hit, negative_hit or miss).",
+	}, []string{"result"})
+
+	// EventsLimited counts events over a client's rate limit or quota, by the limit and what was done
+	EventsLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_limited_total",
+		Help:      "Total number of events over a client rate limit or quota, by limit (rate, daily or monthly) and action (delayed, rejected, sampled or dropped).",
+	}, []string{"client_id", "limit", "action"})
+
+	// EventsQuarantined counts rejected events kept in quarantine, by why they were rejected
+	EventsQuarantined = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_quarantined_total",
+		Help:      "Total number of rejected events quarantined, by category (validation, payload or authorization).",
+	}, []string{"category", "event_type", "client_id"})
+
+	// WorkerPoolSize reports the configured number of workers
+	WorkerPoolSize = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "worker_pool_size",
+		Help:      "Configured number of message processing workers.",
+	})
+
+	// WorkerPoolBusy reports the number of workers currently processing a message
+	WorkerPoolBusy = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "worker_pool_busy",
+		Help:      "Number of workers currently processing a message.",
+	})
+)
+
+// Handler returns the HTTP handler serving the /metrics endpoint
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Outcome maps an error to the outcome label value
+func Outcome(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
+
+// ObserveDynamoDB records the latency of a DynamoDB operation started at start
+func ObserveDynamoDB(operation string, start time.Time, err error) {
+	DynamoDBLatency.WithLabelValues(operation, Outcome(err)).Observe(time.Since(start).Seconds())
+}
+
+// LabelOrUnknown returns value, or Unknown when value is empty
+func LabelOrUnknown(value string) string {
+	if value == "" {
+		return Unknown
+	}
+	return value
+}
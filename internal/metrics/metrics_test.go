@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestOutcome tests the Outcome function
+func TestOutcome(t *testing.T) {
+	assert.Equal(t, "success", Outcome(nil))
+	assert.Equal(t, "error", Outcome(errors.New("boom")))
+}
+
+// TestLabelOrUnknown tests the LabelOrUnknown function
+func TestLabelOrUnknown(t *testing.T) {
+	assert.Equal(t, Unknown, LabelOrUnknown(""))
+	assert.Equal(t, "client-001", LabelOrUnknown("client-001"))
+}
+
+// TestObserveDynamoDB tests that DynamoDB latencies are recorded per operation and outcome
+func TestObserveDynamoDB(t *testing.T) {
+	before := testutil.CollectAndCount(DynamoDBLatency)
+
+	ObserveDynamoDB("TestOperation", time.Now(), nil)
+	ObserveDynamoDB("TestOperation", time.Now(), errors.New("boom"))
+
+	assert.Equal(t, before+2, testutil.CollectAndCount(DynamoDBLatency))
+}
+
+// TestHandler tests that the handler exposes registered metrics
+func TestHandler(t *testing.T) {
+	MessagesReceived.WithLabelValues("monitoring").Inc()
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "event_processor_messages_received_total")
+}
@@ -0,0 +1,230 @@
+package persistence
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// marshalValue converts an arbitrary JSON-compatible Go value to a DynamoDB attribute value.
+//
+// The mapping is lossless for everything encoding/json produces: objects become M, arrays become L
+// (preserving order, duplicates and empty arrays), null becomes NULL and numbers become N using their
+// exact decimal representation. Other Go types are mapped by kind, falling back to their JSON encoding.
+func marshalValue(value interface{}) (types.AttributeValue, error) {
+	switch v := value.(type) {
+	case nil:
+		return &types.AttributeValueMemberNULL{Value: true}, nil
+	case string:
+		return &types.AttributeValueMemberS{Value: v}, nil
+	case bool:
+		return &types.AttributeValueMemberBOOL{Value: v}, nil
+	case float64:
+		return marshalFloat(v, 64)
+	case float32:
+		return marshalFloat(float64(v), 32)
+	case int:
+		return &types.AttributeValueMemberN{Value: strconv.Itoa(v)}, nil
+	case int64:
+		return &types.AttributeValueMemberN{Value: strconv.FormatInt(v, 10)}, nil
+	case uint64:
+		return &types.AttributeValueMemberN{Value: strconv.FormatUint(v, 10)}, nil
+	case json.Number:
+		if !isDecimalNumber(string(v)) {
+			return nil, fmt.Errorf("invalid number %q", v)
+		}
+		return &types.AttributeValueMemberN{Value: string(v)}, nil
+	case *big.Int:
+		if v == nil {
+			return &types.AttributeValueMemberNULL{Value: true}, nil
+		}
+		return &types.AttributeValueMemberN{Value: v.String()}, nil
+	case *big.Float:
+		if v == nil {
+			return &types.AttributeValueMemberNULL{Value: true}, nil
+		}
+		if v.IsInf() {
+			return nil, fmt.Errorf("number %v cannot be stored", v)
+		}
+		return &types.AttributeValueMemberN{Value: v.Text('g', -1)}, nil
+	case []byte:
+		return &types.AttributeValueMemberB{Value: v}, nil
+	case map[string]interface{}:
+		m, err := marshalMap(v)
+		if err != nil {
+			return nil, err
+		}
+		return &types.AttributeValueMemberM{Value: m}, nil
+	case []interface{}:
+		return marshalList(len(v), func(i int) interface{} { return v[i] })
+	}
+
+	return marshalReflect(value)
+}
+
+// marshalMap converts a JSON object into a map of attribute values
+func marshalMap(values map[string]interface{}) (map[string]types.AttributeValue, error) {
+	result := make(map[string]types.AttributeValue, len(values))
+	for key, value := range values {
+		av, err := marshalValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		result[key] = av
+	}
+	return result, nil
+}
+
+// marshalList converts an indexed sequence into an L attribute value
+func marshalList(length int, element func(int) interface{}) (types.AttributeValue, error) {
+	list := make([]types.AttributeValue, length)
+	for i := 0; i < length; i++ {
+		av, err := marshalValue(element(i))
+		if err != nil {
+			return nil, fmt.Errorf("[%d]: %w", i, err)
+		}
+		list[i] = av
+	}
+	return &types.AttributeValueMemberL{Value: list}, nil
+}
+
+// marshalFloat formats a float with the shortest representation that parses back to the same value
+func marshalFloat(value float64, bitSize int) (types.AttributeValue, error) {
+	if !isFinite(value) {
+		return nil, fmt.Errorf("number %v cannot be stored", value)
+	}
+	return &types.AttributeValueMemberN{Value: strconv.FormatFloat(value, 'f', -1, bitSize)}, nil
+}
+
+// marshalReflect handles typed slices, maps, integers and pointers by kind, and anything else via JSON
+func marshalReflect(value interface{}) (types.AttributeValue, error) {
+	rv := reflect.ValueOf(value)
+
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return &types.AttributeValueMemberNULL{Value: true}, nil
+		}
+		return marshalValue(rv.Elem().Interface())
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &types.AttributeValueMemberN{Value: strconv.FormatInt(rv.Int(), 10)}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uintptr:
+		return &types.AttributeValueMemberN{Value: strconv.FormatUint(rv.Uint(), 10)}, nil
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return &types.AttributeValueMemberNULL{Value: true}, nil
+		}
+		return marshalList(rv.Len(), func(i int) interface{} { return rv.Index(i).Interface() })
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			if rv.IsNil() {
+				return &types.AttributeValueMemberNULL{Value: true}, nil
+			}
+			m := make(map[string]types.AttributeValue, rv.Len())
+			iter := rv.MapRange()
+			for iter.Next() {
+				av, err := marshalValue(iter.Value().Interface())
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", iter.Key().String(), err)
+				}
+				m[iter.Key().String()] = av
+			}
+			return &types.AttributeValueMemberM{Value: m}, nil
+		}
+	}
+
+	// Fall back to the value's JSON representation, e.g. for structs and time.Time
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported payload value of type %T: %w", value, err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("unsupported payload value of type %T: %w", value, err)
+	}
+	return marshalValue(decoded)
+}
+
+// unmarshalAttributeValue converts a DynamoDB attribute value back into its JSON-compatible Go form.
+//
+// Numbers become float64 when that representation is exact and json.Number otherwise, so large
+// integers and high-precision decimals survive a round trip unchanged.
+func unmarshalAttributeValue(value types.AttributeValue) interface{} {
+	switch v := value.(type) {
+	case *types.AttributeValueMemberNULL:
+		return nil
+	case *types.AttributeValueMemberS:
+		return v.Value
+	case *types.AttributeValueMemberBOOL:
+		return v.Value
+	case *types.AttributeValueMemberN:
+		return unmarshalNumber(v.Value)
+	case *types.AttributeValueMemberB:
+		return v.Value
+	case *types.AttributeValueMemberM:
+		return unmarshalMap(v.Value)
+	case *types.AttributeValueMemberL:
+		list := make([]interface{}, len(v.Value))
+		for i, element := range v.Value {
+			list[i] = unmarshalAttributeValue(element)
+		}
+		return list
+	case *types.AttributeValueMemberSS:
+		// Written by earlier versions for []string payload values
+		list := make([]interface{}, len(v.Value))
+		for i, element := range v.Value {
+			list[i] = element
+		}
+		return list
+	case *types.AttributeValueMemberNS:
+		list := make([]interface{}, len(v.Value))
+		for i, element := range v.Value {
+			list[i] = unmarshalNumber(element)
+		}
+		return list
+	case *types.AttributeValueMemberBS:
+		list := make([]interface{}, len(v.Value))
+		for i, element := range v.Value {
+			list[i] = element
+		}
+		return list
+	default:
+		return nil
+	}
+}
+
+// unmarshalMap converts a map of attribute values back into a JSON object
+func unmarshalMap(attributes map[string]types.AttributeValue) map[string]interface{} {
+	result := make(map[string]interface{}, len(attributes))
+	for key, value := range attributes {
+		result[key] = unmarshalAttributeValue(value)
+	}
+	return result
+}
+
+// unmarshalNumber returns a float64 when it represents the stored number exactly, json.Number otherwise
+func unmarshalNumber(value string) interface{} {
+	f, err := strconv.ParseFloat(value, 64)
+	if err == nil && strconv.FormatFloat(f, 'f', -1, 64) == value {
+		return f
+	}
+	return json.Number(value)
+}
+
+// isDecimalNumber reports whether s is a valid JSON number
+func isDecimalNumber(s string) bool {
+	return json.Valid([]byte(s)) && s != "" && (s[0] == '-' || (s[0] >= '0' && s[0] <= '9'))
+}
+
+// isFinite reports whether f is neither NaN nor infinite
+func isFinite(f float64) bool {
+	return f-f == 0
+}
@@ -0,0 +1,211 @@
+package persistence
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+	"testing/quick"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// jsonPayload is a randomly generated JSON object used for property-based tests
+type jsonPayload map[string]interface{}
+
+// Generate implements quick.Generator, producing arbitrarily nested JSON-shaped payloads
+func (jsonPayload) Generate(rand *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(jsonPayload(generateObject(rand, 3)))
+}
+
+func generateObject(r *rand.Rand, depth int) map[string]interface{} {
+	object := make(map[string]interface{})
+	for i := r.Intn(6); i > 0; i-- {
+		object[generateString(r)] = generateValue(r, depth)
+	}
+	return object
+}
+
+func generateValue(r *rand.Rand, depth int) interface{} {
+	kinds := 7
+	if depth <= 0 {
+		kinds = 5
+	}
+
+	switch r.Intn(kinds) {
+	case 0:
+		return nil
+	case 1:
+		return r.Intn(2) == 1
+	case 2:
+		return generateString(r)
+	case 3:
+		return generateFloat(r)
+	case 4:
+		// Integers beyond float64 precision, as produced by a decoder using UseNumber
+		return json.Number(strconv.FormatInt(r.Int63(), 10) + strconv.Itoa(r.Intn(1000000)))
+	case 5:
+		list := make([]interface{}, r.Intn(5))
+		for i := range list {
+			list[i] = generateValue(r, depth-1)
+		}
+		return list
+	default:
+		return generateObject(r, depth-1)
+	}
+}
+
+func generateFloat(r *rand.Rand) float64 {
+	switch r.Intn(3) {
+	case 0:
+		return float64(r.Int63n(1<<53) - 1<<52)
+	case 1:
+		return r.NormFloat64() * math.Pow(10, float64(r.Intn(40)-20))
+	default:
+		return 0
+	}
+}
+
+func generateString(r *rand.Rand) string {
+	const alphabet = "abcXYZ019 _-\"\\/é日"
+	runes := []rune(alphabet)
+	out := make([]rune, r.Intn(12))
+	for i := range out {
+		out[i] = runes[r.Intn(len(runes))]
+	}
+	return string(out)
+}
+
+// TestPayloadRoundTripProperty tests that any JSON-shaped payload survives marshal and unmarshal unchanged
+func TestPayloadRoundTripProperty(t *testing.T) {
+	roundTrip := func(payload jsonPayload) bool {
+		attributes, err := marshalMap(payload)
+		if err != nil {
+			t.Logf("marshal failed: %v", err)
+			return false
+		}
+		return reflect.DeepEqual(map[string]interface{}(payload), unmarshalMap(attributes))
+	}
+
+	if err := quick.Check(roundTrip, &quick.Config{MaxCount: 500}); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestJSONRoundTripProperty tests that re-encoding a stored payload yields the original JSON document
+func TestJSONRoundTripProperty(t *testing.T) {
+	roundTrip := func(payload jsonPayload) bool {
+		original, err := json.Marshal(payload)
+		if err != nil {
+			return false
+		}
+
+		// Decode the way a JSON consumer would, without UseNumber
+		var decoded map[string]interface{}
+		if err := json.Unmarshal(original, &decoded); err != nil {
+			return false
+		}
+
+		attributes, err := marshalMap(decoded)
+		if err != nil {
+			t.Logf("marshal failed: %v", err)
+			return false
+		}
+
+		restored, err := json.Marshal(unmarshalMap(attributes))
+		if err != nil {
+			return false
+		}
+
+		var want, got interface{}
+		json.Unmarshal(original, &want)
+		json.Unmarshal(restored, &got)
+		return reflect.DeepEqual(want, got)
+	}
+
+	if err := quick.Check(roundTrip, &quick.Config{MaxCount: 500}); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestUseNumberRoundTrip tests that big numbers decoded with UseNumber keep every digit
+func TestUseNumberRoundTrip(t *testing.T) {
+	document := []byte(`{"id":123456789012345678901234567890,"price":0.10,"ratio":1.5,"nested":[{"n":-0.000001}]}`)
+
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.UseNumber()
+	var payload map[string]interface{}
+	assert.NoError(t, decoder.Decode(&payload))
+
+	attributes, err := marshalMap(payload)
+	assert.NoError(t, err)
+
+	restored := unmarshalMap(attributes)
+	assert.Equal(t, json.Number("123456789012345678901234567890"), restored["id"])
+	assert.Equal(t, json.Number("0.10"), restored["price"])
+	assert.Equal(t, 1.5, restored["ratio"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"n": -0.000001}}, restored["nested"])
+}
+
+// TestMarshalValueErrors tests values that cannot be represented
+func TestMarshalValueErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+	}{
+		{name: "NaN", value: math.NaN()},
+		{name: "Infinity", value: math.Inf(1)},
+		{name: "Invalid Number", value: json.Number("12abc")},
+		{name: "Nested NaN", value: []interface{}{map[string]interface{}{"x": math.NaN()}}},
+		{name: "Unsupported Type", value: make(chan int)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := marshalValue(tt.value)
+			assert.Error(t, err)
+		})
+	}
+}
+
+// TestMarshalTypedValues tests Go types that do not come from encoding/json
+func TestMarshalTypedValues(t *testing.T) {
+	var nilMap map[string]string
+
+	tests := []struct {
+		value    interface{}
+		expected types.AttributeValue
+	}{
+		{value: int32(-7), expected: &types.AttributeValueMemberN{Value: "-7"}},
+		{value: uint8(7), expected: &types.AttributeValueMemberN{Value: "7"}},
+		{value: float32(0.25), expected: &types.AttributeValueMemberN{Value: "0.25"}},
+		{value: []byte("raw"), expected: &types.AttributeValueMemberB{Value: []byte("raw")}},
+		{value: nilMap, expected: &types.AttributeValueMemberNULL{Value: true}},
+		{value: []int{1, 2}, expected: &types.AttributeValueMemberL{Value: []types.AttributeValue{
+			&types.AttributeValueMemberN{Value: "1"},
+			&types.AttributeValueMemberN{Value: "2"},
+		}}},
+		{value: map[string]bool{"ok": true}, expected: &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"ok": &types.AttributeValueMemberBOOL{Value: true},
+		}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%T", tt.value), func(t *testing.T) {
+			result, err := marshalValue(tt.value)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+// TestUnmarshalLegacySets tests that set attributes written by earlier versions are returned as lists
+func TestUnmarshalLegacySets(t *testing.T) {
+	assert.Equal(t, []interface{}{"a", "b"}, unmarshalAttributeValue(&types.AttributeValueMemberSS{Value: []string{"a", "b"}}))
+	assert.Equal(t, []interface{}{1.0, json.Number("1.10")}, unmarshalAttributeValue(&types.AttributeValueMemberNS{Value: []string{"1", "1.10"}}))
+}
@@ -0,0 +1,376 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/sirupsen/logrus"
+
+	"github.com/d-sense/event-processor/internal/metrics"
+)
+
+// MaxBatchItems is the largest number of items written in a single TransactWriteItems call. DynamoDB
+// accepts up to 100, but also caps a transaction at 4 MB.
+const MaxBatchItems = 25
+
+// ErrBatchWriterClosed is returned when writing to a batch writer that has been closed
+var ErrBatchWriterClosed = errors.New("batch writer closed")
+
+// ErrItemExists is returned when writing an item whose key is already stored
+var ErrItemExists = errors.New("item already exists")
+
+// BatchWriterOptions configures a BatchWriter
+type BatchWriterOptions struct {
+	// BatchSize is the number of items that triggers a flush (at most MaxBatchItems)
+	BatchSize int
+
+	// FlushInterval is the longest an item waits in the buffer before being written
+	FlushInterval time.Duration
+
+	// MaxAttempts bounds the number of failed TransactWriteItems calls made for a batch
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry, doubling on each further attempt
+	InitialBackoff time.Duration
+}
+
+// DefaultBatchWriterOptions returns the default batch writer options
+func DefaultBatchWriterOptions() BatchWriterOptions {
+	return BatchWriterOptions{
+		BatchSize:      MaxBatchItems,
+		FlushInterval:  50 * time.Millisecond,
+		MaxAttempts:    5,
+		InitialBackoff: 50 * time.Millisecond,
+	}
+}
+
+// BatchWriter is a write-behind buffer grouping puts that create items into TransactWriteItems calls.
+// Each put is conditional on the key not being stored yet, which BatchWriteItem cannot express, and
+// fails with ErrItemExists otherwise.
+//
+// Write blocks until the item has been durably written or has failed, so callers only acknowledge
+// their input (for example delete the SQS message) once the item is stored. A batch therefore holds
+// at most as many items as there are concurrent writers. Items are flushed when a batch is full, when
+// FlushInterval elapses and on Close. Batches are written one at a time, in order, while new writes
+// keep being accepted into the next batch.
+type BatchWriter struct {
+	client       DynamoDBClient
+	tableName    string
+	keyAttribute string
+	options      BatchWriterOptions
+	logger       *logrus.Logger
+
+	requests  chan *writeRequest
+	done      chan struct{}
+	closeOnce sync.Once
+	stopped   chan struct{}
+
+	// ctx is canceled when Close gives up waiting, interrupting the batch being written
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// writeRequest is a buffered put and the channel its outcome is reported on
+type writeRequest struct {
+	key    string
+	item   map[string]types.AttributeValue
+	result chan error
+}
+
+// pendingWrite is a buffered item and everyone waiting for it; repeated writes to a key are coalesced
+type pendingWrite struct {
+	item    map[string]types.AttributeValue
+	waiters []chan error
+}
+
+// writeBatch is a batch of buffered items in the order they were first written
+type writeBatch struct {
+	keys    []string
+	pending map[string]*pendingWrite
+}
+
+func newWriteBatch() *writeBatch {
+	return &writeBatch{pending: make(map[string]*pendingWrite)}
+}
+
+// NewBatchWriter creates a batch writer for a table whose string hash key is keyAttribute and starts its flush loop
+func NewBatchWriter(client DynamoDBClient, tableName, keyAttribute string, options BatchWriterOptions, logger *logrus.Logger) *BatchWriter {
+	if options.BatchSize <= 0 || options.BatchSize > MaxBatchItems {
+		options.BatchSize = MaxBatchItems
+	}
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &BatchWriter{
+		client:       client,
+		tableName:    tableName,
+		keyAttribute: keyAttribute,
+		options:      options,
+		logger:       logger,
+		requests:     make(chan *writeRequest),
+		done:         make(chan struct{}),
+		stopped:      make(chan struct{}),
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+	go w.run()
+	return w
+}
+
+// Write buffers an item and waits until it has been written
+func (w *BatchWriter) Write(ctx context.Context, item map[string]types.AttributeValue) error {
+	key, ok := w.itemKey(item)
+	if !ok {
+		return fmt.Errorf("item has no %s key", w.keyAttribute)
+	}
+	request := &writeRequest{key: key, item: item, result: make(chan error, 1)}
+
+	select {
+	case w.requests <- request:
+	case <-w.done:
+		return ErrBatchWriterClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-request.result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close flushes buffered items and stops the writer, waiting at most until ctx is done; items not
+// written by then fail
+func (w *BatchWriter) Close(ctx context.Context) error {
+	w.closeOnce.Do(func() { close(w.done) })
+
+	select {
+	case <-w.stopped:
+		return nil
+	case <-ctx.Done():
+		w.cancel()
+		return ctx.Err()
+	}
+}
+
+// run accumulates requests into batches and hands them to a flushing goroutine, one at a time so
+// that later writes to a key land after earlier ones
+func (w *BatchWriter) run() {
+	defer close(w.stopped)
+	defer w.cancel()
+
+	var (
+		current  = newWriteBatch()
+		sealed   []*writeBatch
+		flushing bool
+		flushed  = make(chan struct{})
+		due      bool
+		timer    *time.Timer
+		flushC   <-chan time.Time
+	)
+
+	startFlush := func() {
+		if flushing || len(sealed) == 0 {
+			return
+		}
+		batch := sealed[0]
+		sealed = sealed[1:]
+		flushing = true
+		go func() {
+			w.flush(w.ctx, batch)
+			flushed <- struct{}{}
+		}()
+	}
+
+	seal := func() {
+		if timer != nil {
+			timer.Stop()
+			timer, flushC = nil, nil
+		}
+		due = false
+		if len(current.keys) > 0 {
+			sealed = append(sealed, current)
+			current = newWriteBatch()
+		}
+		startFlush()
+	}
+
+	add := func(request *writeRequest) {
+		if existing, ok := current.pending[request.key]; ok {
+			// A transaction cannot write an item twice, and only the first create of a key can succeed
+			existing.waiters = append(existing.waiters, request.result)
+			return
+		}
+
+		current.keys = append(current.keys, request.key)
+		current.pending[request.key] = &pendingWrite{item: request.item, waiters: []chan error{request.result}}
+		if timer == nil && !due {
+			timer = time.NewTimer(w.options.FlushInterval)
+			flushC = timer.C
+		}
+		if len(current.keys) >= w.options.BatchSize {
+			seal()
+		}
+	}
+
+	for {
+		select {
+		case request := <-w.requests:
+			add(request)
+		case <-flushC:
+			// A batch that is due while another is being written keeps filling until that one is done
+			timer, flushC = nil, nil
+			due = true
+			if !flushing {
+				seal()
+			}
+		case <-flushed:
+			flushing = false
+			if len(sealed) > 0 {
+				startFlush()
+			} else if due {
+				seal()
+			}
+		case <-w.done:
+			// Pick up writers that were already handing over a request, then flush what is left
+		drain:
+			for {
+				select {
+				case request := <-w.requests:
+					add(request)
+				default:
+					break drain
+				}
+			}
+			seal()
+			for flushing {
+				<-flushed
+				flushing = false
+				startFlush()
+			}
+			return
+		}
+	}
+}
+
+// flush writes a batch, retrying with exponential backoff until ctx is done, and reports the outcome.
+// A transaction is canceled as a whole when any of its items already exists; those items fail with
+// ErrItemExists and the rest are written again straight away.
+func (w *BatchWriter) flush(ctx context.Context, batch *writeBatch) {
+	remaining := batch.keys
+	backoff := w.options.InitialBackoff
+	var lastErr error
+
+	for attempt := 1; len(remaining) > 0; {
+		items := make([]types.TransactWriteItem, len(remaining))
+		for i, key := range remaining {
+			items[i] = types.TransactWriteItem{Put: &types.Put{
+				TableName:                aws.String(w.tableName),
+				Item:                     batch.pending[key].item,
+				ConditionExpression:      aws.String("attribute_not_exists(#key)"),
+				ExpressionAttributeNames: map[string]string{"#key": w.keyAttribute},
+			}}
+		}
+
+		start := time.Now()
+		_, err := w.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+		metrics.ObserveDynamoDB("TransactWriteItems", start, err)
+		if err == nil {
+			for _, key := range remaining {
+				w.complete(batch.pending[key], nil)
+			}
+			remaining = nil
+			break
+		}
+
+		var canceled *types.TransactionCanceledException
+		if errors.As(err, &canceled) && len(canceled.CancellationReasons) == len(remaining) {
+			var next []string
+			for i, key := range remaining {
+				if aws.ToString(canceled.CancellationReasons[i].Code) == "ConditionalCheckFailed" {
+					w.complete(batch.pending[key], ErrItemExists)
+					continue
+				}
+				next = append(next, key)
+			}
+			if len(next) < len(remaining) && !failedOtherwise(canceled.CancellationReasons) {
+				// The other items were only canceled along with the existing ones
+				remaining = next
+				continue
+			}
+			remaining = next
+		}
+
+		lastErr = err
+		w.logger.WithError(err).WithField("attempt", attempt).Warn("Batch write failed")
+		if attempt >= w.options.MaxAttempts || len(remaining) == 0 {
+			break
+		}
+		if !sleep(ctx, backoff) {
+			lastErr = ctx.Err()
+			break
+		}
+		backoff *= 2
+		attempt++
+	}
+
+	for _, key := range remaining {
+		w.complete(batch.pending[key], fmt.Errorf("batch write failed: %w", lastErr))
+	}
+}
+
+// failedOtherwise reports whether a canceled transaction had items failing for a reason other than an
+// existing item, such as throttling or a conflicting transaction
+func failedOtherwise(reasons []types.CancellationReason) bool {
+	for _, reason := range reasons {
+		switch aws.ToString(reason.Code) {
+		case "", "None", "ConditionalCheckFailed":
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// sleep waits for d, reporting false if ctx is done first
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// complete reports the outcome of a write to everyone waiting for it; writes after the first one that
+// created the item are duplicates
+func (w *BatchWriter) complete(write *pendingWrite, err error) {
+	for i, waiter := range write.waiters {
+		if i > 0 && err == nil {
+			waiter <- ErrItemExists
+			continue
+		}
+		waiter <- err
+	}
+}
+
+// itemKey returns the value of an item's hash key
+func (w *BatchWriter) itemKey(item map[string]types.AttributeValue) (string, bool) {
+	key, ok := item[w.keyAttribute].(*types.AttributeValueMemberS)
+	if !ok {
+		return "", false
+	}
+	return key.Value, true
+}
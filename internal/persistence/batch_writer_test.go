@@ -0,0 +1,309 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// testBatchWriterOptions flushes quickly and retries without noticeable delay
+func testBatchWriterOptions(batchSize int) BatchWriterOptions {
+	return BatchWriterOptions{
+		BatchSize:      batchSize,
+		FlushInterval:  10 * time.Millisecond,
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+	}
+}
+
+func testItem(id string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"event_id": &types.AttributeValueMemberS{Value: id},
+		"status":   &types.AttributeValueMemberS{Value: "processed"},
+	}
+}
+
+// batchKeys returns the keys written by a TransactWriteItems call
+func batchKeys(input *dynamodb.TransactWriteItemsInput) []string {
+	var keys []string
+	for _, item := range input.TransactItems {
+		keys = append(keys, item.Put.Item["event_id"].(*types.AttributeValueMemberS).Value)
+	}
+	return keys
+}
+
+// canceledTransaction returns the error of a transaction canceled for the given per-item reasons
+func canceledTransaction(codes ...string) error {
+	reasons := make([]types.CancellationReason, len(codes))
+	for i, code := range codes {
+		reasons[i] = types.CancellationReason{Code: aws.String(code)}
+	}
+	return &types.TransactionCanceledException{Message: aws.String("Transaction cancelled"), CancellationReasons: reasons}
+}
+
+// writeConcurrently writes items from separate goroutines and returns each item's outcome
+func writeConcurrently(writer *BatchWriter, ids ...string) map[string]error {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]error)
+	)
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			err := writer.Write(context.Background(), testItem(id))
+			mu.Lock()
+			results[id] = err
+			mu.Unlock()
+		}(id)
+	}
+	wg.Wait()
+	return results
+}
+
+// TestBatchWriterGroupsWrites tests that concurrent writes are grouped into batches no larger than the batch size
+func TestBatchWriterGroupsWrites(t *testing.T) {
+	mockClient := &MockDynamoDBClient{}
+	var (
+		mu      sync.Mutex
+		batches [][]string
+	)
+	mockClient.On("TransactWriteItems", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		mu.Lock()
+		batches = append(batches, batchKeys(args.Get(1).(*dynamodb.TransactWriteItemsInput)))
+		mu.Unlock()
+	}).Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	writer := NewBatchWriter(mockClient, "test-events", "event_id", testBatchWriterOptions(MaxBatchItems), logrus.New())
+	defer writer.Close(context.Background())
+
+	ids := make([]string, 60)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("evt-%02d", i)
+	}
+	for id, err := range writeConcurrently(writer, ids...) {
+		assert.NoError(t, err, id)
+	}
+
+	written := 0
+	for _, batch := range batches {
+		assert.LessOrEqual(t, len(batch), MaxBatchItems)
+		written += len(batch)
+	}
+	assert.Equal(t, len(ids), written)
+	assert.Less(t, len(batches), len(ids))
+}
+
+// TestBatchWriterFailsExistingItems tests that items already stored fail with ErrItemExists and the
+// items canceled along with them are written again straight away
+func TestBatchWriterFailsExistingItems(t *testing.T) {
+	mockClient := &MockDynamoDBClient{}
+	var canceledOrder []string
+	mockClient.On("TransactWriteItems", mock.Anything, mock.MatchedBy(func(input *dynamodb.TransactWriteItemsInput) bool {
+		for _, item := range input.TransactItems {
+			if aws.ToString(item.Put.ConditionExpression) != "attribute_not_exists(#key)" || item.Put.ExpressionAttributeNames["#key"] != "event_id" {
+				return false
+			}
+		}
+		return len(input.TransactItems) == 2
+	})).Run(func(args mock.Arguments) {
+		canceledOrder = batchKeys(args.Get(1).(*dynamodb.TransactWriteItemsInput))
+	}).Return(nil, canceledTransaction("None", "ConditionalCheckFailed")).Once()
+	mockClient.On("TransactWriteItems", mock.Anything, mock.MatchedBy(func(input *dynamodb.TransactWriteItemsInput) bool {
+		return len(input.TransactItems) == 1
+	})).Return(&dynamodb.TransactWriteItemsOutput{}, nil).Once()
+
+	options := testBatchWriterOptions(2)
+	options.InitialBackoff = time.Hour
+	writer := NewBatchWriter(mockClient, "test-events", "event_id", options, logrus.New())
+	defer writer.Close(context.Background())
+
+	results := writeConcurrently(writer, "evt-1", "evt-2")
+	require.Len(t, canceledOrder, 2)
+	assert.NoError(t, results[canceledOrder[0]])
+	assert.ErrorIs(t, results[canceledOrder[1]], ErrItemExists)
+	mockClient.AssertExpectations(t)
+}
+
+// TestBatchWriterRetriesCanceledTransactions tests that transactions canceled for other reasons are retried
+func TestBatchWriterRetriesCanceledTransactions(t *testing.T) {
+	mockClient := &MockDynamoDBClient{}
+	mockClient.On("TransactWriteItems", mock.Anything, mock.Anything).Return(nil, canceledTransaction("ThrottlingError")).Once()
+	mockClient.On("TransactWriteItems", mock.Anything, mock.Anything).Return(&dynamodb.TransactWriteItemsOutput{}, nil).Once()
+
+	writer := NewBatchWriter(mockClient, "test-events", "event_id", testBatchWriterOptions(1), logrus.New())
+	defer writer.Close(context.Background())
+
+	assert.NoError(t, writer.Write(context.Background(), testItem("evt-1")))
+	mockClient.AssertExpectations(t)
+}
+
+// TestBatchWriterFailsAfterMaxAttempts tests that items still unprocessed after all attempts report an error
+func TestBatchWriterFailsAfterMaxAttempts(t *testing.T) {
+	mockClient := &MockDynamoDBClient{}
+	mockClient.On("TransactWriteItems", mock.Anything, mock.Anything).Return(nil, errors.New("throttled")).Times(3)
+
+	writer := NewBatchWriter(mockClient, "test-events", "event_id", testBatchWriterOptions(1), logrus.New())
+	defer writer.Close(context.Background())
+
+	err := writer.Write(context.Background(), testItem("evt-1"))
+	assert.ErrorContains(t, err, "throttled")
+	mockClient.AssertExpectations(t)
+}
+
+// TestBatchWriterCoalescesDuplicateKeys tests that repeated writes to a key within a batch are merged,
+// the first one creating the item and the others failing as duplicates
+func TestBatchWriterCoalescesDuplicateKeys(t *testing.T) {
+	mockClient := &MockDynamoDBClient{}
+	mockClient.On("TransactWriteItems", mock.Anything, mock.MatchedBy(func(input *dynamodb.TransactWriteItemsInput) bool {
+		return len(batchKeys(input)) == 1
+	})).Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	options := testBatchWriterOptions(MaxBatchItems)
+	options.FlushInterval = 50 * time.Millisecond
+	writer := NewBatchWriter(mockClient, "test-events", "event_id", options, logrus.New())
+	defer writer.Close(context.Background())
+
+	results := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() { results <- writer.Write(context.Background(), testItem("evt-1")) }()
+	}
+
+	var created, duplicates int
+	for i := 0; i < 3; i++ {
+		err := <-results
+		switch {
+		case err == nil:
+			created++
+		case errors.Is(err, ErrItemExists):
+			duplicates++
+		default:
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	assert.Equal(t, 1, created)
+	assert.Equal(t, 2, duplicates)
+}
+
+// TestBatchWriterFlushesOnClose tests that buffered items are written when the writer is closed
+func TestBatchWriterFlushesOnClose(t *testing.T) {
+	mockClient := &MockDynamoDBClient{}
+	mockClient.On("TransactWriteItems", mock.Anything, mock.Anything).Return(&dynamodb.TransactWriteItemsOutput{}, nil).Once()
+
+	options := testBatchWriterOptions(MaxBatchItems)
+	options.FlushInterval = time.Hour
+	writer := NewBatchWriter(mockClient, "test-events", "event_id", options, logrus.New())
+
+	result := make(chan error, 1)
+	go func() { result <- writer.Write(context.Background(), testItem("evt-1")) }()
+
+	// Give the write time to reach the buffer before closing
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, writer.Close(context.Background()))
+	assert.NoError(t, <-result)
+
+	assert.ErrorIs(t, writer.Write(context.Background(), testItem("evt-2")), ErrBatchWriterClosed)
+	mockClient.AssertExpectations(t)
+}
+
+// TestBatchWriterAcceptsWritesWhileFlushing tests that writes made while a batch is being written are
+// grouped into the next batch
+func TestBatchWriterAcceptsWritesWhileFlushing(t *testing.T) {
+	mockClient := &MockDynamoDBClient{}
+	release := make(chan struct{})
+	var (
+		mu      sync.Mutex
+		batches [][]string
+	)
+	mockClient.On("TransactWriteItems", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		mu.Lock()
+		batches = append(batches, batchKeys(args.Get(1).(*dynamodb.TransactWriteItemsInput)))
+		first := len(batches) == 1
+		mu.Unlock()
+		if first {
+			<-release
+		}
+	}).Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	writer := NewBatchWriter(mockClient, "test-events", "event_id", testBatchWriterOptions(MaxBatchItems), logrus.New())
+	defer writer.Close(context.Background())
+
+	first := make(chan error, 1)
+	go func() { first <- writer.Write(context.Background(), testItem("evt-1")) }()
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(batches) == 1
+	}, time.Second, time.Millisecond)
+
+	// The first batch is still being written while these are written
+	later := make(chan map[string]error, 1)
+	go func() { later <- writeConcurrently(writer, "evt-2", "evt-3", "evt-4") }()
+	time.Sleep(30 * time.Millisecond)
+	close(release)
+
+	assert.NoError(t, <-first)
+	for id, err := range <-later {
+		assert.NoError(t, err, id)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, batches, 2)
+	assert.ElementsMatch(t, []string{"evt-2", "evt-3", "evt-4"}, batches[1])
+}
+
+// TestBatchWriterCloseInterruptsRetries tests that a close that stops waiting ends the retries of the
+// batch being written
+func TestBatchWriterCloseInterruptsRetries(t *testing.T) {
+	mockClient := &MockDynamoDBClient{}
+	mockClient.On("TransactWriteItems", mock.Anything, mock.Anything).Return(nil, errors.New("throttled")).Once()
+
+	options := testBatchWriterOptions(1)
+	options.InitialBackoff = time.Hour
+	writer := NewBatchWriter(mockClient, "test-events", "event_id", options, logrus.New())
+
+	result := make(chan error, 1)
+	go func() { result <- writer.Write(context.Background(), testItem("evt-1")) }()
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, writer.Close(ctx), context.DeadlineExceeded)
+
+	select {
+	case err := <-result:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("the write should fail once the close stops waiting")
+	}
+	mockClient.AssertExpectations(t)
+}
+
+// TestSaveEventWithBatchWriter tests that the repository waits for the batched write
+func TestSaveEventWithBatchWriter(t *testing.T) {
+	mockClient := &MockDynamoDBClient{}
+	mockClient.On("TransactWriteItems", mock.Anything, mock.Anything).Return(nil, errors.New("dynamodb error"))
+
+	repo := &DynamoDBRepository{
+		client:    mockClient,
+		tableName: "test-events",
+	}
+	repo.writer = NewBatchWriter(mockClient, repo.tableName, "event_id", testBatchWriterOptions(1), logrus.New())
+	defer repo.Close(context.Background())
+
+	err := repo.SaveEvent(context.Background(), createValidProcessedEvent())
+	assert.ErrorContains(t, err, "failed to save event to DynamoDB")
+	mockClient.AssertNotCalled(t, "PutItem", mock.Anything, mock.Anything)
+}
@@ -2,15 +2,23 @@ package persistence
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
 
 	"github.com/d-sense/event-processor/internal/config"
+	"github.com/d-sense/event-processor/internal/metrics"
+	"github.com/d-sense/event-processor/pkg/logger"
 	"github.com/d-sense/event-processor/pkg/models"
 )
 
@@ -19,67 +27,168 @@ type DynamoDBClient interface {
 	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
 	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
 	DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error)
-	ListTables(ctx context.Context, params *dynamodb.ListTablesInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ListTablesOutput, error)
-	CreateTable(ctx context.Context, params *dynamodb.CreateTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error)
+	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
 }
 
+const (
+	// clientIDIndex is the GSI keyed by client_id on the events table
+	clientIDIndex = "client_id_index"
+
+	// statusIndex is the GSI keyed by status on the events table
+	statusIndex = "status_index"
+)
+
 // DynamoDBRepository implements Repository interface using AWS DynamoDB
 type DynamoDBRepository struct {
 	client    DynamoDBClient
 	tableName string
+
+	// clientsTableName is the table holding client configurations
+	clientsTableName string
+
+	// quarantineTableName is the table holding quarantined events
+	quarantineTableName string
+
+	// clientAuditTableName is the table holding the history of client configuration changes
+	clientAuditTableName string
+
+	// writer batches event writes when set, otherwise each event is written with PutItem
+	writer *BatchWriter
 }
 
 // NewDynamoDBRepository creates a new DynamoDB repository
 func NewDynamoDBRepository(awsCfg aws.Config, cfg *config.Config) Repository {
-	return &DynamoDBRepository{
-		client:    dynamodb.NewFromConfig(awsCfg),
-		tableName: cfg.DynamoDBTableName,
+	repo := &DynamoDBRepository{
+		client:               dynamodb.NewFromConfig(awsCfg),
+		tableName:            cfg.DynamoDBTableName,
+		clientsTableName:     cfg.DynamoDBClientsTableName,
+		quarantineTableName:  cfg.DynamoDBQuarantineTableName,
+		clientAuditTableName: cfg.DynamoDBClientAuditTableName,
+	}
+
+	if cfg.DynamoDBBatchSize > 1 {
+		options := DefaultBatchWriterOptions()
+		options.BatchSize = cfg.DynamoDBBatchSize
+		if cfg.DynamoDBFlushIntervalMs > 0 {
+			options.FlushInterval = time.Duration(cfg.DynamoDBFlushIntervalMs) * time.Millisecond
+		}
+		repo.writer = NewBatchWriter(repo.client, repo.tableName, "event_id", options, logger.New(cfg.LogLevel))
 	}
+
+	return repo
 }
 
-// marshalPayload converts a map[string]interface{} to DynamoDB attribute values
-func (r *DynamoDBRepository) marshalPayload(payload map[string]interface{}) map[string]types.AttributeValue {
-	result := make(map[string]types.AttributeValue)
+// Close flushes buffered writes
+func (r *DynamoDBRepository) Close(ctx context.Context) error {
+	if r.writer == nil {
+		return nil
+	}
+	return r.writer.Close(ctx)
+}
+
+// marshalPayload converts a payload to DynamoDB attribute values, see marshalValue for the mapping
+func (r *DynamoDBRepository) marshalPayload(payload map[string]interface{}) (map[string]types.AttributeValue, error) {
+	return marshalMap(payload)
+}
 
-	for key, value := range payload {
-		switch v := value.(type) {
-		case string:
-			result[key] = &types.AttributeValueMemberS{Value: v}
-		case int:
-			result[key] = &types.AttributeValueMemberN{Value: strconv.Itoa(v)}
-		case int64:
-			result[key] = &types.AttributeValueMemberN{Value: strconv.FormatInt(v, 10)}
-		case float64:
-			result[key] = &types.AttributeValueMemberN{Value: strconv.FormatFloat(v, 'f', -1, 64)}
-		case bool:
-			result[key] = &types.AttributeValueMemberBOOL{Value: v}
-		case map[string]interface{}:
-			result[key] = &types.AttributeValueMemberM{Value: r.marshalPayload(v)}
-		case []string:
-			result[key] = &types.AttributeValueMemberSS{Value: v}
-		default:
-			// Convert unknown types to string
-			result[key] = &types.AttributeValueMemberS{Value: fmt.Sprintf("%v", v)}
+// SaveEvent saves an event to DynamoDB, failing with ErrEventExists if it is already stored
+func (r *DynamoDBRepository) SaveEvent(ctx context.Context, event *models.ProcessedEvent) error {
+	item, err := r.eventItem(event)
+	if err != nil {
+		return err
+	}
+
+	// The batch writer only returns once the item is durably written
+	if r.writer != nil {
+		err := r.writer.Write(ctx, item)
+		if errors.Is(err, ErrItemExists) {
+			return fmt.Errorf("%w: %s", ErrEventExists, event.EventID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save event to DynamoDB: %w", err)
 		}
+		return nil
+	}
+
+	input := &dynamodb.PutItemInput{
+		TableName:           aws.String(r.tableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(event_id)"),
 	}
 
-	return result
+	start := time.Now()
+	_, err = r.client.PutItem(ctx, input)
+	metrics.ObserveDynamoDB("PutItem", start, err)
+	if err != nil {
+		var conditionFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionFailed) {
+			return fmt.Errorf("%w: %s", ErrEventExists, event.EventID)
+		}
+		return fmt.Errorf("failed to save event to DynamoDB: %w", err)
+	}
+
+	return nil
 }
 
-// SaveEvent saves an event to DynamoDB
-func (r *DynamoDBRepository) SaveEvent(ctx context.Context, event *models.ProcessedEvent) error {
+// ReplaceEvent replaces an event with a conditional put on its revision; it bypasses the batch
+// writer, which cannot write conditionally
+func (r *DynamoDBRepository) ReplaceEvent(ctx context.Context, event *models.ProcessedEvent, expectedRevision int64) error {
+	item, err := r.eventItem(event)
+	if err != nil {
+		return err
+	}
+
+	values := make(map[string]types.AttributeValue)
+	input := &dynamodb.PutItemInput{
+		TableName:           aws.String(r.tableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_exists(event_id) AND " + revisionCondition(expectedRevision, values)),
+	}
+	if len(values) > 0 {
+		input.ExpressionAttributeValues = values
+	}
+
+	start := time.Now()
+	_, err = r.client.PutItem(ctx, input)
+	metrics.ObserveDynamoDB("PutItem", start, err)
+	if err != nil {
+		var conditionFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionFailed) {
+			// Tell a missing event from a concurrent modification
+			if _, err := r.GetEvent(ctx, event.EventID); err != nil {
+				return err
+			}
+			return fmt.Errorf("%w: event %s was modified concurrently", ErrRevisionConflict, event.EventID)
+		}
+		return fmt.Errorf("failed to replace event: %w", err)
+	}
+
+	return nil
+}
+
+// eventItem converts an event to an item of the events table
+func (r *DynamoDBRepository) eventItem(event *models.ProcessedEvent) (map[string]types.AttributeValue, error) {
+	payload, err := r.marshalPayload(event.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
 	// Manually create the item with correct DynamoDB attribute names
 	item := map[string]types.AttributeValue{
 		"event_id":     &types.AttributeValueMemberS{Value: event.EventID},
 		"event_type":   &types.AttributeValueMemberS{Value: string(event.EventType)},
 		"client_id":    &types.AttributeValueMemberS{Value: event.ClientID},
-		"timestamp":    &types.AttributeValueMemberS{Value: event.Timestamp.Format(time.RFC3339)},
-		"payload":      &types.AttributeValueMemberM{Value: r.marshalPayload(event.Payload)},
+		"timestamp":    &types.AttributeValueMemberS{Value: event.Timestamp.UTC().Format(time.RFC3339)},
+		"payload":      &types.AttributeValueMemberM{Value: payload},
 		"version":      &types.AttributeValueMemberS{Value: event.Version},
-		"processed_at": &types.AttributeValueMemberS{Value: event.ProcessedAt.Format(time.RFC3339)},
+		"processed_at": &types.AttributeValueMemberS{Value: event.ProcessedAt.UTC().Format(time.RFC3339)},
 		"status":       &types.AttributeValueMemberS{Value: string(event.Status)},
 		"retry_count":  &types.AttributeValueMemberN{Value: strconv.Itoa(event.RetryCount)},
-		"ttl":          &types.AttributeValueMemberN{Value: strconv.FormatInt(event.TTL, 10)},
+		"revision":     &types.AttributeValueMemberN{Value: strconv.FormatInt(event.Revision, 10)},
 	}
 
 	// Add error message if present
@@ -87,17 +196,335 @@ func (r *DynamoDBRepository) SaveEvent(ctx context.Context, event *models.Proces
 		item["error_msg"] = &types.AttributeValueMemberS{Value: event.ErrorMsg}
 	}
 
-	input := &dynamodb.PutItemInput{
+	// Items without a ttl attribute are never expired by DynamoDB
+	if event.TTL > 0 {
+		item["ttl"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(event.TTL, 10)}
+	}
+
+	// Reprocessing runs are kept as JSON, like client limits
+	if len(event.Replays) > 0 {
+		replays, err := json.Marshal(event.Replays)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal replays of event %s: %w", event.EventID, err)
+		}
+		item["replays"] = &types.AttributeValueMemberS{Value: string(replays)}
+	}
+
+	// String sets cannot be empty, so events without derived fields have no attribute
+	if len(event.DerivedFields) > 0 {
+		item["derived_fields"] = &types.AttributeValueMemberSS{Value: event.DerivedFields}
+	}
+
+	return item, nil
+}
+
+// GetEvent retrieves a single event by its ID
+func (r *DynamoDBRepository) GetEvent(ctx context.Context, eventID string) (*models.ProcessedEvent, error) {
+	input := &dynamodb.GetItemInput{
 		TableName: aws.String(r.tableName),
-		Item:      item,
+		Key: map[string]types.AttributeValue{
+			"event_id": &types.AttributeValueMemberS{Value: eventID},
+		},
 	}
 
-	_, err := r.client.PutItem(ctx, input)
+	start := time.Now()
+	result, err := r.client.GetItem(ctx, input)
+	metrics.ObserveDynamoDB("GetItem", start, err)
 	if err != nil {
-		return fmt.Errorf("failed to save event to DynamoDB: %w", err)
+		return nil, fmt.Errorf("failed to get event: %w", err)
 	}
 
-	return nil
+	if result.Item == nil {
+		return nil, fmt.Errorf("%w: %s", ErrEventNotFound, eventID)
+	}
+
+	return r.unmarshalEvent(result.Item)
+}
+
+// UpdateEventStatus changes the status of an event with a conditional update on its revision
+func (r *DynamoDBRepository) UpdateEventStatus(ctx context.Context, eventID string, update StatusUpdate) (*models.ProcessedEvent, error) {
+	current, err := r.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkStatusUpdate(current, update); err != nil {
+		return nil, err
+	}
+
+	values := map[string]types.AttributeValue{
+		":status":   &types.AttributeValueMemberS{Value: string(update.Status)},
+		":from":     &types.AttributeValueMemberS{Value: string(current.Status)},
+		":revision": &types.AttributeValueMemberN{Value: strconv.FormatInt(update.ExpectedRevision+1, 10)},
+	}
+
+	updateExpression := "SET #status = :status, revision = :revision"
+	if update.ErrorMsg != "" {
+		updateExpression += ", error_msg = :error_msg"
+		values[":error_msg"] = &types.AttributeValueMemberS{Value: update.ErrorMsg}
+	} else {
+		updateExpression += " REMOVE error_msg"
+	}
+
+	condition := "#status = :from AND " + revisionCondition(update.ExpectedRevision, values)
+
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.tableName),
+		Key: map[string]types.AttributeValue{
+			"event_id": &types.AttributeValueMemberS{Value: eventID},
+		},
+		UpdateExpression:          aws.String(updateExpression),
+		ConditionExpression:       aws.String(condition),
+		ExpressionAttributeNames:  map[string]string{"#status": "status"},
+		ExpressionAttributeValues: values,
+		ReturnValues:              types.ReturnValueAllNew,
+	}
+
+	start := time.Now()
+	result, err := r.client.UpdateItem(ctx, input)
+	metrics.ObserveDynamoDB("UpdateItem", start, err)
+	if err != nil {
+		var conditionFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionFailed) {
+			return nil, fmt.Errorf("%w: event %s was modified concurrently", ErrRevisionConflict, eventID)
+		}
+		return nil, fmt.Errorf("failed to update event status: %w", err)
+	}
+
+	return r.unmarshalEvent(result.Attributes)
+}
+
+// revisionCondition returns the condition expression matching items at the expected revision, adding
+// the values it uses. Events are saved at revision 1; only items written before revisions were
+// introduced have no revision attribute and are read as revision 0.
+func revisionCondition(expected int64, values map[string]types.AttributeValue) string {
+	if expected == 0 {
+		return "attribute_not_exists(revision)"
+	}
+	values[":expected"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(expected, 10)}
+	return "revision = :expected"
+}
+
+// ListEventsByClient lists the events of a client using the client_id_index GSI
+func (r *DynamoDBRepository) ListEventsByClient(ctx context.Context, clientID string, opts ListOptions) (*EventPage, error) {
+	return r.queryIndex(ctx, clientIDIndex, "client_id", clientID, opts)
+}
+
+// ListEventsByStatus lists the events with a status using the status_index GSI
+func (r *DynamoDBRepository) ListEventsByStatus(ctx context.Context, status models.EventStatus, opts ListOptions) (*EventPage, error) {
+	return r.queryIndex(ctx, statusIndex, "status", string(status), opts)
+}
+
+// queryIndex queries a GSI by its hash key, applying the time range as a filter on the timestamp attribute.
+// DynamoDB applies Limit before filtering, so a filtered page may hold fewer events than requested
+// while still returning a cursor.
+func (r *DynamoDBRepository) queryIndex(ctx context.Context, indexName, keyAttribute, keyValue string, opts ListOptions) (*EventPage, error) {
+	startKey, err := decodeCursor(opts.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(r.tableName),
+		IndexName:              aws.String(indexName),
+		KeyConditionExpression: aws.String("#key = :key"),
+		ExpressionAttributeNames: map[string]string{
+			"#key": keyAttribute,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":key": &types.AttributeValueMemberS{Value: keyValue},
+		},
+		Limit:             aws.Int32(int32(opts.PageSize())),
+		ExclusiveStartKey: startKey,
+	}
+
+	if filter := timeRangeFilter(opts, input.ExpressionAttributeNames, input.ExpressionAttributeValues); filter != "" {
+		input.FilterExpression = aws.String(filter)
+	}
+
+	start := time.Now()
+	result, err := r.client.Query(ctx, input)
+	metrics.ObserveDynamoDB("Query", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", indexName, err)
+	}
+
+	page := &EventPage{
+		Events: make([]*models.ProcessedEvent, 0, len(result.Items)),
+	}
+	for _, item := range result.Items {
+		event, err := r.unmarshalEvent(item)
+		if err != nil {
+			return nil, err
+		}
+		page.Events = append(page.Events, event)
+	}
+
+	if len(result.LastEvaluatedKey) > 0 {
+		page.NextCursor, err = encodeCursor(result.LastEvaluatedKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
+
+// timeRangeFilter builds a filter expression over the timestamp attribute, registering its names and values.
+// Timestamps are stored as UTC RFC 3339 strings, which sort lexicographically.
+func timeRangeFilter(opts ListOptions, names map[string]string, values map[string]types.AttributeValue) string {
+	switch {
+	case !opts.From.IsZero() && !opts.To.IsZero():
+		names["#ts"] = "timestamp"
+		values[":from"] = &types.AttributeValueMemberS{Value: opts.From.UTC().Format(time.RFC3339)}
+		values[":to"] = &types.AttributeValueMemberS{Value: opts.To.UTC().Format(time.RFC3339)}
+		return "#ts BETWEEN :from AND :to"
+	case !opts.From.IsZero():
+		names["#ts"] = "timestamp"
+		values[":from"] = &types.AttributeValueMemberS{Value: opts.From.UTC().Format(time.RFC3339)}
+		return "#ts >= :from"
+	case !opts.To.IsZero():
+		names["#ts"] = "timestamp"
+		values[":to"] = &types.AttributeValueMemberS{Value: opts.To.UTC().Format(time.RFC3339)}
+		return "#ts <= :to"
+	default:
+		return ""
+	}
+}
+
+// encodeCursor encodes a LastEvaluatedKey into an opaque pagination cursor
+func encodeCursor(key map[string]types.AttributeValue) (string, error) {
+	values := make(map[string]string, len(key))
+	for name, value := range key {
+		s, ok := value.(*types.AttributeValueMemberS)
+		if !ok {
+			return "", fmt.Errorf("unsupported key attribute type for %s", name)
+		}
+		values[name] = s.Value
+	}
+	return encodeCursorValues(values)
+}
+
+// decodeCursor decodes an opaque pagination cursor into an ExclusiveStartKey
+func decodeCursor(cursor string) (map[string]types.AttributeValue, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	values, err := decodeCursorValues(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	key := make(map[string]types.AttributeValue, len(values))
+	for name, value := range values {
+		key[name] = &types.AttributeValueMemberS{Value: value}
+	}
+	return key, nil
+}
+
+// encodeCursorValues encodes named key values into an opaque pagination cursor
+func encodeCursorValues(values map[string]string) (string, error) {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursorValues decodes an opaque pagination cursor into named key values
+func decodeCursorValues(cursor string) (map[string]string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var values map[string]string
+	if err := json.Unmarshal(data, &values); err != nil || len(values) == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return values, nil
+}
+
+// unmarshalEvent converts a stored item back into a ProcessedEvent
+func (r *DynamoDBRepository) unmarshalEvent(item map[string]types.AttributeValue) (*models.ProcessedEvent, error) {
+	return UnmarshalEventItem(item)
+}
+
+// UnmarshalEventItem converts an item of the events table, such as a stream image, into a ProcessedEvent
+func UnmarshalEventItem(item map[string]types.AttributeValue) (*models.ProcessedEvent, error) {
+	event := &models.ProcessedEvent{
+		Event: models.Event{
+			EventID:   stringAttr(item, "event_id"),
+			EventType: models.EventType(stringAttr(item, "event_type")),
+			ClientID:  stringAttr(item, "client_id"),
+			Version:   stringAttr(item, "version"),
+		},
+		Status:   models.EventStatus(stringAttr(item, "status")),
+		ErrorMsg: stringAttr(item, "error_msg"),
+	}
+
+	var err error
+	if event.Timestamp, err = timeAttr(item, "timestamp"); err != nil {
+		return nil, err
+	}
+	if event.ProcessedAt, err = timeAttr(item, "processed_at"); err != nil {
+		return nil, err
+	}
+
+	if n, ok := item["retry_count"].(*types.AttributeValueMemberN); ok {
+		if event.RetryCount, err = strconv.Atoi(n.Value); err != nil {
+			return nil, fmt.Errorf("invalid retry_count for event %s: %w", event.EventID, err)
+		}
+	}
+	if n, ok := item["ttl"].(*types.AttributeValueMemberN); ok {
+		if event.TTL, err = strconv.ParseInt(n.Value, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid ttl for event %s: %w", event.EventID, err)
+		}
+	}
+
+	if n, ok := item["revision"].(*types.AttributeValueMemberN); ok {
+		if event.Revision, err = strconv.ParseInt(n.Value, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid revision for event %s: %w", event.EventID, err)
+		}
+	}
+
+	if payload, ok := item["payload"].(*types.AttributeValueMemberM); ok {
+		event.Payload = unmarshalMap(payload.Value)
+	}
+
+	if replays, ok := item["replays"].(*types.AttributeValueMemberS); ok {
+		if err := json.Unmarshal([]byte(replays.Value), &event.Replays); err != nil {
+			return nil, fmt.Errorf("invalid replays for event %s: %w", event.EventID, err)
+		}
+	}
+
+	if fields, ok := item["derived_fields"].(*types.AttributeValueMemberSS); ok {
+		event.DerivedFields = fields.Value
+	}
+
+	return event, nil
+}
+
+// stringAttr returns the string value of an attribute, or "" when absent
+func stringAttr(item map[string]types.AttributeValue, name string) string {
+	if s, ok := item[name].(*types.AttributeValueMemberS); ok {
+		return s.Value
+	}
+	return ""
+}
+
+// timeAttr parses an RFC 3339 string attribute, returning the zero time when absent
+func timeAttr(item map[string]types.AttributeValue, name string) (time.Time, error) {
+	value := stringAttr(item, name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s attribute: %w", name, err)
+	}
+	return parsed, nil
 }
 
 // HealthCheck performs a health check on the DynamoDB connection
@@ -106,62 +533,521 @@ func (r *DynamoDBRepository) HealthCheck(ctx context.Context) error {
 		TableName: aws.String(r.tableName),
 	}
 
+	start := time.Now()
 	_, err := r.client.DescribeTable(ctx, input)
+	metrics.ObserveDynamoDB("DescribeTable", start, err)
 	return err
 }
 
 // GetClientConfig retrieves client configuration
 func (r *DynamoDBRepository) GetClientConfig(ctx context.Context, clientID string) (*models.ClientConfig, error) {
 	input := &dynamodb.GetItemInput{
-		TableName: aws.String("events-clients"),
+		TableName: aws.String(r.clientsTableName),
 		Key: map[string]types.AttributeValue{
 			"client_id": &types.AttributeValueMemberS{Value: clientID},
 		},
+		// Caches read a configuration again when its change is streamed, which may be before an
+		// eventually consistent read returns it
+		ConsistentRead: aws.Bool(true),
 	}
 
+	start := time.Now()
 	result, err := r.client.GetItem(ctx, input)
+	metrics.ObserveDynamoDB("GetItem", start, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get client config: %w", err)
 	}
 
 	if result.Item == nil {
-		return nil, fmt.Errorf("client config not found: %s", clientID)
+		return nil, fmt.Errorf("%w: %s", ErrClientConfigNotFound, clientID)
+	}
+
+	config, err := clientConfigFromItem(result.Item)
+	if err != nil {
+		return nil, err
+	}
+	config.ClientID = clientID
+	return config, nil
+}
+
+// PutClientConfig stores a client configuration, replacing any existing one
+func (r *DynamoDBRepository) PutClientConfig(ctx context.Context, config *models.ClientConfig) error {
+	item, err := clientConfigItem(config)
+	if err != nil {
+		return err
+	}
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String(r.clientsTableName),
+		Item:      item,
 	}
 
-	// Manually extract attributes to avoid unmarshaling issues
+	start := time.Now()
+	_, err = r.client.PutItem(ctx, input)
+	metrics.ObserveDynamoDB("PutItem", start, err)
+	if err != nil {
+		return fmt.Errorf("failed to put client config %s: %w", config.ClientID, err)
+	}
+	return nil
+}
+
+// CreateClientConfig stores a client configuration unless the client has one, failing with
+// ErrClientConfigExists otherwise
+func (r *DynamoDBRepository) CreateClientConfig(ctx context.Context, config *models.ClientConfig) error {
+	item, err := clientConfigItem(config)
+	if err != nil {
+		return err
+	}
+	input := &dynamodb.PutItemInput{
+		TableName:           aws.String(r.clientsTableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(client_id)"),
+	}
+
+	start := time.Now()
+	_, err = r.client.PutItem(ctx, input)
+	metrics.ObserveDynamoDB("PutItem", start, err)
+
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return fmt.Errorf("%w: %s", ErrClientConfigExists, config.ClientID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create client config %s: %w", config.ClientID, err)
+	}
+	return nil
+}
+
+// ListClientConfigs returns every client configuration ordered by client ID
+func (r *DynamoDBRepository) ListClientConfigs(ctx context.Context) ([]*models.ClientConfig, error) {
+	var configs []*models.ClientConfig
+	input := &dynamodb.ScanInput{TableName: aws.String(r.clientsTableName)}
+	for {
+		start := time.Now()
+		result, err := r.client.Scan(ctx, input)
+		metrics.ObserveDynamoDB("Scan", start, err)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list client configs: %w", err)
+		}
+
+		for _, item := range result.Items {
+			config, err := clientConfigFromItem(item)
+			if err != nil {
+				return nil, err
+			}
+			configs = append(configs, config)
+		}
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+
+	sort.Slice(configs, func(i, j int) bool { return configs[i].ClientID < configs[j].ClientID })
+	return configs, nil
+}
+
+// DeleteClientConfig removes a client configuration
+func (r *DynamoDBRepository) DeleteClientConfig(ctx context.Context, clientID string) error {
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String(r.clientsTableName),
+		Key: map[string]types.AttributeValue{
+			"client_id": &types.AttributeValueMemberS{Value: clientID},
+		},
+		ConditionExpression: aws.String("attribute_exists(client_id)"),
+	}
+
+	start := time.Now()
+	_, err := r.client.DeleteItem(ctx, input)
+	metrics.ObserveDynamoDB("DeleteItem", start, err)
+
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return fmt.Errorf("%w: %s", ErrClientConfigNotFound, clientID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete client config %s: %w", clientID, err)
+	}
+	return nil
+}
+
+// clientConfigItem converts a client configuration to an events-clients item
+func clientConfigItem(config *models.ClientConfig) (map[string]types.AttributeValue, error) {
+	settings := make(map[string]types.AttributeValue, len(config.Config))
+	for key, value := range config.Config {
+		settings[key] = &types.AttributeValueMemberS{Value: value}
+	}
+
+	item := map[string]types.AttributeValue{
+		"client_id": &types.AttributeValueMemberS{Value: config.ClientID},
+		"config":    &types.AttributeValueMemberM{Value: settings},
+		"active":    &types.AttributeValueMemberBOOL{Value: config.Active},
+	}
+	// String sets cannot be empty, so a client without allowed types has no attribute
+	if len(config.AllowedTypes) > 0 {
+		allowedTypes := make([]string, len(config.AllowedTypes))
+		for i, eventType := range config.AllowedTypes {
+			allowedTypes[i] = string(eventType)
+		}
+		item["allowed_types"] = &types.AttributeValueMemberSS{Value: allowedTypes}
+	}
+	// Policies are nested several levels deep, so they are kept as a JSON document
+	if len(config.Policies) > 0 {
+		policies, err := json.Marshal(config.Policies)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal policies of client %s: %w", config.ClientID, err)
+		}
+		item["policies"] = &types.AttributeValueMemberS{Value: string(policies)}
+	}
+	if config.Limits != nil {
+		limits, err := json.Marshal(config.Limits)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal limits of client %s: %w", config.ClientID, err)
+		}
+		item["limits"] = &types.AttributeValueMemberS{Value: string(limits)}
+	}
+	return item, nil
+}
+
+// clientConfigFromItem extracts a client configuration from an events-clients item, reading the
+// attributes one by one so that items written by hand with missing attributes still load
+func clientConfigFromItem(item map[string]types.AttributeValue) (*models.ClientConfig, error) {
 	config := &models.ClientConfig{
-		ClientID: clientID,
-		Active:   true, // Default to true
+		Active: true, // Default to true
+	}
+
+	if clientIDAttr, ok := item["client_id"].(*types.AttributeValueMemberS); ok {
+		config.ClientID = clientIDAttr.Value
 	}
 
 	// Extract allowed types
-	if allowedTypesAttr, ok := result.Item["allowed_types"]; ok {
-		if allowedTypesSS, ok := allowedTypesAttr.(*types.AttributeValueMemberSS); ok {
-			config.AllowedTypes = make([]models.EventType, len(allowedTypesSS.Value))
-			for i, eventTypeStr := range allowedTypesSS.Value {
-				config.AllowedTypes[i] = models.EventType(eventTypeStr)
-			}
+	if allowedTypesSS, ok := item["allowed_types"].(*types.AttributeValueMemberSS); ok {
+		config.AllowedTypes = make([]models.EventType, len(allowedTypesSS.Value))
+		for i, eventTypeStr := range allowedTypesSS.Value {
+			config.AllowedTypes[i] = models.EventType(eventTypeStr)
 		}
 	}
 
 	// Extract active status
-	if activeAttr, ok := result.Item["active"]; ok {
-		if activeBool, ok := activeAttr.(*types.AttributeValueMemberBOOL); ok {
-			config.Active = activeBool.Value
-		}
+	if activeBool, ok := item["active"].(*types.AttributeValueMemberBOOL); ok {
+		config.Active = activeBool.Value
 	}
 
 	// Extract config map
-	if configAttr, ok := result.Item["config"]; ok {
-		if configMap, ok := configAttr.(*types.AttributeValueMemberM); ok {
-			config.Config = make(map[string]string)
-			for key, value := range configMap.Value {
-				if strValue, ok := value.(*types.AttributeValueMemberS); ok {
-					config.Config[key] = strValue.Value
-				}
+	if configMap, ok := item["config"].(*types.AttributeValueMemberM); ok && len(configMap.Value) > 0 {
+		config.Config = make(map[string]string)
+		for key, value := range configMap.Value {
+			if strValue, ok := value.(*types.AttributeValueMemberS); ok {
+				config.Config[key] = strValue.Value
 			}
 		}
 	}
 
+	if policies, ok := item["policies"].(*types.AttributeValueMemberS); ok {
+		if err := json.Unmarshal([]byte(policies.Value), &config.Policies); err != nil {
+			return nil, fmt.Errorf("invalid policies for client %s: %w", config.ClientID, err)
+		}
+	}
+
+	if limits, ok := item["limits"].(*types.AttributeValueMemberS); ok {
+		if err := json.Unmarshal([]byte(limits.Value), &config.Limits); err != nil {
+			return nil, fmt.Errorf("invalid limits for client %s: %w", config.ClientID, err)
+		}
+	}
+
 	return config, nil
 }
+
+// RecordClientChange stores a change in the client audit table. Changes are keyed by client and a
+// change ID starting with the fixed-width change time, so that a client's changes sort in the order
+// they were made.
+func (r *DynamoDBRepository) RecordClientChange(ctx context.Context, change models.ClientChange) error {
+	item, err := clientChangeItem(change)
+	if err != nil {
+		return err
+	}
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String(r.clientAuditTableName),
+		Item:      item,
+	}
+
+	start := time.Now()
+	_, err = r.client.PutItem(ctx, input)
+	metrics.ObserveDynamoDB("PutItem", start, err)
+	if err != nil {
+		return fmt.Errorf("failed to record change to client %s: %w", change.ClientID, err)
+	}
+	return nil
+}
+
+// ListClientChanges queries the changes to a client, or scans the changes of every client when
+// clientID is empty, in the order they were made
+func (r *DynamoDBRepository) ListClientChanges(ctx context.Context, clientID string) ([]models.ClientChange, error) {
+	var changes []models.ClientChange
+	var startKey map[string]types.AttributeValue
+	for {
+		var (
+			items   []map[string]types.AttributeValue
+			lastKey map[string]types.AttributeValue
+		)
+		if clientID != "" {
+			input := &dynamodb.QueryInput{
+				TableName:              aws.String(r.clientAuditTableName),
+				KeyConditionExpression: aws.String("client_id = :client_id"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":client_id": &types.AttributeValueMemberS{Value: clientID},
+				},
+				ExclusiveStartKey: startKey,
+			}
+			start := time.Now()
+			result, err := r.client.Query(ctx, input)
+			metrics.ObserveDynamoDB("Query", start, err)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list client changes: %w", err)
+			}
+			items, lastKey = result.Items, result.LastEvaluatedKey
+		} else {
+			input := &dynamodb.ScanInput{
+				TableName:         aws.String(r.clientAuditTableName),
+				ExclusiveStartKey: startKey,
+			}
+			start := time.Now()
+			result, err := r.client.Scan(ctx, input)
+			metrics.ObserveDynamoDB("Scan", start, err)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list client changes: %w", err)
+			}
+			items, lastKey = result.Items, result.LastEvaluatedKey
+		}
+
+		for _, item := range items {
+			change, err := clientChangeFromItem(item)
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, change)
+		}
+		if len(lastKey) == 0 {
+			break
+		}
+		startKey = lastKey
+	}
+
+	sortClientChanges(changes)
+	return changes, nil
+}
+
+// clientChangeItem converts a change to an item of the client audit table. The configurations are
+// kept as JSON documents like the policies of a client.
+func clientChangeItem(change models.ClientChange) (map[string]types.AttributeValue, error) {
+	changedAt := change.Time.UTC().Format(sqlTimeLayout)
+	item := map[string]types.AttributeValue{
+		"client_id":  &types.AttributeValueMemberS{Value: change.ClientID},
+		"change_id":  &types.AttributeValueMemberS{Value: changedAt + "#" + uuid.NewString()},
+		"changed_at": &types.AttributeValueMemberS{Value: changedAt},
+		"actor":      &types.AttributeValueMemberS{Value: change.Actor},
+		"action":     &types.AttributeValueMemberS{Value: string(change.Action)},
+	}
+	for name, config := range map[string]*models.ClientConfig{"before": change.Before, "after": change.After} {
+		if config == nil {
+			continue
+		}
+		encoded, err := json.Marshal(config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal change to client %s: %w", change.ClientID, err)
+		}
+		item[name] = &types.AttributeValueMemberS{Value: string(encoded)}
+	}
+	return item, nil
+}
+
+// clientChangeFromItem converts an item of the client audit table back into a change
+func clientChangeFromItem(item map[string]types.AttributeValue) (models.ClientChange, error) {
+	change := models.ClientChange{
+		ClientID: stringAttr(item, "client_id"),
+		Actor:    stringAttr(item, "actor"),
+		Action:   models.ClientAction(stringAttr(item, "action")),
+	}
+
+	var err error
+	if change.Time, err = timeAttr(item, "changed_at"); err != nil {
+		return change, fmt.Errorf("invalid change to client %s: %w", change.ClientID, err)
+	}
+	for name, config := range map[string]**models.ClientConfig{"before": &change.Before, "after": &change.After} {
+		if encoded := stringAttr(item, name); encoded != "" {
+			if err := json.Unmarshal([]byte(encoded), config); err != nil {
+				return change, fmt.Errorf("invalid %s of change to client %s: %w", name, change.ClientID, err)
+			}
+		}
+	}
+	return change, nil
+}
+
+// QuarantineEvent stores a rejected event in the quarantine table
+func (r *DynamoDBRepository) QuarantineEvent(ctx context.Context, event *models.QuarantinedEvent) error {
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String(r.quarantineTableName),
+		Item:      quarantineItem(event),
+	}
+
+	start := time.Now()
+	_, err := r.client.PutItem(ctx, input)
+	metrics.ObserveDynamoDB("PutItem", start, err)
+	if err != nil {
+		return fmt.Errorf("failed to quarantine event %s: %w", event.QuarantineID, err)
+	}
+	return nil
+}
+
+// GetQuarantinedEvent retrieves a quarantined event by its quarantine ID
+func (r *DynamoDBRepository) GetQuarantinedEvent(ctx context.Context, quarantineID string) (*models.QuarantinedEvent, error) {
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String(r.quarantineTableName),
+		Key: map[string]types.AttributeValue{
+			"quarantine_id": &types.AttributeValueMemberS{Value: quarantineID},
+		},
+	}
+
+	start := time.Now()
+	result, err := r.client.GetItem(ctx, input)
+	metrics.ObserveDynamoDB("GetItem", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quarantined event: %w", err)
+	}
+
+	if result.Item == nil {
+		return nil, fmt.Errorf("%w: %s", ErrQuarantinedEventNotFound, quarantineID)
+	}
+	return quarantinedEventFromItem(result.Item)
+}
+
+// ListQuarantinedEvents scans the quarantine table for matching events, most recent first. The
+// table only holds events awaiting a decision, so it is small enough to scan.
+func (r *DynamoDBRepository) ListQuarantinedEvents(ctx context.Context, filter QuarantineFilter) ([]*models.QuarantinedEvent, error) {
+	input := &dynamodb.ScanInput{TableName: aws.String(r.quarantineTableName)}
+
+	var conditions []string
+	values := make(map[string]types.AttributeValue)
+	if filter.ClientID != "" {
+		conditions = append(conditions, "client_id = :client_id")
+		values[":client_id"] = &types.AttributeValueMemberS{Value: filter.ClientID}
+	}
+	if filter.Category != "" {
+		conditions = append(conditions, "category = :category")
+		values[":category"] = &types.AttributeValueMemberS{Value: string(filter.Category)}
+	}
+	if len(conditions) > 0 {
+		input.FilterExpression = aws.String(strings.Join(conditions, " AND "))
+		input.ExpressionAttributeValues = values
+	}
+
+	events := make([]*models.QuarantinedEvent, 0)
+	for {
+		start := time.Now()
+		result, err := r.client.Scan(ctx, input)
+		metrics.ObserveDynamoDB("Scan", start, err)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list quarantined events: %w", err)
+		}
+
+		for _, item := range result.Items {
+			event, err := quarantinedEventFromItem(item)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, event)
+		}
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+
+	return sortQuarantinedEvents(events, filter), nil
+}
+
+// DeleteQuarantinedEvent removes a quarantined event
+func (r *DynamoDBRepository) DeleteQuarantinedEvent(ctx context.Context, quarantineID string) error {
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String(r.quarantineTableName),
+		Key: map[string]types.AttributeValue{
+			"quarantine_id": &types.AttributeValueMemberS{Value: quarantineID},
+		},
+		ConditionExpression: aws.String("attribute_exists(quarantine_id)"),
+	}
+
+	start := time.Now()
+	_, err := r.client.DeleteItem(ctx, input)
+	metrics.ObserveDynamoDB("DeleteItem", start, err)
+
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return fmt.Errorf("%w: %s", ErrQuarantinedEventNotFound, quarantineID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete quarantined event %s: %w", quarantineID, err)
+	}
+	return nil
+}
+
+// quarantineItem converts a quarantined event to an item of the quarantine table
+func quarantineItem(event *models.QuarantinedEvent) map[string]types.AttributeValue {
+	errorValues := make([]types.AttributeValue, len(event.Errors))
+	for i, message := range event.Errors {
+		errorValues[i] = &types.AttributeValueMemberS{Value: message}
+	}
+
+	item := map[string]types.AttributeValue{
+		"quarantine_id":  &types.AttributeValueMemberS{Value: event.QuarantineID},
+		"category":       &types.AttributeValueMemberS{Value: string(event.Category)},
+		"errors":         &types.AttributeValueMemberL{Value: errorValues},
+		"body":           &types.AttributeValueMemberS{Value: event.Body},
+		"quarantined_at": &types.AttributeValueMemberS{Value: event.QuarantinedAt.UTC().Format(time.RFC3339Nano)},
+	}
+	// Rejected bodies may lack any of these, and key attributes of indexes added later cannot be empty
+	if event.ClientID != "" {
+		item["client_id"] = &types.AttributeValueMemberS{Value: event.ClientID}
+	}
+	if event.EventID != "" {
+		item["event_id"] = &types.AttributeValueMemberS{Value: event.EventID}
+	}
+	if event.EventType != "" {
+		item["event_type"] = &types.AttributeValueMemberS{Value: string(event.EventType)}
+	}
+	if event.Validation != nil {
+		validation, _ := json.Marshal(event.Validation)
+		item["validation"] = &types.AttributeValueMemberS{Value: string(validation)}
+	}
+	return item
+}
+
+// quarantinedEventFromItem converts an item of the quarantine table back into a quarantined event
+func quarantinedEventFromItem(item map[string]types.AttributeValue) (*models.QuarantinedEvent, error) {
+	event := &models.QuarantinedEvent{
+		QuarantineID: stringAttr(item, "quarantine_id"),
+		Category:     models.QuarantineCategory(stringAttr(item, "category")),
+		ClientID:     stringAttr(item, "client_id"),
+		EventID:      stringAttr(item, "event_id"),
+		EventType:    models.EventType(stringAttr(item, "event_type")),
+		Body:         stringAttr(item, "body"),
+		Errors:       []string{},
+	}
+
+	var err error
+	if event.QuarantinedAt, err = timeAttr(item, "quarantined_at"); err != nil {
+		return nil, fmt.Errorf("invalid quarantined event %s: %w", event.QuarantineID, err)
+	}
+
+	if errorValues, ok := item["errors"].(*types.AttributeValueMemberL); ok {
+		for _, value := range errorValues.Value {
+			if message, ok := value.(*types.AttributeValueMemberS); ok {
+				event.Errors = append(event.Errors, message.Value)
+			}
+		}
+	}
+	if validation, ok := item["validation"].(*types.AttributeValueMemberS); ok {
+		if err := json.Unmarshal([]byte(validation.Value), &event.Validation); err != nil {
+			return nil, fmt.Errorf("invalid validation for quarantined event %s: %w", event.QuarantineID, err)
+		}
+	}
+	return event, nil
+}
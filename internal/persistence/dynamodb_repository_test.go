@@ -2,7 +2,10 @@ package persistence
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -11,6 +14,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"github.com/d-sense/event-processor/internal/config"
 	"github.com/d-sense/event-processor/pkg/models"
@@ -45,20 +49,44 @@ func (m *MockDynamoDBClient) DescribeTable(ctx context.Context, params *dynamodb
 	return args.Get(0).(*dynamodb.DescribeTableOutput), args.Error(1)
 }
 
-func (m *MockDynamoDBClient) ListTables(ctx context.Context, params *dynamodb.ListTablesInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ListTablesOutput, error) {
+func (m *MockDynamoDBClient) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
 	args := m.Called(ctx, params)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).(*dynamodb.ListTablesOutput), args.Error(1)
+	return args.Get(0).(*dynamodb.UpdateItemOutput), args.Error(1)
 }
 
-func (m *MockDynamoDBClient) CreateTable(ctx context.Context, params *dynamodb.CreateTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error) {
+func (m *MockDynamoDBClient) TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
 	args := m.Called(ctx, params)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).(*dynamodb.CreateTableOutput), args.Error(1)
+	return args.Get(0).(*dynamodb.TransactWriteItemsOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBClient) Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dynamodb.ScanOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBClient) DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dynamodb.DeleteItemOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBClient) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dynamodb.QueryOutput), args.Error(1)
 }
 
 // Test data structures
@@ -89,6 +117,27 @@ type healthCheckTestCase struct {
 	description string
 }
 
+type getEventTestCase struct {
+	name          string
+	eventID       string
+	mockClient    func(*MockDynamoDBClient)
+	expectError   bool
+	errorMsg      string
+	expectedEvent *models.ProcessedEvent
+	description   string
+}
+
+type listEventsTestCase struct {
+	name           string
+	opts           ListOptions
+	mockClient     func(*MockDynamoDBClient)
+	expectError    bool
+	errorMsg       string
+	expectedCount  int
+	expectedCursor bool
+	description    string
+}
+
 type marshalPayloadTestCase struct {
 	name           string
 	payload        map[string]interface{}
@@ -126,6 +175,22 @@ func TestSaveEvent(t *testing.T) {
 			expectError: false,
 			description: "Should successfully save event with complex nested payload",
 		},
+		{
+			name: "Event Without Expiry",
+			event: func() *models.ProcessedEvent {
+				event := createValidProcessedEvent()
+				event.TTL = 0
+				return event
+			}(),
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("PutItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.PutItemInput) bool {
+					_, hasTTL := input.Item["ttl"]
+					return !hasTTL
+				})).Return(&dynamodb.PutItemOutput{}, nil)
+			},
+			expectError: false,
+			description: "Should omit the ttl attribute so DynamoDB never expires the event",
+		},
 		{
 			name:  "DynamoDB PutItem Failure",
 			event: createValidProcessedEvent(),
@@ -136,6 +201,18 @@ func TestSaveEvent(t *testing.T) {
 			errorMsg:    "failed to save event to DynamoDB",
 			description: "Should fail when DynamoDB PutItem operation fails",
 		},
+		{
+			name:  "Event Already Stored",
+			event: createValidProcessedEvent(),
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("PutItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.PutItemInput) bool {
+					return aws.ToString(input.ConditionExpression) == "attribute_not_exists(event_id)"
+				})).Return(nil, &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")})
+			},
+			expectError: true,
+			errorMsg:    "event already exists",
+			description: "Should keep the stored event and report a duplicate when the event is already stored",
+		},
 		{
 			name:  "Event with Zero Timestamp",
 			event: createProcessedEventWithZeroTimestamp(),
@@ -206,7 +283,9 @@ func TestGetClientConfig(t *testing.T) {
 						"rate_limit": &types.AttributeValueMemberS{Value: "1000"},
 					}},
 				}
-				mc.On("GetItem", mock.Anything, mock.AnythingOfType("*dynamodb.GetItemInput")).Return(&dynamodb.GetItemOutput{Item: item}, nil)
+				mc.On("GetItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.GetItemInput) bool {
+					return aws.ToString(input.TableName) == "test-clients"
+				})).Return(&dynamodb.GetItemOutput{Item: item}, nil)
 			},
 			expectError: false,
 			expectedConfig: &models.ClientConfig{
@@ -217,7 +296,7 @@ func TestGetClientConfig(t *testing.T) {
 					"rate_limit": "1000",
 				},
 			},
-			description: "Should successfully retrieve complete client configuration",
+			description: "Should successfully retrieve complete client configuration from the configured clients table",
 		},
 		{
 			name:     "Client Config - Minimal Fields",
@@ -294,6 +373,21 @@ func TestGetClientConfig(t *testing.T) {
 			expectedConfig: nil,
 			description:    "Should fail when client config doesn't exist",
 		},
+		{
+			name:     "Client Config - Invalid Policies",
+			clientID: "client-001",
+			mockClient: func(mc *MockDynamoDBClient) {
+				item := map[string]types.AttributeValue{
+					"client_id": &types.AttributeValueMemberS{Value: "client-001"},
+					"policies":  &types.AttributeValueMemberS{Value: "{"},
+				}
+				mc.On("GetItem", mock.Anything, mock.AnythingOfType("*dynamodb.GetItemInput")).Return(&dynamodb.GetItemOutput{Item: item}, nil)
+			},
+			expectError:    true,
+			errorMsg:       "invalid policies for client client-001",
+			expectedConfig: nil,
+			description:    "Should fail instead of dropping policies that cannot be read",
+		},
 		{
 			name:     "DynamoDB GetItem Failure",
 			clientID: "client-001",
@@ -319,8 +413,9 @@ func TestGetClientConfig(t *testing.T) {
 
 			// Create repository with mock client
 			repo := &DynamoDBRepository{
-				client:    mockClient,
-				tableName: "test-events",
+				client:           mockClient,
+				tableName:        "test-events",
+				clientsTableName: "test-clients",
 			}
 
 			// Execute test
@@ -350,6 +445,824 @@ func TestGetClientConfig(t *testing.T) {
 	}
 }
 
+// TestPutClientConfig tests that client configurations are written as events-clients items
+func TestPutClientConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      *models.ClientConfig
+		mockClient  func(*MockDynamoDBClient)
+		expectError bool
+		errorMsg    string
+		description string
+	}{
+		{
+			name: "Full Client Config",
+			config: &models.ClientConfig{
+				ClientID:     "client-001",
+				AllowedTypes: []models.EventType{models.EventTypeMonitoring},
+				Config:       map[string]string{"tier": "gold"},
+				Active:       true,
+				Policies: map[models.EventType]models.EventPolicy{
+					models.EventTypeMonitoring: {Versions: []string{"1.0"}},
+				},
+				Limits: &models.ClientLimits{RatePerSecond: 5, DailyQuota: 1000},
+			},
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("PutItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.PutItemInput) bool {
+					allowedTypes, ok := input.Item["allowed_types"].(*types.AttributeValueMemberSS)
+					stored, err := clientConfigFromItem(input.Item)
+					return aws.ToString(input.TableName) == "events-clients" && ok && err == nil &&
+						assert.ObjectsAreEqual([]string{"monitoring"}, allowedTypes.Value) &&
+						assert.ObjectsAreEqual(stored, &models.ClientConfig{
+							ClientID:     "client-001",
+							AllowedTypes: []models.EventType{models.EventTypeMonitoring},
+							Config:       map[string]string{"tier": "gold"},
+							Active:       true,
+							Policies: map[models.EventType]models.EventPolicy{
+								models.EventTypeMonitoring: {Versions: []string{"1.0"}},
+							},
+							Limits: &models.ClientLimits{RatePerSecond: 5, DailyQuota: 1000},
+						})
+				})).Return(&dynamodb.PutItemOutput{}, nil)
+			},
+			expectError: false,
+			description: "Should write every field of the configuration",
+		},
+		{
+			name:   "No Allowed Types",
+			config: &models.ClientConfig{ClientID: "client-002", Active: false},
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("PutItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.PutItemInput) bool {
+					_, hasTypes := input.Item["allowed_types"]
+					_, hasPolicies := input.Item["policies"]
+					_, hasLimits := input.Item["limits"]
+					active, ok := input.Item["active"].(*types.AttributeValueMemberBOOL)
+					return !hasTypes && !hasPolicies && !hasLimits && ok && !active.Value
+				})).Return(&dynamodb.PutItemOutput{}, nil)
+			},
+			expectError: false,
+			description: "Should omit the allowed types, as DynamoDB string sets cannot be empty, and the missing policies and limits",
+		},
+		{
+			name:   "DynamoDB PutItem Failure",
+			config: &models.ClientConfig{ClientID: "client-001", Active: true},
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("PutItem", mock.Anything, mock.Anything).Return(nil, errors.New("dynamodb error"))
+			},
+			expectError: true,
+			errorMsg:    "failed to put client config client-001",
+			description: "Should fail when DynamoDB PutItem operation fails",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &MockDynamoDBClient{}
+			tt.mockClient(mockClient)
+			repo := &DynamoDBRepository{client: mockClient, tableName: "test-events", clientsTableName: "events-clients"}
+
+			err := repo.PutClientConfig(context.Background(), tt.config)
+
+			if tt.expectError {
+				assert.Error(t, err, tt.description)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+			} else {
+				assert.NoError(t, err, tt.description)
+			}
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+// TestListClientConfigs tests that every page of the clients table is read and sorted
+func TestListClientConfigs(t *testing.T) {
+	clientItem := func(clientID string) map[string]types.AttributeValue {
+		item, err := clientConfigItem(&models.ClientConfig{ClientID: clientID, Active: true})
+		require.NoError(t, err)
+		return item
+	}
+
+	mockClient := &MockDynamoDBClient{}
+	mockClient.On("Scan", mock.Anything, mock.MatchedBy(func(input *dynamodb.ScanInput) bool {
+		return aws.ToString(input.TableName) == "events-clients" && input.ExclusiveStartKey == nil
+	})).Return(&dynamodb.ScanOutput{
+		Items:            []map[string]types.AttributeValue{clientItem("client-003"), clientItem("client-001")},
+		LastEvaluatedKey: clientItem("client-001"),
+	}, nil).Once()
+	mockClient.On("Scan", mock.Anything, mock.MatchedBy(func(input *dynamodb.ScanInput) bool {
+		return input.ExclusiveStartKey != nil
+	})).Return(&dynamodb.ScanOutput{
+		Items: []map[string]types.AttributeValue{clientItem("client-002")},
+	}, nil).Once()
+
+	repo := &DynamoDBRepository{client: mockClient, tableName: "test-events", clientsTableName: "events-clients"}
+	configs, err := repo.ListClientConfigs(context.Background())
+	require.NoError(t, err)
+
+	var ids []string
+	for _, config := range configs {
+		ids = append(ids, config.ClientID)
+	}
+	assert.Equal(t, []string{"client-001", "client-002", "client-003"}, ids)
+	mockClient.AssertExpectations(t)
+
+	failing := &MockDynamoDBClient{}
+	failing.On("Scan", mock.Anything, mock.Anything).Return(nil, errors.New("dynamodb error"))
+	_, err = (&DynamoDBRepository{client: failing, clientsTableName: "events-clients"}).ListClientConfigs(context.Background())
+	assert.ErrorContains(t, err, "failed to list client configs")
+}
+
+// TestDeleteClientConfig tests deleting client configurations
+func TestDeleteClientConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		mockClient  func(*MockDynamoDBClient)
+		expectError error
+		description string
+	}{
+		{
+			name: "Existing Client",
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("DeleteItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.DeleteItemInput) bool {
+					key, ok := input.Key["client_id"].(*types.AttributeValueMemberS)
+					return aws.ToString(input.TableName) == "events-clients" && ok && key.Value == "client-001" &&
+						aws.ToString(input.ConditionExpression) == "attribute_exists(client_id)"
+				})).Return(&dynamodb.DeleteItemOutput{}, nil)
+			},
+			description: "Should delete the client item",
+		},
+		{
+			name: "Missing Client",
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("DeleteItem", mock.Anything, mock.Anything).
+					Return(nil, &types.ConditionalCheckFailedException{Message: aws.String("condition failed")})
+			},
+			expectError: ErrClientConfigNotFound,
+			description: "Should report a client that does not exist as not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &MockDynamoDBClient{}
+			tt.mockClient(mockClient)
+			repo := &DynamoDBRepository{client: mockClient, tableName: "test-events", clientsTableName: "events-clients"}
+
+			err := repo.DeleteClientConfig(context.Background(), "client-001")
+
+			if tt.expectError != nil {
+				assert.ErrorIs(t, err, tt.expectError, tt.description)
+			} else {
+				assert.NoError(t, err, tt.description)
+			}
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+// TestCreateClientConfig tests that client configurations are only created for new clients
+func TestCreateClientConfig(t *testing.T) {
+	mockClient := &MockDynamoDBClient{}
+	mockClient.On("PutItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.PutItemInput) bool {
+		return aws.ToString(input.TableName) == "events-clients" &&
+			aws.ToString(input.ConditionExpression) == "attribute_not_exists(client_id)"
+	})).Return(&dynamodb.PutItemOutput{}, nil).Once()
+	mockClient.On("PutItem", mock.Anything, mock.Anything).
+		Return(nil, &types.ConditionalCheckFailedException{Message: aws.String("condition failed")})
+
+	repo := &DynamoDBRepository{client: mockClient, clientsTableName: "events-clients"}
+	config := &models.ClientConfig{ClientID: "client-001", Active: true}
+	assert.NoError(t, repo.CreateClientConfig(context.Background(), config))
+	assert.ErrorIs(t, repo.CreateClientConfig(context.Background(), config), ErrClientConfigExists)
+	mockClient.AssertExpectations(t)
+}
+
+// TestListClientChanges tests that the changes of one client are queried, those of every client
+// scanned, and both returned in the order they were made
+func TestListClientChanges(t *testing.T) {
+	base := time.Date(2024, 3, 1, 12, 0, 0, 123456789, time.UTC)
+	changeItem := func(clientID, actor string, changedAt time.Time) map[string]types.AttributeValue {
+		item, err := clientChangeItem(models.ClientChange{
+			Time:     changedAt,
+			Actor:    actor,
+			Action:   models.ClientActionUpdate,
+			ClientID: clientID,
+			Before:   &models.ClientConfig{ClientID: clientID, Active: true},
+			After:    &models.ClientConfig{ClientID: clientID},
+		})
+		require.NoError(t, err)
+		return item
+	}
+
+	mockClient := &MockDynamoDBClient{}
+	mockClient.On("Query", mock.Anything, mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		clientID, ok := input.ExpressionAttributeValues[":client_id"].(*types.AttributeValueMemberS)
+		return aws.ToString(input.TableName) == "events-client-audit" && ok && clientID.Value == "client-001" &&
+			input.ExclusiveStartKey == nil
+	})).Return(&dynamodb.QueryOutput{
+		Items:            []map[string]types.AttributeValue{changeItem("client-001", "alice", base)},
+		LastEvaluatedKey: map[string]types.AttributeValue{"client_id": &types.AttributeValueMemberS{Value: "client-001"}},
+	}, nil).Once()
+	mockClient.On("Query", mock.Anything, mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		return input.ExclusiveStartKey != nil
+	})).Return(&dynamodb.QueryOutput{
+		Items: []map[string]types.AttributeValue{changeItem("client-001", "bob", base.Add(time.Second))},
+	}, nil).Once()
+	mockClient.On("Scan", mock.Anything, mock.MatchedBy(func(input *dynamodb.ScanInput) bool {
+		return aws.ToString(input.TableName) == "events-client-audit"
+	})).Return(&dynamodb.ScanOutput{
+		Items: []map[string]types.AttributeValue{
+			changeItem("client-002", "carol", base.Add(time.Minute)),
+			changeItem("client-001", "alice", base),
+		},
+	}, nil).Once()
+
+	repo := &DynamoDBRepository{client: mockClient, clientAuditTableName: "events-client-audit"}
+	changes, err := repo.ListClientChanges(context.Background(), "client-001")
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	assert.Equal(t, "alice", changes[0].Actor)
+	assert.Equal(t, base, changes[0].Time)
+	assert.Equal(t, &models.ClientConfig{ClientID: "client-001", Active: true}, changes[0].Before)
+	assert.Equal(t, "bob", changes[1].Actor)
+
+	all, err := repo.ListClientChanges(context.Background(), "")
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, "alice", all[0].Actor)
+	assert.Equal(t, "carol", all[1].Actor)
+	mockClient.AssertExpectations(t)
+
+	failing := &MockDynamoDBClient{}
+	failing.On("PutItem", mock.Anything, mock.Anything).Return(nil, errors.New("dynamodb error"))
+	err = (&DynamoDBRepository{client: failing, clientAuditTableName: "events-client-audit"}).RecordClientChange(context.Background(), models.ClientChange{ClientID: "client-001"})
+	assert.ErrorContains(t, err, "failed to record change to client client-001")
+}
+
+// TestQuarantineItemRoundTrip tests that quarantined events survive conversion to items and back
+func TestQuarantineItemRoundTrip(t *testing.T) {
+	quarantinedAt := time.Date(2024, 3, 1, 12, 0, 0, 123456789, time.UTC)
+	tests := []struct {
+		name        string
+		event       *models.QuarantinedEvent
+		missing     []string
+		description string
+	}{
+		{
+			name:        "Complete Event",
+			event:       contractQuarantinedEvent("q-1", "client-001", models.QuarantineAuthorization, quarantinedAt),
+			description: "Should keep every field, including sub-second quarantine times",
+		},
+		{
+			name: "Unparseable Body",
+			event: &models.QuarantinedEvent{
+				QuarantineID:  "q-2",
+				Category:      models.QuarantineValidation,
+				Errors:        []string{},
+				Body:          "{not json",
+				QuarantinedAt: quarantinedAt,
+			},
+			missing:     []string{"client_id", "event_id", "event_type"},
+			description: "Should omit the fields a body without them cannot provide",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := quarantineItem(tt.event)
+			for _, name := range tt.missing {
+				assert.NotContains(t, item, name, tt.description)
+			}
+
+			event, err := quarantinedEventFromItem(item)
+			require.NoError(t, err)
+			assert.Equal(t, tt.event, event, tt.description)
+		})
+	}
+}
+
+// TestListQuarantinedEvents tests filtering, paging and ordering of quarantined events
+func TestListQuarantinedEvents(t *testing.T) {
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	mockClient := &MockDynamoDBClient{}
+	mockClient.On("Scan", mock.Anything, mock.MatchedBy(func(input *dynamodb.ScanInput) bool {
+		return aws.ToString(input.TableName) == "events-quarantine" && input.ExclusiveStartKey == nil &&
+			aws.ToString(input.FilterExpression) == "client_id = :client_id AND category = :category"
+	})).Return(&dynamodb.ScanOutput{
+		Items: []map[string]types.AttributeValue{
+			quarantineItem(contractQuarantinedEvent("q-1", "client-001", models.QuarantinePayload, base)),
+		},
+		LastEvaluatedKey: map[string]types.AttributeValue{"quarantine_id": &types.AttributeValueMemberS{Value: "q-1"}},
+	}, nil).Once()
+	mockClient.On("Scan", mock.Anything, mock.MatchedBy(func(input *dynamodb.ScanInput) bool {
+		return input.ExclusiveStartKey != nil
+	})).Return(&dynamodb.ScanOutput{
+		Items: []map[string]types.AttributeValue{
+			quarantineItem(contractQuarantinedEvent("q-2", "client-001", models.QuarantinePayload, base.Add(time.Minute))),
+		},
+	}, nil).Once()
+
+	repo := &DynamoDBRepository{client: mockClient, quarantineTableName: "events-quarantine"}
+	events, err := repo.ListQuarantinedEvents(context.Background(), QuarantineFilter{ClientID: "client-001", Category: models.QuarantinePayload})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"q-2", "q-1"}, quarantineIDs(events))
+	mockClient.AssertExpectations(t)
+
+	failing := &MockDynamoDBClient{}
+	failing.On("Scan", mock.Anything, mock.Anything).Return(nil, errors.New("dynamodb error"))
+	_, err = (&DynamoDBRepository{client: failing, quarantineTableName: "events-quarantine"}).ListQuarantinedEvents(context.Background(), QuarantineFilter{})
+	assert.ErrorContains(t, err, "failed to list quarantined events")
+}
+
+// TestDeleteQuarantinedEvent tests discarding quarantined events
+func TestDeleteQuarantinedEvent(t *testing.T) {
+	mockClient := &MockDynamoDBClient{}
+	mockClient.On("DeleteItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.DeleteItemInput) bool {
+		key, ok := input.Key["quarantine_id"].(*types.AttributeValueMemberS)
+		return aws.ToString(input.TableName) == "events-quarantine" && ok && key.Value == "q-1"
+	})).Return(&dynamodb.DeleteItemOutput{}, nil).Once()
+	mockClient.On("DeleteItem", mock.Anything, mock.Anything).
+		Return(nil, &types.ConditionalCheckFailedException{Message: aws.String("condition failed")})
+
+	repo := &DynamoDBRepository{client: mockClient, quarantineTableName: "events-quarantine"}
+	assert.NoError(t, repo.DeleteQuarantinedEvent(context.Background(), "q-1"))
+	assert.ErrorIs(t, repo.DeleteQuarantinedEvent(context.Background(), "q-1"), ErrQuarantinedEventNotFound)
+	mockClient.AssertExpectations(t)
+}
+
+// TestGetEvent tests the GetEvent method
+func TestGetEvent(t *testing.T) {
+	stored := createValidProcessedEvent()
+	stored.Timestamp = stored.Timestamp.Truncate(time.Second)
+	stored.ProcessedAt = stored.ProcessedAt.Truncate(time.Second)
+	stored.DerivedFields = []string{"highValue"}
+
+	tests := []getEventTestCase{
+		{
+			name:    "Existing Event",
+			eventID: stored.EventID,
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("GetItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.GetItemInput) bool {
+					key, ok := input.Key["event_id"].(*types.AttributeValueMemberS)
+					return aws.ToString(input.TableName) == "test-events" && ok && key.Value == stored.EventID
+				})).Return(&dynamodb.GetItemOutput{Item: createStoredItem(t, stored)}, nil)
+			},
+			expectError:   false,
+			expectedEvent: stored,
+			description:   "Should unmarshal the stored item back into a ProcessedEvent",
+		},
+		{
+			name:    "Event Not Found",
+			eventID: "missing",
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("GetItem", mock.Anything, mock.AnythingOfType("*dynamodb.GetItemInput")).Return(&dynamodb.GetItemOutput{}, nil)
+			},
+			expectError: true,
+			errorMsg:    "event not found",
+			description: "Should return ErrEventNotFound when the item does not exist",
+		},
+		{
+			name:    "DynamoDB GetItem Failure",
+			eventID: stored.EventID,
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("GetItem", mock.Anything, mock.AnythingOfType("*dynamodb.GetItemInput")).Return(nil, errors.New("dynamodb error"))
+			},
+			expectError: true,
+			errorMsg:    "failed to get event",
+			description: "Should fail when DynamoDB GetItem operation fails",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &MockDynamoDBClient{}
+			tt.mockClient(mockClient)
+
+			repo := &DynamoDBRepository{
+				client:    mockClient,
+				tableName: "test-events",
+			}
+
+			result, err := repo.GetEvent(context.Background(), tt.eventID)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				assert.Nil(t, result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedEvent.EventID, result.EventID)
+				assert.Equal(t, tt.expectedEvent.EventType, result.EventType)
+				assert.Equal(t, tt.expectedEvent.ClientID, result.ClientID)
+				assert.Equal(t, tt.expectedEvent.Status, result.Status)
+				assert.Equal(t, tt.expectedEvent.TTL, result.TTL)
+				assert.True(t, tt.expectedEvent.Timestamp.Equal(result.Timestamp))
+				assert.True(t, tt.expectedEvent.ProcessedAt.Equal(result.ProcessedAt))
+				assert.Equal(t, tt.expectedEvent.Payload, result.Payload)
+			}
+
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+// revisionConditionHolds evaluates the revision part of a condition expression against an item the
+// way DynamoDB does: an item without a revision attribute only matches attribute_not_exists(revision),
+// and an item with one only matches revision = :expected at that revision. Like DynamoDB, it rejects
+// values the condition does not use.
+func revisionConditionHolds(condition string, values map[string]types.AttributeValue, item map[string]types.AttributeValue) bool {
+	expected, hasExpected := values[":expected"].(*types.AttributeValueMemberN)
+	if strings.HasSuffix(condition, "attribute_not_exists(revision)") {
+		_, hasRevision := item["revision"]
+		return !hasExpected && !hasRevision
+	}
+	revision, ok := item["revision"].(*types.AttributeValueMemberN)
+	return ok && hasExpected && strings.HasSuffix(condition, "revision = :expected") && expected.Value == revision.Value
+}
+
+// TestUpdateEventStatus tests the conditional UpdateEventStatus method
+func TestUpdateEventStatus(t *testing.T) {
+	stored := createValidProcessedEvent()
+	stored.Status = models.EventStatusFailed
+	stored.Revision = 2
+
+	updated := *stored
+	updated.Status = models.EventStatusRetrying
+	updated.Revision = 3
+	updated.ErrorMsg = "manual retry"
+
+	legacy := createValidProcessedEvent()
+	legacy.Revision = 0
+
+	reprocessed := *legacy
+	reprocessed.Status = models.EventStatusReprocessed
+	reprocessed.Revision = 1
+
+	tests := []struct {
+		name        string
+		update      StatusUpdate
+		mockClient  func(*MockDynamoDBClient)
+		expectError error
+		errorMsg    string
+		description string
+	}{
+		{
+			name:   "Successful Transition",
+			update: StatusUpdate{Status: models.EventStatusRetrying, ExpectedRevision: 2, ErrorMsg: "manual retry"},
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("GetItem", mock.Anything, mock.AnythingOfType("*dynamodb.GetItemInput")).Return(&dynamodb.GetItemOutput{Item: createStoredItem(t, stored)}, nil)
+				mc.On("UpdateItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.UpdateItemInput) bool {
+					expected, ok := input.ExpressionAttributeValues[":expected"].(*types.AttributeValueMemberN)
+					revision := input.ExpressionAttributeValues[":revision"].(*types.AttributeValueMemberN)
+					return aws.ToString(input.ConditionExpression) == "#status = :from AND revision = :expected" &&
+						ok && expected.Value == "2" && revision.Value == "3" &&
+						input.ReturnValues == types.ReturnValueAllNew
+				})).Return(&dynamodb.UpdateItemOutput{Attributes: createStoredItem(t, &updated)}, nil)
+			},
+			description: "Should update the status conditioned on the current status and revision",
+		},
+		{
+			name:   "Item Without Revision",
+			update: StatusUpdate{Status: models.EventStatusReprocessed},
+			mockClient: func(mc *MockDynamoDBClient) {
+				item := createStoredItem(t, legacy)
+				delete(item, "revision")
+				mc.On("GetItem", mock.Anything, mock.AnythingOfType("*dynamodb.GetItemInput")).Return(&dynamodb.GetItemOutput{Item: item}, nil)
+				mc.On("UpdateItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.UpdateItemInput) bool {
+					return revisionConditionHolds(aws.ToString(input.ConditionExpression), input.ExpressionAttributeValues, item) &&
+						strings.Contains(aws.ToString(input.UpdateExpression), "REMOVE error_msg")
+				})).Return(&dynamodb.UpdateItemOutput{Attributes: createStoredItem(t, &reprocessed)}, nil)
+			},
+			description: "Should accept items written before revisions were tracked",
+		},
+		{
+			name:   "Condition Check Failure",
+			update: StatusUpdate{Status: models.EventStatusRetrying, ExpectedRevision: 2},
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("GetItem", mock.Anything, mock.AnythingOfType("*dynamodb.GetItemInput")).Return(&dynamodb.GetItemOutput{Item: createStoredItem(t, stored)}, nil)
+				mc.On("UpdateItem", mock.Anything, mock.AnythingOfType("*dynamodb.UpdateItemInput")).Return(nil, &types.ConditionalCheckFailedException{})
+			},
+			expectError: ErrRevisionConflict,
+			description: "Should report a revision conflict when another writer got there first",
+		},
+		{
+			name:   "Stale Revision",
+			update: StatusUpdate{Status: models.EventStatusRetrying, ExpectedRevision: 1},
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("GetItem", mock.Anything, mock.AnythingOfType("*dynamodb.GetItemInput")).Return(&dynamodb.GetItemOutput{Item: createStoredItem(t, stored)}, nil)
+			},
+			expectError: ErrRevisionConflict,
+			description: "Should not call UpdateItem when the revision is already known to be stale",
+		},
+		{
+			name:   "Invalid Transition",
+			update: StatusUpdate{Status: models.EventStatusProcessed, ExpectedRevision: 2},
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("GetItem", mock.Anything, mock.AnythingOfType("*dynamodb.GetItemInput")).Return(&dynamodb.GetItemOutput{Item: createStoredItem(t, stored)}, nil)
+			},
+			expectError: ErrInvalidTransition,
+			description: "Should reject transitions outside the lifecycle",
+		},
+		{
+			name:   "DynamoDB UpdateItem Failure",
+			update: StatusUpdate{Status: models.EventStatusRetrying, ExpectedRevision: 2},
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("GetItem", mock.Anything, mock.AnythingOfType("*dynamodb.GetItemInput")).Return(&dynamodb.GetItemOutput{Item: createStoredItem(t, stored)}, nil)
+				mc.On("UpdateItem", mock.Anything, mock.AnythingOfType("*dynamodb.UpdateItemInput")).Return(nil, errors.New("dynamodb error"))
+			},
+			errorMsg:    "failed to update event status",
+			description: "Should fail when DynamoDB UpdateItem operation fails",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &MockDynamoDBClient{}
+			tt.mockClient(mockClient)
+
+			repo := &DynamoDBRepository{
+				client:    mockClient,
+				tableName: "test-events",
+			}
+
+			result, err := repo.UpdateEventStatus(context.Background(), stored.EventID, tt.update)
+
+			switch {
+			case tt.expectError != nil:
+				assert.ErrorIs(t, err, tt.expectError)
+				assert.Nil(t, result)
+			case tt.errorMsg != "":
+				assert.ErrorContains(t, err, tt.errorMsg)
+				assert.Nil(t, result)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, tt.update.Status, result.Status)
+				assert.Equal(t, tt.update.ErrorMsg, result.ErrorMsg)
+			}
+
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+// TestReplaceEvent tests the ReplaceEvent method
+func TestReplaceEvent(t *testing.T) {
+	stored := createValidProcessedEvent()
+	stored.Revision = 2
+
+	replaced := *stored
+	replaced.Status = models.EventStatusReprocessed
+	replaced.Revision = 3
+	replaced.RecordReplay(models.ReplayRecord{RunID: "run-1", PreviousStatus: models.EventStatusProcessed, Status: models.EventStatusReprocessed})
+
+	legacy := createValidProcessedEvent()
+	legacy.Revision = 0
+
+	tests := []struct {
+		name             string
+		expectedRevision int64
+		mockClient       func(*MockDynamoDBClient)
+		expectError      error
+		errorMsg         string
+		description      string
+	}{
+		{
+			name:             "Successful Replace",
+			expectedRevision: 2,
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("PutItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.PutItemInput) bool {
+					expected, ok := input.ExpressionAttributeValues[":expected"].(*types.AttributeValueMemberN)
+					replays, hasReplays := input.Item["replays"].(*types.AttributeValueMemberS)
+					return aws.ToString(input.ConditionExpression) == "attribute_exists(event_id) AND revision = :expected" &&
+						ok && expected.Value == "2" && hasReplays && strings.Contains(replays.Value, `"runId":"run-1"`)
+				})).Return(&dynamodb.PutItemOutput{}, nil)
+			},
+			description: "Should put the event conditioned on the expected revision",
+		},
+		{
+			name:             "Item Without Revision",
+			expectedRevision: 0,
+			mockClient: func(mc *MockDynamoDBClient) {
+				item := createStoredItem(t, legacy)
+				delete(item, "revision")
+				mc.On("PutItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.PutItemInput) bool {
+					return strings.HasPrefix(aws.ToString(input.ConditionExpression), "attribute_exists(event_id) AND ") &&
+						revisionConditionHolds(aws.ToString(input.ConditionExpression), input.ExpressionAttributeValues, item)
+				})).Return(&dynamodb.PutItemOutput{}, nil)
+			},
+			description: "Should accept items written before revisions were tracked",
+		},
+		{
+			name:             "Concurrent Modification",
+			expectedRevision: 2,
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("PutItem", mock.Anything, mock.Anything).Return(nil, &types.ConditionalCheckFailedException{})
+				mc.On("GetItem", mock.Anything, mock.AnythingOfType("*dynamodb.GetItemInput")).Return(&dynamodb.GetItemOutput{Item: createStoredItem(t, stored)}, nil)
+			},
+			expectError: ErrRevisionConflict,
+			description: "Should report a revision conflict when another writer got there first",
+		},
+		{
+			name:             "Missing Event",
+			expectedRevision: 2,
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("PutItem", mock.Anything, mock.Anything).Return(nil, &types.ConditionalCheckFailedException{})
+				mc.On("GetItem", mock.Anything, mock.AnythingOfType("*dynamodb.GetItemInput")).Return(&dynamodb.GetItemOutput{}, nil)
+			},
+			expectError: ErrEventNotFound,
+			description: "Should not create events that were deleted",
+		},
+		{
+			name:             "DynamoDB PutItem Failure",
+			expectedRevision: 2,
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("PutItem", mock.Anything, mock.Anything).Return(nil, errors.New("dynamodb error"))
+			},
+			errorMsg:    "failed to replace event",
+			description: "Should fail when DynamoDB PutItem operation fails",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &MockDynamoDBClient{}
+			tt.mockClient(mockClient)
+
+			repo := &DynamoDBRepository{
+				client:    mockClient,
+				tableName: "test-events",
+			}
+
+			err := repo.ReplaceEvent(context.Background(), &replaced, tt.expectedRevision)
+
+			switch {
+			case tt.expectError != nil:
+				assert.ErrorIs(t, err, tt.expectError, tt.description)
+			case tt.errorMsg != "":
+				assert.ErrorContains(t, err, tt.errorMsg, tt.description)
+			default:
+				assert.NoError(t, err, tt.description)
+			}
+
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+// TestListEventsByClient tests the ListEventsByClient method
+func TestListEventsByClient(t *testing.T) {
+	stored := createValidProcessedEvent()
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []listEventsTestCase{
+		{
+			name: "First Page With More Results",
+			opts: ListOptions{Limit: 1},
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("Query", mock.Anything, mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+					return aws.ToString(input.IndexName) == "client_id_index" &&
+						aws.ToInt32(input.Limit) == 1 &&
+						input.FilterExpression == nil &&
+						input.ExclusiveStartKey == nil
+				})).Return(&dynamodb.QueryOutput{
+					Items: []map[string]types.AttributeValue{createStoredItem(t, stored)},
+					LastEvaluatedKey: map[string]types.AttributeValue{
+						"event_id":  &types.AttributeValueMemberS{Value: stored.EventID},
+						"client_id": &types.AttributeValueMemberS{Value: stored.ClientID},
+					},
+				}, nil)
+			},
+			expectedCount:  1,
+			expectedCursor: true,
+			description:    "Should return a cursor when DynamoDB reports more results",
+		},
+		{
+			name: "Time Range Filter",
+			opts: ListOptions{From: from, To: to},
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("Query", mock.Anything, mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+					fromValue, _ := input.ExpressionAttributeValues[":from"].(*types.AttributeValueMemberS)
+					return aws.ToString(input.FilterExpression) == "#ts BETWEEN :from AND :to" &&
+						aws.ToInt32(input.Limit) == DefaultPageSize &&
+						fromValue != nil && fromValue.Value == "2025-01-01T00:00:00Z"
+				})).Return(&dynamodb.QueryOutput{}, nil)
+			},
+			expectedCount:  0,
+			expectedCursor: false,
+			description:    "Should filter on the timestamp attribute",
+		},
+		{
+			name: "Resume From Cursor",
+			opts: ListOptions{Cursor: mustEncodeCursor(t, map[string]types.AttributeValue{
+				"event_id":  &types.AttributeValueMemberS{Value: "evt-1"},
+				"client_id": &types.AttributeValueMemberS{Value: "client-001"},
+			})},
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("Query", mock.Anything, mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+					key, ok := input.ExclusiveStartKey["event_id"].(*types.AttributeValueMemberS)
+					return ok && key.Value == "evt-1"
+				})).Return(&dynamodb.QueryOutput{}, nil)
+			},
+			expectedCount: 0,
+			description:   "Should resume the query from the decoded cursor",
+		},
+		{
+			name:        "Invalid Cursor",
+			opts:        ListOptions{Cursor: "not a cursor"},
+			mockClient:  func(mc *MockDynamoDBClient) {},
+			expectError: true,
+			errorMsg:    "invalid pagination cursor",
+			description: "Should reject cursors that cannot be decoded",
+		},
+		{
+			name: "DynamoDB Query Failure",
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("Query", mock.Anything, mock.AnythingOfType("*dynamodb.QueryInput")).Return(nil, errors.New("dynamodb error"))
+			},
+			expectError: true,
+			errorMsg:    "failed to query client_id_index",
+			description: "Should fail when DynamoDB Query operation fails",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &MockDynamoDBClient{}
+			tt.mockClient(mockClient)
+
+			repo := &DynamoDBRepository{
+				client:    mockClient,
+				tableName: "test-events",
+			}
+
+			page, err := repo.ListEventsByClient(context.Background(), "client-001", tt.opts)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, page.Events, tt.expectedCount)
+				assert.Equal(t, tt.expectedCursor, page.NextCursor != "")
+			}
+
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+// TestListEventsByStatus tests the ListEventsByStatus method
+func TestListEventsByStatus(t *testing.T) {
+	mockClient := &MockDynamoDBClient{}
+	mockClient.On("Query", mock.Anything, mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		key, _ := input.ExpressionAttributeValues[":key"].(*types.AttributeValueMemberS)
+		return aws.ToString(input.IndexName) == "status_index" &&
+			input.ExpressionAttributeNames["#key"] == "status" &&
+			key != nil && key.Value == "failed"
+	})).Return(&dynamodb.QueryOutput{
+		Items: []map[string]types.AttributeValue{createStoredItem(t, createProcessedEventWithError())},
+	}, nil)
+
+	repo := &DynamoDBRepository{
+		client:    mockClient,
+		tableName: "test-events",
+	}
+
+	page, err := repo.ListEventsByStatus(context.Background(), models.EventStatusFailed, ListOptions{})
+
+	assert.NoError(t, err)
+	assert.Len(t, page.Events, 1)
+	assert.Equal(t, models.EventStatusFailed, page.Events[0].Status)
+	assert.Equal(t, "Processing failed due to validation error", page.Events[0].ErrorMsg)
+	assert.Empty(t, page.NextCursor)
+	mockClient.AssertExpectations(t)
+}
+
+// TestCursorRoundTrip tests that cursors decode back into the original key
+func TestCursorRoundTrip(t *testing.T) {
+	key := map[string]types.AttributeValue{
+		"event_id": &types.AttributeValueMemberS{Value: "evt-1"},
+		"status":   &types.AttributeValueMemberS{Value: "processed"},
+	}
+
+	cursor := mustEncodeCursor(t, key)
+	decoded, err := decodeCursor(cursor)
+
+	assert.NoError(t, err)
+	assert.Equal(t, key, decoded)
+
+	_, err = decodeCursor(base64.RawURLEncoding.EncodeToString([]byte("{}")))
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+// TestListOptionsPageSize tests the PageSize method
+func TestListOptionsPageSize(t *testing.T) {
+	assert.Equal(t, DefaultPageSize, ListOptions{}.PageSize())
+	assert.Equal(t, 10, ListOptions{Limit: 10}.PageSize())
+	assert.Equal(t, MaxPageSize, ListOptions{Limit: MaxPageSize + 1}.PageSize())
+}
+
 // TestHealthCheck tests the HealthCheck method
 func TestHealthCheck(t *testing.T) {
 	tests := []healthCheckTestCase{
@@ -454,9 +1367,49 @@ func TestMarshalPayload(t *testing.T) {
 				"tags": []string{"tag1", "tag2", "tag3"},
 			},
 			expectedResult: map[string]types.AttributeValue{
-				"tags": &types.AttributeValueMemberSS{Value: []string{"tag1", "tag2", "tag3"}},
+				"tags": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+					&types.AttributeValueMemberS{Value: "tag1"},
+					&types.AttributeValueMemberS{Value: "tag2"},
+					&types.AttributeValueMemberS{Value: "tag3"},
+				}},
 			},
-			description: "Should correctly marshal string slice values",
+			description: "Should marshal string slices as ordered lists",
+		},
+		{
+			name: "JSON Array Values",
+			payload: map[string]interface{}{
+				"items": []interface{}{
+					"a",
+					1.5,
+					nil,
+					map[string]interface{}{"id": "x"},
+					[]interface{}{},
+				},
+			},
+			expectedResult: map[string]types.AttributeValue{
+				"items": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+					&types.AttributeValueMemberS{Value: "a"},
+					&types.AttributeValueMemberN{Value: "1.5"},
+					&types.AttributeValueMemberNULL{Value: true},
+					&types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+						"id": &types.AttributeValueMemberS{Value: "x"},
+					}},
+					&types.AttributeValueMemberL{Value: []types.AttributeValue{}},
+				}},
+			},
+			description: "Should marshal JSON arrays, including nested maps and empty arrays, as lists",
+		},
+		{
+			name: "Null And Big Number Values",
+			payload: map[string]interface{}{
+				"missing": nil,
+				"big":     json.Number("123456789012345678901234567890"),
+			},
+			expectedResult: map[string]types.AttributeValue{
+				"missing": &types.AttributeValueMemberNULL{Value: true},
+				"big":     &types.AttributeValueMemberN{Value: "123456789012345678901234567890"},
+			},
+			description: "Should marshal nulls as NULL and keep big numbers verbatim",
 		},
 		{
 			name: "Nested Map Values",
@@ -493,7 +1446,10 @@ func TestMarshalPayload(t *testing.T) {
 					"id":     &types.AttributeValueMemberN{Value: "123"},
 					"name":   &types.AttributeValueMemberS{Value: "John Doe"},
 					"active": &types.AttributeValueMemberBOOL{Value: true},
-					"roles":  &types.AttributeValueMemberSS{Value: []string{"admin", "user"}},
+					"roles": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+						&types.AttributeValueMemberS{Value: "admin"},
+						&types.AttributeValueMemberS{Value: "user"},
+					}},
 					"settings": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
 						"theme": &types.AttributeValueMemberS{Value: "dark"},
 						"lang":  &types.AttributeValueMemberS{Value: "en"},
@@ -505,12 +1461,16 @@ func TestMarshalPayload(t *testing.T) {
 		{
 			name: "Unknown Type Conversion",
 			payload: map[string]interface{}{
-				"custom": struct{ value string }{"test"},
+				"custom": struct {
+					Value string `json:"value"`
+				}{"test"},
 			},
 			expectedResult: map[string]types.AttributeValue{
-				"custom": &types.AttributeValueMemberS{Value: "{test}"},
+				"custom": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+					"value": &types.AttributeValueMemberS{Value: "test"},
+				}},
 			},
-			description: "Should convert unknown types to string",
+			description: "Should convert unknown types through their JSON representation",
 		},
 		{
 			name:           "Empty Payload",
@@ -526,9 +1486,10 @@ func TestMarshalPayload(t *testing.T) {
 			repo := &DynamoDBRepository{}
 
 			// Execute test
-			result := repo.marshalPayload(tt.payload)
+			result, err := repo.marshalPayload(tt.payload)
 
 			// Assertions
+			assert.NoError(t, err)
 			assert.Equal(t, len(tt.expectedResult), len(result))
 
 			for key, expectedValue := range tt.expectedResult {
@@ -563,11 +1524,20 @@ func TestMarshalPayload(t *testing.T) {
 					}
 				case *types.AttributeValueMemberM:
 					if actual, ok := actualValue.(*types.AttributeValueMemberM); ok {
-						// For nested maps, we'll just check that they exist
-						assert.NotNil(t, actual.Value)
+						assert.Equal(t, expected.Value, actual.Value)
 					} else {
 						t.Errorf("Expected map value for key %s, got %T", key, actualValue)
 					}
+				case *types.AttributeValueMemberL:
+					if actual, ok := actualValue.(*types.AttributeValueMemberL); ok {
+						assert.Equal(t, expected.Value, actual.Value)
+					} else {
+						t.Errorf("Expected list value for key %s, got %T", key, actualValue)
+					}
+				case *types.AttributeValueMemberNULL:
+					if _, ok := actualValue.(*types.AttributeValueMemberNULL); !ok {
+						t.Errorf("Expected null value for key %s, got %T", key, actualValue)
+					}
 				}
 			}
 		})
@@ -618,6 +1588,29 @@ func createValidProcessedEvent() *models.ProcessedEvent {
 	}
 }
 
+// createStoredItem captures the item SaveEvent writes for an event
+func createStoredItem(t *testing.T, event *models.ProcessedEvent) map[string]types.AttributeValue {
+	mockClient := &MockDynamoDBClient{}
+	var item map[string]types.AttributeValue
+	mockClient.On("PutItem", mock.Anything, mock.AnythingOfType("*dynamodb.PutItemInput")).Run(func(args mock.Arguments) {
+		item = args.Get(1).(*dynamodb.PutItemInput).Item
+	}).Return(&dynamodb.PutItemOutput{}, nil)
+
+	repo := &DynamoDBRepository{client: mockClient, tableName: "test-events", clientsTableName: "events-clients"}
+	if err := repo.SaveEvent(context.Background(), event); err != nil {
+		t.Fatalf("failed to build stored item: %v", err)
+	}
+	return item
+}
+
+func mustEncodeCursor(t *testing.T, key map[string]types.AttributeValue) string {
+	cursor, err := encodeCursor(key)
+	if err != nil {
+		t.Fatalf("failed to encode cursor: %v", err)
+	}
+	return cursor
+}
+
 func createProcessedEventWithError() *models.ProcessedEvent {
 	event := createValidProcessedEvent()
 	event.Status = models.EventStatusFailed
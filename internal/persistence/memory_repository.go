@@ -0,0 +1,358 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// MemoryRepository implements Repository interface in memory, for development and tests
+type MemoryRepository struct {
+	mu      sync.RWMutex
+	events  map[string]*models.ProcessedEvent
+	clients map[string]*models.ClientConfig
+
+	// quarantine holds rejected events by quarantine ID
+	quarantine map[string]*models.QuarantinedEvent
+
+	// changes holds the history of client configuration changes in the order they were recorded
+	changes []models.ClientChange
+}
+
+// NewMemoryRepository creates an empty in-memory repository seeded with the given client configurations
+func NewMemoryRepository(clients ...*models.ClientConfig) *MemoryRepository {
+	repo := &MemoryRepository{
+		events:     make(map[string]*models.ProcessedEvent),
+		clients:    make(map[string]*models.ClientConfig),
+		quarantine: make(map[string]*models.QuarantinedEvent),
+	}
+	for _, client := range clients {
+		repo.PutClientConfig(context.Background(), client)
+	}
+	return repo
+}
+
+// SaveEvent stores a copy of the event, failing with ErrEventExists if an event with the same ID is stored
+func (r *MemoryRepository) SaveEvent(ctx context.Context, event *models.ProcessedEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.events[event.EventID]; ok {
+		return fmt.Errorf("%w: %s", ErrEventExists, event.EventID)
+	}
+	r.events[event.EventID] = copyEvent(event)
+	return nil
+}
+
+// GetEvent retrieves a single event by its ID
+func (r *MemoryRepository) GetEvent(ctx context.Context, eventID string) (*models.ProcessedEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	event, ok := r.events[eventID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrEventNotFound, eventID)
+	}
+	return copyEvent(event), nil
+}
+
+// UpdateEventStatus changes the status of an event if it is still at the expected revision
+func (r *MemoryRepository) UpdateEventStatus(ctx context.Context, eventID string, update StatusUpdate) (*models.ProcessedEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event, ok := r.events[eventID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrEventNotFound, eventID)
+	}
+	if err := checkStatusUpdate(event, update); err != nil {
+		return nil, err
+	}
+
+	event.Status = update.Status
+	event.ErrorMsg = update.ErrorMsg
+	event.Revision++
+	return copyEvent(event), nil
+}
+
+// ReplaceEvent replaces an event if it is still at the expected revision
+func (r *MemoryRepository) ReplaceEvent(ctx context.Context, event *models.ProcessedEvent, expectedRevision int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, ok := r.events[event.EventID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrEventNotFound, event.EventID)
+	}
+	if current.Revision != expectedRevision {
+		return fmt.Errorf("%w: event %s is at revision %d, expected %d", ErrRevisionConflict, event.EventID, current.Revision, expectedRevision)
+	}
+
+	r.events[event.EventID] = copyEvent(event)
+	return nil
+}
+
+// ListEventsByClient lists the events of a client ordered by timestamp
+func (r *MemoryRepository) ListEventsByClient(ctx context.Context, clientID string, opts ListOptions) (*EventPage, error) {
+	return r.listEvents(func(event *models.ProcessedEvent) bool {
+		return event.ClientID == clientID
+	}, opts)
+}
+
+// ListEventsByStatus lists the events with a status ordered by timestamp
+func (r *MemoryRepository) ListEventsByStatus(ctx context.Context, status models.EventStatus, opts ListOptions) (*EventPage, error) {
+	return r.listEvents(func(event *models.ProcessedEvent) bool {
+		return event.Status == status
+	}, opts)
+}
+
+// listEvents returns a page of matching events in (timestamp, event ID) order
+func (r *MemoryRepository) listEvents(match func(*models.ProcessedEvent) bool, opts ListOptions) (*EventPage, error) {
+	var (
+		hasCursor      bool
+		afterTimestamp time.Time
+		afterID        string
+	)
+	if opts.Cursor != "" {
+		var err error
+		if afterTimestamp, afterID, err = decodeKeysetCursor(opts.Cursor); err != nil {
+			return nil, err
+		}
+		hasCursor = true
+	}
+
+	r.mu.RLock()
+	matches := make([]*models.ProcessedEvent, 0)
+	for _, event := range r.events {
+		if !match(event) {
+			continue
+		}
+		if !opts.From.IsZero() && event.Timestamp.Before(opts.From) {
+			continue
+		}
+		if !opts.To.IsZero() && event.Timestamp.After(opts.To) {
+			continue
+		}
+		if hasCursor && (event.Timestamp.Before(afterTimestamp) ||
+			(event.Timestamp.Equal(afterTimestamp) && event.EventID <= afterID)) {
+			continue
+		}
+		matches = append(matches, copyEvent(event))
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		if !matches[i].Timestamp.Equal(matches[j].Timestamp) {
+			return matches[i].Timestamp.Before(matches[j].Timestamp)
+		}
+		return matches[i].EventID < matches[j].EventID
+	})
+
+	page := &EventPage{Events: matches}
+	if pageSize := opts.PageSize(); len(matches) > pageSize {
+		page.Events = matches[:pageSize]
+
+		var err error
+		if page.NextCursor, err = encodeKeysetCursor(page.Events[pageSize-1]); err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
+
+// PutClientConfig stores a client configuration, replacing any existing one
+func (r *MemoryRepository) PutClientConfig(ctx context.Context, config *models.ClientConfig) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.clients[config.ClientID] = config.Clone()
+	return nil
+}
+
+// CreateClientConfig stores a client configuration, failing with ErrClientConfigExists if the client has one
+func (r *MemoryRepository) CreateClientConfig(ctx context.Context, config *models.ClientConfig) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.clients[config.ClientID]; ok {
+		return fmt.Errorf("%w: %s", ErrClientConfigExists, config.ClientID)
+	}
+	r.clients[config.ClientID] = config.Clone()
+	return nil
+}
+
+// ListClientConfigs returns every client configuration ordered by client ID
+func (r *MemoryRepository) ListClientConfigs(ctx context.Context) ([]*models.ClientConfig, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	configs := make([]*models.ClientConfig, 0, len(r.clients))
+	for _, config := range r.clients {
+		configs = append(configs, config.Clone())
+	}
+	sort.Slice(configs, func(i, j int) bool { return configs[i].ClientID < configs[j].ClientID })
+	return configs, nil
+}
+
+// DeleteClientConfig removes a client configuration
+func (r *MemoryRepository) DeleteClientConfig(ctx context.Context, clientID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.clients[clientID]; !ok {
+		return fmt.Errorf("%w: %s", ErrClientConfigNotFound, clientID)
+	}
+	delete(r.clients, clientID)
+	return nil
+}
+
+// GetClientConfig retrieves client configuration
+func (r *MemoryRepository) GetClientConfig(ctx context.Context, clientID string) (*models.ClientConfig, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	config, ok := r.clients[clientID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrClientConfigNotFound, clientID)
+	}
+	return config.Clone(), nil
+}
+
+// RecordClientChange appends a change to the client history
+func (r *MemoryRepository) RecordClientChange(ctx context.Context, change models.ClientChange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.changes = append(r.changes, cloneClientChange(change))
+	return nil
+}
+
+// ListClientChanges returns the recorded changes to a client, or of every client when clientID is empty
+func (r *MemoryRepository) ListClientChanges(ctx context.Context, clientID string) ([]models.ClientChange, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var changes []models.ClientChange
+	for _, change := range r.changes {
+		if clientID == "" || change.ClientID == clientID {
+			changes = append(changes, cloneClientChange(change))
+		}
+	}
+	sortClientChanges(changes)
+	return changes, nil
+}
+
+// cloneClientChange returns a copy of a change that shares no configuration with it
+func cloneClientChange(change models.ClientChange) models.ClientChange {
+	if change.Before != nil {
+		change.Before = change.Before.Clone()
+	}
+	if change.After != nil {
+		change.After = change.After.Clone()
+	}
+	return change
+}
+
+// QuarantineEvent stores a copy of a rejected event
+func (r *MemoryRepository) QuarantineEvent(ctx context.Context, event *models.QuarantinedEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.quarantine[event.QuarantineID] = copyQuarantinedEvent(event)
+	return nil
+}
+
+// GetQuarantinedEvent retrieves a quarantined event by its quarantine ID
+func (r *MemoryRepository) GetQuarantinedEvent(ctx context.Context, quarantineID string) (*models.QuarantinedEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	event, ok := r.quarantine[quarantineID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrQuarantinedEventNotFound, quarantineID)
+	}
+	return copyQuarantinedEvent(event), nil
+}
+
+// ListQuarantinedEvents lists the matching quarantined events, most recent first
+func (r *MemoryRepository) ListQuarantinedEvents(ctx context.Context, filter QuarantineFilter) ([]*models.QuarantinedEvent, error) {
+	r.mu.RLock()
+	events := make([]*models.QuarantinedEvent, 0)
+	for _, event := range r.quarantine {
+		if filter.matches(event) {
+			events = append(events, copyQuarantinedEvent(event))
+		}
+	}
+	r.mu.RUnlock()
+
+	return sortQuarantinedEvents(events, filter), nil
+}
+
+// DeleteQuarantinedEvent removes a quarantined event
+func (r *MemoryRepository) DeleteQuarantinedEvent(ctx context.Context, quarantineID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.quarantine[quarantineID]; !ok {
+		return fmt.Errorf("%w: %s", ErrQuarantinedEventNotFound, quarantineID)
+	}
+	delete(r.quarantine, quarantineID)
+	return nil
+}
+
+// HealthCheck always succeeds for the in-memory repository
+func (r *MemoryRepository) HealthCheck(ctx context.Context) error {
+	return nil
+}
+
+// copyEvent returns a deep copy so callers cannot mutate stored state
+func copyEvent(event *models.ProcessedEvent) *models.ProcessedEvent {
+	copied := *event
+	if event.Payload != nil {
+		copied.Payload = copyValue(event.Payload).(map[string]interface{})
+	}
+	if event.Replays != nil {
+		copied.Replays = append([]models.ReplayRecord(nil), event.Replays...)
+	}
+	if event.DerivedFields != nil {
+		copied.DerivedFields = append([]string(nil), event.DerivedFields...)
+	}
+	return &copied
+}
+
+// copyValue deep copies JSON-shaped values
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, element := range v {
+			copied[key] = copyValue(element)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, element := range v {
+			copied[i] = copyValue(element)
+		}
+		return copied
+	default:
+		return v
+	}
+}
+
+// copyQuarantinedEvent returns a copy that does not share its errors or validation with the original
+func copyQuarantinedEvent(event *models.QuarantinedEvent) *models.QuarantinedEvent {
+	copied := *event
+	copied.Errors = append([]string{}, event.Errors...)
+	if event.Validation != nil {
+		validation := *event.Validation
+		validation.Violations = append([]models.Violation{}, event.Validation.Violations...)
+		copied.Validation = &validation
+	}
+	return &copied
+}
@@ -0,0 +1,52 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// TestMemoryRepositoryContract runs the repository contract against the in-memory repository
+func TestMemoryRepositoryContract(t *testing.T) {
+	runRepositoryContract(t, repositoryBackend{
+		newRepository: func(t *testing.T) Repository {
+			return NewMemoryRepository()
+		},
+	})
+}
+
+// TestMemoryRepositoryIsolation tests that callers cannot mutate stored state through shared references
+func TestMemoryRepositoryIsolation(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+
+	event := createValidProcessedEvent()
+	event.Payload = map[string]interface{}{"nested": map[string]interface{}{"value": 1.0}}
+	require.NoError(t, repo.SaveEvent(ctx, event))
+
+	event.Payload["nested"].(map[string]interface{})["value"] = 2.0
+
+	stored, err := repo.GetEvent(ctx, event.EventID)
+	require.NoError(t, err)
+	stored.Status = models.EventStatusFailed
+
+	again, err := repo.GetEvent(ctx, event.EventID)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, again.Payload["nested"].(map[string]interface{})["value"])
+	assert.Equal(t, event.Status, again.Status)
+}
+
+// TestMemoryRepositorySeededClients tests seeding with the sample client configurations
+func TestMemoryRepositorySeededClients(t *testing.T) {
+	repo := NewMemoryRepository(SampleClientConfigs()...)
+
+	for _, sample := range SampleClientConfigs() {
+		config, err := repo.GetClientConfig(context.Background(), sample.ClientID)
+		require.NoError(t, err)
+		assert.Equal(t, sample, config)
+	}
+}
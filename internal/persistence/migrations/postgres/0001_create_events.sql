@@ -0,0 +1,20 @@
+-- Events table, mirroring the DynamoDB events table
+CREATE TABLE events (
+    event_id     TEXT PRIMARY KEY,
+    event_type   TEXT        NOT NULL,
+    client_id    TEXT        NOT NULL,
+    timestamp    TIMESTAMPTZ NOT NULL,
+    payload      JSONB       NOT NULL,
+    version      TEXT        NOT NULL,
+    processed_at TIMESTAMPTZ NOT NULL,
+    status       TEXT        NOT NULL,
+    error_msg    TEXT        NOT NULL DEFAULT '',
+    retry_count  INTEGER     NOT NULL DEFAULT 0,
+    ttl          BIGINT      NOT NULL DEFAULT 0
+);
+
+-- Equivalent of the client_id_index GSI
+CREATE INDEX client_id_index ON events (client_id, timestamp, event_id);
+
+-- Equivalent of the status_index GSI
+CREATE INDEX status_index ON events (status, timestamp, event_id);
@@ -0,0 +1,7 @@
+-- Client configurations, mirroring the DynamoDB events-clients table
+CREATE TABLE clients (
+    client_id     TEXT PRIMARY KEY,
+    allowed_types JSONB   NOT NULL DEFAULT '[]',
+    config        JSONB   NOT NULL DEFAULT '{}',
+    active        BOOLEAN NOT NULL DEFAULT TRUE
+);
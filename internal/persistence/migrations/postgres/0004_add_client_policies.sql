@@ -0,0 +1,2 @@
+-- Per event type policies of a client, keyed by event type
+ALTER TABLE clients ADD COLUMN policies JSONB NOT NULL DEFAULT '{}';
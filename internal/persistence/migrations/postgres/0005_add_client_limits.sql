@@ -0,0 +1,2 @@
+-- Rate limits and quotas of a client; NULL for clients without limits
+ALTER TABLE clients ADD COLUMN limits JSONB;
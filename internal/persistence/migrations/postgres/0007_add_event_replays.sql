@@ -0,0 +1,2 @@
+-- Reprocessing runs of an event as a JSON array; NULL for events never reprocessed
+ALTER TABLE events ADD COLUMN replays JSONB;
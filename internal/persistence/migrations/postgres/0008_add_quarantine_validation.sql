@@ -0,0 +1,2 @@
+-- Schema rules broken by events rejected by a schema; NULL for events rejected otherwise
+ALTER TABLE quarantined_events ADD COLUMN validation JSONB;
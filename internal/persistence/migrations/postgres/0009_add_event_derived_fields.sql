@@ -0,0 +1,2 @@
+-- Payload fields added by processing as a JSON array; NULL for events processing added nothing to
+ALTER TABLE events ADD COLUMN derived_fields JSONB;
@@ -0,0 +1,12 @@
+-- History of client configuration changes, mirroring the DynamoDB events-client-audit table
+CREATE TABLE client_changes (
+    change_id     BIGSERIAL PRIMARY KEY,
+    client_id     TEXT        NOT NULL,
+    changed_at    TIMESTAMPTZ NOT NULL,
+    actor         TEXT        NOT NULL,
+    action        TEXT        NOT NULL,
+    before_config JSONB,
+    after_config  JSONB
+);
+
+CREATE INDEX client_changes_client_index ON client_changes (client_id, changed_at);
@@ -0,0 +1,20 @@
+-- Events table, mirroring the DynamoDB events table
+CREATE TABLE events (
+    event_id     TEXT PRIMARY KEY,
+    event_type   TEXT    NOT NULL,
+    client_id    TEXT    NOT NULL,
+    timestamp    TEXT    NOT NULL,
+    payload      TEXT    NOT NULL CHECK (json_valid(payload)),
+    version      TEXT    NOT NULL,
+    processed_at TEXT    NOT NULL,
+    status       TEXT    NOT NULL,
+    error_msg    TEXT    NOT NULL DEFAULT '',
+    retry_count  INTEGER NOT NULL DEFAULT 0,
+    ttl          INTEGER NOT NULL DEFAULT 0
+);
+
+-- Equivalent of the client_id_index GSI
+CREATE INDEX client_id_index ON events (client_id, timestamp, event_id);
+
+-- Equivalent of the status_index GSI
+CREATE INDEX status_index ON events (status, timestamp, event_id);
@@ -0,0 +1,7 @@
+-- Client configurations, mirroring the DynamoDB events-clients table
+CREATE TABLE clients (
+    client_id     TEXT PRIMARY KEY,
+    allowed_types TEXT    NOT NULL DEFAULT '[]' CHECK (json_valid(allowed_types)),
+    config        TEXT    NOT NULL DEFAULT '{}' CHECK (json_valid(config)),
+    active        INTEGER NOT NULL DEFAULT 1
+);
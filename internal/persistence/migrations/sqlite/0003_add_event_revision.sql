@@ -0,0 +1,2 @@
+-- Revision guarding status updates against concurrent writers
+ALTER TABLE events ADD COLUMN revision INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,13 @@
+-- Rejected events awaiting a fix or discard, mirroring the DynamoDB events-quarantine table
+CREATE TABLE quarantined_events (
+    quarantine_id  TEXT PRIMARY KEY,
+    category       TEXT NOT NULL,
+    client_id      TEXT NOT NULL DEFAULT '',
+    event_id       TEXT NOT NULL DEFAULT '',
+    event_type     TEXT NOT NULL DEFAULT '',
+    errors         TEXT NOT NULL DEFAULT '[]' CHECK (json_valid(errors)),
+    body           TEXT NOT NULL,
+    quarantined_at TEXT NOT NULL
+);
+
+CREATE INDEX quarantined_events_client_index ON quarantined_events (client_id, quarantined_at);
@@ -0,0 +1,12 @@
+-- History of client configuration changes, mirroring the DynamoDB events-client-audit table
+CREATE TABLE client_changes (
+    change_id     INTEGER PRIMARY KEY AUTOINCREMENT,
+    client_id     TEXT NOT NULL,
+    changed_at    TEXT NOT NULL,
+    actor         TEXT NOT NULL,
+    action        TEXT NOT NULL,
+    before_config TEXT CHECK (before_config IS NULL OR json_valid(before_config)),
+    after_config  TEXT CHECK (after_config IS NULL OR json_valid(after_config))
+);
+
+CREATE INDEX client_changes_client_index ON client_changes (client_id, changed_at);
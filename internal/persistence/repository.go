@@ -2,18 +2,258 @@ package persistence
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/d-sense/event-processor/internal/config"
 	"github.com/d-sense/event-processor/pkg/models"
 )
 
+// ErrEventNotFound is returned when a requested event does not exist
+var ErrEventNotFound = errors.New("event not found")
+
+// ErrClientConfigNotFound is returned when a client has no stored configuration
+var ErrClientConfigNotFound = errors.New("client config not found")
+
+// ErrClientConfigExists is returned when creating a client configuration that is already stored
+var ErrClientConfigExists = errors.New("client config already exists")
+
+// ErrQuarantinedEventNotFound is returned when a requested quarantined event does not exist
+var ErrQuarantinedEventNotFound = errors.New("quarantined event not found")
+
+// Closer is implemented by repositories that buffer writes and must be closed on shutdown
+type Closer interface {
+	Close(ctx context.Context) error
+}
+
+// Supported values of config.StorageBackend
+const (
+	BackendDynamoDB = "dynamodb"
+	BackendSQLite   = "sqlite"
+	BackendPostgres = "postgres"
+	BackendMemory   = "memory"
+)
+
+// DefaultSQLitePath is the database file used when the SQLite backend has no DATABASE_URL
+const DefaultSQLitePath = "events.db"
+
+// ErrEventExists is returned when saving an event that is already stored. The stored event is kept:
+// it is a redelivery of an event that was processed before and may have moved on since.
+var ErrEventExists = errors.New("event already exists")
+
+// ErrRevisionConflict is returned when an event was modified since the caller read it
+var ErrRevisionConflict = errors.New("event revision conflict")
+
+// ErrInvalidTransition is returned when a status update is not allowed from the event's current status
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+const (
+	// DefaultPageSize is the number of events returned when no limit is given
+	DefaultPageSize = 50
+
+	// MaxPageSize is the largest page size a listing will return
+	MaxPageSize = 1000
+)
+
+// ListOptions controls pagination and time-range filtering of event listings
+type ListOptions struct {
+	// Limit is the maximum number of events to return (DefaultPageSize when zero)
+	Limit int
+
+	// Cursor resumes a listing from the NextCursor of a previous page
+	Cursor string
+
+	// From and To restrict results to events whose timestamp falls within [From, To]
+	From time.Time
+	To   time.Time
+}
+
+// PageSize returns the effective page size for the options
+func (o ListOptions) PageSize() int {
+	switch {
+	case o.Limit <= 0:
+		return DefaultPageSize
+	case o.Limit > MaxPageSize:
+		return MaxPageSize
+	default:
+		return o.Limit
+	}
+}
+
+// StatusUpdate describes a conditional change of an event's status
+type StatusUpdate struct {
+	// Status is the new status, which must be reachable from the current one (see models.CanTransition)
+	Status models.EventStatus
+
+	// ExpectedRevision is the revision the caller last read; the update fails with ErrRevisionConflict otherwise
+	ExpectedRevision int64
+
+	// ErrorMsg replaces the stored error message
+	ErrorMsg string
+}
+
+// checkStatusUpdate validates an update against the current state of an event
+func checkStatusUpdate(current *models.ProcessedEvent, update StatusUpdate) error {
+	if current.Revision != update.ExpectedRevision {
+		return fmt.Errorf("%w: event %s is at revision %d, expected %d", ErrRevisionConflict, current.EventID, current.Revision, update.ExpectedRevision)
+	}
+	if !models.CanTransition(current.Status, update.Status) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, current.Status, update.Status)
+	}
+	return nil
+}
+
+// EventPage is a single page of a listing
+type EventPage struct {
+	Events []*models.ProcessedEvent `json:"events"`
+
+	// NextCursor is empty when there are no more results
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
 // Repository defines the interface for event persistence
 type Repository interface {
 	// Event operations
 	SaveEvent(ctx context.Context, event *models.ProcessedEvent) error
+	GetEvent(ctx context.Context, eventID string) (*models.ProcessedEvent, error)
+	ListEventsByClient(ctx context.Context, clientID string, opts ListOptions) (*EventPage, error)
+	ListEventsByStatus(ctx context.Context, status models.EventStatus, opts ListOptions) (*EventPage, error)
+	UpdateEventStatus(ctx context.Context, eventID string, update StatusUpdate) (*models.ProcessedEvent, error)
 
 	// Client configuration operations
 	GetClientConfig(ctx context.Context, clientID string) (*models.ClientConfig, error)
+	PutClientConfig(ctx context.Context, config *models.ClientConfig) error
+
+	// CreateClientConfig stores a client configuration unless one is stored for the client,
+	// failing with ErrClientConfigExists otherwise
+	CreateClientConfig(ctx context.Context, config *models.ClientConfig) error
+	ListClientConfigs(ctx context.Context) ([]*models.ClientConfig, error)
+	DeleteClientConfig(ctx context.Context, clientID string) error
 
 	// Maintenance operations
 	HealthCheck(ctx context.Context) error
 }
+
+// EventReplacer is implemented by repositories that can replace a stored event unless it was
+// modified since it was read. Every repository of this package is one.
+type EventReplacer interface {
+	// ReplaceEvent stores event in place of the stored event if that is still at expectedRevision,
+	// failing with ErrRevisionConflict otherwise and ErrEventNotFound if there is no stored event.
+	// The event is stored with its own Revision, which callers set to expectedRevision+1.
+	ReplaceEvent(ctx context.Context, event *models.ProcessedEvent, expectedRevision int64) error
+}
+
+// QuarantineFilter restricts a listing of quarantined events; empty fields match every event
+type QuarantineFilter struct {
+	ClientID string
+	Category models.QuarantineCategory
+
+	// Limit is the maximum number of events to return (DefaultPageSize when zero)
+	Limit int
+}
+
+// PageSize returns the effective page size for the filter
+func (f QuarantineFilter) PageSize() int {
+	return ListOptions{Limit: f.Limit}.PageSize()
+}
+
+// matches reports whether a quarantined event passes the filter
+func (f QuarantineFilter) matches(event *models.QuarantinedEvent) bool {
+	return (f.ClientID == "" || event.ClientID == f.ClientID) &&
+		(f.Category == "" || event.Category == f.Category)
+}
+
+// QuarantineStore is implemented by repositories that keep rejected events for inspection. Every
+// repository of this package is one; the interface is separate so that Repository stays small.
+type QuarantineStore interface {
+	// QuarantineEvent stores an event, replacing any event with the same quarantine ID
+	QuarantineEvent(ctx context.Context, event *models.QuarantinedEvent) error
+	GetQuarantinedEvent(ctx context.Context, quarantineID string) (*models.QuarantinedEvent, error)
+
+	// ListQuarantinedEvents returns the matching events, most recently quarantined first
+	ListQuarantinedEvents(ctx context.Context, filter QuarantineFilter) ([]*models.QuarantinedEvent, error)
+	DeleteQuarantinedEvent(ctx context.Context, quarantineID string) error
+}
+
+// ClientAuditStore is implemented by repositories that keep the history of client configuration
+// changes next to the configurations, so that every replica records into and reads the same history.
+// Every repository of this package is one.
+type ClientAuditStore interface {
+	RecordClientChange(ctx context.Context, change models.ClientChange) error
+
+	// ListClientChanges returns the changes to a client in the order they were made, or of every
+	// client when clientID is empty
+	ListClientChanges(ctx context.Context, clientID string) ([]models.ClientChange, error)
+}
+
+// sortClientChanges orders changes by the time they were made, keeping the order of changes made
+// at the same time
+func sortClientChanges(changes []models.ClientChange) {
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Time.Before(changes[j].Time) })
+}
+
+// sortQuarantinedEvents orders events most recently quarantined first and truncates them to a page
+func sortQuarantinedEvents(events []*models.QuarantinedEvent, filter QuarantineFilter) []*models.QuarantinedEvent {
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].QuarantinedAt.Equal(events[j].QuarantinedAt) {
+			return events[i].QuarantinedAt.After(events[j].QuarantinedAt)
+		}
+		return events[i].QuarantineID < events[j].QuarantineID
+	})
+	if pageSize := filter.PageSize(); len(events) > pageSize {
+		events = events[:pageSize]
+	}
+	return events
+}
+
+// encodeKeysetCursor encodes the position after an event in (timestamp, event ID) order
+func encodeKeysetCursor(last *models.ProcessedEvent) (string, error) {
+	return encodeCursorValues(map[string]string{
+		"timestamp": last.Timestamp.UTC().Format(time.RFC3339Nano),
+		"event_id":  last.EventID,
+	})
+}
+
+// decodeKeysetCursor decodes a keyset cursor into the last seen timestamp and event ID
+func decodeKeysetCursor(cursor string) (time.Time, string, error) {
+	values, err := decodeCursorValues(cursor)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, values["timestamp"])
+	if err != nil || values["event_id"] == "" {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	return timestamp, values["event_id"], nil
+}
+
+// NewRepository creates the repository for the configured storage backend
+func NewRepository(ctx context.Context, awsCfg aws.Config, cfg *config.Config) (Repository, error) {
+	switch cfg.StorageBackend {
+	case BackendDynamoDB, "":
+		return NewDynamoDBRepository(awsCfg, cfg), nil
+	case BackendSQLite:
+		dsn := cfg.DatabaseURL
+		if dsn == "" {
+			dsn = DefaultSQLitePath
+		}
+		return NewSQLiteRepository(ctx, dsn)
+	case BackendPostgres:
+		if cfg.DatabaseURL == "" {
+			return nil, fmt.Errorf("DATABASE_URL is required for the %s storage backend", BackendPostgres)
+		}
+		return NewPostgresRepository(ctx, cfg.DatabaseURL)
+	case BackendMemory:
+		return NewMemoryRepository(SampleClientConfigs()...), nil
+	default:
+		return nil, fmt.Errorf("unsupported storage backend: %s", cfg.StorageBackend)
+	}
+}
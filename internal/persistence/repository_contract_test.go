@@ -0,0 +1,597 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// repositoryBackend creates a fresh, empty repository for each contract test
+type repositoryBackend struct {
+	newRepository func(t *testing.T) Repository
+}
+
+// runRepositoryContract runs the behaviour every Repository implementation must provide
+func runRepositoryContract(t *testing.T, backend repositoryBackend) {
+	ctx := context.Background()
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Save And Get Event", func(t *testing.T) {
+		repo := backend.newRepository(t)
+		event := contractEvent("evt-1", "client-a", models.EventStatusProcessed, base)
+		event.Payload = map[string]interface{}{
+			"amount":  1.5,
+			"orderId": json.Number("123456789012345678901234567890"),
+			"items":   []interface{}{"a", "a", map[string]interface{}{"qty": 2.0}},
+			"note":    nil,
+			"flag":    true,
+		}
+		event.DerivedFields = []string{"flag"}
+
+		require.NoError(t, repo.SaveEvent(ctx, event))
+
+		stored, err := repo.GetEvent(ctx, event.EventID)
+		require.NoError(t, err)
+		assert.Equal(t, event, stored)
+	})
+
+	t.Run("Save Keeps Existing Event", func(t *testing.T) {
+		repo := backend.newRepository(t)
+		event := contractEvent("evt-1", "client-a", models.EventStatusFailed, base)
+		require.NoError(t, repo.SaveEvent(ctx, event))
+
+		updated, err := repo.UpdateEventStatus(ctx, event.EventID, StatusUpdate{
+			Status:           models.EventStatusRetrying,
+			ExpectedRevision: event.Revision,
+		})
+		require.NoError(t, err)
+
+		// A redelivery of the event is a duplicate and must not reset it
+		redelivered := contractEvent("evt-1", "client-a", models.EventStatusProcessed, base)
+		assert.ErrorIs(t, repo.SaveEvent(ctx, redelivered), ErrEventExists)
+
+		stored, err := repo.GetEvent(ctx, event.EventID)
+		require.NoError(t, err)
+		assert.Equal(t, models.EventStatusRetrying, stored.Status)
+		assert.Equal(t, updated.Revision, stored.Revision)
+		assert.Equal(t, event.Revision+1, stored.Revision)
+	})
+
+	t.Run("Replace Event At Expected Revision", func(t *testing.T) {
+		repo := backend.newRepository(t)
+		replacer := repo.(EventReplacer)
+		event := contractEvent("evt-1", "client-a", models.EventStatusProcessed, base)
+		require.NoError(t, repo.SaveEvent(ctx, event))
+
+		replaced := *event
+		replaced.Status = models.EventStatusReprocessed
+		replaced.Payload = map[string]interface{}{"value": 2.0}
+		replaced.Revision = 2
+		replaced.RecordReplay(models.ReplayRecord{
+			RunID:          "run-1",
+			ReplayedAt:     base.Add(time.Hour),
+			PreviousStatus: models.EventStatusProcessed,
+			Status:         models.EventStatusReprocessed,
+			Changed:        true,
+		})
+		require.NoError(t, replacer.ReplaceEvent(ctx, &replaced, 1))
+
+		stored, err := repo.GetEvent(ctx, event.EventID)
+		require.NoError(t, err)
+		assert.Equal(t, &replaced, stored)
+
+		// The stored event has moved on to revision 2
+		assert.ErrorIs(t, replacer.ReplaceEvent(ctx, &replaced, 1), ErrRevisionConflict)
+
+		missing := contractEvent("missing", "client-a", models.EventStatusProcessed, base)
+		assert.ErrorIs(t, replacer.ReplaceEvent(ctx, missing, 1), ErrEventNotFound)
+		_, err = repo.GetEvent(ctx, "missing")
+		assert.ErrorIs(t, err, ErrEventNotFound)
+	})
+
+	t.Run("Get Missing Event", func(t *testing.T) {
+		repo := backend.newRepository(t)
+
+		_, err := repo.GetEvent(ctx, "missing")
+		assert.ErrorIs(t, err, ErrEventNotFound)
+	})
+
+	t.Run("List By Client Pages In Timestamp Order", func(t *testing.T) {
+		repo := backend.newRepository(t)
+		for i := 4; i >= 0; i-- {
+			event := contractEvent(fmt.Sprintf("evt-%d", i), "client-a", models.EventStatusProcessed, base.Add(time.Duration(i)*time.Minute))
+			require.NoError(t, repo.SaveEvent(ctx, event))
+		}
+		require.NoError(t, repo.SaveEvent(ctx, contractEvent("other", "client-b", models.EventStatusProcessed, base)))
+
+		var ids []string
+		opts := ListOptions{Limit: 2}
+		for pages := 0; ; pages++ {
+			require.Less(t, pages, 5, "pagination did not terminate")
+
+			page, err := repo.ListEventsByClient(ctx, "client-a", opts)
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(page.Events), 2)
+			for _, event := range page.Events {
+				ids = append(ids, event.EventID)
+			}
+
+			if page.NextCursor == "" {
+				break
+			}
+			opts.Cursor = page.NextCursor
+		}
+
+		assert.Equal(t, []string{"evt-0", "evt-1", "evt-2", "evt-3", "evt-4"}, ids)
+	})
+
+	t.Run("List By Client Breaks Timestamp Ties By ID", func(t *testing.T) {
+		repo := backend.newRepository(t)
+		for _, id := range []string{"evt-c", "evt-a", "evt-b"} {
+			require.NoError(t, repo.SaveEvent(ctx, contractEvent(id, "client-a", models.EventStatusProcessed, base)))
+		}
+
+		first, err := repo.ListEventsByClient(ctx, "client-a", ListOptions{Limit: 2})
+		require.NoError(t, err)
+		second, err := repo.ListEventsByClient(ctx, "client-a", ListOptions{Limit: 2, Cursor: first.NextCursor})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"evt-a", "evt-b"}, eventIDs(first))
+		assert.Equal(t, []string{"evt-c"}, eventIDs(second))
+		assert.Empty(t, second.NextCursor)
+	})
+
+	t.Run("List By Client Within Time Range", func(t *testing.T) {
+		repo := backend.newRepository(t)
+		for i := 0; i < 5; i++ {
+			event := contractEvent(fmt.Sprintf("evt-%d", i), "client-a", models.EventStatusProcessed, base.Add(time.Duration(i)*time.Hour))
+			require.NoError(t, repo.SaveEvent(ctx, event))
+		}
+
+		page, err := repo.ListEventsByClient(ctx, "client-a", ListOptions{
+			From: base.Add(time.Hour),
+			To:   base.Add(3 * time.Hour),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"evt-1", "evt-2", "evt-3"}, eventIDs(page))
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("List By Status", func(t *testing.T) {
+		repo := backend.newRepository(t)
+		require.NoError(t, repo.SaveEvent(ctx, contractEvent("evt-1", "client-a", models.EventStatusFailed, base)))
+		require.NoError(t, repo.SaveEvent(ctx, contractEvent("evt-2", "client-b", models.EventStatusProcessed, base)))
+		require.NoError(t, repo.SaveEvent(ctx, contractEvent("evt-3", "client-b", models.EventStatusFailed, base.Add(time.Second))))
+
+		page, err := repo.ListEventsByStatus(ctx, models.EventStatusFailed, ListOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"evt-1", "evt-3"}, eventIDs(page))
+	})
+
+	t.Run("List With Invalid Cursor", func(t *testing.T) {
+		repo := backend.newRepository(t)
+
+		_, err := repo.ListEventsByClient(ctx, "client-a", ListOptions{Cursor: "not-a-cursor"})
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+	})
+
+	t.Run("List Empty Result", func(t *testing.T) {
+		repo := backend.newRepository(t)
+
+		page, err := repo.ListEventsByStatus(ctx, models.EventStatusProcessed, ListOptions{})
+		require.NoError(t, err)
+		assert.Empty(t, page.Events)
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("Update Event Status", func(t *testing.T) {
+		repo := backend.newRepository(t)
+		require.NoError(t, repo.SaveEvent(ctx, contractEvent("evt-1", "client-a", models.EventStatusFailed, base)))
+
+		updated, err := repo.UpdateEventStatus(ctx, "evt-1", StatusUpdate{
+			Status:           models.EventStatusRetrying,
+			ExpectedRevision: 1,
+			ErrorMsg:         "manual retry",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, models.EventStatusRetrying, updated.Status)
+		assert.Equal(t, int64(2), updated.Revision)
+		assert.Equal(t, "manual retry", updated.ErrorMsg)
+
+		stored, err := repo.GetEvent(ctx, "evt-1")
+		require.NoError(t, err)
+		assert.Equal(t, updated, stored)
+
+		updated, err = repo.UpdateEventStatus(ctx, "evt-1", StatusUpdate{Status: models.EventStatusProcessed, ExpectedRevision: 2})
+		require.NoError(t, err)
+		assert.Equal(t, models.EventStatusProcessed, updated.Status)
+		assert.Equal(t, int64(3), updated.Revision)
+		assert.Empty(t, updated.ErrorMsg)
+
+		page, err := repo.ListEventsByStatus(ctx, models.EventStatusProcessed, ListOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"evt-1"}, eventIDs(page))
+	})
+
+	t.Run("Update Event Status With Stale Revision", func(t *testing.T) {
+		repo := backend.newRepository(t)
+		require.NoError(t, repo.SaveEvent(ctx, contractEvent("evt-1", "client-a", models.EventStatusProcessed, base)))
+
+		_, err := repo.UpdateEventStatus(ctx, "evt-1", StatusUpdate{Status: models.EventStatusReprocessed, ExpectedRevision: 1})
+		require.NoError(t, err)
+
+		_, err = repo.UpdateEventStatus(ctx, "evt-1", StatusUpdate{Status: models.EventStatusReprocessed, ExpectedRevision: 1})
+		assert.ErrorIs(t, err, ErrRevisionConflict)
+	})
+
+	t.Run("Update Event Status With Invalid Transition", func(t *testing.T) {
+		repo := backend.newRepository(t)
+		require.NoError(t, repo.SaveEvent(ctx, contractEvent("evt-1", "client-a", models.EventStatusProcessed, base)))
+
+		_, err := repo.UpdateEventStatus(ctx, "evt-1", StatusUpdate{Status: models.EventStatusPending, ExpectedRevision: 1})
+		assert.ErrorIs(t, err, ErrInvalidTransition)
+
+		stored, err := repo.GetEvent(ctx, "evt-1")
+		require.NoError(t, err)
+		assert.Equal(t, models.EventStatusProcessed, stored.Status)
+		assert.Equal(t, int64(1), stored.Revision)
+	})
+
+	t.Run("Update Missing Event Status", func(t *testing.T) {
+		repo := backend.newRepository(t)
+
+		_, err := repo.UpdateEventStatus(ctx, "missing", StatusUpdate{Status: models.EventStatusProcessed, ExpectedRevision: 1})
+		assert.ErrorIs(t, err, ErrEventNotFound)
+	})
+
+	t.Run("Concurrent Status Updates", func(t *testing.T) {
+		repo := backend.newRepository(t)
+		require.NoError(t, repo.SaveEvent(ctx, contractEvent("evt-1", "client-a", models.EventStatusFailed, base)))
+
+		const writers = 5
+		results := make(chan error, writers)
+		for i := 0; i < writers; i++ {
+			go func() {
+				_, err := repo.UpdateEventStatus(ctx, "evt-1", StatusUpdate{Status: models.EventStatusRetrying, ExpectedRevision: 1})
+				results <- err
+			}()
+		}
+
+		succeeded := 0
+		for i := 0; i < writers; i++ {
+			if err := <-results; err == nil {
+				succeeded++
+			} else {
+				assert.True(t, errors.Is(err, ErrRevisionConflict) || errors.Is(err, ErrInvalidTransition), err)
+			}
+		}
+		assert.Equal(t, 1, succeeded)
+
+		stored, err := repo.GetEvent(ctx, "evt-1")
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), stored.Revision)
+	})
+
+	t.Run("Get Client Config", func(t *testing.T) {
+		repo := backend.newRepository(t)
+		config := &models.ClientConfig{
+			ClientID:     "client-a",
+			AllowedTypes: []models.EventType{models.EventTypeMonitoring, models.EventTypeTransaction},
+			Config:       map[string]string{"tier": "gold"},
+			Active:       true,
+		}
+		require.NoError(t, repo.PutClientConfig(ctx, config))
+
+		stored, err := repo.GetClientConfig(ctx, "client-a")
+		require.NoError(t, err)
+		assert.Equal(t, config, stored)
+	})
+
+	t.Run("Put Replaces Client Config", func(t *testing.T) {
+		repo := backend.newRepository(t)
+		config := &models.ClientConfig{
+			ClientID:     "client-a",
+			AllowedTypes: []models.EventType{models.EventTypeMonitoring},
+			Config:       map[string]string{"tier": "gold"},
+			Active:       true,
+		}
+		require.NoError(t, repo.PutClientConfig(ctx, config))
+
+		replacement := &models.ClientConfig{ClientID: "client-a", Active: false}
+		require.NoError(t, repo.PutClientConfig(ctx, replacement))
+
+		stored, err := repo.GetClientConfig(ctx, "client-a")
+		require.NoError(t, err)
+		assert.Equal(t, replacement, stored)
+	})
+
+	t.Run("Client Config Policies Round Trip", func(t *testing.T) {
+		repo := backend.newRepository(t)
+		maxAmount := 10000.0
+		config := &models.ClientConfig{
+			ClientID:     "client-a",
+			AllowedTypes: []models.EventType{models.EventTypeTransaction},
+			Active:       true,
+			Policies: map[models.EventType]models.EventPolicy{
+				models.EventTypeTransaction: {
+					Versions: []string{"1.0", "1.1"},
+					Payload: map[string]models.PayloadConstraint{
+						"currency": {Required: true, OneOf: []string{"EUR", "USD"}},
+						"amount":   {Max: &maxAmount},
+					},
+					Windows:            []models.TimeWindow{{Start: "09:00", End: "17:00", Days: []string{"mon", "fri"}, Timezone: "Europe/Berlin"}},
+					WindowsOnEventTime: true,
+				},
+			},
+		}
+		require.NoError(t, repo.PutClientConfig(ctx, config))
+
+		stored, err := repo.GetClientConfig(ctx, "client-a")
+		require.NoError(t, err)
+		assert.Equal(t, config, stored)
+	})
+
+	t.Run("Client Config Limits Round Trip", func(t *testing.T) {
+		repo := backend.newRepository(t)
+		config := &models.ClientConfig{
+			ClientID:     "client-a",
+			AllowedTypes: []models.EventType{models.EventTypeMonitoring},
+			Active:       true,
+			Limits:       &models.ClientLimits{RatePerSecond: 2.5, Burst: 10, DailyQuota: 1000, OnExcess: models.ExcessSample, SampleRate: 0.1},
+		}
+		require.NoError(t, repo.PutClientConfig(ctx, config))
+
+		stored, err := repo.GetClientConfig(ctx, "client-a")
+		require.NoError(t, err)
+		assert.Equal(t, config, stored)
+
+		// Removing the limits clears them
+		config.Limits = nil
+		require.NoError(t, repo.PutClientConfig(ctx, config))
+		stored, err = repo.GetClientConfig(ctx, "client-a")
+		require.NoError(t, err)
+		assert.Nil(t, stored.Limits)
+	})
+
+	t.Run("List Client Configs In Client Order", func(t *testing.T) {
+		repo := backend.newRepository(t)
+		for _, clientID := range []string{"client-c", "client-a", "client-b"} {
+			config := &models.ClientConfig{ClientID: clientID, AllowedTypes: []models.EventType{models.EventTypeMonitoring}, Active: true}
+			require.NoError(t, repo.PutClientConfig(ctx, config))
+		}
+
+		configs, err := repo.ListClientConfigs(ctx)
+		require.NoError(t, err)
+		var ids []string
+		for _, config := range configs {
+			ids = append(ids, config.ClientID)
+		}
+		assert.Equal(t, []string{"client-a", "client-b", "client-c"}, ids)
+	})
+
+	t.Run("Delete Client Config", func(t *testing.T) {
+		repo := backend.newRepository(t)
+		require.NoError(t, repo.PutClientConfig(ctx, &models.ClientConfig{ClientID: "client-a", Active: true}))
+
+		require.NoError(t, repo.DeleteClientConfig(ctx, "client-a"))
+		_, err := repo.GetClientConfig(ctx, "client-a")
+		assert.ErrorIs(t, err, ErrClientConfigNotFound)
+
+		assert.ErrorIs(t, repo.DeleteClientConfig(ctx, "client-a"), ErrClientConfigNotFound)
+	})
+
+	t.Run("Get Missing Client Config", func(t *testing.T) {
+		repo := backend.newRepository(t)
+
+		_, err := repo.GetClientConfig(ctx, "missing")
+		assert.ErrorIs(t, err, ErrClientConfigNotFound)
+	})
+
+	t.Run("Create Keeps Existing Client Config", func(t *testing.T) {
+		repo := backend.newRepository(t)
+		config := &models.ClientConfig{ClientID: "client-a", Config: map[string]string{"tier": "gold"}, Active: true}
+		require.NoError(t, repo.CreateClientConfig(ctx, config))
+
+		err := repo.CreateClientConfig(ctx, &models.ClientConfig{ClientID: "client-a", Active: false})
+		assert.ErrorIs(t, err, ErrClientConfigExists)
+		assert.ErrorContains(t, err, "client-a")
+
+		stored, err := repo.GetClientConfig(ctx, "client-a")
+		require.NoError(t, err)
+		assert.Equal(t, config, stored)
+	})
+
+	t.Run("Concurrent Client Creates", func(t *testing.T) {
+		repo := backend.newRepository(t)
+
+		const writers = 5
+		results := make(chan error, writers)
+		for i := 0; i < writers; i++ {
+			go func(i int) {
+				results <- repo.CreateClientConfig(ctx, &models.ClientConfig{ClientID: "client-a", Config: map[string]string{"writer": fmt.Sprint(i)}})
+			}(i)
+		}
+
+		succeeded := 0
+		for i := 0; i < writers; i++ {
+			if err := <-results; err == nil {
+				succeeded++
+			} else {
+				assert.ErrorIs(t, err, ErrClientConfigExists)
+			}
+		}
+		assert.Equal(t, 1, succeeded)
+	})
+
+	t.Run("Quarantine And Get Event", func(t *testing.T) {
+		store := backend.newRepository(t).(QuarantineStore)
+		event := contractQuarantinedEvent("q-1", "client-a", models.QuarantinePayload, base)
+		require.NoError(t, store.QuarantineEvent(ctx, event))
+
+		stored, err := store.GetQuarantinedEvent(ctx, "q-1")
+		require.NoError(t, err)
+		assert.Equal(t, event, stored)
+
+		// Quarantining the same ID again replaces the event
+		event.Errors = []string{"still missing"}
+		require.NoError(t, store.QuarantineEvent(ctx, event))
+		stored, err = store.GetQuarantinedEvent(ctx, "q-1")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"still missing"}, stored.Errors)
+	})
+
+	t.Run("Quarantine Event Without Known Fields", func(t *testing.T) {
+		store := backend.newRepository(t).(QuarantineStore)
+		event := &models.QuarantinedEvent{
+			QuarantineID:  "q-1",
+			Category:      models.QuarantineValidation,
+			Errors:        []string{"invalid JSON"},
+			Body:          "{not json",
+			QuarantinedAt: base,
+		}
+		require.NoError(t, store.QuarantineEvent(ctx, event))
+
+		stored, err := store.GetQuarantinedEvent(ctx, "q-1")
+		require.NoError(t, err)
+		assert.Equal(t, event, stored)
+	})
+
+	t.Run("List Quarantined Events", func(t *testing.T) {
+		store := backend.newRepository(t).(QuarantineStore)
+		require.NoError(t, store.QuarantineEvent(ctx, contractQuarantinedEvent("q-1", "client-a", models.QuarantinePayload, base)))
+		require.NoError(t, store.QuarantineEvent(ctx, contractQuarantinedEvent("q-2", "client-b", models.QuarantineAuthorization, base.Add(time.Minute))))
+		require.NoError(t, store.QuarantineEvent(ctx, contractQuarantinedEvent("q-3", "client-a", models.QuarantineAuthorization, base.Add(2*time.Minute))))
+
+		events, err := store.ListQuarantinedEvents(ctx, QuarantineFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"q-3", "q-2", "q-1"}, quarantineIDs(events))
+
+		events, err = store.ListQuarantinedEvents(ctx, QuarantineFilter{ClientID: "client-a"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"q-3", "q-1"}, quarantineIDs(events))
+
+		events, err = store.ListQuarantinedEvents(ctx, QuarantineFilter{ClientID: "client-a", Category: models.QuarantineAuthorization})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"q-3"}, quarantineIDs(events))
+
+		events, err = store.ListQuarantinedEvents(ctx, QuarantineFilter{Limit: 2})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"q-3", "q-2"}, quarantineIDs(events))
+
+		events, err = store.ListQuarantinedEvents(ctx, QuarantineFilter{ClientID: "client-z"})
+		require.NoError(t, err)
+		assert.NotNil(t, events)
+		assert.Empty(t, events)
+	})
+
+	t.Run("Delete Quarantined Event", func(t *testing.T) {
+		store := backend.newRepository(t).(QuarantineStore)
+		require.NoError(t, store.QuarantineEvent(ctx, contractQuarantinedEvent("q-1", "client-a", models.QuarantinePayload, base)))
+
+		require.NoError(t, store.DeleteQuarantinedEvent(ctx, "q-1"))
+		_, err := store.GetQuarantinedEvent(ctx, "q-1")
+		assert.ErrorIs(t, err, ErrQuarantinedEventNotFound)
+
+		assert.ErrorIs(t, store.DeleteQuarantinedEvent(ctx, "q-1"), ErrQuarantinedEventNotFound)
+	})
+
+	t.Run("Record And List Client Changes", func(t *testing.T) {
+		store := backend.newRepository(t).(ClientAuditStore)
+		created := &models.ClientConfig{ClientID: "client-a", AllowedTypes: []models.EventType{models.EventTypeMonitoring}, Active: true}
+		disabled := &models.ClientConfig{ClientID: "client-a", AllowedTypes: []models.EventType{models.EventTypeMonitoring}}
+		changes := []models.ClientChange{
+			{Time: base, Actor: "alice", Action: models.ClientActionCreate, ClientID: "client-a", After: created},
+			{Time: base.Add(2 * time.Minute), Actor: "carol", Action: models.ClientActionDelete, ClientID: "client-a", Before: disabled},
+			{Time: base.Add(time.Minute), Actor: "bob", Action: models.ClientActionDisable, ClientID: "client-a", Before: created, After: disabled},
+			{Time: base.Add(time.Minute), Actor: "alice", Action: models.ClientActionCreate, ClientID: "client-b", After: &models.ClientConfig{ClientID: "client-b", Active: true}},
+		}
+		for _, change := range changes {
+			require.NoError(t, store.RecordClientChange(ctx, change))
+		}
+
+		history, err := store.ListClientChanges(ctx, "client-a")
+		require.NoError(t, err)
+		require.Len(t, history, 3)
+		for i, expected := range []models.ClientChange{changes[0], changes[2], changes[1]} {
+			assert.True(t, expected.Time.Equal(history[i].Time), "time of change %d", i)
+			history[i].Time = expected.Time
+			assert.Equal(t, expected, history[i])
+		}
+
+		all, err := store.ListClientChanges(ctx, "")
+		require.NoError(t, err)
+		assert.Len(t, all, 4)
+
+		none, err := store.ListClientChanges(ctx, "client-z")
+		require.NoError(t, err)
+		assert.Empty(t, none)
+	})
+
+	t.Run("Health Check", func(t *testing.T) {
+		repo := backend.newRepository(t)
+
+		assert.NoError(t, repo.HealthCheck(ctx))
+	})
+}
+
+// contractEvent creates an event with microsecond precision, the finest every backend stores
+func contractEvent(id, clientID string, status models.EventStatus, timestamp time.Time) *models.ProcessedEvent {
+	return &models.ProcessedEvent{
+		Event: models.Event{
+			EventID:   id,
+			EventType: models.EventTypeMonitoring,
+			ClientID:  clientID,
+			Timestamp: timestamp,
+			Payload:   map[string]interface{}{"value": 1.0},
+			Version:   "1.0",
+		},
+		ProcessedAt: timestamp.Add(1500 * time.Microsecond),
+		Status:      status,
+		TTL:         timestamp.Add(24 * time.Hour).Unix(),
+		Revision:    1,
+	}
+}
+
+func eventIDs(page *EventPage) []string {
+	ids := make([]string, 0, len(page.Events))
+	for _, event := range page.Events {
+		ids = append(ids, event.EventID)
+	}
+	return ids
+}
+
+// contractQuarantinedEvent creates a quarantined event of a transaction missing its amount
+func contractQuarantinedEvent(id, clientID string, category models.QuarantineCategory, quarantinedAt time.Time) *models.QuarantinedEvent {
+	return &models.QuarantinedEvent{
+		QuarantineID: id,
+		Category:     category,
+		ClientID:     clientID,
+		EventID:      "evt-" + id,
+		EventType:    models.EventTypeTransaction,
+		Errors:       []string{"payload validation failed for version 1.1 of transaction events: [(root): amount is required]"},
+		Validation: &models.ValidationFailure{
+			Schema:        models.ValidationSchemaPayload,
+			EventType:     models.EventTypeTransaction,
+			SchemaVersion: "1.1",
+			Violations:    []models.Violation{{Field: "payload.amount", Rule: "required", Message: "(root): amount is required"}},
+		},
+		Body:          `{"eventId":"evt-` + id + `","eventType":"transaction","clientId":"` + clientID + `"}`,
+		QuarantinedAt: quarantinedAt,
+	}
+}
+
+func quarantineIDs(events []*models.QuarantinedEvent) []string {
+	ids := make([]string, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.QuarantineID)
+	}
+	return ids
+}
@@ -0,0 +1,888 @@
+package persistence
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// sqlTimeLayout is a fixed-width UTC layout so that stored timestamps sort lexicographically in SQLite
+const sqlTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// sqlDialect captures the differences between the supported SQL databases
+type sqlDialect struct {
+	name       string
+	driverName string
+
+	// numberedPlaceholders is true for databases using $1, $2... instead of ?
+	numberedPlaceholders bool
+
+	// textTimestamps is true for databases storing timestamps as sqlTimeLayout strings
+	textTimestamps bool
+}
+
+var (
+	sqliteDialect   = sqlDialect{name: "sqlite", driverName: "sqlite", textTimestamps: true}
+	postgresDialect = sqlDialect{name: "postgres", driverName: "pgx", numberedPlaceholders: true}
+)
+
+// rebind rewrites ? placeholders for the dialect
+func (d sqlDialect) rebind(query string) string {
+	if !d.numberedPlaceholders {
+		return query
+	}
+
+	var builder strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			builder.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
+
+// timeValue converts a time into the dialect's column representation
+func (d sqlDialect) timeValue(t time.Time) interface{} {
+	if d.textTimestamps {
+		return t.UTC().Format(sqlTimeLayout)
+	}
+	return t.UTC()
+}
+
+// sqlTime scans timestamp columns stored either natively or as text
+type sqlTime struct {
+	time *time.Time
+}
+
+// Scan implements sql.Scanner
+func (s sqlTime) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case time.Time:
+		*s.time = v.UTC()
+		return nil
+	case string:
+		return s.parse(v)
+	case []byte:
+		return s.parse(string(v))
+	case nil:
+		*s.time = time.Time{}
+		return nil
+	default:
+		return fmt.Errorf("unsupported timestamp column type %T", src)
+	}
+}
+
+func (s sqlTime) parse(value string) error {
+	parsed, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %q: %w", value, err)
+	}
+	*s.time = parsed.UTC()
+	return nil
+}
+
+// SQLRepository implements Repository interface using SQLite or PostgreSQL
+type SQLRepository struct {
+	db      *sql.DB
+	dialect sqlDialect
+}
+
+// NewSQLiteRepository opens an embedded SQLite database and applies pending migrations
+func NewSQLiteRepository(ctx context.Context, dsn string) (*SQLRepository, error) {
+	return newSQLRepository(ctx, sqliteDialect, dsn)
+}
+
+// NewPostgresRepository connects to a PostgreSQL database and applies pending migrations
+func NewPostgresRepository(ctx context.Context, dsn string) (*SQLRepository, error) {
+	return newSQLRepository(ctx, postgresDialect, dsn)
+}
+
+func newSQLRepository(ctx context.Context, dialect sqlDialect, dsn string) (*SQLRepository, error) {
+	db, err := sql.Open(dialect.driverName, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s database: %w", dialect.name, err)
+	}
+
+	if dialect == sqliteDialect {
+		// SQLite allows a single writer; serialising connections avoids SQLITE_BUSY errors
+		db.SetMaxOpenConns(1)
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to %s database: %w", dialect.name, err)
+	}
+
+	if err := migrateSQL(ctx, db, dialect); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLRepository{
+		db:      db,
+		dialect: dialect,
+	}, nil
+}
+
+// Close closes the underlying database
+func (r *SQLRepository) Close() error {
+	return r.db.Close()
+}
+
+// eventColumns lists the events table columns in scan order
+const eventColumns = "event_id, event_type, client_id, timestamp, payload, version, processed_at, status, error_msg, retry_count, ttl, revision, replays, derived_fields"
+
+// SaveEvent inserts an event, failing with ErrEventExists if it is already stored
+func (r *SQLRepository) SaveEvent(ctx context.Context, event *models.ProcessedEvent) error {
+	values, err := r.eventValues(event)
+	if err != nil {
+		return err
+	}
+
+	query := r.dialect.rebind(`INSERT INTO events (` + eventColumns + `)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (event_id) DO NOTHING`)
+
+	result, err := r.db.ExecContext(ctx, query, values...)
+	if err != nil {
+		return fmt.Errorf("failed to save event to %s: %w", r.dialect.name, err)
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to save event to %s: %w", r.dialect.name, err)
+	}
+	if inserted == 0 {
+		return fmt.Errorf("%w: %s", ErrEventExists, event.EventID)
+	}
+
+	return nil
+}
+
+// ReplaceEvent replaces an event if it is still at the expected revision
+func (r *SQLRepository) ReplaceEvent(ctx context.Context, event *models.ProcessedEvent, expectedRevision int64) error {
+	values, err := r.eventValues(event)
+	if err != nil {
+		return err
+	}
+
+	query := r.dialect.rebind(`UPDATE events SET event_type = ?, client_id = ?, timestamp = ?, payload = ?, version = ?,
+    processed_at = ?, status = ?, error_msg = ?, retry_count = ?, ttl = ?, revision = ?, replays = ?, derived_fields = ?
+WHERE event_id = ? AND revision = ?`)
+
+	result, err := r.db.ExecContext(ctx, query, append(values[1:], event.EventID, expectedRevision)...)
+	if err != nil {
+		return fmt.Errorf("failed to replace event: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to replace event: %w", err)
+	}
+	if updated == 0 {
+		// Tell a missing event from a concurrent modification
+		if _, err := r.GetEvent(ctx, event.EventID); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: event %s was modified concurrently", ErrRevisionConflict, event.EventID)
+	}
+
+	return nil
+}
+
+// eventValues returns the column values of an event in eventColumns order
+func (r *SQLRepository) eventValues(event *models.ProcessedEvent) ([]interface{}, error) {
+	payload, err := json.Marshal(event.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	if event.Payload == nil {
+		payload = []byte("{}")
+	}
+
+	// replays is NULL for events never reprocessed
+	var replays sql.NullString
+	if len(event.Replays) > 0 {
+		encoded, err := json.Marshal(event.Replays)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal replays: %w", err)
+		}
+		replays = sql.NullString{String: string(encoded), Valid: true}
+	}
+
+	// derived_fields is NULL for events processing added nothing to
+	var derivedFields sql.NullString
+	if len(event.DerivedFields) > 0 {
+		encoded, err := json.Marshal(event.DerivedFields)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal derived fields: %w", err)
+		}
+		derivedFields = sql.NullString{String: string(encoded), Valid: true}
+	}
+
+	return []interface{}{
+		event.EventID,
+		string(event.EventType),
+		event.ClientID,
+		r.dialect.timeValue(event.Timestamp),
+		string(payload),
+		event.Version,
+		r.dialect.timeValue(event.ProcessedAt),
+		string(event.Status),
+		event.ErrorMsg,
+		event.RetryCount,
+		event.TTL,
+		event.Revision,
+		replays,
+		derivedFields,
+	}, nil
+}
+
+// GetEvent retrieves a single event by its ID
+func (r *SQLRepository) GetEvent(ctx context.Context, eventID string) (*models.ProcessedEvent, error) {
+	query := r.dialect.rebind("SELECT " + eventColumns + " FROM events WHERE event_id = ?")
+
+	event, err := scanEvent(r.db.QueryRowContext(ctx, query, eventID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrEventNotFound, eventID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event: %w", err)
+	}
+
+	return event, nil
+}
+
+// UpdateEventStatus changes the status of an event if it is still at the expected revision
+func (r *SQLRepository) UpdateEventStatus(ctx context.Context, eventID string, update StatusUpdate) (*models.ProcessedEvent, error) {
+	event, err := r.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkStatusUpdate(event, update); err != nil {
+		return nil, err
+	}
+
+	// The revision and status conditions reject the update if another writer got there first
+	query := r.dialect.rebind(`UPDATE events SET status = ?, error_msg = ?, revision = revision + 1
+WHERE event_id = ? AND revision = ? AND status = ?`)
+
+	result, err := r.db.ExecContext(ctx, query,
+		string(update.Status), update.ErrorMsg, eventID, update.ExpectedRevision, string(event.Status))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update event status: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update event status: %w", err)
+	}
+	if updated == 0 {
+		return nil, fmt.Errorf("%w: event %s was modified concurrently", ErrRevisionConflict, eventID)
+	}
+
+	event.Status = update.Status
+	event.ErrorMsg = update.ErrorMsg
+	event.Revision++
+	return event, nil
+}
+
+// ListEventsByClient lists the events of a client ordered by timestamp
+func (r *SQLRepository) ListEventsByClient(ctx context.Context, clientID string, opts ListOptions) (*EventPage, error) {
+	return r.listEvents(ctx, "client_id", clientID, opts)
+}
+
+// ListEventsByStatus lists the events with a status ordered by timestamp
+func (r *SQLRepository) ListEventsByStatus(ctx context.Context, status models.EventStatus, opts ListOptions) (*EventPage, error) {
+	return r.listEvents(ctx, "status", string(status), opts)
+}
+
+// listEvents performs a keyset-paginated listing over an indexed column
+func (r *SQLRepository) listEvents(ctx context.Context, column, value string, opts ListOptions) (*EventPage, error) {
+	conditions := []string{column + " = ?"}
+	args := []interface{}{value}
+
+	if !opts.From.IsZero() {
+		conditions = append(conditions, "timestamp >= ?")
+		args = append(args, r.dialect.timeValue(opts.From))
+	}
+	if !opts.To.IsZero() {
+		conditions = append(conditions, "timestamp <= ?")
+		args = append(args, r.dialect.timeValue(opts.To))
+	}
+
+	if opts.Cursor != "" {
+		afterTimestamp, afterID, err := decodeKeysetCursor(opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, "(timestamp > ? OR (timestamp = ? AND event_id > ?))")
+		args = append(args, r.dialect.timeValue(afterTimestamp), r.dialect.timeValue(afterTimestamp), afterID)
+	}
+
+	// Fetch one extra row to find out whether there is a next page
+	pageSize := opts.PageSize()
+	args = append(args, pageSize+1)
+
+	query := r.dialect.rebind("SELECT " + eventColumns + " FROM events WHERE " +
+		strings.Join(conditions, " AND ") + " ORDER BY timestamp, event_id LIMIT ?")
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events by %s: %w", column, err)
+	}
+	defer rows.Close()
+
+	page := &EventPage{Events: make([]*models.ProcessedEvent, 0, pageSize)}
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list events by %s: %w", column, err)
+		}
+		page.Events = append(page.Events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list events by %s: %w", column, err)
+	}
+
+	if len(page.Events) > pageSize {
+		page.Events = page.Events[:pageSize]
+		last := page.Events[pageSize-1]
+		if page.NextCursor, err = encodeKeysetCursor(last); err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanEvent scans a row selected with eventColumns
+func scanEvent(row rowScanner) (*models.ProcessedEvent, error) {
+	var (
+		event         models.ProcessedEvent
+		eventType     string
+		status        string
+		payload       []byte
+		replays       []byte
+		derivedFields []byte
+	)
+
+	err := row.Scan(
+		&event.EventID,
+		&eventType,
+		&event.ClientID,
+		sqlTime{&event.Timestamp},
+		&payload,
+		&event.Version,
+		sqlTime{&event.ProcessedAt},
+		&status,
+		&event.ErrorMsg,
+		&event.RetryCount,
+		&event.TTL,
+		&event.Revision,
+		&replays,
+		&derivedFields,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	event.EventType = models.EventType(eventType)
+	event.Status = models.EventStatus(status)
+	if event.Payload, err = decodeJSONPayload(payload); err != nil {
+		return nil, fmt.Errorf("invalid payload for event %s: %w", event.EventID, err)
+	}
+	if len(replays) > 0 {
+		if err := json.Unmarshal(replays, &event.Replays); err != nil {
+			return nil, fmt.Errorf("invalid replays for event %s: %w", event.EventID, err)
+		}
+	}
+	if len(derivedFields) > 0 {
+		if err := json.Unmarshal(derivedFields, &event.DerivedFields); err != nil {
+			return nil, fmt.Errorf("invalid derived fields for event %s: %w", event.EventID, err)
+		}
+	}
+
+	return &event, nil
+}
+
+// decodeJSONPayload decodes a stored JSON payload using the same number rules as the DynamoDB backend
+func decodeJSONPayload(data []byte) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var payload map[string]interface{}
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, err
+	}
+	return normalizeNumbers(payload).(map[string]interface{}), nil
+}
+
+// normalizeNumbers replaces json.Number values with float64 wherever that is exact
+func normalizeNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		return unmarshalNumber(string(v))
+	case map[string]interface{}:
+		for key, element := range v {
+			v[key] = normalizeNumbers(element)
+		}
+		return v
+	case []interface{}:
+		for i, element := range v {
+			v[i] = normalizeNumbers(element)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// GetClientConfig retrieves client configuration
+func (r *SQLRepository) GetClientConfig(ctx context.Context, clientID string) (*models.ClientConfig, error) {
+	query := r.dialect.rebind("SELECT client_id, allowed_types, config, active, policies, limits FROM clients WHERE client_id = ?")
+
+	config, err := scanClientConfig(r.db.QueryRowContext(ctx, query, clientID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrClientConfigNotFound, clientID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client config: %w", err)
+	}
+	return config, nil
+}
+
+// PutClientConfig stores a client configuration, replacing any existing one
+func (r *SQLRepository) PutClientConfig(ctx context.Context, config *models.ClientConfig) error {
+	columns, err := marshalClientConfig(config)
+	if err != nil {
+		return err
+	}
+
+	query := r.dialect.rebind(`INSERT INTO clients (client_id, allowed_types, config, active, policies, limits) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (client_id) DO UPDATE SET allowed_types = excluded.allowed_types, config = excluded.config,
+		active = excluded.active, policies = excluded.policies, limits = excluded.limits`)
+	if _, err := r.db.ExecContext(ctx, query, config.ClientID, columns.allowedTypes, columns.settings, config.Active, columns.policies, columns.limits); err != nil {
+		return fmt.Errorf("failed to put client config %s: %w", config.ClientID, err)
+	}
+	return nil
+}
+
+// CreateClientConfig inserts a client configuration, failing with ErrClientConfigExists if the client has one
+func (r *SQLRepository) CreateClientConfig(ctx context.Context, config *models.ClientConfig) error {
+	columns, err := marshalClientConfig(config)
+	if err != nil {
+		return err
+	}
+
+	query := r.dialect.rebind(`INSERT INTO clients (client_id, allowed_types, config, active, policies, limits) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (client_id) DO NOTHING`)
+	result, err := r.db.ExecContext(ctx, query, config.ClientID, columns.allowedTypes, columns.settings, config.Active, columns.policies, columns.limits)
+	if err != nil {
+		return fmt.Errorf("failed to create client config %s: %w", config.ClientID, err)
+	}
+
+	created, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to create client config %s: %w", config.ClientID, err)
+	}
+	if created == 0 {
+		return fmt.Errorf("%w: %s", ErrClientConfigExists, config.ClientID)
+	}
+	return nil
+}
+
+// ListClientConfigs returns every client configuration ordered by client ID
+func (r *SQLRepository) ListClientConfigs(ctx context.Context) ([]*models.ClientConfig, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT client_id, allowed_types, config, active, policies, limits FROM clients ORDER BY client_id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list client configs: %w", err)
+	}
+	defer rows.Close()
+
+	var configs []*models.ClientConfig
+	for rows.Next() {
+		config, err := scanClientConfig(rows)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, config)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list client configs: %w", err)
+	}
+	return configs, nil
+}
+
+// DeleteClientConfig removes a client configuration
+func (r *SQLRepository) DeleteClientConfig(ctx context.Context, clientID string) error {
+	result, err := r.db.ExecContext(ctx, r.dialect.rebind("DELETE FROM clients WHERE client_id = ?"), clientID)
+	if err != nil {
+		return fmt.Errorf("failed to delete client config %s: %w", clientID, err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete client config %s: %w", clientID, err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("%w: %s", ErrClientConfigNotFound, clientID)
+	}
+	return nil
+}
+
+// clientConfigColumns holds the JSON columns of a client configuration
+type clientConfigColumns struct {
+	allowedTypes string
+	settings     string
+	policies     string
+
+	// limits is NULL for clients without limits
+	limits sql.NullString
+}
+
+// marshalClientConfig encodes the JSON columns of a client configuration
+func marshalClientConfig(config *models.ClientConfig) (clientConfigColumns, error) {
+	allowedTypes := config.AllowedTypes
+	if allowedTypes == nil {
+		allowedTypes = []models.EventType{}
+	}
+	encodedTypes, err := json.Marshal(allowedTypes)
+	if err != nil {
+		return clientConfigColumns{}, fmt.Errorf("failed to marshal allowed types: %w", err)
+	}
+
+	settings := config.Config
+	if settings == nil {
+		settings = map[string]string{}
+	}
+	encodedSettings, err := json.Marshal(settings)
+	if err != nil {
+		return clientConfigColumns{}, fmt.Errorf("failed to marshal client config: %w", err)
+	}
+
+	policies := config.Policies
+	if policies == nil {
+		policies = map[models.EventType]models.EventPolicy{}
+	}
+	encodedPolicies, err := json.Marshal(policies)
+	if err != nil {
+		return clientConfigColumns{}, fmt.Errorf("failed to marshal client policies: %w", err)
+	}
+
+	columns := clientConfigColumns{
+		allowedTypes: string(encodedTypes),
+		settings:     string(encodedSettings),
+		policies:     string(encodedPolicies),
+	}
+	if config.Limits != nil {
+		encodedLimits, err := json.Marshal(config.Limits)
+		if err != nil {
+			return clientConfigColumns{}, fmt.Errorf("failed to marshal client limits: %w", err)
+		}
+		columns.limits = sql.NullString{String: string(encodedLimits), Valid: true}
+	}
+	return columns, nil
+}
+
+// scanClientConfig reads a client configuration from a row of client_id, allowed_types, config, active,
+// policies, limits
+func scanClientConfig(row rowScanner) (*models.ClientConfig, error) {
+	var (
+		config       models.ClientConfig
+		allowedTypes []byte
+		settings     []byte
+		policies     []byte
+		limits       []byte
+	)
+	if err := row.Scan(&config.ClientID, &allowedTypes, &settings, &config.Active, &policies, &limits); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(allowedTypes, &config.AllowedTypes); err != nil {
+		return nil, fmt.Errorf("invalid allowed_types for client %s: %w", config.ClientID, err)
+	}
+	if err := json.Unmarshal(settings, &config.Config); err != nil {
+		return nil, fmt.Errorf("invalid config for client %s: %w", config.ClientID, err)
+	}
+	if err := json.Unmarshal(policies, &config.Policies); err != nil {
+		return nil, fmt.Errorf("invalid policies for client %s: %w", config.ClientID, err)
+	}
+	if len(limits) > 0 {
+		if err := json.Unmarshal(limits, &config.Limits); err != nil {
+			return nil, fmt.Errorf("invalid limits for client %s: %w", config.ClientID, err)
+		}
+	}
+	if len(config.AllowedTypes) == 0 {
+		config.AllowedTypes = nil
+	}
+	if len(config.Config) == 0 {
+		config.Config = nil
+	}
+	if len(config.Policies) == 0 {
+		config.Policies = nil
+	}
+	return &config, nil
+}
+
+// RecordClientChange inserts a change into the client history
+func (r *SQLRepository) RecordClientChange(ctx context.Context, change models.ClientChange) error {
+	before, err := marshalNullableConfig(change.Before)
+	if err != nil {
+		return fmt.Errorf("failed to marshal client change: %w", err)
+	}
+	after, err := marshalNullableConfig(change.After)
+	if err != nil {
+		return fmt.Errorf("failed to marshal client change: %w", err)
+	}
+
+	query := r.dialect.rebind(`INSERT INTO client_changes (client_id, changed_at, actor, action, before_config, after_config)
+		VALUES (?, ?, ?, ?, ?, ?)`)
+	if _, err := r.db.ExecContext(ctx, query, change.ClientID, r.dialect.timeValue(change.Time), change.Actor, string(change.Action), before, after); err != nil {
+		return fmt.Errorf("failed to record change to client %s: %w", change.ClientID, err)
+	}
+	return nil
+}
+
+// ListClientChanges lists the changes to a client in the order they were made, or of every client
+// when clientID is empty
+func (r *SQLRepository) ListClientChanges(ctx context.Context, clientID string) ([]models.ClientChange, error) {
+	query := "SELECT client_id, changed_at, actor, action, before_config, after_config FROM client_changes"
+	var args []interface{}
+	if clientID != "" {
+		query += " WHERE client_id = ?"
+		args = append(args, clientID)
+	}
+	query += " ORDER BY changed_at, change_id"
+
+	rows, err := r.db.QueryContext(ctx, r.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list client changes: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []models.ClientChange
+	for rows.Next() {
+		var (
+			change models.ClientChange
+			action string
+			before []byte
+			after  []byte
+		)
+		if err := rows.Scan(&change.ClientID, sqlTime{&change.Time}, &change.Actor, &action, &before, &after); err != nil {
+			return nil, fmt.Errorf("failed to list client changes: %w", err)
+		}
+		change.Action = models.ClientAction(action)
+		if len(before) > 0 {
+			if err := json.Unmarshal(before, &change.Before); err != nil {
+				return nil, fmt.Errorf("invalid before_config for change to client %s: %w", change.ClientID, err)
+			}
+		}
+		if len(after) > 0 {
+			if err := json.Unmarshal(after, &change.After); err != nil {
+				return nil, fmt.Errorf("invalid after_config for change to client %s: %w", change.ClientID, err)
+			}
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list client changes: %w", err)
+	}
+	return changes, nil
+}
+
+// marshalNullableConfig encodes a configuration for a nullable JSON column, NULL for nil configurations
+func marshalNullableConfig(config *models.ClientConfig) (sql.NullString, error) {
+	if config == nil {
+		return sql.NullString{}, nil
+	}
+	encoded, err := json.Marshal(config)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(encoded), Valid: true}, nil
+}
+
+// quarantineColumns lists the quarantined_events table columns in scan order
+const quarantineColumns = "quarantine_id, category, client_id, event_id, event_type, errors, body, quarantined_at, validation"
+
+// QuarantineEvent inserts or replaces a quarantined event
+func (r *SQLRepository) QuarantineEvent(ctx context.Context, event *models.QuarantinedEvent) error {
+	errorMessages := event.Errors
+	if errorMessages == nil {
+		errorMessages = []string{}
+	}
+	encodedErrors, err := json.Marshal(errorMessages)
+	if err != nil {
+		return fmt.Errorf("failed to marshal quarantine errors: %w", err)
+	}
+
+	// validation is NULL for events not rejected by a schema
+	var validation sql.NullString
+	if event.Validation != nil {
+		encoded, err := json.Marshal(event.Validation)
+		if err != nil {
+			return fmt.Errorf("failed to marshal quarantine validation: %w", err)
+		}
+		validation = sql.NullString{String: string(encoded), Valid: true}
+	}
+
+	query := r.dialect.rebind(`INSERT INTO quarantined_events (` + quarantineColumns + `)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (quarantine_id) DO UPDATE SET
+    category = excluded.category,
+    client_id = excluded.client_id,
+    event_id = excluded.event_id,
+    event_type = excluded.event_type,
+    errors = excluded.errors,
+    body = excluded.body,
+    quarantined_at = excluded.quarantined_at,
+    validation = excluded.validation`)
+
+	_, err = r.db.ExecContext(ctx, query,
+		event.QuarantineID,
+		string(event.Category),
+		event.ClientID,
+		event.EventID,
+		string(event.EventType),
+		string(encodedErrors),
+		event.Body,
+		r.dialect.timeValue(event.QuarantinedAt),
+		validation,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to quarantine event %s: %w", event.QuarantineID, err)
+	}
+	return nil
+}
+
+// GetQuarantinedEvent retrieves a quarantined event by its quarantine ID
+func (r *SQLRepository) GetQuarantinedEvent(ctx context.Context, quarantineID string) (*models.QuarantinedEvent, error) {
+	query := r.dialect.rebind("SELECT " + quarantineColumns + " FROM quarantined_events WHERE quarantine_id = ?")
+
+	event, err := scanQuarantinedEvent(r.db.QueryRowContext(ctx, query, quarantineID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrQuarantinedEventNotFound, quarantineID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quarantined event: %w", err)
+	}
+	return event, nil
+}
+
+// ListQuarantinedEvents lists the matching quarantined events, most recent first
+func (r *SQLRepository) ListQuarantinedEvents(ctx context.Context, filter QuarantineFilter) ([]*models.QuarantinedEvent, error) {
+	conditions := []string{"1 = 1"}
+	var args []interface{}
+	if filter.ClientID != "" {
+		conditions = append(conditions, "client_id = ?")
+		args = append(args, filter.ClientID)
+	}
+	if filter.Category != "" {
+		conditions = append(conditions, "category = ?")
+		args = append(args, string(filter.Category))
+	}
+	args = append(args, filter.PageSize())
+
+	query := r.dialect.rebind("SELECT " + quarantineColumns + " FROM quarantined_events WHERE " +
+		strings.Join(conditions, " AND ") + " ORDER BY quarantined_at DESC, quarantine_id LIMIT ?")
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list quarantined events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]*models.QuarantinedEvent, 0)
+	for rows.Next() {
+		event, err := scanQuarantinedEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list quarantined events: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list quarantined events: %w", err)
+	}
+	return events, nil
+}
+
+// DeleteQuarantinedEvent removes a quarantined event
+func (r *SQLRepository) DeleteQuarantinedEvent(ctx context.Context, quarantineID string) error {
+	result, err := r.db.ExecContext(ctx, r.dialect.rebind("DELETE FROM quarantined_events WHERE quarantine_id = ?"), quarantineID)
+	if err != nil {
+		return fmt.Errorf("failed to delete quarantined event %s: %w", quarantineID, err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete quarantined event %s: %w", quarantineID, err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("%w: %s", ErrQuarantinedEventNotFound, quarantineID)
+	}
+	return nil
+}
+
+// scanQuarantinedEvent scans a row selected with quarantineColumns
+func scanQuarantinedEvent(row rowScanner) (*models.QuarantinedEvent, error) {
+	var (
+		event         models.QuarantinedEvent
+		category      string
+		eventType     string
+		errorMessages []byte
+		validation    []byte
+	)
+
+	err := row.Scan(
+		&event.QuarantineID,
+		&category,
+		&event.ClientID,
+		&event.EventID,
+		&eventType,
+		&errorMessages,
+		&event.Body,
+		sqlTime{&event.QuarantinedAt},
+		&validation,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	event.Category = models.QuarantineCategory(category)
+	event.EventType = models.EventType(eventType)
+	if err := json.Unmarshal(errorMessages, &event.Errors); err != nil {
+		return nil, fmt.Errorf("invalid errors for quarantined event %s: %w", event.QuarantineID, err)
+	}
+	if event.Errors == nil {
+		event.Errors = []string{}
+	}
+	if len(validation) > 0 {
+		if err := json.Unmarshal(validation, &event.Validation); err != nil {
+			return nil, fmt.Errorf("invalid validation for quarantined event %s: %w", event.QuarantineID, err)
+		}
+	}
+	return &event, nil
+}
+
+// HealthCheck verifies the database connection
+func (r *SQLRepository) HealthCheck(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
@@ -0,0 +1,155 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d-sense/event-processor/internal/config"
+)
+
+// postgresTestURLEnv names the variable pointing the PostgreSQL tests at a disposable database
+const postgresTestURLEnv = "POSTGRES_TEST_URL"
+
+// TestSQLiteRepositoryContract runs the repository contract against an embedded SQLite database
+func TestSQLiteRepositoryContract(t *testing.T) {
+	runRepositoryContract(t, repositoryBackend{
+		newRepository: func(t *testing.T) Repository {
+			repo, err := NewSQLiteRepository(context.Background(), filepath.Join(t.TempDir(), "events.db"))
+			require.NoError(t, err)
+			t.Cleanup(func() { repo.Close() })
+			return repo
+		},
+	})
+}
+
+// TestPostgresRepositoryContract runs the repository contract against PostgreSQL when one is configured
+func TestPostgresRepositoryContract(t *testing.T) {
+	dsn := os.Getenv(postgresTestURLEnv)
+	if dsn == "" {
+		t.Skipf("%s not set", postgresTestURLEnv)
+	}
+
+	runRepositoryContract(t, repositoryBackend{
+		newRepository: func(t *testing.T) Repository {
+			repo, err := NewPostgresRepository(context.Background(), dsn)
+			require.NoError(t, err)
+			t.Cleanup(func() { repo.Close() })
+
+			_, err = repo.db.Exec("TRUNCATE events, clients")
+			require.NoError(t, err)
+			return repo
+		},
+	})
+}
+
+// TestSQLiteMigrations tests that migrations are recorded and applied only once
+func TestSQLiteMigrations(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "events.db")
+
+	migrations, err := loadSQLMigrations(sqliteDialect.name)
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i := 0; i < 2; i++ {
+		repo, err := NewSQLiteRepository(ctx, path)
+		require.NoError(t, err)
+
+		var applied int
+		require.NoError(t, repo.db.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&applied))
+		assert.Equal(t, len(migrations), applied)
+		repo.Close()
+	}
+}
+
+// TestLoadSQLMigrations tests that every dialect has the same ordered migrations
+func TestLoadSQLMigrations(t *testing.T) {
+	sqlite, err := loadSQLMigrations(sqliteDialect.name)
+	require.NoError(t, err)
+	postgres, err := loadSQLMigrations(postgresDialect.name)
+	require.NoError(t, err)
+
+	require.Equal(t, len(sqlite), len(postgres))
+	for i := range sqlite {
+		assert.Equal(t, i+1, sqlite[i].Version)
+		assert.Equal(t, sqlite[i].Name, postgres[i].Name)
+	}
+
+	_, err = loadSQLMigrations("oracle")
+	assert.Error(t, err)
+}
+
+// TestRebind tests placeholder rewriting per dialect
+func TestRebind(t *testing.T) {
+	query := "SELECT * FROM events WHERE client_id = ? AND timestamp > ? LIMIT ?"
+
+	assert.Equal(t, query, sqliteDialect.rebind(query))
+	assert.Equal(t, "SELECT * FROM events WHERE client_id = $1 AND timestamp > $2 LIMIT $3", postgresDialect.rebind(query))
+}
+
+// TestNewRepository tests backend selection from configuration
+func TestNewRepository(t *testing.T) {
+	tests := []struct {
+		name        string
+		backend     string
+		databaseURL string
+		expectError bool
+	}{
+		{name: "Default Backend", backend: ""},
+		{name: "DynamoDB", backend: BackendDynamoDB},
+		{name: "SQLite", backend: BackendSQLite, databaseURL: filepath.Join(t.TempDir(), "events.db")},
+		{name: "Memory", backend: BackendMemory},
+		{name: "Postgres Without URL", backend: BackendPostgres, expectError: true},
+		{name: "Unsupported Backend", backend: "cassandra", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				DynamoDBTableName: "events",
+				StorageBackend:    tt.backend,
+				DatabaseURL:       tt.databaseURL,
+			}
+
+			repo, err := NewRepository(context.Background(), aws.Config{}, cfg)
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Nil(t, repo)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.NotNil(t, repo)
+			if sqlRepo, ok := repo.(*SQLRepository); ok {
+				sqlRepo.Close()
+			}
+		})
+	}
+}
+
+// TestSQLRepositoryPreservesNumbers tests that JSON payload numbers keep every digit
+func TestSQLRepositoryPreservesNumbers(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewSQLiteRepository(ctx, filepath.Join(t.TempDir(), "events.db"))
+	require.NoError(t, err)
+	defer repo.Close()
+
+	event := createValidProcessedEvent()
+	event.Payload = map[string]interface{}{
+		"price":  json.Number("0.10"),
+		"big":    json.Number("98765432109876543210"),
+		"nested": map[string]interface{}{"n": 42.0},
+	}
+	require.NoError(t, repo.SaveEvent(ctx, event))
+
+	stored, err := repo.GetEvent(ctx, event.EventID)
+	require.NoError(t, err)
+	assert.Equal(t, event.Payload, stored.Payload)
+}
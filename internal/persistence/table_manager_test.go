@@ -13,31 +13,6 @@ import (
 )
 
 // Test data structures
-type createNewLocalTablesTestCase struct {
-	name           string
-	existingTables []string
-	mockClient     func(*MockDynamoDBClient)
-	expectError    bool
-	errorMsg       string
-	description    string
-}
-
-type createEventsTableTestCase struct {
-	name        string
-	mockClient  func(*MockDynamoDBClient)
-	expectError bool
-	errorMsg    string
-	description string
-}
-
-type createEventsClientsTableTestCase struct {
-	name        string
-	mockClient  func(*MockDynamoDBClient)
-	expectError bool
-	errorMsg    string
-	description string
-}
-
 type insertSampleClientConfigsTestCase struct {
 	name        string
 	mockClient  func(*MockDynamoDBClient)
@@ -97,238 +72,6 @@ func TestNewTableManager(t *testing.T) {
 	})
 }
 
-// TestCreateNewLocalTables tests the CreateNewLocalTables method
-func TestCreateNewLocalTables(t *testing.T) {
-	tests := []createNewLocalTablesTestCase{
-		{
-			name:           "Successful Table Creation - No Existing Tables",
-			existingTables: []string{},
-			mockClient: func(mc *MockDynamoDBClient) {
-				// Mock ListTables call
-				mc.On("ListTables", mock.Anything, mock.AnythingOfType("*dynamodb.ListTablesInput")).Return(&dynamodb.ListTablesOutput{
-					TableNames: []string{},
-				}, nil)
-				// Mock CreateTable calls for both tables
-				mc.On("CreateTable", mock.Anything, mock.AnythingOfType("*dynamodb.CreateTableInput")).Return(&dynamodb.CreateTableOutput{}, nil).Times(2)
-			},
-			expectError: false,
-			description: "Should successfully create both tables when none exist",
-		},
-		{
-			name:           "Successful Table Creation - Some Existing Tables",
-			existingTables: []string{"other-table"},
-			mockClient: func(mc *MockDynamoDBClient) {
-				// Mock ListTables call
-				mc.On("ListTables", mock.Anything, mock.AnythingOfType("*dynamodb.ListTablesInput")).Return(&dynamodb.ListTablesOutput{
-					TableNames: []string{"other-table"},
-				}, nil)
-				// Mock CreateTable calls for both tables
-				mc.On("CreateTable", mock.Anything, mock.AnythingOfType("*dynamodb.CreateTableInput")).Return(&dynamodb.CreateTableOutput{}, nil).Times(2)
-			},
-			expectError: false,
-			description: "Should successfully create both tables when other tables exist",
-		},
-		{
-			name:           "ListTables Failure",
-			existingTables: []string{},
-			mockClient: func(mc *MockDynamoDBClient) {
-				mc.On("ListTables", mock.Anything, mock.AnythingOfType("*dynamodb.ListTablesInput")).Return(nil, errors.New("list tables error"))
-			},
-			expectError: true,
-			errorMsg:    "failed to list tables",
-			description: "Should fail when ListTables operation fails",
-		},
-		{
-			name:           "Events Table Creation Failure",
-			existingTables: []string{},
-			mockClient: func(mc *MockDynamoDBClient) {
-				// Mock ListTables call
-				mc.On("ListTables", mock.Anything, mock.AnythingOfType("*dynamodb.ListTablesInput")).Return(&dynamodb.ListTablesOutput{
-					TableNames: []string{},
-				}, nil)
-				// Mock CreateTable call for events table fails
-				mc.On("CreateTable", mock.Anything, mock.AnythingOfType("*dynamodb.CreateTableInput")).Return(nil, errors.New("create events table error"))
-			},
-			expectError: true,
-			errorMsg:    "failed to create events table",
-			description: "Should fail when events table creation fails",
-		},
-		{
-			name:           "EventsClients Table Creation Failure",
-			existingTables: []string{},
-			mockClient: func(mc *MockDynamoDBClient) {
-				// Mock ListTables call
-				mc.On("ListTables", mock.Anything, mock.AnythingOfType("*dynamodb.ListTablesInput")).Return(&dynamodb.ListTablesOutput{
-					TableNames: []string{},
-				}, nil)
-				// Mock CreateTable call for events table succeeds, but events-clients fails
-				mc.On("CreateTable", mock.Anything, mock.AnythingOfType("*dynamodb.CreateTableInput")).Return(&dynamodb.CreateTableOutput{}, nil).Once()
-				mc.On("CreateTable", mock.Anything, mock.AnythingOfType("*dynamodb.CreateTableInput")).Return(nil, errors.New("create events-clients table error")).Once()
-			},
-			expectError: true,
-			errorMsg:    "failed to create events-clients table",
-			description: "Should fail when events-clients table creation fails",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Create mocks
-			mockClient := &MockDynamoDBClient{}
-			logger := logrus.New()
-			logger.SetLevel(logrus.DebugLevel)
-
-			// Setup mocks
-			if tt.mockClient != nil {
-				tt.mockClient(mockClient)
-			}
-
-			// Create table manager with mock client
-			manager := &TableManager{
-				client:     mockClient,
-				tableNames: DefaultTableNames(),
-				logger:     logger,
-			}
-
-			// Execute test
-			err := manager.CreateNewLocalTables(context.Background())
-
-			// Assertions
-			if tt.expectError {
-				assert.Error(t, err)
-				if tt.errorMsg != "" {
-					assert.Contains(t, err.Error(), tt.errorMsg)
-				}
-			} else {
-				assert.NoError(t, err)
-			}
-
-			// Verify mocks
-			mockClient.AssertExpectations(t)
-		})
-	}
-}
-
-// TestCreateEventsTable tests the createEventsTable method
-func TestCreateEventsTable(t *testing.T) {
-	tests := []createEventsTableTestCase{
-		{
-			name: "Successful Events Table Creation",
-			mockClient: func(mc *MockDynamoDBClient) {
-				mc.On("CreateTable", mock.Anything, mock.AnythingOfType("*dynamodb.CreateTableInput")).Return(&dynamodb.CreateTableOutput{}, nil)
-			},
-			expectError: false,
-			description: "Should successfully create events table with correct schema",
-		},
-		{
-			name: "Events Table Creation Failure",
-			mockClient: func(mc *MockDynamoDBClient) {
-				mc.On("CreateTable", mock.Anything, mock.AnythingOfType("*dynamodb.CreateTableInput")).Return(nil, errors.New("create table error"))
-			},
-			expectError: true,
-			errorMsg:    "unable to create 'events' DynamoDB table",
-			description: "Should fail when CreateTable operation fails",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Create mocks
-			mockClient := &MockDynamoDBClient{}
-			logger := logrus.New()
-			logger.SetLevel(logrus.DebugLevel)
-
-			// Setup mocks
-			if tt.mockClient != nil {
-				tt.mockClient(mockClient)
-			}
-
-			// Create table manager with mock client
-			manager := &TableManager{
-				client:     mockClient,
-				tableNames: DefaultTableNames(),
-				logger:     logger,
-			}
-
-			// Execute test
-			err := manager.createEventsTable(context.Background())
-
-			// Assertions
-			if tt.expectError {
-				assert.Error(t, err)
-				if tt.errorMsg != "" {
-					assert.Contains(t, err.Error(), tt.errorMsg)
-				}
-			} else {
-				assert.NoError(t, err)
-			}
-
-			// Verify mocks
-			mockClient.AssertExpectations(t)
-		})
-	}
-}
-
-// TestCreateEventsClientsTable tests the createEventsClientsTable method
-func TestCreateEventsClientsTable(t *testing.T) {
-	tests := []createEventsClientsTableTestCase{
-		{
-			name: "Successful EventsClients Table Creation",
-			mockClient: func(mc *MockDynamoDBClient) {
-				mc.On("CreateTable", mock.Anything, mock.AnythingOfType("*dynamodb.CreateTableInput")).Return(&dynamodb.CreateTableOutput{}, nil)
-			},
-			expectError: false,
-			description: "Should successfully create events-clients table with correct schema",
-		},
-		{
-			name: "EventsClients Table Creation Failure",
-			mockClient: func(mc *MockDynamoDBClient) {
-				mc.On("CreateTable", mock.Anything, mock.AnythingOfType("*dynamodb.CreateTableInput")).Return(nil, errors.New("create table error"))
-			},
-			expectError: true,
-			errorMsg:    "unable to create 'events-clients' DynamoDB table",
-			description: "Should fail when CreateTable operation fails",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Create mocks
-			mockClient := &MockDynamoDBClient{}
-			logger := logrus.New()
-			logger.SetLevel(logrus.DebugLevel)
-
-			// Setup mocks
-			if tt.mockClient != nil {
-				tt.mockClient(mockClient)
-			}
-
-			// Create table manager with mock client
-			manager := &TableManager{
-				client:     mockClient,
-				tableNames: DefaultTableNames(),
-				logger:     logger,
-			}
-
-			// Execute test
-			err := manager.createEventsClientsTable(context.Background())
-
-			// Assertions
-			if tt.expectError {
-				assert.Error(t, err)
-				if tt.errorMsg != "" {
-					assert.Contains(t, err.Error(), tt.errorMsg)
-				}
-			} else {
-				assert.NoError(t, err)
-			}
-
-			// Verify mocks
-			mockClient.AssertExpectations(t)
-		})
-	}
-}
-
 // TestInsertSampleClientConfigs tests the InsertSampleClientConfigs method
 func TestInsertSampleClientConfigs(t *testing.T) {
 	tests := []insertSampleClientConfigsTestCase{
@@ -2,12 +2,24 @@ package processor
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"strconv"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 
+	"github.com/d-sense/event-processor/internal/authz"
+	"github.com/d-sense/event-processor/internal/metrics"
 	"github.com/d-sense/event-processor/internal/persistence"
+	"github.com/d-sense/event-processor/internal/ratelimit"
+	"github.com/d-sense/event-processor/internal/retention"
+	"github.com/d-sense/event-processor/internal/validator"
 	"github.com/d-sense/event-processor/pkg/logger"
 	"github.com/d-sense/event-processor/pkg/models"
 )
@@ -22,18 +34,122 @@ type Validator interface {
 	ValidateAndParseEvent(eventData interface{}) (*models.Event, error)
 }
 
+// Upcaster defines the contract for transforming events of older versions into the current, canonical
+// shape of their event type, e.g. validator.Validator
+type Upcaster interface {
+	Upcast(event *models.Event) (*models.Event, error)
+}
+
+// Authorizer defines the contract for deciding whether a client may send an event
+type Authorizer interface {
+	Authorize(ctx context.Context, event *models.Event, receivedAt time.Time) (*models.ClientConfig, error)
+}
+
+// Limiter defines the contract for enforcing the rate limits and quotas of a client
+type Limiter interface {
+	Admit(ctx context.Context, event *models.Event, config *models.ClientConfig) error
+}
+
+// Quarantine defines the contract for keeping events that retrying cannot fix, e.g. persistence.QuarantineStore
+type Quarantine interface {
+	QuarantineEvent(ctx context.Context, event *models.QuarantinedEvent) error
+}
+
+// ErrInvalidPayload is returned for events whose payload cannot be upcast to the current version of
+// their event type
+var ErrInvalidPayload = errors.New("invalid payload")
+
+// Stages of processing an event, reported by Classify
+const (
+	StageValidation  = "validation"
+	StageTriage      = "triage"
+	StagePersistence = "persistence"
+	StageQuarantine  = "quarantine"
+	StageUnknown     = "unknown"
+)
+
+// Error categories reported by Classify
+const (
+	CategoryInvalidEvent  = "invalid_event"
+	CategoryAuthorization = "authorization"
+	CategoryClientLookup  = "client_lookup"
+	CategoryClientLimits  = "client_limits"
+	CategoryStorage       = "storage"
+	CategoryUnknown       = "unknown"
+)
+
+// StageError is returned by ProcessEvent for events that failed, naming the stage that failed
+type StageError struct {
+	Stage string
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// Classify returns the stage and error category of an error returned by ProcessEvent; errors of
+// other processors are of an unknown stage and category
+func Classify(err error) (string, string) {
+	stage := StageUnknown
+	var stageErr *StageError
+	if errors.As(err, &stageErr) {
+		stage = stageErr.Stage
+	}
+
+	switch {
+	case errors.Is(err, ErrInvalidPayload), stage == StageValidation:
+		return stage, CategoryInvalidEvent
+	case errors.Is(err, authz.ErrDenied):
+		return stage, CategoryAuthorization
+	case errors.Is(err, authz.ErrLookupFailed):
+		return stage, CategoryClientLookup
+	case errors.Is(err, ratelimit.ErrLimitExceeded):
+		return stage, CategoryClientLimits
+	case stage == StagePersistence, stage == StageQuarantine:
+		return stage, CategoryStorage
+	default:
+		return stage, CategoryUnknown
+	}
+}
+
 // EventProcessor handles the core event processing logic
 type EventProcessor struct {
 	repository persistence.Repository
 	validator  Validator
+	upcaster   Upcaster
+	authorizer Authorizer
+	limiter    Limiter
+	quarantine Quarantine
+	ttlPolicy  *retention.Policy
 	logger     *logrus.Logger
 }
 
-// New creates a new EventProcessor instance
-func New(repo persistence.Repository, validator Validator, logger *logrus.Logger) *EventProcessor {
+// New creates a new EventProcessor instance; a nil authorizer allows events of unknown clients, a
+// nil limiter enforces no client limits, a nil quarantine fails rejected events like any other
+// error and a nil ttlPolicy uses the default retention policy. Events are upcast before triage if the
+// validator is also an Upcaster.
+func New(repo persistence.Repository, validator Validator, authorizer Authorizer, limiter Limiter, quarantine Quarantine, ttlPolicy *retention.Policy, logger *logrus.Logger) *EventProcessor {
+	if authorizer == nil {
+		authorizer = authz.New(repo, authz.ModeAllowUnknown, logger)
+	}
+	if ttlPolicy == nil {
+		ttlPolicy = retention.DefaultPolicy()
+	}
+	upcaster, _ := validator.(Upcaster)
+
 	return &EventProcessor{
 		repository: repo,
 		validator:  validator,
+		upcaster:   upcaster,
+		authorizer: authorizer,
+		limiter:    limiter,
+		quarantine: quarantine,
+		ttlPolicy:  ttlPolicy,
 		logger:     logger,
 	}
 }
@@ -57,7 +173,11 @@ func (p *EventProcessor) ProcessEvent(ctx context.Context, eventData interface{}
 	event, err := p.validator.ValidateAndParseEvent(eventData)
 	if err != nil {
 		logger.WithError(err).Error("Event validation failed")
-		return fmt.Errorf("validation failed: %w", err)
+		metrics.EventsFailed.WithLabelValues(metrics.ReasonValidation, metrics.Unknown, metrics.Unknown).Inc()
+		if p.quarantine != nil {
+			return p.quarantineEvent(ctx, eventData, nil, models.QuarantineValidation, err, logger)
+		}
+		return &StageError{Stage: StageValidation, Err: fmt.Errorf("validation failed: %w", err)}
 	}
 
 	// Add event context using standard logrus methods
@@ -68,71 +188,266 @@ func (p *EventProcessor) ProcessEvent(ctx context.Context, eventData interface{}
 	logger.Info("Event validated successfully")
 
 	// Step 2: Perform event triage
-	processedEvent, err := p.triageEvent(ctx, event, logger)
+	processedEvent, err := p.triageEvent(ctx, event, receivedAt(eventData, startTime), p.limiter, logger)
+	if errors.Is(err, ratelimit.ErrSampledOut) {
+		// Dropping excess events is the client's configured action, not a failure
+		logger.WithError(err).Info("Event dropped by client limits")
+		return nil
+	}
 	if err != nil {
 		logger.WithField("event", event).WithError(err).Error("Event triage failed")
-		return fmt.Errorf("triage failed: %w", err)
+		metrics.EventsFailed.WithLabelValues(metrics.ReasonTriage, string(event.EventType), event.ClientID).Inc()
+		if category := rejectionCategory(err); category != "" && p.quarantine != nil {
+			return p.quarantineEvent(ctx, eventData, event, category, err, logger)
+		}
+		return &StageError{Stage: StageTriage, Err: fmt.Errorf("triage failed: %w", err)}
 	}
 
 	// Step 3: Persist the event
-	if err := p.repository.SaveEvent(ctx, processedEvent); err != nil {
+	err = p.repository.SaveEvent(ctx, processedEvent)
+	if errors.Is(err, persistence.ErrEventExists) {
+		// A redelivered message; the stored event may have been updated since and is kept
+		logger.WithError(err).Info("Event already stored, dropping duplicate delivery")
+		return nil
+	}
+	if err != nil {
 		logger.WithField("processed_event", processedEvent).WithError(err).Error("Failed to persist event")
-		return fmt.Errorf("persistence failed: %w", err)
+		metrics.EventsFailed.WithLabelValues(metrics.ReasonPersistence, string(event.EventType), event.ClientID).Inc()
+		return &StageError{Stage: StagePersistence, Err: fmt.Errorf("persistence failed: %w", err)}
 	}
 
 	processingTime := time.Since(startTime)
-	logger.WithField("processing_time_ms", processingTime.Milliseconds())
-	logger.WithField("status", string(processedEvent.Status))
-	logger.Info("Event processed successfully")
+	metrics.ProcessingDuration.WithLabelValues(string(event.EventType)).Observe(processingTime.Seconds())
+	metrics.EventsProcessed.WithLabelValues(string(event.EventType), event.ClientID, string(processedEvent.Status)).Inc()
+
+	logger.WithFields(logrus.Fields{
+		"processing_time_ms": processingTime.Milliseconds(),
+		"status":             string(processedEvent.Status),
+	}).Info("Event processed successfully")
 
 	return nil
 }
 
-// triageEvent performs event triage and routing logic
-func (p *EventProcessor) triageEvent(ctx context.Context, event *models.Event, logger *logrus.Entry) (*models.ProcessedEvent, error) {
-	processedEvent := event.ToProcessedEvent()
+// Reprocess runs a stored event through validation and triage again and returns the event as it
+// would now be stored, without storing it. Client limits are not enforced, as reprocessing is not
+// traffic sent by the client, and nothing is quarantined: events that would now be rejected fail
+// with a StageError instead.
+func (p *EventProcessor) Reprocess(ctx context.Context, stored *models.ProcessedEvent) (*models.ProcessedEvent, error) {
+	logger := logger.WithFields(p.logger, map[string]interface{}{
+		"component":  "event_processor",
+		"event_id":   stored.EventID,
+		"event_type": string(stored.EventType),
+		"client_id":  stored.ClientID,
+	})
 
-	// Perform event-type specific processing
-	switch event.EventType {
-	case models.EventTypeMonitoring:
-		if err := p.processMonitoringEvent(event, processedEvent, logger); err != nil {
-			return nil, err
+	// The handlers derive their fields afresh from what the client sent. Only the fields recorded
+	// as added are removed, so that client fields of the same name are kept.
+	original := stored.Event
+	original.Payload = make(map[string]interface{}, len(stored.Payload))
+	for k, v := range stored.Payload {
+		original.Payload[k] = v
+	}
+	for _, field := range stored.DerivedFields {
+		delete(original.Payload, field)
+	}
+
+	body, err := json.Marshal(original)
+	if err != nil {
+		return nil, &StageError{Stage: StageValidation, Err: fmt.Errorf("validation failed: %w", err)}
+	}
+	event, err := p.validator.ValidateAndParseEvent(body)
+	if err != nil {
+		logger.WithError(err).Debug("Reprocessed event failed validation")
+		return nil, &StageError{Stage: StageValidation, Err: fmt.Errorf("validation failed: %w", err)}
+	}
+
+	// The event is judged by when it was first received, which is when it was first processed
+	processedEvent, err := p.triageEvent(ctx, event, stored.ProcessedAt, nil, logger)
+	if err != nil {
+		logger.WithError(err).Debug("Reprocessed event failed triage")
+		return nil, &StageError{Stage: StageTriage, Err: fmt.Errorf("triage failed: %w", err)}
+	}
+
+	// Reprocessing does not reset the history of the event
+	processedEvent.RetryCount = stored.RetryCount
+	processedEvent.Revision = stored.Revision
+	processedEvent.Replays = stored.Replays
+	return processedEvent, nil
+}
+
+// ProcessingTimeFields are the payload fields the event type handlers set to the time of
+// processing, which differ on every run
+var ProcessingTimeFields = []string{"processedAt", "integrationProcessedAt"}
+
+// rejectionCategory returns the quarantine category of a triage error, or "" for errors that
+// retrying may fix, such as failed client lookups or exceeded limits
+func rejectionCategory(err error) models.QuarantineCategory {
+	switch {
+	case errors.Is(err, ErrInvalidPayload):
+		return models.QuarantinePayload
+	case errors.Is(err, authz.ErrDenied):
+		return models.QuarantineAuthorization
+	default:
+		return ""
+	}
+}
+
+// quarantineEvent stores a rejected event with its raw body so that it is not retried. event is
+// nil when the body could not be parsed. The event is retried if it cannot be quarantined.
+func (p *EventProcessor) quarantineEvent(ctx context.Context, eventData interface{}, event *models.Event, category models.QuarantineCategory, reason error, logger *logrus.Entry) error {
+	quarantined := &models.QuarantinedEvent{
+		QuarantineID:  uuid.NewString(),
+		Category:      category,
+		Errors:        []string{reason.Error()},
+		Validation:    validator.Failure(reason),
+		Body:          rawBody(eventData),
+		QuarantinedAt: time.Now().UTC(),
+	}
+
+	// A redelivered message replaces its earlier quarantine record instead of adding another
+	if message, ok := eventData.(*types.Message); ok && message.MessageId != nil {
+		quarantined.QuarantineID = aws.ToString(message.MessageId)
+	}
+
+	if event == nil {
+		// Keep whatever identifies the event, even though the body is not a valid one
+		var fields struct {
+			EventID   string `json:"eventId"`
+			EventType string `json:"eventType"`
+			ClientID  string `json:"clientId"`
 		}
-	case models.EventTypeUserAction:
-		if err := p.processUserActionEvent(event, processedEvent, logger); err != nil {
-			return nil, err
+		if json.Unmarshal([]byte(quarantined.Body), &fields) == nil {
+			event = &models.Event{EventID: fields.EventID, EventType: models.EventType(fields.EventType), ClientID: fields.ClientID}
 		}
-	case models.EventTypeTransaction:
-		if err := p.processTransactionEvent(event, processedEvent, logger); err != nil {
-			return nil, err
+	}
+	if event != nil {
+		quarantined.EventID = event.EventID
+		quarantined.EventType = event.EventType
+		quarantined.ClientID = event.ClientID
+	}
+
+	if err := p.quarantine.QuarantineEvent(ctx, quarantined); err != nil {
+		logger.WithError(err).Error("Failed to quarantine event")
+		return &StageError{Stage: StageQuarantine, Err: fmt.Errorf("failed to quarantine rejected event: %w (rejected: %v)", err, reason)}
+	}
+
+	metrics.EventsQuarantined.WithLabelValues(string(category), metrics.LabelOrUnknown(string(quarantined.EventType)), metrics.LabelOrUnknown(quarantined.ClientID)).Inc()
+	logger.WithFields(logrus.Fields{
+		"quarantine_id": quarantined.QuarantineID,
+		"category":      string(category),
+	}).Warn("Event quarantined")
+	return nil
+}
+
+// rawBody returns the event exactly as it was received
+func rawBody(eventData interface{}) string {
+	switch data := eventData.(type) {
+	case *types.Message:
+		return aws.ToString(data.Body)
+	case string:
+		return data
+	case []byte:
+		return string(data)
+	default:
+		body, err := json.Marshal(data)
+		if err != nil {
+			return fmt.Sprintf("%v", data)
 		}
+		return string(body)
+	}
+}
+
+// receivedAt returns when SQS received a message, as its SentTimestamp attribute, or fallback for
+// events that did not come from SQS or lack the attribute
+func receivedAt(eventData interface{}, fallback time.Time) time.Time {
+	message, ok := eventData.(*types.Message)
+	if !ok {
+		return fallback
+	}
+	sent, err := strconv.ParseInt(message.Attributes[string(types.MessageSystemAttributeNameSentTimestamp)], 10, 64)
+	if err != nil {
+		return fallback
+	}
+	return time.UnixMilli(sent).UTC()
+}
+
+// triageEvent performs event triage and routing logic for an event received at receivedAt; a nil
+// limiter enforces no client limits
+func (p *EventProcessor) triageEvent(ctx context.Context, event *models.Event, receivedAt time.Time, limiter Limiter, logger *logrus.Entry) (*models.ProcessedEvent, error) {
+	// Handlers see and the event is stored in the current shape of its event type, while clients are
+	// authorized and limited by the version they sent
+	canonical, err := p.upcast(event, logger)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPayload, err)
+	}
+	processedEvent := canonical.ToProcessedEvent()
+
+	// Perform event-type specific processing; the validator has checked the payload against the schema
+	// of its event type, so handlers can rely on its fields
+	switch canonical.EventType {
+	case models.EventTypeMonitoring:
+		p.processMonitoringEvent(canonical, processedEvent, logger)
+	case models.EventTypeUserAction:
+		p.processUserActionEvent(canonical, processedEvent, logger)
+	case models.EventTypeTransaction:
+		p.processTransactionEvent(canonical, processedEvent, logger)
 	case models.EventTypeIntegration:
-		if err := p.processIntegrationEvent(event, processedEvent, logger); err != nil {
-			return nil, err
-		}
+		p.processIntegrationEvent(canonical, processedEvent, logger)
 	default:
-		logger.WithField("event_type", event.EventType).Warn("Unknown event type")
+		logger.WithField("event_type", canonical.EventType).Warn("Unknown event type")
 		processedEvent.Status = models.EventStatusFailed
-		processedEvent.ErrorMsg = fmt.Sprintf("unknown event type: %s", event.EventType)
+		processedEvent.ErrorMsg = fmt.Sprintf("unknown event type: %s", canonical.EventType)
 	}
 
 	// Validate client permissions
-	if err := p.validateClientPermissions(ctx, event.ClientID, event.EventType, logger); err != nil {
+	clientConfig, err := p.authorizer.Authorize(ctx, event, receivedAt)
+	if err != nil {
 		logger.WithError(err).Warn("Client permission validation failed")
 		// Return error to reject the event instead of storing it
 		return nil, fmt.Errorf("client permission validation failed: %w", err)
 	}
 
+	// Enforce the client's rate limit and quotas, which only count authorized events
+	if limiter != nil {
+		if err := limiter.Admit(ctx, event, clientConfig); err != nil {
+			return nil, fmt.Errorf("client limits: %w", err)
+		}
+	}
+
 	// Set final status if not already set
 	if processedEvent.Status == models.EventStatusPending {
 		processedEvent.Status = models.EventStatusProcessed
 	}
 
+	// Expiry depends on the client and the final status
+	if p.ttlPolicy != nil {
+		processedEvent.TTL = p.ttlPolicy.Expiry(processedEvent, clientConfig)
+	}
+
 	return processedEvent, nil
 }
 
+// upcast returns the event in the current shape of its event type
+func (p *EventProcessor) upcast(event *models.Event, logger *logrus.Entry) (*models.Event, error) {
+	if p.upcaster == nil {
+		return event, nil
+	}
+	canonical, err := p.upcaster.Upcast(event)
+	if err != nil {
+		return nil, err
+	}
+	if canonical.Version != event.Version {
+		logger.WithFields(logrus.Fields{
+			"from_version": event.Version,
+			"to_version":   canonical.Version,
+		}).Debug("Event upcast")
+		metrics.EventsUpcast.WithLabelValues(string(event.EventType), event.Version, canonical.Version).Inc()
+	}
+	return canonical, nil
+}
+
 // processMonitoringEvent handles monitoring-specific logic
-func (p *EventProcessor) processMonitoringEvent(event *models.Event, processedEvent *models.ProcessedEvent, logger *logrus.Entry) error {
+func (p *EventProcessor) processMonitoringEvent(event *models.Event, processedEvent *models.ProcessedEvent, logger *logrus.Entry) {
 	logger.Debug("Processing monitoring event")
 
 	// Extract monitoring-specific fields
@@ -146,26 +461,16 @@ func (p *EventProcessor) processMonitoringEvent(event *models.Event, processedEv
 				for k, v := range event.Payload {
 					processedEvent.Payload[k] = v
 				}
-				processedEvent.Payload["priority"] = "high"
+				derive(processedEvent, "priority", "high")
 			}
 		}
 	}
-
-	return nil
 }
 
 // processUserActionEvent handles user action-specific logic
-func (p *EventProcessor) processUserActionEvent(event *models.Event, processedEvent *models.ProcessedEvent, logger *logrus.Entry) error {
+func (p *EventProcessor) processUserActionEvent(event *models.Event, processedEvent *models.ProcessedEvent, logger *logrus.Entry) {
 	logger.Debug("Processing user action event")
 
-	// Validate user action payload
-	requiredFields := []string{"userId", "action", "resource"}
-	for _, field := range requiredFields {
-		if _, exists := event.Payload[field]; !exists {
-			return fmt.Errorf("missing required field for user action: %s", field)
-		}
-	}
-
 	// Add processing timestamp for audit trail
 	if processedEvent.Payload == nil {
 		processedEvent.Payload = make(map[string]interface{})
@@ -173,23 +478,13 @@ func (p *EventProcessor) processUserActionEvent(event *models.Event, processedEv
 	for k, v := range event.Payload {
 		processedEvent.Payload[k] = v
 	}
-	processedEvent.Payload["processedAt"] = time.Now().UTC().Format(time.RFC3339)
-
-	return nil
+	derive(processedEvent, "processedAt", time.Now().UTC().Format(time.RFC3339))
 }
 
 // processTransactionEvent handles transaction-specific logic
-func (p *EventProcessor) processTransactionEvent(event *models.Event, processedEvent *models.ProcessedEvent, logger *logrus.Entry) error {
+func (p *EventProcessor) processTransactionEvent(event *models.Event, processedEvent *models.ProcessedEvent, logger *logrus.Entry) {
 	logger.Debug("Processing transaction event")
 
-	// Validate transaction payload
-	requiredFields := []string{"transactionId", "amount", "currency"}
-	for _, field := range requiredFields {
-		if _, exists := event.Payload[field]; !exists {
-			return fmt.Errorf("missing required field for transaction: %s", field)
-		}
-	}
-
 	// Add transaction processing metadata
 	if processedEvent.Payload == nil {
 		processedEvent.Payload = make(map[string]interface{})
@@ -199,28 +494,18 @@ func (p *EventProcessor) processTransactionEvent(event *models.Event, processedE
 	}
 
 	// Check for high-value transactions
-	if amountFloat, ok := event.Payload["amount"].(float64); ok {
+	if amountFloat, ok := payloadNumber(event.Payload["amount"]); ok {
 		if amountFloat > 10000 { // Threshold for high-value transactions
-			processedEvent.Payload["highValue"] = true
+			derive(processedEvent, "highValue", true)
 			logger.WithField("amount", amountFloat).Info("High-value transaction detected")
 		}
 	}
-
-	return nil
 }
 
 // processIntegrationEvent handles integration-specific logic
-func (p *EventProcessor) processIntegrationEvent(event *models.Event, processedEvent *models.ProcessedEvent, logger *logrus.Entry) error {
+func (p *EventProcessor) processIntegrationEvent(event *models.Event, processedEvent *models.ProcessedEvent, logger *logrus.Entry) {
 	logger.Debug("Processing integration event")
 
-	// Validate integration payload
-	requiredFields := []string{"source", "target", "operation"}
-	for _, field := range requiredFields {
-		if _, exists := event.Payload[field]; !exists {
-			return fmt.Errorf("missing required field for integration: %s", field)
-		}
-	}
-
 	// Add integration metadata
 	if processedEvent.Payload == nil {
 		processedEvent.Payload = make(map[string]interface{})
@@ -228,37 +513,27 @@ func (p *EventProcessor) processIntegrationEvent(event *models.Event, processedE
 	for k, v := range event.Payload {
 		processedEvent.Payload[k] = v
 	}
-	processedEvent.Payload["integrationProcessedAt"] = time.Now().UTC().Format(time.RFC3339)
-
-	return nil
+	derive(processedEvent, "integrationProcessedAt", time.Now().UTC().Format(time.RFC3339))
 }
 
-// validateClientPermissions validates if client has permission to send this event type
-func (p *EventProcessor) validateClientPermissions(ctx context.Context, clientID string, eventType models.EventType, logger *logrus.Entry) error {
-	// Check if client exists and is active
-	clientConfig, err := p.repository.GetClientConfig(ctx, clientID)
-	if err != nil {
-		// If client config doesn't exist, allow by default (could be configurable)
-		logger.WithField("client_id", clientID).Debug("Client config not found, allowing by default")
-		return nil
-	}
-
-	if !clientConfig.Active {
-		return fmt.Errorf("client %s is not active", clientID)
-	}
-
-	// Check if client is allowed to send this event type
-	allowed := false
-	for _, allowedType := range clientConfig.AllowedTypes {
-		if allowedType == eventType {
-			allowed = true
-			break
-		}
+// derive sets a payload field added by a handler and records it as derived
+func derive(processedEvent *models.ProcessedEvent, field string, value interface{}) {
+	processedEvent.Payload[field] = value
+	if !slices.Contains(processedEvent.DerivedFields, field) {
+		processedEvent.DerivedFields = append(processedEvent.DerivedFields, field)
 	}
+}
 
-	if !allowed {
-		return fmt.Errorf("client %s is not allowed to send events of type %s", clientID, eventType)
+// payloadNumber returns a payload number as a float64. Validated payloads hold json.Number, payloads
+// read back from storage float64 wherever that is exact.
+func payloadNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		number, err := v.Float64()
+		return number, err == nil
+	default:
+		return 0, false
 	}
-
-	return nil
 }
@@ -2,14 +2,24 @@ package processor
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/d-sense/event-processor/internal/authz"
+	"github.com/d-sense/event-processor/internal/persistence"
+	"github.com/d-sense/event-processor/internal/ratelimit"
+	"github.com/d-sense/event-processor/internal/retention"
+	"github.com/d-sense/event-processor/internal/validator"
 	"github.com/d-sense/event-processor/pkg/models"
 )
 
@@ -23,6 +33,38 @@ func (m *MockRepository) SaveEvent(ctx context.Context, event *models.ProcessedE
 	return args.Error(0)
 }
 
+func (m *MockRepository) GetEvent(ctx context.Context, eventID string) (*models.ProcessedEvent, error) {
+	args := m.Called(ctx, eventID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ProcessedEvent), args.Error(1)
+}
+
+func (m *MockRepository) ListEventsByClient(ctx context.Context, clientID string, opts persistence.ListOptions) (*persistence.EventPage, error) {
+	args := m.Called(ctx, clientID, opts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*persistence.EventPage), args.Error(1)
+}
+
+func (m *MockRepository) ListEventsByStatus(ctx context.Context, status models.EventStatus, opts persistence.ListOptions) (*persistence.EventPage, error) {
+	args := m.Called(ctx, status, opts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*persistence.EventPage), args.Error(1)
+}
+
+func (m *MockRepository) UpdateEventStatus(ctx context.Context, eventID string, update persistence.StatusUpdate) (*models.ProcessedEvent, error) {
+	args := m.Called(ctx, eventID, update)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ProcessedEvent), args.Error(1)
+}
+
 func (m *MockRepository) GetClientConfig(ctx context.Context, clientID string) (*models.ClientConfig, error) {
 	args := m.Called(ctx, clientID)
 	if args.Get(0) == nil {
@@ -31,6 +73,29 @@ func (m *MockRepository) GetClientConfig(ctx context.Context, clientID string) (
 	return args.Get(0).(*models.ClientConfig), args.Error(1)
 }
 
+func (m *MockRepository) PutClientConfig(ctx context.Context, config *models.ClientConfig) error {
+	args := m.Called(ctx, config)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CreateClientConfig(ctx context.Context, config *models.ClientConfig) error {
+	args := m.Called(ctx, config)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListClientConfigs(ctx context.Context) ([]*models.ClientConfig, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.ClientConfig), args.Error(1)
+}
+
+func (m *MockRepository) DeleteClientConfig(ctx context.Context, clientID string) error {
+	args := m.Called(ctx, clientID)
+	return args.Error(0)
+}
+
 func (m *MockRepository) HealthCheck(ctx context.Context) error {
 	args := m.Called(ctx)
 	return args.Error(0)
@@ -49,12 +114,47 @@ func (m *MockValidator) ValidateAndParseEvent(eventData interface{}) (*models.Ev
 	return args.Get(0).(*models.Event), args.Error(1)
 }
 
+// MockUpcastingValidator is a mock implementation of a Validator that is also an Upcaster
+type MockUpcastingValidator struct {
+	MockValidator
+}
+
+func (m *MockUpcastingValidator) Upcast(event *models.Event) (*models.Event, error) {
+	args := m.Called(event)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Event), args.Error(1)
+}
+
+// MockLimiter is a mock implementation of the Limiter interface
+type MockLimiter struct {
+	mock.Mock
+}
+
+func (m *MockLimiter) Admit(ctx context.Context, event *models.Event, config *models.ClientConfig) error {
+	args := m.Called(ctx, event, config)
+	return args.Error(0)
+}
+
+// MockQuarantine is a mock implementation of the Quarantine interface
+type MockQuarantine struct {
+	mock.Mock
+}
+
+func (m *MockQuarantine) QuarantineEvent(ctx context.Context, event *models.QuarantinedEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
 // Test data structures
 type processEventTestCase struct {
 	name           string
 	eventData      interface{}
 	mockValidator  func(*MockValidator)
 	mockRepository func(*MockRepository)
+	mockLimiter    func(*MockLimiter)
+	mockQuarantine func(*MockQuarantine)
 	expectError    bool
 	errorMsg       string
 	description    string
@@ -73,12 +173,24 @@ type triageEventTestCase struct {
 type eventProcessingTestCase struct {
 	name           string
 	event          *models.Event
-	expectedError  bool
-	errorMsg       string
 	expectedFields map[string]interface{}
 	description    string
 }
 
+// invalidEventMessage is an SQS message whose body fails validation
+var invalidEventMessage = &types.Message{
+	MessageId: aws.String("msg-001"),
+	Body:      aws.String(`{"eventId":"evt-001","clientId":"client-001"}`),
+}
+
+// payloadValidationError is returned for a transaction whose payload lacks its amount
+var payloadValidationError = &validator.ValidationError{ValidationFailure: models.ValidationFailure{
+	Schema:        models.ValidationSchemaPayload,
+	EventType:     models.EventTypeTransaction,
+	SchemaVersion: "1.1",
+	Violations:    []models.Violation{{Field: "payload.amount", Rule: "required", Message: "(root): amount is required"}},
+}}
+
 // TestProcessEvent tests the main ProcessEvent function
 func TestProcessEvent(t *testing.T) {
 	tests := []processEventTestCase{
@@ -122,6 +234,155 @@ func TestProcessEvent(t *testing.T) {
 			errorMsg:    "triage failed",
 			description: "Should fail when client lacks permission for event type",
 		},
+		{
+			name:      "Triage Failure - Event Denied By Policy",
+			eventData: "valid-event-data",
+			mockValidator: func(mv *MockValidator) {
+				mv.On("ValidateAndParseEvent", "valid-event-data").Return(createValidEvent(), nil)
+			},
+			mockRepository: func(mr *MockRepository) {
+				config := createValidClientConfig()
+				config.Policies = map[models.EventType]models.EventPolicy{
+					models.EventTypeMonitoring: {Versions: []string{"2.0"}},
+				}
+				mr.On("GetClientConfig", mock.Anything, "client-001").Return(config, nil)
+			},
+			expectError: true,
+			errorMsg:    `version "1.0" of monitoring events is not allowed`,
+			description: "Should fail when the event violates the policy of its type",
+		},
+		{
+			name:      "Triage Failure - Client Limit Exceeded",
+			eventData: "valid-event-data",
+			mockValidator: func(mv *MockValidator) {
+				mv.On("ValidateAndParseEvent", "valid-event-data").Return(createValidEvent(), nil)
+			},
+			mockRepository: func(mr *MockRepository) {
+				mr.On("GetClientConfig", mock.Anything, "client-001").Return(createValidClientConfig(), nil)
+			},
+			mockLimiter: func(ml *MockLimiter) {
+				ml.On("Admit", mock.Anything, mock.AnythingOfType("*models.Event"), createValidClientConfig()).
+					Return(fmt.Errorf("%w: client client-001 is over its daily quota of 10 events", ratelimit.ErrLimitExceeded))
+			},
+			expectError: true,
+			errorMsg:    "triage failed: client limits: client limit exceeded",
+			description: "Should fail without saving events over the client's limits",
+		},
+		{
+			name:      "Event Sampled Out",
+			eventData: "valid-event-data",
+			mockValidator: func(mv *MockValidator) {
+				mv.On("ValidateAndParseEvent", "valid-event-data").Return(createValidEvent(), nil)
+			},
+			mockRepository: func(mr *MockRepository) {
+				mr.On("GetClientConfig", mock.Anything, "client-001").Return(createValidClientConfig(), nil)
+			},
+			mockLimiter: func(ml *MockLimiter) {
+				ml.On("Admit", mock.Anything, mock.AnythingOfType("*models.Event"), createValidClientConfig()).
+					Return(fmt.Errorf("%w: client client-001 is over its rate limit", ratelimit.ErrSampledOut))
+			},
+			expectError: false,
+			description: "Should drop events the sample action leaves out without failing or saving them",
+		},
+		{
+			name:      "Event Within Client Limits",
+			eventData: "valid-event-data",
+			mockValidator: func(mv *MockValidator) {
+				mv.On("ValidateAndParseEvent", "valid-event-data").Return(createValidEvent(), nil)
+			},
+			mockRepository: func(mr *MockRepository) {
+				mr.On("GetClientConfig", mock.Anything, "client-001").Return(createValidClientConfig(), nil)
+				mr.On("SaveEvent", mock.Anything, mock.AnythingOfType("*models.ProcessedEvent")).Return(nil)
+			},
+			mockLimiter: func(ml *MockLimiter) {
+				ml.On("Admit", mock.Anything, mock.AnythingOfType("*models.Event"), createValidClientConfig()).Return(nil)
+			},
+			expectError: false,
+			description: "Should save events the limiter admits",
+		},
+		{
+			name:      "Invalid Event Quarantined",
+			eventData: invalidEventMessage,
+			mockValidator: func(mv *MockValidator) {
+				mv.On("ValidateAndParseEvent", invalidEventMessage).Return(nil, errors.New("validation failed: [eventType is required]"))
+			},
+			mockQuarantine: func(mq *MockQuarantine) {
+				mq.On("QuarantineEvent", mock.Anything, mock.MatchedBy(func(event *models.QuarantinedEvent) bool {
+					return event.QuarantineID == "msg-001" && event.Category == models.QuarantineValidation &&
+						event.ClientID == "client-001" && event.EventID == "evt-001" && event.EventType == "" &&
+						event.Body == aws.ToString(invalidEventMessage.Body) && event.Validation == nil &&
+						assert.ObjectsAreEqual([]string{"validation failed: [eventType is required]"}, event.Errors)
+				})).Return(nil)
+			},
+			expectError: false,
+			description: "Should quarantine events failing validation with their raw body instead of retrying them",
+		},
+		{
+			name:      "Invalid Payload Quarantined",
+			eventData: "valid-event-data",
+			mockValidator: func(mv *MockValidator) {
+				mv.On("ValidateAndParseEvent", "valid-event-data").Return(nil, payloadValidationError)
+			},
+			mockQuarantine: func(mq *MockQuarantine) {
+				mq.On("QuarantineEvent", mock.Anything, mock.MatchedBy(func(event *models.QuarantinedEvent) bool {
+					return event.QuarantineID != "" && event.Category == models.QuarantineValidation && event.Body == "valid-event-data" &&
+						assert.ObjectsAreEqual([]string{"payload validation failed for version 1.1 of transaction events: [(root): amount is required]"}, event.Errors) &&
+						assert.ObjectsAreEqual(&payloadValidationError.ValidationFailure, event.Validation)
+				})).Return(nil)
+			},
+			expectError: false,
+			description: "Should quarantine events whose payload fails the schema of their event type with the rules it breaks",
+		},
+		{
+			name:      "Denied Event Quarantined",
+			eventData: "valid-event-data",
+			mockValidator: func(mv *MockValidator) {
+				mv.On("ValidateAndParseEvent", "valid-event-data").Return(createValidEvent(), nil)
+			},
+			mockRepository: func(mr *MockRepository) {
+				mr.On("GetClientConfig", mock.Anything, "client-001").Return(createRestrictedClientConfig(), nil)
+			},
+			mockQuarantine: func(mq *MockQuarantine) {
+				mq.On("QuarantineEvent", mock.Anything, mock.MatchedBy(func(event *models.QuarantinedEvent) bool {
+					return event.Category == models.QuarantineAuthorization && event.ClientID == "client-001"
+				})).Return(nil)
+			},
+			expectError: false,
+			description: "Should quarantine events their client may not send",
+		},
+		{
+			name:      "Quarantine Failure",
+			eventData: "valid-event-data",
+			mockValidator: func(mv *MockValidator) {
+				mv.On("ValidateAndParseEvent", "valid-event-data").Return(nil, errors.New("payload validation failed for version 1.1 of transaction events: [(root): amount is required]"))
+			},
+			mockQuarantine: func(mq *MockQuarantine) {
+				mq.On("QuarantineEvent", mock.Anything, mock.AnythingOfType("*models.QuarantinedEvent")).Return(errors.New("dynamodb error"))
+			},
+			expectError: true,
+			errorMsg:    "failed to quarantine rejected event: dynamodb error",
+			description: "Should fail, so the event is retried, when it cannot be quarantined",
+		},
+		{
+			name:      "Limit Exceeded Not Quarantined",
+			eventData: "valid-event-data",
+			mockValidator: func(mv *MockValidator) {
+				mv.On("ValidateAndParseEvent", "valid-event-data").Return(createValidEvent(), nil)
+			},
+			mockRepository: func(mr *MockRepository) {
+				mr.On("GetClientConfig", mock.Anything, "client-001").Return(createValidClientConfig(), nil)
+			},
+			mockLimiter: func(ml *MockLimiter) {
+				ml.On("Admit", mock.Anything, mock.AnythingOfType("*models.Event"), createValidClientConfig()).
+					Return(fmt.Errorf("%w: client client-001 is over its rate limit", ratelimit.ErrLimitExceeded))
+			},
+			mockQuarantine: func(mq *MockQuarantine) {
+				// Events over a limit may be admitted later, so they are retried rather than quarantined
+			},
+			expectError: true,
+			errorMsg:    "triage failed: client limits",
+			description: "Should retry events over the client's limits",
+		},
 		{
 			name:      "Persistence Failure",
 			eventData: "valid-event-data",
@@ -137,6 +398,20 @@ func TestProcessEvent(t *testing.T) {
 			errorMsg:    "persistence failed",
 			description: "Should fail when event persistence fails",
 		},
+		{
+			name:      "Duplicate Delivery",
+			eventData: "valid-event-data",
+			mockValidator: func(mv *MockValidator) {
+				mv.On("ValidateAndParseEvent", "valid-event-data").Return(createValidEvent(), nil)
+			},
+			mockRepository: func(mr *MockRepository) {
+				mr.On("GetClientConfig", mock.Anything, "client-001").Return(createValidClientConfig(), nil)
+				mr.On("SaveEvent", mock.Anything, mock.AnythingOfType("*models.ProcessedEvent")).
+					Return(fmt.Errorf("%w: event-001", persistence.ErrEventExists))
+			},
+			expectError: false,
+			description: "Should acknowledge a redelivered event that is already stored",
+		},
 	}
 
 	for _, tt := range tests {
@@ -159,8 +434,19 @@ func TestProcessEvent(t *testing.T) {
 			processor := &EventProcessor{
 				repository: mockRepo,
 				validator:  mockVal,
+				authorizer: authz.New(mockRepo, authz.ModeAllowUnknown, logger),
 				logger:     logger,
 			}
+			mockLimiter := &MockLimiter{}
+			if tt.mockLimiter != nil {
+				tt.mockLimiter(mockLimiter)
+				processor.limiter = mockLimiter
+			}
+			mockQuarantine := &MockQuarantine{}
+			if tt.mockQuarantine != nil {
+				tt.mockQuarantine(mockQuarantine)
+				processor.quarantine = mockQuarantine
+			}
 
 			// Execute test
 			err := processor.ProcessEvent(context.Background(), tt.eventData)
@@ -178,6 +464,213 @@ func TestProcessEvent(t *testing.T) {
 			// Verify mocks
 			mockRepo.AssertExpectations(t)
 			mockVal.AssertExpectations(t)
+			mockLimiter.AssertExpectations(t)
+			mockQuarantine.AssertExpectations(t)
+		})
+	}
+}
+
+// TestReprocess tests running stored events through validation and triage again
+func TestReprocess(t *testing.T) {
+	// A transaction stored by a handler that flagged it as high value by mistake, with a priority
+	// sent by the client
+	stored := createTransactionEvent().ToProcessedEvent()
+	stored.Payload = map[string]interface{}{"transactionId": "txn123", "amount": 5000.0, "currency": "USD", "highValue": true, "priority": "low"}
+	stored.DerivedFields = []string{"highValue"}
+	stored.Status = models.EventStatusProcessed
+	stored.RetryCount = 2
+	stored.Revision = 4
+	stored.Replays = []models.ReplayRecord{{RunID: "run-0", Status: models.EventStatusReprocessed}}
+
+	withoutDerivedFields := mock.MatchedBy(func(body []byte) bool {
+		return !strings.Contains(string(body), "highValue") && strings.Contains(string(body), `"priority":"low"`)
+	})
+
+	tests := []struct {
+		name           string
+		mockValidator  func(*MockValidator)
+		mockRepository func(*MockRepository)
+		expectedStage  string
+		expectedErr    string
+		description    string
+	}{
+		{
+			name: "Derives Fields Afresh",
+			mockValidator: func(mv *MockValidator) {
+				mv.On("ValidateAndParseEvent", withoutDerivedFields).Return(createTransactionEvent(), nil)
+			},
+			mockRepository: func(mr *MockRepository) {
+				mr.On("GetClientConfig", mock.Anything, "client-001").Return(createValidClientConfig(), nil)
+			},
+			description: "Should remove only the fields the handlers added and keep the history of the event",
+		},
+		{
+			name: "Now Denied",
+			mockValidator: func(mv *MockValidator) {
+				mv.On("ValidateAndParseEvent", withoutDerivedFields).Return(createTransactionEvent(), nil)
+			},
+			mockRepository: func(mr *MockRepository) {
+				config := createValidClientConfig()
+				config.AllowedTypes = []models.EventType{models.EventTypeMonitoring}
+				mr.On("GetClientConfig", mock.Anything, "client-001").Return(config, nil)
+			},
+			expectedStage: StageTriage,
+			expectedErr:   "triage failed: client permission validation failed: event denied: client client-001 is not allowed to send events of type transaction",
+			description:   "Should fail events triage would now reject",
+		},
+		{
+			name: "Now Invalid",
+			mockValidator: func(mv *MockValidator) {
+				mv.On("ValidateAndParseEvent", withoutDerivedFields).Return(nil, errors.New("payload cannot be empty"))
+			},
+			expectedStage: StageValidation,
+			expectedErr:   "validation failed: payload cannot be empty",
+			description:   "Should fail events validation would now reject",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockRepository{}
+			mockVal := &MockValidator{}
+			mockLimiter := &MockLimiter{}
+			tt.mockValidator(mockVal)
+			if tt.mockRepository != nil {
+				tt.mockRepository(mockRepo)
+			}
+
+			// Client limits must not be enforced, so the limiter expects no calls
+			logger := logrus.New()
+			processor := New(mockRepo, mockVal, nil, mockLimiter, nil, nil, logger)
+
+			result, err := processor.Reprocess(context.Background(), stored)
+
+			if tt.expectedStage != "" {
+				assert.EqualError(t, err, tt.expectedErr, tt.description)
+				stage, _ := Classify(err)
+				assert.Equal(t, tt.expectedStage, stage)
+				assert.Nil(t, result)
+			} else {
+				assert.NoError(t, err, tt.description)
+				assert.Equal(t, models.EventStatusProcessed, result.Status)
+				assert.NotContains(t, result.Payload, "highValue")
+				assert.Equal(t, 2, result.RetryCount)
+				assert.Equal(t, int64(4), result.Revision)
+				assert.Equal(t, stored.Replays, result.Replays)
+			}
+			assert.Equal(t, true, stored.Payload["highValue"], "The stored event should not be modified")
+
+			mockRepo.AssertExpectations(t)
+			mockVal.AssertExpectations(t)
+			mockLimiter.AssertExpectations(t)
+		})
+	}
+}
+
+// TestClassify tests the stage and category reported for processing errors
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name             string
+		err              error
+		expectedStage    string
+		expectedCategory string
+		description      string
+	}{
+		{
+			name:             "Validation Failure",
+			err:              &StageError{Stage: StageValidation, Err: errors.New("validation failed: invalid JSON")},
+			expectedStage:    StageValidation,
+			expectedCategory: CategoryInvalidEvent,
+			description:      "Should report events failing validation as invalid",
+		},
+		{
+			name:             "Invalid Payload",
+			err:              &StageError{Stage: StageTriage, Err: fmt.Errorf("triage failed: %w: amount \"15k\" is not a number", ErrInvalidPayload)},
+			expectedStage:    StageTriage,
+			expectedCategory: CategoryInvalidEvent,
+			description:      "Should report payloads that cannot be upcast as invalid",
+		},
+		{
+			name:             "Denied",
+			err:              &StageError{Stage: StageTriage, Err: fmt.Errorf("triage failed: %w", authz.ErrDenied)},
+			expectedStage:    StageTriage,
+			expectedCategory: CategoryAuthorization,
+			description:      "Should report denied events",
+		},
+		{
+			name:             "Client Lookup",
+			err:              &StageError{Stage: StageTriage, Err: fmt.Errorf("triage failed: %w", authz.ErrLookupFailed)},
+			expectedStage:    StageTriage,
+			expectedCategory: CategoryClientLookup,
+			description:      "Should report failed client lookups",
+		},
+		{
+			name:             "Client Limits",
+			err:              &StageError{Stage: StageTriage, Err: fmt.Errorf("triage failed: client limits: %w", ratelimit.ErrLimitExceeded)},
+			expectedStage:    StageTriage,
+			expectedCategory: CategoryClientLimits,
+			description:      "Should report events over a client limit",
+		},
+		{
+			name:             "Persistence",
+			err:              &StageError{Stage: StagePersistence, Err: errors.New("persistence failed: throttled")},
+			expectedStage:    StagePersistence,
+			expectedCategory: CategoryStorage,
+			description:      "Should report storage failures",
+		},
+		{
+			name:             "Other Error",
+			err:              errors.New("boom"),
+			expectedStage:    StageUnknown,
+			expectedCategory: CategoryUnknown,
+			description:      "Should report errors without a stage as unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stage, category := Classify(fmt.Errorf("wrapped: %w", tt.err))
+			assert.Equal(t, tt.expectedStage, stage, tt.description)
+			assert.Equal(t, tt.expectedCategory, category, tt.description)
+		})
+	}
+}
+
+// TestReceivedAt tests reading when SQS received a message
+func TestReceivedAt(t *testing.T) {
+	fallback := time.Date(2025, 3, 5, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		eventData   interface{}
+		expected    time.Time
+		description string
+	}{
+		{
+			name: "SQS Message",
+			eventData: &types.Message{Attributes: map[string]string{
+				string(types.MessageSystemAttributeNameSentTimestamp): "1741170600000",
+			}},
+			expected:    time.Date(2025, 3, 5, 10, 30, 0, 0, time.UTC),
+			description: "Should use the SentTimestamp of SQS messages",
+		},
+		{
+			name:        "SQS Message Without Timestamp",
+			eventData:   &types.Message{},
+			expected:    fallback,
+			description: "Should fall back when the attribute was not requested",
+		},
+		{
+			name:        "Raw Event",
+			eventData:   "valid-event-data",
+			expected:    fallback,
+			description: "Should fall back for events that did not come from SQS",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, receivedAt(tt.eventData, fallback), tt.description)
 		})
 	}
 }
@@ -286,11 +779,12 @@ func TestTriageEvent(t *testing.T) {
 			processor := &EventProcessor{
 				repository: mockRepo,
 				validator:  mockVal,
+				authorizer: authz.New(mockRepo, authz.ModeAllowUnknown, logger),
 				logger:     logger,
 			}
 
 			// Execute test
-			result, err := processor.triageEvent(context.Background(), tt.event, logger.WithField("test", "triage"))
+			result, err := processor.triageEvent(context.Background(), tt.event, time.Now(), processor.limiter, logger.WithField("test", "triage"))
 
 			// Assertions
 			if tt.expectError {
@@ -311,31 +805,182 @@ func TestTriageEvent(t *testing.T) {
 	}
 }
 
+// TestTriageEventTTL tests that triage sets the expiry from the retention policy
+func TestTriageEventTTL(t *testing.T) {
+	policy := &retention.Policy{
+		Default:    30 * 24 * time.Hour,
+		Failed:     90 * 24 * time.Hour,
+		EventTypes: map[models.EventType]time.Duration{models.EventTypeMonitoring: 7 * 24 * time.Hour},
+	}
+
+	tests := []struct {
+		name         string
+		event        *models.Event
+		clientConfig *models.ClientConfig
+		retention    time.Duration
+		description  string
+	}{
+		{
+			name:         "Event Type Retention",
+			event:        createValidEvent(),
+			clientConfig: createValidClientConfig(),
+			retention:    7 * 24 * time.Hour,
+			description:  "Should expire events after their event type's retention",
+		},
+		{
+			name:  "Client Retention",
+			event: createValidEvent(),
+			clientConfig: func() *models.ClientConfig {
+				config := createValidClientConfig()
+				config.Config[retention.ConfigKeyRetentionDays] = "365"
+				return config
+			}(),
+			retention:   365 * 24 * time.Hour,
+			description: "Should expire events after the client's retention",
+		},
+		{
+			name:  "Legal Hold",
+			event: createValidEvent(),
+			clientConfig: func() *models.ClientConfig {
+				config := createValidClientConfig()
+				config.Config[retention.ConfigKeyLegalHold] = "true"
+				return config
+			}(),
+			description: "Should not expire events of a client under legal hold",
+		},
+		{
+			name:         "Failed Event",
+			event:        createUnknownEventType(),
+			clientConfig: nil,
+			retention:    90 * 24 * time.Hour,
+			description:  "Should keep failed events for the failed retention",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockRepository{}
+			if tt.clientConfig != nil {
+				mockRepo.On("GetClientConfig", mock.Anything, tt.event.ClientID).Return(tt.clientConfig, nil)
+			} else {
+				mockRepo.On("GetClientConfig", mock.Anything, tt.event.ClientID).Return(nil, errors.New("not found"))
+			}
+			logger := logrus.New()
+
+			processor := New(mockRepo, &MockValidator{}, nil, nil, nil, policy, logger)
+
+			result, err := processor.triageEvent(context.Background(), tt.event, time.Now(), processor.limiter, logger.WithField("test", "ttl"))
+
+			assert.NoError(t, err)
+			if tt.retention == 0 {
+				assert.Zero(t, result.TTL)
+			} else {
+				assert.Equal(t, result.ProcessedAt.Add(tt.retention).Unix(), result.TTL)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+// TestTriageEventUpcast tests that events are triaged and stored in the current shape of their event type
+func TestTriageEventUpcast(t *testing.T) {
+	// Version 1.0 transactions sent the amount as a string, which the high value check missed
+	legacy := createTransactionEvent()
+	legacy.Payload["amount"] = "15000"
+	canonical := createHighValueTransactionEvent()
+	canonical.Payload["transactionId"] = "txn123"
+	canonical.Payload["amount"] = 15000.0
+	canonical.Version = "1.1"
+
+	tests := []struct {
+		name           string
+		mockValidator  func(*MockUpcastingValidator)
+		clientConfig   *models.ClientConfig
+		expectError    bool
+		errorMsg       string
+		expectedResult func(*testing.T, *models.ProcessedEvent)
+		description    string
+	}{
+		{
+			name: "Upcast Event",
+			mockValidator: func(mv *MockUpcastingValidator) {
+				mv.On("Upcast", legacy).Return(canonical, nil)
+			},
+			clientConfig: func() *models.ClientConfig {
+				config := createValidClientConfig()
+				config.Policies = map[models.EventType]models.EventPolicy{models.EventTypeTransaction: {Versions: []string{"1.0"}}}
+				return config
+			}(),
+			expectedResult: func(t *testing.T, result *models.ProcessedEvent) {
+				assert.Equal(t, "1.1", result.Version)
+				assert.Equal(t, 15000.0, result.Payload["amount"])
+				assert.Equal(t, true, result.Payload["highValue"])
+				assert.Equal(t, models.EventStatusProcessed, result.Status)
+			},
+			description: "Should triage and store the upcast event, authorizing the version the client sent",
+		},
+		{
+			name: "Upcast Failed",
+			mockValidator: func(mv *MockUpcastingValidator) {
+				mv.On("Upcast", legacy).Return(nil, errors.New(`amount "15k" is not a number`))
+			},
+			expectError: true,
+			errorMsg:    `invalid payload: amount "15k" is not a number`,
+			description: "Should reject events that cannot be upcast as invalid payloads",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockRepository{}
+			if tt.clientConfig != nil {
+				mockRepo.On("GetClientConfig", mock.Anything, legacy.ClientID).Return(tt.clientConfig, nil)
+			}
+			mockValidator := &MockUpcastingValidator{}
+			tt.mockValidator(mockValidator)
+			logger := logrus.New()
+
+			processor := New(mockRepo, mockValidator, nil, nil, nil, nil, logger)
+
+			result, err := processor.triageEvent(context.Background(), legacy, time.Now(), processor.limiter, logger.WithField("test", "upcast"))
+
+			if tt.expectError {
+				assert.Error(t, err, tt.description)
+				assert.ErrorIs(t, err, ErrInvalidPayload)
+				assert.Equal(t, tt.errorMsg, err.Error())
+				assert.Nil(t, result)
+			} else {
+				assert.NoError(t, err, tt.description)
+				tt.expectedResult(t, result)
+			}
+			mockValidator.AssertExpectations(t)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
 // TestProcessMonitoringEvent tests monitoring event processing
 func TestProcessMonitoringEvent(t *testing.T) {
 	tests := []eventProcessingTestCase{
 		{
-			name:          "High Severity Event",
-			event:         createHighSeverityMonitoringEvent(),
-			expectedError: false,
+			name:  "High Severity Event",
+			event: createHighSeverityMonitoringEvent(),
 			expectedFields: map[string]interface{}{
 				"priority": "high",
 			},
 			description: "Should add priority flag for high-severity events",
 		},
 		{
-			name:          "Low Severity Event",
-			event:         createLowSeverityMonitoringEvent(),
-			expectedError: false,
+			name:  "Low Severity Event",
+			event: createLowSeverityMonitoringEvent(),
 			expectedFields: map[string]interface{}{
 				"priority": nil, // Should not have priority flag
 			},
 			description: "Should not add priority flag for low-severity events",
 		},
 		{
-			name:          "Critical Severity Event",
-			event:         createCriticalSeverityMonitoringEvent(),
-			expectedError: false,
+			name:  "Critical Severity Event",
+			event: createCriticalSeverityMonitoringEvent(),
 			expectedFields: map[string]interface{}{
 				"priority": "high",
 			},
@@ -353,20 +998,14 @@ func TestProcessMonitoringEvent(t *testing.T) {
 			processedEvent := tt.event.ToProcessedEvent()
 
 			// Execute test
-			err := processor.processMonitoringEvent(tt.event, processedEvent, logger)
-
-			// Assertions
-			if tt.expectedError {
-				assert.Error(t, err)
-			} else {
-				assert.NoError(t, err)
-				// Check expected fields
-				for field, expectedValue := range tt.expectedFields {
-					if expectedValue == nil {
-						assert.NotContains(t, processedEvent.Payload, field)
-					} else {
-						assert.Equal(t, expectedValue, processedEvent.Payload[field])
-					}
+			processor.processMonitoringEvent(tt.event, processedEvent, logger)
+
+			// Check expected fields
+			for field, expectedValue := range tt.expectedFields {
+				if expectedValue == nil {
+					assert.NotContains(t, processedEvent.Payload, field)
+				} else {
+					assert.Equal(t, expectedValue, processedEvent.Payload[field])
 				}
 			}
 		})
@@ -377,35 +1016,13 @@ func TestProcessMonitoringEvent(t *testing.T) {
 func TestProcessUserActionEvent(t *testing.T) {
 	tests := []eventProcessingTestCase{
 		{
-			name:          "Valid User Action Event",
-			event:         createUserActionEvent(),
-			expectedError: false,
+			name:  "Valid User Action Event",
+			event: createUserActionEvent(),
 			expectedFields: map[string]interface{}{
 				"processedAt": mock.AnythingOfType("string"),
 			},
 			description: "Should successfully process user action event with audit timestamp",
 		},
-		{
-			name:          "Missing User ID",
-			event:         createUserActionEventMissingField("userId"),
-			expectedError: true,
-			errorMsg:      "missing required field for user action: userId",
-			description:   "Should fail when userId is missing",
-		},
-		{
-			name:          "Missing Action",
-			event:         createUserActionEventMissingField("action"),
-			expectedError: true,
-			errorMsg:      "missing required field for user action: action",
-			description:   "Should fail when action is missing",
-		},
-		{
-			name:          "Missing Resource",
-			event:         createUserActionEventMissingField("resource"),
-			expectedError: true,
-			errorMsg:      "missing required field for user action: resource",
-			description:   "Should fail when resource is missing",
-		},
 	}
 
 	for _, tt := range tests {
@@ -418,27 +1035,18 @@ func TestProcessUserActionEvent(t *testing.T) {
 			processedEvent := tt.event.ToProcessedEvent()
 
 			// Execute test
-			err := processor.processUserActionEvent(tt.event, processedEvent, logger)
-
-			// Assertions
-			if tt.expectedError {
-				assert.Error(t, err)
-				if tt.errorMsg != "" {
-					assert.Contains(t, err.Error(), tt.errorMsg)
-				}
-			} else {
-				assert.NoError(t, err)
-				// Check expected fields
-				for field, expectedValue := range tt.expectedFields {
-					if field == "processedAt" {
-						assert.Contains(t, processedEvent.Payload, field)
-						// Verify it's a valid timestamp
-						timestampStr := processedEvent.Payload[field].(string)
-						_, parseErr := time.Parse(time.RFC3339, timestampStr)
-						assert.NoError(t, parseErr)
-					} else {
-						assert.Equal(t, expectedValue, processedEvent.Payload[field])
-					}
+			processor.processUserActionEvent(tt.event, processedEvent, logger)
+
+			// Check expected fields
+			for field, expectedValue := range tt.expectedFields {
+				if field == "processedAt" {
+					assert.Contains(t, processedEvent.Payload, field)
+					// Verify it's a valid timestamp
+					timestampStr := processedEvent.Payload[field].(string)
+					_, parseErr := time.Parse(time.RFC3339, timestampStr)
+					assert.NoError(t, parseErr)
+				} else {
+					assert.Equal(t, expectedValue, processedEvent.Payload[field])
 				}
 			}
 		})
@@ -449,44 +1057,21 @@ func TestProcessUserActionEvent(t *testing.T) {
 func TestProcessTransactionEvent(t *testing.T) {
 	tests := []eventProcessingTestCase{
 		{
-			name:          "Valid Transaction Event",
-			event:         createTransactionEvent(),
-			expectedError: false,
+			name:  "Valid Transaction Event",
+			event: createTransactionEvent(),
 			expectedFields: map[string]interface{}{
 				"highValue": nil, // Should not have highValue flag for amount < 10000
 			},
 			description: "Should successfully process transaction event",
 		},
 		{
-			name:          "High Value Transaction",
-			event:         createHighValueTransactionEvent(),
-			expectedError: false,
+			name:  "High Value Transaction",
+			event: createHighValueTransactionEvent(),
 			expectedFields: map[string]interface{}{
 				"highValue": true,
 			},
 			description: "Should flag high-value transactions (>10000)",
 		},
-		{
-			name:          "Missing Transaction ID",
-			event:         createTransactionEventMissingField("transactionId"),
-			expectedError: true,
-			errorMsg:      "missing required field for transaction: transactionId",
-			description:   "Should fail when transactionId is missing",
-		},
-		{
-			name:          "Missing Amount",
-			event:         createTransactionEventMissingField("amount"),
-			expectedError: true,
-			errorMsg:      "missing required field for transaction: amount",
-			description:   "Should fail when amount is missing",
-		},
-		{
-			name:          "Missing Currency",
-			event:         createTransactionEventMissingField("currency"),
-			expectedError: true,
-			errorMsg:      "missing required field for transaction: currency",
-			description:   "Should fail when currency is missing",
-		},
 	}
 
 	for _, tt := range tests {
@@ -499,23 +1084,16 @@ func TestProcessTransactionEvent(t *testing.T) {
 			processedEvent := tt.event.ToProcessedEvent()
 
 			// Execute test
-			err := processor.processTransactionEvent(tt.event, processedEvent, logger)
-
-			// Assertions
-			if tt.expectedError {
-				assert.Error(t, err)
-				if tt.errorMsg != "" {
-					assert.Contains(t, err.Error(), tt.errorMsg)
-				}
-			} else {
-				assert.NoError(t, err)
-				// Check expected fields
-				for field, expectedValue := range tt.expectedFields {
-					if expectedValue == nil {
-						assert.NotContains(t, processedEvent.Payload, field)
-					} else {
-						assert.Equal(t, expectedValue, processedEvent.Payload[field])
-					}
+			processor.processTransactionEvent(tt.event, processedEvent, logger)
+
+			// Check expected fields
+			for field, expectedValue := range tt.expectedFields {
+				if expectedValue == nil {
+					assert.NotContains(t, processedEvent.Payload, field)
+					assert.NotContains(t, processedEvent.DerivedFields, field)
+				} else {
+					assert.Equal(t, expectedValue, processedEvent.Payload[field])
+					assert.Contains(t, processedEvent.DerivedFields, field, "Added fields should be recorded as derived")
 				}
 			}
 		})
@@ -526,35 +1104,13 @@ func TestProcessTransactionEvent(t *testing.T) {
 func TestProcessIntegrationEvent(t *testing.T) {
 	tests := []eventProcessingTestCase{
 		{
-			name:          "Valid Integration Event",
-			event:         createIntegrationEvent(),
-			expectedError: false,
+			name:  "Valid Integration Event",
+			event: createIntegrationEvent(),
 			expectedFields: map[string]interface{}{
 				"integrationProcessedAt": mock.AnythingOfType("string"),
 			},
 			description: "Should successfully process integration event with timestamp",
 		},
-		{
-			name:          "Missing Source",
-			event:         createIntegrationEventMissingField("source"),
-			expectedError: true,
-			errorMsg:      "missing required field for integration: source",
-			description:   "Should fail when source is missing",
-		},
-		{
-			name:          "Missing Target",
-			event:         createIntegrationEventMissingField("target"),
-			expectedError: true,
-			errorMsg:      "missing required field for integration: target",
-			description:   "Should fail when target is missing",
-		},
-		{
-			name:          "Missing Operation",
-			event:         createIntegrationEventMissingField("operation"),
-			expectedError: true,
-			errorMsg:      "missing required field for integration: operation",
-			description:   "Should fail when operation is missing",
-		},
 	}
 
 	for _, tt := range tests {
@@ -567,117 +1123,20 @@ func TestProcessIntegrationEvent(t *testing.T) {
 			processedEvent := tt.event.ToProcessedEvent()
 
 			// Execute test
-			err := processor.processIntegrationEvent(tt.event, processedEvent, logger)
-
-			// Assertions
-			if tt.expectedError {
-				assert.Error(t, err)
-				if tt.errorMsg != "" {
-					assert.Contains(t, err.Error(), tt.errorMsg)
-				}
-			} else {
-				assert.NoError(t, err)
-				// Check expected fields
-				for field, expectedValue := range tt.expectedFields {
-					if field == "integrationProcessedAt" {
-						assert.Contains(t, processedEvent.Payload, field)
-						// Verify it's a valid timestamp
-						timestampStr := processedEvent.Payload[field].(string)
-						_, parseErr := time.Parse(time.RFC3339, timestampStr)
-						assert.NoError(t, parseErr)
-					} else {
-						assert.Equal(t, expectedValue, processedEvent.Payload[field])
-					}
-				}
-			}
-		})
-	}
-}
-
-// TestValidateClientPermissions tests client permission validation
-func TestValidateClientPermissions(t *testing.T) {
-	tests := []struct {
-		name           string
-		clientID       string
-		eventType      models.EventType
-		mockRepository func(*MockRepository)
-		expectError    bool
-		errorMsg       string
-		description    string
-	}{
-		{
-			name:      "Valid Client with Permission",
-			clientID:  "client-001",
-			eventType: models.EventTypeMonitoring,
-			mockRepository: func(mr *MockRepository) {
-				mr.On("GetClientConfig", mock.Anything, "client-001").Return(createValidClientConfig(), nil)
-			},
-			expectError: false,
-			description: "Should allow event when client has permission",
-		},
-		{
-			name:      "Client Without Permission",
-			clientID:  "client-002",
-			eventType: models.EventTypeMonitoring,
-			mockRepository: func(mr *MockRepository) {
-				mr.On("GetClientConfig", mock.Anything, "client-002").Return(createRestrictedClientConfig(), nil)
-			},
-			expectError: true,
-			errorMsg:    "client client-002 is not allowed to send events of type monitoring",
-			description: "Should deny event when client lacks permission",
-		},
-		{
-			name:      "Inactive Client",
-			clientID:  "client-003",
-			eventType: models.EventTypeUserAction,
-			mockRepository: func(mr *MockRepository) {
-				mr.On("GetClientConfig", mock.Anything, "client-003").Return(createInactiveClientConfig(), nil)
-			},
-			expectError: true,
-			errorMsg:    "client client-003 is not active",
-			description: "Should deny event when client is inactive",
-		},
-		{
-			name:      "Client Config Not Found - Allow by Default",
-			clientID:  "client-004",
-			eventType: models.EventTypeTransaction,
-			mockRepository: func(mr *MockRepository) {
-				mr.On("GetClientConfig", mock.Anything, "client-004").Return(nil, errors.New("not found"))
-			},
-			expectError: false,
-			description: "Should allow event when client config not found (default behavior)",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Create mocks
-			mockRepo := &MockRepository{}
-			logger := logrus.New().WithField("test", "permissions")
-
-			// Setup mocks
-			if tt.mockRepository != nil {
-				tt.mockRepository(mockRepo)
-			}
-
-			// Create processor
-			processor := &EventProcessor{repository: mockRepo}
-
-			// Execute test
-			err := processor.validateClientPermissions(context.Background(), tt.clientID, tt.eventType, logger)
-
-			// Assertions
-			if tt.expectError {
-				assert.Error(t, err)
-				if tt.errorMsg != "" {
-					assert.Contains(t, err.Error(), tt.errorMsg)
+			processor.processIntegrationEvent(tt.event, processedEvent, logger)
+
+			// Check expected fields
+			for field, expectedValue := range tt.expectedFields {
+				if field == "integrationProcessedAt" {
+					assert.Contains(t, processedEvent.Payload, field)
+					// Verify it's a valid timestamp
+					timestampStr := processedEvent.Payload[field].(string)
+					_, parseErr := time.Parse(time.RFC3339, timestampStr)
+					assert.NoError(t, parseErr)
+				} else {
+					assert.Equal(t, expectedValue, processedEvent.Payload[field])
 				}
-			} else {
-				assert.NoError(t, err)
 			}
-
-			// Verify mocks
-			mockRepo.AssertExpectations(t)
 		})
 	}
 }
@@ -736,12 +1195,6 @@ func createUserActionEvent() *models.Event {
 	return event
 }
 
-func createUserActionEventMissingField(field string) *models.Event {
-	event := createUserActionEvent()
-	delete(event.Payload, field)
-	return event
-}
-
 func createTransactionEvent() *models.Event {
 	event := createValidEvent()
 	event.EventType = models.EventTypeTransaction
@@ -758,18 +1211,12 @@ func createHighValueTransactionEvent() *models.Event {
 	event.EventType = models.EventTypeTransaction
 	event.Payload = map[string]interface{}{
 		"transactionId": "txn456",
-		"amount":        15000.0,
+		"amount":        json.Number("15000"),
 		"currency":      "USD",
 	}
 	return event
 }
 
-func createTransactionEventMissingField(field string) *models.Event {
-	event := createTransactionEvent()
-	delete(event.Payload, field)
-	return event
-}
-
 func createIntegrationEvent() *models.Event {
 	event := createValidEvent()
 	event.EventType = models.EventTypeIntegration
@@ -781,12 +1228,6 @@ func createIntegrationEvent() *models.Event {
 	return event
 }
 
-func createIntegrationEventMissingField(field string) *models.Event {
-	event := createIntegrationEvent()
-	delete(event.Payload, field)
-	return event
-}
-
 func createUnknownEventType() *models.Event {
 	event := createValidEvent()
 	event.EventType = "unknown_type"
@@ -0,0 +1,245 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/google/uuid"
+
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// MemoryQueue is an in-process stand-in for SQS, used to run the service without external dependencies.
+//
+// It implements the subset of the SQS API used by the consumer and the DLQ tools: messages are keyed
+// by queue URL, received messages stay in flight until deleted or made visible again with
+// ChangeMessageVisibility and DelaySeconds and WaitTimeSeconds are honoured.
+type MemoryQueue struct {
+	mu       sync.Mutex
+	queueURL string
+	queues   map[string]*memoryQueueState
+
+	// sent numbers messages in the order they were sent, which released messages return to
+	sent int64
+
+	// changed is closed and replaced whenever a message is sent, waking long polls
+	changed chan struct{}
+}
+
+type memoryQueueState struct {
+	pending  []*queuedMessage
+	inFlight map[string]*queuedMessage
+}
+
+type queuedMessage struct {
+	message   types.Message
+	visibleAt time.Time
+	sequence  int64
+}
+
+// NewMemoryQueue creates an empty in-memory queue; Publish sends to queueURL
+func NewMemoryQueue(queueURL string) *MemoryQueue {
+	return &MemoryQueue{
+		queueURL: queueURL,
+		queues:   make(map[string]*memoryQueueState),
+		changed:  make(chan struct{}),
+	}
+}
+
+// SendMessage enqueues a message on the queue named by params.QueueUrl
+func (q *MemoryQueue) SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	messageID := uuid.NewString()
+
+	attributes := make(map[string]types.MessageAttributeValue, len(params.MessageAttributes))
+	for name, value := range params.MessageAttributes {
+		attributes[name] = value
+	}
+
+	// System attributes are returned with every message, as if they had all been requested
+	var systemAttributes map[string]string
+	for name, value := range params.MessageSystemAttributes {
+		if systemAttributes == nil {
+			systemAttributes = make(map[string]string, len(params.MessageSystemAttributes))
+		}
+		systemAttributes[name] = aws.ToString(value.StringValue)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.sent++
+	state := q.state(aws.ToString(params.QueueUrl))
+	state.pending = append(state.pending, &queuedMessage{
+		message: types.Message{
+			MessageId:         aws.String(messageID),
+			Body:              params.MessageBody,
+			MessageAttributes: attributes,
+			Attributes:        systemAttributes,
+		},
+		visibleAt: time.Now().Add(time.Duration(params.DelaySeconds) * time.Second),
+		sequence:  q.sent,
+	})
+
+	close(q.changed)
+	q.changed = make(chan struct{})
+
+	return &sqs.SendMessageOutput{MessageId: aws.String(messageID)}, nil
+}
+
+// ReceiveMessage returns visible messages, waiting up to WaitTimeSeconds for one to arrive
+func (q *MemoryQueue) ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+	maxMessages := int(params.MaxNumberOfMessages)
+	if maxMessages <= 0 {
+		maxMessages = 1
+	}
+	deadline := time.Now().Add(time.Duration(params.WaitTimeSeconds) * time.Second)
+
+	for {
+		messages, changed, nextVisible := q.receive(aws.ToString(params.QueueUrl), maxMessages)
+		if len(messages) > 0 {
+			return &sqs.ReceiveMessageOutput{Messages: messages}, nil
+		}
+
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return &sqs.ReceiveMessageOutput{}, nil
+		}
+		if !nextVisible.IsZero() && time.Until(nextVisible) < wait {
+			wait = time.Until(nextVisible)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-changed:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// receive moves up to max visible messages in flight, returning them with a channel signalling new
+// messages and the time the next delayed message becomes visible
+func (q *MemoryQueue) receive(queueURL string, max int) ([]types.Message, <-chan struct{}, time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	state := q.state(queueURL)
+	now := time.Now()
+
+	var (
+		messages    []types.Message
+		remaining   []*queuedMessage
+		nextVisible time.Time
+	)
+	for _, queued := range state.pending {
+		if len(messages) < max && !queued.visibleAt.After(now) {
+			receiptHandle := uuid.NewString()
+			state.inFlight[receiptHandle] = queued
+
+			message := queued.message
+			message.ReceiptHandle = aws.String(receiptHandle)
+			messages = append(messages, message)
+			continue
+		}
+
+		if queued.visibleAt.After(now) && (nextVisible.IsZero() || queued.visibleAt.Before(nextVisible)) {
+			nextVisible = queued.visibleAt
+		}
+		remaining = append(remaining, queued)
+	}
+	state.pending = remaining
+
+	return messages, q.changed, nextVisible
+}
+
+// DeleteMessage removes an in-flight message by its receipt handle
+func (q *MemoryQueue) DeleteMessage(ctx context.Context, params *sqs.DeleteMessageInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	state := q.state(aws.ToString(params.QueueUrl))
+	receiptHandle := aws.ToString(params.ReceiptHandle)
+	if _, ok := state.inFlight[receiptHandle]; !ok {
+		return nil, fmt.Errorf("receipt handle %q is not valid", receiptHandle)
+	}
+	delete(state.inFlight, receiptHandle)
+
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+// ChangeMessageVisibility returns an in-flight message to the queue, visible again after
+// VisibilityTimeout seconds; its receipt handle is no longer valid afterwards
+func (q *MemoryQueue) ChangeMessageVisibility(ctx context.Context, params *sqs.ChangeMessageVisibilityInput, optFns ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	state := q.state(aws.ToString(params.QueueUrl))
+	receiptHandle := aws.ToString(params.ReceiptHandle)
+	queued, ok := state.inFlight[receiptHandle]
+	if !ok {
+		return nil, fmt.Errorf("receipt handle %q is not valid", receiptHandle)
+	}
+	delete(state.inFlight, receiptHandle)
+	queued.visibleAt = time.Now().Add(time.Duration(params.VisibilityTimeout) * time.Second)
+
+	// Keep the send order so that released messages are received in the same order again
+	position := sort.Search(len(state.pending), func(i int) bool {
+		return state.pending[i].sequence > queued.sequence
+	})
+	state.pending = append(state.pending, nil)
+	copy(state.pending[position+1:], state.pending[position:])
+	state.pending[position] = queued
+
+	close(q.changed)
+	q.changed = make(chan struct{})
+
+	return &sqs.ChangeMessageVisibilityOutput{}, nil
+}
+
+// Publish sends an event to the queue in the same format as the producer
+func (q *MemoryQueue) Publish(ctx context.Context, event *models.Event) (string, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	output, err := q.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:          aws.String(q.queueURL),
+		MessageBody:       aws.String(string(body)),
+		MessageAttributes: eventAttributes(string(event.EventType), event.ClientID),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return aws.ToString(output.MessageId), nil
+}
+
+// Len returns the number of pending and in-flight messages on a queue
+func (q *MemoryQueue) Len(queueURL string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	state := q.state(queueURL)
+	return len(state.pending) + len(state.inFlight)
+}
+
+// state returns the state of a queue, creating it on first use; q.mu must be held
+func (q *MemoryQueue) state(queueURL string) *memoryQueueState {
+	state, ok := q.queues[queueURL]
+	if !ok {
+		state = &memoryQueueState{inFlight: make(map[string]*queuedMessage)}
+		q.queues[queueURL] = state
+	}
+	return state
+}
@@ -0,0 +1,173 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+const testQueueURL = "memory://event-queue"
+
+func send(t *testing.T, q *MemoryQueue, body string, delaySeconds int32) {
+	_, err := q.SendMessage(context.Background(), &sqs.SendMessageInput{
+		QueueUrl:     aws.String(testQueueURL),
+		MessageBody:  aws.String(body),
+		DelaySeconds: delaySeconds,
+	})
+	require.NoError(t, err)
+}
+
+func receive(t *testing.T, q *MemoryQueue, max, waitSeconds int32) *sqs.ReceiveMessageOutput {
+	output, err := q.ReceiveMessage(context.Background(), &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(testQueueURL),
+		MaxNumberOfMessages: max,
+		WaitTimeSeconds:     waitSeconds,
+	})
+	require.NoError(t, err)
+	return output
+}
+
+// TestMemoryQueueLifecycle tests send, receive and delete in FIFO order
+func TestMemoryQueueLifecycle(t *testing.T) {
+	q := NewMemoryQueue(testQueueURL)
+	send(t, q, "first", 0)
+	send(t, q, "second", 0)
+	send(t, q, "third", 0)
+
+	output := receive(t, q, 2, 0)
+	require.Len(t, output.Messages, 2)
+	assert.Equal(t, "first", aws.ToString(output.Messages[0].Body))
+	assert.Equal(t, "second", aws.ToString(output.Messages[1].Body))
+	assert.Equal(t, 3, q.Len(testQueueURL))
+
+	// In-flight messages are not delivered again
+	output2 := receive(t, q, 10, 0)
+	require.Len(t, output2.Messages, 1)
+	assert.Equal(t, "third", aws.ToString(output2.Messages[0].Body))
+
+	for _, message := range append(output.Messages, output2.Messages...) {
+		_, err := q.DeleteMessage(context.Background(), &sqs.DeleteMessageInput{
+			QueueUrl:      aws.String(testQueueURL),
+			ReceiptHandle: message.ReceiptHandle,
+		})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 0, q.Len(testQueueURL))
+}
+
+// TestMemoryQueueDeleteUnknownReceipt tests deleting with an invalid receipt handle
+func TestMemoryQueueDeleteUnknownReceipt(t *testing.T) {
+	q := NewMemoryQueue(testQueueURL)
+
+	_, err := q.DeleteMessage(context.Background(), &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(testQueueURL),
+		ReceiptHandle: aws.String("unknown"),
+	})
+	assert.Error(t, err)
+}
+
+// TestMemoryQueueChangeMessageVisibility tests that released messages are received again in send order
+func TestMemoryQueueChangeMessageVisibility(t *testing.T) {
+	q := NewMemoryQueue(testQueueURL)
+	send(t, q, "first", 0)
+	send(t, q, "second", 0)
+	send(t, q, "third", 0)
+
+	output := receive(t, q, 2, 0)
+	require.Len(t, output.Messages, 2)
+	for _, message := range output.Messages {
+		_, err := q.ChangeMessageVisibility(context.Background(), &sqs.ChangeMessageVisibilityInput{
+			QueueUrl:      aws.String(testQueueURL),
+			ReceiptHandle: message.ReceiptHandle,
+		})
+		require.NoError(t, err)
+	}
+
+	output = receive(t, q, 10, 0)
+	require.Len(t, output.Messages, 3)
+	assert.Equal(t, "first", aws.ToString(output.Messages[0].Body))
+	assert.Equal(t, "second", aws.ToString(output.Messages[1].Body))
+	assert.Equal(t, "third", aws.ToString(output.Messages[2].Body))
+
+	// The receipt handle of a released message cannot be used again
+	_, err := q.ChangeMessageVisibility(context.Background(), &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:      aws.String(testQueueURL),
+		ReceiptHandle: aws.String("unknown"),
+	})
+	assert.Error(t, err)
+}
+
+// TestMemoryQueueLongPoll tests that a waiting receive wakes up when a message is sent
+func TestMemoryQueueLongPoll(t *testing.T) {
+	q := NewMemoryQueue(testQueueURL)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		send(t, q, "late", 0)
+	}()
+
+	start := time.Now()
+	output := receive(t, q, 1, 5)
+	require.Len(t, output.Messages, 1)
+	assert.Equal(t, "late", aws.ToString(output.Messages[0].Body))
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+// TestMemoryQueueDelay tests that delayed messages become visible only after their delay
+func TestMemoryQueueDelay(t *testing.T) {
+	q := NewMemoryQueue(testQueueURL)
+	send(t, q, "delayed", 1)
+
+	assert.Empty(t, receive(t, q, 1, 0).Messages)
+
+	output := receive(t, q, 1, 3)
+	require.Len(t, output.Messages, 1)
+	assert.Equal(t, "delayed", aws.ToString(output.Messages[0].Body))
+}
+
+// TestMemoryQueueCancelledReceive tests that a long poll returns when its context is cancelled
+func TestMemoryQueueCancelledReceive(t *testing.T) {
+	q := NewMemoryQueue(testQueueURL)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := q.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:        aws.String(testQueueURL),
+		WaitTimeSeconds: 20,
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// TestMemoryQueuePublish tests that published events use the producer's message format
+func TestMemoryQueuePublish(t *testing.T) {
+	q := NewMemoryQueue(testQueueURL)
+	event := &models.Event{
+		EventID:   "evt-1",
+		EventType: models.EventTypeMonitoring,
+		ClientID:  "client-001",
+		Version:   "1.0",
+		Payload:   map[string]interface{}{"value": 1.0},
+	}
+
+	messageID, err := q.Publish(context.Background(), event)
+	require.NoError(t, err)
+
+	output := receive(t, q, 1, 0)
+	require.Len(t, output.Messages, 1)
+	message := output.Messages[0]
+	assert.Equal(t, messageID, aws.ToString(message.MessageId))
+	assert.Equal(t, "monitoring", aws.ToString(message.MessageAttributes["EventType"].StringValue))
+	assert.Equal(t, "client-001", aws.ToString(message.MessageAttributes["ClientID"].StringValue))
+
+	var decoded models.Event
+	require.NoError(t, json.Unmarshal([]byte(aws.ToString(message.Body)), &decoded))
+	assert.Equal(t, event.EventID, decoded.EventID)
+}
@@ -0,0 +1,70 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// SendMessageAPI is the part of an SQS client needed to send messages; *sqs.Client and MemoryQueue are one
+type SendMessageAPI interface {
+	SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
+}
+
+// Sender sends raw event bodies to a queue, such as events resubmitted from quarantine
+type Sender struct {
+	client   SendMessageAPI
+	queueURL string
+}
+
+// NewSender creates a sender for the queue at queueURL
+func NewSender(client SendMessageAPI, queueURL string) *Sender {
+	return &Sender{
+		client:   client,
+		queueURL: queueURL,
+	}
+}
+
+// Send enqueues body unchanged and returns the message ID. The EventType and ClientID attributes
+// the producer sets are read from the body when it has them.
+func (s *Sender) Send(ctx context.Context, body string) (string, error) {
+	var fields struct {
+		EventType string `json:"eventType"`
+		ClientID  string `json:"clientId"`
+	}
+	// Bodies that are not events are still sent, so that they fail validation like any other
+	_ = json.Unmarshal([]byte(body), &fields)
+
+	output, err := s.client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:          aws.String(s.queueURL),
+		MessageBody:       aws.String(body),
+		MessageAttributes: eventAttributes(fields.EventType, fields.ClientID),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to send message: %w", err)
+	}
+	return aws.ToString(output.MessageId), nil
+}
+
+// eventAttributes returns the message attributes the producer sets, skipping empty values as SQS
+// rejects them
+func eventAttributes(eventType, clientID string) map[string]types.MessageAttributeValue {
+	attributes := make(map[string]types.MessageAttributeValue, 2)
+	if eventType != "" {
+		attributes["EventType"] = types.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(eventType),
+		}
+	}
+	if clientID != "" {
+		attributes["ClientID"] = types.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(clientID),
+		}
+	}
+	return attributes
+}
@@ -0,0 +1,60 @@
+package queue
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSenderSend tests that raw bodies are sent unchanged with the attributes found in them
+func TestSenderSend(t *testing.T) {
+	tests := []struct {
+		name               string
+		body               string
+		expectedAttributes map[string]string
+		description        string
+	}{
+		{
+			name:               "Event Body",
+			body:               `{"eventId":"evt-1","eventType":"transaction","clientId":"client-001"}`,
+			expectedAttributes: map[string]string{"EventType": "transaction", "ClientID": "client-001"},
+			description:        "Should set the event type and client attributes like the producer",
+		},
+		{
+			name:               "Event Without Client",
+			body:               `{"eventId":"evt-1","eventType":"monitoring"}`,
+			expectedAttributes: map[string]string{"EventType": "monitoring"},
+			description:        "Should leave out attributes the body has no value for",
+		},
+		{
+			name:               "Invalid JSON",
+			body:               "{not json",
+			expectedAttributes: map[string]string{},
+			description:        "Should send bodies that are not events without attributes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewMemoryQueue(testQueueURL)
+
+			messageID, err := NewSender(q, testQueueURL).Send(context.Background(), tt.body)
+			require.NoError(t, err)
+
+			output := receive(t, q, 1, 0)
+			require.Len(t, output.Messages, 1)
+			message := output.Messages[0]
+			assert.Equal(t, messageID, aws.ToString(message.MessageId))
+			assert.Equal(t, tt.body, aws.ToString(message.Body), tt.description)
+
+			attributes := make(map[string]string)
+			for name, value := range message.MessageAttributes {
+				attributes[name] = aws.ToString(value.StringValue)
+			}
+			assert.Equal(t, tt.expectedAttributes, attributes, tt.description)
+		})
+	}
+}
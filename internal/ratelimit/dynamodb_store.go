@@ -0,0 +1,200 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/d-sense/event-processor/internal/metrics"
+)
+
+// maxBucketAttempts bounds the read-update cycles of a token bucket contended by other replicas
+const maxBucketAttempts = 5
+
+// bucketIdleTTL is how long an unused bucket is kept; a bucket missing from the table is full
+const bucketIdleTTL = 24 * time.Hour
+
+// DynamoDBClient defines the DynamoDB operations of the store
+type DynamoDBClient interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+}
+
+// DynamoDBStore keeps buckets and counters in a DynamoDB table keyed by counter_id, so that limits
+// hold across replicas. Counters are changed with conditional updates only, and expire through the
+// table's ttl attribute.
+type DynamoDBStore struct {
+	client    DynamoDBClient
+	tableName string
+}
+
+// NewDynamoDBStore creates a store on the named table
+func NewDynamoDBStore(awsCfg aws.Config, tableName string) *DynamoDBStore {
+	return &DynamoDBStore{client: dynamodb.NewFromConfig(awsCfg), tableName: tableName}
+}
+
+// TakeToken takes a token from the bucket at key. The bucket is read and written back with a
+// condition on its version, so concurrent takes from other replicas are retried rather than lost.
+func (s *DynamoDBStore) TakeToken(ctx context.Context, key string, rate float64, burst int, now time.Time) (bool, time.Duration, error) {
+	for attempt := 0; attempt < maxBucketAttempts; attempt++ {
+		current, version, err := s.getBucket(ctx, key)
+		if err != nil {
+			return false, 0, err
+		}
+
+		next, ok, wait := current.take(rate, burst, now)
+		if !ok {
+			return false, wait, nil
+		}
+
+		err = s.putBucket(ctx, key, next, version)
+		var conflict *types.ConditionalCheckFailedException
+		if errors.As(err, &conflict) {
+			continue
+		}
+		if err != nil {
+			return false, 0, err
+		}
+		return true, 0, nil
+	}
+	return false, 0, fmt.Errorf("bucket %s changed concurrently %d times", key, maxBucketAttempts)
+}
+
+// getBucket reads the bucket at key and its version; a missing bucket is version 0
+func (s *DynamoDBStore) getBucket(ctx context.Context, key string) (bucket, int64, error) {
+	start := time.Now()
+	output, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.tableName),
+		Key:            map[string]types.AttributeValue{"counter_id": &types.AttributeValueMemberS{Value: key}},
+		ConsistentRead: aws.Bool(true),
+	})
+	metrics.ObserveDynamoDB("GetItem", start, err)
+	if err != nil {
+		return bucket{}, 0, fmt.Errorf("failed to read bucket %s: %w", key, err)
+	}
+	if output.Item == nil {
+		return bucket{}, 0, nil
+	}
+
+	// A missing attribute reads as "", which fails to parse
+	tokens, tokensErr := strconv.ParseFloat(numberAttribute(output.Item, "tokens"), 64)
+	updated, updatedErr := strconv.ParseInt(numberAttribute(output.Item, "updated_at"), 10, 64)
+	version, versionErr := strconv.ParseInt(numberAttribute(output.Item, "version"), 10, 64)
+	if err := errors.Join(tokensErr, updatedErr, versionErr); err != nil {
+		return bucket{}, 0, fmt.Errorf("invalid bucket %s: %w", key, err)
+	}
+	return bucket{tokens: tokens, updated: time.Unix(0, updated)}, version, nil
+}
+
+// putBucket writes the bucket at key if it is still at version
+func (s *DynamoDBStore) putBucket(ctx context.Context, key string, b bucket, version int64) error {
+	condition := "#version = :version"
+	if version == 0 {
+		condition = "attribute_not_exists(counter_id)"
+	}
+
+	values := map[string]types.AttributeValue{
+		":tokens":  numberValue(strconv.FormatFloat(b.tokens, 'f', -1, 64)),
+		":updated": numberValue(strconv.FormatInt(b.updated.UnixNano(), 10)),
+		":next":    numberValue(strconv.FormatInt(version+1, 10)),
+		":ttl":     numberValue(strconv.FormatInt(b.updated.Add(bucketIdleTTL).Unix(), 10)),
+	}
+	if version != 0 {
+		values[":version"] = numberValue(strconv.FormatInt(version, 10))
+	}
+
+	start := time.Now()
+	_, err := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(s.tableName),
+		Key:                 map[string]types.AttributeValue{"counter_id": &types.AttributeValueMemberS{Value: key}},
+		UpdateExpression:    aws.String("SET #tokens = :tokens, #updated = :updated, #version = :next, #ttl = :ttl"),
+		ConditionExpression: aws.String(condition),
+		ExpressionAttributeNames: map[string]string{
+			"#tokens":  "tokens",
+			"#updated": "updated_at",
+			"#version": "version",
+			"#ttl":     "ttl",
+		},
+		ExpressionAttributeValues: values,
+	})
+	metrics.ObserveDynamoDB("UpdateItem", start, err)
+
+	var conflict *types.ConditionalCheckFailedException
+	if err != nil && !errors.As(err, &conflict) {
+		return fmt.Errorf("failed to update bucket %s: %w", key, err)
+	}
+	return err
+}
+
+// Increment counts an event against the counter at key with a single conditional update, which
+// fails once the counter has reached limit
+func (s *DynamoDBStore) Increment(ctx context.Context, key string, limit int64, expiresAt time.Time) (bool, error) {
+	start := time.Now()
+	_, err := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(s.tableName),
+		Key:                 map[string]types.AttributeValue{"counter_id": &types.AttributeValueMemberS{Value: key}},
+		UpdateExpression:    aws.String("ADD #count :one SET #ttl = :ttl"),
+		ConditionExpression: aws.String("attribute_not_exists(#count) OR #count < :limit"),
+		ExpressionAttributeNames: map[string]string{
+			"#count": "count",
+			"#ttl":   "ttl",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":one":   numberValue("1"),
+			":limit": numberValue(strconv.FormatInt(limit, 10)),
+			":ttl":   numberValue(strconv.FormatInt(expiresAt.Unix(), 10)),
+		},
+	})
+	metrics.ObserveDynamoDB("UpdateItem", start, err)
+
+	var exhausted *types.ConditionalCheckFailedException
+	if errors.As(err, &exhausted) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to increment counter %s: %w", key, err)
+	}
+	return true, nil
+}
+
+// Release gives back an event counted at key. The condition keeps the counter from going below zero
+// or being recreated after it expired.
+func (s *DynamoDBStore) Release(ctx context.Context, key string) error {
+	start := time.Now()
+	_, err := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                aws.String(s.tableName),
+		Key:                      map[string]types.AttributeValue{"counter_id": &types.AttributeValueMemberS{Value: key}},
+		UpdateExpression:         aws.String("ADD #count :minusOne"),
+		ConditionExpression:      aws.String("#count > :zero"),
+		ExpressionAttributeNames: map[string]string{"#count": "count"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":minusOne": numberValue("-1"),
+			":zero":     numberValue("0"),
+		},
+	})
+	metrics.ObserveDynamoDB("UpdateItem", start, err)
+
+	var missing *types.ConditionalCheckFailedException
+	if err != nil && !errors.As(err, &missing) {
+		return fmt.Errorf("failed to release counter %s: %w", key, err)
+	}
+	return nil
+}
+
+func numberValue(value string) types.AttributeValue {
+	return &types.AttributeValueMemberN{Value: value}
+}
+
+// numberAttribute returns the value of a numeric attribute of an item, or "" when it has none
+func numberAttribute(item map[string]types.AttributeValue, name string) string {
+	if attribute, ok := item[name].(*types.AttributeValueMemberN); ok {
+		return attribute.Value
+	}
+	return ""
+}
@@ -0,0 +1,284 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockDynamoDBClient is a mock implementation of the DynamoDBClient interface
+type MockDynamoDBClient struct {
+	mock.Mock
+}
+
+func (m *MockDynamoDBClient) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dynamodb.GetItemOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBClient) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dynamodb.UpdateItemOutput), args.Error(1)
+}
+
+// bucketItem returns a stored bucket with the given tokens, update time and version
+func bucketItem(tokens string, updated time.Time, version string) *dynamodb.GetItemOutput {
+	return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+		"counter_id": &types.AttributeValueMemberS{Value: "rate#client-001"},
+		"tokens":     &types.AttributeValueMemberN{Value: tokens},
+		"updated_at": &types.AttributeValueMemberN{Value: strconv.FormatInt(updated.UnixNano(), 10)},
+		"version":    &types.AttributeValueMemberN{Value: version},
+	}}
+}
+
+// TestDynamoDBStoreTakeToken tests taking tokens from buckets shared through conditional updates
+func TestDynamoDBStoreTakeToken(t *testing.T) {
+	now := time.Date(2025, 3, 5, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		mockClient   func(*MockDynamoDBClient)
+		expectTaken  bool
+		expectedWait time.Duration
+		expectError  bool
+		errorMsg     string
+		description  string
+	}{
+		{
+			name: "New Bucket",
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("GetItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.GetItemInput) bool {
+					return aws.ToString(input.TableName) == "events-limits" && aws.ToBool(input.ConsistentRead)
+				})).Return(&dynamodb.GetItemOutput{}, nil)
+				mc.On("UpdateItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.UpdateItemInput) bool {
+					return aws.ToString(input.ConditionExpression) == "attribute_not_exists(counter_id)" &&
+						input.ExpressionAttributeValues[":tokens"].(*types.AttributeValueMemberN).Value == "9" &&
+						input.ExpressionAttributeValues[":next"].(*types.AttributeValueMemberN).Value == "1"
+				})).Return(&dynamodb.UpdateItemOutput{}, nil)
+			},
+			expectTaken: true,
+			description: "Should create a full bucket and take a token from it",
+		},
+		{
+			name: "Refilled Bucket",
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("GetItem", mock.Anything, mock.Anything).Return(bucketItem("0", now.Add(-time.Second), "7"), nil)
+				mc.On("UpdateItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.UpdateItemInput) bool {
+					return aws.ToString(input.ConditionExpression) == "#version = :version" &&
+						input.ExpressionAttributeValues[":version"].(*types.AttributeValueMemberN).Value == "7" &&
+						input.ExpressionAttributeValues[":tokens"].(*types.AttributeValueMemberN).Value == "3"
+				})).Return(&dynamodb.UpdateItemOutput{}, nil)
+			},
+			expectTaken: true,
+			description: "Should refill the bucket for the time passed and update it at its version",
+		},
+		{
+			name: "Empty Bucket",
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("GetItem", mock.Anything, mock.Anything).Return(bucketItem("0.5", now, "7"), nil)
+			},
+			expectTaken:  false,
+			expectedWait: 125 * time.Millisecond,
+			description:  "Should not write an empty bucket and report the wait for the next token",
+		},
+		{
+			name: "Concurrent Take",
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("GetItem", mock.Anything, mock.Anything).Return(bucketItem("3", now, "7"), nil).Once()
+				mc.On("GetItem", mock.Anything, mock.Anything).Return(bucketItem("2", now, "8"), nil).Once()
+				mc.On("UpdateItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.UpdateItemInput) bool {
+					return input.ExpressionAttributeValues[":version"].(*types.AttributeValueMemberN).Value == "7"
+				})).Return(nil, &types.ConditionalCheckFailedException{}).Once()
+				mc.On("UpdateItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.UpdateItemInput) bool {
+					return input.ExpressionAttributeValues[":version"].(*types.AttributeValueMemberN).Value == "8" &&
+						input.ExpressionAttributeValues[":tokens"].(*types.AttributeValueMemberN).Value == "1"
+				})).Return(&dynamodb.UpdateItemOutput{}, nil).Once()
+			},
+			expectTaken: true,
+			description: "Should reread the bucket when another replica changed it",
+		},
+		{
+			name: "Persistent Contention",
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("GetItem", mock.Anything, mock.Anything).Return(bucketItem("3", now, "7"), nil).Times(maxBucketAttempts)
+				mc.On("UpdateItem", mock.Anything, mock.Anything).Return(nil, &types.ConditionalCheckFailedException{}).Times(maxBucketAttempts)
+			},
+			expectError: true,
+			errorMsg:    "bucket rate#client-001 changed concurrently 5 times",
+			description: "Should give up after a bounded number of attempts",
+		},
+		{
+			name: "Invalid Bucket",
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("GetItem", mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+					"counter_id": &types.AttributeValueMemberS{Value: "rate#client-001"},
+				}}, nil)
+			},
+			expectError: true,
+			errorMsg:    "invalid bucket rate#client-001",
+			description: "Should fail on buckets without their attributes",
+		},
+		{
+			name: "GetItem Failure",
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("GetItem", mock.Anything, mock.Anything).Return(nil, errors.New("dynamodb error"))
+			},
+			expectError: true,
+			errorMsg:    "failed to read bucket rate#client-001: dynamodb error",
+			description: "Should fail when the bucket cannot be read",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &MockDynamoDBClient{}
+			tt.mockClient(mockClient)
+			store := &DynamoDBStore{client: mockClient, tableName: "events-limits"}
+
+			taken, wait, err := store.TakeToken(context.Background(), "rate#client-001", 4, 10, now)
+
+			if tt.expectError {
+				assert.ErrorContains(t, err, tt.errorMsg, tt.description)
+			} else {
+				assert.NoError(t, err, tt.description)
+				assert.Equal(t, tt.expectTaken, taken, tt.description)
+				assert.Equal(t, tt.expectedWait, wait)
+			}
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+// TestDynamoDBStoreIncrement tests counting events with a conditional atomic update
+func TestDynamoDBStoreIncrement(t *testing.T) {
+	expiresAt := time.Date(2025, 3, 6, 1, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		mockClient    func(*MockDynamoDBClient)
+		expectCounted bool
+		expectError   bool
+		errorMsg      string
+		description   string
+	}{
+		{
+			name: "Under Limit",
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("UpdateItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.UpdateItemInput) bool {
+					return aws.ToString(input.TableName) == "events-limits" &&
+						aws.ToString(input.UpdateExpression) == "ADD #count :one SET #ttl = :ttl" &&
+						aws.ToString(input.ConditionExpression) == "attribute_not_exists(#count) OR #count < :limit" &&
+						input.ExpressionAttributeValues[":limit"].(*types.AttributeValueMemberN).Value == "1000" &&
+						input.ExpressionAttributeValues[":ttl"].(*types.AttributeValueMemberN).Value == strconv.FormatInt(expiresAt.Unix(), 10)
+				})).Return(&dynamodb.UpdateItemOutput{}, nil)
+			},
+			expectCounted: true,
+			description:   "Should count the event",
+		},
+		{
+			name: "Limit Reached",
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("UpdateItem", mock.Anything, mock.Anything).Return(nil, &types.ConditionalCheckFailedException{})
+			},
+			expectCounted: false,
+			description:   "Should not count the event once the limit is reached",
+		},
+		{
+			name: "UpdateItem Failure",
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("UpdateItem", mock.Anything, mock.Anything).Return(nil, errors.New("dynamodb error"))
+			},
+			expectError: true,
+			errorMsg:    "failed to increment counter daily#client-001#2025-03-05: dynamodb error",
+			description: "Should fail when the counter cannot be updated",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &MockDynamoDBClient{}
+			tt.mockClient(mockClient)
+			store := &DynamoDBStore{client: mockClient, tableName: "events-limits"}
+
+			counted, err := store.Increment(context.Background(), "daily#client-001#2025-03-05", 1000, expiresAt)
+
+			if tt.expectError {
+				assert.ErrorContains(t, err, tt.errorMsg, tt.description)
+			} else {
+				assert.NoError(t, err, tt.description)
+				assert.Equal(t, tt.expectCounted, counted, tt.description)
+			}
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+// TestDynamoDBStoreRelease tests giving back counted events with a conditional atomic update
+func TestDynamoDBStoreRelease(t *testing.T) {
+	tests := []struct {
+		name        string
+		mockClient  func(*MockDynamoDBClient)
+		expectError bool
+		errorMsg    string
+		description string
+	}{
+		{
+			name: "Counted Event",
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("UpdateItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.UpdateItemInput) bool {
+					return aws.ToString(input.UpdateExpression) == "ADD #count :minusOne" &&
+						aws.ToString(input.ConditionExpression) == "#count > :zero" &&
+						input.ExpressionAttributeValues[":minusOne"].(*types.AttributeValueMemberN).Value == "-1"
+				})).Return(&dynamodb.UpdateItemOutput{}, nil)
+			},
+			description: "Should decrement the counter",
+		},
+		{
+			name: "Expired Counter",
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("UpdateItem", mock.Anything, mock.Anything).Return(nil, &types.ConditionalCheckFailedException{})
+			},
+			description: "Should leave counters that are gone or at zero",
+		},
+		{
+			name: "UpdateItem Failure",
+			mockClient: func(mc *MockDynamoDBClient) {
+				mc.On("UpdateItem", mock.Anything, mock.Anything).Return(nil, errors.New("dynamodb error"))
+			},
+			expectError: true,
+			errorMsg:    "failed to release counter daily#client-001#2025-03-05: dynamodb error",
+			description: "Should fail when the counter cannot be updated",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &MockDynamoDBClient{}
+			tt.mockClient(mockClient)
+			store := &DynamoDBStore{client: mockClient, tableName: "events-limits"}
+
+			err := store.Release(context.Background(), "daily#client-001#2025-03-05")
+
+			if tt.expectError {
+				assert.ErrorContains(t, err, tt.errorMsg, tt.description)
+			} else {
+				assert.NoError(t, err, tt.description)
+			}
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/d-sense/event-processor/internal/metrics"
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+var (
+	// ErrLimitExceeded is returned for events over a limit of their client that are rejected
+	ErrLimitExceeded = errors.New("client limit exceeded")
+
+	// ErrSampledOut is returned for events over a limit of their client that the sample action drops
+	ErrSampledOut = errors.New("event sampled out")
+)
+
+// Names of the limits used for the limit label
+const (
+	limitRate    = "rate"
+	limitDaily   = "daily"
+	limitMonthly = "monthly"
+)
+
+// quotaGrace keeps quota counters a while past their period, so clock skew between replicas does
+// not reset a counter that is still in use
+const quotaGrace = time.Hour
+
+// Limiter enforces the rate limits and quotas configured in client configurations
+type Limiter struct {
+	store    Store
+	maxDelay time.Duration
+	logger   *logrus.Logger
+
+	now    func() time.Time
+	sleep  func(ctx context.Context, d time.Duration) error
+	sample func() float64
+}
+
+// New creates a limiter keeping its buckets and counters in store. The delay action holds an event
+// for at most maxDelay before rejecting it.
+func New(store Store, maxDelay time.Duration, logger *logrus.Logger) *Limiter {
+	return &Limiter{
+		store:    store,
+		maxDelay: maxDelay,
+		logger:   logger,
+		now:      time.Now,
+		sleep:    sleep,
+		sample:   rand.Float64,
+	}
+}
+
+// ValidateLimits checks that limits can be enforced
+func ValidateLimits(limits models.ClientLimits) error {
+	var problems []error
+	if limits.RatePerSecond < 0 || math.IsInf(limits.RatePerSecond, 0) || math.IsNaN(limits.RatePerSecond) {
+		problems = append(problems, errors.New("ratePerSecond must be a finite number, not negative"))
+	}
+	if limits.Burst < 0 {
+		problems = append(problems, errors.New("burst must not be negative"))
+	}
+	if limits.Burst > 0 && limits.RatePerSecond == 0 {
+		problems = append(problems, errors.New("burst needs a ratePerSecond"))
+	}
+	if limits.DailyQuota < 0 || limits.MonthlyQuota < 0 {
+		problems = append(problems, errors.New("quotas must not be negative"))
+	}
+	switch limits.OnExcess {
+	case "", models.ExcessDelay, models.ExcessReject, models.ExcessSample:
+	default:
+		problems = append(problems, fmt.Errorf("unknown onExcess action %q: expected %s, %s or %s",
+			limits.OnExcess, models.ExcessDelay, models.ExcessReject, models.ExcessSample))
+	}
+	if limits.SampleRate < 0 || limits.SampleRate > 1 {
+		problems = append(problems, errors.New("sampleRate must be between 0 and 1"))
+	}
+	if limits.SampleRate > 0 && limits.OnExcess != models.ExcessSample {
+		problems = append(problems, errors.New("sampleRate only applies to the sample action"))
+	}
+	return errors.Join(problems...)
+}
+
+// Admit decides whether the event fits the limits of its client. Events of clients without limits
+// are always admitted. Events over a limit are delayed, or fail with an error wrapping
+// ErrLimitExceeded or ErrSampledOut, depending on the client's action. Events over a quota are
+// never delayed, as a quota only frees up in the next day or month.
+func (l *Limiter) Admit(ctx context.Context, event *models.Event, config *models.ClientConfig) error {
+	if config == nil || config.Limits == nil {
+		return nil
+	}
+	limits := config.Limits
+
+	// An event rejected by one limit must not use up the others. Quotas are counted first, and the
+	// counts given back when a later limit rejects the event; the rate limit comes last, as a taken
+	// token cannot be given back.
+	// An event kept by sampling still goes through the remaining limits, so it counts against
+	// quotas it fits and takes a token if one is available.
+	var counted []string
+	sampled := false
+	for _, q := range quotas(event.ClientID, limits, l.now()) {
+		ok, err := l.store.Increment(ctx, q.key, q.quota, q.expiresAt)
+		if err != nil {
+			l.release(ctx, counted)
+			return fmt.Errorf("failed to count event against the %s quota: %w", q.limit, err)
+		}
+		if !ok {
+			if err := l.excess(event, limits, q.limit, fmt.Sprintf("%s quota of %d events", q.limit, q.quota), sampled); err != nil {
+				l.release(ctx, counted)
+				return err
+			}
+			sampled = true
+			continue
+		}
+		counted = append(counted, q.key)
+	}
+
+	if limits.RatePerSecond > 0 {
+		admitted, err := l.takeToken(ctx, event.ClientID, limits)
+		if err == nil && !admitted {
+			err = l.excess(event, limits, limitRate, fmt.Sprintf("rate limit of %v events per second", limits.RatePerSecond), sampled)
+		}
+		if err != nil {
+			l.release(ctx, counted)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// quota is a quota counter an event is counted against
+type quota struct {
+	limit     string
+	key       string
+	quota     int64
+	expiresAt time.Time
+}
+
+// quotas returns the counters of the client's quotas for the day and month of now
+func quotas(clientID string, limits *models.ClientLimits, now time.Time) []quota {
+	// Quotas are counted in UTC so that every replica agrees on the day and month
+	now = now.UTC()
+	periods := []struct {
+		limit  string
+		quota  int64
+		period string
+		end    time.Time
+	}{
+		{limitDaily, limits.DailyQuota, now.Format("2006-01-02"), time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)},
+		{limitMonthly, limits.MonthlyQuota, now.Format("2006-01"), time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	var counters []quota
+	for _, p := range periods {
+		if p.quota <= 0 {
+			continue
+		}
+		counters = append(counters, quota{
+			limit:     p.limit,
+			key:       fmt.Sprintf("%s#%s#%s", p.limit, clientID, p.period),
+			quota:     p.quota,
+			expiresAt: p.end.Add(quotaGrace),
+		})
+	}
+	return counters
+}
+
+// release gives back the counts of a rejected event. Failures are only logged: the event stays
+// counted, which errs on the side of the quota.
+func (l *Limiter) release(ctx context.Context, keys []string) {
+	// The event may be rejected because ctx is done, which must not keep its counts
+	ctx = context.WithoutCancel(ctx)
+	for _, key := range keys {
+		if err := l.store.Release(ctx, key); err != nil {
+			l.logger.WithError(err).WithField("counter", key).Warn("Failed to give back the quota count of a rejected event")
+		}
+	}
+}
+
+// takeToken takes a token from the client's bucket. With the delay action it waits for a token as
+// long as one becomes available within the max delay.
+func (l *Limiter) takeToken(ctx context.Context, clientID string, limits *models.ClientLimits) (bool, error) {
+	burst := limits.Burst
+	if burst <= 0 {
+		burst = int(math.Ceil(limits.RatePerSecond))
+	}
+	key := fmt.Sprintf("%s#%s", limitRate, clientID)
+
+	deadline := l.now().Add(l.maxDelay)
+	delayed := false
+	for {
+		now := l.now()
+		taken, wait, err := l.store.TakeToken(ctx, key, limits.RatePerSecond, burst, now)
+		if err != nil {
+			return false, fmt.Errorf("failed to take a token for client %s: %w", clientID, err)
+		}
+		if taken {
+			if delayed {
+				metrics.EventsLimited.WithLabelValues(clientID, limitRate, "delayed").Inc()
+			}
+			return true, nil
+		}
+		if limits.OnExcess != models.ExcessDelay || now.Add(wait).After(deadline) {
+			return false, nil
+		}
+
+		// Another replica may take the token first, in which case the next round waits again
+		delayed = true
+		if err := l.sleep(ctx, wait); err != nil {
+			return false, err
+		}
+	}
+}
+
+// excess applies the client's action to an event over a limit. sampled is whether sampling kept
+// the event at an earlier limit.
+func (l *Limiter) excess(event *models.Event, limits *models.ClientLimits, limit, reason string, sampled bool) error {
+	logger := l.logger.WithFields(logrus.Fields{
+		"client_id": event.ClientID,
+		"event_id":  event.EventID,
+		"limit":     limit,
+	})
+
+	if limits.OnExcess == models.ExcessSample {
+		// An event kept by sampling at an earlier limit is not sampled again, so that events over
+		// several limits are kept at the sample rate too
+		if sampled || l.sample() < limits.SampleRate {
+			metrics.EventsLimited.WithLabelValues(event.ClientID, limit, "sampled").Inc()
+			logger.Debug("Event over limit kept by sampling")
+			return nil
+		}
+		metrics.EventsLimited.WithLabelValues(event.ClientID, limit, "dropped").Inc()
+		return fmt.Errorf("%w: client %s is over its %s", ErrSampledOut, event.ClientID, reason)
+	}
+
+	metrics.EventsLimited.WithLabelValues(event.ClientID, limit, "rejected").Inc()
+	logger.Warn("Event over limit rejected")
+	return fmt.Errorf("%w: client %s is over its %s", ErrLimitExceeded, event.ClientID, reason)
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
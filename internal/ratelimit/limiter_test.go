@@ -0,0 +1,341 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// MockStore is a mock implementation of the Store interface
+type MockStore struct {
+	mock.Mock
+}
+
+func (m *MockStore) TakeToken(ctx context.Context, key string, rate float64, burst int, now time.Time) (bool, time.Duration, error) {
+	args := m.Called(ctx, key, rate, burst, now)
+	return args.Bool(0), args.Get(1).(time.Duration), args.Error(2)
+}
+
+func (m *MockStore) Increment(ctx context.Context, key string, limit int64, expiresAt time.Time) (bool, error) {
+	args := m.Called(ctx, key, limit, expiresAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStore) Release(ctx context.Context, key string) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func limitedClient(limits models.ClientLimits) *models.ClientConfig {
+	return &models.ClientConfig{ClientID: "client-001", AllowedTypes: []models.EventType{models.EventTypeMonitoring}, Active: true, Limits: &limits}
+}
+
+// TestAdmit tests enforcing rate limits and quotas with each excess action
+func TestAdmit(t *testing.T) {
+	// 2025-03-31 23:30 UTC, the last day of the month
+	now := time.Date(2025, 3, 31, 23, 30, 0, 0, time.UTC)
+	dailyExpiry := time.Date(2025, 4, 1, 1, 0, 0, 0, time.UTC)
+	monthlyExpiry := time.Date(2025, 4, 1, 1, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		config      *models.ClientConfig
+		mockStore   func(*MockStore)
+		sample      float64
+		expectError error
+		errorMsg    string
+		expectSleep []time.Duration
+		description string
+	}{
+		{
+			name:        "Unknown Client",
+			config:      nil,
+			mockStore:   func(ms *MockStore) {},
+			description: "Should admit events of clients without a configuration",
+		},
+		{
+			name:        "Client Without Limits",
+			config:      &models.ClientConfig{ClientID: "client-001", Active: true},
+			mockStore:   func(ms *MockStore) {},
+			description: "Should admit events of clients without limits",
+		},
+		{
+			name:   "Within Every Limit",
+			config: limitedClient(models.ClientLimits{RatePerSecond: 2.5, DailyQuota: 100, MonthlyQuota: 1000}),
+			mockStore: func(ms *MockStore) {
+				ms.On("TakeToken", mock.Anything, "rate#client-001", 2.5, 3, now).Return(true, time.Duration(0), nil)
+				ms.On("Increment", mock.Anything, "daily#client-001#2025-03-31", int64(100), dailyExpiry).Return(true, nil)
+				ms.On("Increment", mock.Anything, "monthly#client-001#2025-03", int64(1000), monthlyExpiry).Return(true, nil)
+			},
+			description: "Should take a token and count the event against both quotas, burst defaulting to the rate rounded up",
+		},
+		{
+			name:   "Rate Limited - Reject",
+			config: limitedClient(models.ClientLimits{RatePerSecond: 10, Burst: 20, DailyQuota: 100}),
+			mockStore: func(ms *MockStore) {
+				ms.On("Increment", mock.Anything, "daily#client-001#2025-03-31", int64(100), dailyExpiry).Return(true, nil)
+				ms.On("TakeToken", mock.Anything, "rate#client-001", 10.0, 20, now).Return(false, 100*time.Millisecond, nil)
+				ms.On("Release", mock.Anything, "daily#client-001#2025-03-31").Return(nil)
+			},
+			expectError: ErrLimitExceeded,
+			errorMsg:    "client limit exceeded: client client-001 is over its rate limit of 10 events per second",
+			description: "Should reject events over the rate limit, giving back their quota counts",
+		},
+		{
+			name:   "Rate Limited - Delay",
+			config: limitedClient(models.ClientLimits{RatePerSecond: 10, OnExcess: models.ExcessDelay}),
+			mockStore: func(ms *MockStore) {
+				ms.On("TakeToken", mock.Anything, "rate#client-001", 10.0, 10, now).Return(false, 100*time.Millisecond, nil).Once()
+				ms.On("TakeToken", mock.Anything, "rate#client-001", 10.0, 10, now).Return(false, 300*time.Millisecond, nil).Once()
+				ms.On("TakeToken", mock.Anything, "rate#client-001", 10.0, 10, now).Return(true, time.Duration(0), nil).Once()
+			},
+			expectSleep: []time.Duration{100 * time.Millisecond, 300 * time.Millisecond},
+			description: "Should wait for a token, waiting again when another replica took it first",
+		},
+		{
+			name:   "Rate Limited - Delay Too Long",
+			config: limitedClient(models.ClientLimits{RatePerSecond: 0.1, OnExcess: models.ExcessDelay}),
+			mockStore: func(ms *MockStore) {
+				ms.On("TakeToken", mock.Anything, "rate#client-001", 0.1, 1, now).Return(false, 10*time.Second, nil)
+			},
+			expectError: ErrLimitExceeded,
+			errorMsg:    "over its rate limit of 0.1 events per second",
+			description: "Should reject events that would wait longer than the max delay",
+		},
+		{
+			name:   "Rate Limited - Sampled In",
+			config: limitedClient(models.ClientLimits{RatePerSecond: 10, OnExcess: models.ExcessSample, SampleRate: 0.25}),
+			mockStore: func(ms *MockStore) {
+				ms.On("TakeToken", mock.Anything, "rate#client-001", 10.0, 10, now).Return(false, 100*time.Millisecond, nil)
+			},
+			sample:      0.2,
+			description: "Should keep the sampled share of excess events",
+		},
+		{
+			name:   "Rate Limited - Sampled Out",
+			config: limitedClient(models.ClientLimits{RatePerSecond: 10, OnExcess: models.ExcessSample, SampleRate: 0.25}),
+			mockStore: func(ms *MockStore) {
+				ms.On("TakeToken", mock.Anything, "rate#client-001", 10.0, 10, now).Return(false, 100*time.Millisecond, nil)
+			},
+			sample:      0.3,
+			expectError: ErrSampledOut,
+			errorMsg:    "event sampled out: client client-001 is over its rate limit",
+			description: "Should drop the rest of the excess events",
+		},
+		{
+			name:   "Daily Quota Exhausted - Delay",
+			config: limitedClient(models.ClientLimits{DailyQuota: 100, MonthlyQuota: 1000, OnExcess: models.ExcessDelay}),
+			mockStore: func(ms *MockStore) {
+				ms.On("Increment", mock.Anything, "daily#client-001#2025-03-31", int64(100), dailyExpiry).Return(false, nil)
+			},
+			expectError: ErrLimitExceeded,
+			errorMsg:    "client client-001 is over its daily quota of 100 events",
+			description: "Should reject events over a quota even with the delay action",
+		},
+		{
+			name:   "Daily Quota Exhausted - Sampled In",
+			config: limitedClient(models.ClientLimits{RatePerSecond: 10, DailyQuota: 100, MonthlyQuota: 1000, OnExcess: models.ExcessSample, SampleRate: 0.25}),
+			mockStore: func(ms *MockStore) {
+				ms.On("Increment", mock.Anything, "daily#client-001#2025-03-31", int64(100), dailyExpiry).Return(false, nil)
+				ms.On("Increment", mock.Anything, "monthly#client-001#2025-03", int64(1000), monthlyExpiry).Return(true, nil)
+				ms.On("TakeToken", mock.Anything, "rate#client-001", 10.0, 10, now).Return(true, time.Duration(0), nil)
+			},
+			sample:      0.2,
+			description: "Should count sampled events against the remaining quotas and take a token",
+		},
+		{
+			name:   "Daily Quota Exhausted - Sampled In, Rate Limited",
+			config: limitedClient(models.ClientLimits{RatePerSecond: 10, DailyQuota: 100, OnExcess: models.ExcessSample, SampleRate: 0.25}),
+			mockStore: func(ms *MockStore) {
+				ms.On("Increment", mock.Anything, "daily#client-001#2025-03-31", int64(100), dailyExpiry).Return(false, nil)
+				ms.On("TakeToken", mock.Anything, "rate#client-001", 10.0, 10, now).Return(false, 100*time.Millisecond, nil)
+			},
+			sample:      0.2,
+			description: "Should keep events sampled at a quota when they are over the rate limit too",
+		},
+		{
+			name:   "Daily Quota Exhausted - Sampled Out",
+			config: limitedClient(models.ClientLimits{RatePerSecond: 10, DailyQuota: 100, MonthlyQuota: 1000, OnExcess: models.ExcessSample, SampleRate: 0.25}),
+			mockStore: func(ms *MockStore) {
+				ms.On("Increment", mock.Anything, "daily#client-001#2025-03-31", int64(100), dailyExpiry).Return(false, nil)
+			},
+			sample:      0.3,
+			expectError: ErrSampledOut,
+			errorMsg:    "event sampled out: client client-001 is over its daily quota of 100 events",
+			description: "Should drop events sampled out at a quota without counting or limiting them further",
+		},
+		{
+			name:   "Daily Quota Exhausted - Rate Limited Client",
+			config: limitedClient(models.ClientLimits{RatePerSecond: 10, DailyQuota: 100}),
+			mockStore: func(ms *MockStore) {
+				ms.On("Increment", mock.Anything, "daily#client-001#2025-03-31", int64(100), dailyExpiry).Return(false, nil)
+			},
+			expectError: ErrLimitExceeded,
+			errorMsg:    "client client-001 is over its daily quota of 100 events",
+			description: "Should reject events over a quota without taking a token",
+		},
+		{
+			name:   "Monthly Quota Exhausted",
+			config: limitedClient(models.ClientLimits{DailyQuota: 100, MonthlyQuota: 1000}),
+			mockStore: func(ms *MockStore) {
+				ms.On("Increment", mock.Anything, "daily#client-001#2025-03-31", int64(100), dailyExpiry).Return(true, nil)
+				ms.On("Increment", mock.Anything, "monthly#client-001#2025-03", int64(1000), monthlyExpiry).Return(false, nil)
+				ms.On("Release", mock.Anything, "daily#client-001#2025-03-31").Return(nil)
+			},
+			expectError: ErrLimitExceeded,
+			errorMsg:    "client client-001 is over its monthly quota of 1000 events",
+			description: "Should reject events over the monthly quota, giving back their daily count",
+		},
+		{
+			name:   "Store Failure",
+			config: limitedClient(models.ClientLimits{DailyQuota: 100}),
+			mockStore: func(ms *MockStore) {
+				ms.On("Increment", mock.Anything, "daily#client-001#2025-03-31", int64(100), dailyExpiry).Return(false, errors.New("dynamodb error"))
+			},
+			errorMsg:    "failed to count event against the daily quota: dynamodb error",
+			description: "Should fail events that cannot be counted, so they are retried",
+		},
+		{
+			name:   "Store Failure After Counting",
+			config: limitedClient(models.ClientLimits{DailyQuota: 100, MonthlyQuota: 1000}),
+			mockStore: func(ms *MockStore) {
+				ms.On("Increment", mock.Anything, "daily#client-001#2025-03-31", int64(100), dailyExpiry).Return(true, nil)
+				ms.On("Increment", mock.Anything, "monthly#client-001#2025-03", int64(1000), monthlyExpiry).Return(false, errors.New("dynamodb error"))
+				ms.On("Release", mock.Anything, "daily#client-001#2025-03-31").Return(errors.New("dynamodb error"))
+			},
+			errorMsg:    "failed to count event against the monthly quota: dynamodb error",
+			description: "Should give back the counts of events that fail, as they are retried",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := &MockStore{}
+			tt.mockStore(mockStore)
+
+			var slept []time.Duration
+			limiter := New(mockStore, 500*time.Millisecond, testLogger())
+			limiter.now = func() time.Time { return now }
+			limiter.sleep = func(ctx context.Context, d time.Duration) error {
+				slept = append(slept, d)
+				return nil
+			}
+			limiter.sample = func() float64 { return tt.sample }
+
+			event := &models.Event{EventID: "evt-001", EventType: models.EventTypeMonitoring, ClientID: "client-001"}
+			err := limiter.Admit(context.Background(), event, tt.config)
+
+			switch {
+			case tt.expectError != nil:
+				assert.ErrorIs(t, err, tt.expectError, tt.description)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+			case tt.errorMsg != "":
+				require.Error(t, err, tt.description)
+				assert.NotErrorIs(t, err, ErrLimitExceeded)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+			default:
+				assert.NoError(t, err, tt.description)
+			}
+			assert.Equal(t, tt.expectSleep, slept)
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
+
+// TestAdmitSharedStore tests that limiters sharing a store enforce one limit together
+func TestAdmitSharedStore(t *testing.T) {
+	store := NewMemoryStore()
+	config := limitedClient(models.ClientLimits{RatePerSecond: 1, Burst: 3, DailyQuota: 5})
+	event := &models.Event{EventID: "evt-001", EventType: models.EventTypeMonitoring, ClientID: "client-001"}
+	now := time.Date(2025, 3, 5, 10, 0, 0, 0, time.UTC)
+
+	store.now = func() time.Time { return now }
+	replicas := []*Limiter{New(store, 0, testLogger()), New(store, 0, testLogger())}
+	for _, replica := range replicas {
+		replica.now = func() time.Time { return now }
+	}
+
+	admitted := 0
+	for i := 0; i < 4; i++ {
+		if replicas[i%2].Admit(context.Background(), event, config) == nil {
+			admitted++
+		}
+	}
+	assert.Equal(t, 3, admitted, "the burst is shared")
+
+	// Refilled tokens are limited by the daily quota, of which 3 events are used
+	now = now.Add(time.Hour)
+	admitted = 0
+	for i := 0; i < 4; i++ {
+		if replicas[i%2].Admit(context.Background(), event, config) == nil {
+			admitted++
+		}
+	}
+	assert.Equal(t, 2, admitted, "the daily quota is shared")
+}
+
+// TestValidateLimits tests that limits which cannot be enforced are rejected
+func TestValidateLimits(t *testing.T) {
+	tests := []struct {
+		name        string
+		limits      models.ClientLimits
+		expectError bool
+		errorMsg    string
+		description string
+	}{
+		{
+			name:        "Valid Limits",
+			limits:      models.ClientLimits{RatePerSecond: 0.5, Burst: 5, DailyQuota: 100, MonthlyQuota: 2000, OnExcess: models.ExcessSample, SampleRate: 0.1},
+			expectError: false,
+			description: "Should accept well formed limits",
+		},
+		{
+			name:        "Empty Limits",
+			limits:      models.ClientLimits{},
+			expectError: false,
+			description: "Should accept limits that are all off",
+		},
+		{
+			name:        "Invalid Limits",
+			limits:      models.ClientLimits{RatePerSecond: -1, Burst: -1, DailyQuota: -5, OnExcess: "queue", SampleRate: 2},
+			expectError: true,
+			errorMsg:    "ratePerSecond must be a finite number, not negative\nburst must not be negative\nquotas must not be negative\nunknown onExcess action \"queue\": expected delay, reject or sample\nsampleRate must be between 0 and 1\nsampleRate only applies to the sample action",
+			description: "Should report every invalid limit",
+		},
+		{
+			name:        "Burst Without Rate",
+			limits:      models.ClientLimits{Burst: 5},
+			expectError: true,
+			errorMsg:    "burst needs a ratePerSecond",
+			description: "Should reject a burst without a rate",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateLimits(tt.limits)
+
+			if tt.expectError {
+				assert.Error(t, err, tt.description)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+			} else {
+				assert.NoError(t, err, tt.description)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Store keeps the token buckets and quota counters of clients. Limits only hold across replicas when
+// every replica uses the same store.
+type Store interface {
+	// TakeToken takes a token from the bucket at key, which refills at rate tokens per second up to
+	// burst tokens and starts full. When the bucket is empty it returns false and how long until the
+	// next token is available.
+	TakeToken(ctx context.Context, key string, rate float64, burst int, now time.Time) (bool, time.Duration, error)
+
+	// Increment counts an event against the counter at key unless the counter has reached limit. The
+	// counter is removed some time after expiresAt.
+	Increment(ctx context.Context, key string, limit int64, expiresAt time.Time) (bool, error)
+
+	// Release gives back an event counted at key, for events that another limit rejects
+	Release(ctx context.Context, key string) error
+}
+
+// bucket is the state of a token bucket
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// take refills the bucket for the time passed since it was last updated and takes a token if one
+// is available, returning the new state and the wait for the next token when none is
+func (b bucket) take(rate float64, burst int, now time.Time) (bucket, bool, time.Duration) {
+	tokens := float64(burst)
+	if !b.updated.IsZero() {
+		elapsed := now.Sub(b.updated).Seconds()
+		if elapsed < 0 {
+			// Another replica's clock is ahead; do not refill for negative time
+			elapsed = 0
+		}
+		tokens = math.Min(float64(burst), b.tokens+elapsed*rate)
+	}
+
+	if tokens < 1 {
+		wait := time.Duration((1 - tokens) / rate * float64(time.Second))
+		return b, false, wait
+	}
+	return bucket{tokens: tokens - 1, updated: now}, true, 0
+}
+
+// MemoryStore keeps buckets and counters in memory, so limits only hold per process
+type MemoryStore struct {
+	mu       sync.Mutex
+	buckets  map[string]bucket
+	counters map[string]counter
+	now      func() time.Time
+}
+
+// counter is an in-memory quota counter
+type counter struct {
+	count     int64
+	expiresAt time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:  make(map[string]bucket),
+		counters: make(map[string]counter),
+		now:      time.Now,
+	}
+}
+
+// TakeToken takes a token from the bucket at key
+func (s *MemoryStore) TakeToken(ctx context.Context, key string, rate float64, burst int, now time.Time) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next, ok, wait := s.buckets[key].take(rate, burst, now)
+	s.buckets[key] = next
+	return ok, wait, nil
+}
+
+// Increment counts an event against the counter at key unless it has reached limit
+func (s *MemoryStore) Increment(ctx context.Context, key string, limit int64, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Drop expired counters so that the map does not grow with every day and month
+	now := s.now()
+	for name, c := range s.counters {
+		if now.After(c.expiresAt) {
+			delete(s.counters, name)
+		}
+	}
+
+	c := s.counters[key]
+	if c.count >= limit {
+		return false, nil
+	}
+	s.counters[key] = counter{count: c.count + 1, expiresAt: expiresAt}
+	return true, nil
+}
+
+// Release gives back an event counted at key
+func (s *MemoryStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.counters[key]; ok && c.count > 0 {
+		c.count--
+		s.counters[key] = c
+	}
+	return nil
+}
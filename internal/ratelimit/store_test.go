@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBucketTake tests refilling and emptying a token bucket
+func TestBucketTake(t *testing.T) {
+	start := time.Date(2025, 3, 5, 10, 0, 0, 0, time.UTC)
+
+	// A new bucket starts full
+	b, ok, _ := bucket{}.take(2, 3, start)
+	require.True(t, ok)
+	assert.Equal(t, 2.0, b.tokens)
+
+	b, _, _ = b.take(2, 3, start)
+	b, _, _ = b.take(2, 3, start)
+	_, ok, wait := b.take(2, 3, start)
+	assert.False(t, ok, "the burst is used up")
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	b, ok, _ = b.take(2, 3, start.Add(500*time.Millisecond))
+	assert.True(t, ok, "a token is refilled after 1/rate seconds")
+	assert.Equal(t, 0.0, b.tokens)
+
+	b, _, _ = b.take(2, 3, start.Add(time.Hour))
+	assert.Equal(t, 2.0, b.tokens, "refills stop at the burst")
+
+	_, ok, _ = bucket{tokens: 0, updated: start}.take(2, 3, start.Add(-time.Second))
+	assert.False(t, ok, "time going backwards does not refill")
+}
+
+// TestMemoryStoreIncrement tests that counters stop at their limit and expire
+func TestMemoryStoreIncrement(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 5, 10, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	expiresAt := now.Add(time.Hour)
+
+	for i := 0; i < 2; i++ {
+		counted, err := store.Increment(ctx, "daily#client-001#2025-03-05", 2, expiresAt)
+		require.NoError(t, err)
+		assert.True(t, counted)
+	}
+	counted, err := store.Increment(ctx, "daily#client-001#2025-03-05", 2, expiresAt)
+	require.NoError(t, err)
+	assert.False(t, counted, "the limit is reached")
+
+	counted, err = store.Increment(ctx, "daily#client-002#2025-03-05", 2, expiresAt)
+	require.NoError(t, err)
+	assert.True(t, counted, "counters are independent")
+
+	now = expiresAt.Add(time.Second)
+	counted, err = store.Increment(ctx, "daily#client-001#2025-03-05", 2, expiresAt)
+	require.NoError(t, err)
+	assert.True(t, counted, "expired counters start over")
+}
+
+// TestMemoryStoreRelease tests giving back counted events
+func TestMemoryStoreRelease(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	expiresAt := store.now().Add(time.Hour)
+
+	counted, err := store.Increment(ctx, "daily#client-001#2025-03-05", 1, expiresAt)
+	require.NoError(t, err)
+	require.True(t, counted)
+
+	require.NoError(t, store.Release(ctx, "daily#client-001#2025-03-05"))
+	counted, err = store.Increment(ctx, "daily#client-001#2025-03-05", 1, expiresAt)
+	require.NoError(t, err)
+	assert.True(t, counted, "a released count is available again")
+
+	require.NoError(t, store.Release(ctx, "daily#client-001#2025-03-05"))
+	require.NoError(t, store.Release(ctx, "daily#client-001#2025-03-05"))
+	assert.Equal(t, int64(0), store.counters["daily#client-001#2025-03-05"].count, "counters do not go below zero")
+	require.NoError(t, store.Release(ctx, "daily#client-002#2025-03-05"))
+	assert.NotContains(t, store.counters, "daily#client-002#2025-03-05", "missing counters are not created")
+}
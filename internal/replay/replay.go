@@ -0,0 +1,422 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/d-sense/event-processor/internal/metrics"
+	"github.com/d-sense/event-processor/internal/persistence"
+	"github.com/d-sense/event-processor/internal/processor"
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// ErrInvalidOptions is returned for replays with an invalid filter or rate
+var ErrInvalidOptions = errors.New("invalid replay options")
+
+// pageSize is the number of stored events read at a time
+const pageSize = 100
+
+// MaxListedChanges bounds the changes listed in a Result; the counts cover every event
+const MaxListedChanges = 1000
+
+// Results of reprocessing an event, used for the result label of metrics.EventsReplayed
+const (
+	resultChanged   = "changed"
+	resultUnchanged = "unchanged"
+	resultFailed    = "failed"
+)
+
+// Reprocessor runs a stored event through processing again, see processor.EventProcessor.Reprocess
+type Reprocessor interface {
+	Reprocess(ctx context.Context, stored *models.ProcessedEvent) (*models.ProcessedEvent, error)
+}
+
+// Store selects and replaces stored events. Every repository of the persistence package is one;
+// caching wrappers are not, as they do not replace events.
+type Store interface {
+	ListEventsByClient(ctx context.Context, clientID string, opts persistence.ListOptions) (*persistence.EventPage, error)
+	ListEventsByStatus(ctx context.Context, status models.EventStatus, opts persistence.ListOptions) (*persistence.EventPage, error)
+	persistence.EventReplacer
+}
+
+// Filter selects the stored events to reprocess; empty fields match every event
+type Filter struct {
+	ClientID  string             `json:"clientId,omitempty"`
+	Status    models.EventStatus `json:"status,omitempty"`
+	EventType models.EventType   `json:"eventType,omitempty"`
+
+	// From and To restrict the events to those whose timestamp falls within [From, To]
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+
+	// Limit is the maximum number of events to reprocess; 0 reprocesses every match
+	Limit int `json:"limit,omitempty"`
+}
+
+// Validate checks that the filter can be used to select events
+func (f Filter) Validate() error {
+	if f.Status != "" && !models.IsValidEventStatus(string(f.Status)) {
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidOptions, f.Status)
+	}
+	if f.EventType != "" && !models.IsValidEventType(string(f.EventType)) {
+		return fmt.Errorf("%w: unknown event type %q", ErrInvalidOptions, f.EventType)
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && f.To.Before(f.From) {
+		return fmt.Errorf("%w: to is before from", ErrInvalidOptions)
+	}
+	if f.Limit < 0 {
+		return fmt.Errorf("%w: limit must not be negative", ErrInvalidOptions)
+	}
+	return nil
+}
+
+// Options controls a replay
+type Options struct {
+	Filter Filter `json:"filter"`
+
+	// DryRun reports what reprocessing would change without storing anything
+	DryRun bool `json:"dryRun"`
+
+	// RatePerSecond caps how many events are reprocessed per second; 0 does not wait between events
+	RatePerSecond float64 `json:"ratePerSecond"`
+}
+
+// FieldChange describes a changed field of an event; Before or After is absent for payload fields
+// that were added or removed
+type FieldChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// Change describes how reprocessing changed, or would change, an event
+type Change struct {
+	EventID   string           `json:"eventId"`
+	ClientID  string           `json:"clientId"`
+	EventType models.EventType `json:"eventType"`
+	Fields    []FieldChange    `json:"fields"`
+}
+
+// Failure describes an event that could not be reprocessed
+type Failure struct {
+	EventID string `json:"eventId"`
+	Error   string `json:"error"`
+}
+
+// Result summarises a replay
+type Result struct {
+	RunID  string `json:"runId"`
+	DryRun bool   `json:"dryRun"`
+
+	Matched   int `json:"matched"`
+	Changed   int `json:"changed"`
+	Unchanged int `json:"unchanged"`
+
+	// Changes lists the first MaxListedChanges changed events
+	Changes  []Change  `json:"changes"`
+	Failures []Failure `json:"failures"`
+}
+
+// Replayer reprocesses stored events, e.g. the failed events of a client after a fix
+type Replayer struct {
+	store     Store
+	processor Reprocessor
+	logger    *logrus.Logger
+}
+
+// NewReplayer creates a replayer reading and replacing events in store
+func NewReplayer(store Store, processor Reprocessor, logger *logrus.Logger) *Replayer {
+	return &Replayer{
+		store:     store,
+		processor: processor,
+		logger:    logger,
+	}
+}
+
+// Run reprocesses the selected events. Events are selected with the client index when a client
+// is given and the status index otherwise, every status being listed in turn when neither is.
+//
+// Events move along the status transitions only: processed events that pass again become
+// reprocessed, and failed events are retried and become processed, or failed with the error that
+// would now reject them. Events whose outcome is not an allowed transition, such as processed events
+// that would now fail, are left as they are and reported as failures. Every reprocessed event
+// records the run; an event modified while it is reprocessed is left as it is and reported as a
+// failure.
+func (r *Replayer) Run(ctx context.Context, options Options) (*Result, error) {
+	if err := options.Filter.Validate(); err != nil {
+		return nil, err
+	}
+	if options.RatePerSecond < 0 {
+		return nil, fmt.Errorf("%w: ratePerSecond must not be negative", ErrInvalidOptions)
+	}
+
+	result := &Result{
+		RunID:    uuid.NewString(),
+		DryRun:   options.DryRun,
+		Changes:  []Change{},
+		Failures: []Failure{},
+	}
+	pacer := newPacer(options.RatePerSecond)
+
+	err := r.scan(ctx, options.Filter, result.RunID, func(stored *models.ProcessedEvent) error {
+		if err := pacer.wait(ctx); err != nil {
+			return err
+		}
+		result.Matched++
+
+		change, err := r.replayEvent(ctx, stored, result.RunID, options.DryRun)
+		switch {
+		case err != nil:
+			result.Failures = append(result.Failures, Failure{EventID: stored.EventID, Error: err.Error()})
+			r.count(options, stored, resultFailed)
+		case len(change.Fields) == 0:
+			result.Unchanged++
+			r.count(options, stored, resultUnchanged)
+		default:
+			result.Changed++
+			if len(result.Changes) < MaxListedChanges {
+				result.Changes = append(result.Changes, change)
+			}
+			r.count(options, stored, resultChanged)
+		}
+		return nil
+	})
+
+	r.logger.WithFields(logrus.Fields{
+		"run_id":    result.RunID,
+		"dry_run":   result.DryRun,
+		"matched":   result.Matched,
+		"changed":   result.Changed,
+		"unchanged": result.Unchanged,
+		"failed":    len(result.Failures),
+	}).Info("Replay finished")
+
+	// The result covers the events reprocessed before the run was interrupted
+	return result, err
+}
+
+// replayEvent reprocesses a single event and, unless dryRun is set, stores the outcome
+func (r *Replayer) replayEvent(ctx context.Context, stored *models.ProcessedEvent, runID string, dryRun bool) (Change, error) {
+	replayed := *stored
+	outcome, err := r.processor.Reprocess(ctx, stored)
+	if err != nil {
+		replayed.ErrorMsg = err.Error()
+	} else {
+		replayed = *outcome
+	}
+	status, transitionErr := replayStatus(stored.Status, err == nil)
+	if transitionErr != nil {
+		if err != nil {
+			return Change{}, fmt.Errorf("%w: %v", transitionErr, err)
+		}
+		return Change{}, transitionErr
+	}
+	replayed.Status = status
+
+	change := Change{
+		EventID:   stored.EventID,
+		ClientID:  stored.ClientID,
+		EventType: stored.EventType,
+		Fields:    diff(stored, &replayed),
+	}
+	if dryRun {
+		return change, nil
+	}
+
+	replayed.Revision = stored.Revision + 1
+	replayed.RecordReplay(models.ReplayRecord{
+		RunID:          runID,
+		ReplayedAt:     time.Now().UTC(),
+		PreviousStatus: stored.Status,
+		Status:         replayed.Status,
+		Changed:        len(change.Fields) > 0,
+	})
+	return change, r.store.ReplaceEvent(ctx, &replayed, stored.Revision)
+}
+
+// replayStatus returns the status an event moves to when it is reprocessed, successfully or not.
+// Only the transitions of the status table are made: processed events become reprocessed, failed
+// events are retried and become processed or failed, and an event whose outcome the table does not
+// allow is left as it is.
+func replayStatus(from models.EventStatus, succeeded bool) (models.EventStatus, error) {
+	to := models.EventStatusFailed
+	if succeeded {
+		to = models.EventStatusReprocessed
+		if !models.CanTransition(from, to) {
+			to = models.EventStatusProcessed
+		}
+	}
+
+	switch {
+	case models.CanTransition(from, to):
+		return to, nil
+	case models.CanTransition(from, models.EventStatusRetrying) && models.CanTransition(models.EventStatusRetrying, to):
+		return to, nil
+	default:
+		return "", fmt.Errorf("%w: %s -> %s", persistence.ErrInvalidTransition, from, to)
+	}
+}
+
+// scan calls fn with every selected event not yet reprocessed by the run, stopping at the limit
+func (r *Replayer) scan(ctx context.Context, filter Filter, runID string, fn func(*models.ProcessedEvent) error) error {
+	type listing func(opts persistence.ListOptions) (*persistence.EventPage, error)
+
+	var listings []listing
+	switch {
+	case filter.ClientID != "":
+		listings = append(listings, func(opts persistence.ListOptions) (*persistence.EventPage, error) {
+			return r.store.ListEventsByClient(ctx, filter.ClientID, opts)
+		})
+	case filter.Status != "":
+		listings = append(listings, func(opts persistence.ListOptions) (*persistence.EventPage, error) {
+			return r.store.ListEventsByStatus(ctx, filter.Status, opts)
+		})
+	default:
+		for _, status := range eventStatuses {
+			status := status
+			listings = append(listings, func(opts persistence.ListOptions) (*persistence.EventPage, error) {
+				return r.store.ListEventsByStatus(ctx, status, opts)
+			})
+		}
+	}
+
+	seen := 0
+	for _, list := range listings {
+		opts := persistence.ListOptions{Limit: pageSize, From: filter.From, To: filter.To}
+		for {
+			page, err := list(opts)
+			if err != nil {
+				return fmt.Errorf("failed to list events: %w", err)
+			}
+
+			for _, event := range page.Events {
+				// Events reprocessed earlier in the run are listed again under their new status
+				if !filter.matches(event) || event.ReplayedBy(runID) {
+					continue
+				}
+				if err := fn(event); err != nil {
+					return err
+				}
+				if seen++; filter.Limit > 0 && seen >= filter.Limit {
+					return nil
+				}
+			}
+
+			if page.NextCursor == "" {
+				break
+			}
+			opts.Cursor = page.NextCursor
+		}
+	}
+	return nil
+}
+
+// eventStatuses are listed in turn when a replay selects neither a client nor a status
+var eventStatuses = []models.EventStatus{
+	models.EventStatusPending,
+	models.EventStatusProcessed,
+	models.EventStatusFailed,
+	models.EventStatusRetrying,
+	models.EventStatusReprocessed,
+}
+
+// matches reports whether a listed event passes the parts of the filter its index does not apply
+func (f Filter) matches(event *models.ProcessedEvent) bool {
+	return (f.ClientID == "" || event.ClientID == f.ClientID) &&
+		(f.Status == "" || event.Status == f.Status) &&
+		(f.EventType == "" || event.EventType == f.EventType)
+}
+
+// count records a reprocessed event; dry runs change nothing and are not counted
+func (r *Replayer) count(options Options, event *models.ProcessedEvent, result string) {
+	if options.DryRun {
+		return
+	}
+	metrics.EventsReplayed.WithLabelValues(string(event.EventType), event.ClientID, result).Inc()
+}
+
+// diff lists the fields reprocessing changes. A successful outcome is not a change of status,
+// whether the event was processed or already reprocessed, and payload fields set to the time of
+// processing are ignored, as they differ on every run.
+func diff(before, after *models.ProcessedEvent) []FieldChange {
+	fields := []FieldChange{}
+	if outcome(before.Status) != outcome(after.Status) {
+		fields = append(fields, FieldChange{Field: "status", Before: before.Status, After: after.Status})
+	}
+	if before.ErrorMsg != after.ErrorMsg {
+		fields = append(fields, FieldChange{Field: "errorMsg", Before: before.ErrorMsg, After: after.ErrorMsg})
+	}
+
+	keys := make(map[string]bool, len(before.Payload)+len(after.Payload))
+	for key := range before.Payload {
+		keys[key] = true
+	}
+	for key := range after.Payload {
+		keys[key] = true
+	}
+	for _, field := range processor.ProcessingTimeFields {
+		delete(keys, field)
+	}
+
+	names := make([]string, 0, len(keys))
+	for key := range keys {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+
+	for _, key := range names {
+		previous, hadField := before.Payload[key]
+		current, hasField := after.Payload[key]
+		if hadField != hasField || !reflect.DeepEqual(previous, current) {
+			fields = append(fields, FieldChange{Field: "payload." + key, Before: previous, After: current})
+		}
+	}
+	return fields
+}
+
+// outcome maps the statuses of successfully processed events to one
+func outcome(status models.EventStatus) models.EventStatus {
+	if status == models.EventStatusReprocessed {
+		return models.EventStatusProcessed
+	}
+	return status
+}
+
+// pacer spaces out reprocessing to stay under a rate
+type pacer struct {
+	interval time.Duration
+	next     time.Time
+}
+
+func newPacer(ratePerSecond float64) *pacer {
+	if ratePerSecond <= 0 {
+		return &pacer{}
+	}
+	return &pacer{interval: time.Duration(float64(time.Second) / ratePerSecond)}
+}
+
+// wait blocks until the next event may be reprocessed
+func (p *pacer) wait(ctx context.Context) error {
+	if p.interval == 0 {
+		return nil
+	}
+
+	if delay := time.Until(p.next); delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	p.next = time.Now().Add(p.interval)
+	return nil
+}
@@ -0,0 +1,298 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d-sense/event-processor/internal/persistence"
+	"github.com/d-sense/event-processor/internal/processor"
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// fixedReprocessor reprocesses events as a fixed handler would: it rejects events without an
+// amount and flags high value transactions by a lower threshold than the stored events used
+type fixedReprocessor struct{}
+
+func (fixedReprocessor) Reprocess(ctx context.Context, stored *models.ProcessedEvent) (*models.ProcessedEvent, error) {
+	amount, ok := amountOf(stored.Payload["amount"])
+	if !ok {
+		return nil, &processor.StageError{Stage: processor.StageTriage, Err: fmt.Errorf("triage failed: %w: amount \"15k\" is not a number", processor.ErrInvalidPayload)}
+	}
+
+	outcome := *stored
+	outcome.Status = models.EventStatusProcessed
+	outcome.ErrorMsg = ""
+	outcome.Payload = map[string]interface{}{"amount": stored.Payload["amount"], "processedAt": time.Now().UTC().Format(time.RFC3339)}
+	if amount > 1000 {
+		outcome.Payload["highValue"] = true
+	}
+	return &outcome, nil
+}
+
+// amountOf returns an amount as stored, a float64 where that is exact and a json.Number otherwise
+func amountOf(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		amount, err := v.Float64()
+		return amount, err == nil
+	default:
+		return 0, false
+	}
+}
+
+var base = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func storedEvent(id, clientID string, status models.EventStatus, minute int, payload map[string]interface{}) *models.ProcessedEvent {
+	return &models.ProcessedEvent{
+		Event: models.Event{
+			EventID:   id,
+			EventType: models.EventTypeTransaction,
+			ClientID:  clientID,
+			Timestamp: base.Add(time.Duration(minute) * time.Minute),
+			Payload:   payload,
+			Version:   "1.0",
+		},
+		ProcessedAt: base,
+		Status:      status,
+		Revision:    1,
+	}
+}
+
+// newTestRepository stores a client's failed, processed and unfixable events next to another client's
+func newTestRepository(t *testing.T) *persistence.MemoryRepository {
+	repo := persistence.NewMemoryRepository()
+	for _, event := range []*models.ProcessedEvent{
+		storedEvent("evt-1", "client-001", models.EventStatusFailed, 1, map[string]interface{}{"amount": json.Number("5000.000000000000001"), "processedAt": "2024-03-01T12:01:00Z"}),
+		storedEvent("evt-2", "client-001", models.EventStatusProcessed, 2, map[string]interface{}{"amount": 50.0, "processedAt": "2024-03-01T12:02:00Z"}),
+		storedEvent("evt-3", "client-001", models.EventStatusProcessed, 3, map[string]interface{}{"currency": "USD"}),
+		storedEvent("evt-4", "client-002", models.EventStatusFailed, 4, map[string]interface{}{"amount": 20.0}),
+	} {
+		require.NoError(t, repo.SaveEvent(context.Background(), event))
+	}
+	return repo
+}
+
+// TestRun tests reprocessing stored events
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name          string
+		options       Options
+		expectError   error
+		assertResult  func(*testing.T, *Result)
+		assertStorage func(*testing.T, *persistence.MemoryRepository)
+		description   string
+	}{
+		{
+			name:    "Dry Run",
+			options: Options{Filter: Filter{ClientID: "client-001"}, DryRun: true},
+			assertResult: func(t *testing.T, result *Result) {
+				assert.Equal(t, 3, result.Matched)
+				assert.Equal(t, 1, result.Changed)
+				assert.Equal(t, 1, result.Unchanged)
+				require.Len(t, result.Changes, 1)
+				assert.Equal(t, Change{
+					EventID:   "evt-1",
+					ClientID:  "client-001",
+					EventType: models.EventTypeTransaction,
+					Fields: []FieldChange{
+						{Field: "status", Before: models.EventStatusFailed, After: models.EventStatusProcessed},
+						{Field: "payload.highValue", After: true},
+					},
+				}, result.Changes[0])
+				assert.Equal(t, []Failure{{
+					EventID: "evt-3",
+					Error:   "invalid status transition: processed -> failed: triage failed: invalid payload: amount \"15k\" is not a number",
+				}}, result.Failures, "Processed events that would now fail cannot become failed")
+			},
+			assertStorage: func(t *testing.T, repo *persistence.MemoryRepository) {
+				event, err := repo.GetEvent(context.Background(), "evt-1")
+				require.NoError(t, err)
+				assert.Equal(t, models.EventStatusFailed, event.Status)
+				assert.Equal(t, int64(1), event.Revision)
+				assert.Empty(t, event.Replays)
+			},
+			description: "Should report what would change without storing anything",
+		},
+		{
+			name:    "Failed Events Of A Client",
+			options: Options{Filter: Filter{ClientID: "client-001", Status: models.EventStatusFailed}},
+			assertResult: func(t *testing.T, result *Result) {
+				assert.Equal(t, 1, result.Matched)
+				assert.Equal(t, 1, result.Changed)
+				assert.Empty(t, result.Failures)
+			},
+			assertStorage: func(t *testing.T, repo *persistence.MemoryRepository) {
+				event, err := repo.GetEvent(context.Background(), "evt-1")
+				require.NoError(t, err)
+				assert.Equal(t, models.EventStatusProcessed, event.Status, "Failed events should be retried")
+				assert.Equal(t, true, event.Payload["highValue"])
+				assert.Equal(t, int64(2), event.Revision)
+				require.Len(t, event.Replays, 1)
+				assert.Equal(t, models.EventStatusFailed, event.Replays[0].PreviousStatus)
+				assert.Equal(t, models.EventStatusProcessed, event.Replays[0].Status)
+				assert.True(t, event.Replays[0].Changed)
+
+				other, err := repo.GetEvent(context.Background(), "evt-4")
+				require.NoError(t, err)
+				assert.Equal(t, models.EventStatusFailed, other.Status, "Other clients' events should be left alone")
+			},
+			description: "Should reprocess the selected events and record the run on them",
+		},
+		{
+			name:    "Every Status In A Time Range",
+			options: Options{Filter: Filter{From: base.Add(2 * time.Minute), To: base.Add(4 * time.Minute)}},
+			assertResult: func(t *testing.T, result *Result) {
+				assert.Equal(t, 3, result.Matched, "Events should be reprocessed once even though their status changes")
+				assert.Equal(t, 1, result.Changed)
+				assert.Equal(t, 1, result.Unchanged)
+				require.Len(t, result.Failures, 1)
+				assert.Equal(t, "evt-3", result.Failures[0].EventID)
+			},
+			assertStorage: func(t *testing.T, repo *persistence.MemoryRepository) {
+				event, err := repo.GetEvent(context.Background(), "evt-2")
+				require.NoError(t, err)
+				assert.Equal(t, models.EventStatusReprocessed, event.Status)
+				require.Len(t, event.Replays, 1)
+				assert.False(t, event.Replays[0].Changed)
+
+				event, err = repo.GetEvent(context.Background(), "evt-3")
+				require.NoError(t, err)
+				assert.Equal(t, models.EventStatusProcessed, event.Status)
+				assert.Empty(t, event.Replays, "Events whose outcome is not an allowed transition should be left alone")
+
+				event, err = repo.GetEvent(context.Background(), "evt-1")
+				require.NoError(t, err)
+				assert.Empty(t, event.Replays, "Events outside the time range should be left alone")
+			},
+			description: "Should list every status when neither a client nor a status is given",
+		},
+		{
+			name:    "Limit",
+			options: Options{Filter: Filter{Status: models.EventStatusFailed, Limit: 1}, RatePerSecond: 100},
+			assertResult: func(t *testing.T, result *Result) {
+				assert.Equal(t, 1, result.Matched)
+			},
+			description: "Should stop at the limit",
+		},
+		{
+			name:        "Unknown Status",
+			options:     Options{Filter: Filter{Status: "done"}},
+			expectError: ErrInvalidOptions,
+			description: "Should reject unknown statuses",
+		},
+		{
+			name:        "Negative Rate",
+			options:     Options{RatePerSecond: -1},
+			expectError: ErrInvalidOptions,
+			description: "Should reject negative rates",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTestRepository(t)
+			replayer := NewReplayer(repo, fixedReprocessor{}, logrus.New())
+
+			result, err := replayer.Run(context.Background(), tt.options)
+
+			if tt.expectError != nil {
+				assert.ErrorIs(t, err, tt.expectError, tt.description)
+				return
+			}
+			require.NoError(t, err, tt.description)
+			assert.NotEmpty(t, result.RunID)
+			assert.Equal(t, tt.options.DryRun, result.DryRun)
+			if tt.assertResult != nil {
+				tt.assertResult(t, result)
+			}
+			if tt.assertStorage != nil {
+				tt.assertStorage(t, repo)
+			}
+		})
+	}
+}
+
+// conflictingStore modifies every event just before it is replaced
+type conflictingStore struct {
+	*persistence.MemoryRepository
+}
+
+func (s conflictingStore) ReplaceEvent(ctx context.Context, event *models.ProcessedEvent, expectedRevision int64) error {
+	if _, err := s.UpdateEventStatus(ctx, event.EventID, persistence.StatusUpdate{Status: models.EventStatusRetrying, ExpectedRevision: expectedRevision}); err != nil {
+		return err
+	}
+	return s.MemoryRepository.ReplaceEvent(ctx, event, expectedRevision)
+}
+
+// TestRunConcurrentModification tests that events modified while they are reprocessed are left alone
+func TestRunConcurrentModification(t *testing.T) {
+	repo := newTestRepository(t)
+	replayer := NewReplayer(conflictingStore{repo}, fixedReprocessor{}, logrus.New())
+
+	result, err := replayer.Run(context.Background(), Options{Filter: Filter{ClientID: "client-002"}})
+	require.NoError(t, err)
+	require.Len(t, result.Failures, 1)
+	assert.Equal(t, "evt-4", result.Failures[0].EventID)
+	assert.Contains(t, result.Failures[0].Error, "event revision conflict")
+
+	event, err := repo.GetEvent(context.Background(), "evt-4")
+	require.NoError(t, err)
+	assert.Equal(t, models.EventStatusRetrying, event.Status)
+	assert.Empty(t, event.Replays)
+}
+
+// TestReplayStatus tests that replays only make allowed status transitions
+func TestReplayStatus(t *testing.T) {
+	tests := []struct {
+		name           string
+		from           models.EventStatus
+		succeeded      bool
+		expectedStatus models.EventStatus
+		expectError    bool
+		description    string
+	}{
+		{name: "Processed Passes", from: models.EventStatusProcessed, succeeded: true, expectedStatus: models.EventStatusReprocessed, description: "Should mark processed events reprocessed"},
+		{name: "Processed Fails", from: models.EventStatusProcessed, succeeded: false, expectError: true, description: "Should not move processed events to failed"},
+		{name: "Reprocessed Passes", from: models.EventStatusReprocessed, succeeded: true, expectedStatus: models.EventStatusReprocessed, description: "Should keep reprocessed events reprocessed"},
+		{name: "Reprocessed Fails", from: models.EventStatusReprocessed, succeeded: false, expectedStatus: models.EventStatusFailed, description: "Should fail reprocessed events"},
+		{name: "Failed Passes", from: models.EventStatusFailed, succeeded: true, expectedStatus: models.EventStatusProcessed, description: "Should retry failed events into processed"},
+		{name: "Failed Fails", from: models.EventStatusFailed, succeeded: false, expectedStatus: models.EventStatusFailed, description: "Should retry failed events into failed"},
+		{name: "Pending Passes", from: models.EventStatusPending, succeeded: true, expectedStatus: models.EventStatusProcessed, description: "Should process pending events"},
+		{name: "Retrying Fails", from: models.EventStatusRetrying, succeeded: false, expectedStatus: models.EventStatusFailed, description: "Should fail retrying events"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err := replayStatus(tt.from, tt.succeeded)
+
+			if tt.expectError {
+				assert.ErrorIs(t, err, persistence.ErrInvalidTransition, tt.description)
+			} else {
+				require.NoError(t, err, tt.description)
+				assert.Equal(t, tt.expectedStatus, status, tt.description)
+			}
+		})
+	}
+}
+
+// TestRecordReplay tests that events keep a bounded history of runs
+func TestRecordReplay(t *testing.T) {
+	event := storedEvent("evt-1", "client-001", models.EventStatusProcessed, 0, nil)
+	for i := 0; i < models.MaxReplayRecords+2; i++ {
+		event.RecordReplay(models.ReplayRecord{RunID: fmt.Sprintf("run-%d", i)})
+	}
+
+	require.Len(t, event.Replays, models.MaxReplayRecords)
+	assert.Equal(t, "run-2", event.Replays[0].RunID)
+	assert.True(t, event.ReplayedBy("run-11"))
+	assert.False(t, event.ReplayedBy("run-1"))
+}
@@ -0,0 +1,86 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/d-sense/event-processor/internal/persistence"
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// maxHoldAttempts bounds how often an event that keeps being modified is read again to be held
+const maxHoldAttempts = 3
+
+// EventStore is the part of a repository a legal hold changes; every persistence.Repository is one
+type EventStore interface {
+	GetEvent(ctx context.Context, eventID string) (*models.ProcessedEvent, error)
+	ListEventsByClient(ctx context.Context, clientID string, opts persistence.ListOptions) (*persistence.EventPage, error)
+	persistence.EventReplacer
+}
+
+// LegalHold reports whether a client is under legal hold. client may be nil when the client has no
+// configuration.
+func LegalHold(client *models.ClientConfig) bool {
+	return client != nil && client.Config[ConfigKeyLegalHold] == "true"
+}
+
+// Hold removes the expiry of every stored event of a client, so that a legal hold also keeps the
+// events written before it was set. It returns the number of events it changed. Holding events
+// again is harmless, so a hold that failed part way is completed by running it again.
+func Hold(ctx context.Context, store EventStore, clientID string) (int, error) {
+	held := 0
+	opts := persistence.ListOptions{Limit: persistence.MaxPageSize}
+	for {
+		page, err := store.ListEventsByClient(ctx, clientID, opts)
+		if err != nil {
+			return held, fmt.Errorf("failed to list events of client %s: %w", clientID, err)
+		}
+		for _, event := range page.Events {
+			changed, err := holdEvent(ctx, store, event)
+			if err != nil {
+				return held, err
+			}
+			if changed {
+				held++
+			}
+		}
+		if page.NextCursor == "" {
+			return held, nil
+		}
+		opts.Cursor = page.NextCursor
+	}
+}
+
+// holdEvent removes the expiry of a stored event, reading it again when it was modified since it
+// was listed. It reports whether the event was changed.
+func holdEvent(ctx context.Context, store EventStore, event *models.ProcessedEvent) (bool, error) {
+	for attempt := 1; ; attempt++ {
+		if event.TTL == 0 {
+			return false, nil
+		}
+
+		held := *event
+		held.TTL = 0
+		held.Revision = event.Revision + 1
+		err := store.ReplaceEvent(ctx, &held, event.Revision)
+		switch {
+		case err == nil:
+			return true, nil
+		case errors.Is(err, persistence.ErrEventNotFound):
+			// Expired since it was listed
+			return false, nil
+		case !errors.Is(err, persistence.ErrRevisionConflict) || attempt == maxHoldAttempts:
+			return false, fmt.Errorf("failed to hold event %s: %w", event.EventID, err)
+		}
+
+		current, err := store.GetEvent(ctx, event.EventID)
+		if errors.Is(err, persistence.ErrEventNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to hold event %s: %w", event.EventID, err)
+		}
+		event = current
+	}
+}
@@ -0,0 +1,122 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d-sense/event-processor/internal/persistence"
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// conflictingStore modifies an event right before the first attempt to replace it, as a
+// concurrent status update would
+type conflictingStore struct {
+	*persistence.MemoryRepository
+	conflicts map[string]int
+	failWith  error
+}
+
+func (s *conflictingStore) ReplaceEvent(ctx context.Context, event *models.ProcessedEvent, expectedRevision int64) error {
+	if s.failWith != nil {
+		return s.failWith
+	}
+	if s.conflicts[event.EventID] > 0 {
+		s.conflicts[event.EventID]--
+		if _, err := s.UpdateEventStatus(ctx, event.EventID, persistence.StatusUpdate{Status: models.EventStatusRetrying, ExpectedRevision: expectedRevision}); err != nil {
+			return err
+		}
+	}
+	return s.MemoryRepository.ReplaceEvent(ctx, event, expectedRevision)
+}
+
+func heldTestEvent(id, clientID string, ttl int64) *models.ProcessedEvent {
+	return &models.ProcessedEvent{
+		Event: models.Event{
+			EventID:   id,
+			EventType: models.EventTypeTransaction,
+			ClientID:  clientID,
+			Timestamp: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			Payload:   map[string]interface{}{"amount": 10.0},
+			Version:   "1.1",
+		},
+		ProcessedAt: time.Date(2025, 1, 1, 0, 0, 1, 0, time.UTC),
+		Status:      models.EventStatusFailed,
+		TTL:         ttl,
+		Revision:    1,
+	}
+}
+
+// TestHold tests removing the expiry of the stored events of a client
+func TestHold(t *testing.T) {
+	tests := []struct {
+		name        string
+		conflicts   map[string]int
+		failWith    error
+		expectHeld  int
+		expectError bool
+		errorMsg    string
+		expectTTL   map[string]int64
+		description string
+	}{
+		{
+			name:        "Hold Events",
+			expectHeld:  2,
+			expectTTL:   map[string]int64{"evt-1": 0, "evt-2": 0, "evt-3": 0, "evt-4": 1900000000},
+			description: "Should remove the expiry of every event of the client, leaving other clients alone",
+		},
+		{
+			name:        "Modified Meanwhile",
+			conflicts:   map[string]int{"evt-1": 1},
+			expectHeld:  2,
+			expectTTL:   map[string]int64{"evt-1": 0, "evt-2": 0, "evt-3": 0, "evt-4": 1900000000},
+			description: "Should read events modified since they were listed again",
+		},
+		{
+			name:        "Modified Repeatedly",
+			conflicts:   map[string]int{"evt-1": maxHoldAttempts},
+			expectError: true,
+			errorMsg:    "failed to hold event evt-1",
+			description: "Should give up on events that keep being modified",
+		},
+		{
+			name:        "Store Failure",
+			failWith:    errors.New("dynamodb error"),
+			expectError: true,
+			errorMsg:    "failed to hold event evt-1: dynamodb error",
+			description: "Should report events that cannot be replaced",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := persistence.NewMemoryRepository()
+			require.NoError(t, repo.SaveEvent(ctx, heldTestEvent("evt-1", "client-001", 1900000000)))
+			require.NoError(t, repo.SaveEvent(ctx, heldTestEvent("evt-2", "client-001", 1900000000)))
+			require.NoError(t, repo.SaveEvent(ctx, heldTestEvent("evt-3", "client-001", 0)))
+			require.NoError(t, repo.SaveEvent(ctx, heldTestEvent("evt-4", "client-002", 1900000000)))
+			store := &conflictingStore{MemoryRepository: repo, conflicts: tt.conflicts, failWith: tt.failWith}
+
+			held, err := Hold(ctx, store, "client-001")
+
+			if tt.expectError {
+				assert.Error(t, err, tt.description)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				return
+			}
+			require.NoError(t, err, tt.description)
+			assert.Equal(t, tt.expectHeld, held)
+			for id, ttl := range tt.expectTTL {
+				stored, err := repo.GetEvent(ctx, id)
+				require.NoError(t, err)
+				assert.Equal(t, ttl, stored.TTL, fmt.Sprintf("ttl of %s", id))
+			}
+		})
+	}
+}
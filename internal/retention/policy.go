@@ -0,0 +1,124 @@
+package retention
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/d-sense/event-processor/internal/config"
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// Client config keys read by the policy
+const (
+	// ConfigKeyRetentionDays overrides the retention of all events of a client
+	ConfigKeyRetentionDays = "retention_days"
+
+	// ConfigKeyLegalHold set to "true" stops events of a client from expiring
+	ConfigKeyLegalHold = "legal_hold"
+)
+
+// day is the unit retention periods are configured in
+const day = 24 * time.Hour
+
+// Policy decides how long stored events are kept before DynamoDB expires them.
+//
+// The retention of an event is the client's retention_days if set, otherwise the retention of its
+// event type, otherwise Default. Failed events are kept for at least Failed so they can be
+// investigated and replayed, and events of a client under legal hold never expire.
+type Policy struct {
+	Default    time.Duration
+	Failed     time.Duration
+	EventTypes map[models.EventType]time.Duration
+}
+
+// DefaultPolicy returns the policy used when none is configured
+func DefaultPolicy() *Policy {
+	return &Policy{
+		Default: models.DefaultRetention,
+		Failed:  90 * day,
+	}
+}
+
+// NewPolicy creates a policy from the retention configuration
+func NewPolicy(cfg *config.Config) (*Policy, error) {
+	policy := DefaultPolicy()
+	if cfg.TTLDefaultDays > 0 {
+		policy.Default = time.Duration(cfg.TTLDefaultDays) * day
+	}
+	if cfg.TTLFailedDays > 0 {
+		policy.Failed = time.Duration(cfg.TTLFailedDays) * day
+	}
+
+	eventTypes, err := parseEventTypeDays(cfg.TTLEventTypeDays)
+	if err != nil {
+		return nil, err
+	}
+	policy.EventTypes = eventTypes
+
+	return policy, nil
+}
+
+// Expiry returns the Unix time an event expires at, or zero if it must not expire.
+// client may be nil when the client has no configuration.
+func (p *Policy) Expiry(event *models.ProcessedEvent, client *models.ClientConfig) int64 {
+	if LegalHold(client) {
+		return 0
+	}
+
+	retention := p.Default
+	if typeRetention, ok := p.EventTypes[event.EventType]; ok {
+		retention = typeRetention
+	}
+	if clientRetention, ok := clientRetention(client); ok {
+		retention = clientRetention
+	}
+	if event.Status == models.EventStatusFailed && p.Failed > retention {
+		retention = p.Failed
+	}
+
+	processedAt := event.ProcessedAt
+	if processedAt.IsZero() {
+		processedAt = time.Now()
+	}
+	return processedAt.Add(retention).Unix()
+}
+
+// clientRetention returns the retention configured for a client, ignoring invalid values
+func clientRetention(client *models.ClientConfig) (time.Duration, bool) {
+	if client == nil {
+		return 0, false
+	}
+	days, err := strconv.Atoi(client.Config[ConfigKeyRetentionDays])
+	if err != nil || days <= 0 {
+		return 0, false
+	}
+	return time.Duration(days) * day, true
+}
+
+// parseEventTypeDays parses a comma separated list of event_type=days pairs
+func parseEventTypeDays(value string) (map[models.EventType]time.Duration, error) {
+	eventTypes := make(map[models.EventType]time.Duration)
+	if strings.TrimSpace(value) == "" {
+		return eventTypes, nil
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		eventType, daysValue, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid event type retention %q: expected event_type=days", pair)
+		}
+		eventType = strings.TrimSpace(eventType)
+		if !models.IsValidEventType(eventType) {
+			return nil, fmt.Errorf("invalid event type retention %q: unknown event type %s", pair, eventType)
+		}
+		days, err := strconv.Atoi(strings.TrimSpace(daysValue))
+		if err != nil || days <= 0 {
+			return nil, fmt.Errorf("invalid event type retention %q: days must be a positive integer", pair)
+		}
+		eventTypes[models.EventType(eventType)] = time.Duration(days) * day
+	}
+
+	return eventTypes, nil
+}
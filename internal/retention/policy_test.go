@@ -0,0 +1,134 @@
+package retention
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d-sense/event-processor/internal/config"
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// TestExpiry tests how the policy combines client, event type and status retention
+func TestExpiry(t *testing.T) {
+	processedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	policy := &Policy{
+		Default: 30 * day,
+		Failed:  90 * day,
+		EventTypes: map[models.EventType]time.Duration{
+			models.EventTypeTransaction: 365 * day,
+			models.EventTypeMonitoring:  7 * day,
+		},
+	}
+
+	tests := []struct {
+		name      string
+		eventType models.EventType
+		status    models.EventStatus
+		client    *models.ClientConfig
+		retention time.Duration
+		never     bool
+	}{
+		{
+			name:      "Default Retention",
+			eventType: models.EventTypeUserAction,
+			status:    models.EventStatusProcessed,
+			retention: 30 * day,
+		},
+		{
+			name:      "Event Type Retention",
+			eventType: models.EventTypeTransaction,
+			status:    models.EventStatusProcessed,
+			retention: 365 * day,
+		},
+		{
+			name:      "Client Retention Overrides Event Type",
+			eventType: models.EventTypeTransaction,
+			status:    models.EventStatusProcessed,
+			client:    &models.ClientConfig{Config: map[string]string{ConfigKeyRetentionDays: "10"}},
+			retention: 10 * day,
+		},
+		{
+			name:      "Invalid Client Retention Is Ignored",
+			eventType: models.EventTypeUserAction,
+			status:    models.EventStatusProcessed,
+			client:    &models.ClientConfig{Config: map[string]string{ConfigKeyRetentionDays: "forever"}},
+			retention: 30 * day,
+		},
+		{
+			name:      "Failed Events Are Kept Longer",
+			eventType: models.EventTypeMonitoring,
+			status:    models.EventStatusFailed,
+			retention: 90 * day,
+		},
+		{
+			name:      "Failed Retention Does Not Shorten Longer Retention",
+			eventType: models.EventTypeTransaction,
+			status:    models.EventStatusFailed,
+			retention: 365 * day,
+		},
+		{
+			name:      "Legal Hold Never Expires",
+			eventType: models.EventTypeMonitoring,
+			status:    models.EventStatusProcessed,
+			client: &models.ClientConfig{Config: map[string]string{
+				ConfigKeyLegalHold:     "true",
+				ConfigKeyRetentionDays: "1",
+			}},
+			never: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &models.ProcessedEvent{
+				Event:       models.Event{EventType: tt.eventType},
+				ProcessedAt: processedAt,
+				Status:      tt.status,
+			}
+
+			expiry := policy.Expiry(event, tt.client)
+
+			if tt.never {
+				assert.Zero(t, expiry)
+			} else {
+				assert.Equal(t, processedAt.Add(tt.retention).Unix(), expiry)
+			}
+		})
+	}
+}
+
+// TestNewPolicy tests building a policy from configuration
+func TestNewPolicy(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		policy, err := NewPolicy(&config.Config{})
+		require.NoError(t, err)
+		assert.Equal(t, models.DefaultRetention, policy.Default)
+		assert.Equal(t, 90*day, policy.Failed)
+		assert.Empty(t, policy.EventTypes)
+	})
+
+	t.Run("Configured", func(t *testing.T) {
+		policy, err := NewPolicy(&config.Config{
+			TTLDefaultDays:   14,
+			TTLFailedDays:    180,
+			TTLEventTypeDays: "transaction=365, monitoring = 7",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 14*day, policy.Default)
+		assert.Equal(t, 180*day, policy.Failed)
+		assert.Equal(t, map[models.EventType]time.Duration{
+			models.EventTypeTransaction: 365 * day,
+			models.EventTypeMonitoring:  7 * day,
+		}, policy.EventTypes)
+	})
+
+	for _, value := range []string{"transaction", "unknown=5", "transaction=0", "transaction=abc"} {
+		t.Run("Invalid "+value, func(t *testing.T) {
+			_, err := NewPolicy(&config.Config{TTLEventTypeDays: value})
+			assert.ErrorContains(t, err, "invalid event type retention")
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package stream
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams/types"
+
+	"github.com/d-sense/event-processor/internal/persistence"
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// ChangeType is the kind of modification a change event describes
+type ChangeType string
+
+const (
+	ChangeTypeInsert ChangeType = "insert"
+	ChangeTypeModify ChangeType = "modify"
+	ChangeTypeRemove ChangeType = "remove"
+)
+
+// ttlPrincipal is the identity DynamoDB reports for items deleted by TTL
+const ttlPrincipal = "dynamodb.amazonaws.com"
+
+// ChangeEvent is a single change to the events table, as emitted to sinks
+type ChangeEvent struct {
+	Type           ChangeType `json:"type"`
+	EventID        string     `json:"eventId"`
+	SequenceNumber string     `json:"sequenceNumber"`
+	ChangedAt      time.Time  `json:"changedAt"`
+
+	// ClientID is set instead of EventID on changes to the clients table, which carry no images
+	ClientID string `json:"clientId,omitempty"`
+
+	// Expired is set on removals performed by DynamoDB TTL rather than by a client
+	Expired bool `json:"expired,omitempty"`
+
+	// NewImage is absent on removals and OldImage on inserts
+	NewImage *models.ProcessedEvent `json:"newImage,omitempty"`
+	OldImage *models.ProcessedEvent `json:"oldImage,omitempty"`
+}
+
+// changeFromRecord converts a stream record into a change event
+func changeFromRecord(record types.Record) (ChangeEvent, error) {
+	if record.Dynamodb == nil {
+		return ChangeEvent{}, fmt.Errorf("stream record %s has no data", aws.ToString(record.EventID))
+	}
+
+	change := ChangeEvent{
+		SequenceNumber: aws.ToString(record.Dynamodb.SequenceNumber),
+		ChangedAt:      aws.ToTime(record.Dynamodb.ApproximateCreationDateTime).UTC(),
+	}
+
+	switch record.EventName {
+	case types.OperationTypeInsert:
+		change.Type = ChangeTypeInsert
+	case types.OperationTypeModify:
+		change.Type = ChangeTypeModify
+	case types.OperationTypeRemove:
+		change.Type = ChangeTypeRemove
+		if identity := record.UserIdentity; identity != nil {
+			change.Expired = aws.ToString(identity.Type) == "Service" && aws.ToString(identity.PrincipalId) == ttlPrincipal
+		}
+	default:
+		return ChangeEvent{}, fmt.Errorf("stream record %s has unknown operation %q", change.SequenceNumber, record.EventName)
+	}
+
+	if key, ok := record.Dynamodb.Keys["client_id"].(*types.AttributeValueMemberS); ok {
+		change.ClientID = key.Value
+		return change, nil
+	}
+	if key, ok := record.Dynamodb.Keys["event_id"].(*types.AttributeValueMemberS); ok {
+		change.EventID = key.Value
+	}
+
+	var err error
+	if change.NewImage, err = imageEvent(record.Dynamodb.NewImage); err != nil {
+		return ChangeEvent{}, fmt.Errorf("invalid new image for record %s: %w", change.SequenceNumber, err)
+	}
+	if change.OldImage, err = imageEvent(record.Dynamodb.OldImage); err != nil {
+		return ChangeEvent{}, fmt.Errorf("invalid old image for record %s: %w", change.SequenceNumber, err)
+	}
+
+	return change, nil
+}
+
+// imageEvent converts a stream image into an event, returning nil when there is no image
+func imageEvent(image map[string]types.AttributeValue) (*models.ProcessedEvent, error) {
+	if len(image) == 0 {
+		return nil, nil
+	}
+	return persistence.UnmarshalEventItem(tableItem(image))
+}
+
+// tableItem converts stream attribute values into their DynamoDB table equivalents
+func tableItem(image map[string]types.AttributeValue) map[string]dynamodbtypes.AttributeValue {
+	item := make(map[string]dynamodbtypes.AttributeValue, len(image))
+	for name, value := range image {
+		if converted := tableValue(value); converted != nil {
+			item[name] = converted
+		}
+	}
+	return item
+}
+
+// tableValue converts a single stream attribute value, returning nil for unknown members
+func tableValue(value types.AttributeValue) dynamodbtypes.AttributeValue {
+	switch v := value.(type) {
+	case *types.AttributeValueMemberS:
+		return &dynamodbtypes.AttributeValueMemberS{Value: v.Value}
+	case *types.AttributeValueMemberN:
+		return &dynamodbtypes.AttributeValueMemberN{Value: v.Value}
+	case *types.AttributeValueMemberB:
+		return &dynamodbtypes.AttributeValueMemberB{Value: v.Value}
+	case *types.AttributeValueMemberBOOL:
+		return &dynamodbtypes.AttributeValueMemberBOOL{Value: v.Value}
+	case *types.AttributeValueMemberNULL:
+		return &dynamodbtypes.AttributeValueMemberNULL{Value: v.Value}
+	case *types.AttributeValueMemberSS:
+		return &dynamodbtypes.AttributeValueMemberSS{Value: v.Value}
+	case *types.AttributeValueMemberNS:
+		return &dynamodbtypes.AttributeValueMemberNS{Value: v.Value}
+	case *types.AttributeValueMemberBS:
+		return &dynamodbtypes.AttributeValueMemberBS{Value: v.Value}
+	case *types.AttributeValueMemberM:
+		return &dynamodbtypes.AttributeValueMemberM{Value: tableItem(v.Value)}
+	case *types.AttributeValueMemberL:
+		list := make([]dynamodbtypes.AttributeValue, 0, len(v.Value))
+		for _, element := range v.Value {
+			if converted := tableValue(element); converted != nil {
+				list = append(list, converted)
+			}
+		}
+		return &dynamodbtypes.AttributeValueMemberL{Value: list}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,123 @@
+package stream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// streamImage builds a stream image of a stored event
+func streamImage(eventID string, status models.EventStatus, revision string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"event_id":     &types.AttributeValueMemberS{Value: eventID},
+		"event_type":   &types.AttributeValueMemberS{Value: "transaction"},
+		"client_id":    &types.AttributeValueMemberS{Value: "client-001"},
+		"timestamp":    &types.AttributeValueMemberS{Value: "2025-01-01T10:00:00Z"},
+		"processed_at": &types.AttributeValueMemberS{Value: "2025-01-01T10:00:01Z"},
+		"status":       &types.AttributeValueMemberS{Value: string(status)},
+		"revision":     &types.AttributeValueMemberN{Value: revision},
+		"payload": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"amount": &types.AttributeValueMemberN{Value: "12.5"},
+			"tags": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+				&types.AttributeValueMemberS{Value: "a"},
+				&types.AttributeValueMemberBOOL{Value: true},
+			}},
+		}},
+	}
+}
+
+// streamRecord builds a stream record for a change to an event
+func streamRecord(operation types.OperationType, sequenceNumber string, oldImage, newImage map[string]types.AttributeValue) types.Record {
+	return types.Record{
+		EventName: operation,
+		Dynamodb: &types.StreamRecord{
+			ApproximateCreationDateTime: aws.Time(time.Date(2025, 1, 1, 10, 0, 2, 0, time.UTC)),
+			Keys: map[string]types.AttributeValue{
+				"event_id": &types.AttributeValueMemberS{Value: "evt-1"},
+			},
+			NewImage:       newImage,
+			OldImage:       oldImage,
+			SequenceNumber: aws.String(sequenceNumber),
+		},
+	}
+}
+
+// TestChangeFromRecord tests the conversion of stream records into change events
+func TestChangeFromRecord(t *testing.T) {
+	t.Run("Insert", func(t *testing.T) {
+		change, err := changeFromRecord(streamRecord(types.OperationTypeInsert, "100", nil, streamImage("evt-1", models.EventStatusProcessed, "1")))
+		require.NoError(t, err)
+
+		assert.Equal(t, ChangeTypeInsert, change.Type)
+		assert.Equal(t, "evt-1", change.EventID)
+		assert.Equal(t, "100", change.SequenceNumber)
+		assert.Equal(t, time.Date(2025, 1, 1, 10, 0, 2, 0, time.UTC), change.ChangedAt)
+		assert.Nil(t, change.OldImage)
+		require.NotNil(t, change.NewImage)
+		assert.Equal(t, models.EventTypeTransaction, change.NewImage.EventType)
+		assert.Equal(t, int64(1), change.NewImage.Revision)
+		assert.Equal(t, map[string]interface{}{"amount": 12.5, "tags": []interface{}{"a", true}}, change.NewImage.Payload)
+	})
+
+	t.Run("Modify", func(t *testing.T) {
+		change, err := changeFromRecord(streamRecord(types.OperationTypeModify, "101",
+			streamImage("evt-1", models.EventStatusFailed, "1"),
+			streamImage("evt-1", models.EventStatusRetrying, "2")))
+		require.NoError(t, err)
+
+		assert.Equal(t, ChangeTypeModify, change.Type)
+		assert.Equal(t, models.EventStatusFailed, change.OldImage.Status)
+		assert.Equal(t, models.EventStatusRetrying, change.NewImage.Status)
+	})
+
+	t.Run("Remove By Client", func(t *testing.T) {
+		change, err := changeFromRecord(streamRecord(types.OperationTypeRemove, "102", streamImage("evt-1", models.EventStatusProcessed, "1"), nil))
+		require.NoError(t, err)
+
+		assert.Equal(t, ChangeTypeRemove, change.Type)
+		assert.False(t, change.Expired)
+		assert.Nil(t, change.NewImage)
+		assert.NotNil(t, change.OldImage)
+	})
+
+	t.Run("Remove By TTL", func(t *testing.T) {
+		record := streamRecord(types.OperationTypeRemove, "103", streamImage("evt-1", models.EventStatusProcessed, "1"), nil)
+		record.UserIdentity = &types.Identity{PrincipalId: aws.String("dynamodb.amazonaws.com"), Type: aws.String("Service")}
+
+		change, err := changeFromRecord(record)
+		require.NoError(t, err)
+		assert.True(t, change.Expired)
+	})
+
+	t.Run("Client Change", func(t *testing.T) {
+		record := streamRecord(types.OperationTypeModify, "105", nil, nil)
+		record.Dynamodb.Keys = map[string]types.AttributeValue{
+			"client_id": &types.AttributeValueMemberS{Value: "client-001"},
+		}
+
+		change, err := changeFromRecord(record)
+		require.NoError(t, err)
+
+		assert.Equal(t, ChangeTypeModify, change.Type)
+		assert.Equal(t, "client-001", change.ClientID)
+		assert.Empty(t, change.EventID)
+		assert.Nil(t, change.NewImage)
+		assert.Nil(t, change.OldImage)
+	})
+
+	t.Run("Unknown Operation", func(t *testing.T) {
+		_, err := changeFromRecord(streamRecord("TRUNCATE", "104", nil, nil))
+		assert.ErrorContains(t, err, "unknown operation")
+	})
+
+	t.Run("Missing Data", func(t *testing.T) {
+		_, err := changeFromRecord(types.Record{EventName: types.OperationTypeInsert})
+		assert.ErrorContains(t, err, "has no data")
+	})
+}
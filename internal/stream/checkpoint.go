@@ -0,0 +1,110 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// CheckpointStore records the last sequence number delivered from each shard.
+//
+// Shard iterators expire after 15 minutes, so the reader checkpoints sequence numbers and resumes
+// with an AFTER_SEQUENCE_NUMBER iterator on restart.
+type CheckpointStore interface {
+	// Load returns the checkpoint of a shard, or "" when it has none
+	Load(ctx context.Context, shardID string) (string, error)
+	Save(ctx context.Context, shardID, sequenceNumber string) error
+}
+
+// MemoryCheckpointStore keeps checkpoints in memory, so a restarted reader starts from the trim horizon
+type MemoryCheckpointStore struct {
+	mu          sync.Mutex
+	checkpoints map[string]string
+}
+
+// NewMemoryCheckpointStore creates an empty in-memory checkpoint store
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{checkpoints: make(map[string]string)}
+}
+
+// Load returns the checkpoint of a shard
+func (s *MemoryCheckpointStore) Load(ctx context.Context, shardID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.checkpoints[shardID], nil
+}
+
+// Save records the checkpoint of a shard
+func (s *MemoryCheckpointStore) Save(ctx context.Context, shardID, sequenceNumber string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoints[shardID] = sequenceNumber
+	return nil
+}
+
+// FileCheckpointStore keeps checkpoints in a JSON file mapping shard IDs to sequence numbers
+type FileCheckpointStore struct {
+	mu          sync.Mutex
+	path        string
+	checkpoints map[string]string
+}
+
+// NewFileCheckpointStore creates a checkpoint store backed by path, loading existing checkpoints
+func NewFileCheckpointStore(path string) (*FileCheckpointStore, error) {
+	store := &FileCheckpointStore{path: path, checkpoints: make(map[string]string)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoints: %w", err)
+	}
+	if err := json.Unmarshal(data, &store.checkpoints); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint file %s: %w", path, err)
+	}
+
+	return store, nil
+}
+
+// Load returns the checkpoint of a shard
+func (s *FileCheckpointStore) Load(ctx context.Context, shardID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.checkpoints[shardID], nil
+}
+
+// Save records the checkpoint of a shard, replacing the file atomically
+func (s *FileCheckpointStore) Save(ctx context.Context, shardID, sequenceNumber string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checkpoints[shardID] = sequenceNumber
+	data, err := json.MarshalIndent(s.checkpoints, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoints: %w", err)
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoints: %w", err)
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to write checkpoints: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("failed to write checkpoints: %w", err)
+	}
+	if err := os.Rename(temp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write checkpoints: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package stream
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFileCheckpointStore tests that checkpoints survive reopening the store
+func TestFileCheckpointStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "checkpoints.json")
+
+	store, err := NewFileCheckpointStore(path)
+	require.NoError(t, err)
+
+	checkpoint, err := store.Load(ctx, "shard-1")
+	require.NoError(t, err)
+	assert.Empty(t, checkpoint)
+
+	require.NoError(t, store.Save(ctx, "shard-1", "100"))
+	require.NoError(t, store.Save(ctx, "shard-2", "200"))
+	require.NoError(t, store.Save(ctx, "shard-1", "150"))
+
+	reopened, err := NewFileCheckpointStore(path)
+	require.NoError(t, err)
+
+	checkpoint, err = reopened.Load(ctx, "shard-1")
+	require.NoError(t, err)
+	assert.Equal(t, "150", checkpoint)
+
+	checkpoint, err = reopened.Load(ctx, "shard-2")
+	require.NoError(t, err)
+	assert.Equal(t, "200", checkpoint)
+
+	// Only the checkpoint file is left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+// TestFileCheckpointStoreInvalidFile tests that a corrupt checkpoint file is reported
+func TestFileCheckpointStoreInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoints.json")
+	require.NoError(t, os.WriteFile(path, []byte("not json"), 0o644))
+
+	_, err := NewFileCheckpointStore(path)
+	assert.ErrorContains(t, err, "invalid checkpoint file")
+}
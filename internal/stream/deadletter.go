@@ -0,0 +1,63 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// DeadLetter is a batch of changes a sink kept rejecting
+type DeadLetter struct {
+	Sink     string        `json:"sink"`
+	Error    string        `json:"error"`
+	FailedAt time.Time     `json:"failedAt"`
+	Changes  []ChangeEvent `json:"changes"`
+}
+
+// DeadLetterStore keeps the batches a sink rejected on every delivery attempt, so that the reader can
+// move on and the batches can be inspected and sent again by hand
+type DeadLetterStore interface {
+	Add(ctx context.Context, letter DeadLetter) error
+}
+
+// FileDeadLetterStore appends dead letters to a file as newline delimited JSON
+type FileDeadLetterStore struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileDeadLetterStore opens path for appending, creating it if needed
+func NewFileDeadLetterStore(path string) (*FileDeadLetterStore, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dead letter file: %w", err)
+	}
+	return &FileDeadLetterStore{file: file}, nil
+}
+
+// Add appends one line per dead letter and syncs the file
+func (s *FileDeadLetterStore) Add(ctx context.Context, letter DeadLetter) error {
+	line, err := json.Marshal(letter)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write dead letter: %w", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync dead letter file: %w", err)
+	}
+	return nil
+}
+
+// Close closes the file
+func (s *FileDeadLetterStore) Close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,53 @@
+package stream
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFileDeadLetterStore tests that dead letters are appended to the file, one per line
+func TestFileDeadLetterStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "dead-letters.ndjson")
+	failedAt := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	store, err := NewFileDeadLetterStore(path)
+	require.NoError(t, err)
+	require.NoError(t, store.Add(ctx, DeadLetter{Sink: SinkWebhook, Error: "status 400", FailedAt: failedAt, Changes: testChanges()}))
+	require.NoError(t, store.Close())
+
+	// Reopening appends rather than truncates
+	store, err = NewFileDeadLetterStore(path)
+	require.NoError(t, err)
+	require.NoError(t, store.Add(ctx, DeadLetter{Sink: SinkSQS, Error: "message too long", FailedAt: failedAt, Changes: testChanges()[:1]}))
+	require.NoError(t, store.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var letters []DeadLetter
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var letter DeadLetter
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &letter))
+		letters = append(letters, letter)
+	}
+	require.NoError(t, scanner.Err())
+
+	require.Len(t, letters, 2)
+	assert.Equal(t, SinkWebhook, letters[0].Sink)
+	assert.Equal(t, "status 400", letters[0].Error)
+	assert.Equal(t, failedAt, letters[0].FailedAt)
+	assert.Len(t, letters[0].Changes, 2)
+	assert.Equal(t, SinkSQS, letters[1].Sink)
+	assert.Equal(t, "100", letters[1].Changes[0].SequenceNumber)
+}
@@ -0,0 +1,408 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams/types"
+	"github.com/sirupsen/logrus"
+
+	"github.com/d-sense/event-processor/internal/metrics"
+)
+
+// StreamsClient defines the interface for DynamoDB Streams operations
+type StreamsClient interface {
+	DescribeStream(ctx context.Context, params *dynamodbstreams.DescribeStreamInput, optFns ...func(*dynamodbstreams.Options)) (*dynamodbstreams.DescribeStreamOutput, error)
+	GetShardIterator(ctx context.Context, params *dynamodbstreams.GetShardIteratorInput, optFns ...func(*dynamodbstreams.Options)) (*dynamodbstreams.GetShardIteratorOutput, error)
+	GetRecords(ctx context.Context, params *dynamodbstreams.GetRecordsInput, optFns ...func(*dynamodbstreams.Options)) (*dynamodbstreams.GetRecordsOutput, error)
+}
+
+// TableDescriber is the part of the DynamoDB API used to find the stream of a table
+type TableDescriber interface {
+	DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error)
+}
+
+// LatestStreamARN returns the ARN of the current stream of a table
+func LatestStreamARN(ctx context.Context, client TableDescriber, tableName string) (string, error) {
+	output, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(tableName)})
+	if err != nil {
+		return "", err
+	}
+	if output.Table == nil || output.Table.LatestStreamArn == nil {
+		return "", fmt.Errorf("streams are not enabled on table %s", tableName)
+	}
+	return aws.ToString(output.Table.LatestStreamArn), nil
+}
+
+// ReaderOptions configures a Reader
+type ReaderOptions struct {
+	// PollInterval is the wait between GetRecords calls on a shard with no new records, and the
+	// backoff after a failed call or delivery
+	PollInterval time.Duration
+
+	// ShardRefreshInterval is how often the stream is described to discover new shards
+	ShardRefreshInterval time.Duration
+
+	// Limit bounds the number of records read by a GetRecords call
+	Limit int32
+
+	// MaxDeliveryAttempts bounds how often a batch is sent to a sink rejecting it before the batch is
+	// dead-lettered for that sink, so that a batch the sink can never accept does not stall the shard
+	MaxDeliveryAttempts int
+
+	// DeadLetters receives the batches a sink rejected MaxDeliveryAttempts times; without it they are
+	// dropped
+	DeadLetters DeadLetterStore
+}
+
+// DefaultReaderOptions returns the default reader options
+func DefaultReaderOptions() ReaderOptions {
+	return ReaderOptions{
+		PollInterval:         time.Second,
+		ShardRefreshInterval: 30 * time.Second,
+		Limit:                100,
+		MaxDeliveryAttempts:  10,
+	}
+}
+
+// Reader reads the stream of the events or clients table and delivers its changes to sinks.
+//
+// Each open shard is read by its own goroutine. A child shard is only read once its parent has been
+// read to the end, so the changes to an event reach the sinks in order. A batch is checkpointed once
+// every sink has accepted it or it has been dead-lettered for the sinks that kept rejecting it.
+type Reader struct {
+	client      StreamsClient
+	streamARN   string
+	sinks       []Sink
+	checkpoints CheckpointStore
+	options     ReaderOptions
+	logger      *logrus.Logger
+
+	mu       sync.Mutex
+	reading  map[string]bool
+	finished map[string]bool
+}
+
+// NewReader creates a reader of the stream identified by streamARN
+func NewReader(client StreamsClient, streamARN string, sinks []Sink, checkpoints CheckpointStore, options ReaderOptions, logger *logrus.Logger) *Reader {
+	defaults := DefaultReaderOptions()
+	if options.PollInterval <= 0 {
+		options.PollInterval = defaults.PollInterval
+	}
+	if options.ShardRefreshInterval <= 0 {
+		options.ShardRefreshInterval = defaults.ShardRefreshInterval
+	}
+	if options.Limit <= 0 {
+		options.Limit = defaults.Limit
+	}
+	if options.MaxDeliveryAttempts <= 0 {
+		options.MaxDeliveryAttempts = defaults.MaxDeliveryAttempts
+	}
+
+	return &Reader{
+		client:      client,
+		streamARN:   streamARN,
+		sinks:       sinks,
+		checkpoints: checkpoints,
+		options:     options,
+		logger:      logger,
+		reading:     make(map[string]bool),
+		finished:    make(map[string]bool),
+	}
+}
+
+// Run reads the stream until ctx is done, then waits for shard readers to stop
+func (r *Reader) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	ticker := time.NewTicker(r.options.ShardRefreshInterval)
+	defer ticker.Stop()
+
+	for ctx.Err() == nil {
+		shards, err := r.listShards(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			r.logger.WithError(err).Error("Failed to describe stream")
+		}
+
+		for _, shard := range r.readyShards(shards) {
+			wg.Add(1)
+			go func(shardID string) {
+				defer wg.Done()
+				r.readShard(ctx, shardID)
+			}(aws.ToString(shard.ShardId))
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// listShards returns all shards of the stream
+func (r *Reader) listShards(ctx context.Context) ([]types.Shard, error) {
+	var (
+		shards  []types.Shard
+		startID *string
+	)
+	for {
+		output, err := r.client.DescribeStream(ctx, &dynamodbstreams.DescribeStreamInput{
+			StreamArn:             aws.String(r.streamARN),
+			ExclusiveStartShardId: startID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe stream %s: %w", r.streamARN, err)
+		}
+		if output.StreamDescription == nil {
+			return shards, nil
+		}
+
+		shards = append(shards, output.StreamDescription.Shards...)
+		startID = output.StreamDescription.LastEvaluatedShardId
+		if startID == nil {
+			return shards, nil
+		}
+	}
+}
+
+// readyShards marks and returns the shards that should start being read: not already read and
+// whose parent has been read to the end or is no longer part of the stream
+func (r *Reader) readyShards(shards []types.Shard) []types.Shard {
+	known := make(map[string]bool, len(shards))
+	for _, shard := range shards {
+		known[aws.ToString(shard.ShardId)] = true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ready []types.Shard
+	for _, shard := range shards {
+		shardID := aws.ToString(shard.ShardId)
+		if r.reading[shardID] || r.finished[shardID] {
+			continue
+		}
+		parentID := aws.ToString(shard.ParentShardId)
+		if parentID != "" && known[parentID] && !r.finished[parentID] {
+			continue
+		}
+
+		r.reading[shardID] = true
+		ready = append(ready, shard)
+	}
+	return ready
+}
+
+// readShard reads a shard from its checkpoint until it is closed or ctx is done
+func (r *Reader) readShard(ctx context.Context, shardID string) {
+	defer func() {
+		r.mu.Lock()
+		delete(r.reading, shardID)
+		r.mu.Unlock()
+	}()
+	if ctx.Err() != nil {
+		return
+	}
+
+	logger := r.logger.WithField("shard_id", shardID)
+	logger.Info("Reading shard")
+
+	iterator, err := r.shardIterator(ctx, shardID)
+	for iterator == nil {
+		if err == nil || ctx.Err() != nil {
+			return
+		}
+		logger.WithError(err).Error("Failed to get shard iterator")
+		if !r.sleep(ctx) {
+			return
+		}
+		iterator, err = r.shardIterator(ctx, shardID)
+	}
+
+	for ctx.Err() == nil {
+		output, err := r.client.GetRecords(ctx, &dynamodbstreams.GetRecordsInput{
+			ShardIterator: iterator,
+			Limit:         aws.Int32(r.options.Limit),
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			var expired *types.ExpiredIteratorException
+			if errors.As(err, &expired) {
+				// Resume from the last checkpoint with a fresh iterator
+				if next, iteratorErr := r.shardIterator(ctx, shardID); iteratorErr == nil && next != nil {
+					iterator = next
+					continue
+				}
+			}
+			logger.WithError(err).Error("Failed to get stream records")
+			if !r.sleep(ctx) {
+				return
+			}
+			continue
+		}
+
+		if len(output.Records) > 0 {
+			if !r.deliver(ctx, shardID, output.Records, logger) {
+				return
+			}
+		}
+
+		if output.NextShardIterator == nil {
+			logger.Info("Shard closed")
+			r.mu.Lock()
+			r.finished[shardID] = true
+			r.mu.Unlock()
+			return
+		}
+		iterator = output.NextShardIterator
+
+		if len(output.Records) == 0 && !r.sleep(ctx) {
+			return
+		}
+	}
+}
+
+// shardIterator returns an iterator positioned after the shard's checkpoint, or at its trim horizon
+func (r *Reader) shardIterator(ctx context.Context, shardID string) (*string, error) {
+	checkpoint, err := r.checkpoints.Load(ctx, shardID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+
+	input := &dynamodbstreams.GetShardIteratorInput{
+		StreamArn:         aws.String(r.streamARN),
+		ShardId:           aws.String(shardID),
+		ShardIteratorType: types.ShardIteratorTypeTrimHorizon,
+	}
+	if checkpoint != "" {
+		input.ShardIteratorType = types.ShardIteratorTypeAfterSequenceNumber
+		input.SequenceNumber = aws.String(checkpoint)
+	}
+
+	output, err := r.client.GetShardIterator(ctx, input)
+	var trimmed *types.TrimmedDataAccessException
+	if errors.As(err, &trimmed) {
+		// Records after the checkpoint have been trimmed, continue with the oldest available record
+		r.logger.WithField("shard_id", shardID).Warn("Checkpoint is beyond the stream retention, changes were lost")
+		input.ShardIteratorType = types.ShardIteratorTypeTrimHorizon
+		input.SequenceNumber = nil
+		output, err = r.client.GetShardIterator(ctx, input)
+	}
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		// The shard has been trimmed from the stream
+		r.mu.Lock()
+		r.finished[shardID] = true
+		r.mu.Unlock()
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get iterator for shard %s: %w", shardID, err)
+	}
+
+	return output.ShardIterator, nil
+}
+
+// deliver sends records to every sink, retrying until they are accepted or dead-lettered, then
+// checkpoints them. It returns false when ctx is done before the records were delivered.
+func (r *Reader) deliver(ctx context.Context, shardID string, records []types.Record, logger *logrus.Entry) bool {
+	var (
+		changes            = make([]ChangeEvent, 0, len(records))
+		lastSequenceNumber string
+	)
+	for _, record := range records {
+		change, err := changeFromRecord(record)
+		if err != nil {
+			logger.WithError(err).Warn("Skipping invalid stream record")
+			continue
+		}
+		changes = append(changes, change)
+		lastSequenceNumber = change.SequenceNumber
+	}
+	if len(changes) == 0 {
+		return true
+	}
+
+	// Only retry the sinks that have not accepted the batch yet
+	pending := r.sinks
+	for attempt := 1; len(pending) > 0; attempt++ {
+		var failed []Sink
+		for _, sink := range pending {
+			err := sink.Send(ctx, changes)
+			for _, change := range changes {
+				metrics.StreamChanges.WithLabelValues(string(change.Type), sink.Name(), metrics.Outcome(err)).Inc()
+			}
+			if err == nil {
+				continue
+			}
+			logger.WithError(err).WithField("sink", sink.Name()).Error("Failed to deliver changes")
+			if attempt < r.options.MaxDeliveryAttempts || !r.deadLetter(ctx, sink, err, changes, logger) {
+				failed = append(failed, sink)
+			}
+		}
+
+		pending = failed
+		if len(pending) > 0 && !r.sleep(ctx) {
+			return false
+		}
+	}
+
+	if err := r.checkpoints.Save(ctx, shardID, lastSequenceNumber); err != nil {
+		// The batch is delivered again after a restart
+		logger.WithError(err).Error("Failed to save checkpoint")
+	}
+	logger.WithField("changes", len(changes)).Debug("Delivered changes")
+	return true
+}
+
+// deadLetter gives up on delivering changes to a sink, reporting false when they could not be
+// dead-lettered and delivery should go on
+func (r *Reader) deadLetter(ctx context.Context, sink Sink, cause error, changes []ChangeEvent, logger *logrus.Entry) bool {
+	logger = logger.WithFields(logrus.Fields{
+		"sink":     sink.Name(),
+		"changes":  len(changes),
+		"attempts": r.options.MaxDeliveryAttempts,
+	})
+
+	if r.options.DeadLetters == nil {
+		logger.Error("Dropping changes the sink keeps rejecting")
+	} else {
+		letter := DeadLetter{Sink: sink.Name(), Error: cause.Error(), FailedAt: time.Now().UTC(), Changes: changes}
+		if err := r.options.DeadLetters.Add(ctx, letter); err != nil {
+			logger.WithError(err).Error("Failed to dead-letter changes")
+			return false
+		}
+		logger.Error("Dead-lettered changes the sink keeps rejecting")
+	}
+
+	for _, change := range changes {
+		metrics.StreamChanges.WithLabelValues(string(change.Type), sink.Name(), metrics.OutcomeDeadLetter).Inc()
+	}
+	return true
+}
+
+// sleep waits for the poll interval, returning false when ctx is done first
+func (r *Reader) sleep(ctx context.Context) bool {
+	timer := time.NewTimer(r.options.PollInterval)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
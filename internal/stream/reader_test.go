@@ -0,0 +1,339 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams/types"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+const testStreamARN = "arn:aws:dynamodb:us-east-1:000000000000:table/events/stream/2025-01-01T00:00:00.000"
+
+// MockStreamsClient is a mock implementation of the StreamsClient interface
+type MockStreamsClient struct {
+	mock.Mock
+}
+
+func (m *MockStreamsClient) DescribeStream(ctx context.Context, params *dynamodbstreams.DescribeStreamInput, optFns ...func(*dynamodbstreams.Options)) (*dynamodbstreams.DescribeStreamOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dynamodbstreams.DescribeStreamOutput), args.Error(1)
+}
+
+func (m *MockStreamsClient) GetShardIterator(ctx context.Context, params *dynamodbstreams.GetShardIteratorInput, optFns ...func(*dynamodbstreams.Options)) (*dynamodbstreams.GetShardIteratorOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dynamodbstreams.GetShardIteratorOutput), args.Error(1)
+}
+
+func (m *MockStreamsClient) GetRecords(ctx context.Context, params *dynamodbstreams.GetRecordsInput, optFns ...func(*dynamodbstreams.Options)) (*dynamodbstreams.GetRecordsOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dynamodbstreams.GetRecordsOutput), args.Error(1)
+}
+
+// recordingSink records delivered changes and fails the first failures sends
+type recordingSink struct {
+	mu       sync.Mutex
+	name     string
+	failures int
+	attempts int
+	changes  []ChangeEvent
+}
+
+func (s *recordingSink) Name() string {
+	return s.name
+}
+
+func (s *recordingSink) Send(ctx context.Context, changes []ChangeEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attempts++
+	if s.attempts <= s.failures {
+		return errors.New("sink unavailable")
+	}
+	s.changes = append(s.changes, changes...)
+	return nil
+}
+
+func (s *recordingSink) sequenceNumbers() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sequenceNumbers []string
+	for _, change := range s.changes {
+		sequenceNumbers = append(sequenceNumbers, change.SequenceNumber)
+	}
+	return sequenceNumbers
+}
+
+// recordingDeadLetters records dead letters, or fails to store them with failWith
+type recordingDeadLetters struct {
+	mu       sync.Mutex
+	failWith error
+	letters  []DeadLetter
+}
+
+func (d *recordingDeadLetters) Add(ctx context.Context, letter DeadLetter) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.failWith != nil {
+		return d.failWith
+	}
+	d.letters = append(d.letters, letter)
+	return nil
+}
+
+func (d *recordingDeadLetters) stored() []DeadLetter {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]DeadLetter(nil), d.letters...)
+}
+
+func testReaderOptions() ReaderOptions {
+	return ReaderOptions{
+		PollInterval:         time.Millisecond,
+		ShardRefreshInterval: 5 * time.Millisecond,
+		Limit:                10,
+	}
+}
+
+func testShard(id, parentID string) types.Shard {
+	shard := types.Shard{ShardId: aws.String(id)}
+	if parentID != "" {
+		shard.ParentShardId = aws.String(parentID)
+	}
+	return shard
+}
+
+func insertRecord(sequenceNumber string) types.Record {
+	return streamRecord(types.OperationTypeInsert, sequenceNumber, nil, streamImage("evt-"+sequenceNumber, models.EventStatusProcessed, "1"))
+}
+
+// expectShards makes DescribeStream return the given shards
+func expectShards(client *MockStreamsClient, shards ...types.Shard) {
+	client.On("DescribeStream", mock.Anything, mock.MatchedBy(func(input *dynamodbstreams.DescribeStreamInput) bool {
+		return aws.ToString(input.StreamArn) == testStreamARN
+	})).Return(&dynamodbstreams.DescribeStreamOutput{
+		StreamDescription: &types.StreamDescription{Shards: shards},
+	}, nil)
+}
+
+// expectIterator makes GetShardIterator for a shard return iterator
+func expectIterator(client *MockStreamsClient, shardID string, iteratorType types.ShardIteratorType, iterator string) *mock.Call {
+	return client.On("GetShardIterator", mock.Anything, mock.MatchedBy(func(input *dynamodbstreams.GetShardIteratorInput) bool {
+		return aws.ToString(input.ShardId) == shardID && input.ShardIteratorType == iteratorType
+	})).Return(&dynamodbstreams.GetShardIteratorOutput{ShardIterator: aws.String(iterator)}, nil)
+}
+
+// expectRecords makes GetRecords for an iterator return records and the next iterator, nil closing the shard
+func expectRecords(client *MockStreamsClient, iterator string, next *string, records ...types.Record) *mock.Call {
+	return client.On("GetRecords", mock.Anything, mock.MatchedBy(func(input *dynamodbstreams.GetRecordsInput) bool {
+		return aws.ToString(input.ShardIterator) == iterator
+	})).Return(&dynamodbstreams.GetRecordsOutput{Records: records, NextShardIterator: next}, nil)
+}
+
+// runReader runs a reader until done reports true, failing the test after a timeout
+func runReader(t *testing.T, reader *Reader, done func() bool) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() { result <- reader.Run(ctx) }()
+
+	assert.Eventually(t, done, 2*time.Second, time.Millisecond)
+	cancel()
+	require.NoError(t, <-result)
+}
+
+// TestReaderReadsShardsInOrder tests that a child shard is read only after its parent is closed
+func TestReaderReadsShardsInOrder(t *testing.T) {
+	client := &MockStreamsClient{}
+	expectShards(client, testShard("shard-child", "shard-parent"), testShard("shard-parent", ""))
+	expectIterator(client, "shard-parent", types.ShardIteratorTypeTrimHorizon, "parent-1")
+	expectRecords(client, "parent-1", aws.String("parent-2"), insertRecord("100"), insertRecord("101"))
+	expectRecords(client, "parent-2", nil, insertRecord("102"))
+	expectIterator(client, "shard-child", types.ShardIteratorTypeTrimHorizon, "child-1")
+	expectRecords(client, "child-1", aws.String("child-2"), insertRecord("200"))
+	expectRecords(client, "child-2", aws.String("child-2"))
+
+	sink := &recordingSink{name: "recording"}
+	checkpoints := NewMemoryCheckpointStore()
+	reader := NewReader(client, testStreamARN, []Sink{sink}, checkpoints, testReaderOptions(), logrus.New())
+
+	runReader(t, reader, func() bool { return len(sink.sequenceNumbers()) == 4 })
+
+	assert.Equal(t, []string{"100", "101", "102", "200"}, sink.sequenceNumbers())
+	parent, _ := checkpoints.Load(context.Background(), "shard-parent")
+	child, _ := checkpoints.Load(context.Background(), "shard-child")
+	assert.Equal(t, "102", parent)
+	assert.Equal(t, "200", child)
+}
+
+// TestReaderResumesFromCheckpoint tests that reading continues after the checkpointed sequence number
+func TestReaderResumesFromCheckpoint(t *testing.T) {
+	client := &MockStreamsClient{}
+	expectShards(client, testShard("shard-1", ""))
+	client.On("GetShardIterator", mock.Anything, mock.MatchedBy(func(input *dynamodbstreams.GetShardIteratorInput) bool {
+		return input.ShardIteratorType == types.ShardIteratorTypeAfterSequenceNumber && aws.ToString(input.SequenceNumber) == "101"
+	})).Return(&dynamodbstreams.GetShardIteratorOutput{ShardIterator: aws.String("resume")}, nil)
+	expectRecords(client, "resume", aws.String("resume"), insertRecord("102"))
+
+	checkpoints := NewMemoryCheckpointStore()
+	require.NoError(t, checkpoints.Save(context.Background(), "shard-1", "101"))
+
+	sink := &recordingSink{name: "recording"}
+	reader := NewReader(client, testStreamARN, []Sink{sink}, checkpoints, testReaderOptions(), logrus.New())
+
+	runReader(t, reader, func() bool { return len(sink.sequenceNumbers()) > 0 })
+
+	assert.Equal(t, "102", sink.sequenceNumbers()[0])
+	client.AssertNotCalled(t, "GetShardIterator", mock.Anything, mock.MatchedBy(func(input *dynamodbstreams.GetShardIteratorInput) bool {
+		return input.ShardIteratorType == types.ShardIteratorTypeTrimHorizon
+	}))
+}
+
+// TestReaderRetriesFailedSinks tests that a batch is resent only to the sinks that failed and checkpointed once all accepted it
+func TestReaderRetriesFailedSinks(t *testing.T) {
+	client := &MockStreamsClient{}
+	expectShards(client, testShard("shard-1", ""))
+	expectIterator(client, "shard-1", types.ShardIteratorTypeTrimHorizon, "it-1")
+	expectRecords(client, "it-1", nil, insertRecord("100"))
+
+	healthy := &recordingSink{name: "healthy"}
+	flaky := &recordingSink{name: "flaky", failures: 2}
+	checkpoints := NewMemoryCheckpointStore()
+	reader := NewReader(client, testStreamARN, []Sink{healthy, flaky}, checkpoints, testReaderOptions(), logrus.New())
+
+	runReader(t, reader, func() bool {
+		checkpoint, _ := checkpoints.Load(context.Background(), "shard-1")
+		return checkpoint == "100"
+	})
+
+	assert.Equal(t, []string{"100"}, healthy.sequenceNumbers())
+	assert.Equal(t, []string{"100"}, flaky.sequenceNumbers())
+	assert.Equal(t, 3, flaky.attempts)
+}
+
+// TestReaderDeadLettersRejectedBatches tests that a batch a sink keeps rejecting is dead-lettered for
+// that sink, so that the shard is read on
+func TestReaderDeadLettersRejectedBatches(t *testing.T) {
+	client := &MockStreamsClient{}
+	expectShards(client, testShard("shard-1", ""))
+	expectIterator(client, "shard-1", types.ShardIteratorTypeTrimHorizon, "it-1")
+	expectRecords(client, "it-1", aws.String("it-2"), insertRecord("100"))
+	expectRecords(client, "it-2", nil, insertRecord("101"))
+
+	healthy := &recordingSink{name: "healthy"}
+	poisoned := &recordingSink{name: "poisoned", failures: 3}
+	deadLetters := &recordingDeadLetters{}
+	checkpoints := NewMemoryCheckpointStore()
+	options := testReaderOptions()
+	options.MaxDeliveryAttempts = 3
+	options.DeadLetters = deadLetters
+	reader := NewReader(client, testStreamARN, []Sink{healthy, poisoned}, checkpoints, options, logrus.New())
+
+	runReader(t, reader, func() bool {
+		checkpoint, _ := checkpoints.Load(context.Background(), "shard-1")
+		return checkpoint == "101"
+	})
+
+	assert.Equal(t, []string{"100", "101"}, healthy.sequenceNumbers())
+	assert.Equal(t, []string{"101"}, poisoned.sequenceNumbers())
+	assert.Equal(t, 4, poisoned.attempts)
+
+	letters := deadLetters.stored()
+	require.Len(t, letters, 1)
+	assert.Equal(t, "poisoned", letters[0].Sink)
+	assert.Equal(t, "sink unavailable", letters[0].Error)
+	require.Len(t, letters[0].Changes, 1)
+	assert.Equal(t, "100", letters[0].Changes[0].SequenceNumber)
+}
+
+// TestReaderRetriesWhenDeadLettersFail tests that a batch is not checkpointed until it is either
+// delivered or dead-lettered
+func TestReaderRetriesWhenDeadLettersFail(t *testing.T) {
+	client := &MockStreamsClient{}
+	expectShards(client, testShard("shard-1", ""))
+	expectIterator(client, "shard-1", types.ShardIteratorTypeTrimHorizon, "it-1")
+	expectRecords(client, "it-1", nil, insertRecord("100"))
+
+	flaky := &recordingSink{name: "flaky", failures: 4}
+	checkpoints := NewMemoryCheckpointStore()
+	options := testReaderOptions()
+	options.MaxDeliveryAttempts = 2
+	options.DeadLetters = &recordingDeadLetters{failWith: errors.New("disk full")}
+	reader := NewReader(client, testStreamARN, []Sink{flaky}, checkpoints, options, logrus.New())
+
+	runReader(t, reader, func() bool {
+		checkpoint, _ := checkpoints.Load(context.Background(), "shard-1")
+		return checkpoint == "100"
+	})
+
+	assert.Equal(t, []string{"100"}, flaky.sequenceNumbers())
+	assert.Equal(t, 5, flaky.attempts)
+}
+
+// TestReaderRenewsExpiredIterator tests that an expired iterator is replaced from the last checkpoint
+func TestReaderRenewsExpiredIterator(t *testing.T) {
+	client := &MockStreamsClient{}
+	expectShards(client, testShard("shard-1", ""))
+	expectIterator(client, "shard-1", types.ShardIteratorTypeTrimHorizon, "it-1")
+	expectRecords(client, "it-1", aws.String("it-2"), insertRecord("100"))
+	client.On("GetRecords", mock.Anything, mock.MatchedBy(func(input *dynamodbstreams.GetRecordsInput) bool {
+		return aws.ToString(input.ShardIterator) == "it-2"
+	})).Return(nil, &types.ExpiredIteratorException{Message: aws.String("expired")})
+	client.On("GetShardIterator", mock.Anything, mock.MatchedBy(func(input *dynamodbstreams.GetShardIteratorInput) bool {
+		return input.ShardIteratorType == types.ShardIteratorTypeAfterSequenceNumber && aws.ToString(input.SequenceNumber) == "100"
+	})).Return(&dynamodbstreams.GetShardIteratorOutput{ShardIterator: aws.String("it-3")}, nil)
+	expectRecords(client, "it-3", nil, insertRecord("101"))
+
+	sink := &recordingSink{name: "recording"}
+	reader := NewReader(client, testStreamARN, []Sink{sink}, NewMemoryCheckpointStore(), testReaderOptions(), logrus.New())
+
+	runReader(t, reader, func() bool { return len(sink.sequenceNumbers()) == 2 })
+
+	assert.Equal(t, []string{"100", "101"}, sink.sequenceNumbers())
+}
+
+// TestReaderListsAllShardPages tests that shards are listed across DescribeStream pages
+func TestReaderListsAllShardPages(t *testing.T) {
+	client := &MockStreamsClient{}
+	client.On("DescribeStream", mock.Anything, mock.MatchedBy(func(input *dynamodbstreams.DescribeStreamInput) bool {
+		return input.ExclusiveStartShardId == nil
+	})).Return(&dynamodbstreams.DescribeStreamOutput{StreamDescription: &types.StreamDescription{
+		Shards:               []types.Shard{testShard("shard-1", "")},
+		LastEvaluatedShardId: aws.String("shard-1"),
+	}}, nil)
+	client.On("DescribeStream", mock.Anything, mock.MatchedBy(func(input *dynamodbstreams.DescribeStreamInput) bool {
+		return aws.ToString(input.ExclusiveStartShardId) == "shard-1"
+	})).Return(&dynamodbstreams.DescribeStreamOutput{StreamDescription: &types.StreamDescription{
+		Shards: []types.Shard{testShard("shard-2", "")},
+	}}, nil)
+
+	reader := NewReader(client, testStreamARN, nil, NewMemoryCheckpointStore(), testReaderOptions(), logrus.New())
+
+	shards, err := reader.listShards(context.Background())
+	require.NoError(t, err)
+	require.Len(t, shards, 2)
+	assert.Equal(t, "shard-2", aws.ToString(shards[1].ShardId))
+}
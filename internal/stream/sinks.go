@@ -0,0 +1,295 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/sirupsen/logrus"
+
+	"github.com/d-sense/event-processor/internal/archive"
+	"github.com/d-sense/event-processor/internal/config"
+)
+
+// Sink names accepted in STREAM_SINKS
+const (
+	SinkSQS     = "sqs"
+	SinkWebhook = "webhook"
+	SinkFile    = "file"
+	SinkArchive = "archive"
+
+	// SinkInvalidation is not listed in STREAM_SINKS: the server reads the clients table with it
+	SinkInvalidation = "invalidation"
+)
+
+// Sink receives change events read from the stream.
+//
+// Delivery is at least once: a batch is resent after a failure or a restart before its checkpoint
+// was saved, so sinks should tolerate duplicates, e.g. by keying on the sequence number.
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, changes []ChangeEvent) error
+}
+
+// SQSSender is the subset of the SQS API used by the SQS sink
+type SQSSender interface {
+	SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
+}
+
+// SQSSink sends each change event as an SQS message
+type SQSSink struct {
+	client   SQSSender
+	queueURL string
+}
+
+// NewSQSSink creates a sink sending to queueURL
+func NewSQSSink(client SQSSender, queueURL string) *SQSSink {
+	return &SQSSink{client: client, queueURL: queueURL}
+}
+
+// Name returns the sink name
+func (s *SQSSink) Name() string {
+	return SinkSQS
+}
+
+// Send sends the changes in order, one message per change
+func (s *SQSSink) Send(ctx context.Context, changes []ChangeEvent) error {
+	for _, change := range changes {
+		body, err := json.Marshal(change)
+		if err != nil {
+			return fmt.Errorf("failed to marshal change: %w", err)
+		}
+
+		_, err = s.client.SendMessage(ctx, &sqs.SendMessageInput{
+			QueueUrl:    aws.String(s.queueURL),
+			MessageBody: aws.String(string(body)),
+			MessageAttributes: map[string]sqstypes.MessageAttributeValue{
+				"ChangeType": {
+					DataType:    aws.String("String"),
+					StringValue: aws.String(string(change.Type)),
+				},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to send change %s to SQS: %w", change.SequenceNumber, err)
+		}
+	}
+	return nil
+}
+
+// WebhookSink posts batches of change events to an HTTP endpoint
+type WebhookSink struct {
+	client *http.Client
+	url    string
+}
+
+// webhookRequest is the JSON body posted by the webhook sink
+type webhookRequest struct {
+	Changes []ChangeEvent `json:"changes"`
+}
+
+// NewWebhookSink creates a sink posting to url
+func NewWebhookSink(client *http.Client, url string) *WebhookSink {
+	return &WebhookSink{client: client, url: url}
+}
+
+// Name returns the sink name
+func (s *WebhookSink) Name() string {
+	return SinkWebhook
+}
+
+// Send posts the changes as a single request, failing on any non-2xx response
+func (s *WebhookSink) Send(ctx context.Context, changes []ChangeEvent) error {
+	body, err := json.Marshal(webhookRequest{Changes: changes})
+	if err != nil {
+		return fmt.Errorf("failed to marshal changes: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post changes: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// FileSink appends change events to a file as newline delimited JSON
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileSink opens path for appending, creating it if needed
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open change file: %w", err)
+	}
+	return &FileSink{file: file}, nil
+}
+
+// Name returns the sink name
+func (s *FileSink) Name() string {
+	return SinkFile
+}
+
+// Send appends one line per change and syncs the file
+func (s *FileSink) Send(ctx context.Context, changes []ChangeEvent) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, change := range changes {
+		if err := encoder.Encode(change); err != nil {
+			return fmt.Errorf("failed to marshal change: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.file.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write changes: %w", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync change file: %w", err)
+	}
+	return nil
+}
+
+// Close closes the file
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// ArchiveSink archives the events removed from the table, whether deleted or expired by TTL
+type ArchiveSink struct {
+	archiver *archive.Archiver
+}
+
+// NewArchiveSink creates a sink writing removed events to archiver
+func NewArchiveSink(archiver *archive.Archiver) *ArchiveSink {
+	return &ArchiveSink{archiver: archiver}
+}
+
+// Name returns the sink name
+func (s *ArchiveSink) Name() string {
+	return SinkArchive
+}
+
+// Send archives the old image of every removal and ignores other changes
+func (s *ArchiveSink) Send(ctx context.Context, changes []ChangeEvent) error {
+	var records []archive.Record
+	for _, change := range changes {
+		if change.Type != ChangeTypeRemove || change.OldImage == nil {
+			continue
+		}
+		records = append(records, archive.Record{
+			Event:          change.OldImage,
+			RemovedAt:      change.ChangedAt,
+			Expired:        change.Expired,
+			SequenceNumber: change.SequenceNumber,
+		})
+	}
+	return s.archiver.Archive(ctx, records)
+}
+
+// Close rolls the archive files that are still being filled
+func (s *ArchiveSink) Close() error {
+	return s.archiver.Close(context.Background())
+}
+
+// ClientCache caches client configurations; *clients.CachedRepository is one
+type ClientCache interface {
+	Invalidate(clientID string)
+}
+
+// InvalidationSink drops the cached configuration of every client changed in the clients table, so
+// that a change made through one replica reaches the caches of the others
+type InvalidationSink struct {
+	cache ClientCache
+}
+
+// NewInvalidationSink creates a sink invalidating cache
+func NewInvalidationSink(cache ClientCache) *InvalidationSink {
+	return &InvalidationSink{cache: cache}
+}
+
+// Name returns the sink name
+func (s *InvalidationSink) Name() string {
+	return SinkInvalidation
+}
+
+// Send invalidates the changed clients and ignores changes to other tables
+func (s *InvalidationSink) Send(ctx context.Context, changes []ChangeEvent) error {
+	for _, change := range changes {
+		if change.ClientID != "" {
+			s.cache.Invalidate(change.ClientID)
+		}
+	}
+	return nil
+}
+
+// NewSinks creates the sinks listed in cfg.StreamSinks
+func NewSinks(cfg *config.Config, sqsClient SQSSender, archiveStore archive.Store, logger *logrus.Logger) ([]Sink, error) {
+	var sinks []Sink
+	for _, name := range strings.Split(cfg.StreamSinks, ",") {
+		switch strings.TrimSpace(name) {
+		case "":
+			continue
+		case SinkSQS:
+			if cfg.StreamSQSQueueURL == "" {
+				return nil, fmt.Errorf("the %s sink requires STREAM_SQS_QUEUE_URL", SinkSQS)
+			}
+			sinks = append(sinks, NewSQSSink(sqsClient, cfg.StreamSQSQueueURL))
+		case SinkWebhook:
+			if cfg.StreamWebhookURL == "" {
+				return nil, fmt.Errorf("the %s sink requires STREAM_WEBHOOK_URL", SinkWebhook)
+			}
+			sinks = append(sinks, NewWebhookSink(&http.Client{Timeout: 10 * time.Second}, cfg.StreamWebhookURL))
+		case SinkFile:
+			sink, err := NewFileSink(cfg.StreamFilePath)
+			if err != nil {
+				return nil, err
+			}
+			sinks = append(sinks, sink)
+		case SinkArchive:
+			options := archive.ArchiverOptions{
+				SpoolDir:     cfg.ArchiveSpoolDir,
+				MaxFileBytes: int64(cfg.ArchiveRollSizeMB) << 20,
+				MaxFileAge:   time.Duration(cfg.ArchiveRollIntervalSeconds) * time.Second,
+			}
+			archiver, err := archive.NewArchiver(archiveStore, cfg.ArchiveFormat, options, logger)
+			if err != nil {
+				return nil, err
+			}
+			sinks = append(sinks, NewArchiveSink(archiver))
+		default:
+			return nil, fmt.Errorf("unknown stream sink %q", name)
+		}
+	}
+
+	if len(sinks) == 0 {
+		return nil, fmt.Errorf("no stream sinks configured")
+	}
+	return sinks, nil
+}
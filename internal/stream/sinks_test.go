@@ -0,0 +1,237 @@
+package stream
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d-sense/event-processor/internal/archive"
+	"github.com/d-sense/event-processor/internal/config"
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// MockSQSSender is a mock implementation of the SQSSender interface
+type MockSQSSender struct {
+	mock.Mock
+}
+
+func (m *MockSQSSender) SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*sqs.SendMessageOutput), args.Error(1)
+}
+
+func testChanges() []ChangeEvent {
+	return []ChangeEvent{
+		{Type: ChangeTypeInsert, EventID: "evt-1", SequenceNumber: "100"},
+		{Type: ChangeTypeRemove, EventID: "evt-2", SequenceNumber: "101", Expired: true},
+	}
+}
+
+// TestSQSSink tests that each change is sent as a message tagged with its change type
+func TestSQSSink(t *testing.T) {
+	sender := &MockSQSSender{}
+	sender.On("SendMessage", mock.Anything, mock.MatchedBy(func(input *sqs.SendMessageInput) bool {
+		var change ChangeEvent
+		if err := json.Unmarshal([]byte(aws.ToString(input.MessageBody)), &change); err != nil {
+			return false
+		}
+		return aws.ToString(input.QueueUrl) == "changes-queue" &&
+			aws.ToString(input.MessageAttributes["ChangeType"].StringValue) == string(change.Type)
+	})).Return(&sqs.SendMessageOutput{}, nil).Twice()
+
+	sink := NewSQSSink(sender, "changes-queue")
+	require.NoError(t, sink.Send(context.Background(), testChanges()))
+	sender.AssertExpectations(t)
+
+	failing := &MockSQSSender{}
+	failing.On("SendMessage", mock.Anything, mock.Anything).Return(nil, errors.New("sqs error"))
+	err := NewSQSSink(failing, "changes-queue").Send(context.Background(), testChanges())
+	assert.ErrorContains(t, err, "failed to send change 100 to SQS")
+}
+
+// recordingCache records the clients it is asked to invalidate
+type recordingCache struct {
+	invalidated []string
+}
+
+func (c *recordingCache) Invalidate(clientID string) {
+	c.invalidated = append(c.invalidated, clientID)
+}
+
+// TestInvalidationSink tests that changed clients are dropped from the cache
+func TestInvalidationSink(t *testing.T) {
+	cache := &recordingCache{}
+	changes := []ChangeEvent{
+		{Type: ChangeTypeModify, ClientID: "client-001", SequenceNumber: "100"},
+		{Type: ChangeTypeInsert, EventID: "evt-1", SequenceNumber: "101"},
+		{Type: ChangeTypeRemove, ClientID: "client-002", SequenceNumber: "102"},
+	}
+
+	require.NoError(t, NewInvalidationSink(cache).Send(context.Background(), changes))
+	assert.Equal(t, []string{"client-001", "client-002"}, cache.invalidated)
+}
+
+// TestWebhookSink tests that changes are posted as a single JSON request
+func TestWebhookSink(t *testing.T) {
+	var received webhookRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.Client(), server.URL)
+	require.NoError(t, sink.Send(context.Background(), testChanges()))
+	assert.Equal(t, testChanges(), received.Changes)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+
+	err := NewWebhookSink(failing.Client(), failing.URL).Send(context.Background(), testChanges())
+	assert.ErrorContains(t, err, "webhook returned status 502")
+}
+
+// TestFileSink tests that changes are appended as newline delimited JSON
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "changes.ndjson")
+
+	sink, err := NewFileSink(path)
+	require.NoError(t, err)
+	require.NoError(t, sink.Send(context.Background(), testChanges()))
+	require.NoError(t, sink.Send(context.Background(), testChanges()[:1]))
+	require.NoError(t, sink.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var lines []ChangeEvent
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var change ChangeEvent
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &change))
+		lines = append(lines, change)
+	}
+	assert.Equal(t, append(testChanges(), testChanges()[0]), lines)
+}
+
+// TestArchiveSink tests that only removals are archived
+func TestArchiveSink(t *testing.T) {
+	store := archive.NewFileStore(t.TempDir())
+	archiver, err := archive.NewArchiver(store, archive.FormatNDJSON, archive.ArchiverOptions{SpoolDir: t.TempDir()}, logrus.New())
+	require.NoError(t, err)
+
+	removed := &models.ProcessedEvent{Event: models.Event{EventID: "evt-2", EventType: models.EventTypeTransaction, ClientID: "client-001"}}
+	changes := []ChangeEvent{
+		{Type: ChangeTypeInsert, EventID: "evt-1", SequenceNumber: "100", NewImage: &models.ProcessedEvent{}},
+		{Type: ChangeTypeRemove, EventID: "evt-2", SequenceNumber: "101", Expired: true, OldImage: removed,
+			ChangedAt: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)},
+	}
+	sink := NewArchiveSink(archiver)
+	require.NoError(t, sink.Send(context.Background(), changes))
+	require.NoError(t, sink.Close())
+
+	records, err := archive.Restore(context.Background(), store, archive.Filter{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "101", records[0].SequenceNumber)
+	assert.True(t, records[0].Expired)
+	assert.Equal(t, "evt-2", records[0].Event.EventID)
+}
+
+// TestNewSinks tests building sinks from configuration
+func TestNewSinks(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "changes.ndjson")
+
+	tests := []struct {
+		name        string
+		cfg         *config.Config
+		expected    []string
+		errorMsg    string
+		description string
+	}{
+		{
+			name: "All Sinks",
+			cfg: &config.Config{
+				StreamSinks:       "sqs, webhook,file",
+				StreamSQSQueueURL: "changes-queue",
+				StreamWebhookURL:  "http://localhost:9000/changes",
+				StreamFilePath:    filePath,
+			},
+			expected:    []string{SinkSQS, SinkWebhook, SinkFile},
+			description: "Should create every listed sink in order",
+		},
+		{
+			name:        "SQS Without Queue",
+			cfg:         &config.Config{StreamSinks: "sqs"},
+			errorMsg:    "requires STREAM_SQS_QUEUE_URL",
+			description: "Should require a queue URL for the SQS sink",
+		},
+		{
+			name:        "Webhook Without URL",
+			cfg:         &config.Config{StreamSinks: "webhook"},
+			errorMsg:    "requires STREAM_WEBHOOK_URL",
+			description: "Should require a URL for the webhook sink",
+		},
+		{
+			name:        "Unknown Archive Format",
+			cfg:         &config.Config{StreamSinks: "archive", ArchiveFormat: "csv"},
+			errorMsg:    `unknown archive format "csv"`,
+			description: "Should reject unknown archive formats",
+		},
+		{
+			name:        "Unknown Sink",
+			cfg:         &config.Config{StreamSinks: "kafka"},
+			errorMsg:    `unknown stream sink "kafka"`,
+			description: "Should reject unknown sinks",
+		},
+		{
+			name:        "No Sinks",
+			cfg:         &config.Config{StreamSinks: " , "},
+			errorMsg:    "no stream sinks configured",
+			description: "Should require at least one sink",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sinks, err := NewSinks(tt.cfg, &MockSQSSender{}, archive.NewFileStore(t.TempDir()), logrus.New())
+
+			if tt.errorMsg != "" {
+				assert.ErrorContains(t, err, tt.errorMsg)
+				return
+			}
+			require.NoError(t, err)
+
+			var names []string
+			for _, sink := range sinks {
+				names = append(names, sink.Name())
+				if fileSink, ok := sink.(*FileSink); ok {
+					fileSink.Close()
+				}
+			}
+			assert.Equal(t, tt.expected, names)
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package validator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/xeipuuv/gojsonschema"
+
+	"github.com/d-sense/event-processor/internal/metrics"
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// maxActualLength bounds the value sent that a violation keeps, as whole objects can break a rule
+const maxActualLength = 100
+
+// rootField is how gojsonschema names the validated document itself
+const rootField = "(root)"
+
+// ValidationError is returned for events that do not match a schema, listing every rule they break
+type ValidationError struct {
+	models.ValidationFailure
+
+	// fields are the fields declared by the schema, labelling the counted violations
+	fields declaredFields
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		messages = append(messages, violation.Message)
+	}
+
+	if e.Schema != models.ValidationSchemaPayload {
+		return fmt.Sprintf("validation failed: %v", messages)
+	}
+	message := fmt.Sprintf("payload validation failed for version %s of %s events: %v", e.SchemaVersion, e.EventType, messages)
+	if e.ClientSchema != "" {
+		return fmt.Sprintf("client %s: %s", e.ClientSchema, message)
+	}
+	return message
+}
+
+// Failure returns the description of the ValidationError in err's chain, or nil if it has none
+func Failure(err error) *models.ValidationFailure {
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		return nil
+	}
+	return &validationErr.ValidationFailure
+}
+
+// newValidationError describes the errors of a failed validation against a schema declaring fields;
+// prefix is the path of the validated document within the event, empty for the event itself
+func newValidationError(result *gojsonschema.Result, failure models.ValidationFailure, prefix string, fields declaredFields) *ValidationError {
+	failure.Violations = make([]models.Violation, 0, len(result.Errors()))
+	for _, err := range result.Errors() {
+		details := err.Details()
+		violation := models.Violation{
+			Field:    fieldPath(prefix, err.Field()),
+			Rule:     err.Type(),
+			Expected: expectedValue(details),
+			Message:  err.String(),
+		}
+
+		property, hasProperty := details["property"].(string)
+		switch {
+		case err.Type() == "required" && hasProperty:
+			// The error is on the object missing the field
+			violation.Field = fieldPath(violation.Field, property)
+		case hasProperty:
+			violation.Actual = property
+		case details["given"] != nil:
+			violation.Actual = fmt.Sprint(details["given"])
+		default:
+			violation.Actual = actualValue(err.Value())
+		}
+		failure.Violations = append(failure.Violations, violation)
+	}
+	// Errors are found in map order, so they are sorted for stable reports
+	sort.SliceStable(failure.Violations, func(i, j int) bool { return failure.Violations[i].Field < failure.Violations[j].Field })
+	return &ValidationError{ValidationFailure: failure, fields: fields}
+}
+
+// fieldPath returns the path of a field of the document at prefix
+func fieldPath(prefix, field string) string {
+	switch {
+	case field == rootField && prefix != "":
+		return prefix
+	case prefix == "" || prefix == rootField:
+		return field
+	default:
+		return prefix + "." + field
+	}
+}
+
+// expectedValue returns what the broken rule allows, if its details say
+func expectedValue(details gojsonschema.ErrorDetails) string {
+	for _, key := range []string{"expected", "allowed", "pattern", "format", "min", "max", "multiple"} {
+		if value, ok := details[key]; ok {
+			return fmt.Sprint(value)
+		}
+	}
+	return ""
+}
+
+// actualValue returns the value sent as JSON, truncated to maxActualLength
+func actualValue(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	if len(encoded) > maxActualLength {
+		return string(encoded[:maxActualLength])
+	}
+	return string(encoded)
+}
+
+// countViolations counts the broken rules of an error by field and rule. Fields are counted by the
+// path the schema declares them at, with array indexes replaced by *, and fields the schema does not
+// declare as other, so that clients choosing payload field names cannot add label values.
+func countViolations(validationErr *ValidationError) {
+	eventType := metrics.Unknown
+	if models.IsValidEventType(string(validationErr.EventType)) {
+		eventType = string(validationErr.EventType)
+	}
+	for _, violation := range validationErr.Violations {
+		field := validationErr.fields.label(violation.Field)
+		metrics.ValidationViolations.WithLabelValues(validationErr.Schema, eventType, field, violation.Rule).Inc()
+	}
+}
+
+// eventTypeOf reads the event type of an event body on a best effort basis
+func eventTypeOf(eventBytes []byte) models.EventType {
+	var fields struct {
+		EventType string `json:"eventType"`
+	}
+	if json.Unmarshal(eventBytes, &fields) != nil {
+		return ""
+	}
+	return models.EventType(fields.EventType)
+}
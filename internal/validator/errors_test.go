@@ -0,0 +1,151 @@
+package validator
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d-sense/event-processor/internal/metrics"
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// TestValidationError tests the violations reported for events that do not match a schema
+func TestValidationError(t *testing.T) {
+	tests := []struct {
+		name            string
+		eventJSON       string
+		expectedFailure models.ValidationFailure
+		errorMsg        string
+		description     string
+	}{
+		{
+			name:      "Event Schema",
+			eventJSON: `{"eventId":"not-a-uuid","eventType":"transaction","clientId":"client-001","timestamp":"2025-01-21T10:00:00Z","payload":{"amount":1},"version":"1","extra":true}`,
+			expectedFailure: models.ValidationFailure{
+				Schema:    models.ValidationSchemaEvent,
+				EventType: models.EventTypeTransaction,
+				Violations: []models.Violation{
+					{Field: "(root)", Rule: "additional_property_not_allowed", Actual: "extra", Message: "(root): Additional property extra is not allowed"},
+					{Field: "eventId", Rule: "pattern", Expected: "^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$", Actual: `"not-a-uuid"`, Message: "eventId: Does not match pattern '^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$'"},
+					{Field: "version", Rule: "pattern", Expected: `^\d+\.\d+$`, Actual: `"1"`, Message: `version: Does not match pattern '^\d+\.\d+$'`},
+				},
+			},
+			errorMsg:    "validation failed: [(root): Additional property extra is not allowed",
+			description: "Should report every rule of the event schema the event breaks",
+		},
+		{
+			name:      "Missing Event Field",
+			eventJSON: `{"eventId":"123e4567-e89b-12d3-a456-426614174000","eventType":"transaction","timestamp":"2025-01-21T10:00:00Z","payload":{"amount":1},"version":"1.1"}`,
+			expectedFailure: models.ValidationFailure{
+				Schema:    models.ValidationSchemaEvent,
+				EventType: models.EventTypeTransaction,
+				Violations: []models.Violation{
+					{Field: "clientId", Rule: "required", Message: "(root): clientId is required"},
+				},
+			},
+			errorMsg:    "validation failed: [(root): clientId is required]",
+			description: "Should report missing fields under their own name",
+		},
+		{
+			name:      "Payload Schema",
+			eventJSON: `{"eventId":"123e4567-e89b-12d3-a456-426614174000","eventType":"transaction","clientId":"client-001","timestamp":"2025-01-21T10:00:00Z","payload":{"transactionId":"txn-1","amount":true},"version":"1.1"}`,
+			expectedFailure: models.ValidationFailure{
+				Schema:        models.ValidationSchemaPayload,
+				EventType:     models.EventTypeTransaction,
+				SchemaVersion: "1.1",
+				Violations: []models.Violation{
+					{Field: "payload.amount", Rule: "invalid_type", Expected: "number", Actual: "boolean", Message: "amount: Invalid type. Expected: number, given: boolean"},
+					{Field: "payload.currency", Rule: "required", Message: "(root): currency is required"},
+				},
+			},
+			errorMsg:    "payload validation failed for version 1.1 of transaction events",
+			description: "Should report payload fields under the payload, with the version validated against",
+		},
+		{
+			name:      "Client Schema",
+			eventJSON: `{"eventId":"123e4567-e89b-12d3-a456-426614174000","eventType":"transaction","clientId":"client-002","timestamp":"2025-01-21T10:00:00Z","payload":{"transactionId":"txn-1","amount":10,"currency":"EUR"},"version":"1.1"}`,
+			expectedFailure: models.ValidationFailure{
+				Schema:        models.ValidationSchemaPayload,
+				EventType:     models.EventTypeTransaction,
+				SchemaVersion: "1.1",
+				ClientSchema:  "client-002",
+				Violations: []models.Violation{
+					{Field: "payload.status", Rule: "required", Message: "(root): status is required"},
+				},
+			},
+			errorMsg:    "client client-002: payload validation failed for version 1.1 of transaction events: [(root): status is required]",
+			description: "Should name the client whose schema the payload fails",
+		},
+	}
+
+	dir := createTestSchemaDir(t)
+	writeSchema(t, dir, "payloads/transaction/1.1.json", `{
+		"type": "object",
+		"required": ["transactionId", "amount", "currency"],
+		"properties": {"amount": {"type": "number"}}
+	}`)
+	writeSchema(t, dir, "payloads/clients/client-002/transaction/1.1.json", `{"required": ["status"]}`)
+	validator, err := New(dir)
+	require.NoError(t, err)
+	fingerprint := validator.Status().Fingerprint
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := validator.ValidateAndParseEvent(tt.eventJSON)
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errorMsg, tt.description)
+			failure := Failure(err)
+			require.NotNil(t, failure, tt.description)
+			tt.expectedFailure.SchemaFingerprint = fingerprint
+			assert.Equal(t, tt.expectedFailure, *failure, tt.description)
+		})
+	}
+}
+
+// TestFailure tests finding the violations of an error
+func TestFailure(t *testing.T) {
+	validationErr := &ValidationError{ValidationFailure: models.ValidationFailure{Schema: models.ValidationSchemaEvent}}
+
+	assert.Equal(t, &validationErr.ValidationFailure, Failure(fmt.Errorf("validation failed: %w", validationErr)), "Wrapped errors should be unwrapped")
+	assert.Nil(t, Failure(errors.New("timestamp cannot be zero")), "Errors of other rules have no violations")
+	assert.Nil(t, Failure(nil))
+}
+
+// TestCountViolations tests that violations are counted by the declared field, without array indexes
+func TestCountViolations(t *testing.T) {
+	fields := newDeclaredFields([]byte(`{
+		"type": "object",
+		"properties": {"items": {"type": "array", "items": {"type": "object", "required": ["id"]}}}
+	}`), "payload")
+	counter := metrics.ValidationViolations.WithLabelValues(models.ValidationSchemaPayload, "integration", "payload.items.*.id", "required")
+	other := metrics.ValidationViolations.WithLabelValues(models.ValidationSchemaPayload, "integration", "other", "required")
+	unknown := metrics.ValidationViolations.WithLabelValues(models.ValidationSchemaEvent, metrics.Unknown, "other", "enum")
+	before, beforeOther, beforeUnknown := testutil.ToFloat64(counter), testutil.ToFloat64(other), testutil.ToFloat64(unknown)
+
+	countViolations(&ValidationError{
+		ValidationFailure: models.ValidationFailure{
+			Schema:    models.ValidationSchemaPayload,
+			EventType: models.EventTypeIntegration,
+			Violations: []models.Violation{
+				{Field: "payload.items.0.id", Rule: "required"},
+				{Field: "payload.items.12.id", Rule: "required"},
+				{Field: "payload.customerName", Rule: "required"},
+			},
+		},
+		fields: fields,
+	})
+	countViolations(&ValidationError{ValidationFailure: models.ValidationFailure{
+		Schema:     models.ValidationSchemaEvent,
+		EventType:  "made_up",
+		Violations: []models.Violation{{Field: "eventType", Rule: "enum"}},
+	}})
+
+	assert.Equal(t, before+2, testutil.ToFloat64(counter))
+	assert.Equal(t, beforeOther+1, testutil.ToFloat64(other), "Fields the schema does not declare should be counted as other")
+	assert.Equal(t, beforeUnknown+1, testutil.ToFloat64(unknown), "Event types that do not exist should not be used as labels")
+}
@@ -0,0 +1,127 @@
+package validator
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// otherField is the field label of violations on fields a schema does not declare
+const otherField = "other"
+
+// maxRefDepth bounds how deeply nested $refs are followed, as recursive schemas declare endless paths
+const maxRefDepth = 8
+
+// declaredFields is the set of field paths a schema declares, with array indexes written as *. They
+// bound the field label of violations, as the field names of payloads are chosen by clients.
+type declaredFields map[string]bool
+
+// newDeclaredFields returns the fields declared by a schema for a document at prefix, empty for the
+// event itself. The properties, required fields and items of the schema and its subschemas are
+// declared; schemas that do not parse declare no fields.
+func newDeclaredFields(schemaBytes []byte, prefix string) declaredFields {
+	fields := declaredFields{fieldPath(prefix, rootField): true}
+	var schema interface{}
+	if json.Unmarshal(schemaBytes, &schema) != nil {
+		return fields
+	}
+	fields.walk(schema, schema, prefix, 0)
+	return fields
+}
+
+// walk declares the fields of the subschema node describing the document at path
+func (f declaredFields) walk(root, node interface{}, path string, refDepth int) {
+	schema, ok := node.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	if ref, ok := schema["$ref"].(string); ok && refDepth < maxRefDepth {
+		if target, ok := resolveRef(root, ref); ok {
+			f.walk(root, target, path, refDepth+1)
+		}
+	}
+
+	if properties, ok := schema["properties"].(map[string]interface{}); ok {
+		for name, property := range properties {
+			f.declare(path, name)
+			f.walk(root, property, fieldPath(path, name), refDepth)
+		}
+	}
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			if name, ok := name.(string); ok {
+				f.declare(path, name)
+			}
+		}
+	}
+
+	items := []interface{}{schema["items"], schema["additionalItems"]}
+	if tuple, ok := schema["items"].([]interface{}); ok {
+		items = append(items, tuple...)
+	}
+	for _, item := range items {
+		if _, ok := item.(map[string]interface{}); ok {
+			f.declare(path, "*")
+			f.walk(root, item, fieldPath(path, "*"), refDepth)
+		}
+	}
+
+	// Subschemas applying to the same document
+	for _, key := range []string{"allOf", "anyOf", "oneOf"} {
+		if subschemas, ok := schema[key].([]interface{}); ok {
+			for _, subschema := range subschemas {
+				f.walk(root, subschema, path, refDepth)
+			}
+		}
+	}
+	for _, key := range []string{"if", "then", "else"} {
+		f.walk(root, schema[key], path, refDepth)
+	}
+}
+
+// declare adds the field name of the document at path
+func (f declaredFields) declare(path, name string) {
+	f[collapseIndexes(fieldPath(path, name))] = true
+}
+
+// label returns the field label of a violation on field
+func (f declaredFields) label(field string) string {
+	if collapsed := collapseIndexes(field); f[collapsed] {
+		return collapsed
+	}
+	return otherField
+}
+
+// collapseIndexes replaces the array indexes of a field path with *
+func collapseIndexes(field string) string {
+	segments := strings.Split(field, ".")
+	for i, segment := range segments {
+		if segment != "" && strings.Trim(segment, "0123456789") == "" {
+			segments[i] = "*"
+		}
+	}
+	return strings.Join(segments, ".")
+}
+
+// resolveRef returns the subschema a local JSON pointer reference such as #/definitions/item points to
+func resolveRef(root interface{}, ref string) (interface{}, bool) {
+	pointer, ok := strings.CutPrefix(ref, "#")
+	if !ok {
+		return nil, false
+	}
+	node := root
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if token == "" {
+			continue
+		}
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		object, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if node, ok = object[token]; !ok {
+			return nil, false
+		}
+	}
+	return node, true
+}
@@ -0,0 +1,117 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDeclaredFields tests the field labels of violations
+func TestDeclaredFields(t *testing.T) {
+	schema := []byte(`{
+		"type": "object",
+		"required": ["transactionId", "currency"],
+		"properties": {
+			"transactionId": {"type": "string"},
+			"lines": {"type": "array", "items": {"$ref": "#/definitions/line"}},
+			"node": {"$ref": "#/definitions/node"}
+		},
+		"allOf": [{"properties": {"discount": {"type": "number"}}}],
+		"definitions": {
+			"line": {"type": "object", "properties": {"sku": {"type": "string"}}},
+			"node": {"type": "object", "properties": {"child": {"$ref": "#/definitions/node"}}}
+		}
+	}`)
+
+	tests := []struct {
+		name        string
+		prefix      string
+		field       string
+		expected    string
+		description string
+	}{
+		{
+			name:        "Property",
+			prefix:      "payload",
+			field:       "payload.transactionId",
+			expected:    "payload.transactionId",
+			description: "Should label declared properties with their path",
+		},
+		{
+			name:        "Required Without Property",
+			prefix:      "payload",
+			field:       "payload.currency",
+			expected:    "payload.currency",
+			description: "Should declare required fields",
+		},
+		{
+			name:        "Array Item Through Reference",
+			prefix:      "payload",
+			field:       "payload.lines.3.sku",
+			expected:    "payload.lines.*.sku",
+			description: "Should replace array indexes with * and follow local references",
+		},
+		{
+			name:        "Subschema Property",
+			prefix:      "payload",
+			field:       "payload.discount",
+			expected:    "payload.discount",
+			description: "Should declare the properties of subschemas",
+		},
+		{
+			name:        "Recursive Reference",
+			prefix:      "payload",
+			field:       "payload.node.child.child",
+			expected:    "payload.node.child.child",
+			description: "Should follow recursive references up to a bounded depth",
+		},
+		{
+			name:        "Too Deep Recursive Reference",
+			prefix:      "payload",
+			field:       "payload.node" + strings.Repeat(".child", maxRefDepth+1),
+			expected:    otherField,
+			description: "Should stop following recursive references",
+		},
+		{
+			name:        "Undeclared Field",
+			prefix:      "payload",
+			field:       "payload.customer-chosen-name",
+			expected:    otherField,
+			description: "Should label fields the schema does not declare as other",
+		},
+		{
+			name:        "Payload Root",
+			prefix:      "payload",
+			field:       "payload",
+			expected:    "payload",
+			description: "Should label the validated document itself",
+		},
+		{
+			name:        "Event Root",
+			field:       rootField,
+			expected:    rootField,
+			description: "Should label the event itself",
+		},
+		{
+			name:        "Event Field",
+			field:       "transactionId",
+			expected:    "transactionId",
+			description: "Should label fields of the event without a prefix",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := newDeclaredFields(schema, tt.prefix)
+			assert.Equal(t, tt.expected, fields.label(tt.field), tt.description)
+		})
+	}
+}
+
+// TestDeclaredFieldsInvalidSchema tests that schemas that do not parse only declare the document itself
+func TestDeclaredFieldsInvalidSchema(t *testing.T) {
+	fields := newDeclaredFields([]byte(`{`), "payload")
+	assert.Equal(t, "payload", fields.label("payload"))
+	assert.Equal(t, otherField, fields.label("payload.amount"))
+}
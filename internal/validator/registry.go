@@ -0,0 +1,424 @@
+package validator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// ErrUnsupportedVersion is returned for events of a version their event type has neither a schema
+// nor an upcaster for
+var ErrUnsupportedVersion = errors.New("unsupported version")
+
+// UpcastFunc transforms the payload of an event to the next version of its event type. The payload
+// is a copy the function may modify.
+type UpcastFunc func(payload map[string]interface{}) (map[string]interface{}, error)
+
+type upcaster struct {
+	to string
+	fn UpcastFunc
+}
+
+// clientsDir is the payload schema directory holding the schemas of clients with stricter contracts
+const clientsDir = "clients"
+
+// payloadSchema is a compiled payload schema and the fields it declares
+type payloadSchema struct {
+	schema *gojsonschema.Schema
+	fields declaredFields
+}
+
+// versionedSchemas holds payload schemas by event type and version
+type versionedSchemas map[models.EventType]map[string]*payloadSchema
+
+// Registry holds the payload schemas of each version of an event type, and the upcasters that
+// transform payloads of older versions into the current, canonical shape. Clients with stricter
+// contracts can have schemas of their own, which their payloads must match as well.
+type Registry struct {
+	schemas       versionedSchemas
+	clientSchemas map[string]versionedSchemas
+	upcasters     map[models.EventType]map[string]upcaster
+}
+
+// NewRegistry creates an empty registry, which accepts every version of every event type
+func NewRegistry() *Registry {
+	return &Registry{
+		schemas:       make(versionedSchemas),
+		clientSchemas: make(map[string]versionedSchemas),
+		upcasters:     make(map[models.EventType]map[string]upcaster),
+	}
+}
+
+// LoadRegistry creates a registry with the built-in upcasters and the payload schemas in dir, stored
+// as <event type>/<version>.json, and the schemas of clients, stored as
+// clients/<client id>/<event type>/<version>.json. A missing dir has no schemas.
+func LoadRegistry(dir string) (*Registry, error) {
+	registry := NewRegistry()
+	if err := registerBuiltinUpcasters(registry); err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		return registry, nil
+	}
+
+	err := loadSchemaDir(dir, registry.RegisterSchema)
+	if errors.Is(err, fs.ErrNotExist) {
+		return registry, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	clients, err := os.ReadDir(filepath.Join(dir, clientsDir))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read client payload schema directory: %w", err)
+	}
+	for _, client := range clients {
+		if !client.IsDir() {
+			continue
+		}
+		clientID := client.Name()
+		err := loadSchemaDir(filepath.Join(dir, clientsDir, clientID), func(eventType models.EventType, version string, schemaBytes []byte) error {
+			return registry.RegisterClientSchema(clientID, eventType, version, schemaBytes)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("client %s: %w", clientID, err)
+		}
+	}
+	return registry, nil
+}
+
+// loadSchemaDir registers the schemas in dir, stored as <event type>/<version>.json
+func loadSchemaDir(dir string, register func(eventType models.EventType, version string, schemaBytes []byte) error) error {
+	typeDirs, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read payload schema directory: %w", err)
+	}
+	for _, typeDir := range typeDirs {
+		if !typeDir.IsDir() || typeDir.Name() == clientsDir {
+			continue
+		}
+		eventType := models.EventType(typeDir.Name())
+		if !models.IsValidEventType(string(eventType)) {
+			return fmt.Errorf("payload schema directory %s is not named after an event type", typeDir.Name())
+		}
+
+		files, err := os.ReadDir(filepath.Join(dir, typeDir.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read payload schema directory: %w", err)
+		}
+		for _, file := range files {
+			if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+				continue
+			}
+			schemaBytes, err := os.ReadFile(filepath.Join(dir, typeDir.Name(), file.Name()))
+			if err != nil {
+				return fmt.Errorf("failed to load payload schema: %w", err)
+			}
+			if err := register(eventType, strings.TrimSuffix(file.Name(), ".json"), schemaBytes); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RegisterSchema adds the payload schema of a version of an event type
+func (r *Registry) RegisterSchema(eventType models.EventType, version string, schemaBytes []byte) error {
+	return r.schemas.register(eventType, version, schemaBytes)
+}
+
+// RegisterClientSchema adds a schema the payloads of a version of an event type must match as well
+// when sent by the client
+func (r *Registry) RegisterClientSchema(clientID string, eventType models.EventType, version string, schemaBytes []byte) error {
+	if r.clientSchemas[clientID] == nil {
+		r.clientSchemas[clientID] = make(versionedSchemas)
+	}
+	return r.clientSchemas[clientID].register(eventType, version, schemaBytes)
+}
+
+func (s versionedSchemas) register(eventType models.EventType, version string, schemaBytes []byte) error {
+	if !models.IsValidEventType(string(eventType)) {
+		return fmt.Errorf("invalid event type for payload schema: %s", eventType)
+	}
+	canonical, err := canonicalVersion(version)
+	if err != nil {
+		return fmt.Errorf("invalid payload schema version for %s events: %w", eventType, err)
+	}
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(schemaBytes))
+	if err != nil {
+		return fmt.Errorf("failed to compile payload schema %s of %s events: %w", version, eventType, err)
+	}
+
+	if s[eventType] == nil {
+		s[eventType] = make(map[string]*payloadSchema)
+	}
+	s[eventType][canonical] = &payloadSchema{schema: schema, fields: newDeclaredFields(schemaBytes, "payload")}
+	return nil
+}
+
+// RegisterUpcaster adds the function transforming payloads of an event type from one version to a
+// later one; each version can be upcast by a single function
+func (r *Registry) RegisterUpcaster(eventType models.EventType, from, to string, fn UpcastFunc) error {
+	fromVersion, err := canonicalVersion(from)
+	if err != nil {
+		return fmt.Errorf("invalid upcaster version for %s events: %w", eventType, err)
+	}
+	toVersion, err := canonicalVersion(to)
+	if err != nil {
+		return fmt.Errorf("invalid upcaster version for %s events: %w", eventType, err)
+	}
+	if compareVersions(toVersion, fromVersion) <= 0 {
+		return fmt.Errorf("upcaster of %s events must go to a later version than %s, not %s", eventType, from, to)
+	}
+	if _, exists := r.upcasters[eventType][fromVersion]; exists {
+		return fmt.Errorf("version %s of %s events already has an upcaster", from, eventType)
+	}
+
+	if r.upcasters[eventType] == nil {
+		r.upcasters[eventType] = make(map[string]upcaster)
+	}
+	r.upcasters[eventType][fromVersion] = upcaster{to: toVersion, fn: fn}
+	return nil
+}
+
+// CurrentVersion returns the latest version of an event type known to the registry, or "" if it
+// knows none
+func (r *Registry) CurrentVersion(eventType models.EventType) string {
+	current := ""
+	consider := func(version string) {
+		if current == "" || compareVersions(version, current) > 0 {
+			current = version
+		}
+	}
+	for version := range r.schemas[eventType] {
+		consider(version)
+	}
+	for _, next := range r.upcasters[eventType] {
+		consider(next.to)
+	}
+	return current
+}
+
+// Check validates the payload of an event against the schema of its version, and the client's schema
+// of that version if it has one. Event types without schemas accept every version; otherwise the
+// version must have a schema or an upcaster.
+func (r *Registry) Check(event *models.Event) error {
+	schemas := r.schemas[event.EventType]
+	clientSchemas := r.clientSchemas[event.ClientID][event.EventType]
+	if len(schemas) == 0 && len(clientSchemas) == 0 {
+		return nil
+	}
+
+	version, err := canonicalVersion(event.Version)
+	if err != nil {
+		return fmt.Errorf("%w %s of %s events", ErrUnsupportedVersion, event.Version, event.EventType)
+	}
+
+	if len(schemas) > 0 {
+		schema, ok := schemas[version]
+		if _, upcast := r.upcasters[event.EventType][version]; !ok && !upcast {
+			return fmt.Errorf("%w %s of %s events", ErrUnsupportedVersion, event.Version, event.EventType)
+		}
+		if ok {
+			if err := validatePayload(schema, event.EventType, version, "", event.Payload); err != nil {
+				return err
+			}
+		}
+	}
+
+	// A client's schema can only make its contract stricter
+	if schema, ok := clientSchemas[version]; ok {
+		return validatePayload(schema, event.EventType, version, event.ClientID, event.Payload)
+	}
+	return nil
+}
+
+// Upcast returns the event with its payload transformed to the current version of its event type,
+// validated against the schema of that version. Events already at the current version, or of a
+// version without an upcaster, are returned as they are.
+func (r *Registry) Upcast(event *models.Event) (*models.Event, error) {
+	chain := r.upcasters[event.EventType]
+	version, err := canonicalVersion(event.Version)
+	if err != nil || len(chain) == 0 {
+		return event, nil
+	}
+	if _, ok := chain[version]; !ok {
+		return event, nil
+	}
+
+	upcast := *event
+	upcast.Payload = copyPayload(event.Payload)
+	// Every upcaster goes to a later version, so the chain ends
+	for next, ok := chain[version]; ok; next, ok = chain[version] {
+		payload, err := next.fn(upcast.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upcast %s event from version %s to %s: %w", event.EventType, version, next.to, err)
+		}
+		upcast.Payload = payload
+		version = next.to
+	}
+	upcast.Version = version
+
+	if schema, ok := r.schemas[event.EventType][version]; ok {
+		if err := validatePayload(schema, event.EventType, version, "", upcast.Payload); err != nil {
+			return nil, fmt.Errorf("upcast from version %s: %w", event.Version, err)
+		}
+	}
+	return &upcast, nil
+}
+
+// EventTypeVersions describes the payload versions of an event type known to a registry
+type EventTypeVersions struct {
+	EventType      models.EventType `json:"eventType"`
+	CurrentVersion string           `json:"currentVersion"`
+
+	// Versions are the versions with a schema
+	Versions []string `json:"versions"`
+	Upcasts  []Upcast `json:"upcasts"`
+}
+
+// Upcast describes an upcaster
+type Upcast struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ClientVersions describes the versions of an event type a client has schemas of
+type ClientVersions struct {
+	ClientID  string           `json:"clientId"`
+	EventType models.EventType `json:"eventType"`
+	Versions  []string         `json:"versions"`
+}
+
+// Versions returns the versions of every event type with schemas or upcasters, ordered by event type
+func (r *Registry) Versions() []EventTypeVersions {
+	eventTypes := make(map[models.EventType]bool)
+	for eventType := range r.schemas {
+		eventTypes[eventType] = true
+	}
+	for eventType := range r.upcasters {
+		eventTypes[eventType] = true
+	}
+
+	versions := make([]EventTypeVersions, 0, len(eventTypes))
+	for eventType := range eventTypes {
+		described := EventTypeVersions{
+			EventType:      eventType,
+			CurrentVersion: r.CurrentVersion(eventType),
+			Versions:       sortedVersions(r.schemas[eventType]),
+			Upcasts:        []Upcast{},
+		}
+		for from, next := range r.upcasters[eventType] {
+			described.Upcasts = append(described.Upcasts, Upcast{From: from, To: next.to})
+		}
+		sort.Slice(described.Upcasts, func(i, j int) bool {
+			return compareVersions(described.Upcasts[i].From, described.Upcasts[j].From) < 0
+		})
+		versions = append(versions, described)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].EventType < versions[j].EventType })
+	return versions
+}
+
+// ClientVersions returns the versions clients have schemas of, ordered by client and event type
+func (r *Registry) ClientVersions() []ClientVersions {
+	versions := []ClientVersions{}
+	for clientID, schemas := range r.clientSchemas {
+		for eventType, byVersion := range schemas {
+			versions = append(versions, ClientVersions{ClientID: clientID, EventType: eventType, Versions: sortedVersions(byVersion)})
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		if versions[i].ClientID != versions[j].ClientID {
+			return versions[i].ClientID < versions[j].ClientID
+		}
+		return versions[i].EventType < versions[j].EventType
+	})
+	return versions
+}
+
+func sortedVersions(schemas map[string]*payloadSchema) []string {
+	versions := make([]string, 0, len(schemas))
+	for version := range schemas {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return compareVersions(versions[i], versions[j]) < 0 })
+	return versions
+}
+
+// validatePayload validates a payload against the schema of a version of its event type, which is
+// the schema of clientID if it is not empty. Payloads that do not match fail with a ValidationError.
+func validatePayload(schema *payloadSchema, eventType models.EventType, version, clientID string, payload map[string]interface{}) error {
+	result, err := schema.schema.Validate(gojsonschema.NewGoLoader(payload))
+	if err != nil {
+		return fmt.Errorf("payload validation error: %w", err)
+	}
+
+	if !result.Valid() {
+		return newValidationError(result, models.ValidationFailure{
+			Schema:        models.ValidationSchemaPayload,
+			EventType:     eventType,
+			SchemaVersion: version,
+			ClientSchema:  clientID,
+		}, "payload", schema.fields)
+	}
+	return nil
+}
+
+// canonicalVersion parses a major.minor version, returning it without leading zeros
+func canonicalVersion(version string) (string, error) {
+	major, minor, err := parseVersion(version)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d.%d", major, minor), nil
+}
+
+func parseVersion(version string) (int, int, error) {
+	majorText, minorText, found := strings.Cut(version, ".")
+	if !found {
+		return 0, 0, fmt.Errorf("version %q is not of the form major.minor", version)
+	}
+	major, err := strconv.Atoi(majorText)
+	if err != nil || major < 0 {
+		return 0, 0, fmt.Errorf("version %q is not of the form major.minor", version)
+	}
+	minor, err := strconv.Atoi(minorText)
+	if err != nil || minor < 0 {
+		return 0, 0, fmt.Errorf("version %q is not of the form major.minor", version)
+	}
+	return major, minor, nil
+}
+
+// compareVersions compares two canonical versions numerically, so that 1.10 is later than 1.9
+func compareVersions(a, b string) int {
+	aMajor, aMinor, _ := parseVersion(a)
+	bMajor, bMinor, _ := parseVersion(b)
+	switch {
+	case aMajor != bMajor:
+		return aMajor - bMajor
+	default:
+		return aMinor - bMinor
+	}
+}
+
+func copyPayload(payload map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(payload))
+	for k, v := range payload {
+		copied[k] = v
+	}
+	return copied
+}
@@ -0,0 +1,365 @@
+package validator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// payloadSchemaDir holds the payload schemas shipped with the service
+const payloadSchemaDir = "../../schemas/payloads"
+
+func createTransaction(version string, payload map[string]interface{}) *models.Event {
+	return &models.Event{
+		EventID:   "123e4567-e89b-12d3-a456-426614174000",
+		EventType: models.EventTypeTransaction,
+		ClientID:  "client-001",
+		Payload:   payload,
+		Version:   version,
+	}
+}
+
+// TestRegistryCheck tests validating payloads against the schema of their version
+func TestRegistryCheck(t *testing.T) {
+	tests := []struct {
+		name        string
+		event       *models.Event
+		expectError bool
+		errorMsg    string
+		description string
+	}{
+		{
+			name:        "Current Version",
+			event:       createTransaction("1.1", map[string]interface{}{"transactionId": "txn-1", "amount": 25.5, "currency": "EUR"}),
+			description: "Should accept payloads matching the schema of their version",
+		},
+		{
+			name:        "Older Version",
+			event:       createTransaction("1.0", map[string]interface{}{"transactionId": "txn-1", "amount": "25.50", "currency": "eur"}),
+			description: "Should accept payloads matching the schema of an older version",
+		},
+		{
+			name:        "Older Shape At Current Version",
+			event:       createTransaction("1.1", map[string]interface{}{"transactionId": "txn-1", "amount": "25.50", "currency": "EUR"}),
+			expectError: true,
+			errorMsg:    "payload validation failed for version 1.1 of transaction events",
+			description: "Should reject payloads in the shape of another version",
+		},
+		{
+			name:        "Unsupported Version",
+			event:       createTransaction("2.0", map[string]interface{}{"transactionId": "txn-1", "amount": 25.5, "currency": "EUR"}),
+			expectError: true,
+			errorMsg:    "unsupported version 2.0 of transaction events",
+			description: "Should reject versions without a schema or upcaster",
+		},
+		{
+			name: "User Action Field Type",
+			event: &models.Event{
+				EventType: models.EventTypeUserAction,
+				Payload:   map[string]interface{}{"userId": 42.0, "action": "login", "resource": "/api/auth"},
+				Version:   "1.0",
+			},
+			expectError: true,
+			errorMsg:    "userId: Invalid type. Expected: string, given: integer",
+			description: "Should check the types of fields, not only their presence",
+		},
+		{
+			name: "Integration Missing Field",
+			event: &models.Event{
+				EventType: models.EventTypeIntegration,
+				Payload:   map[string]interface{}{"source": "crm", "target": "warehouse"},
+				Version:   "1.0",
+			},
+			expectError: true,
+			errorMsg:    "operation is required",
+			description: "Should reject payloads missing a field their event type requires",
+		},
+		{
+			name: "Monitoring Severity",
+			event: &models.Event{
+				EventType: models.EventTypeMonitoring,
+				Payload:   map[string]interface{}{"severity": "urgent", "metric": "cpu.usage"},
+				Version:   "1.0",
+			},
+			expectError: true,
+			errorMsg:    "severity must be one of the following",
+			description: "Should reject unknown severities",
+		},
+	}
+
+	registry, err := LoadRegistry(payloadSchemaDir)
+	require.NoError(t, err)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := registry.Check(tt.event)
+
+			if tt.expectError {
+				assert.Error(t, err, tt.description)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+			} else {
+				assert.NoError(t, err, tt.description)
+			}
+		})
+	}
+}
+
+// TestRegistryClientSchemas tests that client schemas make the contract of a client stricter
+func TestRegistryClientSchemas(t *testing.T) {
+	registry, err := LoadRegistry(payloadSchemaDir)
+	require.NoError(t, err)
+	require.NoError(t, registry.RegisterClientSchema("client-002", models.EventTypeTransaction, "1.1", []byte(`{
+		"type": "object",
+		"properties": {
+			"currency": {"enum": ["EUR", "USD"]},
+			"amount": {"maximum": 50000}
+		}
+	}`)))
+
+	tests := []struct {
+		name        string
+		event       *models.Event
+		expectError bool
+		errorMsg    string
+		description string
+	}{
+		{
+			name: "Within Client Contract",
+			event: func() *models.Event {
+				event := createTransaction("1.1", map[string]interface{}{"transactionId": "txn-1", "amount": 25.5, "currency": "EUR"})
+				event.ClientID = "client-002"
+				return event
+			}(),
+			description: "Should accept payloads matching both schemas",
+		},
+		{
+			name: "Outside Client Contract",
+			event: func() *models.Event {
+				event := createTransaction("1.1", map[string]interface{}{"transactionId": "txn-1", "amount": 25.5, "currency": "GBP"})
+				event.ClientID = "client-002"
+				return event
+			}(),
+			expectError: true,
+			errorMsg:    "client client-002: payload validation failed for version 1.1 of transaction events",
+			description: "Should reject payloads failing the client's schema",
+		},
+		{
+			name: "Failing Event Type Schema",
+			event: func() *models.Event {
+				event := createTransaction("1.1", map[string]interface{}{"amount": 25.5, "currency": "EUR"})
+				event.ClientID = "client-002"
+				return event
+			}(),
+			expectError: true,
+			errorMsg:    "transactionId is required",
+			description: "Should still require the event type's schema",
+		},
+		{
+			name:        "Other Client",
+			event:       createTransaction("1.1", map[string]interface{}{"transactionId": "txn-1", "amount": 25.5, "currency": "GBP"}),
+			description: "Should not apply the schema to other clients",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := registry.Check(tt.event)
+
+			if tt.expectError {
+				assert.Error(t, err, tt.description)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+			} else {
+				assert.NoError(t, err, tt.description)
+			}
+		})
+	}
+}
+
+// TestRegistryUpcast tests transforming payloads of older versions into the current shape
+func TestRegistryUpcast(t *testing.T) {
+	tests := []struct {
+		name            string
+		event           *models.Event
+		expectError     bool
+		errorMsg        string
+		expectedVersion string
+		expectedPayload map[string]interface{}
+		description     string
+	}{
+		{
+			name:            "Upcast Transaction",
+			event:           createTransaction("1.0", map[string]interface{}{"transactionId": "txn-1", "amount": " 25.50", "currency": "eur"}),
+			expectedVersion: "1.1",
+			expectedPayload: map[string]interface{}{"transactionId": "txn-1", "amount": 25.5, "currency": "EUR"},
+			description:     "Should convert string amounts and lower case currencies",
+		},
+		{
+			name:            "Current Version",
+			event:           createTransaction("1.1", map[string]interface{}{"transactionId": "txn-1", "amount": 25.5, "currency": "EUR"}),
+			expectedVersion: "1.1",
+			expectedPayload: map[string]interface{}{"transactionId": "txn-1", "amount": 25.5, "currency": "EUR"},
+			description:     "Should leave events of the current version alone",
+		},
+		{
+			name:        "Invalid Amount",
+			event:       createTransaction("1.0", map[string]interface{}{"transactionId": "txn-1", "amount": "25,50", "currency": "EUR"}),
+			expectError: true,
+			errorMsg:    `failed to upcast transaction event from version 1.0 to 1.1: amount "25,50" is not a number`,
+			description: "Should fail for payloads the upcaster cannot transform",
+		},
+		{
+			name:        "Invalid Result",
+			event:       createTransaction("1.0", map[string]interface{}{"transactionId": "txn-1", "amount": 25.5, "currency": 978}),
+			expectError: true,
+			errorMsg:    "upcast from version 1.0: payload validation failed for version 1.1 of transaction events",
+			description: "Should validate the result against the schema of the current version",
+		},
+	}
+
+	registry, err := LoadRegistry(payloadSchemaDir)
+	require.NoError(t, err)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := copyPayload(tt.event.Payload)
+
+			result, err := registry.Upcast(tt.event)
+
+			if tt.expectError {
+				assert.Error(t, err, tt.description)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				assert.Nil(t, result)
+			} else {
+				require.NoError(t, err, tt.description)
+				assert.Equal(t, tt.expectedVersion, result.Version)
+				assert.Equal(t, tt.expectedPayload, result.Payload)
+			}
+			assert.Equal(t, original, tt.event.Payload, "The event should not be modified")
+		})
+	}
+}
+
+// TestRegistryUpcastChain tests upcasting across several versions
+func TestRegistryUpcastChain(t *testing.T) {
+	registry := NewRegistry()
+	require.NoError(t, registry.RegisterUpcaster(models.EventTypeUserAction, "1.0", "1.9", func(payload map[string]interface{}) (map[string]interface{}, error) {
+		payload["userId"] = payload["user"]
+		delete(payload, "user")
+		return payload, nil
+	}))
+	require.NoError(t, registry.RegisterUpcaster(models.EventTypeUserAction, "1.9", "1.10", func(payload map[string]interface{}) (map[string]interface{}, error) {
+		payload["action"] = "view"
+		return payload, nil
+	}))
+
+	result, err := registry.Upcast(&models.Event{EventType: models.EventTypeUserAction, Version: "1.0", Payload: map[string]interface{}{"user": "u-1"}})
+	require.NoError(t, err)
+	assert.Equal(t, "1.10", result.Version)
+	assert.Equal(t, map[string]interface{}{"userId": "u-1", "action": "view"}, result.Payload)
+	assert.Equal(t, "1.10", registry.CurrentVersion(models.EventTypeUserAction), "Versions should compare numerically")
+	assert.Empty(t, registry.CurrentVersion(models.EventTypeIntegration))
+}
+
+// TestRegisterUpcaster tests rejecting upcasters that could not form a chain
+func TestRegisterUpcaster(t *testing.T) {
+	noop := func(payload map[string]interface{}) (map[string]interface{}, error) { return payload, nil }
+
+	tests := []struct {
+		name        string
+		from, to    string
+		errorMsg    string
+		description string
+	}{
+		{
+			name:        "Downgrade",
+			from:        "1.1",
+			to:          "1.0",
+			errorMsg:    "upcaster of transaction events must go to a later version than 1.1, not 1.0",
+			description: "Should reject upcasters to earlier versions",
+		},
+		{
+			name:        "Second Upcaster",
+			from:        "1.0",
+			to:          "2.0",
+			errorMsg:    "version 1.0 of transaction events already has an upcaster",
+			description: "Should reject a second upcaster of a version",
+		},
+		{
+			name:        "Invalid Version",
+			from:        "1",
+			to:          "2.0",
+			errorMsg:    `invalid upcaster version for transaction events: version "1" is not of the form major.minor`,
+			description: "Should reject versions that are not of the form major.minor",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewRegistry()
+			require.NoError(t, registry.RegisterUpcaster(models.EventTypeTransaction, "1.0", "1.1", noop))
+
+			err := registry.RegisterUpcaster(models.EventTypeTransaction, tt.from, tt.to, noop)
+
+			assert.EqualError(t, err, tt.errorMsg, tt.description)
+		})
+	}
+}
+
+// TestLoadRegistry tests loading payload schemas from a directory
+func TestLoadRegistry(t *testing.T) {
+	t.Run("Missing Directory", func(t *testing.T) {
+		registry, err := LoadRegistry(filepath.Join(t.TempDir(), "missing"))
+		require.NoError(t, err)
+		assert.Equal(t, "1.1", registry.CurrentVersion(models.EventTypeTransaction), "Built-in upcasters should be registered")
+	})
+
+	t.Run("Event Type Without Schemas", func(t *testing.T) {
+		registry := NewRegistry()
+		assert.NoError(t, registry.Check(&models.Event{EventType: models.EventTypeMonitoring, Payload: map[string]interface{}{"any": 1.0}, Version: "7.3"}))
+	})
+
+	t.Run("Client Schemas", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "clients", "client-003", "user_action"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "clients", "client-003", "user_action", "1.0.json"), []byte(`{"required": ["userAgent"]}`), 0o644))
+
+		registry, err := LoadRegistry(dir)
+		require.NoError(t, err)
+		event := &models.Event{EventType: models.EventTypeUserAction, ClientID: "client-003", Payload: map[string]interface{}{"userId": "u-1"}, Version: "1.0"}
+		assert.ErrorContains(t, registry.Check(event), "client client-003")
+		event.ClientID = "client-001"
+		assert.NoError(t, registry.Check(event))
+	})
+
+	t.Run("Unknown Event Type", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "payments"), 0o755))
+
+		_, err := LoadRegistry(dir)
+		assert.EqualError(t, err, "payload schema directory payments is not named after an event type")
+	})
+
+	t.Run("Invalid Schema", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "monitoring"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "monitoring", "1.0.json"), []byte(`{"type": 12}`), 0o644))
+
+		_, err := LoadRegistry(dir)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to compile payload schema 1.0 of monitoring events")
+	})
+
+	t.Run("Unsupported Version Error", func(t *testing.T) {
+		registry, err := LoadRegistry(payloadSchemaDir)
+		require.NoError(t, err)
+
+		err = registry.Check(createTransaction("3.0", map[string]interface{}{"amount": 1.0}))
+		assert.True(t, errors.Is(err, ErrUnsupportedVersion))
+	})
+}
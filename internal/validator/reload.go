@@ -0,0 +1,186 @@
+package validator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xeipuuv/gojsonschema"
+
+	"github.com/d-sense/event-processor/internal/metrics"
+)
+
+// Layout of a schema directory
+const (
+	EventSchemaFile  = "event-schema.json"
+	PayloadSchemaDir = "payloads"
+)
+
+// schemaSet holds the compiled schemas of a schema directory
+type schemaSet struct {
+	schema      *gojsonschema.Schema
+	fields      declaredFields
+	registry    *Registry
+	fingerprint string
+	loadedAt    time.Time
+}
+
+// loadSchemaSet compiles the schemas in dir; it fails if any of them does not compile
+func loadSchemaSet(dir string) (*schemaSet, error) {
+	// The fingerprint is taken first, so that changes made while loading are loaded by the next reload
+	fingerprint, err := fingerprintDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	schemaBytes, err := os.ReadFile(filepath.Join(dir, EventSchemaFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schema file: %w", err)
+	}
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(schemaBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile schema: %w", err)
+	}
+
+	registry, err := LoadRegistry(filepath.Join(dir, PayloadSchemaDir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load payload schemas: %w", err)
+	}
+
+	return &schemaSet{
+		schema:      schema,
+		fields:      newDeclaredFields(schemaBytes, ""),
+		registry:    registry,
+		fingerprint: fingerprint,
+		loadedAt:    time.Now().UTC(),
+	}, nil
+}
+
+// fingerprintDir hashes the names and content of the schema files in dir
+func fingerprintDir(dir string) (string, error) {
+	hash := sha256.New()
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		relative, _ := filepath.Rel(dir, path)
+		fmt.Fprintf(hash, "%s\x00%d\x00", filepath.ToSlash(relative), len(content))
+		hash.Write(content)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to read schema directory: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Reload loads the schema directory again if it changed since it was last loaded, and reports
+// whether it did. The new schemas replace the ones in use at once, and only if all of them compile;
+// otherwise the schemas in use are kept and the error is returned. Content that failed to load is
+// not loaded again until it changes.
+func (v *Validator) Reload() (bool, error) {
+	fingerprint, err := fingerprintDir(v.dir)
+	if err == nil {
+		v.mu.RLock()
+		unchanged := fingerprint == v.schemas.fingerprint
+		failed := fingerprint == v.failed
+		reloadErr := v.reloadErr
+		v.mu.RUnlock()
+		if unchanged {
+			return false, nil
+		}
+		if failed {
+			return false, reloadErr
+		}
+	}
+
+	var schemas *schemaSet
+	if err == nil {
+		schemas, err = loadSchemaSet(v.dir)
+	}
+	metrics.SchemaReloads.WithLabelValues(metrics.Outcome(err)).Inc()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err != nil {
+		v.failed = fingerprint
+		v.reloadErr = err
+		return false, err
+	}
+	v.schemas = schemas
+	v.failed = ""
+	v.reloadErr = nil
+	return true, nil
+}
+
+// Watch reloads the schema directory whenever it changes, checking every interval until ctx is done
+func (v *Validator) Watch(ctx context.Context, interval time.Duration, logger *logrus.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// A failure is logged once, not on every check until the directory is fixed
+	lastFailure := ""
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		reloaded, err := v.Reload()
+		switch {
+		case err != nil:
+			if err.Error() != lastFailure {
+				logger.WithError(err).WithField("schema_dir", v.dir).Error("Failed to reload schemas, keeping the schemas in use")
+			}
+			lastFailure = err.Error()
+			continue
+		case reloaded:
+			logger.WithField("schema_dir", v.dir).Info("Schemas reloaded")
+		}
+		lastFailure = ""
+	}
+}
+
+// SchemaStatus describes the schemas a validator uses
+type SchemaStatus struct {
+	Dir         string              `json:"dir"`
+	Fingerprint string              `json:"fingerprint"`
+	LoadedAt    time.Time           `json:"loadedAt"`
+	EventTypes  []EventTypeVersions `json:"eventTypes"`
+	Clients     []ClientVersions    `json:"clients"`
+
+	// ReloadError is why the current content of the directory is not in use
+	ReloadError string `json:"reloadError,omitempty"`
+}
+
+// Status returns the versions of the schemas in use
+func (v *Validator) Status() SchemaStatus {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	status := SchemaStatus{
+		Dir:         v.dir,
+		Fingerprint: v.schemas.fingerprint,
+		LoadedAt:    v.schemas.loadedAt,
+		EventTypes:  v.schemas.registry.Versions(),
+		Clients:     v.schemas.registry.ClientVersions(),
+	}
+	if v.reloadErr != nil {
+		status.ReloadError = v.reloadErr.Error()
+	}
+	return status
+}
@@ -0,0 +1,173 @@
+package validator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+const userActionEvent = `{"eventId":"123e4567-e89b-12d3-a456-426614174000","eventType":"user_action","clientId":"client-001","timestamp":"2025-01-21T10:00:00Z","payload":{"userId":"u-1"},"version":"1.0"}`
+
+// writeSchema writes a file of a schema directory
+func writeSchema(t *testing.T, dir, name, content string) {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+// TestReload tests reloading a changed schema directory
+func TestReload(t *testing.T) {
+	tests := []struct {
+		name             string
+		change           func(t *testing.T, dir string)
+		expectReloaded   bool
+		expectError      bool
+		errorMsg         string
+		expectValidation bool
+		description      string
+	}{
+		{
+			name:        "Unchanged",
+			change:      func(t *testing.T, dir string) {},
+			description: "Should not reload an unchanged directory",
+		},
+		{
+			name: "New Payload Schema",
+			change: func(t *testing.T, dir string) {
+				writeSchema(t, dir, "payloads/user_action/1.0.json", `{"required": ["userId", "action"]}`)
+			},
+			expectReloaded:   true,
+			expectValidation: true,
+			description:      "Should validate against schemas added to the directory",
+		},
+		{
+			name: "Schema Does Not Compile",
+			change: func(t *testing.T, dir string) {
+				writeSchema(t, dir, "payloads/user_action/1.0.json", `{"required": ["userId", "action"]}`)
+				writeSchema(t, dir, "payloads/transaction/1.1.json", `{"type": "amount"}`)
+			},
+			expectError: true,
+			errorMsg:    "failed to compile payload schema 1.1 of transaction events",
+			description: "Should keep every schema in use when one of the new ones does not compile",
+		},
+		{
+			name: "Event Schema Removed",
+			change: func(t *testing.T, dir string) {
+				require.NoError(t, os.Remove(filepath.Join(dir, EventSchemaFile)))
+			},
+			expectError: true,
+			errorMsg:    "failed to load schema file",
+			description: "Should keep the schemas in use when the event schema is missing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := createTestSchemaDir(t)
+			validator, err := New(dir)
+			require.NoError(t, err)
+			loaded := validator.Status()
+
+			tt.change(t, dir)
+			reloaded, err := validator.Reload()
+
+			assert.Equal(t, tt.expectReloaded, reloaded, tt.description)
+			if tt.expectError {
+				assert.ErrorContains(t, err, tt.errorMsg, tt.description)
+				assert.Equal(t, loaded.Fingerprint, validator.Status().Fingerprint)
+				assert.Contains(t, validator.Status().ReloadError, tt.errorMsg)
+
+				// The failed content is not loaded again until it changes
+				reloaded, again := validator.Reload()
+				assert.False(t, reloaded)
+				assert.Equal(t, err, again)
+			} else {
+				assert.NoError(t, err, tt.description)
+				assert.Empty(t, validator.Status().ReloadError)
+			}
+
+			_, err = validator.ValidateAndParseEvent(userActionEvent)
+			if tt.expectValidation {
+				assert.ErrorContains(t, err, "action is required")
+			} else {
+				assert.NoError(t, err, "The schemas loaded first should still be in use")
+			}
+		})
+	}
+}
+
+// TestReloadAfterFix tests that a fixed directory is loaded and the reload error cleared
+func TestReloadAfterFix(t *testing.T) {
+	dir := createTestSchemaDir(t)
+	validator, err := New(dir)
+	require.NoError(t, err)
+
+	writeSchema(t, dir, "payloads/user_action/1.0.json", `{"type": 12}`)
+	_, err = validator.Reload()
+	require.Error(t, err)
+
+	writeSchema(t, dir, "payloads/user_action/1.0.json", `{"type": "object"}`)
+	reloaded, err := validator.Reload()
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+
+	status := validator.Status()
+	assert.Empty(t, status.ReloadError)
+	require.Len(t, status.EventTypes, 2)
+	assert.Equal(t, EventTypeVersions{EventType: models.EventTypeTransaction, CurrentVersion: "1.1", Versions: []string{}, Upcasts: []Upcast{{From: "1.0", To: "1.1"}}}, status.EventTypes[0])
+	assert.Equal(t, EventTypeVersions{EventType: models.EventTypeUserAction, CurrentVersion: "1.0", Versions: []string{"1.0"}, Upcasts: []Upcast{}}, status.EventTypes[1])
+}
+
+// TestStatus tests listing the loaded schema versions
+func TestStatus(t *testing.T) {
+	validator, err := New("../../schemas")
+	require.NoError(t, err)
+
+	status := validator.Status()
+
+	assert.Equal(t, "../../schemas", status.Dir)
+	assert.NotEmpty(t, status.Fingerprint)
+	assert.False(t, status.LoadedAt.IsZero())
+	assert.Empty(t, status.Clients)
+	versions := make(map[models.EventType][]string)
+	for _, eventType := range status.EventTypes {
+		versions[eventType.EventType] = eventType.Versions
+	}
+	assert.Equal(t, map[models.EventType][]string{
+		models.EventTypeIntegration: {"1.0"},
+		models.EventTypeMonitoring:  {"1.0"},
+		models.EventTypeTransaction: {"1.0", "1.1"},
+		models.EventTypeUserAction:  {"1.0"},
+	}, versions)
+}
+
+// TestWatch tests that watched directories are reloaded when they change
+func TestWatch(t *testing.T) {
+	dir := createTestSchemaDir(t)
+	validator, err := New(dir)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		validator.Watch(ctx, 10*time.Millisecond, logrus.New())
+		close(done)
+	}()
+
+	writeSchema(t, dir, "payloads/user_action/1.0.json", `{"required": ["userId", "action"]}`)
+	assert.Eventually(t, func() bool {
+		_, err := validator.ValidateAndParseEvent(userActionEvent)
+		return err != nil
+	}, time.Second, 10*time.Millisecond, "Changed schemas should be used without a restart")
+
+	cancel()
+	<-done
+}
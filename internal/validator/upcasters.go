@@ -0,0 +1,30 @@
+package validator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/d-sense/event-processor/pkg/models"
+)
+
+// registerBuiltinUpcasters adds the upcasters of every event type whose payload changed shape
+func registerBuiltinUpcasters(registry *Registry) error {
+	return registry.RegisterUpcaster(models.EventTypeTransaction, "1.0", "1.1", upcastTransaction10)
+}
+
+// upcastTransaction10 upcasts transactions from version 1.0, which allowed amounts as strings and
+// currency codes in any case, to version 1.1
+func upcastTransaction10(payload map[string]interface{}) (map[string]interface{}, error) {
+	if amount, ok := payload["amount"].(string); ok {
+		value, err := strconv.ParseFloat(strings.TrimSpace(amount), 64)
+		if err != nil {
+			return nil, fmt.Errorf("amount %q is not a number", amount)
+		}
+		payload["amount"] = value
+	}
+	if currency, ok := payload["currency"].(string); ok {
+		payload["currency"] = strings.ToUpper(currency)
+	}
+	return payload, nil
+}
@@ -1,9 +1,11 @@
 package validator
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
 	"github.com/xeipuuv/gojsonschema"
@@ -11,28 +13,38 @@ import (
 	"github.com/d-sense/event-processor/pkg/models"
 )
 
+// Validator validates events against the schemas of a schema directory, which it can reload while
+// events are being validated
 type Validator struct {
-	schema *gojsonschema.Schema
-}
+	dir string
 
-// New creates a new validator with the provided schema file
-func New(schemaPath string) *Validator {
-	// Load schema file
-	schemaBytes, err := os.ReadFile(schemaPath)
-	if err != nil {
-		panic(fmt.Sprintf("Failed to load schema file: %v", err))
-	}
+	mu      sync.RWMutex
+	schemas *schemaSet
 
-	// Compile schema
-	schemaLoader := gojsonschema.NewBytesLoader(schemaBytes)
-	schema, err := gojsonschema.NewSchema(schemaLoader)
+	// failed is the fingerprint of directory content that did not load, and reloadErr why
+	failed    string
+	reloadErr error
+}
+
+// New creates a new validator with the schemas in dir: the event schema in event-schema.json and
+// the versioned payload schemas in payloads/
+func New(dir string) (*Validator, error) {
+	schemas, err := loadSchemaSet(dir)
 	if err != nil {
-		panic(fmt.Sprintf("Failed to compile schema: %v", err))
+		return nil, err
 	}
 
 	return &Validator{
-		schema: schema,
-	}
+		dir:     dir,
+		schemas: schemas,
+	}, nil
+}
+
+// current returns the schemas in use
+func (v *Validator) current() *schemaSet {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.schemas
 }
 
 // ValidateAndParseEvent validates and parses event data into Event struct
@@ -62,14 +74,20 @@ func (v *Validator) ValidateAndParseEvent(eventData interface{}) (*models.Event,
 		}
 	}
 
+	// Use the same schemas throughout, even if they are reloaded meanwhile
+	schemas := v.current()
+
 	// First validate against schema
-	if err := v.ValidateEventBytes(eventBytes); err != nil {
-		return nil, err
+	if err := schemas.validateEventBytes(eventBytes); err != nil {
+		return nil, schemas.reportFailure(err)
 	}
 
-	// Parse into Event struct
+	// Parse into Event struct, keeping payload numbers as json.Number so large integers and
+	// high-precision decimals reach storage unchanged
 	var event models.Event
-	if err := json.Unmarshal(eventBytes, &event); err != nil {
+	decoder := json.NewDecoder(bytes.NewReader(eventBytes))
+	decoder.UseNumber()
+	if err := decoder.Decode(&event); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal event: %w", err)
 	}
 
@@ -78,26 +96,61 @@ func (v *Validator) ValidateAndParseEvent(eventData interface{}) (*models.Event,
 		return nil, err
 	}
 
+	// Validate the payload against the schema of its version
+	if err := schemas.registry.Check(&event); err != nil {
+		return nil, schemas.reportFailure(err)
+	}
+
 	return &event, nil
 }
 
-// ValidateEventBytes validates event bytes against the JSON schema
+// Upcast returns a validated event with its payload transformed to the current version of its
+// event type
+func (v *Validator) Upcast(event *models.Event) (*models.Event, error) {
+	schemas := v.current()
+	upcast, err := schemas.registry.Upcast(event)
+	if err != nil {
+		return nil, schemas.reportFailure(err)
+	}
+	return upcast, nil
+}
+
+// ValidateEventBytes validates event bytes against the JSON schema; events that do not match it
+// fail with a ValidationError
 func (v *Validator) ValidateEventBytes(eventBytes []byte) error {
+	schemas := v.current()
+	if err := schemas.validateEventBytes(eventBytes); err != nil {
+		return schemas.reportFailure(err)
+	}
+	return nil
+}
+
+// reportFailure records the schemas a ValidationError in err's chain was found with, and counts its
+// violations; err is returned as it is
+func (s *schemaSet) reportFailure(err error) error {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		validationErr.SchemaFingerprint = s.fingerprint
+		countViolations(validationErr)
+	}
+	return err
+}
+
+func (s *schemaSet) validateEventBytes(eventBytes []byte) error {
 	// Create document loader
 	documentLoader := gojsonschema.NewBytesLoader(eventBytes)
 
 	// Validate against schema
-	result, err := v.schema.Validate(documentLoader)
+	result, err := s.schema.Validate(documentLoader)
 	if err != nil {
 		return fmt.Errorf("validation error: %w", err)
 	}
 
 	if !result.Valid() {
-		var errors []string
-		for _, err := range result.Errors() {
-			errors = append(errors, err.String())
-		}
-		return fmt.Errorf("validation failed: %v", errors)
+		return newValidationError(result, models.ValidationFailure{
+			Schema:    models.ValidationSchemaEvent,
+			EventType: eventTypeOf(eventBytes),
+		}, "", s.fields)
 	}
 
 	return nil
@@ -2,6 +2,8 @@ package validator
 
 import (
 	"encoding/json"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -31,49 +33,9 @@ type eventTestCase struct {
 
 // Helper function to create a test validator
 func createTestValidator(t *testing.T) *Validator {
-	// Create a temporary schema file for testing
-	schemaContent := `{
-		"$schema": "http://json-schema.org/draft-07/schema#",
-		"type": "object",
-		"required": ["eventId", "eventType", "clientId", "timestamp", "payload", "version"],
-		"properties": {
-			"eventId": {
-				"type": "string",
-				"pattern": "^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$"
-			},
-			"eventType": {
-				"type": "string",
-				"enum": ["monitoring", "user_action", "transaction", "integration"]
-			},
-			"clientId": {
-				"type": "string",
-				"minLength": 1,
-				"maxLength": 100,
-				"pattern": "^[a-zA-Z0-9_-]+$"
-			},
-			"timestamp": {
-				"type": "string",
-				"format": "date-time"
-			},
-			"payload": {
-				"type": "object",
-				"minProperties": 1,
-				"additionalProperties": true
-			},
-			"version": {
-				"type": "string",
-				"pattern": "^\\d+\\.\\d+$"
-			}
-		},
-		"additionalProperties": false
-	}`
-
-	// Create a temporary file
-	tmpFile := createTempSchemaFile(t, schemaContent)
-	defer cleanupTempFile(t, tmpFile)
-
-	validator := New(tmpFile)
-	require.NotNil(t, validator)
+	// Validate against the service's event schema, without payload schemas
+	validator, err := New(createTestSchemaDir(t))
+	require.NoError(t, err)
 	return validator
 }
 
@@ -346,19 +308,94 @@ func TestBusinessRulesValidation(t *testing.T) {
 
 // TestValidatorCreation tests the validator constructor
 func TestValidatorCreation(t *testing.T) {
-	t.Run("Valid Schema File", func(t *testing.T) {
+	t.Run("Valid Schema Directory", func(t *testing.T) {
 		validator := createTestValidator(t)
 		assert.NotNil(t, validator)
-		assert.NotNil(t, validator.schema)
+		assert.NotNil(t, validator.current().schema)
 	})
 
-	t.Run("Invalid Schema File Path", func(t *testing.T) {
-		assert.Panics(t, func() {
-			New("nonexistent-file.json")
-		}, "Should panic when schema file doesn't exist")
+	t.Run("Missing Schema Directory", func(t *testing.T) {
+		validator, err := New("nonexistent-dir")
+		assert.Error(t, err, "Should fail when the schema directory doesn't exist")
+		assert.Nil(t, validator)
+	})
+
+	t.Run("Invalid Payload Schema Directory", func(t *testing.T) {
+		dir := createTestSchemaDir(t)
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, PayloadSchemaDir, "payments"), 0o755))
+		_, err := New(dir)
+		assert.ErrorContains(t, err, "failed to load payload schemas", "Should fail when payload schemas cannot be loaded")
 	})
 }
 
+// TestVersionedPayloads tests validating and upcasting payloads by their version
+func TestVersionedPayloads(t *testing.T) {
+	validator, err := New("../../schemas")
+	require.NoError(t, err)
+	transaction := func(version, payload string) string {
+		return `{"eventId":"123e4567-e89b-12d3-a456-426614174000","eventType":"transaction","clientId":"client-001","timestamp":"2025-01-21T10:00:00Z","payload":` + payload + `,"version":"` + version + `"}`
+	}
+
+	tests := []struct {
+		name            string
+		eventJSON       string
+		expectError     bool
+		errorMsg        string
+		expectedPayload map[string]interface{}
+		description     string
+	}{
+		{
+			name:            "Older Version",
+			eventJSON:       transaction("1.0", `{"transactionId":"txn-1","amount":"12000","currency":"usd"}`),
+			expectedPayload: map[string]interface{}{"transactionId": "txn-1", "amount": 12000.0, "currency": "USD"},
+			description:     "Should accept and upcast payloads of an older version",
+		},
+		{
+			name:            "Current Version",
+			eventJSON:       transaction("1.1", `{"transactionId":"txn-1","amount":12000,"currency":"USD"}`),
+			expectedPayload: map[string]interface{}{"transactionId": "txn-1", "amount": json.Number("12000"), "currency": "USD"},
+			description:     "Should accept payloads of the current version",
+		},
+		{
+			name:            "Precise Amount",
+			eventJSON:       transaction("1.1", `{"transactionId":"txn-1","amount":9007199254740993.25,"currency":"USD"}`),
+			expectedPayload: map[string]interface{}{"transactionId": "txn-1", "amount": json.Number("9007199254740993.25"), "currency": "USD"},
+			description:     "Should keep numbers a float64 cannot represent exactly",
+		},
+		{
+			name:        "Missing Field",
+			eventJSON:   transaction("1.1", `{"transactionId":"txn-1","currency":"USD"}`),
+			expectError: true,
+			errorMsg:    "payload validation failed for version 1.1 of transaction events",
+			description: "Should reject payloads failing the schema of their version",
+		},
+		{
+			name:        "Unsupported Version",
+			eventJSON:   transaction("0.9", `{"transactionId":"txn-1","amount":1,"currency":"USD"}`),
+			expectError: true,
+			errorMsg:    "unsupported version 0.9 of transaction events",
+			description: "Should reject versions without a schema or upcaster",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := validator.ValidateAndParseEvent(tt.eventJSON)
+
+			if tt.expectError {
+				assert.Error(t, err, tt.description)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				return
+			}
+			require.NoError(t, err, tt.description)
+			upcast, err := validator.Upcast(event)
+			require.NoError(t, err)
+			assert.Equal(t, "1.1", upcast.Version)
+			assert.Equal(t, tt.expectedPayload, upcast.Payload)
+		})
+	}
+}
+
 // TestEdgeCases tests various edge cases and boundary conditions
 func TestEdgeCases(t *testing.T) {
 	validator := createTestValidator(t)
@@ -435,12 +472,12 @@ func assertValidEvent(t *testing.T, event *models.Event) {
 	assert.NotEmpty(t, event.Version)
 }
 
-// createTempSchemaFile creates a temporary schema file for testing purposes
-func createTempSchemaFile(t *testing.T, content string) string {
-	// We are using a placeholder here for the actual implementation.
-	return "../../schemas/event-schema.json"
-}
+// createTestSchemaDir creates a schema directory holding the service's event schema
+func createTestSchemaDir(t *testing.T) string {
+	schema, err := os.ReadFile(filepath.Join("../../schemas", EventSchemaFile))
+	require.NoError(t, err)
 
-func cleanupTempFile(t *testing.T, filePath string) {
-	// Of course, we don't want to remove/delete the actual file.
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, EventSchemaFile), schema, 0o644))
+	return dir
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 
 	"github.com/d-sense/event-processor/internal/config"
 )
@@ -27,3 +28,12 @@ func NewSession(cfg *config.Config) (aws.Config, error) {
 
 	return awsCfg, nil
 }
+
+// NewS3Client creates an S3 client for the configured endpoint, using path style addressing so
+// S3 compatible stores work without bucket subdomains
+func NewS3Client(awsCfg aws.Config, cfg *config.Config) *s3.Client {
+	return s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		o.BaseEndpoint = aws.String(cfg.AWSEndpointURL)
+		o.UsePathStyle = true
+	})
+}
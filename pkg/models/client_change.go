@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// ClientAction is a change made to a client configuration
+type ClientAction string
+
+const (
+	ClientActionCreate  ClientAction = "create"
+	ClientActionUpdate  ClientAction = "update"
+	ClientActionEnable  ClientAction = "enable"
+	ClientActionDisable ClientAction = "disable"
+	ClientActionDelete  ClientAction = "delete"
+)
+
+// ClientChange records who changed a client configuration and how
+type ClientChange struct {
+	Time     time.Time    `json:"time"`
+	Actor    string       `json:"actor"`
+	Action   ClientAction `json:"action"`
+	ClientID string       `json:"clientId"`
+
+	// Before is nil for created clients and After is nil for deleted ones
+	Before *ClientConfig `json:"before,omitempty"`
+	After  *ClientConfig `json:"after,omitempty"`
+}
@@ -18,11 +18,22 @@ const (
 type EventStatus string
 
 const (
-	EventStatusPending   EventStatus = "pending"
-	EventStatusProcessed EventStatus = "processed"
-	EventStatusFailed    EventStatus = "failed"
+	EventStatusPending     EventStatus = "pending"
+	EventStatusProcessed   EventStatus = "processed"
+	EventStatusFailed      EventStatus = "failed"
+	EventStatusRetrying    EventStatus = "retrying"
+	EventStatusReprocessed EventStatus = "reprocessed"
 )
 
+// allowedTransitions lists the statuses each status may move to
+var allowedTransitions = map[EventStatus][]EventStatus{
+	EventStatusPending:     {EventStatusProcessed, EventStatusFailed},
+	EventStatusFailed:      {EventStatusRetrying},
+	EventStatusRetrying:    {EventStatusProcessed, EventStatusFailed},
+	EventStatusProcessed:   {EventStatusReprocessed},
+	EventStatusReprocessed: {EventStatusReprocessed, EventStatusFailed},
+}
+
 // Event represents the core event structure
 type Event struct {
 	EventID   string                 `json:"eventId" dynamodb:"event_id"`
@@ -40,20 +51,67 @@ type ProcessedEvent struct {
 	Status      EventStatus `json:"status" dynamodb:"status"`
 	ErrorMsg    string      `json:"errorMsg,omitempty" dynamodb:"error_msg,omitempty"`
 	RetryCount  int         `json:"retryCount" dynamodb:"retry_count"`
-	TTL         int64       `json:"ttl" dynamodb:"ttl"`
+
+	// TTL is the Unix time the event expires at; zero means it never expires
+	TTL int64 `json:"ttl" dynamodb:"ttl"`
+
+	// Revision is incremented on every status update and guards against concurrent modification
+	Revision int64 `json:"revision" dynamodb:"revision"`
+
+	// Replays lists the reprocessing runs of the event, oldest first and at most MaxReplayRecords
+	Replays []ReplayRecord `json:"replays,omitempty" dynamodb:"replays,omitempty"`
+
+	// DerivedFields lists the payload fields added by processing rather than sent by the client
+	DerivedFields []string `json:"derivedFields,omitempty" dynamodb:"derived_fields,omitempty"`
+}
+
+// MaxReplayRecords is the number of reprocessing runs kept on an event
+const MaxReplayRecords = 10
+
+// ReplayRecord records a run that reprocessed a stored event
+type ReplayRecord struct {
+	RunID          string      `json:"runId"`
+	ReplayedAt     time.Time   `json:"replayedAt"`
+	PreviousStatus EventStatus `json:"previousStatus"`
+	Status         EventStatus `json:"status"`
+
+	// Changed reports whether the run changed the status, error or payload of the event
+	Changed bool `json:"changed"`
+}
+
+// RecordReplay adds a reprocessing run to the event, dropping the oldest runs beyond MaxReplayRecords
+func (e *ProcessedEvent) RecordReplay(record ReplayRecord) {
+	replays := append(append([]ReplayRecord(nil), e.Replays...), record)
+	if len(replays) > MaxReplayRecords {
+		replays = replays[len(replays)-MaxReplayRecords:]
+	}
+	e.Replays = replays
+}
+
+// ReplayedBy reports whether the event was already reprocessed by a run
+func (e *ProcessedEvent) ReplayedBy(runID string) bool {
+	for _, record := range e.Replays {
+		if record.RunID == runID {
+			return true
+		}
+	}
+	return false
 }
 
-// ToProcessedEvent converts an Event to ProcessedEvent
+// DefaultRetention is how long a processed event is kept when no retention policy applies
+const DefaultRetention = 30 * 24 * time.Hour
+
+// ToProcessedEvent converts an Event to ProcessedEvent, expiring after DefaultRetention
 func (e *Event) ToProcessedEvent() *ProcessedEvent {
-	// CWe should calculate TTL: 30 days from now
-	ttl := time.Now().Add(30 * 24 * time.Hour).Unix()
+	processedAt := time.Now().UTC()
 
 	return &ProcessedEvent{
 		Event:       *e,
-		ProcessedAt: time.Now().UTC(),
+		ProcessedAt: processedAt,
 		Status:      EventStatusPending,
 		RetryCount:  0,
-		TTL:         ttl,
+		TTL:         processedAt.Add(DefaultRetention).Unix(),
+		Revision:    1,
 	}
 }
 
@@ -67,10 +125,36 @@ func IsValidEventType(eventType string) bool {
 	}
 }
 
+// IsValidEventStatus checks if the event status is valid
+func IsValidEventStatus(status string) bool {
+	switch EventStatus(status) {
+	case EventStatusPending, EventStatusProcessed, EventStatusFailed, EventStatusRetrying, EventStatusReprocessed:
+		return true
+	default:
+		return false
+	}
+}
+
+// CanTransition reports whether an event may move from one status to another
+func CanTransition(from, to EventStatus) bool {
+	for _, allowed := range allowedTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
 // ClientConfig represents per-client configuration
 type ClientConfig struct {
 	ClientID     string            `json:"clientId" dynamodb:"client_id"`
 	AllowedTypes []EventType       `json:"allowedTypes" dynamodb:"allowed_types"`
 	Config       map[string]string `json:"config" dynamodb:"config"`
 	Active       bool              `json:"active" dynamodb:"active"`
+
+	// Policies further restricts the events of allowed types, keyed by event type
+	Policies map[EventType]EventPolicy `json:"policies,omitempty" dynamodb:"policies"`
+
+	// Limits caps how fast and how many events the client may send; nil means unlimited
+	Limits *ClientLimits `json:"limits,omitempty" dynamodb:"limits"`
 }
@@ -0,0 +1,33 @@
+package models
+
+// ExcessAction decides what happens to events over a client's rate limit or quota
+type ExcessAction string
+
+const (
+	// ExcessDelay holds events until the rate limit admits them; events over a quota are rejected
+	ExcessDelay ExcessAction = "delay"
+
+	// ExcessReject fails events, so they are retried and eventually dead lettered
+	ExcessReject ExcessAction = "reject"
+
+	// ExcessSample keeps a random SampleRate share of the excess events and drops the rest
+	ExcessSample ExcessAction = "sample"
+)
+
+// ClientLimits caps the events of a client. Zero values leave the respective limit off.
+type ClientLimits struct {
+	// RatePerSecond is the steady rate of the client's token bucket and Burst its size; Burst
+	// defaults to the rate rounded up
+	RatePerSecond float64 `json:"ratePerSecond,omitempty"`
+	Burst         int     `json:"burst,omitempty"`
+
+	// DailyQuota and MonthlyQuota cap the events per UTC day and month
+	DailyQuota   int64 `json:"dailyQuota,omitempty"`
+	MonthlyQuota int64 `json:"monthlyQuota,omitempty"`
+
+	// OnExcess defaults to reject
+	OnExcess ExcessAction `json:"onExcess,omitempty"`
+
+	// SampleRate is the share of excess events kept by the sample action, between 0 and 1
+	SampleRate float64 `json:"sampleRate,omitempty"`
+}
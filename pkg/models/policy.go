@@ -0,0 +1,109 @@
+package models
+
+// EventPolicy restricts the events of one type a client may send, on top of AllowedTypes
+type EventPolicy struct {
+	// Versions lists the accepted event versions; empty accepts every version
+	Versions []string `json:"versions,omitempty"`
+
+	// Payload constrains payload fields by name
+	Payload map[string]PayloadConstraint `json:"payload,omitempty"`
+
+	// Windows lists when events are accepted; empty accepts events at any time
+	Windows []TimeWindow `json:"windows,omitempty"`
+
+	// WindowsOnEventTime checks windows against the timestamp the client set on the event instead of
+	// the time the event was received
+	WindowsOnEventTime bool `json:"windowsOnEventTime,omitempty"`
+}
+
+// PayloadConstraint restricts a single top-level payload field; constraints on the value only
+// apply when the field is present
+type PayloadConstraint struct {
+	Required bool `json:"required,omitempty"`
+
+	// OneOf lists the accepted values of a string field
+	OneOf []string `json:"oneOf,omitempty"`
+
+	// Pattern is a regular expression a string field must match
+	Pattern string `json:"pattern,omitempty"`
+
+	// Min and Max bound a numeric field, inclusive
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+}
+
+// TimeWindow is a daily time range, e.g. 09:00 to 17:00 on weekdays. A window whose end is not after
+// its start spans midnight.
+type TimeWindow struct {
+	// Start and End are HH:MM times of day; End is exclusive
+	Start string `json:"start"`
+	End   string `json:"end"`
+
+	// Days restricts the window to days of the week given as mon, tue, ...; empty means every day
+	Days []string `json:"days,omitempty"`
+
+	// Timezone is the IANA name of the zone Start and End are in; empty means UTC
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// Clone returns a deep copy of the client configuration
+func (c *ClientConfig) Clone() *ClientConfig {
+	copied := *c
+	if c.AllowedTypes != nil {
+		copied.AllowedTypes = append([]EventType(nil), c.AllowedTypes...)
+	}
+	if c.Config != nil {
+		copied.Config = make(map[string]string, len(c.Config))
+		for key, value := range c.Config {
+			copied.Config[key] = value
+		}
+	}
+	if c.Policies != nil {
+		copied.Policies = make(map[EventType]EventPolicy, len(c.Policies))
+		for eventType, policy := range c.Policies {
+			copied.Policies[eventType] = policy.clone()
+		}
+	}
+	if c.Limits != nil {
+		limits := *c.Limits
+		copied.Limits = &limits
+	}
+	return &copied
+}
+
+// clone returns a deep copy of the policy
+func (p EventPolicy) clone() EventPolicy {
+	copied := EventPolicy{Versions: cloneStrings(p.Versions), WindowsOnEventTime: p.WindowsOnEventTime}
+	if p.Payload != nil {
+		copied.Payload = make(map[string]PayloadConstraint, len(p.Payload))
+		for field, constraint := range p.Payload {
+			constraint.OneOf = cloneStrings(constraint.OneOf)
+			constraint.Min = cloneFloat(constraint.Min)
+			constraint.Max = cloneFloat(constraint.Max)
+			copied.Payload[field] = constraint
+		}
+	}
+	if p.Windows != nil {
+		copied.Windows = make([]TimeWindow, len(p.Windows))
+		for i, window := range p.Windows {
+			window.Days = cloneStrings(window.Days)
+			copied.Windows[i] = window
+		}
+	}
+	return copied
+}
+
+func cloneStrings(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	return append([]string(nil), values...)
+}
+
+func cloneFloat(value *float64) *float64 {
+	if value == nil {
+		return nil
+	}
+	copied := *value
+	return &copied
+}
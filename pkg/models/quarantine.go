@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+)
+
+// QuarantineCategory says why an event was quarantined
+type QuarantineCategory string
+
+const (
+	// QuarantineValidation is for events failing the JSON schema or business rules
+	QuarantineValidation QuarantineCategory = "validation"
+
+	// QuarantinePayload is for events missing a field their event type requires
+	QuarantinePayload QuarantineCategory = "payload"
+
+	// QuarantineAuthorization is for events their client may not send
+	QuarantineAuthorization QuarantineCategory = "authorization"
+)
+
+// IsValidQuarantineCategory checks if a quarantine category is known
+func IsValidQuarantineCategory(category string) bool {
+	switch QuarantineCategory(category) {
+	case QuarantineValidation, QuarantinePayload, QuarantineAuthorization:
+		return true
+	default:
+		return false
+	}
+}
+
+// QuarantinedEvent is an event rejected for a reason retrying cannot fix, kept with its raw body
+// until it is fixed and resubmitted or discarded
+type QuarantinedEvent struct {
+	QuarantineID string             `json:"quarantineId" dynamodb:"quarantine_id"`
+	Category     QuarantineCategory `json:"category" dynamodb:"category"`
+
+	// ClientID, EventID and EventType are read from the body on a best effort basis and may be
+	// empty for bodies that are not valid events
+	ClientID  string    `json:"clientId,omitempty" dynamodb:"client_id,omitempty"`
+	EventID   string    `json:"eventId,omitempty" dynamodb:"event_id,omitempty"`
+	EventType EventType `json:"eventType,omitempty" dynamodb:"event_type,omitempty"`
+
+	// Errors lists the reasons the event was rejected
+	Errors []string `json:"errors" dynamodb:"errors"`
+
+	// Validation lists the schema rules the event breaks, for events rejected by a schema
+	Validation *ValidationFailure `json:"validation,omitempty" dynamodb:"validation,omitempty"`
+
+	// Body is the event exactly as it was received
+	Body string `json:"body" dynamodb:"body"`
+
+	QuarantinedAt time.Time `json:"quarantinedAt" dynamodb:"quarantined_at"`
+}
@@ -0,0 +1,44 @@
+package models
+
+// Schemas an event is validated against, reported by ValidationFailure
+const (
+	// ValidationSchemaEvent is the schema every event envelope must match
+	ValidationSchemaEvent = "event"
+
+	// ValidationSchemaPayload is the payload schema of the event's type and version
+	ValidationSchemaPayload = "payload"
+)
+
+// Violation is a schema rule an event breaks
+type Violation struct {
+	// Field is the path of the field breaking the rule, e.g. payload.amount; fields of the payload
+	// are prefixed with payload
+	Field string `json:"field"`
+
+	// Rule names the broken rule, e.g. required, invalid_type or enum
+	Rule string `json:"rule"`
+
+	// Expected and Actual are what the rule allows and what was sent, when the rule says
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+
+	Message string `json:"message"`
+}
+
+// ValidationFailure describes why an event does not match a schema
+type ValidationFailure struct {
+	// Schema is ValidationSchemaEvent or ValidationSchemaPayload
+	Schema    string    `json:"schema"`
+	EventType EventType `json:"eventType,omitempty"`
+
+	// SchemaVersion is the payload version the event was validated against
+	SchemaVersion string `json:"schemaVersion,omitempty"`
+
+	// ClientSchema is the client whose own payload schema failed, empty for the shared schemas
+	ClientSchema string `json:"clientSchema,omitempty"`
+
+	// SchemaFingerprint identifies the schemas in use, as listed by the schema admin API
+	SchemaFingerprint string `json:"schemaFingerprint,omitempty"`
+
+	Violations []Violation `json:"violations"`
+}
@@ -82,8 +82,8 @@ fi
 
 # Check recent logs for successful infrastructure setup
 echo -e "\n📊 Checking infrastructure setup logs..."
-if docker-compose logs event-processor | grep -q "Infrastructure setup completed successfully"; then
-    print_status "OK" "Infrastructure setup completed successfully"
+if docker-compose logs event-processor | grep -q "Infrastructure matches the spec"; then
+    print_status "OK" "Infrastructure matches the spec"
 else
     print_status "WARN" "Infrastructure setup logs not found (may still be initializing)"
 fi